@@ -34,7 +34,6 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	"go.opentelemetry.io/otel/trace"
-	"golang.org/x/mod/semver"
 	"golang.org/x/oauth2"
 	xgithub "golang.org/x/oauth2/github"
 	"golang.org/x/sync/errgroup"
@@ -404,27 +403,29 @@ func Server(vip *viper.Viper, newAPI func(context.Context, *coderd.Options) (*co
 					return xerrors.Errorf("dial postgres: %w", err)
 				}
 				defer sqlDB.Close()
-				// Ensure the PostgreSQL version is >=13.0.0!
-				version, err := sqlDB.QueryContext(ctx, "SHOW server_version;")
+				err = sqlDB.Ping()
 				if err != nil {
-					return xerrors.Errorf("get postgres version: %w", err)
-				}
-				if !version.Next() {
-					return xerrors.Errorf("no rows returned for version select")
+					return xerrors.Errorf("ping postgres: %w", err)
 				}
-				var versionStr string
-				err = version.Scan(&versionStr)
+				// Fail before migrating rather than with syntax errors or
+				// subtle comparison bugs later.
+				err = database.New(sqlDB).CheckServerVersion(ctx, database.MinServerVersion)
 				if err != nil {
-					return xerrors.Errorf("scan version: %w", err)
+					return xerrors.Errorf("check postgres version: %w", err)
 				}
-				versionStr = strings.Split(versionStr, " ")[0]
-				if semver.Compare("v"+versionStr, "v13") < 0 {
-					return xerrors.New("PostgreSQL version must be v13.0.0 or higher!")
-				}
-
-				err = sqlDB.Ping()
+				err = database.New(sqlDB).CheckEncoding(ctx)
 				if err != nil {
-					return xerrors.Errorf("ping postgres: %w", err)
+					// Refusing to start would strand deployments created
+					// before the check existed, so only new databases fail.
+					var migrated bool
+					migratedErr := sqlDB.QueryRowContext(ctx, "SELECT to_regclass('schema_migrations') IS NOT NULL").Scan(&migrated)
+					if migratedErr != nil {
+						return xerrors.Errorf("check schema migrations: %w", migratedErr)
+					}
+					if !migrated {
+						return xerrors.Errorf("check postgres encoding: %w", err)
+					}
+					logger.Warn(ctx, "postgres encoding does not fold non-ASCII case, so usernames and emails differing only in case may collide", slog.Error(err))
 				}
 				err = migrations.Up(sqlDB)
 				if err != nil {
@@ -1201,6 +1202,11 @@ func startBuiltinPostgres(ctx context.Context, cfg config.Root, logger slog.Logg
 			Username("coder").
 			Password(pgPassword).
 			Database("coder").
+			// Without a locale initdb inherits the host's, which is C with
+			// SQL_ASCII on slim containers, failing CheckEncoding. C.UTF-8
+			// is built into glibc and musl, unlike en_US.UTF-8, which
+			// minimal images often do not generate.
+			Locale("C.UTF-8").
 			Port(uint32(pgPort)).
 			Logger(stdlibLogger.Writer()),
 	)
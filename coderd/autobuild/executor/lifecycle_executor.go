@@ -220,6 +220,9 @@ func getNextTransition(
 		// it ensures we will not stop too early.
 		return database.WorkspaceTransitionStop, priorHistory.Deadline, nil
 	case database.WorkspaceTransitionStop:
+		if ws.DormantAt.Valid {
+			return "", time.Time{}, xerrors.Errorf("workspace is dormant")
+		}
 		sched, err := schedule.Weekly(ws.AutostartSchedule.String)
 		if err != nil {
 			return "", time.Time{}, xerrors.Errorf("workspace has invalid autostart schedule: %w", err)
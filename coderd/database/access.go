@@ -0,0 +1,95 @@
+package database
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"golang.org/x/xerrors"
+)
+
+// WorkspaceAccessLevel is what a user may do with a workspace.
+type WorkspaceAccessLevel string
+
+const (
+	WorkspaceAccessNone WorkspaceAccessLevel = "none"
+	WorkspaceAccessRead WorkspaceAccessLevel = "read"
+	WorkspaceAccessFull WorkspaceAccessLevel = "full"
+)
+
+// WorkspaceAccess is a workspace along with a user's access to it.
+type WorkspaceAccess struct {
+	Workspace
+	Access WorkspaceAccessLevel `db:"access" json:"access"`
+}
+
+type accessQuerier interface {
+	// GetWorkspaceWithUserAccess returns the workspace and the access the
+	// user has to it, resolved in the same round-trip. It returns
+	// sql.ErrNoRows if either the workspace or the user does not exist.
+	//
+	// The access level is a SQL rendering of the built-in roles in
+	// coderd/rbac and is meant for listing and display, not enforcement;
+	// rbac stays the source of truth and this query must be updated when
+	// the built-in roles change.
+	GetWorkspaceWithUserAccess(ctx context.Context, workspaceID, userID uuid.UUID) (WorkspaceAccess, error)
+}
+
+func (q *sqlQuerier) GetWorkspaceWithUserAccess(ctx context.Context, workspaceID, userID uuid.UUID) (WorkspaceAccess, error) {
+	// Access is resolved in order, first match wins:
+	//
+	//  1. The site-wide "owner" role can do anything.
+	//  2. The workspace owner has full access, but only while they are
+	//     still a member of the workspace's organization. Member
+	//     permissions are user-scoped and rbac requires org membership for
+	//     objects that belong to an org.
+	//  3. The org's "organization-admin:<org_id>" member role can do
+	//     anything within that org.
+	//  4. The site-wide "template-admin" role may read every workspace.
+	//  5. Anyone else, including auditors and user admins, has no access.
+	//
+	// Groups and ACLs only apply to templates, and API key scopes narrow
+	// access per request, so neither is considered here.
+	const query = `-- name: GetWorkspaceWithUserAccess :one
+	SELECT
+		workspaces.id, workspaces.created_at, workspaces.updated_at, workspaces.owner_id,
+		workspaces.organization_id, workspaces.template_id, workspaces.deleted, workspaces.name,
+		workspaces.autostart_schedule, workspaces.ttl, workspaces.last_used_at, workspaces.dormant_at,
+		CASE
+			WHEN 'owner' = ANY(users.rbac_roles) THEN 'full'
+			WHEN workspaces.owner_id = users.id AND organization_members.user_id IS NOT NULL THEN 'full'
+			WHEN 'organization-admin:' || workspaces.organization_id::text = ANY(organization_members.roles) THEN 'full'
+			WHEN 'template-admin' = ANY(users.rbac_roles) THEN 'read'
+			ELSE 'none'
+		END AS access
+	FROM
+		workspaces
+	JOIN
+		users ON users.id = $2
+	LEFT JOIN
+		organization_members ON organization_members.organization_id = workspaces.organization_id
+		AND organization_members.user_id = users.id
+	WHERE
+		workspaces.id = $1
+	`
+
+	var i WorkspaceAccess
+	err := q.db.QueryRowContext(ctx, query, workspaceID, userID).Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.OwnerID,
+		&i.OrganizationID,
+		&i.TemplateID,
+		&i.Deleted,
+		&i.Name,
+		&i.AutostartSchedule,
+		&i.Ttl,
+		&i.LastUsedAt,
+		&i.DormantAt,
+		&i.Access,
+	)
+	if err != nil {
+		return WorkspaceAccess{}, xerrors.Errorf("get workspace with user access: %w", err)
+	}
+	return i, nil
+}
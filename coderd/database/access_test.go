@@ -0,0 +1,88 @@
+//go:build linux
+
+package database_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/coder/coder/coderd/database"
+	"github.com/coder/coder/coderd/database/dbtestutil"
+	"github.com/coder/coder/coderd/rbac"
+)
+
+func TestGetWorkspaceWithUserAccess(t *testing.T) {
+	t.Parallel()
+
+	db, _ := dbtestutil.NewDB(t)
+	ctx := context.Background()
+
+	owner, org, template := insertTemplate(t, db)
+	workspace, err := db.InsertWorkspace(ctx, database.InsertWorkspaceParams{
+		ID:             uuid.New(),
+		CreatedAt:      database.Now(),
+		UpdatedAt:      database.Now(),
+		OwnerID:        owner.ID,
+		OrganizationID: org.ID,
+		TemplateID:     template.ID,
+		Name:           "workspace",
+	})
+	require.NoError(t, err)
+
+	insertUser := func(name string, siteRoles []string, orgRoles ...string) uuid.UUID {
+		user, err := db.InsertUser(ctx, database.InsertUserParams{
+			ID:             uuid.New(),
+			Email:          name + "@coder.com",
+			Username:       name,
+			HashedPassword: []byte{},
+			CreatedAt:      database.Now(),
+			UpdatedAt:      database.Now(),
+			RBACRoles:      siteRoles,
+			LoginType:      database.LoginTypePassword,
+		})
+		require.NoError(t, err)
+		_, err = db.InsertOrganizationMember(ctx, database.InsertOrganizationMemberParams{
+			OrganizationID: org.ID,
+			UserID:         user.ID,
+			CreatedAt:      database.Now(),
+			UpdatedAt:      database.Now(),
+			Roles:          append([]string{rbac.RoleOrgMember(org.ID)}, orgRoles...),
+		})
+		require.NoError(t, err)
+		return user.ID
+	}
+
+	access := func(userID uuid.UUID) database.WorkspaceAccessLevel {
+		got, err := db.GetWorkspaceWithUserAccess(ctx, workspace.ID, userID)
+		require.NoError(t, err)
+		require.Equal(t, workspace, got.Workspace)
+		return got.Access
+	}
+
+	// The owner has no access until they join the workspace's org.
+	require.Equal(t, database.WorkspaceAccessNone, access(owner.ID))
+	_, err = db.InsertOrganizationMember(ctx, database.InsertOrganizationMemberParams{
+		OrganizationID: org.ID,
+		UserID:         owner.ID,
+		CreatedAt:      database.Now(),
+		UpdatedAt:      database.Now(),
+		Roles:          []string{rbac.RoleOrgMember(org.ID)},
+	})
+	require.NoError(t, err)
+	require.Equal(t, database.WorkspaceAccessFull, access(owner.ID))
+
+	require.Equal(t, database.WorkspaceAccessFull, access(insertUser("siteowner", []string{rbac.RoleOwner()})))
+	require.Equal(t, database.WorkspaceAccessFull, access(insertUser("orgadmin", []string{}, rbac.RoleOrgAdmin(org.ID))))
+	require.Equal(t, database.WorkspaceAccessRead, access(insertUser("templateadmin", []string{rbac.RoleTemplateAdmin()})))
+	require.Equal(t, database.WorkspaceAccessNone, access(insertUser("useradmin", []string{rbac.RoleUserAdmin()})))
+	require.Equal(t, database.WorkspaceAccessNone, access(insertUser("member", []string{})))
+
+	_, err = db.GetWorkspaceWithUserAccess(ctx, workspace.ID, uuid.New())
+	require.ErrorIs(t, err, sql.ErrNoRows)
+	_, err = db.GetWorkspaceWithUserAccess(ctx, uuid.New(), owner.ID)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
@@ -0,0 +1,98 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/google/uuid"
+	"golang.org/x/xerrors"
+)
+
+// aggregateQuerier assembles nested structures in a single round-trip by
+// building them as JSON in Postgres. This trades Go-side stitching of
+// several queries for a heavier query: the planner evaluates a correlated
+// subquery per parent row, and the whole result is materialized as one
+// JSONB value, so these are best suited to fetching a single parent.
+type aggregateQuerier interface {
+	// GetWorkspaceWithBuildsJSON returns a workspace with all of its builds,
+	// ordered by build number, each with the resources of its job. The
+	// provisioner state of builds is not included.
+	GetWorkspaceWithBuildsJSON(ctx context.Context, id uuid.UUID) (WorkspaceWithBuilds, error)
+}
+
+type WorkspaceWithBuilds struct {
+	Workspace
+	Builds []WorkspaceBuildWithResources `json:"builds"`
+}
+
+type WorkspaceBuildWithResources struct {
+	WorkspaceBuild
+	Resources []WorkspaceResource `json:"resources"`
+}
+
+func (q *sqlQuerier) GetWorkspaceWithBuildsJSON(ctx context.Context, id uuid.UUID) (WorkspaceWithBuilds, error) {
+	// Nullable columns are built in the shape encoding/json expects for
+	// sql.Null* types, and last_used_at is converted to a timestamp with a
+	// time zone so it is encoded with an offset.
+	const query = `-- name: GetWorkspaceWithBuildsJSON :one
+	SELECT
+		jsonb_build_object(
+			'id', w.id,
+			'created_at', w.created_at,
+			'updated_at', w.updated_at,
+			'owner_id', w.owner_id,
+			'organization_id', w.organization_id,
+			'template_id', w.template_id,
+			'deleted', w.deleted,
+			'name', w.name,
+			'autostart_schedule', jsonb_build_object('String', COALESCE(w.autostart_schedule, ''), 'Valid', w.autostart_schedule IS NOT NULL),
+			'ttl', jsonb_build_object('Int64', COALESCE(w.ttl, 0), 'Valid', w.ttl IS NOT NULL),
+			'last_used_at', w.last_used_at AT TIME ZONE 'UTC',
+			'dormant_at', jsonb_build_object('Time', w.dormant_at, 'Valid', w.dormant_at IS NOT NULL),
+			'builds', COALESCE((
+				SELECT
+					jsonb_agg(jsonb_build_object(
+						'id', b.id,
+						'created_at', b.created_at,
+						'updated_at', b.updated_at,
+						'workspace_id', b.workspace_id,
+						'template_version_id', b.template_version_id,
+						'build_number', b.build_number,
+						'transition', b.transition,
+						'initiator_id', b.initiator_id,
+						'job_id', b.job_id,
+						'deadline', b.deadline,
+						'reason', b.reason,
+						'resources', COALESCE((
+							SELECT
+								jsonb_agg(to_jsonb(r) ORDER BY r.created_at, r.id)
+							FROM
+								workspace_resources r
+							WHERE
+								r.job_id = b.job_id
+						), '[]'::jsonb)
+					) ORDER BY b.build_number)
+				FROM
+					workspace_builds b
+				WHERE
+					b.workspace_id = w.id
+			), '[]'::jsonb)
+		)
+	FROM
+		workspaces w
+	WHERE
+		w.id = $1
+	`
+
+	var raw []byte
+	err := q.db.GetContext(ctx, &raw, query, id)
+	if err != nil {
+		return WorkspaceWithBuilds{}, xerrors.Errorf("select workspace with builds: %w", err)
+	}
+	var workspace WorkspaceWithBuilds
+	err = json.Unmarshal(raw, &workspace)
+	if err != nil {
+		return WorkspaceWithBuilds{}, xerrors.Errorf("unmarshal workspace with builds: %w", err)
+	}
+	return workspace, nil
+}
@@ -0,0 +1,140 @@
+//go:build linux
+
+package database_test
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/coder/coder/coderd/database"
+	"github.com/coder/coder/coderd/database/migrations"
+)
+
+func TestGetWorkspaceWithBuildsJSON(t *testing.T) {
+	t.Parallel()
+	if testing.Short() {
+		t.SkipNow()
+	}
+
+	sqlDB := testSQLDB(t)
+	err := migrations.Up(sqlDB)
+	require.NoError(t, err, "migrations")
+	db := database.New(sqlDB)
+	ctx := context.Background()
+
+	user, org, template := insertTemplate(t, db)
+	version, err := db.InsertTemplateVersion(ctx, database.InsertTemplateVersionParams{
+		ID:             uuid.New(),
+		TemplateID:     uuid.NullUUID{UUID: template.ID, Valid: true},
+		OrganizationID: org.ID,
+		CreatedAt:      database.Now(),
+		UpdatedAt:      database.Now(),
+		Name:           "version",
+		JobID:          uuid.New(),
+	})
+	require.NoError(t, err)
+	workspace, err := db.InsertWorkspace(ctx, database.InsertWorkspaceParams{
+		ID:                uuid.New(),
+		CreatedAt:         database.Now(),
+		UpdatedAt:         database.Now(),
+		OwnerID:           user.ID,
+		OrganizationID:    org.ID,
+		TemplateID:        template.ID,
+		Name:              "workspace",
+		AutostartSchedule: sql.NullString{String: "CRON_TZ=UTC 0 9 * * 1-5", Valid: true},
+	})
+	require.NoError(t, err)
+	for number := int32(1); number <= 2; number++ {
+		job, err := db.InsertProvisionerJob(ctx, database.InsertProvisionerJobParams{
+			ID:             uuid.New(),
+			CreatedAt:      database.Now(),
+			UpdatedAt:      database.Now(),
+			OrganizationID: org.ID,
+			InitiatorID:    user.ID,
+			Provisioner:    database.ProvisionerTypeEcho,
+			StorageMethod:  database.ProvisionerStorageMethodFile,
+			FileID:         uuid.New(),
+			Type:           database.ProvisionerJobTypeWorkspaceBuild,
+			Input:          json.RawMessage("{}"),
+		})
+		require.NoError(t, err)
+		_, err = db.InsertWorkspaceBuild(ctx, database.InsertWorkspaceBuildParams{
+			ID:                uuid.New(),
+			CreatedAt:         database.Now(),
+			UpdatedAt:         database.Now(),
+			WorkspaceID:       workspace.ID,
+			TemplateVersionID: version.ID,
+			BuildNumber:       number,
+			Transition:        database.WorkspaceTransitionStart,
+			InitiatorID:       user.ID,
+			JobID:             job.ID,
+			Reason:            database.BuildReasonInitiator,
+		})
+		require.NoError(t, err)
+		_, err = db.InsertWorkspaceResource(ctx, database.InsertWorkspaceResourceParams{
+			ID:         uuid.New(),
+			CreatedAt:  database.Now(),
+			JobID:      job.ID,
+			Transition: database.WorkspaceTransitionStart,
+			Type:       "docker_container",
+			Name:       "dev",
+		})
+		require.NoError(t, err)
+	}
+
+	got, err := db.GetWorkspaceWithBuildsJSON(ctx, workspace.ID)
+	require.NoError(t, err)
+
+	// Assemble the same structure with one query per level.
+	want := database.WorkspaceWithBuilds{}
+	want.Workspace, err = db.GetWorkspaceByID(ctx, workspace.ID)
+	require.NoError(t, err)
+	builds, err := db.GetWorkspaceBuildsByWorkspaceID(ctx, database.GetWorkspaceBuildsByWorkspaceIDParams{
+		WorkspaceID: workspace.ID,
+	})
+	require.NoError(t, err)
+	for _, build := range builds {
+		resources, err := db.GetWorkspaceResourcesByJobID(ctx, build.JobID)
+		require.NoError(t, err)
+		build.ProvisionerState = nil
+		want.Builds = append(want.Builds, database.WorkspaceBuildWithResources{
+			WorkspaceBuild: build,
+			Resources:      resources,
+		})
+	}
+
+	// Compare as JSON with timestamps in UTC, since equal instants can be
+	// decoded with different locations.
+	wantJSON, err := json.Marshal(normalizeTimes(t, want))
+	require.NoError(t, err)
+	gotJSON, err := json.Marshal(normalizeTimes(t, got))
+	require.NoError(t, err)
+	require.JSONEq(t, string(wantJSON), string(gotJSON))
+
+	_, err = db.GetWorkspaceWithBuildsJSON(ctx, uuid.New())
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+// normalizeTimes converts every timestamp in v to UTC.
+func normalizeTimes(t *testing.T, v database.WorkspaceWithBuilds) database.WorkspaceWithBuilds {
+	t.Helper()
+
+	v.CreatedAt = v.CreatedAt.UTC()
+	v.UpdatedAt = v.UpdatedAt.UTC()
+	v.LastUsedAt = v.LastUsedAt.UTC()
+	for i := range v.Builds {
+		build := &v.Builds[i]
+		build.CreatedAt = build.CreatedAt.UTC()
+		build.UpdatedAt = build.UpdatedAt.UTC()
+		build.Deadline = build.Deadline.UTC()
+		for j := range build.Resources {
+			build.Resources[j].CreatedAt = build.Resources[j].CreatedAt.UTC()
+		}
+	}
+	return v
+}
@@ -0,0 +1,53 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"golang.org/x/xerrors"
+)
+
+type archiveQuerier interface {
+	// ArchiveAuditLogs moves audit logs older than olderThan from
+	// audit_logs to audit_logs_archive and returns how many were moved.
+	ArchiveAuditLogs(ctx context.Context, olderThan time.Time) (int64, error)
+}
+
+func (q *sqlQuerier) ArchiveAuditLogs(ctx context.Context, olderThan time.Time) (int64, error) {
+	// The delete and insert are one statement, so no reader sees a row
+	// missing from both tables and a failed insert keeps the rows live.
+	// Columns are listed rather than using * so that a column added to
+	// audit_logs without the archive fails loudly instead of shifting
+	// values into the wrong columns.
+	const query = `-- name: ArchiveAuditLogs :execrows
+	WITH moved AS (
+		DELETE FROM
+			audit_logs
+		WHERE
+			"time" < $1
+		RETURNING
+			id, "time", user_id, organization_id, ip, user_agent, resource_type, resource_id,
+			resource_target, action, diff, status_code, additional_fields, request_id, resource_icon
+	)
+	INSERT INTO
+		audit_logs_archive (
+			id, "time", user_id, organization_id, ip, user_agent, resource_type, resource_id,
+			resource_target, action, diff, status_code, additional_fields, request_id, resource_icon
+		)
+	SELECT
+		id, "time", user_id, organization_id, ip, user_agent, resource_type, resource_id,
+		resource_target, action, diff, status_code, additional_fields, request_id, resource_icon
+	FROM
+		moved
+	`
+
+	result, err := q.db.ExecContext(ctx, query, olderThan)
+	if err != nil {
+		return 0, xerrors.Errorf("archive audit logs: %w", err)
+	}
+	moved, err := result.RowsAffected()
+	if err != nil {
+		return 0, xerrors.Errorf("rows affected: %w", err)
+	}
+	return moved, nil
+}
@@ -0,0 +1,60 @@
+package database_test
+
+import (
+	"context"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"github.com/tabbed/pqtype"
+
+	"github.com/coder/coder/coderd/database"
+	"github.com/coder/coder/coderd/database/dbtestutil"
+)
+
+func TestArchiveAuditLogs(t *testing.T) {
+	t.Parallel()
+
+	db, _ := dbtestutil.NewDB(t)
+	ctx := context.Background()
+	now := database.Now()
+
+	for _, age := range []time.Duration{72 * time.Hour, 48 * time.Hour, time.Hour} {
+		_, err := db.InsertAuditLog(ctx, database.InsertAuditLogParams{
+			ID:               uuid.New(),
+			Time:             now.Add(-age),
+			UserID:           uuid.New(),
+			OrganizationID:   uuid.New(),
+			Ip:               pqtype.Inet{IPNet: net.IPNet{IP: net.IPv4(127, 0, 0, 1), Mask: net.CIDRMask(32, 32)}, Valid: true},
+			ResourceType:     database.ResourceTypeWorkspace,
+			ResourceID:       uuid.New(),
+			Action:           database.AuditActionCreate,
+			Diff:             []byte("{}"),
+			AdditionalFields: []byte("{}"),
+			RequestID:        uuid.New(),
+		})
+		require.NoError(t, err)
+	}
+
+	moved, err := db.ArchiveAuditLogs(ctx, now.Add(-24*time.Hour))
+	require.NoError(t, err)
+	require.EqualValues(t, 2, moved)
+
+	moved, err = db.ArchiveAuditLogs(ctx, now.Add(-24*time.Hour))
+	require.NoError(t, err)
+	require.Zero(t, moved, "archived logs are no longer live")
+
+	moved, err = db.ArchiveAuditLogs(ctx, now)
+	require.NoError(t, err)
+	require.EqualValues(t, 1, moved)
+
+	if os.Getenv("DB") != "" {
+		var archived int
+		err = db.Unwrap().QueryRow("SELECT count(*) FROM audit_logs_archive").Scan(&archived)
+		require.NoError(t, err)
+		require.Equal(t, 3, archived)
+	}
+}
@@ -0,0 +1,44 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// ArgsError annotates an error from a Store call with a summary of the
+// arguments that caused it, with registered fields redacted.
+type ArgsError struct {
+	Method string
+	Args   string
+	Err    error
+}
+
+func (e *ArgsError) Error() string {
+	return fmt.Sprintf("%s(%s): %s", e.Method, e.Args, e.Err)
+}
+
+func (e *ArgsError) Unwrap() error {
+	return e.Err
+}
+
+// AnnotateErrorArgs is an Interceptor that wraps errors from query methods
+// in an *ArgsError, so a constraint violation also reports the input that
+// caused it. sql.ErrNoRows is returned unchanged since it is expected.
+// Fields registered with RedactField are never included.
+func AnnotateErrorArgs(ctx context.Context, call Call, next Invoker) ([]interface{}, error) {
+	res, err := next(ctx)
+	if err == nil || call.Method == "InTx" || errors.Is(err, sql.ErrNoRows) {
+		return res, err
+	}
+	var argsErr *ArgsError
+	if errors.As(err, &argsErr) {
+		return res, err
+	}
+	return res, &ArgsError{
+		Method: call.Method,
+		Args:   summarizeArgs(call.Args),
+		Err:    err,
+	}
+}
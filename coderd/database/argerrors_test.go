@@ -0,0 +1,50 @@
+package database_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+
+	"github.com/coder/coder/coderd/database"
+	"github.com/coder/coder/coderd/database/databasefake"
+)
+
+func TestAnnotateErrorArgs(t *testing.T) {
+	t.Parallel()
+
+	// Simulate the error Postgres returns for a duplicate username.
+	duplicate := func(ctx context.Context, call database.Call, next database.Invoker) ([]interface{}, error) {
+		if call.Method == "InsertUser" {
+			return []interface{}{database.User{}}, &pq.Error{
+				Code:       "23505",
+				Message:    `duplicate key value violates unique constraint "users_username_lower_idx"`,
+				Constraint: "users_username_lower_idx",
+			}
+		}
+		return next(ctx)
+	}
+	db := database.Intercept(databasefake.New(), database.AnnotateErrorArgs, duplicate)
+
+	_, err := db.InsertUser(context.Background(), database.InsertUserParams{
+		ID:             uuid.New(),
+		Username:       "coder",
+		HashedPassword: []byte("secret"),
+	})
+	require.Error(t, err)
+	var argsErr *database.ArgsError
+	require.True(t, errors.As(err, &argsErr), "expected ArgsError, got %v", err)
+	require.Equal(t, "InsertUser", argsErr.Method)
+	require.Contains(t, err.Error(), `Username="coder"`)
+	require.Contains(t, err.Error(), `HashedPassword=<redacted>`)
+	require.NotContains(t, err.Error(), "secret")
+	require.True(t, database.IsUniqueViolation(err, database.UniqueUsersUsernameLowerIndex))
+
+	_, err = db.GetUserByID(context.Background(), uuid.New())
+	require.ErrorIs(t, err, sql.ErrNoRows)
+	require.False(t, errors.As(err, &argsErr), "no rows is not annotated")
+}
@@ -0,0 +1,201 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"golang.org/x/xerrors"
+)
+
+// BatchError identifies the row that made a batch insert fail.
+type BatchError struct {
+	FailedIndex int
+	Err         error
+}
+
+func (e BatchError) Error() string {
+	return fmt.Sprintf("batch row %d: %s", e.FailedIndex, e.Err)
+}
+
+func (e BatchError) Unwrap() error {
+	return e.Err
+}
+
+// errProbeDone rolls back the rows inserted while locating a failure.
+var errProbeDone = xerrors.New("batch probe done")
+
+type batchQuerier interface {
+	// InsertProvisionerJobLogsPrecise is InsertProvisionerJobLogs, except
+	// that a failed insert returns a BatchError naming the offending log.
+	// See InsertBatchPrecise for the cost.
+	InsertProvisionerJobLogsPrecise(ctx context.Context, arg InsertProvisionerJobLogsParams) ([]ProvisionerJobLog, error)
+	// UpdateWorkspaceTTLs sets the TTL of each workspace in ids to the
+	// value at the same index of ttls, in one statement. The slices must
+	// be the same length and ids must not repeat. Workspaces that do not
+	// exist are skipped.
+	UpdateWorkspaceTTLs(ctx context.Context, ids []uuid.UUID, ttls []sql.NullInt64) error
+}
+
+// InsertBatchPrecise runs insert for the n rows of a batch in a savepoint.
+// insert must insert rows [start, end). If the batch fails, the rows are
+// retried one at a time, each in its own savepoint and keeping the rows
+// before it, so a conflict between two rows of the batch is attributed to
+// the later one. The retries are then rolled back and a BatchError for the
+// first failing row is returned. If no single row fails, the original error
+// is returned.
+//
+// Locating the row costs up to n extra statements and savepoints, so use it
+// where callers need to report which row was bad, such as imports of
+// user-supplied data, rather than as the default.
+func InsertBatchPrecise(ctx context.Context, store Store, n int, insert func(tx Store, start, end int) error) error {
+	var batchErr error
+	err := store.InSavepoint(ctx, func(tx Store) error {
+		batchErr = insert(tx, 0, n)
+		return batchErr
+	})
+	if err == nil {
+		return nil
+	}
+	if batchErr == nil {
+		// The savepoint itself failed, so there is nothing to retry.
+		return err
+	}
+
+	var located *BatchError
+	err = store.InSavepoint(ctx, func(probe Store) error {
+		for i := 0; i < n; i++ {
+			var rowErr error
+			_ = probe.InSavepoint(ctx, func(row Store) error {
+				rowErr = insert(row, i, i+1)
+				return rowErr
+			})
+			if rowErr != nil {
+				located = &BatchError{FailedIndex: i, Err: rowErr}
+				break
+			}
+		}
+		return errProbeDone
+	})
+	if located != nil {
+		return *located
+	}
+	if err != nil && !xerrors.Is(err, errProbeDone) {
+		return xerrors.Errorf("locate failed row: %w", err)
+	}
+	return batchErr
+}
+
+// ItemError is the failure of one item of ForEachWithSavepoint.
+type ItemError struct {
+	Index int
+	Err   error
+}
+
+func (e ItemError) Error() string {
+	return fmt.Sprintf("item %d: %s", e.Index, e.Err)
+}
+
+func (e ItemError) Unwrap() error {
+	return e.Err
+}
+
+// ForEachWithSavepoint runs fn for each item in one transaction, or in a
+// savepoint if store is already in one, and each call in a savepoint of its
+// own. An item that fails is rolled back on its own and reported in
+// failures, in order, while the others are kept. err is only set if the
+// transaction itself failed, in which case nothing is kept.
+//
+// fn must do all of its work through the Store it is given.
+func ForEachWithSavepoint[T any](ctx context.Context, store Store, items []T, fn func(tx Store, item T) error) (failures []ItemError, err error) {
+	err = store.InSavepoint(ctx, func(tx Store) error {
+		failures = nil
+		for i, item := range items {
+			var itemErr error
+			err := tx.InSavepoint(ctx, func(itemTx Store) error {
+				itemErr = fn(itemTx, item)
+				return itemErr
+			})
+			if itemErr != nil {
+				failures = append(failures, ItemError{Index: i, Err: itemErr})
+				continue
+			}
+			if err != nil {
+				return xerrors.Errorf("item %d: %w", i, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return failures, nil
+}
+
+func (q *sqlQuerier) InsertProvisionerJobLogsPrecise(ctx context.Context, arg InsertProvisionerJobLogsParams) ([]ProvisionerJobLog, error) {
+	var logs []ProvisionerJobLog
+	err := InsertBatchPrecise(ctx, q, len(arg.ID), func(tx Store, start, end int) error {
+		var err error
+		logs, err = tx.InsertProvisionerJobLogs(ctx, InsertProvisionerJobLogsParams{
+			ID:        arg.ID[start:end],
+			JobID:     arg.JobID,
+			CreatedAt: arg.CreatedAt[start:end],
+			Source:    arg.Source[start:end],
+			Level:     arg.Level[start:end],
+			Stage:     arg.Stage[start:end],
+			Output:    arg.Output[start:end],
+		})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return logs, nil
+}
+
+// validateBatchUpdate checks that ids has one value per ID and no ID twice,
+// since which of two values an UPDATE ... FROM applies is unspecified.
+func validateBatchUpdate(ids []uuid.UUID, values int) error {
+	if len(ids) != values {
+		return xerrors.Errorf("got %d ids but %d values", len(ids), values)
+	}
+	seen := make(map[uuid.UUID]struct{}, len(ids))
+	for _, id := range ids {
+		if _, ok := seen[id]; ok {
+			return xerrors.Errorf("id %s appears more than once", id)
+		}
+		seen[id] = struct{}{}
+	}
+	return nil
+}
+
+func (q *sqlQuerier) UpdateWorkspaceTTLs(ctx context.Context, ids []uuid.UUID, ttls []sql.NullInt64) error {
+	err := validateBatchUpdate(ids, len(ttls))
+	if err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	// unnest zips the arrays into rows, so every workspace is joined to its
+	// own value. Further per-row updates can follow the same shape with one
+	// array per column.
+	const query = `-- name: UpdateWorkspaceTTLs :exec
+	UPDATE
+		workspaces
+	SET
+		ttl = t.ttl
+	FROM
+		unnest($1 :: uuid[], $2 :: bigint[]) AS t(id, ttl)
+	WHERE
+		workspaces.id = t.id
+	`
+
+	_, err = q.db.ExecContext(ctx, query, pq.Array(ids), pq.Array(ttls))
+	if err != nil {
+		return xerrors.Errorf("update workspace ttls: %w", err)
+	}
+	return nil
+}
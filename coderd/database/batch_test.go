@@ -0,0 +1,162 @@
+package database_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/xerrors"
+
+	"github.com/coder/coder/coderd/database"
+	"github.com/coder/coder/coderd/database/databasefake"
+)
+
+func TestInsertProvisionerJobLogsPrecise(t *testing.T) {
+	t.Parallel()
+
+	arg := database.InsertProvisionerJobLogsParams{JobID: uuid.New()}
+	for i := 0; i < 3; i++ {
+		arg.ID = append(arg.ID, uuid.New())
+		arg.CreatedAt = append(arg.CreatedAt, time.Now())
+		arg.Source = append(arg.Source, database.LogSourceProvisioner)
+		arg.Level = append(arg.Level, database.LogLevelInfo)
+		arg.Stage = append(arg.Stage, "stage")
+		arg.Output = append(arg.Output, "output")
+	}
+	// failOn fails the given calls of InsertProvisionerJobLogs: the batch is
+	// the first call and each row is retried after it.
+	newDB := func(t *testing.T, failOn ...int) (database.Store, func() []string) {
+		sqlDB, connector := newRecordingDB()
+		t.Cleanup(func() { _ = sqlDB.Close() })
+		var (
+			mu    sync.Mutex
+			calls int
+		)
+		connector.hook = func(_ context.Context, query string) error {
+			if !strings.Contains(query, "InsertProvisionerJobLogs") {
+				return nil
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			calls++
+			for _, n := range failOn {
+				if calls == n {
+					return &pq.Error{Code: "23505", Message: "duplicate key"}
+				}
+			}
+			return nil
+		}
+		return database.New(sqlDB), connector.Queries
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		t.Parallel()
+		db, queries := newDB(t)
+		_, err := db.InsertProvisionerJobLogsPrecise(context.Background(), arg)
+		require.NoError(t, err)
+		require.Len(t, queries(), 1, "the batch is inserted once")
+	})
+
+	t.Run("LocatesRow", func(t *testing.T) {
+		t.Parallel()
+		db, queries := newDB(t, 1, 4)
+		_, err := db.InsertProvisionerJobLogsPrecise(context.Background(), arg)
+		var batchErr database.BatchError
+		require.ErrorAs(t, err, &batchErr)
+		require.Equal(t, 2, batchErr.FailedIndex)
+		require.True(t, database.IsUniqueViolation(err))
+		// The probe runs in a transaction of its own with a savepoint per row,
+		// all rolled back.
+		require.Contains(t, queries(), "ROLLBACK TO SAVEPOINT savepoint_2")
+	})
+
+	t.Run("NoSingleRowFails", func(t *testing.T) {
+		t.Parallel()
+		db, _ := newDB(t, 1)
+		_, err := db.InsertProvisionerJobLogsPrecise(context.Background(), arg)
+		require.True(t, database.IsUniqueViolation(err), "the batch error is returned")
+		require.False(t, errors.As(err, &database.BatchError{}))
+	})
+}
+
+func TestForEachWithSavepoint(t *testing.T) {
+	t.Parallel()
+
+	items := []string{"a", "bad", "c", "bad"}
+	errBad := xerrors.New("bad item")
+	fn := func(tx database.Store, item string) error {
+		if item == "bad" {
+			return errBad
+		}
+		_, err := tx.GetLicenses(context.Background())
+		return err
+	}
+
+	t.Run("PartialSuccess", func(t *testing.T) {
+		t.Parallel()
+		sqlDB, connector := newRecordingDB()
+		t.Cleanup(func() { _ = sqlDB.Close() })
+
+		failures, err := database.ForEachWithSavepoint(context.Background(), database.New(sqlDB), items, fn)
+		require.NoError(t, err)
+		require.Len(t, failures, 2)
+		require.Equal(t, 1, failures[0].Index)
+		require.Equal(t, 3, failures[1].Index)
+		require.ErrorIs(t, failures[0], errBad)
+
+		var rolledBack, released int
+		for _, query := range connector.Queries() {
+			switch {
+			case strings.HasPrefix(query, "ROLLBACK TO SAVEPOINT"):
+				rolledBack++
+			case strings.HasPrefix(query, "RELEASE SAVEPOINT"):
+				released++
+			}
+		}
+		require.Equal(t, 2, rolledBack, "only the failed items are rolled back")
+		require.Equal(t, 2, released)
+	})
+
+	t.Run("TransactionFails", func(t *testing.T) {
+		t.Parallel()
+		sqlDB, connector := newRecordingDB()
+		t.Cleanup(func() { _ = sqlDB.Close() })
+		var savepoints atomic.Int32
+		connector.hook = func(_ context.Context, query string) error {
+			if strings.HasPrefix(query, "SAVEPOINT") && savepoints.Add(1) == 3 {
+				return &pq.Error{Code: "25P02", Message: "current transaction is aborted"}
+			}
+			return nil
+		}
+
+		failures, err := database.ForEachWithSavepoint(context.Background(), database.New(sqlDB), items, fn)
+		require.ErrorContains(t, err, "item 2")
+		require.Nil(t, failures)
+	})
+
+	t.Run("Fake", func(t *testing.T) {
+		t.Parallel()
+		db := databasefake.New()
+		failures, err := database.ForEachWithSavepoint(context.Background(), db, []string{"a", "bad"},
+			func(tx database.Store, item string) error {
+				if item == "bad" {
+					return errBad
+				}
+				_, err := tx.InsertLicense(context.Background(), database.InsertLicenseParams{JWT: item})
+				return err
+			})
+		require.NoError(t, err)
+		require.Len(t, failures, 1)
+		require.Equal(t, 1, failures[0].Index)
+		licenses, err := db.GetLicenses(context.Background())
+		require.NoError(t, err)
+		require.Len(t, licenses, 1)
+	})
+}
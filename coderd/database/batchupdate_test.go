@@ -0,0 +1,56 @@
+//go:build linux
+
+package database_test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/coder/coder/coderd/database"
+	"github.com/coder/coder/coderd/database/dbtestutil"
+)
+
+func TestUpdateWorkspaceTTLs(t *testing.T) {
+	t.Parallel()
+
+	db, _ := dbtestutil.NewDB(t)
+	ctx := context.Background()
+
+	user, org, template := insertTemplate(t, db)
+	ids := make([]uuid.UUID, 3)
+	for i := range ids {
+		workspace, err := db.InsertWorkspace(ctx, database.InsertWorkspaceParams{
+			ID:             uuid.New(),
+			CreatedAt:      database.Now(),
+			UpdatedAt:      database.Now(),
+			OwnerID:        user.ID,
+			OrganizationID: org.ID,
+			TemplateID:     template.ID,
+			Name:           fmt.Sprintf("workspace%d", i),
+			Ttl:            sql.NullInt64{Int64: 60, Valid: true},
+		})
+		require.NoError(t, err)
+		ids[i] = workspace.ID
+	}
+
+	// The last workspace is left out and keeps its TTL.
+	ttls := []sql.NullInt64{{Int64: 3600, Valid: true}, {}}
+	err := db.UpdateWorkspaceTTLs(ctx, ids[:2], ttls)
+	require.NoError(t, err)
+	for i, want := range append(ttls, sql.NullInt64{Int64: 60, Valid: true}) {
+		workspace, err := db.GetWorkspaceByID(ctx, ids[i])
+		require.NoError(t, err)
+		require.Equal(t, want, workspace.Ttl, "workspace %d", i)
+	}
+
+	err = db.UpdateWorkspaceTTLs(ctx, ids, ttls)
+	require.ErrorContains(t, err, "got 3 ids but 2 values")
+	err = db.UpdateWorkspaceTTLs(ctx, []uuid.UUID{ids[0], ids[0]}, ttls)
+	require.ErrorContains(t, err, "appears more than once")
+	require.NoError(t, db.UpdateWorkspaceTTLs(ctx, nil, nil))
+}
@@ -0,0 +1,115 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+
+	"golang.org/x/xerrors"
+)
+
+// ErrCircuitOpen is returned by Stores created with NewWithCircuitBreakers
+// for calls to a method whose breaker is open.
+var ErrCircuitOpen = xerrors.New("database circuit breaker open")
+
+// CircuitBreaker trips for Methods after Failures consecutive failed calls
+// to any of them, and fails their calls with ErrCircuitOpen for Cooldown.
+// Once Cooldown has passed, a single trial call is let through: the
+// breaker closes if it succeeds and stays open for another Cooldown if it
+// fails.
+type CircuitBreaker struct {
+	Methods  []string
+	Failures int
+	Cooldown time.Duration
+}
+
+type breakerState struct {
+	name   string
+	config CircuitBreaker
+
+	mu       sync.Mutex
+	failures int
+	// openUntil is when an open breaker lets a trial call through, or zero
+	// while it is closed.
+	openUntil time.Time
+	trial     bool
+}
+
+// NewWithCircuitBreakers returns a Store that stops calling methods that
+// keep failing, such as a report query that times out under load, so they
+// fail fast instead of tying up connections, while other methods keep
+// working. breakers are keyed by group name, which is reported in
+// ErrCircuitOpen errors; a method must be in at most one group, or
+// NewWithCircuitBreakers panics, and a group of one method is a per-method
+// breaker. Methods in no group are never
+// broken. sql.ErrNoRows and context.Canceled do not count as failures, and
+// neither do transactions, whose failures belong to their callback, though
+// the calls made inside them count.
+func NewWithCircuitBreakers(store Store, breakers map[string]CircuitBreaker) Store {
+	methods := map[string]*breakerState{}
+	for name, config := range breakers {
+		state := &breakerState{name: name, config: config}
+		for _, method := range config.Methods {
+			if other, ok := methods[method]; ok && other != state {
+				panic(fmt.Sprintf("developer error: method %s is in circuit breaker groups %s and %s", method, other.name, name))
+			}
+			methods[method] = state
+		}
+	}
+	return Intercept(store, func(ctx context.Context, call Call, next Invoker) ([]interface{}, error) {
+		state, ok := methods[call.Method]
+		if !ok {
+			return next(ctx)
+		}
+		err := state.allow()
+		if err != nil {
+			return nil, xerrors.Errorf("%s: %w", call.Method, err)
+		}
+		results, err := next(ctx)
+		state.record(err)
+		return results, err
+	})
+}
+
+// allow reports whether a call may run, claiming the trial call of a
+// breaker whose cooldown has passed.
+func (b *breakerState) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.openUntil.IsZero() {
+		return nil
+	}
+	if b.trial || time.Now().Before(b.openUntil) {
+		return xerrors.Errorf("%w: %s", ErrCircuitOpen, b.name)
+	}
+	b.trial = true
+	return nil
+}
+
+func (b *breakerState) record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if xerrors.Is(err, context.Canceled) {
+		// The caller gave up, which says nothing about the method, so a
+		// canceled trial lets the next call try instead.
+		b.trial = false
+		return
+	}
+	if err == nil || xerrors.Is(err, sql.ErrNoRows) {
+		if b.trial || b.openUntil.IsZero() {
+			b.failures = 0
+			b.openUntil = time.Time{}
+			b.trial = false
+		}
+		return
+	}
+	b.failures++
+	if b.trial || b.failures >= b.config.Failures {
+		b.openUntil = time.Now().Add(b.config.Cooldown)
+		b.trial = false
+	}
+}
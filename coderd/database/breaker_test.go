@@ -0,0 +1,91 @@
+package database_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/xerrors"
+
+	"github.com/coder/coder/coderd/database"
+	"github.com/coder/coder/coderd/database/databasefake"
+)
+
+func TestNewWithCircuitBreakers(t *testing.T) {
+	t.Parallel()
+
+	// GetAuditLogsOffset fails while failing is set, like a report query
+	// timing out under load.
+	var failing atomic.Bool
+	failing.Store(true)
+	var calls atomic.Int32
+	store := database.Intercept(databasefake.New(), func(ctx context.Context, call database.Call, next database.Invoker) ([]interface{}, error) {
+		if call.Method != "GetAuditLogsOffset" {
+			return next(ctx)
+		}
+		calls.Add(1)
+		if failing.Load() {
+			return nil, xerrors.New("canceling statement due to statement timeout")
+		}
+		return next(ctx)
+	})
+	db := database.NewWithCircuitBreakers(store, map[string]database.CircuitBreaker{
+		"reports": {
+			Methods:  []string{"GetAuditLogsOffset", "GetAuditLogCount"},
+			Failures: 3,
+			Cooldown: 50 * time.Millisecond,
+		},
+		"users": {
+			Methods:  []string{"GetUsers"},
+			Failures: 1,
+			Cooldown: time.Hour,
+		},
+	})
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		_, err := db.GetAuditLogsOffset(ctx, database.GetAuditLogsOffsetParams{})
+		require.Error(t, err)
+		require.NotErrorIs(t, err, database.ErrCircuitOpen, "below the threshold")
+	}
+	_, err := db.GetAuditLogsOffset(ctx, database.GetAuditLogsOffsetParams{})
+	require.ErrorIs(t, err, database.ErrCircuitOpen)
+	require.EqualValues(t, 3, calls.Load(), "open breakers fail fast")
+	_, err = db.GetAuditLogCount(ctx, database.GetAuditLogCountParams{})
+	require.ErrorIs(t, err, database.ErrCircuitOpen, "the group shares a breaker")
+
+	// Other groups and ungrouped methods keep working.
+	_, err = db.GetUsers(ctx, database.GetUsersParams{})
+	require.NoError(t, err)
+	_, err = db.GetTemplates(ctx)
+	require.NoError(t, err)
+
+	// A failed trial reopens the breaker for another cooldown.
+	time.Sleep(60 * time.Millisecond)
+	_, err = db.GetAuditLogsOffset(ctx, database.GetAuditLogsOffsetParams{})
+	require.Error(t, err)
+	require.NotErrorIs(t, err, database.ErrCircuitOpen, "trial call")
+	_, err = db.GetAuditLogsOffset(ctx, database.GetAuditLogsOffsetParams{})
+	require.ErrorIs(t, err, database.ErrCircuitOpen)
+
+	// A successful trial closes it.
+	failing.Store(false)
+	time.Sleep(60 * time.Millisecond)
+	_, err = db.GetAuditLogsOffset(ctx, database.GetAuditLogsOffsetParams{})
+	require.NoError(t, err)
+	_, err = db.GetAuditLogCount(ctx, database.GetAuditLogCountParams{})
+	require.NoError(t, err)
+}
+
+func TestNewWithCircuitBreakersDuplicateMethod(t *testing.T) {
+	t.Parallel()
+
+	require.Panics(t, func() {
+		database.NewWithCircuitBreakers(databasefake.New(), map[string]database.CircuitBreaker{
+			"reports": {Methods: []string{"GetAuditLogsOffset"}, Failures: 3, Cooldown: time.Second},
+			"audit":   {Methods: []string{"GetAuditLogCount", "GetAuditLogsOffset"}, Failures: 3, Cooldown: time.Second},
+		})
+	}, "a method in two groups would be governed by whichever was added last")
+}
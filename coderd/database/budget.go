@@ -0,0 +1,80 @@
+package database
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"golang.org/x/xerrors"
+)
+
+// ErrBudgetExceeded is returned by Stores created with NewWithQueryBudget
+// once the query budget of the context has been used up.
+var ErrBudgetExceeded = xerrors.New("query budget exceeded")
+
+type queryBudgetKey struct{}
+
+type queryBudget struct {
+	mu        sync.Mutex
+	remaining time.Duration
+}
+
+// WithQueryBudget returns a context that allows at most total cumulative
+// time in query methods of a Store created with NewWithQueryBudget, e.g. to
+// hold a request handler that issues dozens of queries to its latency SLO.
+// The budget is shared by every call made with the context or a context
+// derived from it, including concurrent ones, whose durations are summed.
+func WithQueryBudget(ctx context.Context, total time.Duration) context.Context {
+	return context.WithValue(ctx, queryBudgetKey{}, &queryBudget{remaining: total})
+}
+
+// QueryBudgetRemaining returns what is left of the query budget set by
+// WithQueryBudget.
+func QueryBudgetRemaining(ctx context.Context) (time.Duration, bool) {
+	budget, ok := ctx.Value(queryBudgetKey{}).(*queryBudget)
+	if !ok {
+		return 0, false
+	}
+	budget.mu.Lock()
+	defer budget.mu.Unlock()
+	return budget.remaining, true
+}
+
+// NewWithQueryBudget returns a Store that charges the duration of each query
+// method call to the budget set on its context by WithQueryBudget. Calls
+// fail with ErrBudgetExceeded once the budget is used up, and a call that
+// outlasts what remains is canceled. Calls without a budget are not
+// limited. Calls inside transactions are charged, but beginning and
+// committing the transaction is not.
+func NewWithQueryBudget(store Store) Store {
+	return Intercept(store, interceptQueryBudget)
+}
+
+func interceptQueryBudget(ctx context.Context, call Call, next Invoker) ([]interface{}, error) {
+	budget, ok := ctx.Value(queryBudgetKey{}).(*queryBudget)
+	if !ok || call.Method == "InTx" {
+		return next(ctx)
+	}
+	budget.mu.Lock()
+	remaining := budget.remaining
+	budget.mu.Unlock()
+	if remaining <= 0 {
+		return nil, xerrors.Errorf("%s: %w", call.Method, ErrBudgetExceeded)
+	}
+
+	queryCtx, cancel := context.WithTimeout(ctx, remaining)
+	defer cancel()
+	start := time.Now()
+	results, err := next(queryCtx)
+	elapsed := time.Since(start)
+
+	budget.mu.Lock()
+	budget.remaining -= elapsed
+	exhausted := budget.remaining <= 0
+	budget.mu.Unlock()
+	// Only blame the budget when it, not the caller, ended the call.
+	if err != nil && exhausted && ctx.Err() == nil && queryCtx.Err() != nil {
+		return nil, xerrors.Errorf("%s: %w: %s", call.Method, ErrBudgetExceeded, err)
+	}
+	return results, err
+}
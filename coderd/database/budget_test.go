@@ -0,0 +1,55 @@
+package database_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/coder/coder/coderd/database"
+)
+
+func TestQueryBudget(t *testing.T) {
+	t.Parallel()
+
+	sqlDB, connector := newRecordingDB()
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	var calls atomic.Int64
+	connector.hook = func(ctx context.Context, _ string) error {
+		calls.Add(1)
+		select {
+		case <-time.After(50 * time.Millisecond):
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	db := database.NewWithQueryBudget(database.New(sqlDB))
+
+	require.NoError(t, db.DeleteAPIKeyByID(context.Background(), "key"), "no budget")
+
+	ctx := database.WithQueryBudget(context.Background(), 130*time.Millisecond)
+	require.NoError(t, db.DeleteAPIKeyByID(ctx, "key"))
+	require.NoError(t, db.InTx(func(tx database.Store) error {
+		return tx.DeleteAPIKeyByID(ctx, "key")
+	}), "calls inside transactions are charged")
+	remaining, ok := database.QueryBudgetRemaining(ctx)
+	require.True(t, ok)
+	require.Less(t, remaining, 50*time.Millisecond)
+
+	calls.Store(0)
+	start := time.Now()
+	err := db.DeleteAPIKeyByID(ctx, "key")
+	require.ErrorIs(t, err, database.ErrBudgetExceeded, "the remaining budget cuts the query short")
+	require.Less(t, time.Since(start), 50*time.Millisecond)
+	require.EqualValues(t, 1, calls.Load())
+
+	err = db.DeleteAPIKeyByID(ctx, "key")
+	require.ErrorIs(t, err, database.ErrBudgetExceeded)
+	require.ErrorContains(t, err, "DeleteAPIKeyByID")
+	require.EqualValues(t, 1, calls.Load(), "an exhausted budget fails without querying")
+
+	require.NoError(t, db.DeleteAPIKeyByID(context.Background(), "key"), "other requests are unaffected")
+}
@@ -0,0 +1,142 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// burstIdleTimeout is how long a burst connection may sit idle before it is
+// closed, so the extra connections are given back soon after a spike.
+const burstIdleTimeout = 10 * time.Second
+
+// BurstOption configures WithBurstPool.
+type BurstOption func(*burstDB)
+
+// WithBurstMetrics registers metrics for the burst pool: its open and in-use
+// connections, the statements it ran and how often a spike outlasted the
+// window.
+func WithBurstMetrics(registerer prometheus.Registerer) BurstOption {
+	return func(b *burstDB) {
+		factory := promauto.With(registerer)
+		factory.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: "coderd",
+			Subsystem: "db",
+			Name:      "burst_open_connections",
+			Help:      "The number of open connections in the burst pool.",
+		}, func() float64 { return float64(b.sdb.Stats().OpenConnections) })
+		factory.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: "coderd",
+			Subsystem: "db",
+			Name:      "burst_in_use_connections",
+			Help:      "The number of burst pool connections running a statement.",
+		}, func() float64 { return float64(b.sdb.Stats().InUse) })
+		b.statements = factory.NewCounter(prometheus.CounterOpts{
+			Namespace: "coderd",
+			Subsystem: "db",
+			Name:      "burst_statements_total",
+			Help:      "The total number of statements run on the burst pool because the primary pool was exhausted.",
+		})
+		b.exceeded = factory.NewCounter(prometheus.CounterOpts{
+			Namespace: "coderd",
+			Subsystem: "db",
+			Name:      "burst_window_exceeded_total",
+			Help:      "The total number of spikes that outlasted the burst window, after which statements queued for the primary pool.",
+		})
+	}
+}
+
+// WithBurstPool lets statements overflow into burst, a pool of up to size
+// connections, when every connection of the primary pool is in use, instead
+// of queueing for one. It is meant for short spikes: once the primary pool
+// has been exhausted for window, statements queue for it again until it has
+// a free connection, which starts a new window. New caps burst at size open
+// connections and closes those idle for more than ten seconds, so the extra
+// connections are given back soon after a spike.
+//
+// Like WithSecondaryPool, only statements made outside a transaction
+// overflow; transactions and WithConn always wait for the primary pool.
+func WithBurstPool(burst *sql.DB, size int, window time.Duration, opts ...BurstOption) Option {
+	return func(o *options) {
+		o.burstPool = burst
+		o.burstSize = size
+		o.burstWindow = window
+		o.burstOpts = opts
+	}
+}
+
+// burstDB runs statements on burst while the primary pool is exhausted,
+// for at most window per spike.
+type burstDB struct {
+	DBTX
+	burst   DBTX
+	sdb     *sql.DB
+	primary *sql.DB
+	window  time.Duration
+
+	mu sync.Mutex
+	// spikeStart is when the primary pool was first seen exhausted in the
+	// current spike, or zero if it is not.
+	spikeStart time.Time
+	exhausted  bool
+
+	// statements and exceeded are set by WithBurstMetrics.
+	statements prometheus.Counter
+	exceeded   prometheus.Counter
+}
+
+func (b *burstDB) pick() DBTX {
+	stats := b.primary.Stats()
+	saturated := stats.MaxOpenConnections > 0 && stats.InUse >= stats.MaxOpenConnections
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !saturated {
+		b.spikeStart = time.Time{}
+		b.exhausted = false
+		return b.DBTX
+	}
+	now := time.Now()
+	if b.spikeStart.IsZero() {
+		b.spikeStart = now
+	}
+	if now.Sub(b.spikeStart) > b.window {
+		if !b.exhausted && b.exceeded != nil {
+			b.exceeded.Inc()
+		}
+		b.exhausted = true
+		return b.DBTX
+	}
+	if b.statements != nil {
+		b.statements.Inc()
+	}
+	return b.burst
+}
+
+func (b *burstDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return b.pick().ExecContext(ctx, query, args...)
+}
+
+func (b *burstDB) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return b.pick().PrepareContext(ctx, query)
+}
+
+func (b *burstDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return b.pick().QueryContext(ctx, query, args...)
+}
+
+func (b *burstDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return b.pick().QueryRowContext(ctx, query, args...)
+}
+
+func (b *burstDB) SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	return b.pick().SelectContext(ctx, dest, query, args...)
+}
+
+func (b *burstDB) GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	return b.pick().GetContext(ctx, dest, query, args...)
+}
@@ -0,0 +1,89 @@
+package database_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/coder/coder/coderd/database"
+)
+
+func TestWithBurstPool(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	primaryDB, primary := newRecordingDB()
+	t.Cleanup(func() { _ = primaryDB.Close() })
+	burstDB, burst := newRecordingDB()
+	t.Cleanup(func() { _ = burstDB.Close() })
+	registry := prometheus.NewRegistry()
+	const window = 200 * time.Millisecond
+	db := database.New(primaryDB, database.WithBurstPool(burstDB, 5, window, database.WithBurstMetrics(registry)))
+	require.Equal(t, 5, burstDB.Stats().MaxOpenConnections)
+
+	metric := func(name string) float64 {
+		families, err := registry.Gather()
+		require.NoError(t, err)
+		for _, family := range families {
+			if family.GetName() != name {
+				continue
+			}
+			m := family.GetMetric()[0]
+			if m.GetGauge() != nil {
+				return m.GetGauge().GetValue()
+			}
+			return m.GetCounter().GetValue()
+		}
+		return -1
+	}
+	// spike takes every connection of the primary pool, which New limits
+	// to 40, and returns a function giving them back.
+	spike := func() func() {
+		conns := make([]*sql.Conn, 0, 40)
+		for i := 0; i < 40; i++ {
+			conn, err := primaryDB.Conn(ctx)
+			require.NoError(t, err)
+			conns = append(conns, conn)
+		}
+		return func() {
+			for _, conn := range conns {
+				_ = conn.Close()
+			}
+		}
+	}
+	get := func(ctx context.Context) {
+		_, _ = db.GetUserByID(ctx, uuid.New())
+	}
+
+	get(ctx)
+	require.Len(t, primary.Queries(), 1, "the primary pool has free connections")
+	require.Empty(t, burst.Queries())
+
+	end := spike()
+	get(ctx)
+	get(ctx)
+	require.Len(t, burst.Queries(), 2, "the spike overflows into the burst pool")
+	require.EqualValues(t, 2, metric("coderd_db_burst_statements_total"))
+	require.EqualValues(t, 1, metric("coderd_db_burst_open_connections"))
+
+	time.Sleep(window + 50*time.Millisecond)
+	timeoutCtx, timeoutCancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	get(timeoutCtx)
+	timeoutCancel()
+	require.Len(t, burst.Queries(), 2, "a spike outlasting the window queues for the primary pool")
+	require.EqualValues(t, 1, metric("coderd_db_burst_window_exceeded_total"))
+	end()
+
+	get(ctx)
+	require.Len(t, primary.Queries(), 2)
+	end = spike()
+	defer end()
+	get(ctx)
+	require.Len(t, burst.Queries(), 3, "the next spike gets a new window")
+}
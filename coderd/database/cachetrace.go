@@ -0,0 +1,65 @@
+package database
+
+import (
+	"context"
+	"sync"
+)
+
+type cacheTraceKey struct{}
+
+// CacheTrace records whether Store calls made with a context were served
+// from a cache. It is intended for debugging stale-data reports: attach one
+// to a request context with WithCacheTrace and inspect it after the calls
+// complete.
+type CacheTrace struct {
+	mu     sync.Mutex
+	method string
+	hit    bool
+	hits   map[string]int
+	misses map[string]int
+}
+
+// WithCacheTrace returns a context that records cache results reported by
+// caching layers, and the trace they are recorded into.
+func WithCacheTrace(ctx context.Context) (context.Context, *CacheTrace) {
+	trace := &CacheTrace{
+		hits:   map[string]int{},
+		misses: map[string]int{},
+	}
+	return context.WithValue(ctx, cacheTraceKey{}, trace), trace
+}
+
+// RecordCacheResult reports whether a call to method was served from a
+// cache. Caching layers call it for every lookup. It is a no-op unless ctx
+// was created by WithCacheTrace, so the cost when tracing is off is a
+// single context lookup.
+func RecordCacheResult(ctx context.Context, method string, hit bool) {
+	trace, ok := ctx.Value(cacheTraceKey{}).(*CacheTrace)
+	if !ok {
+		return
+	}
+	trace.mu.Lock()
+	defer trace.mu.Unlock()
+	trace.method = method
+	trace.hit = hit
+	if hit {
+		trace.hits[method]++
+	} else {
+		trace.misses[method]++
+	}
+}
+
+// Last returns the method of the most recently recorded call and whether it
+// was served from a cache. The method is empty if nothing was recorded.
+func (t *CacheTrace) Last() (method string, hit bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.method, t.hit
+}
+
+// Counts returns the number of cache hits and misses recorded for method.
+func (t *CacheTrace) Counts(method string) (hits, misses int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.hits[method], t.misses[method]
+}
@@ -0,0 +1,77 @@
+package database_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/coder/coder/coderd/database"
+	"github.com/coder/coder/coderd/database/databasefake"
+)
+
+func TestCacheTrace(t *testing.T) {
+	t.Parallel()
+
+	// A minimal read-through cache keyed by method and first argument.
+	var mu sync.Mutex
+	cache := map[string][]interface{}{}
+	cached := func(ctx context.Context, call database.Call, next database.Invoker) ([]interface{}, error) {
+		key := call.Method
+		if len(call.Args) > 0 {
+			if id, ok := call.Args[0].(uuid.UUID); ok {
+				key += id.String()
+			}
+		}
+		mu.Lock()
+		res, ok := cache[key]
+		mu.Unlock()
+		database.RecordCacheResult(ctx, call.Method, ok)
+		if ok {
+			return res, nil
+		}
+		res, err := next(ctx)
+		if err == nil {
+			mu.Lock()
+			cache[key] = res
+			mu.Unlock()
+		}
+		return res, err
+	}
+
+	fake := databasefake.New()
+	user, err := fake.InsertUser(context.Background(), database.InsertUserParams{
+		ID:        uuid.New(),
+		Email:     "coder@coder.com",
+		Username:  "coder",
+		RBACRoles: []string{},
+	})
+	require.NoError(t, err)
+	db := database.Intercept(fake, cached)
+
+	// Without a trace, recording is a no-op.
+	_, err = db.GetUserByID(context.Background(), user.ID)
+	require.NoError(t, err)
+
+	ctx, trace := database.WithCacheTrace(context.Background())
+	method, _ := trace.Last()
+	require.Empty(t, method)
+
+	_, err = db.GetUserByID(ctx, user.ID)
+	require.NoError(t, err)
+	method, hit := trace.Last()
+	require.Equal(t, "GetUserByID", method)
+	require.True(t, hit)
+
+	_, err = db.GetUserByEmailOrUsername(ctx, database.GetUserByEmailOrUsernameParams{Username: "coder"})
+	require.NoError(t, err)
+	method, hit = trace.Last()
+	require.Equal(t, "GetUserByEmailOrUsername", method)
+	require.False(t, hit)
+
+	hits, misses := trace.Counts("GetUserByID")
+	require.Equal(t, 1, hits)
+	require.Equal(t, 0, misses)
+}
@@ -0,0 +1,78 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/lib/pq"
+)
+
+// cancelDB makes statements that Postgres canceled because their context
+// ended match the context's error, so callers can tell a client that went
+// away, or a deadline that passed, from a failing query with
+// errors.Is(err, context.Canceled). The driver cancels a running statement
+// once its context is done, so a request context passed all the way down
+// stops the query on the server rather than leaving it to run to
+// completion for no one.
+//
+// QueryRowContext defers its error to Scan and cannot be mapped; the
+// *pq.Error reports query_canceled (57014) instead.
+type cancelDB struct {
+	DBTX
+}
+
+// canceledError wraps a query_canceled error so it matches the error of the
+// context that canceled it while preserving the underlying *pq.Error.
+type canceledError struct {
+	err   error
+	cause error
+}
+
+func (e canceledError) Error() string {
+	return e.err.Error()
+}
+
+func (e canceledError) Unwrap() error {
+	return e.err
+}
+
+func (e canceledError) Is(target error) bool {
+	return target == e.cause
+}
+
+// mapCanceled maps err to a canceledError if Postgres canceled the
+// statement and ctx is done.
+func mapCanceled(ctx context.Context, err error) error {
+	if err == nil || ctx.Err() == nil || errors.Is(err, ctx.Err()) {
+		return err
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "57014" {
+		return canceledError{err: err, cause: ctx.Err()}
+	}
+	return err
+}
+
+func (c *cancelDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	result, err := c.DBTX.ExecContext(ctx, query, args...)
+	return result, mapCanceled(ctx, err)
+}
+
+func (c *cancelDB) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	stmt, err := c.DBTX.PrepareContext(ctx, query)
+	return stmt, mapCanceled(ctx, err)
+}
+
+func (c *cancelDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	rows, err := c.DBTX.QueryContext(ctx, query, args...)
+	return rows, mapCanceled(ctx, err)
+}
+
+func (c *cancelDB) SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	return mapCanceled(ctx, c.DBTX.SelectContext(ctx, dest, query, args...))
+}
+
+func (c *cancelDB) GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	return mapCanceled(ctx, c.DBTX.GetContext(ctx, dest, query, args...))
+}
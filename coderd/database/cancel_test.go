@@ -0,0 +1,88 @@
+//go:build linux
+
+package database_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/coder/coder/coderd/database"
+	"github.com/coder/coder/coderd/database/migrations"
+)
+
+func TestQueryCancellation(t *testing.T) {
+	t.Parallel()
+
+	t.Run("MapsError", func(t *testing.T) {
+		t.Parallel()
+		sqlDB, connector := newRecordingDB()
+		t.Cleanup(func() { _ = sqlDB.Close() })
+		ctx, cancel := context.WithCancel(context.Background())
+		connector.hook = func(hookCtx context.Context, _ string) error {
+			cancel()
+			<-hookCtx.Done()
+			return &pq.Error{Code: "57014", Message: "canceling statement due to user request"}
+		}
+		db := database.New(sqlDB)
+
+		err := db.DeleteAPIKeyByID(ctx, "key")
+		require.ErrorIs(t, err, context.Canceled, "the request context reaches the driver")
+		var pqErr *pq.Error
+		require.True(t, errors.As(err, &pqErr), "the driver error is preserved")
+
+		connector.hook = func(context.Context, string) error {
+			return &pq.Error{Code: "57014", Message: "canceling statement due to statement timeout"}
+		}
+		err = db.DeleteAPIKeyByID(context.Background(), "key")
+		require.Error(t, err)
+		require.NotErrorIs(t, err, context.Canceled, "canceled by the server, not the caller")
+	})
+
+	t.Run("Postgres", func(t *testing.T) {
+		t.Parallel()
+		if testing.Short() {
+			t.SkipNow()
+		}
+		sqlDB := testSQLDB(t)
+		require.NoError(t, migrations.Up(sqlDB))
+		db := database.New(sqlDB, database.WithQueryRewriter(func(method, query string) string {
+			if method == "DeleteAPIKeyByID" {
+				return "SELECT pg_sleep(30), $1::text"
+			}
+			return query
+		}))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		t.Cleanup(cancel)
+		done := make(chan error, 1)
+		go func() {
+			done <- db.DeleteAPIKeyByID(ctx, "key")
+		}()
+
+		const query = `SELECT pid FROM pg_stat_activity WHERE state = 'active' AND query LIKE 'SELECT pg_sleep(30)%'`
+		var pid int
+		require.Eventually(t, func() bool {
+			return sqlDB.QueryRow(query).Scan(&pid) == nil
+		}, 10*time.Second, 10*time.Millisecond, "the query starts")
+
+		// Simulates the client disconnecting mid-request.
+		cancel()
+		select {
+		case err := <-done:
+			assert.ErrorIs(t, err, context.Canceled)
+		case <-time.After(5 * time.Second):
+			t.Fatal("query did not return after cancellation")
+		}
+		require.Eventually(t, func() bool {
+			var state string
+			err := sqlDB.QueryRow(`SELECT COALESCE(state, '') FROM pg_stat_activity WHERE pid = $1`, pid).Scan(&state)
+			return err != nil || state != "active"
+		}, 2*time.Second, 10*time.Millisecond, "the query is canceled on the server")
+	})
+}
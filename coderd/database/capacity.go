@@ -0,0 +1,123 @@
+package database
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"golang.org/x/xerrors"
+)
+
+// TablespaceSpace is the disk space used by one tablespace.
+type TablespaceSpace struct {
+	Name string `db:"name" json:"name"`
+	// Location is the tablespace directory, or empty for the built-in
+	// pg_default and pg_global tablespaces inside the data directory.
+	Location  string `db:"location" json:"location"`
+	SizeBytes int64  `db:"size_bytes" json:"size_bytes"`
+}
+
+// capacityQuerier reports how much disk the database uses. Postgres cannot
+// report free space on the filesystem beneath it, so capacity is tracked as
+// a size trend against a cap the operator configures from what they
+// provisioned: sample GetDatabaseSize periodically and pass the samples to
+// ProjectTimeToFull.
+type capacityQuerier interface {
+	// GetDatabaseSize returns the size of the current database in bytes.
+	GetDatabaseSize(ctx context.Context) (int64, error)
+	// GetTablespaceSizes returns the space used by each tablespace, which
+	// includes every database stored in it. Reading tablespaces other than
+	// the current database's default requires CREATE on them or the
+	// pg_read_all_stats role; those the user cannot read are omitted.
+	GetTablespaceSizes(ctx context.Context) ([]TablespaceSpace, error)
+}
+
+func (q *sqlQuerier) GetDatabaseSize(ctx context.Context) (int64, error) {
+	const query = `-- name: GetDatabaseSize :one
+	SELECT pg_database_size(current_database())
+	`
+
+	var size int64
+	err := q.db.GetContext(ctx, &size, query)
+	if err != nil {
+		return 0, xerrors.Errorf("get database size: %w", err)
+	}
+	return size, nil
+}
+
+func (q *sqlQuerier) GetTablespaceSizes(ctx context.Context) ([]TablespaceSpace, error) {
+	const query = `-- name: GetTablespaceSizes :many
+	SELECT
+		spcname AS name,
+		pg_tablespace_location(oid) AS location,
+		pg_tablespace_size(oid) AS size_bytes
+	FROM
+		pg_tablespace
+	WHERE
+		has_tablespace_privilege(oid, 'CREATE')
+		OR pg_has_role('pg_read_all_stats', 'USAGE')
+		OR oid = (SELECT dattablespace FROM pg_database WHERE datname = current_database())
+	ORDER BY
+		spcname
+	`
+
+	spaces := []TablespaceSpace{}
+	err := q.db.SelectContext(ctx, &spaces, query)
+	if err != nil {
+		return nil, xerrors.Errorf("get tablespace sizes: %w", err)
+	}
+	return spaces, nil
+}
+
+// SizeSample is a database size observed at a point in time.
+type SizeSample struct {
+	Time  time.Time
+	Bytes int64
+}
+
+// ProjectTimeToFull estimates how long after the latest sample the size
+// will reach capacity bytes, from the least-squares growth rate across
+// samples. It reports false if there are fewer than two samples spanning
+// some time or the size is not growing, and zero if capacity is already
+// reached. Samples should cover long enough to smooth out vacuum and bulk
+// deletes; the projection is linear and will not anticipate bursts.
+func ProjectTimeToFull(samples []SizeSample, capacity int64) (time.Duration, bool) {
+	if len(samples) < 2 {
+		return 0, false
+	}
+	latest := samples[0]
+	for _, s := range samples[1:] {
+		if s.Time.After(latest.Time) {
+			latest = s
+		}
+	}
+	if latest.Bytes >= capacity {
+		return 0, true
+	}
+
+	// Fit bytes = a + rate*seconds, with seconds relative to the latest
+	// sample to keep the sums small.
+	var sumX, sumY, sumXX, sumXY float64
+	for _, s := range samples {
+		x := s.Time.Sub(latest.Time).Seconds()
+		y := float64(s.Bytes)
+		sumX += x
+		sumY += y
+		sumXX += x * x
+		sumXY += x * y
+	}
+	n := float64(len(samples))
+	denominator := n*sumXX - sumX*sumX
+	if denominator == 0 {
+		return 0, false
+	}
+	rate := (n*sumXY - sumX*sumY) / denominator
+	if rate <= 0 {
+		return 0, false
+	}
+	seconds := float64(capacity-latest.Bytes) / rate
+	if seconds >= math.MaxInt64/float64(time.Second) {
+		return math.MaxInt64, true
+	}
+	return time.Duration(seconds * float64(time.Second)), true
+}
@@ -0,0 +1,66 @@
+package database_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/coder/coder/coderd/database"
+)
+
+func TestGetDatabaseSize(t *testing.T) {
+	t.Parallel()
+
+	sqlDB, connector := newRecordingDB()
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	connector.rows = func(string) ([]string, [][]driver.Value) {
+		return []string{"pg_database_size"}, [][]driver.Value{{int64(8 << 20)}}
+	}
+	size, err := database.New(sqlDB).GetDatabaseSize(context.Background())
+	require.NoError(t, err)
+	require.EqualValues(t, 8<<20, size)
+}
+
+func TestProjectTimeToFull(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2022, 10, 1, 0, 0, 0, 0, time.UTC)
+	// Growing by 1 GiB a day, 5 GiB used.
+	growing := []database.SizeSample{
+		{Time: start, Bytes: 3 << 30},
+		{Time: start.Add(24 * time.Hour), Bytes: 4 << 30},
+		{Time: start.Add(48 * time.Hour), Bytes: 5 << 30},
+	}
+
+	remaining, ok := database.ProjectTimeToFull(growing, 10<<30)
+	require.True(t, ok)
+	require.Equal(t, 5*24*time.Hour, remaining.Round(time.Second))
+
+	remaining, ok = database.ProjectTimeToFull(growing, 5<<30)
+	require.True(t, ok, "already full")
+	require.Zero(t, remaining)
+
+	// Order of samples does not matter.
+	remaining, ok = database.ProjectTimeToFull([]database.SizeSample{growing[2], growing[0], growing[1]}, 10<<30)
+	require.True(t, ok)
+	require.Equal(t, 5*24*time.Hour, remaining.Round(time.Second))
+
+	_, ok = database.ProjectTimeToFull(growing[:1], 10<<30)
+	require.False(t, ok, "one sample has no trend")
+	_, ok = database.ProjectTimeToFull([]database.SizeSample{
+		{Time: start, Bytes: 5 << 30},
+		{Time: start.Add(time.Hour), Bytes: 4 << 30},
+	}, 10<<30)
+	require.False(t, ok, "shrinking")
+
+	remaining, ok = database.ProjectTimeToFull([]database.SizeSample{
+		{Time: start, Bytes: 0},
+		{Time: start.Add(24 * 365 * time.Hour), Bytes: 1},
+	}, math.MaxInt64)
+	require.True(t, ok)
+	require.Equal(t, time.Duration(math.MaxInt64), remaining, "does not overflow")
+}
@@ -0,0 +1,317 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"cdr.dev/slog"
+	"github.com/lib/pq"
+	"golang.org/x/xerrors"
+)
+
+// LSN is a Postgres write-ahead log position.
+type LSN uint64
+
+// ParseLSN parses an LSN in the X/X form Postgres prints.
+func ParseLSN(s string) (LSN, error) {
+	var hi, lo uint32
+	_, err := fmt.Sscanf(s, "%X/%X", &hi, &lo)
+	if err != nil {
+		return 0, xerrors.Errorf("parse lsn %q: %w", s, err)
+	}
+	return LSN(uint64(hi)<<32 | uint64(lo)), nil
+}
+
+func (l LSN) String() string {
+	return fmt.Sprintf("%X/%X", uint32(l>>32), uint32(l))
+}
+
+// ChangeEvent is one committed transaction read from a logical replication
+// slot.
+type ChangeEvent struct {
+	XID uint32
+	// LSN is the end of the transaction's commit record. Pass it to
+	// ChangeStream.Ack once the event has been processed.
+	LSN LSN
+	// Data holds the output of the slot's decoding plugin for the
+	// transaction, in order, e.g. BEGIN, one row per change and COMMIT for
+	// test_decoding, or a single JSON document for wal2json.
+	Data []string
+}
+
+// ChangeStreamOptions configures NewChangeStream.
+type ChangeStreamOptions struct {
+	// Slot is the name of the logical replication slot. It is created if
+	// it does not exist.
+	Slot string
+	// Plugin is the output plugin the slot is created with. Defaults to
+	// test_decoding, which ships with Postgres.
+	Plugin string
+	// PollInterval is how often the slot is checked for new changes while
+	// it is idle or unreachable. Defaults to one second.
+	PollInterval time.Duration
+	// BatchSize bounds the changes read from the slot at once. Whole
+	// transactions are always read, so a batch may exceed it. Defaults to
+	// 1000.
+	BatchSize int
+}
+
+// ChangeStream delivers committed transactions from a logical replication
+// slot, in commit order and at least once. The slot records what has been
+// acknowledged with Ack, so a stream opened on the same slot after a
+// restart continues from there; transactions delivered but not yet
+// acknowledged are delivered again.
+//
+// Reads are batched. Every event of a batch must be acknowledged, by
+// acknowledging its last event, before the next batch is read.
+//
+// Operating a change stream requires wal_level = logical, a free slot in
+// max_replication_slots and a role with the REPLICATION attribute. A slot
+// retains WAL until it is acknowledged, whether or not anything is reading
+// it, so a stalled consumer fills the disk of the primary: monitor
+// pg_replication_slots and remove slots that are no longer used with
+// DropChangeStreamSlot.
+//
+// Lib/pq does not speak the streaming replication protocol, so the slot is
+// polled over an ordinary connection with the SQL-level logical decoding
+// functions. Connection failures are retried with the connection pool of
+// db.
+type ChangeStream struct {
+	db     *sql.DB
+	logger slog.Logger
+	opts   ChangeStreamOptions
+
+	changes chan ChangeEvent
+
+	mu        sync.Mutex
+	acked     LSN
+	delivered LSN
+	ackSignal chan struct{}
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewChangeStream creates the slot if needed and starts reading it until
+// ctx is done or Close is called. It is not part of Store so that only
+// deployments that opt in hold a replication slot.
+func NewChangeStream(ctx context.Context, db *sql.DB, logger slog.Logger, opts ChangeStreamOptions) (*ChangeStream, error) {
+	if err := validateIdentifier("replication slot", opts.Slot); err != nil {
+		return nil, err
+	}
+	if opts.Plugin == "" {
+		opts.Plugin = "test_decoding"
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = time.Second
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 1000
+	}
+	err := createChangeStreamSlot(ctx, db, opts.Slot, opts.Plugin)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	s := &ChangeStream{
+		db:        db,
+		logger:    logger,
+		opts:      opts,
+		changes:   make(chan ChangeEvent),
+		ackSignal: make(chan struct{}, 1),
+		cancel:    cancel,
+		done:      make(chan struct{}),
+	}
+	go func() {
+		defer close(s.done)
+		defer close(s.changes)
+		s.run(ctx)
+	}()
+	return s, nil
+}
+
+func createChangeStreamSlot(ctx context.Context, db *sql.DB, slot, plugin string) error {
+	const existing = `-- name: NewChangeStream :one
+	SELECT slot_type, COALESCE(plugin, '') FROM pg_replication_slots WHERE slot_name = $1
+	`
+	const create = `-- name: NewChangeStream :exec
+	SELECT pg_create_logical_replication_slot($1, $2)
+	`
+
+	var slotType, slotPlugin string
+	err := db.QueryRowContext(ctx, existing, slot).Scan(&slotType, &slotPlugin)
+	if err == nil {
+		if slotType != "logical" || slotPlugin != plugin {
+			return xerrors.Errorf("replication slot %q is a %s slot with plugin %q, not a logical slot with plugin %q", slot, slotType, slotPlugin, plugin)
+		}
+		return nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return xerrors.Errorf("get replication slot: %w", err)
+	}
+	_, err = db.ExecContext(ctx, create, slot, plugin)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "42710" {
+		// Another process created it first.
+		return nil
+	}
+	if err != nil {
+		return xerrors.Errorf("create replication slot: %w", err)
+	}
+	return nil
+}
+
+// DropChangeStreamSlot removes a slot created by NewChangeStream, releasing
+// the WAL it retains. No stream may be reading the slot.
+func DropChangeStreamSlot(ctx context.Context, db *sql.DB, slot string) error {
+	const query = `-- name: DropChangeStreamSlot :exec
+	SELECT pg_drop_replication_slot($1)
+	`
+	_, err := db.ExecContext(ctx, query, slot)
+	if err != nil {
+		return xerrors.Errorf("drop replication slot: %w", err)
+	}
+	return nil
+}
+
+// Changes returns the channel events are delivered on. It is closed when
+// the stream stops.
+func (s *ChangeStream) Changes() <-chan ChangeEvent {
+	return s.changes
+}
+
+// Ack records that every event up to and including the one at lsn has
+// been processed, so the slot may release it and it is not delivered again
+// after a restart.
+func (s *ChangeStream) Ack(ctx context.Context, lsn LSN) error {
+	const query = `-- name: ChangeStreamAck :exec
+	SELECT pg_replication_slot_advance($1, $2::pg_lsn)
+	`
+	_, err := s.db.ExecContext(ctx, query, s.opts.Slot, lsn.String())
+	if err != nil {
+		return xerrors.Errorf("advance replication slot: %w", err)
+	}
+	s.mu.Lock()
+	if lsn > s.acked {
+		s.acked = lsn
+	}
+	s.mu.Unlock()
+	select {
+	case s.ackSignal <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Close stops reading the slot. Events that were not acknowledged are
+// delivered again by the next stream on the slot.
+func (s *ChangeStream) Close() error {
+	s.cancel()
+	<-s.done
+	return nil
+}
+
+func (s *ChangeStream) run(ctx context.Context) {
+	for {
+		// Wait for the previous batch to be acknowledged, since the slot
+		// only advances on Ack and would return it again.
+		s.mu.Lock()
+		pending := s.acked < s.delivered
+		s.mu.Unlock()
+		if pending {
+			select {
+			case <-ctx.Done():
+				return
+			case <-s.ackSignal:
+			}
+			continue
+		}
+
+		events, err := s.peek(ctx)
+		if err != nil && ctx.Err() == nil {
+			s.logger.Warn(ctx, "read replication slot", slog.F("slot", s.opts.Slot), slog.Error(err))
+		}
+		if len(events) == 0 {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(s.opts.PollInterval):
+			}
+			continue
+		}
+		for _, event := range events {
+			select {
+			case <-ctx.Done():
+				return
+			case s.changes <- event:
+			}
+			s.mu.Lock()
+			s.delivered = event.LSN
+			s.mu.Unlock()
+		}
+	}
+}
+
+// peek reads the next batch from the slot without consuming it, skipping
+// transactions that were already delivered.
+func (s *ChangeStream) peek(ctx context.Context) ([]ChangeEvent, error) {
+	const query = `-- name: ChangeStreamPeek :many
+	SELECT lsn::text, xid::text::bigint, data FROM pg_logical_slot_peek_changes($1, NULL, $2)
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, s.opts.Slot, s.opts.BatchSize)
+	if err != nil {
+		return nil, xerrors.Errorf("peek changes: %w", err)
+	}
+	defer rows.Close()
+
+	s.mu.Lock()
+	delivered := s.delivered
+	s.mu.Unlock()
+
+	// Rows of a transaction are contiguous and transactions are in commit
+	// order, so a transaction ends where the xid changes.
+	var (
+		events  []ChangeEvent
+		current *ChangeEvent
+	)
+	flush := func() {
+		if current != nil && current.LSN > delivered {
+			events = append(events, *current)
+		}
+		current = nil
+	}
+	for rows.Next() {
+		var (
+			lsnText string
+			xid     int64
+			data    string
+		)
+		err := rows.Scan(&lsnText, &xid, &data)
+		if err != nil {
+			return nil, xerrors.Errorf("scan change: %w", err)
+		}
+		lsn, err := ParseLSN(lsnText)
+		if err != nil {
+			return nil, err
+		}
+		if current != nil && current.XID != uint32(xid) {
+			flush()
+		}
+		if current == nil {
+			current = &ChangeEvent{XID: uint32(xid)}
+		}
+		current.LSN = lsn
+		current.Data = append(current.Data, data)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, xerrors.Errorf("read changes: %w", err)
+	}
+	flush()
+	return events, nil
+}
@@ -0,0 +1,98 @@
+//go:build linux
+
+package database_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"cdr.dev/slog/sloggers/slogtest"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/coder/coder/coderd/database"
+	"github.com/coder/coder/coderd/database/migrations"
+)
+
+func TestParseLSN(t *testing.T) {
+	t.Parallel()
+
+	lsn, err := database.ParseLSN("16/B374D848")
+	require.NoError(t, err)
+	require.Equal(t, database.LSN(0x16B374D848), lsn)
+	require.Equal(t, "16/B374D848", lsn.String())
+
+	_, err = database.ParseLSN("B374D848")
+	require.Error(t, err)
+}
+
+func TestChangeStream(t *testing.T) {
+	t.Parallel()
+	if testing.Short() {
+		t.SkipNow()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	sqlDB := testSQLDB(t)
+	var walLevel string
+	require.NoError(t, sqlDB.QueryRowContext(ctx, "SHOW wal_level").Scan(&walLevel))
+	if walLevel != "logical" {
+		t.Skip("requires wal_level = logical")
+	}
+	require.NoError(t, migrations.Up(sqlDB), "migrations")
+	db := database.New(sqlDB)
+
+	logger := slogtest.Make(t, nil)
+	opts := database.ChangeStreamOptions{
+		Slot:         "test_change_stream",
+		PollInterval: 10 * time.Millisecond,
+	}
+	stream, err := database.NewChangeStream(ctx, sqlDB, logger, opts)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = stream.Close()
+		_ = database.DropChangeStreamSlot(context.Background(), sqlDB, opts.Slot)
+	})
+
+	insertKey := func() string {
+		id := uuid.NewString()
+		_, err := db.InsertLicense(ctx, database.InsertLicenseParams{
+			UploadedAt: database.Now(),
+			JWT:        id,
+			Exp:        database.Now().Add(time.Hour),
+		})
+		require.NoError(t, err)
+		return id
+	}
+	next := func(stream *database.ChangeStream) database.ChangeEvent {
+		select {
+		case event := <-stream.Changes():
+			return event
+		case <-ctx.Done():
+			t.Fatal("timed out waiting for a change")
+			return database.ChangeEvent{}
+		}
+	}
+
+	first := insertKey()
+	event := next(stream)
+	require.Contains(t, strings.Join(event.Data, "\n"), first)
+	require.NoError(t, stream.Ack(ctx, event.LSN))
+
+	second := insertKey()
+	event = next(stream)
+	require.Contains(t, strings.Join(event.Data, "\n"), second)
+	require.NoError(t, stream.Close())
+
+	// The second transaction was not acknowledged, so a new stream on the
+	// slot delivers it again, but not the first.
+	stream, err = database.NewChangeStream(ctx, sqlDB, logger, opts)
+	require.NoError(t, err)
+	event = next(stream)
+	data := strings.Join(event.Data, "\n")
+	require.Contains(t, data, second)
+	require.NotContains(t, data, first)
+}
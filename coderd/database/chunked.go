@@ -0,0 +1,96 @@
+package database
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"golang.org/x/xerrors"
+)
+
+// retentionTables maps each table DeleteExpiredInChunks accepts to the
+// column its rows expire by. Both are interpolated into SQL, so only these
+// are allowed, and the map is unexported so no other package can add to
+// it.
+var retentionTables = map[string]string{
+	"agent_stats":          "created_at",
+	"api_keys":             "expires_at",
+	"audit_logs":           `"time"`,
+	"audit_logs_archive":   `"time"`,
+	"provisioner_job_logs": "created_at",
+}
+
+type chunkedQuerier interface {
+	// DeleteExpiredInChunks deletes rows of table that expired before
+	// olderThan, at most chunkSize rows per statement, until none remain,
+	// and returns the total deleted. Outside a transaction each chunk
+	// commits on its own, so locks are held for one chunk at a time.
+	DeleteExpiredInChunks(ctx context.Context, table string, olderThan time.Time, chunkSize int) (int64, error)
+}
+
+// RetentionTables returns the tables DeleteExpiredInChunks accepts, in
+// sorted order.
+func RetentionTables() []string {
+	tables := make([]string, 0, len(retentionTables))
+	for name := range retentionTables {
+		tables = append(tables, name)
+	}
+	sort.Strings(tables)
+	return tables
+}
+
+// retentionColumn returns the expiry column of table, or an error naming
+// the allowed tables.
+func retentionColumn(table string) (string, error) {
+	column, ok := retentionTables[table]
+	if !ok {
+		return "", xerrors.Errorf("table %q does not support expiry, must be one of %q", table, RetentionTables())
+	}
+	return column, nil
+}
+
+func (q *sqlQuerier) DeleteExpiredInChunks(ctx context.Context, table string, olderThan time.Time, chunkSize int) (int64, error) {
+	column, err := retentionColumn(table)
+	if err != nil {
+		return 0, err
+	}
+	if chunkSize <= 0 {
+		return 0, xerrors.Errorf("chunk size must be positive, got %d", chunkSize)
+	}
+	// ctid addresses the physical row, so each chunk is found and deleted
+	// without needing a primary key on the table.
+	query := `-- name: DeleteExpiredInChunks :execrows
+	DELETE FROM
+		` + table + `
+	WHERE
+		ctid IN (
+			SELECT
+				ctid
+			FROM
+				` + table + `
+			WHERE
+				` + column + ` < $1
+			LIMIT
+				$2
+		)
+	`
+
+	var total int64
+	for {
+		if err := ctx.Err(); err != nil {
+			return total, xerrors.Errorf("delete expired %s: %w", table, err)
+		}
+		result, err := q.db.ExecContext(ctx, query, olderThan, chunkSize)
+		if err != nil {
+			return total, xerrors.Errorf("delete expired %s: %w", table, err)
+		}
+		deleted, err := result.RowsAffected()
+		if err != nil {
+			return total, xerrors.Errorf("rows affected: %w", err)
+		}
+		total += deleted
+		if deleted < int64(chunkSize) {
+			return total, nil
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package database_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/coder/coder/coderd/database"
+	"github.com/coder/coder/coderd/database/dbtestutil"
+)
+
+func TestDeleteExpiredInChunks(t *testing.T) {
+	t.Parallel()
+
+	db, _ := dbtestutil.NewDB(t)
+	ctx := context.Background()
+	now := database.Now()
+
+	for i := 0; i < 5; i++ {
+		insertAgentStat(t, db, now.Add(-48*time.Hour))
+	}
+	recent := insertAgentStat(t, db, now)
+
+	// Five expired rows in chunks of two takes three statements.
+	deleted, err := db.DeleteExpiredInChunks(ctx, "agent_stats", now.Add(-24*time.Hour), 2)
+	require.NoError(t, err)
+	require.EqualValues(t, 5, deleted)
+
+	latest, err := db.GetLatestAgentStat(ctx, recent.AgentID)
+	require.NoError(t, err)
+	require.Equal(t, recent.ID, latest.ID, "unexpired rows are kept")
+
+	deleted, err = db.DeleteExpiredInChunks(ctx, "agent_stats", now.Add(-24*time.Hour), 2)
+	require.NoError(t, err)
+	require.Zero(t, deleted)
+
+	_, err = db.DeleteExpiredInChunks(ctx, "users", now, 2)
+	require.ErrorContains(t, err, "does not support expiry")
+	require.NotContains(t, database.RetentionTables(), "users")
+	tables := database.RetentionTables()
+	tables[0] = "users"
+	require.NotContains(t, database.RetentionTables(), "users", "callers cannot change the allowed tables")
+	_, err = db.DeleteExpiredInChunks(ctx, "agent_stats", now, 0)
+	require.ErrorContains(t, err, "chunk size must be positive")
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = db.DeleteExpiredInChunks(canceled, "agent_stats", now.Add(time.Hour), 2)
+	require.ErrorIs(t, err, context.Canceled)
+}
+
+func insertAgentStat(t *testing.T, db database.Store, createdAt time.Time) database.AgentStat {
+	t.Helper()
+	stat, err := db.InsertAgentStat(context.Background(), database.InsertAgentStatParams{
+		ID:          uuid.New(),
+		CreatedAt:   createdAt,
+		UserID:      uuid.New(),
+		AgentID:     uuid.New(),
+		WorkspaceID: uuid.New(),
+		TemplateID:  uuid.New(),
+		Payload:     []byte("{}"),
+	})
+	require.NoError(t, err)
+	return stat
+}
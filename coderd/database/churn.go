@@ -0,0 +1,99 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"cdr.dev/slog"
+)
+
+// ChurnOption configures MonitorConnectionChurn.
+type ChurnOption func(*churnMonitor)
+
+// WithChurnInterval sets how often pool statistics are sampled. It defaults
+// to one minute.
+func WithChurnInterval(interval time.Duration) ChurnOption {
+	return func(m *churnMonitor) {
+		m.interval = interval
+	}
+}
+
+// WithChurnThreshold sets the rate, in connections per second closed by the
+// idle limit, above which a warning is logged. It defaults to 5.
+func WithChurnThreshold(perSecond float64) ChurnOption {
+	return func(m *churnMonitor) {
+		m.threshold = perSecond
+	}
+}
+
+type churnMonitor struct {
+	sdb       *sql.DB
+	logger    slog.Logger
+	interval  time.Duration
+	threshold float64
+}
+
+// MonitorConnectionChurn samples sdb's pool statistics and logs a warning
+// when connections are being closed by the MaxIdleConns limit faster than the
+// threshold. Each such close means a connection was dialed only to be thrown
+// away when it was returned, so a high rate indicates the idle limit set by
+// New is too low for the steady number of connections in use. The monitor
+// runs until ctx is done or the returned function is called, which waits for
+// it to exit.
+func MonitorConnectionChurn(ctx context.Context, sdb *sql.DB, logger slog.Logger, opts ...ChurnOption) func() {
+	m := &churnMonitor{
+		sdb:       sdb,
+		logger:    logger,
+		interval:  time.Minute,
+		threshold: 5,
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		m.run(ctx)
+	}()
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+func (m *churnMonitor) run(ctx context.Context) {
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	last, lastAt := m.sdb.Stats(), time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		stats, now := m.sdb.Stats(), time.Now()
+		m.check(ctx, last, stats, now.Sub(lastAt))
+		last, lastAt = stats, now
+	}
+}
+
+func (m *churnMonitor) check(ctx context.Context, last, stats sql.DBStats, elapsed time.Duration) {
+	if elapsed <= 0 {
+		return
+	}
+	closed := stats.MaxIdleClosed - last.MaxIdleClosed
+	rate := float64(closed) / elapsed.Seconds()
+	if rate < m.threshold {
+		return
+	}
+	m.logger.Warn(ctx, "high database connection churn, consider raising MaxIdleConns",
+		slog.F("idle_closed_per_second", rate),
+		slog.F("in_use", stats.InUse),
+		slog.F("idle", stats.Idle),
+		slog.F("open", stats.OpenConnections),
+	)
+}
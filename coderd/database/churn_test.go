@@ -0,0 +1,59 @@
+package database_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"cdr.dev/slog"
+
+	"github.com/coder/coder/coderd/database"
+)
+
+func TestMonitorConnectionChurn(t *testing.T) {
+	t.Parallel()
+
+	sqlDB, _ := newRecordingDB()
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	// New caps idle connections at 3.
+	_ = database.New(sqlDB)
+	sink := &captureSink{}
+	stop := database.MonitorConnectionChurn(context.Background(), sqlDB, slog.Make(sink),
+		database.WithChurnInterval(10*time.Millisecond), database.WithChurnThreshold(1))
+	t.Cleanup(stop)
+
+	require.Eventually(t, func() bool {
+		// Hold more connections than may stay idle, then return them all so
+		// the extras are closed.
+		conns := make([]*sql.Conn, 0, 10)
+		for i := 0; i < 10; i++ {
+			conn, err := sqlDB.Conn(context.Background())
+			if err != nil {
+				return false
+			}
+			conns = append(conns, conn)
+		}
+		for _, conn := range conns {
+			_ = conn.Close()
+		}
+		return len(sink.Messages()) > 0
+	}, time.Second, 20*time.Millisecond)
+	stop()
+	require.Contains(t, sink.Messages()[0], "MaxIdleConns")
+}
+
+func TestMonitorConnectionChurnQuiet(t *testing.T) {
+	t.Parallel()
+
+	sqlDB, _ := newRecordingDB()
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	sink := &captureSink{}
+	stop := database.MonitorConnectionChurn(context.Background(), sqlDB, slog.Make(sink),
+		database.WithChurnInterval(time.Millisecond))
+	time.Sleep(20 * time.Millisecond)
+	stop()
+	require.Empty(t, sink.Messages())
+}
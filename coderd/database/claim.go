@@ -0,0 +1,72 @@
+package database
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"golang.org/x/xerrors"
+)
+
+type claimQuerier interface {
+	// ClaimPendingBuilds starts the provisioner jobs of up to limit
+	// workspace builds that have not started, oldest first, assigns them
+	// to workerID and returns the builds. Jobs locked by a concurrent claim
+	// are skipped rather than waited for, so workers get disjoint batches
+	// and no build is claimed twice. Builds have no status of their own; a
+	// build is pending while its job has not been started, canceled or
+	// completed. It returns an empty slice when nothing is pending.
+	ClaimPendingBuilds(ctx context.Context, workerID uuid.UUID, limit int32) ([]WorkspaceBuild, error)
+}
+
+func (q *sqlQuerier) ClaimPendingBuilds(ctx context.Context, workerID uuid.UUID, limit int32) ([]WorkspaceBuild, error) {
+	// As in AcquireProvisionerJob, SKIP LOCKED jumps over jobs another
+	// worker is claiming. The lock and the update are one statement, so
+	// the claim is atomic without an explicit transaction.
+	const query = `-- name: ClaimPendingBuilds :many
+	WITH pending AS (
+		SELECT
+			provisioner_jobs.id
+		FROM
+			provisioner_jobs
+		JOIN
+			workspace_builds ON workspace_builds.job_id = provisioner_jobs.id
+		WHERE
+			provisioner_jobs.started_at IS NULL
+			AND provisioner_jobs.canceled_at IS NULL
+			AND provisioner_jobs.completed_at IS NULL
+		ORDER BY
+			provisioner_jobs.created_at, provisioner_jobs.id
+		LIMIT
+			$2
+		FOR UPDATE OF provisioner_jobs SKIP LOCKED
+	), claimed AS (
+		UPDATE
+			provisioner_jobs
+		SET
+			started_at = now(),
+			updated_at = now(),
+			worker_id = $1
+		FROM
+			pending
+		WHERE
+			provisioner_jobs.id = pending.id
+		RETURNING
+			provisioner_jobs.id, provisioner_jobs.created_at
+	)
+	SELECT
+		workspace_builds.*
+	FROM
+		workspace_builds
+	JOIN
+		claimed ON claimed.id = workspace_builds.job_id
+	ORDER BY
+		claimed.created_at, claimed.id
+	`
+
+	builds := []WorkspaceBuild{}
+	err := q.db.SelectContext(ctx, &builds, query, workerID, limit)
+	if err != nil {
+		return nil, xerrors.Errorf("claim pending builds: %w", err)
+	}
+	return builds, nil
+}
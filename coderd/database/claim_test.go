@@ -0,0 +1,124 @@
+//go:build linux
+
+package database_test
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/coder/coder/coderd/database"
+	"github.com/coder/coder/coderd/database/dbtestutil"
+)
+
+func TestClaimPendingBuilds(t *testing.T) {
+	t.Parallel()
+
+	db, _ := dbtestutil.NewDB(t)
+	ctx := context.Background()
+
+	user, org, template := insertTemplate(t, db)
+	version, err := db.InsertTemplateVersion(ctx, database.InsertTemplateVersionParams{
+		ID:             uuid.New(),
+		TemplateID:     uuid.NullUUID{UUID: template.ID, Valid: true},
+		OrganizationID: org.ID,
+		CreatedAt:      database.Now(),
+		UpdatedAt:      database.Now(),
+		Name:           "version",
+		JobID:          uuid.New(),
+	})
+	require.NoError(t, err)
+	workspace, err := db.InsertWorkspace(ctx, database.InsertWorkspaceParams{
+		ID:             uuid.New(),
+		CreatedAt:      database.Now(),
+		UpdatedAt:      database.Now(),
+		OwnerID:        user.ID,
+		OrganizationID: org.ID,
+		TemplateID:     template.ID,
+		Name:           "workspace",
+	})
+	require.NoError(t, err)
+
+	const builds = 20
+	createdAt := database.Now()
+	for i := 0; i < builds; i++ {
+		createdAt = createdAt.Add(time.Second)
+		job, err := db.InsertProvisionerJob(ctx, database.InsertProvisionerJobParams{
+			ID:             uuid.New(),
+			CreatedAt:      createdAt,
+			UpdatedAt:      createdAt,
+			OrganizationID: org.ID,
+			InitiatorID:    user.ID,
+			Provisioner:    database.ProvisionerTypeEcho,
+			StorageMethod:  database.ProvisionerStorageMethodFile,
+			FileID:         uuid.New(),
+			Type:           database.ProvisionerJobTypeWorkspaceBuild,
+			Input:          json.RawMessage("{}"),
+		})
+		require.NoError(t, err)
+		_, err = db.InsertWorkspaceBuild(ctx, database.InsertWorkspaceBuildParams{
+			ID:                uuid.New(),
+			CreatedAt:         createdAt,
+			UpdatedAt:         createdAt,
+			WorkspaceID:       workspace.ID,
+			TemplateVersionID: version.ID,
+			BuildNumber:       int32(i + 1),
+			Transition:        database.WorkspaceTransitionStart,
+			InitiatorID:       user.ID,
+			JobID:             job.ID,
+			Reason:            database.BuildReasonInitiator,
+		})
+		require.NoError(t, err)
+	}
+
+	// Workers claim small batches until nothing is left.
+	const workers = 4
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		claimed = map[uuid.UUID]uuid.UUID{}
+	)
+	for i := 0; i < workers; i++ {
+		workerID := uuid.New()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				batch, err := db.ClaimPendingBuilds(ctx, workerID, 3)
+				if !assert.NoError(t, err) || len(batch) == 0 {
+					return
+				}
+				assert.LessOrEqual(t, len(batch), 3)
+				mu.Lock()
+				for _, build := range batch {
+					if other, ok := claimed[build.ID]; ok {
+						t.Errorf("build %d claimed by %s and %s", build.BuildNumber, other, workerID)
+					}
+					claimed[build.ID] = workerID
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	require.Len(t, claimed, builds, "every build is claimed once")
+
+	for buildID, workerID := range claimed {
+		build, err := db.GetWorkspaceBuildByID(ctx, buildID)
+		require.NoError(t, err)
+		job, err := db.GetProvisionerJobByID(ctx, build.JobID)
+		require.NoError(t, err)
+		require.True(t, job.StartedAt.Valid)
+		require.Equal(t, uuid.NullUUID{UUID: workerID, Valid: true}, job.WorkerID)
+	}
+
+	batch, err := db.ClaimPendingBuilds(ctx, uuid.New(), 3)
+	require.NoError(t, err)
+	require.Empty(t, batch)
+}
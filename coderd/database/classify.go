@@ -0,0 +1,45 @@
+package database
+
+import "strings"
+
+// readMethods lists query methods that only read data but whose names do
+// not start with "Get".
+var readMethods = map[string]bool{
+	"CheckConstraintViolations": true,
+	"CheckEncoding":             true,
+	"CheckGroupsExist":          true,
+	"CheckRLSEnabled":           true,
+	"CheckServerVersion":        true,
+	"CheckTemplatesExist":       true,
+	"CheckUsersExist":           true,
+	"CopyOut":                   true,
+	"DBNow":                     true,
+	"DumpSchema":                true,
+	"ExplainQuery":              true,
+	"ExportAuditLogsPage":       true,
+	"FindDuplicates":            true,
+	"FindOrphanedRows":          true,
+	"ParameterValue":            true,
+	"ParameterValues":           true,
+	"ReplicationLag":            true,
+	"RunIntegrityChecks":        true,
+	"StreamCompressed":          true,
+	"StreamInto":                true,
+	"VerifySequenceOwnership":   true,
+}
+
+// lockingMethods lists query methods named like reads that take row locks,
+// which Postgres only allows in read-write transactions on a primary.
+var lockingMethods = map[string]bool{
+	"GetTemplateByIDForShare": true,
+}
+
+// isReadMethod reports whether a query method only reads data. Methods are
+// classified by name, and anything not known to be a read is treated as a
+// write so callers err on the side of caution.
+func isReadMethod(method string) bool {
+	if lockingMethods[method] {
+		return false
+	}
+	return strings.HasPrefix(method, "Get") || readMethods[method]
+}
@@ -0,0 +1,73 @@
+package database
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestReadMethodsClassified fails when a Store method is a read that
+// isReadMethod does not know about, which would make maintenance mode,
+// read-only transaction hints and hedging treat it as a write. Methods
+// named like writes are skipped, and every other write is listed here, so
+// a new method must be classified one way or the other.
+func TestReadMethodsClassified(t *testing.T) {
+	t.Parallel()
+
+	writes := map[string]bool{
+		"AcquireLease":            true,
+		"AcquireProvisionerJob":   true,
+		"ArchiveAuditLogs":        true,
+		"ClaimPendingBuilds":      true,
+		"CommitPrepared":          true,
+		"DequeueOutbox":           true,
+		"EnqueueOutbox":           true,
+		"IncrementShardedCounter": true,
+		"MarkWorkspacesInactive":  true,
+		"NextBuildNumber":         true,
+		"Notify":                  true,
+		"RenameWorkspace":         true,
+		"RenewLease":              true,
+		"ResetSequence":           true,
+		"RollbackPrepared":        true,
+		"SoftDeleteUsersByOrg":    true,
+		"TouchTokens":             true,
+		"WithMigrationLock":       true,
+		// Not queries themselves.
+		"InPreparedTx":     true,
+		"InReadTx":         true,
+		"InSavepoint":      true,
+		"InTx":             true,
+		"InTxOpts":         true,
+		"InTxReadOnlyHint": true,
+		"Ping":             true,
+		"Reconnect":        true,
+		"TxAge":            true,
+		"TxDepth":          true,
+		"TxStartTime":      true,
+		"Unwrap":           true,
+		"WithConn":         true,
+		"WithTempTable":    true,
+	}
+	writePrefixes := []string{"Insert", "Update", "Delete", "Upsert"}
+
+	store := reflect.TypeOf((*Store)(nil)).Elem()
+	for i := 0; i < store.NumMethod(); i++ {
+		method := store.Method(i).Name
+		if isReadMethod(method) || lockingMethods[method] || writes[method] {
+			continue
+		}
+		isWrite := false
+		for _, prefix := range writePrefixes {
+			isWrite = isWrite || strings.HasPrefix(method, prefix)
+		}
+		if !isWrite {
+			t.Errorf("%s is not classified: add it to readMethods in classify.go if it only reads, or to writes here", method)
+		}
+	}
+	for method := range writes {
+		if _, ok := store.MethodByName(method); !ok {
+			t.Errorf("%s is listed as a write but is not a Store method", method)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package database_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/coder/coder/coderd/database"
+)
+
+func TestWithConn(t *testing.T) {
+	t.Parallel()
+
+	sqlDB, connector := newRecordingDB()
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	db := database.New(sqlDB)
+	ctx := context.Background()
+
+	err := db.WithConn(ctx, func(conn database.Store) error {
+		err := conn.DeleteAPIKeyByID(ctx, "a")
+		require.NoError(t, err)
+		// The connection stays checked out between queries.
+		require.Equal(t, 1, sqlDB.Stats().InUse)
+
+		err = conn.InTx(func(tx database.Store) error {
+			require.Equal(t, 1, sqlDB.Stats().InUse, "transaction uses the pinned connection")
+			return tx.DeleteAPIKeyByID(ctx, "b")
+		})
+		require.NoError(t, err)
+
+		return conn.WithConn(ctx, func(nested database.Store) error {
+			require.Equal(t, 1, sqlDB.Stats().InUse, "nested calls reuse the connection")
+			return nested.DeleteAPIKeyByID(ctx, "c")
+		})
+	})
+	require.NoError(t, err)
+	require.Equal(t, 0, sqlDB.Stats().InUse, "connection is released")
+	require.Len(t, connector.Queries(), 3)
+}
@@ -0,0 +1,98 @@
+package database_test
+
+import (
+	"context"
+	"crypto/x509"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+
+	"github.com/coder/coder/coderd/database"
+)
+
+// failingConnector fails every connection attempt with err.
+type failingConnector struct {
+	err error
+}
+
+func (c failingConnector) Connect(context.Context) (driver.Conn, error) {
+	return nil, c.err
+}
+
+func (failingConnector) Driver() driver.Driver {
+	return nil
+}
+
+func TestConnectErrors(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name    string
+		err     error
+		failure database.ConnectFailure
+	}{
+		{"Refused", &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}, database.ConnectFailureDial},
+		{"DNS", &net.DNSError{Err: "no such host", Name: "postgres.invalid", IsNotFound: true}, database.ConnectFailureDial},
+		{"Password", &pq.Error{Code: "28P01", Message: "password authentication failed"}, database.ConnectFailureAuth},
+		{"Authorization", &pq.Error{Code: "28000", Message: "no pg_hba.conf entry"}, database.ConnectFailureAuth},
+		{"UnknownAuthority", x509.UnknownAuthorityError{}, database.ConnectFailureTLS},
+		{"SSLNotSupported", pq.ErrSSLNotSupported, database.ConnectFailureTLS},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			sqlDB := sql.OpenDB(database.ClassifyConnectErrors(failingConnector{err: tc.err}))
+			t.Cleanup(func() { _ = sqlDB.Close() })
+			db := database.New(sqlDB)
+
+			err := db.DeleteAPIKeyByID(context.Background(), "key")
+			require.ErrorIs(t, err, database.ErrConnectFailed)
+			var connectErr *database.ConnectError
+			require.ErrorAs(t, err, &connectErr)
+			require.Equal(t, tc.failure, connectErr.Failure)
+			require.ErrorIs(t, err, tc.err, "driver error is preserved")
+		})
+	}
+
+	t.Run("Unclassified", func(t *testing.T) {
+		t.Parallel()
+
+		// The database not existing is a failure to connect, but not one
+		// of the classified kinds.
+		sqlDB := sql.OpenDB(database.ClassifyConnectErrors(failingConnector{err: &pq.Error{Code: "3D000"}}))
+		t.Cleanup(func() { _ = sqlDB.Close() })
+		err := database.New(sqlDB).DeleteAPIKeyByID(context.Background(), "key")
+		require.Error(t, err)
+		require.NotErrorIs(t, err, database.ErrConnectFailed)
+	})
+
+	t.Run("QueryError", func(t *testing.T) {
+		t.Parallel()
+
+		sqlDB, connector := newRecordingDB()
+		t.Cleanup(func() { _ = sqlDB.Close() })
+		connector.hook = func(context.Context, string) error {
+			return &pq.Error{Code: "28000"}
+		}
+		err := database.New(sqlDB).DeleteAPIKeyByID(context.Background(), "key")
+		require.Error(t, err)
+		require.NotErrorIs(t, err, database.ErrConnectFailed, "query errors are not connect errors")
+	})
+
+	t.Run("Open", func(t *testing.T) {
+		t.Parallel()
+
+		// Nothing listens on port 1, so the dial is refused.
+		_, _, err := database.Open(context.Background(), "postgres://postgres@127.0.0.1:1/postgres?sslmode=disable")
+		require.ErrorIs(t, err, database.ErrConnectFailed)
+		var connectErr *database.ConnectError
+		require.ErrorAs(t, err, &connectErr)
+		require.Equal(t, database.ConnectFailureDial, connectErr.Failure)
+	})
+}
@@ -0,0 +1,142 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"time"
+
+	"github.com/lib/pq"
+	"golang.org/x/xerrors"
+)
+
+// WithSchema sets search_path to schema on every connection so unqualified
+// table names in generated queries resolve to it. The schema must already
+// exist.
+//
+// Session settings are applied when a connection is established, so they
+// are OpenOptions that New does not accept.
+func WithSchema(schema string) OpenOption {
+	return sessionOption(func(o *options) {
+		o.schema = schema
+	})
+}
+
+// WithTimezone sets the session TimeZone on every connection, so SQL-side
+// date arithmetic such as date_trunc('day', ...) buckets by local days
+// rather than UTC ones. Formatting timestamps in the application is
+// generally preferable; this is for operators whose SQL bucketing must
+// follow a fixed regional timezone. name must be an IANA timezone name such
+// as "Europe/Berlin". Like WithSchema, it is only accepted by Open.
+func WithTimezone(name string) OpenOption {
+	return sessionOption(func(o *options) {
+		o.timezone = name
+	})
+}
+
+// sessionOption is an OpenOption that is not an Option, so passing it to
+// New does not compile.
+type sessionOption func(*options)
+
+func (opt sessionOption) applyOpen(o *options) {
+	opt(o)
+}
+
+// validateTimezone rejects names Postgres would not recognize. "Local" is
+// rejected since it names the application host's zone, which the database
+// server knows nothing about.
+func validateTimezone(name string) error {
+	if name == "Local" {
+		return xerrors.Errorf("invalid timezone %q", name)
+	}
+	_, err := time.LoadLocation(name)
+	if err != nil {
+		return xerrors.Errorf("invalid timezone %q: %w", name, err)
+	}
+	return nil
+}
+
+// sessionInit returns the statements run on every new connection.
+func (o *options) sessionInit() []string {
+	var stmts []string
+	if o.schema != "" {
+		stmts = append(stmts, "SET search_path TO "+pq.QuoteIdentifier(o.schema))
+	}
+	if o.timezone != "" {
+		stmts = append(stmts, "SET TIME ZONE "+pq.QuoteLiteral(o.timezone))
+	}
+	return stmts
+}
+
+// openDB opens a connection pool for dsn that runs the configured session
+// initialization on every new connection.
+func (o *options) openDB(dsn string) (*sql.DB, error) {
+	if o.timezone != "" {
+		err := validateTimezone(o.timezone)
+		if err != nil {
+			return nil, err
+		}
+	}
+	connector, err := pq.NewConnector(dsn)
+	if err != nil {
+		return nil, xerrors.Errorf("create connector: %w", err)
+	}
+	classified := ClassifyConnectErrors(connector)
+	init := o.sessionInit()
+	if len(init) == 0 {
+		return sql.OpenDB(classified), nil
+	}
+	return sql.OpenDB(&initConnector{
+		Connector: classified,
+		init:      init,
+	}), nil
+}
+
+// ClassifyConnectErrors wraps c so that dial, auth and TLS failures from
+// establishing a connection are returned as *ConnectError, and match
+// ErrConnectFailed wherever a query had to open a connection first. Open
+// does this already; use it for pools passed to New that should report
+// connection failures the same way.
+func ClassifyConnectErrors(c driver.Connector) driver.Connector {
+	return &classifyingConnector{Connector: c}
+}
+
+type classifyingConnector struct {
+	driver.Connector
+}
+
+func (c *classifyingConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, classifyConnectError(err)
+	}
+	return conn, nil
+}
+
+// initConnector runs statements on each connection before it is handed to
+// the pool, so session settings apply consistently to pooled connections
+// and the transactions run on them.
+type initConnector struct {
+	driver.Connector
+	init []string
+}
+
+func (c *initConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	execer, ok := conn.(driver.ExecerContext)
+	if !ok {
+		_ = conn.Close()
+		return nil, xerrors.Errorf("driver connection %T cannot execute session statements", conn)
+	}
+	for _, stmt := range c.init {
+		_, err = execer.ExecContext(ctx, stmt, nil)
+		if err != nil {
+			_ = conn.Close()
+			return nil, xerrors.Errorf("initialize session %q: %w", stmt, err)
+		}
+	}
+	return conn, nil
+}
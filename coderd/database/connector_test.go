@@ -0,0 +1,71 @@
+//go:build linux
+
+package database_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/coder/coder/coderd/database"
+	"github.com/coder/coder/coderd/database/migrations"
+)
+
+func TestWithSchema(t *testing.T) {
+	t.Parallel()
+	if testing.Short() {
+		t.SkipNow()
+	}
+
+	ctx := context.Background()
+	dsn := testDSN(t)
+	admin := testSQLDB(t)
+	_, err := admin.ExecContext(ctx, "CREATE SCHEMA coder")
+	require.NoError(t, err)
+
+	db, sqlDB, err := database.Open(ctx, dsn, database.WithSchema("coder"))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	// Migrations run on the same pool, so they create tables in the schema.
+	err = migrations.Up(sqlDB)
+	require.NoError(t, err, "migrations")
+
+	org, err := db.InsertOrganization(ctx, database.InsertOrganizationParams{
+		ID:        uuid.New(),
+		Name:      "coder",
+		CreatedAt: database.Now(),
+		UpdatedAt: database.Now(),
+	})
+	require.NoError(t, err)
+	err = db.InTx(func(tx database.Store) error {
+		got, err := tx.GetOrganizationByID(ctx, org.ID)
+		require.NoError(t, err)
+		require.Equal(t, org.ID, got.ID)
+		return nil
+	})
+	require.NoError(t, err)
+
+	var schema string
+	err = admin.QueryRowContext(ctx, "SELECT table_schema FROM information_schema.tables WHERE table_name = 'organizations'").Scan(&schema)
+	require.NoError(t, err)
+	require.Equal(t, "coder", schema)
+}
+
+func TestWithTimezoneSession(t *testing.T) {
+	t.Parallel()
+	if testing.Short() {
+		t.SkipNow()
+	}
+
+	ctx := context.Background()
+	_, sqlDB, err := database.Open(ctx, testDSN(t), database.WithTimezone("America/New_York"))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = sqlDB.Close() })
+
+	var timezone string
+	err = sqlDB.QueryRowContext(ctx, "SHOW timezone").Scan(&timezone)
+	require.NoError(t, err)
+	require.Equal(t, "America/New_York", timezone)
+}
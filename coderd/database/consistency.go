@@ -0,0 +1,97 @@
+package database
+
+import (
+	"context"
+
+	"github.com/lib/pq"
+	"golang.org/x/xerrors"
+)
+
+// consistencyQuerier supports read-after-write consistency with a secondary
+// pool on a replica.
+type consistencyQuerier interface {
+	// GetCurrentLSN returns the primary's current WAL position. Called
+	// after a write, it is a consistency token: reads made with
+	// WithMinLSN(ctx, token) see the write. It fails on a replica.
+	GetCurrentLSN(ctx context.Context) (LSN, error)
+	// GetTxSnapshot returns the snapshot that decides which transactions
+	// the calling transaction sees, for debugging reads that saw stale
+	// data. Under the default read committed isolation every statement
+	// takes a new snapshot, so this is the snapshot of the GetTxSnapshot
+	// statement itself; under repeatable read and serializable it is the
+	// transaction's. It fails outside a transaction.
+	GetTxSnapshot(ctx context.Context) (SnapshotInfo, error)
+}
+
+// SnapshotInfo is a transaction snapshot as reported by
+// txid_current_snapshot(). Transactions below Xmin had finished when it was
+// taken and are visible if they committed, those from Xmax on had not
+// started and are invisible, and those in between are invisible only if
+// listed in InProgress.
+type SnapshotInfo struct {
+	Xmin       int64   `json:"xmin"`
+	Xmax       int64   `json:"xmax"`
+	InProgress []int64 `json:"in_progress"`
+	// TxID is the calling transaction's own ID, or zero if it has not
+	// written anything and so was not assigned one.
+	TxID int64 `json:"txid"`
+}
+
+type minLSNKey struct{}
+
+// WithMinLSN returns a context whose reads must observe every write up to
+// lsn, as returned by GetCurrentLSN, e.g. a token a client echoes back
+// after its own write. Reads routed by WithSecondaryPool go to the primary
+// pool instead while the secondary has not replayed lsn, and are not
+// hedged.
+func WithMinLSN(ctx context.Context, lsn LSN) context.Context {
+	return context.WithValue(ctx, minLSNKey{}, lsn)
+}
+
+// MinLSNFromContext returns the LSN set by WithMinLSN.
+func MinLSNFromContext(ctx context.Context) (LSN, bool) {
+	lsn, ok := ctx.Value(minLSNKey{}).(LSN)
+	return lsn, ok
+}
+
+func (q *sqlQuerier) GetCurrentLSN(ctx context.Context) (LSN, error) {
+	const query = `-- name: GetCurrentLSN :one
+	SELECT pg_current_wal_lsn()::text
+	`
+
+	var text string
+	err := q.db.GetContext(ctx, &text, query)
+	if err != nil {
+		return 0, xerrors.Errorf("get current lsn: %w", err)
+	}
+	return ParseLSN(text)
+}
+
+func (q *sqlQuerier) GetTxSnapshot(ctx context.Context) (SnapshotInfo, error) {
+	if !q.inTx {
+		return SnapshotInfo{}, xerrors.New("get tx snapshot must be called inside a transaction")
+	}
+	// The txid functions are deprecated in favor of pg_current_snapshot()
+	// from Postgres 13 on, but still work everywhere.
+	const query = `-- name: GetTxSnapshot :one
+	SELECT
+		txid_snapshot_xmin(snapshot),
+		txid_snapshot_xmax(snapshot),
+		ARRAY(SELECT txid_snapshot_xip(snapshot) ORDER BY 1),
+		COALESCE(txid_current_if_assigned(), 0)
+	FROM
+		txid_current_snapshot() AS snapshot
+	`
+
+	var snapshot SnapshotInfo
+	err := q.db.QueryRowContext(ctx, query).Scan(
+		&snapshot.Xmin,
+		&snapshot.Xmax,
+		(*pq.Int64Array)(&snapshot.InProgress),
+		&snapshot.TxID,
+	)
+	if err != nil {
+		return SnapshotInfo{}, xerrors.Errorf("get tx snapshot: %w", err)
+	}
+	return snapshot, nil
+}
@@ -0,0 +1,89 @@
+//go:build linux
+
+package database_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/coder/coder/coderd/database"
+	"github.com/coder/coder/coderd/database/migrations"
+)
+
+func TestGetTxSnapshot(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Query", func(t *testing.T) {
+		t.Parallel()
+		sqlDB, connector := newRecordingDB()
+		t.Cleanup(func() { _ = sqlDB.Close() })
+		connector.rows = func(string) ([]string, [][]driver.Value) {
+			return []string{"xmin", "xmax", "xip", "txid"}, [][]driver.Value{
+				{int64(740), int64(745), "{740,742}", int64(0)},
+			}
+		}
+		db := database.New(sqlDB)
+		ctx := context.Background()
+
+		_, err := db.GetTxSnapshot(ctx)
+		require.Error(t, err, "outside a transaction")
+		require.Empty(t, connector.Queries())
+
+		err = db.InTx(func(tx database.Store) error {
+			snapshot, err := tx.GetTxSnapshot(ctx)
+			require.NoError(t, err)
+			require.Equal(t, database.SnapshotInfo{
+				Xmin:       740,
+				Xmax:       745,
+				InProgress: []int64{740, 742},
+			}, snapshot)
+			return nil
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("Postgres", func(t *testing.T) {
+		t.Parallel()
+		if testing.Short() {
+			t.SkipNow()
+		}
+
+		sqlDB := testSQLDB(t)
+		err := migrations.Up(sqlDB)
+		require.NoError(t, err, "migrations")
+		db := database.New(sqlDB)
+		ctx := context.Background()
+
+		// A transaction that has written something and is still open is in
+		// progress for every snapshot taken meanwhile.
+		other, err := sqlDB.BeginTx(ctx, nil)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = other.Rollback() })
+		var otherID int64
+		err = other.QueryRowContext(ctx, "SELECT txid_current()").Scan(&otherID)
+		require.NoError(t, err)
+
+		err = db.InTxOpts(ctx, database.TxOptions{Isolation: sql.LevelRepeatableRead}, func(tx database.Store) error {
+			before, err := tx.GetTxSnapshot(ctx)
+			require.NoError(t, err)
+			require.LessOrEqual(t, before.Xmin, otherID)
+			require.Greater(t, before.Xmax, otherID)
+			require.Contains(t, before.InProgress, otherID)
+			require.Zero(t, before.TxID, "nothing written yet")
+
+			err = tx.IncrementShardedCounter(ctx, "snapshots", 1)
+			require.NoError(t, err)
+			after, err := tx.GetTxSnapshot(ctx)
+			require.NoError(t, err)
+			require.GreaterOrEqual(t, after.TxID, before.Xmax, "assigned by the write")
+			after.TxID = 0
+			require.Equal(t, before, after, "repeatable read keeps its snapshot")
+			return nil
+		})
+		require.NoError(t, err)
+	})
+}
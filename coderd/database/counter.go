@@ -0,0 +1,67 @@
+package database
+
+import (
+	"context"
+	"math/rand"
+
+	"golang.org/x/xerrors"
+)
+
+// counterShards is the number of rows each sharded counter is spread over.
+// Concurrent increments collide on a row about 1/counterShards as often as
+// on a single row.
+const counterShards = 16
+
+// counterQuerier maintains counters that are written far more often than
+// they are read, such as usage counters for billing.
+type counterQuerier interface {
+	// IncrementShardedCounter adds delta to the counter name, creating it
+	// if needed. delta may be negative. Each call updates one shard
+	// chosen at random, so concurrent increments rarely wait on each
+	// other's row lock.
+	IncrementShardedCounter(ctx context.Context, name string, delta int64) error
+	// GetShardedCounter returns the sum of the counter's shards, or zero
+	// if it was never incremented.
+	GetShardedCounter(ctx context.Context, name string) (int64, error)
+}
+
+func (q *sqlQuerier) IncrementShardedCounter(ctx context.Context, name string, delta int64) error {
+	if name == "" {
+		return xerrors.New("counter name must not be empty")
+	}
+	const query = `-- name: IncrementShardedCounter :exec
+	INSERT INTO
+		sharded_counters (name, shard, value)
+	VALUES
+		($1, $2, $3)
+	ON CONFLICT (name, shard) DO UPDATE SET
+		value = sharded_counters.value + EXCLUDED.value
+	`
+
+	// The shard only spreads contention, so it need not be unpredictable.
+	// nolint:gosec
+	shard := rand.Int31n(counterShards)
+	_, err := q.db.ExecContext(ctx, query, name, shard, delta)
+	if err != nil {
+		return xerrors.Errorf("increment counter %q: %w", name, err)
+	}
+	return nil
+}
+
+func (q *sqlQuerier) GetShardedCounter(ctx context.Context, name string) (int64, error) {
+	const query = `-- name: GetShardedCounter :one
+	SELECT
+		COALESCE(sum(value), 0)::bigint
+	FROM
+		sharded_counters
+	WHERE
+		name = $1
+	`
+
+	var value int64
+	err := q.db.GetContext(ctx, &value, query, name)
+	if err != nil {
+		return 0, xerrors.Errorf("get counter %q: %w", name, err)
+	}
+	return value, nil
+}
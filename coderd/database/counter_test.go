@@ -0,0 +1,55 @@
+package database_test
+
+import (
+	"context"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/coder/coder/coderd/database/dbtestutil"
+)
+
+func TestShardedCounter(t *testing.T) {
+	t.Parallel()
+
+	// Increments from many goroutines at once must never be lost.
+	db, _ := dbtestutil.NewDB(t)
+	ctx := context.Background()
+
+	value, err := db.GetShardedCounter(ctx, "workspaces")
+	require.NoError(t, err)
+	require.Zero(t, value, "missing counters are zero")
+	require.Error(t, db.IncrementShardedCounter(ctx, "", 1), "empty name")
+
+	const workers, increments = 8, 50
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < increments; j++ {
+				assert.NoError(t, db.IncrementShardedCounter(ctx, "workspaces", 2))
+			}
+		}()
+	}
+	wg.Wait()
+	require.NoError(t, db.IncrementShardedCounter(ctx, "workspaces", -5))
+	require.NoError(t, db.IncrementShardedCounter(ctx, "templates", 1))
+
+	value, err = db.GetShardedCounter(ctx, "workspaces")
+	require.NoError(t, err)
+	require.EqualValues(t, workers*increments*2-5, value)
+	value, err = db.GetShardedCounter(ctx, "templates")
+	require.NoError(t, err)
+	require.EqualValues(t, 1, value)
+
+	if os.Getenv("DB") != "" {
+		var shards int
+		err = db.Unwrap().QueryRow(`SELECT count(*) FROM sharded_counters WHERE name = 'workspaces'`).Scan(&shards)
+		require.NoError(t, err)
+		require.Greater(t, shards, 1, "increments are spread over shards")
+	}
+}
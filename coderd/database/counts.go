@@ -0,0 +1,118 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/lib/pq"
+	"golang.org/x/xerrors"
+)
+
+// Counts holds the number of live users, workspaces and templates.
+type Counts struct {
+	Users      int64 `db:"users" json:"users"`
+	Workspaces int64 `db:"workspaces" json:"workspaces"`
+	Templates  int64 `db:"templates" json:"templates"`
+}
+
+type countsQuerier interface {
+	// GetCounts returns the number of users, workspaces and templates that
+	// are not deleted, in a single round-trip.
+	GetCounts(ctx context.Context) (Counts, error)
+	// GetApproximateRowCount returns the planner's estimate of the number of
+	// rows in table, read from pg_class.reltuples. It is near-instant where
+	// count(*) must scan the table, but is only as fresh as the last
+	// ANALYZE or autovacuum run and may be off by a wide margin after bulk
+	// changes. Tables that have never been analyzed report 0. Use the exact
+	// counts when the number must be correct.
+	GetApproximateRowCount(ctx context.Context, table string) (int64, error)
+	// GetConsistentRowCounts returns the exact number of rows in each of
+	// tables, all counted in one read-only REPEATABLE READ transaction so
+	// they reflect a single point in time, as when comparing a live
+	// database with a restored backup. It starts its own transaction, so
+	// it cannot be called inside InTx.
+	GetConsistentRowCounts(ctx context.Context, tables []string) (map[string]int64, error)
+}
+
+func (q *sqlQuerier) GetCounts(ctx context.Context) (Counts, error) {
+	// Independent reads are batched as scalar subqueries of one SELECT
+	// rather than as several statements in one query string. Postgres only
+	// accepts multiple statements over the simple query protocol, which
+	// cannot carry parameters, and each result set would have to be read
+	// with NextResultSet. A single statement avoids both, keeps no session
+	// state between round-trips so it is safe behind pgbouncer in
+	// transaction pooling mode, and its subqueries share one snapshot so
+	// the counts agree with each other. Add further counts as subqueries
+	// here rather than as separate Store calls.
+	const query = `-- name: GetCounts :one
+	SELECT
+		(SELECT count(*) FROM users WHERE deleted = false) AS users,
+		(SELECT count(*) FROM workspaces WHERE deleted = false) AS workspaces,
+		(SELECT count(*) FROM templates WHERE deleted = false) AS templates
+	`
+
+	var counts Counts
+	err := q.db.GetContext(ctx, &counts, query)
+	if err != nil {
+		return Counts{}, xerrors.Errorf("get counts: %w", err)
+	}
+	return counts, nil
+}
+
+func (q *sqlQuerier) GetApproximateRowCount(ctx context.Context, table string) (int64, error) {
+	err := validateIdentifier("table", table)
+	if err != nil {
+		return 0, err
+	}
+	// reltuples is -1 until the table is first analyzed.
+	const query = `-- name: GetApproximateRowCount :one
+	SELECT
+		GREATEST(reltuples, 0)::bigint
+	FROM
+		pg_class
+	WHERE
+		oid = to_regclass($1)
+	`
+
+	var count int64
+	err = q.db.GetContext(ctx, &count, query, table)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, xerrors.Errorf("table %q does not exist", table)
+	}
+	if err != nil {
+		return 0, xerrors.Errorf("get approximate row count: %w", err)
+	}
+	return count, nil
+}
+
+func (q *sqlQuerier) GetConsistentRowCounts(ctx context.Context, tables []string) (map[string]int64, error) {
+	if q.inTx {
+		return nil, xerrors.New("get consistent row counts must not be called inside a transaction")
+	}
+	for _, table := range tables {
+		err := validateIdentifier("table", table)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	counts := make(map[string]int64, len(tables))
+	opts := TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true}
+	err := q.InTxOpts(ctx, opts, func(tx Store) error {
+		db := txQuerier(tx).db
+		for _, table := range tables {
+			var count int64
+			err := db.GetContext(ctx, &count, "-- name: GetConsistentRowCounts :one\nSELECT count(*) FROM "+pq.QuoteIdentifier(table))
+			if err != nil {
+				return xerrors.Errorf("count %s: %w", table, err)
+			}
+			counts[table] = count
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, xerrors.Errorf("get consistent row counts: %w", err)
+	}
+	return counts, nil
+}
@@ -0,0 +1,81 @@
+package database_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/coder/coder/coderd/database"
+	"github.com/coder/coder/coderd/database/databasefake"
+)
+
+func TestGetCounts(t *testing.T) {
+	t.Parallel()
+
+	t.Run("SingleRoundTrip", func(t *testing.T) {
+		t.Parallel()
+		sqlDB, connector := newRecordingDB()
+		t.Cleanup(func() { _ = sqlDB.Close() })
+		connector.rows = func(string) ([]string, [][]driver.Value) {
+			return []string{"users", "workspaces", "templates"}, [][]driver.Value{{int64(3), int64(2), int64(1)}}
+		}
+		counts, err := database.New(sqlDB).GetCounts(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, database.Counts{Users: 3, Workspaces: 2, Templates: 1}, counts)
+		require.Len(t, connector.Queries(), 1)
+	})
+
+	t.Run("Fake", func(t *testing.T) {
+		t.Parallel()
+		db := databasefake.New()
+		ctx := context.Background()
+		for _, name := range []string{"a", "b"} {
+			_, err := db.InsertUser(ctx, database.InsertUserParams{
+				ID:        uuid.New(),
+				Email:     name + "@coder.com",
+				Username:  name,
+				RBACRoles: []string{},
+			})
+			require.NoError(t, err)
+		}
+		counts, err := db.GetCounts(ctx)
+		require.NoError(t, err)
+		require.Equal(t, database.Counts{Users: 2}, counts)
+	})
+}
+
+func TestGetApproximateRowCount(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Estimate", func(t *testing.T) {
+		t.Parallel()
+		sqlDB, connector := newRecordingDB()
+		t.Cleanup(func() { _ = sqlDB.Close() })
+		connector.rows = func(string) ([]string, [][]driver.Value) {
+			return []string{"reltuples"}, [][]driver.Value{{int64(1200)}}
+		}
+		count, err := database.New(sqlDB).GetApproximateRowCount(context.Background(), "audit_logs")
+		require.NoError(t, err)
+		require.EqualValues(t, 1200, count)
+	})
+
+	t.Run("MissingTable", func(t *testing.T) {
+		t.Parallel()
+		sqlDB, _ := newRecordingDB()
+		t.Cleanup(func() { _ = sqlDB.Close() })
+		_, err := database.New(sqlDB).GetApproximateRowCount(context.Background(), "missing")
+		require.ErrorContains(t, err, "does not exist")
+	})
+
+	t.Run("InvalidName", func(t *testing.T) {
+		t.Parallel()
+		sqlDB, connector := newRecordingDB()
+		t.Cleanup(func() { _ = sqlDB.Close() })
+		_, err := database.New(sqlDB).GetApproximateRowCount(context.Background(), "users; DROP TABLE users")
+		require.Error(t, err)
+		require.Empty(t, connector.Queries())
+	})
+}
@@ -1,14 +1,18 @@
 package databasefake
 
 import (
+	"bytes"
 	"context"
 	"database/sql"
+	"encoding/json"
+	"io"
 	"sort"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
 	"github.com/lib/pq"
 	"golang.org/x/exp/maps"
 	"golang.org/x/exp/slices"
@@ -50,9 +54,12 @@ func New() database.Store {
 			templateVersions:               make([]database.TemplateVersion, 0),
 			templates:                      make([]database.Template, 0),
 			workspaceBuilds:                make([]database.WorkspaceBuild, 0),
+			workspaceIdempotencyKeys:       map[string]uuid.UUID{},
+			counters:                       map[string]int64{},
 			workspaceApps:                  make([]database.WorkspaceApp, 0),
 			workspaces:                     make([]database.Workspace, 0),
 			licenses:                       make([]database.License, 0),
+			migrationLock:                  make(chan struct{}, 1),
 		},
 	}
 }
@@ -76,6 +83,8 @@ func (inTxMutex) RUnlock() {}
 type fakeQuerier struct {
 	mutex rwMutex
 	*data
+	txDepth int
+	txStart time.Time
 }
 
 type data struct {
@@ -89,6 +98,7 @@ type data struct {
 	// New tables
 	agentStats                     []database.AgentStat
 	auditLogs                      []database.AuditLog
+	auditLogsArchive               []database.AuditLogsArchive
 	files                          []database.File
 	gitSSHKey                      []database.GitSSHKey
 	groups                         []database.Group
@@ -108,6 +118,13 @@ type data struct {
 	workspaces                     []database.Workspace
 	licenses                       []database.License
 	replicas                       []database.Replica
+	leases                         []database.Lease
+	workspaceIdempotencyKeys       map[string]uuid.UUID
+	outbox                         []database.OutboxEvent
+	counters                       map[string]int64
+	// migrationLock is a semaphore held by WithMigrationLock. The fake's
+	// mutex cannot be used, since function runs queries.
+	migrationLock chan struct{}
 
 	deploymentID  string
 	derpMeshKey   string
@@ -118,11 +135,80 @@ func (*fakeQuerier) Ping(_ context.Context) (time.Duration, error) {
 	return 0, nil
 }
 
+// Reconnect is a no-op since the fake has no connections.
+func (*fakeQuerier) Reconnect(_ context.Context) error {
+	return nil
+}
+
+// Unwrap returns nil since the fake has no pool.
+func (*fakeQuerier) Unwrap() *sqlx.DB {
+	return nil
+}
+
 // InTx doesn't rollback data properly for in-memory yet.
 func (q *fakeQuerier) InTx(fn func(database.Store) error) error {
 	q.mutex.Lock()
 	defer q.mutex.Unlock()
-	return fn(&fakeQuerier{mutex: inTxMutex{}, data: q.data})
+	start := q.txStart
+	if q.txDepth == 0 {
+		start = time.Now()
+	}
+	return fn(&fakeQuerier{mutex: inTxMutex{}, data: q.data, txDepth: q.txDepth + 1, txStart: start})
+}
+
+func (q *fakeQuerier) TxStartTime() time.Time {
+	return q.txStart
+}
+
+func (q *fakeQuerier) TxAge() time.Duration {
+	if q.txDepth == 0 {
+		return -1
+	}
+	return time.Since(q.txStart)
+}
+
+// WithConn runs fn directly since the fake has no connections.
+func (q *fakeQuerier) WithConn(_ context.Context, fn func(database.Store) error) error {
+	return fn(q)
+}
+
+func (q *fakeQuerier) WithMigrationLock(ctx context.Context, function func() error) error {
+	select {
+	case q.migrationLock <- struct{}{}:
+	case <-ctx.Done():
+		return xerrors.Errorf("acquire migration lock: %w", ctx.Err())
+	}
+	defer func() { <-q.migrationLock }()
+	return function()
+}
+
+// WithTempTable is not implemented since the fake cannot run raw SQL.
+func (*fakeQuerier) WithTempTable(_ context.Context, _ string, _ func(database.TempTableStore) error) error {
+	panic("not implemented")
+}
+
+func (q *fakeQuerier) TxDepth() int {
+	return q.txDepth
+}
+
+// InTxOpts ignores the options since the fake has no isolation or locking.
+func (q *fakeQuerier) InTxOpts(_ context.Context, _ database.TxOptions, fn func(database.Store) error) error {
+	return q.InTx(fn)
+}
+
+// InSavepoint runs fn like InTx since the fake cannot roll back changes.
+func (q *fakeQuerier) InSavepoint(_ context.Context, fn func(database.Store) error) error {
+	return q.InTx(fn)
+}
+
+func (q *fakeQuerier) InReadTx(_ context.Context, fn func(database.Store) error) error {
+	return q.InTx(fn)
+}
+
+// InTxReadOnlyHint does not reject writes, since the fake does not classify
+// its methods.
+func (q *fakeQuerier) InTxReadOnlyHint(fn func(database.Store) error) error {
+	return q.InTx(fn)
 }
 
 func (q *fakeQuerier) AcquireProvisionerJob(_ context.Context, arg database.AcquireProvisionerJobParams) (database.ProvisionerJob, error) {
@@ -152,6 +238,42 @@ func (q *fakeQuerier) AcquireProvisionerJob(_ context.Context, arg database.Acqu
 	}
 	return database.ProvisionerJob{}, sql.ErrNoRows
 }
+func (q *fakeQuerier) ClaimPendingBuilds(_ context.Context, workerID uuid.UUID, limit int32) ([]database.WorkspaceBuild, error) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	jobs := make([]int, 0)
+	for index, job := range q.provisionerJobs {
+		if !job.StartedAt.Valid && !job.CanceledAt.Valid && !job.CompletedAt.Valid {
+			jobs = append(jobs, index)
+		}
+	}
+	sort.SliceStable(jobs, func(i, j int) bool {
+		return q.provisionerJobs[jobs[i]].CreatedAt.Before(q.provisionerJobs[jobs[j]].CreatedAt)
+	})
+
+	now := database.Now()
+	builds := make([]database.WorkspaceBuild, 0)
+	for _, index := range jobs {
+		if len(builds) >= int(limit) {
+			break
+		}
+		job := q.provisionerJobs[index]
+		for _, build := range q.workspaceBuilds {
+			if build.JobID != job.ID {
+				continue
+			}
+			job.StartedAt = sql.NullTime{Time: now, Valid: true}
+			job.UpdatedAt = now
+			job.WorkerID = uuid.NullUUID{UUID: workerID, Valid: true}
+			q.provisionerJobs[index] = job
+			builds = append(builds, build)
+			break
+		}
+	}
+	return builds, nil
+}
+
 func (*fakeQuerier) DeleteOldAgentStats(_ context.Context) error {
 	// no-op
 	return nil
@@ -174,6 +296,35 @@ func (q *fakeQuerier) InsertAgentStat(_ context.Context, p database.InsertAgentS
 	return stat, nil
 }
 
+func (q *fakeQuerier) UpsertAgentStats(_ context.Context, arg []database.InsertAgentStatParams) error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	for _, p := range arg {
+		stat := database.AgentStat{
+			ID:          p.ID,
+			CreatedAt:   p.CreatedAt,
+			WorkspaceID: p.WorkspaceID,
+			AgentID:     p.AgentID,
+			UserID:      p.UserID,
+			Payload:     p.Payload,
+			TemplateID:  p.TemplateID,
+		}
+		replaced := false
+		for i, existing := range q.agentStats {
+			if existing.ID == p.ID {
+				q.agentStats[i] = stat
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			q.agentStats = append(q.agentStats, stat)
+		}
+	}
+	return nil
+}
+
 func (q *fakeQuerier) GetLatestAgentStat(_ context.Context, agentID uuid.UUID) (database.AgentStat, error) {
 	q.mutex.RLock()
 	defer q.mutex.RUnlock()
@@ -635,6 +786,10 @@ func (q *fakeQuerier) GetAuthorizedWorkspaces(ctx context.Context, arg database.
 	q.mutex.RLock()
 	defer q.mutex.RUnlock()
 
+	if arg.Status != "" && !database.IsValidWorkspaceStatus(arg.Status) {
+		return nil, xerrors.Errorf("unknown workspace status in filter: %q", arg.Status)
+	}
+
 	workspaces := make([]database.Workspace, 0)
 	for _, workspace := range q.workspaces {
 		if arg.OwnerID != uuid.Nil && workspace.OwnerID != arg.OwnerID {
@@ -674,13 +829,13 @@ func (q *fakeQuerier) GetAuthorizedWorkspaces(ctx context.Context, arg database.
 				return nil, xerrors.Errorf("get provisioner job: %w", err)
 			}
 
-			switch arg.Status {
-			case "pending":
+			switch database.WorkspaceStatus(arg.Status) {
+			case database.WorkspaceStatusPending:
 				if !job.StartedAt.Valid {
 					continue
 				}
 
-			case "starting":
+			case database.WorkspaceStatusStarting:
 				if !job.StartedAt.Valid &&
 					!job.CanceledAt.Valid &&
 					job.CompletedAt.Valid &&
@@ -689,7 +844,7 @@ func (q *fakeQuerier) GetAuthorizedWorkspaces(ctx context.Context, arg database.
 					continue
 				}
 
-			case "running":
+			case database.WorkspaceStatusRunning:
 				if !job.CompletedAt.Valid &&
 					job.CanceledAt.Valid &&
 					job.Error.Valid ||
@@ -697,7 +852,7 @@ func (q *fakeQuerier) GetAuthorizedWorkspaces(ctx context.Context, arg database.
 					continue
 				}
 
-			case "stopping":
+			case database.WorkspaceStatusStopping:
 				if !job.StartedAt.Valid &&
 					!job.CanceledAt.Valid &&
 					job.CompletedAt.Valid &&
@@ -706,7 +861,7 @@ func (q *fakeQuerier) GetAuthorizedWorkspaces(ctx context.Context, arg database.
 					continue
 				}
 
-			case "stopped":
+			case database.WorkspaceStatusStopped:
 				if !job.CompletedAt.Valid &&
 					job.CanceledAt.Valid &&
 					job.Error.Valid ||
@@ -714,23 +869,23 @@ func (q *fakeQuerier) GetAuthorizedWorkspaces(ctx context.Context, arg database.
 					continue
 				}
 
-			case "failed":
+			case database.WorkspaceStatusFailed:
 				if (!job.CanceledAt.Valid && !job.Error.Valid) ||
 					(!job.CompletedAt.Valid && !job.Error.Valid) {
 					continue
 				}
 
-			case "canceling":
+			case database.WorkspaceStatusCanceling:
 				if !job.CanceledAt.Valid && job.CompletedAt.Valid {
 					continue
 				}
 
-			case "canceled":
+			case database.WorkspaceStatusCanceled:
 				if !job.CanceledAt.Valid && !job.CompletedAt.Valid {
 					continue
 				}
 
-			case "deleted":
+			case database.WorkspaceStatusDeleted:
 				if !job.StartedAt.Valid &&
 					job.CanceledAt.Valid &&
 					!job.CompletedAt.Valid &&
@@ -739,7 +894,7 @@ func (q *fakeQuerier) GetAuthorizedWorkspaces(ctx context.Context, arg database.
 					continue
 				}
 
-			case "deleting":
+			case database.WorkspaceStatusDeleting:
 				if !job.CompletedAt.Valid &&
 					job.CanceledAt.Valid &&
 					job.Error.Valid &&
@@ -798,6 +953,10 @@ func (q *fakeQuerier) GetAuthorizedWorkspaceCount(ctx context.Context, arg datab
 	q.mutex.RLock()
 	defer q.mutex.RUnlock()
 
+	if arg.Status != "" && !database.IsValidWorkspaceStatus(arg.Status) {
+		return 0, xerrors.Errorf("unknown workspace status in filter: %q", arg.Status)
+	}
+
 	workspaces := make([]database.Workspace, 0)
 	for _, workspace := range q.workspaces {
 		if arg.OwnerID != uuid.Nil && workspace.OwnerID != arg.OwnerID {
@@ -837,13 +996,13 @@ func (q *fakeQuerier) GetAuthorizedWorkspaceCount(ctx context.Context, arg datab
 				return 0, xerrors.Errorf("get provisioner job: %w", err)
 			}
 
-			switch arg.Status {
-			case "pending":
+			switch database.WorkspaceStatus(arg.Status) {
+			case database.WorkspaceStatusPending:
 				if !job.StartedAt.Valid {
 					continue
 				}
 
-			case "starting":
+			case database.WorkspaceStatusStarting:
 				if !job.StartedAt.Valid &&
 					!job.CanceledAt.Valid &&
 					job.CompletedAt.Valid &&
@@ -852,7 +1011,7 @@ func (q *fakeQuerier) GetAuthorizedWorkspaceCount(ctx context.Context, arg datab
 					continue
 				}
 
-			case "running":
+			case database.WorkspaceStatusRunning:
 				if !job.CompletedAt.Valid &&
 					job.CanceledAt.Valid &&
 					job.Error.Valid ||
@@ -860,7 +1019,7 @@ func (q *fakeQuerier) GetAuthorizedWorkspaceCount(ctx context.Context, arg datab
 					continue
 				}
 
-			case "stopping":
+			case database.WorkspaceStatusStopping:
 				if !job.StartedAt.Valid &&
 					!job.CanceledAt.Valid &&
 					job.CompletedAt.Valid &&
@@ -869,7 +1028,7 @@ func (q *fakeQuerier) GetAuthorizedWorkspaceCount(ctx context.Context, arg datab
 					continue
 				}
 
-			case "stopped":
+			case database.WorkspaceStatusStopped:
 				if !job.CompletedAt.Valid &&
 					job.CanceledAt.Valid &&
 					job.Error.Valid ||
@@ -877,23 +1036,23 @@ func (q *fakeQuerier) GetAuthorizedWorkspaceCount(ctx context.Context, arg datab
 					continue
 				}
 
-			case "failed":
+			case database.WorkspaceStatusFailed:
 				if (!job.CanceledAt.Valid && !job.Error.Valid) ||
 					(!job.CompletedAt.Valid && !job.Error.Valid) {
 					continue
 				}
 
-			case "canceling":
+			case database.WorkspaceStatusCanceling:
 				if !job.CanceledAt.Valid && job.CompletedAt.Valid {
 					continue
 				}
 
-			case "canceled":
+			case database.WorkspaceStatusCanceled:
 				if !job.CanceledAt.Valid && !job.CompletedAt.Valid {
 					continue
 				}
 
-			case "deleted":
+			case database.WorkspaceStatusDeleted:
 				if !job.StartedAt.Valid &&
 					job.CanceledAt.Valid &&
 					!job.CompletedAt.Valid &&
@@ -902,7 +1061,7 @@ func (q *fakeQuerier) GetAuthorizedWorkspaceCount(ctx context.Context, arg datab
 					continue
 				}
 
-			case "deleting":
+			case database.WorkspaceStatusDeleting:
 				if !job.CompletedAt.Valid &&
 					job.CanceledAt.Valid &&
 					job.Error.Valid &&
@@ -1355,6 +1514,12 @@ func (q *fakeQuerier) GetTemplateByID(_ context.Context, id uuid.UUID) (database
 	return database.Template{}, sql.ErrNoRows
 }
 
+// GetTemplateByIDForShare takes no lock, since the fake's mutex serializes
+// every call.
+func (q *fakeQuerier) GetTemplateByIDForShare(ctx context.Context, id uuid.UUID) (database.Template, error) {
+	return q.GetTemplateByID(ctx, id)
+}
+
 func (q *fakeQuerier) GetTemplateByOrganizationAndName(_ context.Context, arg database.GetTemplateByOrganizationAndNameParams) (database.Template, error) {
 	q.mutex.RLock()
 	defer q.mutex.RUnlock()
@@ -2371,6 +2536,36 @@ func (q *fakeQuerier) InsertUser(_ context.Context, arg database.InsertUserParam
 	return user, nil
 }
 
+func (q *fakeQuerier) InsertOrGetUser(_ context.Context, arg database.InsertUserParams) (bool, database.User, error) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	for _, user := range q.users {
+		if strings.EqualFold(user.Email, arg.Email) && !user.Deleted {
+			return false, user, nil
+		}
+	}
+	for _, user := range q.users {
+		if strings.EqualFold(user.Username, arg.Username) && !user.Deleted {
+			return false, database.User{}, errDuplicateKey
+		}
+	}
+
+	user := database.User{
+		ID:             arg.ID,
+		Email:          arg.Email,
+		HashedPassword: arg.HashedPassword,
+		CreatedAt:      arg.CreatedAt,
+		UpdatedAt:      arg.UpdatedAt,
+		Username:       arg.Username,
+		Status:         database.UserStatusActive,
+		RBACRoles:      arg.RBACRoles,
+		LoginType:      arg.LoginType,
+	}
+	q.users = append(q.users, user)
+	return true, user, nil
+}
+
 func (q *fakeQuerier) UpdateUserRoles(_ context.Context, arg database.UpdateUserRolesParams) (database.User, error) {
 	q.mutex.Lock()
 	defer q.mutex.Unlock()
@@ -2418,6 +2613,25 @@ func (q *fakeQuerier) UpdateUserProfile(_ context.Context, arg database.UpdateUs
 	return database.User{}, sql.ErrNoRows
 }
 
+func (q *fakeQuerier) UpdateUserAudited(_ context.Context, arg database.UpdateUserProfileParams) (database.User, database.User, error) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	for index, user := range q.users {
+		if user.ID != arg.ID {
+			continue
+		}
+		before := user
+		user.Email = arg.Email
+		user.Username = arg.Username
+		user.AvatarURL = arg.AvatarURL
+		user.UpdatedAt = arg.UpdatedAt
+		q.users[index] = user
+		return before, user, nil
+	}
+	return database.User{}, database.User{}, sql.ErrNoRows
+}
+
 func (q *fakeQuerier) UpdateUserStatus(_ context.Context, arg database.UpdateUserStatusParams) (database.User, error) {
 	q.mutex.Lock()
 	defer q.mutex.Unlock()
@@ -2485,6 +2699,133 @@ func (q *fakeQuerier) InsertWorkspace(_ context.Context, arg database.InsertWork
 	return workspace, nil
 }
 
+func (q *fakeQuerier) InsertWorkspaceIdempotent(ctx context.Context, idempotencyKey string, arg database.InsertWorkspaceParams) (database.Workspace, bool, error) {
+	if idempotencyKey == "" {
+		return database.Workspace{}, false, xerrors.New("idempotency key must not be empty")
+	}
+	var (
+		workspace database.Workspace
+		created   bool
+	)
+	err := q.InTx(func(tx database.Store) error {
+		var err error
+		if id, ok := q.workspaceIdempotencyKeys[idempotencyKey]; ok {
+			workspace, err = tx.GetWorkspaceByID(ctx, id)
+			return err
+		}
+		workspace, err = tx.InsertWorkspace(ctx, arg)
+		if err != nil {
+			return err
+		}
+		q.workspaceIdempotencyKeys[idempotencyKey] = workspace.ID
+		created = true
+		return nil
+	})
+	return workspace, created, err
+}
+
+func (q *fakeQuerier) EnqueueOutbox(_ context.Context, topic string, payload json.RawMessage) (database.OutboxEvent, error) {
+	if topic == "" {
+		return database.OutboxEvent{}, xerrors.New("outbox topic must not be empty")
+	}
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	event := database.OutboxEvent{
+		ID:        uuid.New(),
+		Topic:     topic,
+		Payload:   payload,
+		CreatedAt: database.Now(),
+	}
+	q.outbox = append(q.outbox, event)
+	return event, nil
+}
+
+// DequeueOutbox returns events in the order they were enqueued. The fake
+// cannot roll back, so events dequeued in a failed transaction stay
+// published.
+func (q *fakeQuerier) DequeueOutbox(_ context.Context, limit int32) ([]database.OutboxEvent, error) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	events := []database.OutboxEvent{}
+	for i, event := range q.outbox {
+		if len(events) >= int(limit) {
+			break
+		}
+		if event.PublishedAt.Valid {
+			continue
+		}
+		event.PublishedAt = sql.NullTime{Time: database.Now(), Valid: true}
+		q.outbox[i] = event
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// IncrementShardedCounter keeps a single value per counter, since the
+// fake's mutex serializes every call anyway.
+func (q *fakeQuerier) IncrementShardedCounter(_ context.Context, name string, delta int64) error {
+	if name == "" {
+		return xerrors.New("counter name must not be empty")
+	}
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	q.counters[name] += delta
+	return nil
+}
+
+func (q *fakeQuerier) GetShardedCounter(_ context.Context, name string) (int64, error) {
+	q.mutex.RLock()
+	defer q.mutex.RUnlock()
+
+	return q.counters[name], nil
+}
+
+func (q *fakeQuerier) InsertWorkspaceWithQuota(_ context.Context, orgID uuid.UUID, max int, arg database.InsertWorkspaceParams) (database.Workspace, error) {
+	if arg.OrganizationID != orgID {
+		return database.Workspace{}, xerrors.Errorf("workspace belongs to organization %s, not %s", arg.OrganizationID, orgID)
+	}
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	found := false
+	for _, organization := range q.organizations {
+		if organization.ID == orgID {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return database.Workspace{}, sql.ErrNoRows
+	}
+	count := 0
+	for _, workspace := range q.workspaces {
+		if workspace.OrganizationID == orgID && !workspace.Deleted {
+			count++
+		}
+	}
+	if count >= max {
+		return database.Workspace{}, xerrors.Errorf("%w: organization has %d workspaces", database.ErrQuotaExceeded, max)
+	}
+
+	//nolint:gosimple
+	workspace := database.Workspace{
+		ID:                arg.ID,
+		CreatedAt:         arg.CreatedAt,
+		UpdatedAt:         arg.UpdatedAt,
+		OwnerID:           arg.OwnerID,
+		OrganizationID:    arg.OrganizationID,
+		TemplateID:        arg.TemplateID,
+		Name:              arg.Name,
+		AutostartSchedule: arg.AutostartSchedule,
+		Ttl:               arg.Ttl,
+	}
+	q.workspaces = append(q.workspaces, workspace)
+	return workspace, nil
+}
+
 func (q *fakeQuerier) InsertWorkspaceBuild(_ context.Context, arg database.InsertWorkspaceBuildParams) (database.WorkspaceBuild, error) {
 	q.mutex.Lock()
 	defer q.mutex.Unlock()
@@ -2758,7 +3099,7 @@ func (q *fakeQuerier) UpdateWorkspace(_ context.Context, arg database.UpdateWork
 			if other.Deleted || other.ID == workspace.ID || workspace.OwnerID != other.OwnerID {
 				continue
 			}
-			if other.Name == arg.Name {
+			if strings.EqualFold(other.Name, arg.Name) {
 				return database.Workspace{}, errDuplicateKey
 			}
 		}
@@ -2772,6 +3113,14 @@ func (q *fakeQuerier) UpdateWorkspace(_ context.Context, arg database.UpdateWork
 	return database.Workspace{}, sql.ErrNoRows
 }
 
+func (q *fakeQuerier) RenameWorkspace(ctx context.Context, id uuid.UUID, newName string) error {
+	_, err := q.UpdateWorkspace(ctx, database.UpdateWorkspaceParams{ID: id, Name: newName})
+	if xerrors.Is(err, errDuplicateKey) {
+		return xerrors.Errorf("rename workspace to %q: %w", newName, database.ErrNameTaken)
+	}
+	return err
+}
+
 func (q *fakeQuerier) UpdateWorkspaceAutostart(_ context.Context, arg database.UpdateWorkspaceAutostartParams) error {
 	q.mutex.Lock()
 	defer q.mutex.Unlock()
@@ -2804,6 +3153,32 @@ func (q *fakeQuerier) UpdateWorkspaceTTL(_ context.Context, arg database.UpdateW
 	return sql.ErrNoRows
 }
 
+func (q *fakeQuerier) UpdateWorkspaceTTLs(_ context.Context, ids []uuid.UUID, ttls []sql.NullInt64) error {
+	if len(ids) != len(ttls) {
+		return xerrors.Errorf("got %d ids but %d values", len(ids), len(ttls))
+	}
+	byID := make(map[uuid.UUID]sql.NullInt64, len(ids))
+	for i, id := range ids {
+		if _, ok := byID[id]; ok {
+			return xerrors.Errorf("id %s appears more than once", id)
+		}
+		byID[id] = ttls[i]
+	}
+
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	for index, workspace := range q.workspaces {
+		ttl, ok := byID[workspace.ID]
+		if !ok {
+			continue
+		}
+		workspace.Ttl = ttl
+		q.workspaces[index] = workspace
+	}
+	return nil
+}
+
 func (q *fakeQuerier) UpdateWorkspaceLastUsedAt(_ context.Context, arg database.UpdateWorkspaceLastUsedAtParams) error {
 	q.mutex.Lock()
 	defer q.mutex.Unlock()
@@ -2813,6 +3188,7 @@ func (q *fakeQuerier) UpdateWorkspaceLastUsedAt(_ context.Context, arg database.
 			continue
 		}
 		workspace.LastUsedAt = arg.LastUsedAt
+		workspace.DormantAt = sql.NullTime{}
 		q.workspaces[index] = workspace
 		return nil
 	}
@@ -2965,8 +3341,10 @@ func (q *fakeQuerier) GetAuditLogsOffset(ctx context.Context, arg database.GetAu
 
 	logs := make([]database.GetAuditLogsOffsetRow, 0, arg.Limit)
 
-	// q.auditLogs are already sorted by time DESC, so no need to sort after the fact.
-	for _, alog := range q.auditLogs {
+	// q.auditLogs is kept sorted by time and then ID, so walking it
+	// backwards matches the query's "time" DESC, id DESC.
+	for i := len(q.auditLogs) - 1; i >= 0; i-- {
+		alog := q.auditLogs[i]
 		if arg.Offset > 0 {
 			arg.Offset--
 			continue
@@ -3069,6 +3447,9 @@ func (q *fakeQuerier) InsertAuditLog(_ context.Context, arg database.InsertAudit
 
 	q.auditLogs = append(q.auditLogs, alog)
 	slices.SortFunc(q.auditLogs, func(a, b database.AuditLog) bool {
+		if a.Time.Equal(b.Time) {
+			return a.ID.String() < b.ID.String()
+		}
 		return a.Time.Before(b.Time)
 	})
 
@@ -3438,3 +3819,811 @@ func (q *fakeQuerier) GetReplicasUpdatedAfter(_ context.Context, updatedAt time.
 	}
 	return replicas, nil
 }
+
+// ReplicationLag always reports ErrNotReplica since the fake has no replicas.
+func (*fakeQuerier) ReplicationLag(_ context.Context) (time.Duration, error) {
+	return 0, database.ErrNotReplica
+}
+
+func (*fakeQuerier) DBNow(_ context.Context) (time.Time, error) {
+	return database.Now(), nil
+}
+
+func (*fakeQuerier) GetForeignKeyDependents(_ context.Context, _ string) ([]string, error) {
+	panic("not implemented")
+}
+
+func (*fakeQuerier) GetDisabledConstraints(_ context.Context) ([]database.ConstraintInfo, error) {
+	panic("not implemented")
+}
+
+func (*fakeQuerier) RunIntegrityChecks(_ context.Context, _ []database.IntegrityCheck) ([]database.IntegrityViolation, error) {
+	panic("not implemented")
+}
+
+func (*fakeQuerier) CheckRLSEnabled(_ context.Context, _ []string) ([]database.RLSStatus, error) {
+	panic("not implemented")
+}
+
+func (q *fakeQuerier) NextBuildNumber(_ context.Context, workspaceID uuid.UUID) (int32, error) {
+	q.mutex.RLock()
+	defer q.mutex.RUnlock()
+
+	found := false
+	for _, workspace := range q.workspaces {
+		if workspace.ID == workspaceID {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return 0, sql.ErrNoRows
+	}
+
+	var number int32
+	for _, workspaceBuild := range q.workspaceBuilds {
+		if workspaceBuild.WorkspaceID == workspaceID && workspaceBuild.BuildNumber > number {
+			number = workspaceBuild.BuildNumber
+		}
+	}
+	return number + 1, nil
+}
+
+// Notify is a no-op since the fake has no listeners.
+func (*fakeQuerier) Notify(_ context.Context, _, _ string) error {
+	return nil
+}
+
+func (q *fakeQuerier) CheckUsersExist(_ context.Context, ids []uuid.UUID) ([]uuid.UUID, error) {
+	q.mutex.RLock()
+	defer q.mutex.RUnlock()
+
+	missing := []uuid.UUID{}
+	for _, id := range ids {
+		found := false
+		for _, user := range q.users {
+			if user.ID == id && !user.Deleted {
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, id)
+		}
+	}
+	return missing, nil
+}
+
+func (q *fakeQuerier) CheckGroupsExist(_ context.Context, ids []uuid.UUID) ([]uuid.UUID, error) {
+	q.mutex.RLock()
+	defer q.mutex.RUnlock()
+
+	missing := []uuid.UUID{}
+	for _, id := range ids {
+		found := false
+		for _, group := range q.groups {
+			if group.ID == id {
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, id)
+		}
+	}
+	return missing, nil
+}
+
+func (q *fakeQuerier) CheckTemplatesExist(_ context.Context, ids []uuid.UUID) ([]uuid.UUID, error) {
+	q.mutex.RLock()
+	defer q.mutex.RUnlock()
+
+	missing := []uuid.UUID{}
+	for _, id := range ids {
+		found := false
+		for _, template := range q.templates {
+			if template.ID == id && !template.Deleted {
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, id)
+		}
+	}
+	return missing, nil
+}
+
+func (q *fakeQuerier) InsertWorkspaceReturningComputed(ctx context.Context, arg database.InsertWorkspaceReturningComputedParams) (database.Workspace, error) {
+	now := database.Now()
+	return q.InsertWorkspace(ctx, database.InsertWorkspaceParams{
+		ID:                arg.ID,
+		CreatedAt:         now,
+		UpdatedAt:         now,
+		OwnerID:           arg.OwnerID,
+		OrganizationID:    arg.OrganizationID,
+		TemplateID:        arg.TemplateID,
+		Name:              arg.Name,
+		AutostartSchedule: arg.AutostartSchedule,
+		Ttl:               arg.Ttl,
+	})
+}
+
+func (q *fakeQuerier) SoftDeleteUsersByOrg(_ context.Context, orgID uuid.UUID) ([]uuid.UUID, error) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	members := map[uuid.UUID]bool{}
+	elsewhere := map[uuid.UUID]bool{}
+	for _, member := range q.organizationMembers {
+		if member.OrganizationID == orgID {
+			members[member.UserID] = true
+		} else {
+			elsewhere[member.UserID] = true
+		}
+	}
+	ids := []uuid.UUID{}
+	for i, user := range q.users {
+		if !user.Deleted && members[user.ID] && !elsewhere[user.ID] {
+			user.Deleted = true
+			q.users[i] = user
+			ids = append(ids, user.ID)
+		}
+	}
+	return ids, nil
+}
+
+func (*fakeQuerier) CheckConstraintViolations(_ context.Context, _, _ string) (int64, error) {
+	panic("not implemented")
+}
+
+func (*fakeQuerier) FindDuplicates(_ context.Context, _ string, _ []string) ([]database.DuplicateGroup, error) {
+	panic("not implemented")
+}
+
+func (*fakeQuerier) FindOrphanedRows(_ context.Context, _, _, _ string) ([]uuid.UUID, error) {
+	panic("not implemented")
+}
+
+func (q *fakeQuerier) GetWorkspaceWithBuildsJSON(_ context.Context, id uuid.UUID) (database.WorkspaceWithBuilds, error) {
+	q.mutex.RLock()
+	defer q.mutex.RUnlock()
+
+	var result database.WorkspaceWithBuilds
+	found := false
+	for _, workspace := range q.workspaces {
+		if workspace.ID == id {
+			result.Workspace = workspace
+			found = true
+			break
+		}
+	}
+	if !found {
+		return database.WorkspaceWithBuilds{}, sql.ErrNoRows
+	}
+
+	result.Builds = []database.WorkspaceBuildWithResources{}
+	for _, workspaceBuild := range q.workspaceBuilds {
+		if workspaceBuild.WorkspaceID != id {
+			continue
+		}
+		workspaceBuild.ProvisionerState = nil
+		build := database.WorkspaceBuildWithResources{
+			WorkspaceBuild: workspaceBuild,
+			Resources:      []database.WorkspaceResource{},
+		}
+		for _, resource := range q.provisionerJobResources {
+			if resource.JobID == workspaceBuild.JobID {
+				build.Resources = append(build.Resources, resource)
+			}
+		}
+		sort.Slice(build.Resources, func(i, j int) bool {
+			a, b := build.Resources[i], build.Resources[j]
+			if !a.CreatedAt.Equal(b.CreatedAt) {
+				return a.CreatedAt.Before(b.CreatedAt)
+			}
+			return a.ID.String() < b.ID.String()
+		})
+		result.Builds = append(result.Builds, build)
+	}
+	sort.Slice(result.Builds, func(i, j int) bool {
+		return result.Builds[i].BuildNumber < result.Builds[j].BuildNumber
+	})
+	return result, nil
+}
+
+func (*fakeQuerier) ResetSequence(_ context.Context, _, _ string) error {
+	panic("not implemented")
+}
+
+func (q *fakeQuerier) UpdateTemplateIfVersion(_ context.Context, id uuid.UUID, expectedUpdatedAt time.Time, arg database.UpdateTemplateIfVersionParams) (bool, error) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	for idx, tpl := range q.templates {
+		if tpl.ID != id {
+			continue
+		}
+		if !tpl.UpdatedAt.Equal(expectedUpdatedAt) {
+			return false, nil
+		}
+		now := database.Now()
+		if !now.After(expectedUpdatedAt) {
+			now = expectedUpdatedAt.Add(time.Microsecond)
+		}
+		tpl.UpdatedAt = now
+		tpl.Name = arg.Name
+		tpl.Description = arg.Description
+		tpl.Icon = arg.Icon
+		tpl.MaxTtl = arg.MaxTtl
+		tpl.MinAutostartInterval = arg.MinAutostartInterval
+		q.templates[idx] = tpl
+		return true, nil
+	}
+	return false, nil
+}
+
+func (q *fakeQuerier) GetTemplateETag(ctx context.Context, id uuid.UUID) (string, error) {
+	template, err := q.GetTemplateByID(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	return database.TemplateETag(template)
+}
+
+func (q *fakeQuerier) UpdateTemplateIfMatch(ctx context.Context, id uuid.UUID, etag string, arg database.UpdateTemplateIfVersionParams) (database.Template, error) {
+	var template database.Template
+	err := q.InTx(func(tx database.Store) error {
+		current, err := tx.GetTemplateByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		currentETag, err := database.TemplateETag(current)
+		if err != nil {
+			return err
+		}
+		if currentETag != etag {
+			return database.ErrPreconditionFailed
+		}
+		_, err = tx.UpdateTemplateIfVersion(ctx, id, current.UpdatedAt, arg)
+		if err != nil {
+			return err
+		}
+		template, err = tx.GetTemplateByID(ctx, id)
+		return err
+	})
+	return template, err
+}
+
+func (*fakeQuerier) CopyOut(_ context.Context, _ string, _ io.Writer) (int64, error) {
+	panic("not implemented")
+}
+
+func (*fakeQuerier) StreamCompressed(_ context.Context, _ string, _ []interface{}, _ io.Writer, _ string) (int64, error) {
+	panic("not implemented")
+}
+
+func (*fakeQuerier) StreamInto(_ context.Context, _ string, _ []interface{}, _ chan<- database.Row) error {
+	panic("not implemented")
+}
+
+func (*fakeQuerier) ExplainQuery(_ context.Context, _ string, _ ...interface{}) (database.PlanSummary, error) {
+	panic("not implemented")
+}
+
+func (q *fakeQuerier) GetUsersByIDsOrdered(ctx context.Context, ids []uuid.UUID) ([]database.User, error) {
+	users, err := q.GetUsersByIDs(ctx, ids)
+	if err != nil && !xerrors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	ordered := make([]database.User, len(ids))
+	for i, id := range ids {
+		for _, user := range users {
+			if user.ID == id {
+				ordered[i] = user
+				break
+			}
+		}
+	}
+	return ordered, nil
+}
+
+func (q *fakeQuerier) GetProvisionerJobsByIDsOrdered(ctx context.Context, ids []uuid.UUID) ([]database.ProvisionerJob, error) {
+	jobs, err := q.GetProvisionerJobsByIDs(ctx, ids)
+	if err != nil && !xerrors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	ordered := make([]database.ProvisionerJob, len(ids))
+	for i, id := range ids {
+		for _, job := range jobs {
+			if job.ID == id {
+				ordered[i] = job
+				break
+			}
+		}
+	}
+	return ordered, nil
+}
+
+func (q *fakeQuerier) GetWorkspacesModifiedSince(_ context.Context, arg database.GetWorkspacesModifiedSinceParams) ([]database.Workspace, error) {
+	q.mutex.RLock()
+	defer q.mutex.RUnlock()
+
+	workspaces := []database.Workspace{}
+	for _, workspace := range q.workspaces {
+		if workspace.UpdatedAt.After(arg.UpdatedAt) ||
+			(workspace.UpdatedAt.Equal(arg.UpdatedAt) && workspace.ID.String() > arg.AfterID.String()) {
+			workspaces = append(workspaces, workspace)
+		}
+	}
+	sort.Slice(workspaces, func(i, j int) bool {
+		a, b := workspaces[i], workspaces[j]
+		if !a.UpdatedAt.Equal(b.UpdatedAt) {
+			return a.UpdatedAt.Before(b.UpdatedAt)
+		}
+		return a.ID.String() < b.ID.String()
+	})
+	if int(arg.Limit) < len(workspaces) {
+		workspaces = workspaces[:arg.Limit]
+	}
+	return workspaces, nil
+}
+
+// InPreparedTx is not implemented since the fake cannot keep a transaction
+// open beyond the callback.
+func (*fakeQuerier) InPreparedTx(_ context.Context, _ string, _ func(database.Store) error) error {
+	panic("not implemented")
+}
+
+func (*fakeQuerier) CommitPrepared(_ context.Context, _ string) error {
+	panic("not implemented")
+}
+
+func (*fakeQuerier) RollbackPrepared(_ context.Context, _ string) error {
+	panic("not implemented")
+}
+
+func (q *fakeQuerier) GetUsersByIDsMustFindAll(ctx context.Context, ids []uuid.UUID) ([]database.User, error) {
+	users, err := q.GetUsersByIDsOrdered(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	return users, database.MissingIDs(ids, users, func(u database.User) uuid.UUID { return u.ID })
+}
+
+func (q *fakeQuerier) GetProvisionerJobsByIDsMustFindAll(ctx context.Context, ids []uuid.UUID) ([]database.ProvisionerJob, error) {
+	jobs, err := q.GetProvisionerJobsByIDsOrdered(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	return jobs, database.MissingIDs(ids, jobs, func(j database.ProvisionerJob) uuid.UUID { return j.ID })
+}
+
+// GetTopQueriesByTime reports the extension as missing since the fake does
+// not track statements.
+func (*fakeQuerier) GetTopQueriesByTime(_ context.Context, _ int32) ([]database.QueryStat, error) {
+	return nil, database.ErrExtensionMissing{Extension: "pg_stat_statements"}
+}
+
+func (q *fakeQuerier) AcquireLease(_ context.Context, name string, owner uuid.UUID, ttl time.Duration) (bool, error) {
+	if ttl <= 0 {
+		return false, xerrors.Errorf("lease ttl must be positive, got %s", ttl)
+	}
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	now := database.Now()
+	for i, lease := range q.leases {
+		if lease.Name != name {
+			continue
+		}
+		if !lease.ExpiresAt.Before(now) && lease.OwnerID != owner {
+			return false, nil
+		}
+		q.leases[i].OwnerID = owner
+		q.leases[i].ExpiresAt = now.Add(ttl)
+		return true, nil
+	}
+	q.leases = append(q.leases, database.Lease{
+		Name:      name,
+		OwnerID:   owner,
+		ExpiresAt: now.Add(ttl),
+	})
+	return true, nil
+}
+
+func (q *fakeQuerier) RenewLease(_ context.Context, name string, owner uuid.UUID, ttl time.Duration) (bool, error) {
+	if ttl <= 0 {
+		return false, xerrors.Errorf("lease ttl must be positive, got %s", ttl)
+	}
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	now := database.Now()
+	for i, lease := range q.leases {
+		if lease.Name != name || lease.OwnerID != owner || lease.ExpiresAt.Before(now) {
+			continue
+		}
+		q.leases[i].ExpiresAt = now.Add(ttl)
+		return true, nil
+	}
+	return false, nil
+}
+
+func (q *fakeQuerier) GetCounts(_ context.Context) (database.Counts, error) {
+	q.mutex.RLock()
+	defer q.mutex.RUnlock()
+
+	var counts database.Counts
+	for _, user := range q.users {
+		if !user.Deleted {
+			counts.Users++
+		}
+	}
+	for _, workspace := range q.workspaces {
+		if !workspace.Deleted {
+			counts.Workspaces++
+		}
+	}
+	for _, template := range q.templates {
+		if !template.Deleted {
+			counts.Templates++
+		}
+	}
+	return counts, nil
+}
+
+// GetApproximateRowCount returns exact counts for the tables the fake
+// stores, since it has no statistics to estimate from.
+func (q *fakeQuerier) GetApproximateRowCount(_ context.Context, table string) (int64, error) {
+	q.mutex.RLock()
+	defer q.mutex.RUnlock()
+
+	return q.rowCount(table)
+}
+
+// GetConsistentRowCounts counts under one lock, which no transaction can
+// hold concurrently since InTx takes the same lock.
+func (q *fakeQuerier) GetConsistentRowCounts(_ context.Context, tables []string) (map[string]int64, error) {
+	if q.txDepth > 0 {
+		return nil, xerrors.New("get consistent row counts must not be called inside a transaction")
+	}
+	q.mutex.RLock()
+	defer q.mutex.RUnlock()
+
+	counts := make(map[string]int64, len(tables))
+	for _, table := range tables {
+		count, err := q.rowCount(table)
+		if err != nil {
+			return nil, err
+		}
+		counts[table] = count
+	}
+	return counts, nil
+}
+
+// rowCount returns the number of rows in one of the tables the fake
+// stores. The caller must hold the mutex.
+func (q *fakeQuerier) rowCount(table string) (int64, error) {
+	switch table {
+	case "users":
+		return int64(len(q.users)), nil
+	case "workspaces":
+		return int64(len(q.workspaces)), nil
+	case "templates":
+		return int64(len(q.templates)), nil
+	case "audit_logs":
+		return int64(len(q.auditLogs)), nil
+	default:
+		return 0, xerrors.Errorf("table %q does not exist", table)
+	}
+}
+
+// CheckEncoding always passes since Go strings compare as UTF-8.
+func (*fakeQuerier) CheckEncoding(_ context.Context) error {
+	return nil
+}
+
+func (*fakeQuerier) CheckServerVersion(_ context.Context, _ int) error {
+	return nil
+}
+
+func (*fakeQuerier) DumpSchema(_ context.Context) (string, error) {
+	panic("not implemented")
+}
+
+func (*fakeQuerier) VerifySequenceOwnership(_ context.Context) ([]database.SequenceIssue, error) {
+	panic("not implemented")
+}
+
+// InsertProvisionerJobLogsPrecise inserts directly since the fake enforces
+// no constraints on logs and cannot roll back a failed probe.
+func (q *fakeQuerier) InsertProvisionerJobLogsPrecise(ctx context.Context, arg database.InsertProvisionerJobLogsParams) ([]database.ProvisionerJobLog, error) {
+	return q.InsertProvisionerJobLogs(ctx, arg)
+}
+
+func (q *fakeQuerier) GetWorkspaceWithUserAccess(_ context.Context, workspaceID, userID uuid.UUID) (database.WorkspaceAccess, error) {
+	q.mutex.RLock()
+	defer q.mutex.RUnlock()
+
+	var (
+		workspace database.Workspace
+		user      database.User
+		found     int
+	)
+	for _, w := range q.workspaces {
+		if w.ID == workspaceID {
+			workspace = w
+			found++
+			break
+		}
+	}
+	for _, u := range q.users {
+		if u.ID == userID {
+			user = u
+			found++
+			break
+		}
+	}
+	if found != 2 {
+		return database.WorkspaceAccess{}, sql.ErrNoRows
+	}
+
+	var (
+		member   bool
+		orgRoles []string
+	)
+	for _, m := range q.organizationMembers {
+		if m.OrganizationID == workspace.OrganizationID && m.UserID == userID {
+			member = true
+			orgRoles = m.Roles
+			break
+		}
+	}
+
+	access := database.WorkspaceAccessNone
+	switch {
+	case slices.Contains(user.RBACRoles, rbac.RoleOwner()):
+		access = database.WorkspaceAccessFull
+	case workspace.OwnerID == userID && member:
+		access = database.WorkspaceAccessFull
+	case slices.Contains(orgRoles, rbac.RoleOrgAdmin(workspace.OrganizationID)):
+		access = database.WorkspaceAccessFull
+	case slices.Contains(user.RBACRoles, rbac.RoleTemplateAdmin()):
+		access = database.WorkspaceAccessRead
+	}
+	return database.WorkspaceAccess{Workspace: workspace, Access: access}, nil
+}
+
+func (q *fakeQuerier) ArchiveAuditLogs(_ context.Context, olderThan time.Time) (int64, error) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	var moved int64
+	kept := q.auditLogs[:0]
+	for _, log := range q.auditLogs {
+		if !log.Time.Before(olderThan) {
+			kept = append(kept, log)
+			continue
+		}
+		q.auditLogsArchive = append(q.auditLogsArchive, database.AuditLogsArchive(log))
+		moved++
+	}
+	q.auditLogs = kept
+	return moved, nil
+}
+
+func (q *fakeQuerier) UpdateUserHashedPasswordReturning(_ context.Context, arg database.UpdateUserHashedPasswordParams) (database.User, error) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	for i, user := range q.users {
+		if user.ID != arg.ID {
+			continue
+		}
+		user.HashedPassword = arg.HashedPassword
+		user.UpdatedAt = database.Now()
+		q.users[i] = user
+		return user, nil
+	}
+	return database.User{}, sql.ErrNoRows
+}
+
+func (q *fakeQuerier) UpdateWorkspaceAutostartReturning(_ context.Context, arg database.UpdateWorkspaceAutostartParams) (database.Workspace, error) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	for index, workspace := range q.workspaces {
+		if workspace.ID != arg.ID {
+			continue
+		}
+		workspace.AutostartSchedule = arg.AutostartSchedule
+		workspace.UpdatedAt = database.Now()
+		q.workspaces[index] = workspace
+		return workspace, nil
+	}
+	return database.Workspace{}, sql.ErrNoRows
+}
+
+func (q *fakeQuerier) UpdateWorkspaceTTLReturning(_ context.Context, arg database.UpdateWorkspaceTTLParams) (database.Workspace, error) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	for index, workspace := range q.workspaces {
+		if workspace.ID != arg.ID {
+			continue
+		}
+		workspace.Ttl = arg.Ttl
+		workspace.UpdatedAt = database.Now()
+		q.workspaces[index] = workspace
+		return workspace, nil
+	}
+	return database.Workspace{}, sql.ErrNoRows
+}
+
+func (q *fakeQuerier) MarkWorkspacesInactive(_ context.Context, olderThan time.Time) (database.WriteResult[database.Workspace], error) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	var result database.WriteResult[database.Workspace]
+	for index, workspace := range q.workspaces {
+		if workspace.Deleted || workspace.DormantAt.Valid || !workspace.AutostartSchedule.Valid || !workspace.LastUsedAt.Before(olderThan) {
+			continue
+		}
+		workspace.DormantAt = sql.NullTime{Time: database.Now(), Valid: true}
+		workspace.UpdatedAt = workspace.DormantAt.Time
+		q.workspaces[index] = workspace
+		result.Rows = append(result.Rows, workspace)
+	}
+	sort.Slice(result.Rows, func(i, j int) bool {
+		return bytes.Compare(result.Rows[i].ID[:], result.Rows[j].ID[:]) < 0
+	})
+	result.Affected = int64(len(result.Rows))
+	return result, nil
+}
+
+func (q *fakeQuerier) UpdateTemplateActiveVersionByIDReturning(_ context.Context, arg database.UpdateTemplateActiveVersionByIDParams) (database.Template, error) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	for index, template := range q.templates {
+		if template.ID != arg.ID {
+			continue
+		}
+		template.ActiveVersionID = arg.ActiveVersionID
+		template.UpdatedAt = arg.UpdatedAt
+		q.templates[index] = template
+		return template, nil
+	}
+	return database.Template{}, sql.ErrNoRows
+}
+
+func (*fakeQuerier) GetDatabaseSize(_ context.Context) (int64, error) {
+	panic("not implemented")
+}
+
+func (*fakeQuerier) GetTablespaceSizes(_ context.Context) ([]database.TablespaceSpace, error) {
+	panic("not implemented")
+}
+
+func (q *fakeQuerier) ExportAuditLogsPage(_ context.Context, cursor database.Cursor, limit int32) ([]database.AuditLog, database.Cursor, bool, error) {
+	if limit <= 0 {
+		return nil, cursor, false, xerrors.Errorf("export page limit must be positive, got %d", limit)
+	}
+	q.mutex.RLock()
+	defer q.mutex.RUnlock()
+
+	// auditLogs is kept sorted by time and then ID.
+	logs := []database.AuditLog{}
+	for _, log := range q.auditLogs {
+		if log.Time.Before(cursor.Time) || (log.Time.Equal(cursor.Time) && log.ID.String() <= cursor.ID.String()) {
+			continue
+		}
+		logs = append(logs, log)
+		if len(logs) == int(limit) {
+			break
+		}
+	}
+	next := cursor
+	if len(logs) > 0 {
+		last := logs[len(logs)-1]
+		next = database.Cursor{Time: last.Time, ID: last.ID}
+	}
+	return logs, next, len(logs) < int(limit), nil
+}
+
+func (*fakeQuerier) GetUnusedIndexes(_ context.Context) ([]database.IndexUsage, error) {
+	panic("not implemented")
+}
+
+// GetCurrentLSN returns zero since the fake has no replicas to wait for.
+func (*fakeQuerier) GetCurrentLSN(_ context.Context) (database.LSN, error) {
+	return 0, nil
+}
+
+func (*fakeQuerier) GetTxSnapshot(_ context.Context) (database.SnapshotInfo, error) {
+	panic("not implemented")
+}
+
+func (*fakeQuerier) GetTableBloat(_ context.Context) ([]database.TableBloat, error) {
+	panic("not implemented")
+}
+
+func (*fakeQuerier) GetWaitEvents(_ context.Context) ([]database.WaitEventCount, error) {
+	panic("not implemented")
+}
+
+func (*fakeQuerier) GetCacheHitRatio(_ context.Context) (float64, error) {
+	panic("not implemented")
+}
+
+func (*fakeQuerier) GetDistinctValues(_ context.Context, _, _ string) ([]string, error) {
+	panic("not implemented")
+}
+
+func (*fakeQuerier) GetTableXIDAge(_ context.Context, _ int32) ([]database.TableXIDAge, error) {
+	panic("not implemented")
+}
+
+func (q *fakeQuerier) TouchTokens(_ context.Context, ids []string, at time.Time) error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	for index, apiKey := range q.apiKeys {
+		if slices.Contains(ids, apiKey.ID) && apiKey.LastUsed.Before(at) {
+			q.apiKeys[index].LastUsed = at
+		}
+	}
+	return nil
+}
+
+func (q *fakeQuerier) DeleteExpiredInChunks(ctx context.Context, table string, olderThan time.Time, chunkSize int) (int64, error) {
+	if !slices.Contains(database.RetentionTables(), table) {
+		return 0, xerrors.Errorf("table %q does not support expiry", table)
+	}
+	if chunkSize <= 0 {
+		return 0, xerrors.Errorf("chunk size must be positive, got %d", chunkSize)
+	}
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	var deleted int64
+	switch table {
+	case "agent_stats":
+		q.agentStats, deleted = deleteExpired(q.agentStats, olderThan, func(s database.AgentStat) time.Time { return s.CreatedAt })
+	case "api_keys":
+		q.apiKeys, deleted = deleteExpired(q.apiKeys, olderThan, func(k database.APIKey) time.Time { return k.ExpiresAt })
+	case "audit_logs":
+		q.auditLogs, deleted = deleteExpired(q.auditLogs, olderThan, func(l database.AuditLog) time.Time { return l.Time })
+	case "audit_logs_archive":
+		q.auditLogsArchive, deleted = deleteExpired(q.auditLogsArchive, olderThan, func(l database.AuditLogsArchive) time.Time { return l.Time })
+	case "provisioner_job_logs":
+		q.provisionerJobLogs, deleted = deleteExpired(q.provisionerJobLogs, olderThan, func(l database.ProvisionerJobLog) time.Time { return l.CreatedAt })
+	}
+	return deleted, nil
+}
+
+// deleteExpired drops the rows expiring before olderThan and returns the
+// rest with how many were dropped.
+func deleteExpired[T any](rows []T, olderThan time.Time, expiry func(T) time.Time) ([]T, int64) {
+	kept := rows[:0]
+	for _, row := range rows {
+		if expiry(row).Before(olderThan) {
+			continue
+		}
+		kept = append(kept, row)
+	}
+	return kept, int64(len(rows) - len(kept))
+}
+
+func (*fakeQuerier) GetBlockingLocks(_ context.Context) ([]database.LockWait, error) {
+	panic("not implemented")
+}
+
+func (*fakeQuerier) GetIdleInTransaction(_ context.Context, _ time.Duration) ([]database.ConnInfo, error) {
+	panic("not implemented")
+}
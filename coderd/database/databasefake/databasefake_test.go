@@ -8,7 +8,9 @@ import (
 	"testing"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/coder/coder/coderd/database"
 
@@ -111,3 +113,31 @@ func methods(rt reflect.Type) map[string]bool {
 	}
 	return methods
 }
+
+// TestGetAuditLogsOffsetOrder ensures the fake orders audit logs like the
+// query, newest first with ties broken by descending ID.
+func TestGetAuditLogsOffsetOrder(t *testing.T) {
+	t.Parallel()
+
+	uut := databasefake.New()
+	ctx := context.Background()
+	now := database.Now()
+	ids := []uuid.UUID{
+		uuid.MustParse("00000000-0000-0000-0000-000000000001"),
+		uuid.MustParse("00000000-0000-0000-0000-000000000002"),
+		uuid.MustParse("00000000-0000-0000-0000-000000000003"),
+	}
+	times := []time.Time{now, now, now.Add(-time.Minute)}
+	for i, id := range ids {
+		_, err := uut.InsertAuditLog(ctx, database.InsertAuditLogParams{ID: id, Time: times[i]})
+		require.NoError(t, err)
+	}
+
+	logs, err := uut.GetAuditLogsOffset(ctx, database.GetAuditLogsOffsetParams{Limit: 3})
+	require.NoError(t, err)
+	got := make([]uuid.UUID, 0, len(logs))
+	for _, log := range logs {
+		got = append(got, log.ID)
+	}
+	require.Equal(t, []uuid.UUID{ids[1], ids[0], ids[2]}, got)
+}
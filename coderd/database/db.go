@@ -11,11 +11,17 @@ package database
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
 	"errors"
+	"fmt"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/jmoiron/sqlx"
 	"golang.org/x/xerrors"
+
+	"cdr.dev/slog"
 )
 
 // Store contains all queryable database functions.
@@ -26,7 +32,125 @@ type Store interface {
 	customQuerier
 
 	Ping(ctx context.Context) (time.Duration, error)
+	// Reconnect closes every idle pooled connection so that later queries
+	// connect again, e.g. to reach a new primary after a failover. See
+	// sqlQuerier.Reconnect.
+	Reconnect(ctx context.Context) error
+	// Unwrap returns the pool New was given, for integrations that need
+	// the database handle itself. It returns nil for transaction and
+	// WithConn Stores and for Stores not backed by Postgres. Queries made
+	// through it bypass every Store wrapper and option, including
+	// metrics, timeouts and maintenance mode, so it is a last resort.
+	Unwrap() *sqlx.DB
 	InTx(func(Store) error) error
+	// InTxOpts is like InTx but starts the transaction with the given
+	// options. When already inside a transaction, the outer transaction is
+	// reused and opts are ignored. The transaction is rolled back if ctx
+	// ends, so request handlers should prefer it to InTx, whose transaction
+	// outlives a client that disconnects.
+	InTxOpts(ctx context.Context, opts TxOptions, function func(Store) error) error
+	// InReadTx performs read-only database operations inside a
+	// transaction. It is shorthand for InTxOpts with ReadOnly set. Long
+	// reports that need a consistent snapshot should use InTxOpts with
+	// Deferrable instead.
+	InReadTx(ctx context.Context, function func(Store) error) error
+	// InTxReadOnlyHint is like InReadTx for callers declaring that function
+	// only reads. Write methods are rejected with ErrWriteInReadOnlyTx
+	// naming the method instead of failing in the database with a generic
+	// read-only error. Inside a transaction it runs in a savepoint so a
+	// rejected write cannot leak into the outer transaction.
+	InTxReadOnlyHint(function func(Store) error) error
+	// InSavepoint runs function inside a savepoint of the current
+	// transaction. If function returns an error, only its changes are rolled
+	// back and the outer transaction can continue. Outside a transaction it
+	// behaves like InTx.
+	InSavepoint(ctx context.Context, function func(Store) error) error
+	// TxDepth returns how many InTx calls the Store is nested in: 0 outside
+	// a transaction, 1 inside the outermost transaction, and so on.
+	TxDepth() int
+	// TxStartTime returns when the current transaction began, or the zero
+	// time outside a transaction. Nested calls report the outermost
+	// transaction.
+	TxStartTime() time.Time
+	// TxAge returns how long the current transaction has been open, or -1
+	// outside a transaction.
+	TxAge() time.Duration
+	// WithConn runs function with a Store pinned to a single pool
+	// connection, so session state such as settings or temporary tables
+	// carries across its queries without the locking of a transaction. The
+	// connection is held for the whole callback. Inside a transaction or
+	// another WithConn, the current connection is reused.
+	WithConn(ctx context.Context, function func(Store) error) error
+	// WithTempTable runs function on a pinned connection after creating a
+	// temporary table with ddl, which must be a CREATE TEMP TABLE
+	// statement, and drops the table afterwards. Only the TempTableStore
+	// passed to function can run raw SQL.
+	WithTempTable(ctx context.Context, ddl string, function func(TempTableStore) error) error
+	// WithMigrationLock runs function while holding a lock shared by every
+	// Store on the database, so that only one process migrates at a time.
+	// See sqlQuerier.WithMigrationLock.
+	WithMigrationLock(ctx context.Context, function func() error) error
+	// InPreparedTx runs function in a transaction and prepares it for
+	// two-phase commit as gid instead of committing it. See
+	// sqlQuerier.InPreparedTx for the operational caveats.
+	InPreparedTx(ctx context.Context, gid string, function func(Store) error) error
+}
+
+// TxOptions configures a transaction started by InTxOpts.
+type TxOptions struct {
+	// Isolation is the isolation level. The zero value uses the database
+	// default.
+	Isolation sql.IsolationLevel
+	ReadOnly  bool
+	// LockTimeout sets lock_timeout for the transaction so statements fail
+	// fast with ErrLockTimeout instead of waiting indefinitely for a lock
+	// (e.g. one held by a migration). Zero uses the server default.
+	LockTimeout time.Duration
+	// Deferrable makes a serializable read-only transaction wait for a safe
+	// snapshot when it starts. After that it can neither block nor fail
+	// with a serialization error, which makes it the best mode for long
+	// consistent reports. It requires Isolation to be
+	// sql.LevelSerializable and ReadOnly to be set.
+	Deferrable bool
+	// PlannerSettings are planner parameters set with SET LOCAL for the
+	// transaction only, e.g. {"enable_seqscan": "off"}, to force a better
+	// plan for a query the planner gets wrong without changing it for
+	// everything else. Names must be in plannerSettings. Like the other
+	// options, it has no effect on a transaction nested in another.
+	PlannerSettings map[string]string
+}
+
+// plannerSettings are the parameters TxOptions.PlannerSettings may set:
+// those that only influence how queries are planned, so a transaction
+// cannot change its own semantics or resource limits by accident.
+var plannerSettings = map[string]bool{
+	"cpu_index_tuple_cost":            true,
+	"cpu_operator_cost":               true,
+	"cpu_tuple_cost":                  true,
+	"effective_cache_size":            true,
+	"enable_bitmapscan":               true,
+	"enable_hashagg":                  true,
+	"enable_hashjoin":                 true,
+	"enable_indexonlyscan":            true,
+	"enable_indexscan":                true,
+	"enable_material":                 true,
+	"enable_memoize":                  true,
+	"enable_mergejoin":                true,
+	"enable_nestloop":                 true,
+	"enable_parallel_hash":            true,
+	"enable_partition_pruning":        true,
+	"enable_seqscan":                  true,
+	"enable_sort":                     true,
+	"enable_tidscan":                  true,
+	"from_collapse_limit":             true,
+	"jit":                             true,
+	"join_collapse_limit":             true,
+	"max_parallel_workers_per_gather": true,
+	"parallel_setup_cost":             true,
+	"parallel_tuple_cost":             true,
+	"plan_cache_mode":                 true,
+	"random_page_cost":                true,
+	"seq_page_cost":                   true,
 }
 
 // DBTX represents a database connection or transaction.
@@ -39,9 +163,209 @@ type DBTX interface {
 	GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
 }
 
+// Option configures a Store created by New or Open.
+type Option func(*options)
+
+// OpenOption configures a Store created by Open. Every Option is an
+// OpenOption; session settings such as WithSchema are only OpenOptions,
+// since they apply to connections as Open establishes them and New is
+// given a pool whose connections it did not create.
+type OpenOption interface {
+	applyOpen(o *options)
+}
+
+func (opt Option) applyOpen(o *options) {
+	opt(o)
+}
+
+type options struct {
+	queryRewriter   func(method, query string) string
+	connectAttempts int
+	connectBackoff  time.Duration
+	lazyConnect     bool
+	schema          string
+	timezone        string
+	logger          slog.Logger
+	readOnlyHints   bool
+	driverName      string
+	maxTxDepth      int
+	rollbackTimeout time.Duration
+	defaultTimeout  time.Duration
+	strictTx        bool
+	maxResultRows   int
+	// secondaryPool and secondaryMethods are set by WithSecondaryPool.
+	secondaryPool    *sql.DB
+	secondaryMethods map[string]bool
+	// hedgedReads and hedgeDelay are set by WithHedgedReads.
+	hedgedReads bool
+	hedgeDelay  time.Duration
+	txRetries   int
+	retryable   func(error) bool
+	// explainQueries is set by WithQueryExplain.
+	explainQueries bool
+	// burstPool, burstSize, burstWindow and burstOpts are set by
+	// WithBurstPool.
+	burstPool   *sql.DB
+	burstSize   int
+	burstWindow time.Duration
+	burstOpts   []BurstOption
+	// slowQueryThreshold and slowQueryAdvice are set by WithSlowQueryLog
+	// and WithSlowQueryAdvice. advisor explains slow reads on the pool
+	// New was given.
+	slowQueryThreshold time.Duration
+	slowQueryAdvice    bool
+	advisor            *indexAdvisor
+}
+
+// WithStrictTransactions makes InTx, InTxOpts and InReadTx return
+// ErrNestedTransaction when called inside a transaction instead of
+// silently reusing it, so an inner error cannot doom an unrelated outer
+// transaction by accident. To migrate, enable it in tests first, then
+// either pass the transaction's Store down to helpers or use InSavepoint
+// where independent rollback of the inner work is wanted.
+func WithStrictTransactions(strict bool) Option {
+	return func(o *options) {
+		o.strictTx = strict
+	}
+}
+
+// defaultRollbackTimeout bounds how long InTx waits for the deferred
+// rollback of a failed transaction.
+const defaultRollbackTimeout = 5 * time.Second
+
+// WithRollbackTimeout bounds how long InTx waits for the rollback of a
+// failed transaction, so a hung connection cannot block the caller (for
+// example, during shutdown) forever. It defaults to 5 seconds. A rollback
+// that times out keeps running in the background until the driver returns.
+func WithRollbackTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.rollbackTimeout = timeout
+	}
+}
+
+// WithMaxTxDepth makes InTx return ErrTxDepthExceeded when transactions are
+// nested more than max levels deep. This catches helpers that recursively
+// reenter a transaction path. Zero, the default, means no limit.
+func WithMaxTxDepth(max int) Option {
+	return func(o *options) {
+		o.maxTxDepth = max
+	}
+}
+
+// WithDriverName sets the driver name given to sqlx, which selects the bind
+// variable style used when rebinding queries. It defaults to "postgres".
+// Use it when sdb was opened with a Postgres-compatible driver registered
+// under another name. The generated queries use $N placeholders, so the
+// driver name must map to sqlx's DOLLAR bind type: "postgres", "pgx",
+// "pq-timeouts", "cloudsqlpostgres", "nrpostgres" and "cockroach" are known
+// to work.
+func WithDriverName(name string) Option {
+	return func(o *options) {
+		o.driverName = name
+	}
+}
+
+// WithLogger sets the logger used for diagnostics emitted by the Store.
+func WithLogger(logger slog.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// WithReadOnlyTxHints logs a debug message for each read-write transaction
+// that completes without calling any write method, suggesting InReadTx
+// instead. It adds bookkeeping to every statement in a transaction, so it
+// is intended for debugging only.
+func WithReadOnlyTxHints(enabled bool) Option {
+	return func(o *options) {
+		o.readOnlyHints = enabled
+	}
+}
+
+// WithConnectRetry makes Open retry the initial connect-and-ping up to
+// attempts times, waiting backoff between attempts. This avoids startup
+// races where Coder starts before Postgres accepts connections.
+func WithConnectRetry(attempts int, backoff time.Duration) Option {
+	return func(o *options) {
+		o.connectAttempts = attempts
+		o.connectBackoff = backoff
+	}
+}
+
+// WithEagerConnect controls whether Open verifies connectivity before
+// returning. Eager, the default, pings the database, retrying as
+// configured by WithConnectRetry, so a misconfigured DSN fails boot
+// instead of the first request, and leaves the connection idle in the
+// pool for it. Lazy skips the ping, for processes that must start while
+// the database is unreachable and can gate readiness on Store.Ping
+// instead; the first query then pays the connect cost and reports its
+// failure. New never connects.
+func WithEagerConnect(eager bool) Option {
+	return func(o *options) {
+		o.lazyConnect = !eager
+	}
+}
+
+// Open connects to the Postgres database at dsn and returns a Store along
+// with the underlying connection pool, which the caller must close. The
+// connection is verified with a ping, retrying as configured by
+// WithConnectRetry, unless WithEagerConnect(false) is given.
+func Open(ctx context.Context, dsn string, opts ...OpenOption) (Store, *sql.DB, error) {
+	var o options
+	for _, opt := range opts {
+		opt.applyOpen(&o)
+	}
+	attempts := o.connectAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	sdb, err := o.openDB(dsn)
+	if err != nil {
+		return nil, nil, xerrors.Errorf("open database: %w", err)
+	}
+	if o.lazyConnect {
+		return newStore(sdb, o), sdb, nil
+	}
+	var errs []string
+	for attempt := 1; ; attempt++ {
+		err = sdb.PingContext(ctx)
+		if err == nil {
+			break
+		}
+		errs = append(errs, err.Error())
+		if attempt >= attempts {
+			_ = sdb.Close()
+			return nil, nil, xerrors.Errorf("connect after %d attempt(s): %s: %w", attempt, strings.Join(errs, "; "), err)
+		}
+		select {
+		case <-ctx.Done():
+			_ = sdb.Close()
+			return nil, nil, xerrors.Errorf("connect: %s: %w", strings.Join(errs, "; "), ctx.Err())
+		case <-time.After(o.connectBackoff):
+		}
+	}
+	return newStore(sdb, o), sdb, nil
+}
+
 // New creates a new database store using a SQL database connection.
-func New(sdb *sql.DB) Store {
-	dbx := sqlx.NewDb(sdb, "postgres")
+func New(sdb *sql.DB, opts ...Option) Store {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return newStore(sdb, o)
+}
+
+func newStore(sdb *sql.DB, o options) Store {
+	driverName := o.driverName
+	if driverName == "" {
+		driverName = "postgres"
+	}
+	dbx := sqlx.NewDb(sdb, driverName)
+	if o.slowQueryAdvice {
+		o.advisor = newIndexAdvisor(dbx)
+	}
 
 	// The default is 0 but the request will fail with a 500 if the DB
 	// cannot accept new connections, so we try to limit that here.
@@ -59,12 +383,66 @@ func New(sdb *sql.DB) Store {
 	// later determined to be a better middle ground as to not use up all
 	// of PGs default connection limit while simultaneously avoiding a lot
 	// of connection churn.
-	dbx.SetMaxIdleConns(3)
+	dbx.SetMaxIdleConns(defaultMaxIdleConns)
+
+	db := o.wrap(dbx)
+	if o.burstPool != nil {
+		o.burstPool.SetMaxOpenConns(o.burstSize)
+		o.burstPool.SetMaxIdleConns(o.burstSize)
+		o.burstPool.SetConnMaxIdleTime(burstIdleTimeout)
+		burst := &burstDB{
+			DBTX:    db,
+			burst:   o.wrap(sqlx.NewDb(o.burstPool, driverName)),
+			sdb:     o.burstPool,
+			primary: sdb,
+			window:  o.burstWindow,
+		}
+		for _, opt := range o.burstOpts {
+			opt(burst)
+		}
+		db = burst
+	}
+	var secondary DBTX
+	if o.secondaryPool != nil {
+		secondary = o.wrap(sqlx.NewDb(o.secondaryPool, driverName))
+		db = &routingDB{DBTX: db, secondary: secondary, methods: o.secondaryMethods}
+	}
+	if o.hedgedReads {
+		// Without a second pool, the hedge at least avoids a stalled
+		// connection by taking another one from the same pool.
+		if secondary == nil {
+			secondary = o.wrap(dbx)
+		}
+		db = &hedgedDB{DBTX: db, hedge: secondary, delay: o.hedgeDelay}
+	}
+	var store Store = &sqlQuerier{
+		db:   db,
+		sdb:  dbx,
+		opts: &o,
+	}
+	if o.defaultTimeout > 0 {
+		store = Intercept(store, defaultTimeout(o.defaultTimeout))
+	}
+	return store
+}
 
-	return &sqlQuerier{
-		db:  dbx,
-		sdb: dbx,
+// defaultMaxIdleConns is the idle connection limit New sets; see New for
+// how it was chosen.
+const defaultMaxIdleConns = 3
+
+// wrap applies the configured DBTX middleware to a connection or
+// transaction.
+func (o *options) wrap(db DBTX) DBTX {
+	if o.queryRewriter != nil {
+		db = &rewriteDB{DBTX: db, rewrite: o.queryRewriter}
+	}
+	if o.maxResultRows > 0 {
+		db = &limitDB{DBTX: db, max: o.maxResultRows}
+	}
+	if o.slowQueryThreshold > 0 {
+		db = &slowDB{DBTX: db, opts: o}
 	}
+	return &cancelDB{DBTX: db}
 }
 
 // queries encompasses both are sqlc generated
@@ -75,50 +453,305 @@ type querier interface {
 }
 
 type sqlQuerier struct {
-	sdb *sqlx.DB
-	db  DBTX
+	sdb  *sqlx.DB
+	db   DBTX
+	opts *options
+	inTx bool
+	// depth is shared by every level of a transaction, so nested calls that
+	// reuse the same querier see the current nesting.
+	depth *int
+	// conn is set for Stores returned by WithConn.
+	conn *sqlx.Conn
+	// txStart is when the transaction began, if inTx.
+	txStart time.Time
+}
+
+// Unwrap returns the pool, unless q is scoped to a transaction or a
+// connection.
+func (q *sqlQuerier) Unwrap() *sqlx.DB {
+	if q.inTx || q.conn != nil {
+		return nil
+	}
+	return q.sdb
 }
 
 // Ping returns the time it takes to ping the database.
 func (q *sqlQuerier) Ping(ctx context.Context) (time.Duration, error) {
 	start := time.Now()
-	err := q.sdb.PingContext(ctx)
+	var err error
+	if q.conn != nil {
+		err = q.conn.PingContext(ctx)
+	} else {
+		err = q.sdb.PingContext(ctx)
+	}
 	return time.Since(start), err
 }
 
 // InTx performs database operations inside a transaction.
 func (q *sqlQuerier) InTx(function func(Store) error) error {
-	if _, ok := q.db.(*sqlx.Tx); ok {
+	return q.InTxOpts(context.Background(), TxOptions{}, function)
+}
+
+// inCurrentTx runs function in the current transaction, or in a new one
+// if there is none. Methods that lock rows for the rest of their work use
+// it so they can be called from a transaction even under
+// WithStrictTransactions.
+func (q *sqlQuerier) inCurrentTx(ctx context.Context, function func(Store) error) error {
+	if q.inTx {
+		return function(q)
+	}
+	return q.InTxOpts(ctx, TxOptions{}, function)
+}
+
+func (q *sqlQuerier) InSavepoint(ctx context.Context, function func(Store) error) error {
+	if !q.inTx {
+		return q.InTxOpts(ctx, TxOptions{}, function)
+	}
+	if max := q.opts.maxTxDepth; max > 0 && *q.depth >= max {
+		return xerrors.Errorf("%w: max %d", ErrTxDepthExceeded, max)
+	}
+	*q.depth++
+	defer func() { *q.depth-- }()
+
+	name := fmt.Sprintf("savepoint_%d", *q.depth)
+	_, err := q.db.ExecContext(ctx, "SAVEPOINT "+name)
+	if err != nil {
+		return xerrors.Errorf("create savepoint: %w", err)
+	}
+	err = function(q)
+	if err != nil {
+		_, rerr := q.db.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+name)
+		if rerr != nil {
+			return xerrors.Errorf("execute savepoint (rollback to savepoint: %s): %w", rerr.Error(), mapTxError(err))
+		}
+		return xerrors.Errorf("execute savepoint: %w", mapTxError(err))
+	}
+	_, err = q.db.ExecContext(ctx, "RELEASE SAVEPOINT "+name)
+	if err != nil {
+		return xerrors.Errorf("release savepoint: %w", err)
+	}
+	return nil
+}
+
+// rollback rolls back transaction, giving up after the configured rollback
+// timeout. sqlx.Tx.Rollback does not accept a context, so the rollback runs
+// in its own goroutine and is abandoned on timeout.
+func (q *sqlQuerier) rollback(transaction *sqlx.Tx) error {
+	timeout := q.opts.rollbackTimeout
+	if timeout <= 0 {
+		timeout = defaultRollbackTimeout
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- transaction.Rollback()
+	}()
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case err := <-done:
+		return err
+	case <-timer.C:
+		return xerrors.Errorf("rollback did not finish within %s", timeout)
+	}
+}
+
+func (q *sqlQuerier) WithConn(ctx context.Context, function func(Store) error) error {
+	if q.inTx || q.conn != nil {
+		return function(q)
+	}
+
+	conn, err := q.sdb.Connx(ctx)
+	if err != nil {
+		return xerrors.Errorf("acquire connection: %w", err)
+	}
+	defer conn.Close()
+
+	return function(&sqlQuerier{
+		sdb:  q.sdb,
+		db:   q.opts.wrap(conn),
+		opts: q.opts,
+		conn: conn,
+	})
+}
+
+// discardConn closes conn's underlying connection instead of returning it
+// to the pool, for sessions left in a state, such as an open transaction
+// or a held advisory lock, that the next user of the connection must not
+// inherit.
+func discardConn(conn *sqlx.Conn) {
+	_ = conn.Raw(func(interface{}) error {
+		return driver.ErrBadConn
+	})
+}
+
+// txQuerier returns the *sqlQuerier that InTx, InTxOpts, InReadTx,
+// InSavepoint and WithConn pass to their callbacks, for methods that issue
+// raw SQL on the transaction or connection.
+func txQuerier(s Store) *sqlQuerier {
+	// nolint:forcetypeassert
+	return s.(*sqlQuerier)
+}
+
+func (q *sqlQuerier) TxStartTime() time.Time {
+	return q.txStart
+}
+
+func (q *sqlQuerier) TxAge() time.Duration {
+	if !q.inTx {
+		return -1
+	}
+	return time.Since(q.txStart)
+}
+
+func (q *sqlQuerier) TxDepth() int {
+	if q.depth == nil {
+		return 0
+	}
+	return *q.depth
+}
+
+func (q *sqlQuerier) InReadTx(ctx context.Context, function func(Store) error) error {
+	return q.InTxOpts(ctx, TxOptions{ReadOnly: true}, function)
+}
+
+func (q *sqlQuerier) InTxReadOnlyHint(function func(Store) error) error {
+	guard := &writeTracker{}
+	run := func(tx Store) error {
+		guarded := *txQuerier(tx)
+		guarded.db = &readOnlyGuardDB{DBTX: guarded.db, tracker: guard}
+		err := function(&guarded)
+		// Writes issued through QueryRowContext cannot be rejected up front
+		// and are instead refused by the read-only transaction, so report
+		// any attempted write as the cause regardless of what function
+		// returned.
+		if method := guard.firstWrite(); method != "" {
+			return xerrors.Errorf("%w: %s called in InTxReadOnlyHint", ErrWriteInReadOnlyTx, method)
+		}
+		return err
+	}
+	if q.inTx {
+		return q.InSavepoint(context.Background(), run)
+	}
+	return q.InTxOpts(context.Background(), TxOptions{ReadOnly: true}, run)
+}
+
+func (q *sqlQuerier) InTxOpts(ctx context.Context, opts TxOptions, function func(Store) error) error {
+	if q.inTx {
 		// If the current inner "db" is already a transaction, we just reuse it.
 		// We do not need to handle commit/rollback as the outer tx will handle
 		// that.
+		if q.opts.strictTx {
+			return ErrNestedTransaction
+		}
+		if max := q.opts.maxTxDepth; max > 0 && *q.depth >= max {
+			return xerrors.Errorf("%w: max %d", ErrTxDepthExceeded, max)
+		}
+		*q.depth++
+		defer func() { *q.depth-- }()
 		err := function(q)
 		if err != nil {
-			return xerrors.Errorf("execute transaction: %w", err)
+			return xerrors.Errorf("execute transaction: %w", mapTxError(err))
 		}
 		return nil
 	}
 
-	transaction, err := q.sdb.BeginTxx(context.Background(), nil)
+	if opts.Deferrable && (opts.Isolation != sql.LevelSerializable || !opts.ReadOnly) {
+		return xerrors.New("deferrable transactions must be serializable and read-only")
+	}
+	for name := range opts.PlannerSettings {
+		if !plannerSettings[name] {
+			return xerrors.Errorf("%q is not a planner setting that may be set", name)
+		}
+	}
+	retryable := q.opts.retryable
+	if retryable == nil {
+		retryable = IsRetryableError
+	}
+	for attempt := 0; ; attempt++ {
+		err := q.runTx(ctx, opts, function)
+		if err == nil || attempt >= q.opts.txRetries || ctx.Err() != nil || !retryable(err) {
+			return err
+		}
+		q.opts.txLogger(ctx).Debug(ctx, "retrying transaction", slog.F("attempt", attempt+1), slog.Error(err))
+	}
+}
+
+// runTx runs function in a new transaction, once.
+func (q *sqlQuerier) runTx(ctx context.Context, opts TxOptions, function func(Store) error) error {
+	txOpts := &sql.TxOptions{
+		Isolation: opts.Isolation,
+		ReadOnly:  opts.ReadOnly,
+	}
+	var transaction *sqlx.Tx
+	var err error
+	if q.conn != nil {
+		transaction, err = q.conn.BeginTxx(ctx, txOpts)
+	} else {
+		transaction, err = q.sdb.BeginTxx(ctx, txOpts)
+	}
 	if err != nil {
 		return xerrors.Errorf("begin transaction: %w", err)
 	}
 	defer func() {
-		rerr := transaction.Rollback()
+		rerr := q.rollback(transaction)
 		if rerr == nil || errors.Is(rerr, sql.ErrTxDone) {
 			// no need to do anything, tx committed successfully
 			return
 		}
+		q.opts.txLogger(ctx).Warn(ctx, "roll back transaction", slog.Error(rerr))
 		// couldn't roll back for some reason, extend returned error
 		err = xerrors.Errorf("defer (%s): %w", rerr.Error(), err)
 	}()
-	err = function(&sqlQuerier{db: transaction})
+	if opts.Deferrable {
+		// database/sql has no deferrable option, and this must precede
+		// every other statement in the transaction.
+		_, err = transaction.ExecContext(ctx, "SET TRANSACTION DEFERRABLE")
+		if err != nil {
+			return xerrors.Errorf("set deferrable: %w", err)
+		}
+	}
+	if opts.LockTimeout > 0 {
+		_, err = transaction.ExecContext(ctx, "SELECT set_config('lock_timeout', $1, true)", fmt.Sprintf("%dms", opts.LockTimeout.Milliseconds()))
+		if err != nil {
+			return xerrors.Errorf("set lock timeout: %w", err)
+		}
+	}
+	names := make([]string, 0, len(opts.PlannerSettings))
+	for name := range opts.PlannerSettings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		_, err = transaction.ExecContext(ctx, "SELECT set_config($1, $2, true)", name, opts.PlannerSettings[name])
+		if err != nil {
+			return xerrors.Errorf("set %s: %w", name, err)
+		}
+	}
+	var txDB DBTX = transaction
+	var writes *writeTracker
+	if q.opts.readOnlyHints && !opts.ReadOnly {
+		writes = &writeTracker{}
+		txDB = &writeTrackingDB{DBTX: txDB, tracker: writes}
+	}
+	txDB = nameTx(ctx, txDB)
+	depth := 1
+	err = function(&sqlQuerier{
+		db:      q.opts.wrap(txDB),
+		opts:    q.opts,
+		inTx:    true,
+		depth:   &depth,
+		txStart: time.Now(),
+	})
 	if err != nil {
-		return xerrors.Errorf("execute transaction: %w", err)
+		return xerrors.Errorf("execute transaction: %w", mapTxError(err))
 	}
 	err = transaction.Commit()
 	if err != nil {
-		return xerrors.Errorf("commit transaction: %w", err)
+		return xerrors.Errorf("commit transaction: %w", mapTxError(err))
+	}
+	if writes != nil && !writes.wrote() {
+		q.opts.txLogger(ctx).Debug(ctx, "read-write transaction performed no writes, consider using InReadTx",
+			slog.F("methods", writes.methods()))
 	}
 	return nil
 }
@@ -6,6 +6,7 @@ import (
 	"context"
 	"database/sql"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/require"
@@ -52,16 +53,248 @@ func TestNestedInTx(t *testing.T) {
 	require.Equal(t, uid, user.ID, "user id expected")
 }
 
+func TestNewSingleUse(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Refused", func(t *testing.T) {
+		t.Parallel()
+		_, err := database.NewSingleUse(context.Background(), "postgres://postgres@127.0.0.1:1/postgres?sslmode=disable")
+		require.ErrorContains(t, err, "connect")
+	})
+
+	t.Run("Postgres", func(t *testing.T) {
+		t.Parallel()
+		if testing.Short() {
+			t.SkipNow()
+		}
+
+		// GetDeploymentID reports the backend serving it instead, to
+		// tell connections apart.
+		db, err := database.NewSingleUse(context.Background(), testDSN(t), database.WithQueryRewriter(func(method, query string) string {
+			if method == "GetDeploymentID" {
+				return "SELECT pg_backend_pid()::text"
+			}
+			return query
+		}))
+		require.NoError(t, err)
+		ctx := context.Background()
+
+		backend, err := db.GetDeploymentID(ctx)
+		require.NoError(t, err)
+		var inTx, inConn string
+		err = db.InTx(func(tx database.Store) error {
+			var err error
+			inTx, err = tx.GetDeploymentID(ctx)
+			return err
+		})
+		require.NoError(t, err)
+		err = db.WithConn(ctx, func(conn database.Store) error {
+			var err error
+			inConn, err = conn.GetDeploymentID(ctx)
+			return err
+		})
+		require.NoError(t, err)
+		again, err := db.GetDeploymentID(ctx)
+		require.NoError(t, err)
+		require.Equal(t, backend, inTx)
+		require.Equal(t, backend, inConn)
+		require.Equal(t, backend, again)
+		require.Error(t, db.Reconnect(ctx))
+		_, err = db.Ping(ctx)
+		require.NoError(t, err, "pings the connection instead of waiting for the pool")
+
+		require.NoError(t, db.Close())
+		_, err = db.GetDeploymentID(ctx)
+		require.Error(t, err, "closed")
+	})
+}
+
+func TestInTxPlannerSettings(t *testing.T) {
+	t.Parallel()
+	if testing.Short() {
+		t.SkipNow()
+	}
+
+	sqlDB := testSQLDB(t)
+	// One connection, so the setting would be visible after the
+	// transaction if it leaked out of it.
+	sqlDB.SetMaxOpenConns(1)
+	// GetDeploymentID reads the setting under test instead, since there is
+	// no query method that returns one.
+	db := database.New(sqlDB, database.WithQueryRewriter(func(method, query string) string {
+		if method == "GetDeploymentID" {
+			return "SELECT current_setting('enable_seqscan')"
+		}
+		return query
+	}))
+	ctx := context.Background()
+
+	var inside string
+	err := db.InTxOpts(ctx, database.TxOptions{PlannerSettings: map[string]string{"enable_seqscan": "off"}}, func(tx database.Store) error {
+		var err error
+		inside, err = tx.GetDeploymentID(ctx)
+		return err
+	})
+	require.NoError(t, err)
+	require.Equal(t, "off", inside)
+	after, err := db.GetDeploymentID(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "on", after, "reverted when the transaction ends")
+}
+
+func TestInTxLockTimeout(t *testing.T) {
+	t.Parallel()
+	if testing.Short() {
+		t.SkipNow()
+	}
+
+	sqlDB := testSQLDB(t)
+	err := migrations.Up(sqlDB)
+	require.NoError(t, err, "migrations")
+	db := database.New(sqlDB)
+	ctx := context.Background()
+
+	user, err := db.InsertUser(ctx, database.InsertUserParams{
+		ID:             uuid.New(),
+		Email:          "coder@coder.com",
+		Username:       "coder",
+		HashedPassword: []byte{},
+		CreatedAt:      database.Now(),
+		UpdatedAt:      database.Now(),
+		RBACRoles:      []string{},
+		LoginType:      database.LoginTypePassword,
+	})
+	require.NoError(t, err)
+	update := func(tx database.Store) error {
+		_, err := tx.UpdateUserProfile(ctx, database.UpdateUserProfileParams{
+			ID:        user.ID,
+			Email:     user.Email,
+			Username:  user.Username,
+			UpdatedAt: database.Now(),
+		})
+		return err
+	}
+
+	locked := make(chan struct{})
+	release := make(chan struct{})
+	holderDone := make(chan error, 1)
+	go func() {
+		holderDone <- db.InTx(func(tx database.Store) error {
+			err := update(tx)
+			close(locked)
+			<-release
+			return err
+		})
+	}()
+	<-locked
+
+	start := time.Now()
+	err = db.InTxOpts(ctx, database.TxOptions{LockTimeout: 50 * time.Millisecond}, update)
+	require.ErrorIs(t, err, database.ErrLockTimeout)
+	require.Less(t, time.Since(start), 5*time.Second, "should fail fast")
+
+	close(release)
+	require.NoError(t, <-holderDone)
+}
+
 func testSQLDB(t testing.TB) *sql.DB {
 	t.Helper()
 
+	db, err := sql.Open("postgres", testDSN(t))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+
+	return db
+}
+
+func testDSN(t testing.TB) string {
+	t.Helper()
+
 	connection, closeFn, err := postgres.Open()
 	require.NoError(t, err)
 	t.Cleanup(closeFn)
+	return connection
+}
 
-	db, err := sql.Open("postgres", connection)
+func TestInSavepoint(t *testing.T) {
+	t.Parallel()
+	if testing.Short() {
+		t.SkipNow()
+	}
+
+	sqlDB := testSQLDB(t)
+	err := migrations.Up(sqlDB)
+	require.NoError(t, err, "migrations")
+	db := database.New(sqlDB)
+	ctx := context.Background()
+
+	insert := func(db database.Store, name string) error {
+		_, err := db.InsertUser(ctx, database.InsertUserParams{
+			ID:             uuid.New(),
+			Email:          name + "@coder.com",
+			Username:       name,
+			HashedPassword: []byte{},
+			CreatedAt:      database.Now(),
+			UpdatedAt:      database.Now(),
+			RBACRoles:      []string{},
+			LoginType:      database.LoginTypePassword,
+		})
+		return err
+	}
+	err = db.InTx(func(tx database.Store) error {
+		err := insert(tx, "kept")
+		require.NoError(t, err)
+		err = tx.InSavepoint(ctx, func(sp database.Store) error {
+			err := insert(sp, "rolledback")
+			require.NoError(t, err)
+			// A duplicate username fails the savepoint, not the transaction.
+			return insert(sp, "rolledback")
+		})
+		require.Error(t, err)
+		return nil
+	})
 	require.NoError(t, err)
-	t.Cleanup(func() { _ = db.Close() })
 
-	return db
+	users, err := db.GetUsers(ctx, database.GetUsersParams{})
+	require.NoError(t, err)
+	require.Len(t, users, 1)
+	require.Equal(t, "kept", users[0].Username)
+}
+
+func TestDeferrableReadTx(t *testing.T) {
+	t.Parallel()
+	if testing.Short() {
+		t.SkipNow()
+	}
+
+	sqlDB := testSQLDB(t)
+	err := migrations.Up(sqlDB)
+	require.NoError(t, err, "migrations")
+	db := database.New(sqlDB)
+	ctx := context.Background()
+
+	opts := database.TxOptions{Isolation: sql.LevelSerializable, ReadOnly: true, Deferrable: true}
+	err = db.InTxOpts(ctx, opts, func(tx database.Store) error {
+		before, err := tx.GetUserCount(ctx)
+		require.NoError(t, err)
+
+		// A write committed concurrently is not visible to the report.
+		_, err = db.InsertUser(ctx, database.InsertUserParams{
+			ID:             uuid.New(),
+			Email:          "late@coder.com",
+			Username:       "late",
+			HashedPassword: []byte{},
+			CreatedAt:      database.Now(),
+			UpdatedAt:      database.Now(),
+			RBACRoles:      []string{},
+			LoginType:      database.LoginTypePassword,
+		})
+		require.NoError(t, err)
+
+		after, err := tx.GetUserCount(ctx)
+		require.NoError(t, err)
+		require.Equal(t, before, after)
+		return nil
+	})
+	require.NoError(t, err)
 }
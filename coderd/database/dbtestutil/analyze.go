@@ -0,0 +1,52 @@
+package dbtestutil
+
+import (
+	"context"
+	"database/sql"
+
+	"golang.org/x/xerrors"
+
+	"github.com/coder/coder/coderd/database"
+)
+
+// AnalyzeAll runs ANALYZE on every table in the current schema in one
+// statement, so the planner has statistics for seeded data instead of
+// guessing from empty tables. Call it after seeding and before the queries
+// under test; autovacuum gets there eventually, but not reliably within a
+// test.
+//
+// It is meant for test setup: ANALYZE does not change data and is safe on
+// any database, but it reads a sample of every table, which is slow on a
+// large one. It does nothing for the fake, which has no planner, and fails
+// in a transaction, where the Store has no connection of its own to run it
+// on.
+func AnalyzeAll(ctx context.Context, db database.Store) error {
+	sqlDB := db.Unwrap()
+	if sqlDB == nil {
+		if db.TxDepth() > 0 {
+			return xerrors.New("analyze: cannot run in a transaction")
+		}
+		return nil
+	}
+
+	var tables sql.NullString
+	err := sqlDB.QueryRowContext(ctx, `
+		SELECT
+			string_agg(quote_ident(tablename), ', ')
+		FROM
+			pg_tables
+		WHERE
+			schemaname = current_schema()
+	`).Scan(&tables)
+	if err != nil {
+		return xerrors.Errorf("list tables: %w", err)
+	}
+	if !tables.Valid {
+		return nil
+	}
+	_, err = sqlDB.ExecContext(ctx, "ANALYZE "+tables.String)
+	if err != nil {
+		return xerrors.Errorf("analyze: %w", err)
+	}
+	return nil
+}
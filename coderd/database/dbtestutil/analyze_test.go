@@ -0,0 +1,45 @@
+package dbtestutil_test
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/coder/coder/coderd/database"
+	"github.com/coder/coder/coderd/database/dbtestutil"
+)
+
+func TestAnalyzeAll(t *testing.T) {
+	t.Parallel()
+
+	db, _ := dbtestutil.NewDB(t)
+	ctx := context.Background()
+
+	_, err := db.InsertOrganization(ctx, database.InsertOrganizationParams{
+		ID:        uuid.New(),
+		Name:      "org",
+		CreatedAt: database.Now(),
+		UpdatedAt: database.Now(),
+	})
+	require.NoError(t, err)
+
+	err = dbtestutil.AnalyzeAll(ctx, db)
+	require.NoError(t, err)
+	if os.Getenv("DB") != "" {
+		// ANALYZE records the row estimate in the catalog right away, while
+		// pg_stat_user_tables may lag behind.
+		var reltuples float64
+		err = db.Unwrap().QueryRowContext(ctx,
+			"SELECT reltuples FROM pg_class WHERE oid = 'organizations'::regclass").Scan(&reltuples)
+		require.NoError(t, err)
+		require.Equal(t, float64(1), reltuples)
+	}
+
+	err = db.InTx(func(tx database.Store) error {
+		return dbtestutil.AnalyzeAll(ctx, tx)
+	})
+	require.ErrorContains(t, err, "transaction")
+}
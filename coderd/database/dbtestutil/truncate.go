@@ -0,0 +1,72 @@
+package dbtestutil
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+
+	"golang.org/x/xerrors"
+)
+
+// testDatabaseName matches the databases postgres.Open creates for CI and
+// any database whose name starts with "test".
+var testDatabaseName = regexp.MustCompile(`^(ci[a-z]{10}|test.*)$`)
+
+// TruncateOptions guards TruncateAll against running on a real database.
+type TruncateOptions struct {
+	// IKnowThisIsATestDatabase must be set, as a statement by the caller
+	// that every row in the database may be destroyed.
+	IKnowThisIsATestDatabase bool
+	// DatabaseName, if set, must match the name of the connected database
+	// instead of the default pattern, e.g. for the "postgres" database of
+	// a throwaway container.
+	DatabaseName *regexp.Regexp
+}
+
+// TruncateAll empties every table in the current schema in a single
+// TRUNCATE ... RESTART IDENTITY CASCADE, which is much faster than deleting
+// rows table by table in foreign key order. The migration version table is
+// left alone so the schema does not appear unmigrated.
+//
+// It refuses to run unless opts.IKnowThisIsATestDatabase is set and the
+// database name looks like a test database: one created by postgres.Open
+// in CI, one whose name starts with "test", or one matching opts.DatabaseName.
+func TruncateAll(ctx context.Context, db *sql.DB, opts TruncateOptions) error {
+	if !opts.IKnowThisIsATestDatabase {
+		return xerrors.New("refusing to truncate: IKnowThisIsATestDatabase is not set")
+	}
+	var name string
+	err := db.QueryRowContext(ctx, "SELECT current_database()").Scan(&name)
+	if err != nil {
+		return xerrors.Errorf("get database name: %w", err)
+	}
+	pattern := testDatabaseName
+	if opts.DatabaseName != nil {
+		pattern = opts.DatabaseName
+	}
+	if !pattern.MatchString(name) {
+		return xerrors.Errorf("refusing to truncate: database %q does not match %q", name, pattern.String())
+	}
+
+	var tables sql.NullString
+	err = db.QueryRowContext(ctx, `
+		SELECT
+			string_agg(quote_ident(tablename), ', ')
+		FROM
+			pg_tables
+		WHERE
+			schemaname = current_schema()
+			AND tablename <> 'schema_migrations'
+	`).Scan(&tables)
+	if err != nil {
+		return xerrors.Errorf("list tables: %w", err)
+	}
+	if !tables.Valid {
+		return nil
+	}
+	_, err = db.ExecContext(ctx, "TRUNCATE "+tables.String+" RESTART IDENTITY CASCADE")
+	if err != nil {
+		return xerrors.Errorf("truncate: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package dbtestutil_test
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/coder/coder/coderd/database"
+	"github.com/coder/coder/coderd/database/dbtestutil"
+	"github.com/coder/coder/coderd/database/migrations"
+	"github.com/coder/coder/coderd/database/postgres"
+)
+
+func TestTruncateAll(t *testing.T) {
+	t.Parallel()
+
+	t.Run("RequiresFlag", func(t *testing.T) {
+		t.Parallel()
+		// The flag is checked before the database is touched.
+		err := dbtestutil.TruncateAll(context.Background(), nil, dbtestutil.TruncateOptions{})
+		require.ErrorContains(t, err, "IKnowThisIsATestDatabase")
+	})
+
+	t.Run("Postgres", func(t *testing.T) {
+		t.Parallel()
+		if testing.Short() {
+			t.SkipNow()
+		}
+
+		connection, closeFn, err := postgres.Open()
+		require.NoError(t, err)
+		t.Cleanup(closeFn)
+		sqlDB, err := sql.Open("postgres", connection)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = sqlDB.Close() })
+		err = migrations.Up(sqlDB)
+		require.NoError(t, err)
+		db := database.New(sqlDB)
+		ctx := context.Background()
+
+		_, err = db.InsertOrganization(ctx, database.InsertOrganizationParams{
+			ID:        uuid.New(),
+			Name:      "org",
+			CreatedAt: database.Now(),
+			UpdatedAt: database.Now(),
+		})
+		require.NoError(t, err)
+
+		err = dbtestutil.TruncateAll(ctx, sqlDB, dbtestutil.TruncateOptions{
+			IKnowThisIsATestDatabase: true,
+			DatabaseName:             regexp.MustCompile(`^production$`),
+		})
+		require.ErrorContains(t, err, "does not match")
+
+		// Databases from a throwaway container are named "postgres".
+		err = dbtestutil.TruncateAll(ctx, sqlDB, dbtestutil.TruncateOptions{
+			IKnowThisIsATestDatabase: true,
+			DatabaseName:             regexp.MustCompile(`^(postgres|ci[a-z]{10})$`),
+		})
+		require.NoError(t, err)
+		organizations, err := db.GetOrganizations(ctx)
+		require.NoError(t, err)
+		require.Empty(t, organizations)
+
+		var version int
+		err = sqlDB.QueryRowContext(ctx, "SELECT version FROM schema_migrations").Scan(&version)
+		require.NoError(t, err, "migration version is kept")
+		require.NotZero(t, version)
+	})
+}
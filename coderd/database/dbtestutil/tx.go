@@ -0,0 +1,72 @@
+package dbtestutil
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/coder/coder/coderd/database"
+)
+
+// TxTracker records whether each query method call on a Store was made
+// inside a transaction, so tests can assert that a handler did all of its
+// database work in a single transaction.
+type TxTracker struct {
+	mu      sync.Mutex
+	inTx    int
+	outside []string
+}
+
+// TrackTransactions returns a Store that records every query method call
+// on store in the returned tracker.
+func TrackTransactions(store database.Store) (database.Store, *TxTracker) {
+	tracker := &TxTracker{}
+	return database.Intercept(store, tracker.intercept), tracker
+}
+
+func (tr *TxTracker) intercept(ctx context.Context, call database.Call, next database.Invoker) ([]interface{}, error) {
+	if call.Method != "InTx" {
+		tr.mu.Lock()
+		if call.InTx {
+			tr.inTx++
+		} else {
+			tr.outside = append(tr.outside, call.Method)
+		}
+		tr.mu.Unlock()
+	}
+	return next(ctx)
+}
+
+// Counts returns the number of calls made inside and outside transactions.
+func (tr *TxTracker) Counts() (inTx, outside int) {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	return tr.inTx, len(tr.outside)
+}
+
+// Outside returns the methods called outside a transaction, in order.
+func (tr *TxTracker) Outside() []string {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	return append([]string(nil), tr.outside...)
+}
+
+// Reset clears the recorded calls, e.g. after test setup.
+func (tr *TxTracker) Reset() {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	tr.inTx = 0
+	tr.outside = nil
+}
+
+// RequireAllInTx fails the test unless at least one call was recorded and
+// every call was made inside a transaction.
+func (tr *TxTracker) RequireAllInTx(t testing.TB) {
+	t.Helper()
+
+	inTx, _ := tr.Counts()
+	require.Empty(t, tr.Outside(), "queries ran outside a transaction")
+	require.NotZero(t, inTx, "no queries were recorded")
+}
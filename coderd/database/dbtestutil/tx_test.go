@@ -0,0 +1,40 @@
+package dbtestutil_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/coder/coder/coderd/database"
+	"github.com/coder/coder/coderd/database/databasefake"
+	"github.com/coder/coder/coderd/database/dbtestutil"
+)
+
+func TestTrackTransactions(t *testing.T) {
+	t.Parallel()
+
+	db, tracker := dbtestutil.TrackTransactions(databasefake.New())
+	ctx := context.Background()
+
+	err := db.InTx(func(tx database.Store) error {
+		_, err := tx.GetUsers(ctx, database.GetUsersParams{})
+		require.NoError(t, err)
+		_, err = tx.GetOrganizations(ctx)
+		return err
+	})
+	require.Error(t, err, "no organizations")
+	tracker.RequireAllInTx(t)
+
+	_, err = db.GetUsers(ctx, database.GetUsersParams{})
+	require.NoError(t, err)
+	inTx, outside := tracker.Counts()
+	require.Equal(t, 2, inTx)
+	require.Equal(t, 1, outside)
+	require.Equal(t, []string{"GetUsers"}, tracker.Outside())
+
+	tracker.Reset()
+	inTx, outside = tracker.Counts()
+	require.Zero(t, inTx)
+	require.Zero(t, outside)
+}
@@ -0,0 +1,105 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/lib/pq"
+	"golang.org/x/xerrors"
+)
+
+// distinctColumns are the columns GetDistinctValues may be asked for, by
+// table. They are low-cardinality columns that filters are offered on.
+var distinctColumns = map[string]map[string]bool{
+	"audit_logs":       {"action": true, "organization_id": true, "resource_type": true, "user_id": true},
+	"provisioner_jobs": {"provisioner": true, "type": true},
+	"templates":        {"name": true, "provisioner": true},
+	"users":            {"login_type": true, "status": true},
+	"workspace_builds": {"reason": true, "transition": true},
+}
+
+type distinctQuerier interface {
+	// GetDistinctValues returns the distinct non-NULL values of column in
+	// table as text, in the column's sort order, e.g. to populate a filter
+	// dropdown. Only the columns in an allowlist may be queried.
+	//
+	// If the table has a valid, non-partial btree index whose first
+	// column is column, the values are found with a loose index scan: one
+	// index probe per distinct value, which is fast on a large table with
+	// few values. Otherwise it falls back to SELECT DISTINCT, which reads
+	// the whole table; create such an index, e.g.
+	// CREATE INDEX ON audit_logs (action), to speed a column up.
+	GetDistinctValues(ctx context.Context, table, column string) ([]string, error)
+}
+
+func (q *sqlQuerier) GetDistinctValues(ctx context.Context, table, column string) ([]string, error) {
+	if !distinctColumns[table][column] {
+		return nil, xerrors.Errorf("distinct values of %s.%s may not be queried", table, column)
+	}
+	const indexed = `-- name: GetDistinctValues :one
+	SELECT EXISTS (
+		SELECT
+			1
+		FROM
+			pg_index
+		JOIN
+			pg_class index_class ON index_class.oid = pg_index.indexrelid
+		JOIN
+			pg_am ON pg_am.oid = index_class.relam
+		JOIN
+			pg_attribute ON pg_attribute.attrelid = pg_index.indrelid AND pg_attribute.attnum = pg_index.indkey[0]
+		WHERE
+			pg_index.indrelid = to_regclass($1)
+			AND pg_attribute.attname = $2
+			AND pg_am.amname = 'btree'
+			AND pg_index.indisvalid
+			AND pg_index.indpred IS NULL
+	)
+	`
+	var hasIndex bool
+	err := q.db.GetContext(ctx, &hasIndex, indexed, pq.QuoteIdentifier(table), column)
+	if err != nil {
+		return nil, xerrors.Errorf("find index: %w", err)
+	}
+
+	quotedTable, quotedColumn := pq.QuoteIdentifier(table), pq.QuoteIdentifier(column)
+	var query string
+	if hasIndex {
+		// Each step probes the index for the smallest value above the
+		// last one, skipping over all of that value's rows.
+		query = fmt.Sprintf(`-- name: GetDistinctValues :many
+		WITH RECURSIVE loose AS (
+			(SELECT %[2]s AS value FROM %[1]s WHERE %[2]s IS NOT NULL ORDER BY %[2]s LIMIT 1)
+			UNION ALL
+			SELECT
+				(SELECT %[2]s FROM %[1]s WHERE %[2]s > loose.value ORDER BY %[2]s LIMIT 1)
+			FROM
+				loose
+			WHERE
+				loose.value IS NOT NULL
+		)
+		SELECT
+			value::text
+		FROM
+			loose
+		WHERE
+			value IS NOT NULL
+		`, quotedTable, quotedColumn)
+	} else {
+		query = fmt.Sprintf(`-- name: GetDistinctValues :many
+		SELECT
+			value::text
+		FROM
+			(SELECT DISTINCT %[2]s AS value FROM %[1]s WHERE %[2]s IS NOT NULL) AS distinct_values
+		ORDER BY
+			distinct_values.value
+		`, quotedTable, quotedColumn)
+	}
+
+	values := []string{}
+	err = q.db.SelectContext(ctx, &values, query)
+	if err != nil {
+		return nil, xerrors.Errorf("get distinct values: %w", err)
+	}
+	return values, nil
+}
@@ -0,0 +1,97 @@
+//go:build linux
+
+package database_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"github.com/tabbed/pqtype"
+
+	"github.com/coder/coder/coderd/database"
+	"github.com/coder/coder/coderd/database/migrations"
+)
+
+func TestGetDistinctValues(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Query", func(t *testing.T) {
+		t.Parallel()
+		for _, indexed := range []bool{true, false} {
+			sqlDB, connector := newRecordingDB()
+			t.Cleanup(func() { _ = sqlDB.Close() })
+			connector.rows = func(query string) ([]string, [][]driver.Value) {
+				if strings.Contains(query, "pg_index") {
+					return []string{"exists"}, [][]driver.Value{{indexed}}
+				}
+				return []string{"value"}, [][]driver.Value{{"create"}, {"delete"}}
+			}
+			db := database.New(sqlDB)
+			ctx := context.Background()
+
+			values, err := db.GetDistinctValues(ctx, "audit_logs", "action")
+			require.NoError(t, err)
+			require.Equal(t, []string{"create", "delete"}, values)
+			queries := connector.Queries()
+			require.Len(t, queries, 2)
+			if indexed {
+				require.Contains(t, queries[1], `WHERE "action" > loose.value ORDER BY "action" LIMIT 1`)
+			} else {
+				require.Contains(t, queries[1], `SELECT DISTINCT "action" AS value FROM "audit_logs"`)
+			}
+
+			_, err = db.GetDistinctValues(ctx, "audit_logs", "diff")
+			require.Error(t, err, "column not allowed")
+			_, err = db.GetDistinctValues(ctx, "audit_logs; DROP TABLE users", "action")
+			require.Error(t, err, "table not allowed")
+			require.Len(t, connector.Queries(), 2)
+		}
+	})
+
+	t.Run("Postgres", func(t *testing.T) {
+		t.Parallel()
+		if testing.Short() {
+			t.SkipNow()
+		}
+
+		sqlDB := testSQLDB(t)
+		err := migrations.Up(sqlDB)
+		require.NoError(t, err, "migrations")
+		db := database.New(sqlDB)
+		ctx := context.Background()
+
+		users := []uuid.UUID{uuid.New(), uuid.New()}
+		for i := 0; i < 6; i++ {
+			action := database.AuditActionCreate
+			if i%3 == 0 {
+				action = database.AuditActionDelete
+			}
+			_, err := db.InsertAuditLog(ctx, database.InsertAuditLogParams{
+				ID:               uuid.New(),
+				Time:             database.Now(),
+				UserID:           users[i%2],
+				OrganizationID:   uuid.New(),
+				Ip:               pqtype.Inet{IPNet: net.IPNet{IP: net.IPv4(127, 0, 0, 1), Mask: net.CIDRMask(32, 32)}, Valid: true},
+				ResourceType:     database.ResourceTypeWorkspace,
+				ResourceID:       uuid.New(),
+				Action:           action,
+				Diff:             []byte("{}"),
+				AdditionalFields: []byte("{}"),
+			})
+			require.NoError(t, err)
+		}
+
+		values, err := db.GetDistinctValues(ctx, "audit_logs", "action")
+		require.NoError(t, err, "without an index")
+		require.Equal(t, []string{string(database.AuditActionCreate), string(database.AuditActionDelete)}, values)
+
+		values, err = db.GetDistinctValues(ctx, "audit_logs", "user_id")
+		require.NoError(t, err, "with an index")
+		require.ElementsMatch(t, []string{users[0].String(), users[1].String()}, values)
+	})
+}
@@ -0,0 +1,89 @@
+package database
+
+import (
+	"context"
+	"sync"
+
+	"golang.org/x/xerrors"
+)
+
+var (
+	// ErrDraining is returned for calls started after Drainer.Drain.
+	ErrDraining = xerrors.New("database is draining")
+	// ErrDrained is returned for calls started after Drainer.Drain has
+	// returned successfully, and by later calls to Drain.
+	ErrDrained = xerrors.New("database is drained")
+)
+
+// Drainer tracks in-flight Store calls and transactions so they can finish
+// before the connection pool is closed. Attach it with
+// Intercept(store, drainer.Interceptor()).
+//
+// On shutdown, call Drain and then close the *sql.DB passed to New. Once
+// Drain succeeds the Drainer is closed for good: it cannot be resumed, and
+// calls through it fail with ErrDrained.
+type Drainer struct {
+	mu       sync.RWMutex
+	draining bool
+	drained  bool
+	inflight sync.WaitGroup
+}
+
+// NewDrainer creates a Drainer that accepts calls until Drain is called.
+func NewDrainer() *Drainer {
+	return &Drainer{}
+}
+
+// Interceptor returns an Interceptor that tracks calls and rejects new ones
+// with ErrDraining once draining has started. Calls made inside a
+// transaction that was already in-flight are always allowed so the
+// transaction can complete.
+func (d *Drainer) Interceptor() Interceptor {
+	return func(ctx context.Context, call Call, next Invoker) ([]interface{}, error) {
+		if call.InTx {
+			return next(ctx)
+		}
+		d.mu.RLock()
+		if d.drained {
+			d.mu.RUnlock()
+			return nil, ErrDrained
+		}
+		if d.draining {
+			d.mu.RUnlock()
+			return nil, ErrDraining
+		}
+		d.inflight.Add(1)
+		d.mu.RUnlock()
+		defer d.inflight.Done()
+
+		return next(ctx)
+	}
+}
+
+// Drain stops accepting new calls and blocks until all in-flight calls have
+// returned or ctx is done. If ctx is done first, Drain may be called again
+// to keep waiting. Once it succeeds the pool is safe to close.
+func (d *Drainer) Drain(ctx context.Context) error {
+	d.mu.Lock()
+	if d.drained {
+		d.mu.Unlock()
+		return ErrDrained
+	}
+	d.draining = true
+	d.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		d.inflight.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		d.mu.Lock()
+		d.drained = true
+		d.mu.Unlock()
+		return nil
+	case <-ctx.Done():
+		return xerrors.Errorf("wait for in-flight calls: %w", ctx.Err())
+	}
+}
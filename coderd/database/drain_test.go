@@ -0,0 +1,55 @@
+package database_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/coder/coder/coderd/database"
+	"github.com/coder/coder/coderd/database/databasefake"
+	"github.com/coder/coder/testutil"
+)
+
+func TestDrain(t *testing.T) {
+	t.Parallel()
+
+	drainer := database.NewDrainer()
+	db := database.Intercept(databasefake.New(), drainer.Interceptor())
+
+	inTx := make(chan struct{})
+	release := make(chan struct{})
+	txDone := make(chan error, 1)
+	go func() {
+		txDone <- db.InTx(func(tx database.Store) error {
+			close(inTx)
+			<-release
+			// Calls inside an in-flight transaction are still allowed.
+			_, err := tx.GetUsers(context.Background(), database.GetUsersParams{})
+			return err
+		})
+	}()
+	<-inTx
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := drainer.Drain(ctx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	_, err = db.GetUsers(context.Background(), database.GetUsersParams{})
+	require.ErrorIs(t, err, database.ErrDraining)
+	err = db.InTx(func(database.Store) error { return nil })
+	require.ErrorIs(t, err, database.ErrDraining)
+
+	close(release)
+	ctx, cancel = context.WithTimeout(context.Background(), testutil.WaitShort)
+	defer cancel()
+	require.NoError(t, drainer.Drain(ctx))
+	assert.NoError(t, <-txDone)
+
+	_, err = db.GetUsers(context.Background(), database.GetUsersParams{})
+	require.ErrorIs(t, err, database.ErrDrained, "a drained database stays closed")
+	require.ErrorIs(t, drainer.Drain(ctx), database.ErrDrained)
+}
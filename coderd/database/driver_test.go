@@ -0,0 +1,154 @@
+package database_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"sync"
+)
+
+// recordingConnector is a driver.Connector that records every statement it
+// receives and returns no rows, for testing what reaches the driver without a
+// real database.
+type recordingConnector struct {
+	mu      sync.Mutex
+	queries []string
+	// rows optionally returns the columns and rows for a query.
+	rows func(query string) ([]string, [][]driver.Value)
+	// rollback optionally replaces the result of rolling back a transaction.
+	rollback func() error
+	// hook optionally runs before each statement and can fail it.
+	hook func(ctx context.Context, query string) error
+	// next optionally runs each time the driver produces a row.
+	next func()
+	// closed counts the connections closed.
+	closed int
+}
+
+func newRecordingDB() (*sql.DB, *recordingConnector) {
+	connector := &recordingConnector{}
+	return sql.OpenDB(connector), connector
+}
+
+func (c *recordingConnector) Queries() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.queries...)
+}
+
+// Closed returns how many connections have been closed.
+func (c *recordingConnector) Closed() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.closed
+}
+
+func (c *recordingConnector) record(query string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.queries = append(c.queries, query)
+}
+
+func (c *recordingConnector) Connect(context.Context) (driver.Conn, error) {
+	return &recordingConn{connector: c}, nil
+}
+
+func (c *recordingConnector) Driver() driver.Driver {
+	return recordingDriver{connector: c}
+}
+
+type recordingDriver struct {
+	connector *recordingConnector
+}
+
+func (d recordingDriver) Open(string) (driver.Conn, error) {
+	return d.connector.Connect(context.Background())
+}
+
+type recordingConn struct {
+	connector *recordingConnector
+}
+
+func (*recordingConn) Prepare(string) (driver.Stmt, error) {
+	return nil, driver.ErrSkip
+}
+
+func (c *recordingConn) Close() error {
+	c.connector.mu.Lock()
+	defer c.connector.mu.Unlock()
+	c.connector.closed++
+	return nil
+}
+
+func (c *recordingConn) Begin() (driver.Tx, error) {
+	return recordingTx{connector: c.connector}, nil
+}
+
+func (c *recordingConn) BeginTx(context.Context, driver.TxOptions) (driver.Tx, error) {
+	return recordingTx{connector: c.connector}, nil
+}
+
+func (c *recordingConn) QueryContext(ctx context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+	c.connector.record(query)
+	if c.connector.hook != nil {
+		if err := c.connector.hook(ctx, query); err != nil {
+			return nil, err
+		}
+	}
+	if c.connector.rows != nil {
+		columns, values := c.connector.rows(query)
+		return &staticRows{columns: columns, values: values, next: c.connector.next}, nil
+	}
+	return emptyRows{}, nil
+}
+
+func (c *recordingConn) ExecContext(ctx context.Context, query string, _ []driver.NamedValue) (driver.Result, error) {
+	c.connector.record(query)
+	if c.connector.hook != nil {
+		if err := c.connector.hook(ctx, query); err != nil {
+			return nil, err
+		}
+	}
+	return driver.RowsAffected(0), nil
+}
+
+type recordingTx struct {
+	connector *recordingConnector
+}
+
+func (recordingTx) Commit() error { return nil }
+
+func (tx recordingTx) Rollback() error {
+	if tx.connector.rollback != nil {
+		return tx.connector.rollback()
+	}
+	return nil
+}
+
+type emptyRows struct{}
+
+func (emptyRows) Columns() []string         { return nil }
+func (emptyRows) Close() error              { return nil }
+func (emptyRows) Next([]driver.Value) error { return io.EOF }
+
+type staticRows struct {
+	columns []string
+	values  [][]driver.Value
+	next    func()
+}
+
+func (r *staticRows) Columns() []string { return r.columns }
+func (*staticRows) Close() error        { return nil }
+
+func (r *staticRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	if r.next != nil {
+		r.next()
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
@@ -153,6 +153,26 @@ CREATE TABLE audit_logs (
     resource_icon text NOT NULL
 );
 
+CREATE TABLE audit_logs_archive (
+    id uuid NOT NULL,
+    "time" timestamp with time zone NOT NULL,
+    user_id uuid NOT NULL,
+    organization_id uuid NOT NULL,
+    ip inet NOT NULL,
+    user_agent character varying(256) NOT NULL,
+    resource_type resource_type NOT NULL,
+    resource_id uuid NOT NULL,
+    resource_target text NOT NULL,
+    action audit_action NOT NULL,
+    diff jsonb NOT NULL,
+    status_code integer NOT NULL,
+    additional_fields jsonb NOT NULL,
+    request_id uuid NOT NULL,
+    resource_icon text NOT NULL
+);
+
+COMMENT ON TABLE audit_logs_archive IS 'Audit logs moved out of audit_logs by retention. Columns must match audit_logs.';
+
 CREATE TABLE files (
     hash character varying(64) NOT NULL,
     created_at timestamp with time zone NOT NULL,
@@ -182,6 +202,14 @@ CREATE TABLE groups (
     avatar_url text DEFAULT ''::text NOT NULL
 );
 
+CREATE TABLE leases (
+    name text NOT NULL,
+    owner_id uuid NOT NULL,
+    expires_at timestamp with time zone NOT NULL
+);
+
+COMMENT ON TABLE leases IS 'Named leases held by one owner until they expire, used for coordinating work across replicas.';
+
 CREATE TABLE licenses (
     id integer NOT NULL,
     uploaded_at timestamp with time zone NOT NULL,
@@ -217,6 +245,16 @@ CREATE TABLE organizations (
     updated_at timestamp with time zone NOT NULL
 );
 
+CREATE TABLE outbox (
+    id uuid NOT NULL,
+    topic text NOT NULL,
+    payload jsonb NOT NULL,
+    created_at timestamp with time zone NOT NULL,
+    published_at timestamp with time zone
+);
+
+COMMENT ON TABLE outbox IS 'Events written in the transaction that caused them, for a relay to publish to a broker once it commits.';
+
 CREATE TABLE parameter_schemas (
     id uuid NOT NULL,
     created_at timestamp with time zone NOT NULL,
@@ -300,6 +338,14 @@ CREATE TABLE replicas (
     error text DEFAULT ''::text NOT NULL
 );
 
+CREATE TABLE sharded_counters (
+    name text NOT NULL,
+    shard integer NOT NULL,
+    value bigint DEFAULT 0 NOT NULL
+);
+
+COMMENT ON TABLE sharded_counters IS 'Counters split across rows so concurrent increments do not contend for one row lock. A counter''s value is the sum of its shards.';
+
 CREATE TABLE site_configs (
     key character varying(256) NOT NULL,
     value character varying(8192) NOT NULL
@@ -413,6 +459,14 @@ CREATE TABLE workspace_builds (
     reason build_reason DEFAULT 'initiator'::public.build_reason NOT NULL
 );
 
+CREATE TABLE workspace_idempotency_keys (
+    idempotency_key text NOT NULL,
+    workspace_id uuid NOT NULL,
+    created_at timestamp with time zone NOT NULL
+);
+
+COMMENT ON TABLE workspace_idempotency_keys IS 'Client-supplied keys that make workspace creation safe to retry. The key is claimed before the workspace is inserted, so the foreign key is deferred.';
+
 CREATE TABLE workspace_resource_metadata (
     workspace_resource_id uuid NOT NULL,
     key character varying(1024) NOT NULL,
@@ -442,9 +496,12 @@ CREATE TABLE workspaces (
     name character varying(64) NOT NULL,
     autostart_schedule text,
     ttl bigint,
-    last_used_at timestamp without time zone DEFAULT '0001-01-01 00:00:00'::timestamp without time zone NOT NULL
+    last_used_at timestamp without time zone DEFAULT '0001-01-01 00:00:00'::timestamp without time zone NOT NULL,
+    dormant_at timestamp with time zone
 );
 
+COMMENT ON COLUMN workspaces.dormant_at IS 'dormant_at is when the workspace was marked inactive for disuse, or NULL if it is active. Dormant workspaces are not autostarted, and using the workspace clears it.';
+
 ALTER TABLE ONLY licenses ALTER COLUMN id SET DEFAULT nextval('public.licenses_id_seq'::regclass);
 
 ALTER TABLE ONLY agent_stats
@@ -456,6 +513,9 @@ ALTER TABLE ONLY api_keys
 ALTER TABLE ONLY audit_logs
     ADD CONSTRAINT audit_logs_pkey PRIMARY KEY (id);
 
+ALTER TABLE ONLY audit_logs_archive
+    ADD CONSTRAINT audit_logs_archive_pkey PRIMARY KEY (id);
+
 ALTER TABLE ONLY files
     ADD CONSTRAINT files_hash_created_by_key UNIQUE (hash, created_by);
 
@@ -474,6 +534,9 @@ ALTER TABLE ONLY groups
 ALTER TABLE ONLY groups
     ADD CONSTRAINT groups_pkey PRIMARY KEY (id);
 
+ALTER TABLE ONLY leases
+    ADD CONSTRAINT leases_pkey PRIMARY KEY (name);
+
 ALTER TABLE ONLY licenses
     ADD CONSTRAINT licenses_jwt_key UNIQUE (jwt);
 
@@ -486,6 +549,9 @@ ALTER TABLE ONLY organization_members
 ALTER TABLE ONLY organizations
     ADD CONSTRAINT organizations_pkey PRIMARY KEY (id);
 
+ALTER TABLE ONLY outbox
+    ADD CONSTRAINT outbox_pkey PRIMARY KEY (id);
+
 ALTER TABLE ONLY parameter_schemas
     ADD CONSTRAINT parameter_schemas_job_id_name_key UNIQUE (job_id, name);
 
@@ -510,6 +576,9 @@ ALTER TABLE ONLY provisioner_job_logs
 ALTER TABLE ONLY provisioner_jobs
     ADD CONSTRAINT provisioner_jobs_pkey PRIMARY KEY (id);
 
+ALTER TABLE ONLY sharded_counters
+    ADD CONSTRAINT sharded_counters_pkey PRIMARY KEY (name, shard);
+
 ALTER TABLE ONLY site_configs
     ADD CONSTRAINT site_configs_key_key UNIQUE (key);
 
@@ -546,6 +615,9 @@ ALTER TABLE ONLY workspace_builds
 ALTER TABLE ONLY workspace_builds
     ADD CONSTRAINT workspace_builds_workspace_id_build_number_key UNIQUE (workspace_id, build_number);
 
+ALTER TABLE ONLY workspace_idempotency_keys
+    ADD CONSTRAINT workspace_idempotency_keys_pkey PRIMARY KEY (idempotency_key);
+
 ALTER TABLE ONLY workspace_resource_metadata
     ADD CONSTRAINT workspace_resource_metadata_pkey PRIMARY KEY (workspace_resource_id, key);
 
@@ -567,6 +639,8 @@ CREATE INDEX idx_audit_log_resource_id ON audit_logs USING btree (resource_id);
 
 CREATE INDEX idx_audit_log_user_id ON audit_logs USING btree (user_id);
 
+CREATE INDEX idx_audit_logs_archive_time_desc ON audit_logs_archive USING btree ("time" DESC);
+
 CREATE INDEX idx_audit_logs_time_desc ON audit_logs USING btree ("time" DESC);
 
 CREATE INDEX idx_organization_member_organization_id_uuid ON organization_members USING btree (organization_id);
@@ -577,10 +651,14 @@ CREATE UNIQUE INDEX idx_organization_name ON organizations USING btree (name);
 
 CREATE UNIQUE INDEX idx_organization_name_lower ON organizations USING btree (lower(name));
 
+CREATE INDEX idx_outbox_unpublished ON outbox USING btree (created_at, id) WHERE (published_at IS NULL);
+
 CREATE UNIQUE INDEX idx_users_email ON users USING btree (email) WHERE (deleted = false);
 
 CREATE UNIQUE INDEX idx_users_username ON users USING btree (username) WHERE (deleted = false);
 
+CREATE INDEX idx_workspaces_updated_at_id ON workspaces USING btree (updated_at, id);
+
 CREATE UNIQUE INDEX templates_organization_id_name_idx ON templates USING btree (organization_id, lower((name)::text)) WHERE (deleted = false);
 
 CREATE UNIQUE INDEX users_email_lower_idx ON users USING btree (lower(email)) WHERE (deleted = false);
@@ -652,6 +730,9 @@ ALTER TABLE ONLY workspace_builds
 ALTER TABLE ONLY workspace_builds
     ADD CONSTRAINT workspace_builds_workspace_id_fkey FOREIGN KEY (workspace_id) REFERENCES workspaces(id) ON DELETE CASCADE;
 
+ALTER TABLE ONLY workspace_idempotency_keys
+    ADD CONSTRAINT workspace_idempotency_keys_workspace_id_fkey FOREIGN KEY (workspace_id) REFERENCES workspaces(id) ON DELETE CASCADE DEFERRABLE INITIALLY DEFERRED;
+
 ALTER TABLE ONLY workspace_resource_metadata
     ADD CONSTRAINT workspace_resource_metadata_workspace_resource_id_fkey FOREIGN KEY (workspace_resource_id) REFERENCES workspace_resources(id) ON DELETE CASCADE;
 
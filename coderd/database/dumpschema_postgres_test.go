@@ -0,0 +1,44 @@
+//go:build linux
+
+package database_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/coder/coder/coderd/database"
+	"github.com/coder/coder/coderd/database/migrations"
+)
+
+func TestDumpSchemaPostgres(t *testing.T) {
+	t.Parallel()
+	if testing.Short() {
+		t.SkipNow()
+	}
+
+	sqlDB := testSQLDB(t)
+	err := migrations.Up(sqlDB)
+	require.NoError(t, err, "migrations")
+	// A foreign key referencing a standalone unique index does not imply
+	// the index.
+	_, err = sqlDB.Exec(`
+		CREATE TABLE dump_parents (code text NOT NULL);
+		CREATE UNIQUE INDEX dump_parents_code_idx ON dump_parents (code);
+		CREATE TABLE dump_children (parent_code text REFERENCES dump_parents (code));
+	`)
+	require.NoError(t, err)
+	db := database.New(sqlDB)
+
+	first, err := db.DumpSchema(context.Background())
+	require.NoError(t, err)
+	require.Contains(t, first, "CREATE TABLE users (")
+	require.Contains(t, first, "ADD CONSTRAINT users_pkey PRIMARY KEY (id);")
+	require.Contains(t, first, "CREATE UNIQUE INDEX dump_parents_code_idx ON public.dump_parents USING btree (code);")
+	require.NotContains(t, first, "CREATE UNIQUE INDEX users_pkey", "indexes backing constraints are implied")
+
+	second, err := db.DumpSchema(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, first, second)
+}
@@ -0,0 +1,60 @@
+package database_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/coder/coder/coderd/database"
+)
+
+func TestDumpSchema(t *testing.T) {
+	t.Parallel()
+
+	sqlDB, connector := newRecordingDB()
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	connector.rows = func(query string) ([]string, [][]driver.Value) {
+		switch {
+		case strings.Contains(query, "pg_attribute"):
+			return []string{"table_name", "column_name", "column_type", "not_null", "column_default"}, [][]driver.Value{
+				{"api_keys", "id", "text", true, ""},
+				{"api_keys", "lifetime_seconds", "bigint", true, "86400"},
+				{"users", "email", "text", true, ""},
+				{"users", "status", "user_status", false, "'active'::user_status"},
+			}
+		case strings.Contains(query, "pg_get_constraintdef"):
+			return []string{"table_name", "name", "definition"}, [][]driver.Value{
+				{"api_keys", "api_keys_pkey", "PRIMARY KEY (id)"},
+			}
+		default:
+			return []string{"table_name", "name", "definition"}, [][]driver.Value{
+				{"users", "idx_users_email", "CREATE UNIQUE INDEX idx_users_email ON public.users USING btree (email)"},
+			}
+		}
+	}
+
+	db := database.New(sqlDB)
+	first, err := db.DumpSchema(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, `CREATE TABLE api_keys (
+    id text NOT NULL,
+    lifetime_seconds bigint NOT NULL DEFAULT 86400
+);
+
+CREATE TABLE users (
+    email text NOT NULL,
+    status user_status DEFAULT 'active'::user_status
+);
+
+ALTER TABLE api_keys ADD CONSTRAINT api_keys_pkey PRIMARY KEY (id);
+
+CREATE UNIQUE INDEX idx_users_email ON public.users USING btree (email);
+`, first)
+
+	second, err := db.DumpSchema(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, first, second)
+}
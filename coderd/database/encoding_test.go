@@ -0,0 +1,109 @@
+package database_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/coder/coder/coderd/database"
+	"github.com/coder/coder/coderd/database/databasefake"
+)
+
+func TestCheckEncoding(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name     string
+		encoding string
+		collate  string
+		ctype    string
+		provider string
+		problem  string
+	}{
+		{name: "UTF8", encoding: "UTF8", collate: "en_US.UTF-8", ctype: "en_US.UTF-8"},
+		{name: "CCollate", encoding: "UTF8", collate: "C", ctype: "C.utf8"},
+		{name: "CUTF8", encoding: "UTF8", collate: "C.UTF-8", ctype: "C.UTF-8"},
+		{name: "Modifier", encoding: "UTF8", collate: "de_DE.utf8@euro", ctype: "de_DE.utf8@euro"},
+		{name: "SQLASCII", encoding: "SQL_ASCII", collate: "C", ctype: "C", problem: "server_encoding"},
+		{name: "CCtype", encoding: "UTF8", collate: "C", ctype: "C", problem: "LC_CTYPE"},
+		{name: "ICU", encoding: "UTF8", collate: "C", ctype: "C", provider: "i"},
+		{name: "ICUSQLASCII", encoding: "SQL_ASCII", collate: "C", ctype: "C", provider: "i", problem: "server_encoding"},
+		{name: "Libc", encoding: "UTF8", collate: "C", ctype: "C", provider: "c", problem: "LC_CTYPE"},
+		{name: "Latin1Collate", encoding: "UTF8", collate: "en_US.ISO-8859-1", ctype: "en_US.UTF-8", problem: "LC_COLLATE"},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			sqlDB, connector := newRecordingDB()
+			t.Cleanup(func() { _ = sqlDB.Close() })
+			connector.rows = func(string) ([]string, [][]driver.Value) {
+				provider := tc.provider
+				if provider == "" {
+					provider = "c"
+				}
+				return []string{"server_encoding", "collate", "ctype", "locale_provider"}, [][]driver.Value{
+					{tc.encoding, tc.collate, tc.ctype, provider},
+				}
+			}
+			err := database.New(sqlDB).CheckEncoding(context.Background())
+			if tc.problem == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorIs(t, err, database.ErrUnsupportedEncoding)
+			require.ErrorContains(t, err, tc.problem)
+		})
+	}
+
+	t.Run("Fake", func(t *testing.T) {
+		t.Parallel()
+		require.NoError(t, databasefake.New().CheckEncoding(context.Background()))
+	})
+}
+
+func TestCheckServerVersion(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name       string
+		num        int64
+		version    string
+		minVersion int
+		message    string
+	}{
+		{name: "Supported", num: 150004, version: "15.4", minVersion: database.MinServerVersion},
+		{name: "Minimum", num: 130000, version: "13.0", minVersion: database.MinServerVersion},
+		{name: "Old", num: 120016, version: "12.16 (Debian 12.16-1.pgdg120+1)", minVersion: database.MinServerVersion, message: "PostgreSQL 12.16 (Debian 12.16-1.pgdg120+1) is older than 13.0"},
+		{name: "Ancient", num: 90624, version: "9.6.24", minVersion: 100000, message: "PostgreSQL 9.6.24 is older than 10.0"},
+		{name: "MinorRelease", num: 130003, version: "13.3", minVersion: 130004, message: "older than 13.4"},
+		{name: "PreTenMinimum", num: 90500, version: "9.5.0", minVersion: 90624, message: "older than 9.6.24"},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			sqlDB, connector := newRecordingDB()
+			t.Cleanup(func() { _ = sqlDB.Close() })
+			connector.rows = func(string) ([]string, [][]driver.Value) {
+				return []string{"server_version_num", "server_version"}, [][]driver.Value{
+					{tc.num, tc.version},
+				}
+			}
+			err := database.New(sqlDB).CheckServerVersion(context.Background(), tc.minVersion)
+			if tc.message == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorIs(t, err, database.ErrUnsupportedServerVersion)
+			require.ErrorContains(t, err, tc.message)
+		})
+	}
+
+	t.Run("Fake", func(t *testing.T) {
+		t.Parallel()
+		require.NoError(t, databasefake.New().CheckServerVersion(context.Background(), database.MinServerVersion))
+	})
+}
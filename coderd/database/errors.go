@@ -1,11 +1,181 @@
 package database
 
 import (
+	"crypto/tls"
+	"crypto/x509"
 	"errors"
+	"fmt"
+	"net"
 
+	"github.com/google/uuid"
 	"github.com/lib/pq"
+	"golang.org/x/xerrors"
 )
 
+// ErrNotReplica is returned by replica-only queries when they are executed
+// against a primary that is not in recovery.
+var ErrNotReplica = xerrors.New("database is not a replica")
+
+// ErrUnsupportedEncoding is matched by errors from CheckEncoding when the
+// database's encoding or locale would break text comparisons.
+var ErrUnsupportedEncoding = xerrors.New("unsupported database encoding")
+
+// ErrUnsupportedServerVersion is matched by errors from CheckServerVersion
+// when the server is too old.
+var ErrUnsupportedServerVersion = xerrors.New("unsupported postgres version")
+
+// ErrLockTimeout is matched by errors from transactions that failed to
+// acquire a lock within their TxOptions.LockTimeout.
+var ErrLockTimeout = xerrors.New("lock timeout")
+
+// ErrTxDepthExceeded is returned by InTx when nesting would exceed the
+// depth configured with WithMaxTxDepth.
+var ErrTxDepthExceeded = xerrors.New("transaction nesting depth exceeded")
+
+// ErrNestedTransaction is returned by InTx inside a transaction when
+// WithStrictTransactions is enabled.
+var ErrNestedTransaction = xerrors.New("already in a transaction; use InSavepoint")
+
+// ErrResultTooLarge is returned by multi-row reads whose result set exceeds
+// the cap configured with WithMaxResultRows.
+var ErrResultTooLarge = xerrors.New("result set too large")
+
+// ErrWriteInReadOnlyTx is returned by InTxReadOnlyHint when its callback
+// calls a write method.
+var ErrWriteInReadOnlyTx = xerrors.New("write in read-only transaction")
+
+// ErrMaintenanceMode is returned by a Maintenance store for writes while
+// writes are disabled.
+var ErrMaintenanceMode = xerrors.New("writes are disabled for maintenance")
+
+// ErrConnectFailed is matched by errors from establishing a new connection,
+// as opposed to errors from running a query on one. Use errors.As with
+// *ConnectError to see why the connection failed.
+var ErrConnectFailed = xerrors.New("connect to database")
+
+// ConnectFailure is the reason a connection could not be established.
+type ConnectFailure string
+
+const (
+	// ConnectFailureDial means the server could not be reached, for
+	// example because the host did not resolve or refused the connection.
+	ConnectFailureDial ConnectFailure = "dial"
+	// ConnectFailureAuth means the server rejected the credentials.
+	ConnectFailureAuth ConnectFailure = "auth"
+	// ConnectFailureTLS means the TLS handshake or certificate
+	// verification failed, or the server does not support TLS.
+	ConnectFailureTLS ConnectFailure = "tls"
+)
+
+// ConnectError is returned when a new connection cannot be established. It
+// matches ErrConnectFailed and unwraps to the driver's error.
+type ConnectError struct {
+	Failure ConnectFailure
+	Err     error
+}
+
+func (e *ConnectError) Error() string {
+	return fmt.Sprintf("connect to database (%s): %s", e.Failure, e.Err.Error())
+}
+
+func (e *ConnectError) Unwrap() error {
+	return e.Err
+}
+
+func (*ConnectError) Is(target error) bool {
+	return target == ErrConnectFailed
+}
+
+// classifyConnectError wraps err in a *ConnectError if it is a dial, auth or
+// TLS failure, and returns it unchanged otherwise.
+func classifyConnectError(err error) error {
+	var (
+		opErr        *net.OpError
+		dnsErr       *net.DNSError
+		pqErr        *pq.Error
+		recordErr    tls.RecordHeaderError
+		authorityErr x509.UnknownAuthorityError
+		hostnameErr  x509.HostnameError
+		invalidErr   x509.CertificateInvalidError
+	)
+	switch {
+	case err == nil:
+		return nil
+	case errors.As(err, &pqErr):
+		// invalid_authorization_specification and invalid_password.
+		if pqErr.Code.Class() == "28" {
+			return &ConnectError{Failure: ConnectFailureAuth, Err: err}
+		}
+		return err
+	case errors.As(err, &recordErr), errors.As(err, &authorityErr), errors.As(err, &hostnameErr),
+		errors.As(err, &invalidErr), errors.Is(err, pq.ErrSSLNotSupported), errors.Is(err, pq.ErrSSLKeyHasWorldPermissions):
+		return &ConnectError{Failure: ConnectFailureTLS, Err: err}
+	case errors.As(err, &opErr), errors.As(err, &dnsErr):
+		return &ConnectError{Failure: ConnectFailureDial, Err: err}
+	default:
+		return err
+	}
+}
+
+// ErrMissingIDs is returned by the MustFindAll batch reads when some of the
+// requested IDs have no row. IDs lists each missing ID once, in request
+// order.
+type ErrMissingIDs struct {
+	IDs []uuid.UUID
+}
+
+func (e ErrMissingIDs) Error() string {
+	return fmt.Sprintf("%d requested IDs not found", len(e.IDs))
+}
+
+// ErrExtensionMissing is returned by queries that depend on a Postgres
+// extension that is not installed or not loaded.
+type ErrExtensionMissing struct {
+	Extension string
+}
+
+func (e ErrExtensionMissing) Error() string {
+	return fmt.Sprintf("extension %q is not available", e.Extension)
+}
+
+// lockTimeoutError wraps a lock_not_available error so it matches
+// ErrLockTimeout while preserving the underlying *pq.Error.
+type lockTimeoutError struct {
+	err error
+}
+
+func (e lockTimeoutError) Error() string {
+	return e.err.Error()
+}
+
+func (e lockTimeoutError) Unwrap() error {
+	return e.err
+}
+
+func (lockTimeoutError) Is(target error) bool {
+	return target == ErrLockTimeout
+}
+
+// mapTxError maps Postgres errors from a transaction to typed errors.
+func mapTxError(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "55P03" {
+		return lockTimeoutError{err: err}
+	}
+	return err
+}
+
+// IsRetryableError reports whether err means Postgres aborted the
+// transaction only because of concurrent transactions, so running it again
+// may succeed: serialization_failure (40001) or deadlock_detected (40P01).
+func IsRetryableError(err error) bool {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return false
+	}
+	return pqErr.Code == "40001" || pqErr.Code == "40P01"
+}
+
 // IsUniqueViolation checks if the error is due to a unique violation.
 // If one or more specific unique constraints are given as arguments,
 // the error must be caused by one of them. If no constraints are given,
@@ -0,0 +1,92 @@
+package database
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+
+	"github.com/google/uuid"
+	"golang.org/x/xerrors"
+)
+
+// ErrPreconditionFailed is returned by conditional updates when the
+// resource no longer matches the version the caller expected.
+var ErrPreconditionFailed = xerrors.New("precondition failed")
+
+// etagQuerier backs HTTP ETag and If-Match handling with versions derived
+// from the stored row, so any change to the row, including changes made by
+// queries that do not bump updated_at, yields a new version.
+type etagQuerier interface {
+	// GetTemplateETag returns the current version of the template, as
+	// computed by TemplateETag.
+	GetTemplateETag(ctx context.Context, id uuid.UUID) (string, error)
+	// UpdateTemplateIfMatch updates the template metadata only if its
+	// version still equals etag and returns the updated template. It fails
+	// with ErrPreconditionFailed if the template changed since the caller
+	// read etag, and with sql.ErrNoRows if it does not exist.
+	UpdateTemplateIfMatch(ctx context.Context, id uuid.UUID, etag string, arg UpdateTemplateIfVersionParams) (Template, error)
+}
+
+// TemplateETag returns a version of template that changes whenever any of
+// its columns do. It is opaque to callers; HTTP handlers must quote it.
+func TemplateETag(template Template) (string, error) {
+	// Drivers may scan timestamps in different locations; the version
+	// must only depend on the instant.
+	template.CreatedAt = template.CreatedAt.UTC()
+	template.UpdatedAt = template.UpdatedAt.UTC()
+	data, err := json.Marshal(template)
+	if err != nil {
+		return "", xerrors.Errorf("marshal template: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:16]), nil
+}
+
+func (q *sqlQuerier) GetTemplateETag(ctx context.Context, id uuid.UUID) (string, error) {
+	template, err := q.GetTemplateByID(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	return TemplateETag(template)
+}
+
+func (q *sqlQuerier) UpdateTemplateIfMatch(ctx context.Context, id uuid.UUID, etag string, arg UpdateTemplateIfVersionParams) (Template, error) {
+	// As in NextBuildNumber, the read that is compared is a separate
+	// statement from the lock, so it sees what a concurrent writer that
+	// held the lock committed.
+	const lock = `-- name: UpdateTemplateIfMatch :one
+	SELECT id FROM templates WHERE id = $1 FOR UPDATE
+	`
+
+	var template Template
+	err := q.inCurrentTx(ctx, func(tx Store) error {
+		var locked uuid.UUID
+		err := txQuerier(tx).db.GetContext(ctx, &locked, lock, id)
+		if err != nil {
+			return xerrors.Errorf("lock template: %w", err)
+		}
+		current, err := tx.GetTemplateByID(ctx, id)
+		if err != nil {
+			return xerrors.Errorf("get template: %w", err)
+		}
+		currentETag, err := TemplateETag(current)
+		if err != nil {
+			return err
+		}
+		if currentETag != etag {
+			return ErrPreconditionFailed
+		}
+		// The row is locked, so the compare-and-set cannot miss.
+		_, err = tx.UpdateTemplateIfVersion(ctx, id, current.UpdatedAt, arg)
+		if err != nil {
+			return err
+		}
+		template, err = tx.GetTemplateByID(ctx, id)
+		return err
+	})
+	if err != nil {
+		return Template{}, err
+	}
+	return template, nil
+}
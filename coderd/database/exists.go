@@ -0,0 +1,74 @@
+package database
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"golang.org/x/xerrors"
+)
+
+// existenceQuerier validates sets of IDs with a single query per set.
+// Each method returns the IDs that do not exist, in input order, or an
+// empty (non-nil) slice when all of them do. Soft-deleted rows count as
+// missing.
+type existenceQuerier interface {
+	CheckUsersExist(ctx context.Context, ids []uuid.UUID) (missing []uuid.UUID, err error)
+	CheckGroupsExist(ctx context.Context, ids []uuid.UUID) (missing []uuid.UUID, err error)
+	CheckTemplatesExist(ctx context.Context, ids []uuid.UUID) (missing []uuid.UUID, err error)
+}
+
+func (q *sqlQuerier) CheckUsersExist(ctx context.Context, ids []uuid.UUID) ([]uuid.UUID, error) {
+	const query = `-- name: CheckUsersExist :many
+	SELECT
+		ids.id
+	FROM
+		unnest($1::uuid[]) WITH ORDINALITY AS ids(id, ord)
+	WHERE
+		NOT EXISTS (SELECT 1 FROM users WHERE users.id = ids.id AND NOT users.deleted)
+	ORDER BY
+		ids.ord
+	`
+	return q.missingIDs(ctx, query, ids)
+}
+
+func (q *sqlQuerier) CheckGroupsExist(ctx context.Context, ids []uuid.UUID) ([]uuid.UUID, error) {
+	const query = `-- name: CheckGroupsExist :many
+	SELECT
+		ids.id
+	FROM
+		unnest($1::uuid[]) WITH ORDINALITY AS ids(id, ord)
+	WHERE
+		NOT EXISTS (SELECT 1 FROM groups WHERE groups.id = ids.id)
+	ORDER BY
+		ids.ord
+	`
+	return q.missingIDs(ctx, query, ids)
+}
+
+func (q *sqlQuerier) CheckTemplatesExist(ctx context.Context, ids []uuid.UUID) ([]uuid.UUID, error) {
+	const query = `-- name: CheckTemplatesExist :many
+	SELECT
+		ids.id
+	FROM
+		unnest($1::uuid[]) WITH ORDINALITY AS ids(id, ord)
+	WHERE
+		NOT EXISTS (SELECT 1 FROM templates WHERE templates.id = ids.id AND NOT templates.deleted)
+	ORDER BY
+		ids.ord
+	`
+	return q.missingIDs(ctx, query, ids)
+}
+
+// missingIDs runs an existence query that selects the absent IDs from $1.
+func (q *sqlQuerier) missingIDs(ctx context.Context, query string, ids []uuid.UUID) ([]uuid.UUID, error) {
+	missing := []uuid.UUID{}
+	if len(ids) == 0 {
+		return missing, nil
+	}
+	err := q.db.SelectContext(ctx, &missing, query, pq.Array(ids))
+	if err != nil {
+		return nil, xerrors.Errorf("select missing ids: %w", err)
+	}
+	return missing, nil
+}
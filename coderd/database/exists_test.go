@@ -0,0 +1,107 @@
+package database_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/coder/coder/coderd/database"
+	"github.com/coder/coder/coderd/database/dbtestutil"
+)
+
+func TestCheckExist(t *testing.T) {
+	t.Parallel()
+
+	db, _ := dbtestutil.NewDB(t)
+	ctx := context.Background()
+
+	user, err := db.InsertUser(ctx, database.InsertUserParams{
+		ID:             uuid.New(),
+		Email:          "coder@coder.com",
+		Username:       "coder",
+		HashedPassword: []byte{},
+		CreatedAt:      database.Now(),
+		UpdatedAt:      database.Now(),
+		RBACRoles:      []string{},
+		LoginType:      database.LoginTypePassword,
+	})
+	require.NoError(t, err)
+	deletedUser, err := db.InsertUser(ctx, database.InsertUserParams{
+		ID:             uuid.New(),
+		Email:          "deleted@coder.com",
+		Username:       "deleted",
+		HashedPassword: []byte{},
+		CreatedAt:      database.Now(),
+		UpdatedAt:      database.Now(),
+		RBACRoles:      []string{},
+		LoginType:      database.LoginTypePassword,
+	})
+	require.NoError(t, err)
+	err = db.UpdateUserDeletedByID(ctx, database.UpdateUserDeletedByIDParams{
+		ID:      deletedUser.ID,
+		Deleted: true,
+	})
+	require.NoError(t, err)
+	org, err := db.InsertOrganization(ctx, database.InsertOrganizationParams{
+		ID:        uuid.New(),
+		Name:      "org",
+		CreatedAt: database.Now(),
+		UpdatedAt: database.Now(),
+	})
+	require.NoError(t, err)
+	group, err := db.InsertGroup(ctx, database.InsertGroupParams{
+		ID:             uuid.New(),
+		Name:           "group",
+		OrganizationID: org.ID,
+	})
+	require.NoError(t, err)
+	template, err := db.InsertTemplate(ctx, database.InsertTemplateParams{
+		ID:             uuid.New(),
+		CreatedAt:      database.Now(),
+		UpdatedAt:      database.Now(),
+		OrganizationID: org.ID,
+		Name:           "template",
+		Provisioner:    database.ProvisionerTypeEcho,
+		CreatedBy:      user.ID,
+		UserACL:        database.TemplateACL{},
+		GroupACL:       database.TemplateACL{},
+	})
+	require.NoError(t, err)
+
+	absent := uuid.New()
+
+	missing, err := db.CheckUsersExist(ctx, []uuid.UUID{user.ID})
+	require.NoError(t, err)
+	require.NotNil(t, missing)
+	require.Empty(t, missing)
+	missing, err = db.CheckUsersExist(ctx, []uuid.UUID{absent, user.ID, deletedUser.ID})
+	require.NoError(t, err)
+	require.Equal(t, []uuid.UUID{absent, deletedUser.ID}, missing)
+
+	missing, err = db.CheckGroupsExist(ctx, []uuid.UUID{group.ID, absent})
+	require.NoError(t, err)
+	require.Equal(t, []uuid.UUID{absent}, missing)
+
+	missing, err = db.CheckTemplatesExist(ctx, []uuid.UUID{template.ID})
+	require.NoError(t, err)
+	require.Empty(t, missing)
+	missing, err = db.CheckTemplatesExist(ctx, []uuid.UUID{absent})
+	require.NoError(t, err)
+	require.Equal(t, []uuid.UUID{absent}, missing)
+}
+
+func TestCheckExistEmptyInput(t *testing.T) {
+	t.Parallel()
+
+	sqlDB, connector := newRecordingDB()
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	db := database.New(sqlDB)
+
+	missing, err := db.CheckUsersExist(context.Background(), nil)
+	require.NoError(t, err)
+	require.NotNil(t, missing)
+	require.Empty(t, missing)
+	require.Empty(t, connector.Queries(), "empty input must not query")
+}
@@ -0,0 +1,256 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"reflect"
+
+	"github.com/lib/pq"
+	"golang.org/x/xerrors"
+)
+
+// largeTableRows is the estimated row count from which PlanSummary reports a
+// sequential scan as a likely regression.
+const largeTableRows = 10000
+
+// WithQueryExplain enables ExplainQuery, which calls arbitrary Store
+// methods by name. It is meant for CI jobs that snapshot query plans, not
+// for production Stores.
+func WithQueryExplain() Option {
+	return func(o *options) {
+		o.explainQueries = true
+	}
+}
+
+type explainQuerier interface {
+	// ExplainQuery plans the statement that method issues when called with
+	// sampleArgs, without running it, and summarizes the plan so CI can
+	// detect queries that regress to a sequential scan after a schema or
+	// query change. Only the first statement of methods that issue several
+	// is planned. Planning happens in a read-only transaction that is
+	// rolled back. It requires WithQueryExplain.
+	ExplainQuery(ctx context.Context, method string, sampleArgs ...interface{}) (PlanSummary, error)
+}
+
+// PlanSummary is the gist of a query plan from ExplainQuery, stable enough
+// to compare across deploys.
+type PlanSummary struct {
+	Method string `json:"method"`
+	// NodeTypes are the plan's node types in depth-first order, e.g.
+	// ["Limit", "Index Scan"].
+	NodeTypes []string `json:"node_types"`
+	// EstimatedRows and TotalCost are the planner's estimates for the
+	// whole statement.
+	EstimatedRows float64 `json:"estimated_rows"`
+	TotalCost     float64 `json:"total_cost"`
+	// SeqScans are the tables scanned sequentially, and LargeSeqScans
+	// those of them estimated to hold at least 10000 rows. Estimates come
+	// from the last ANALYZE, so seed and analyze representative data
+	// before comparing plans.
+	SeqScans      []string `json:"seq_scans"`
+	LargeSeqScans []string `json:"large_seq_scans"`
+	// Plan is the full EXPLAIN (FORMAT JSON) output.
+	Plan json.RawMessage `json:"plan"`
+}
+
+// errExplained stops a method once its statement has been planned, and
+// rolls back the transaction it was planned in.
+var errExplained = xerrors.New("statement explained")
+
+type planNode struct {
+	NodeType     string     `json:"Node Type"`
+	RelationName string     `json:"Relation Name"`
+	Filter       string     `json:"Filter"`
+	PlanRows     float64    `json:"Plan Rows"`
+	TotalCost    float64    `json:"Total Cost"`
+	Plans        []planNode `json:"Plans"`
+}
+
+func (q *sqlQuerier) ExplainQuery(ctx context.Context, method string, sampleArgs ...interface{}) (PlanSummary, error) {
+	if !q.opts.explainQueries {
+		return PlanSummary{}, xerrors.New("explain query requires WithQueryExplain")
+	}
+	if q.inTx {
+		return PlanSummary{}, xerrors.New("explain query must not be called inside a transaction")
+	}
+
+	summary := PlanSummary{Method: method}
+	err := q.InReadTx(ctx, func(store Store) error {
+		tx := txQuerier(store)
+		explainer := &explainDB{DBTX: tx.db, ctx: ctx}
+		call, err := methodCall(&sqlQuerier{
+			sdb:     tx.sdb,
+			db:      explainer,
+			opts:    tx.opts,
+			inTx:    true,
+			depth:   tx.depth,
+			txStart: tx.txStart,
+		}, method, ctx, sampleArgs)
+		if err != nil {
+			return err
+		}
+		call()
+		if explainer.err != nil {
+			return explainer.err
+		}
+		if explainer.plan == nil {
+			return xerrors.Errorf("%s did not issue a statement", method)
+		}
+		summary.Plan = explainer.plan
+		err = summarizePlan(ctx, tx.db, &summary)
+		if err != nil {
+			return err
+		}
+		return errExplained
+	})
+	if err != nil && !xerrors.Is(err, errExplained) {
+		return PlanSummary{}, xerrors.Errorf("explain %s: %w", method, err)
+	}
+	return summary, nil
+}
+
+// methodCall returns a function calling the named method of q with ctx and
+// args, or an error if they do not fit its signature.
+func methodCall(q *sqlQuerier, method string, ctx context.Context, args []interface{}) (func(), error) {
+	fn := reflect.ValueOf(q).MethodByName(method)
+	if !fn.IsValid() {
+		return nil, xerrors.Errorf("no method %q", method)
+	}
+	typ := fn.Type()
+	contextType := reflect.TypeOf((*context.Context)(nil)).Elem()
+	if typ.IsVariadic() || typ.NumIn() != len(args)+1 || typ.In(0) != contextType {
+		return nil, xerrors.Errorf("%s takes %d argument(s) after the context, got %d", method, typ.NumIn()-1, len(args))
+	}
+	in := []reflect.Value{reflect.ValueOf(ctx)}
+	for i, arg := range args {
+		want := typ.In(i + 1)
+		if arg == nil {
+			in = append(in, reflect.Zero(want))
+			continue
+		}
+		value := reflect.ValueOf(arg)
+		if !value.Type().AssignableTo(want) {
+			return nil, xerrors.Errorf("argument %d of %s must be %s, got %T", i+1, method, want, arg)
+		}
+		in = append(in, value)
+	}
+	return func() { fn.Call(in) }, nil
+}
+
+// summarizePlan fills in summary from its plan, looking up the size of the
+// sequentially scanned tables.
+func summarizePlan(ctx context.Context, db DBTX, summary *PlanSummary) error {
+	var plans []struct {
+		Plan planNode `json:"Plan"`
+	}
+	err := json.Unmarshal(summary.Plan, &plans)
+	if err != nil || len(plans) == 0 {
+		return xerrors.Errorf("parse plan: %w", err)
+	}
+	root := plans[0].Plan
+	summary.EstimatedRows = root.PlanRows
+	summary.TotalCost = root.TotalCost
+	var walk func(node planNode)
+	walk = func(node planNode) {
+		summary.NodeTypes = append(summary.NodeTypes, node.NodeType)
+		if node.NodeType == "Seq Scan" && node.RelationName != "" {
+			summary.SeqScans = append(summary.SeqScans, node.RelationName)
+		}
+		for _, child := range node.Plans {
+			walk(child)
+		}
+	}
+	walk(root)
+	if len(summary.SeqScans) == 0 {
+		return nil
+	}
+
+	large, err := largeTables(ctx, db, summary.SeqScans)
+	if err != nil {
+		return err
+	}
+	for _, name := range summary.SeqScans {
+		if large[name] {
+			summary.LargeSeqScans = append(summary.LargeSeqScans, name)
+		}
+	}
+	return nil
+}
+
+// largeTables returns which of tables are estimated to hold at least
+// largeTableRows rows.
+func largeTables(ctx context.Context, db DBTX, tables []string) (map[string]bool, error) {
+	const query = `-- name: ExplainQuery :many
+	SELECT
+		relname
+	FROM
+		pg_class
+	WHERE
+		relnamespace = current_schema()::regnamespace
+		AND relname = ANY($1)
+		AND reltuples >= $2
+	`
+	var names []string
+	err := db.SelectContext(ctx, &names, query, pq.Array(tables), largeTableRows)
+	if err != nil {
+		return nil, xerrors.Errorf("get table sizes: %w", err)
+	}
+	large := make(map[string]bool, len(names))
+	for _, name := range names {
+		large[name] = true
+	}
+	return large, nil
+}
+
+// explainDB plans the first statement it is given instead of running it.
+// Later statements fail with errExplained.
+type explainDB struct {
+	DBTX
+	// ctx is used for the EXPLAIN, since queries called without a context
+	// have none of their own.
+	ctx  context.Context
+	plan json.RawMessage
+	err  error
+}
+
+func (d *explainDB) explain(query string, args []interface{}) error {
+	if d.plan != nil || d.err != nil {
+		return errExplained
+	}
+	var plan string
+	d.err = d.DBTX.QueryRowContext(d.ctx, "EXPLAIN (FORMAT JSON) "+query, args...).Scan(&plan)
+	if d.err != nil {
+		d.err = xerrors.Errorf("explain: %w", d.err)
+		return d.err
+	}
+	d.plan = json.RawMessage(plan)
+	return errExplained
+}
+
+func (d *explainDB) ExecContext(_ context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return nil, d.explain(query, args)
+}
+
+func (d *explainDB) QueryContext(_ context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return nil, d.explain(query, args)
+}
+
+// QueryRowContext cannot return an error of its own, so it hands the method
+// an empty result instead.
+func (d *explainDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	_ = d.explain(query, args)
+	return d.DBTX.QueryRowContext(ctx, "SELECT WHERE false")
+}
+
+func (d *explainDB) SelectContext(_ context.Context, _ interface{}, query string, args ...interface{}) error {
+	return d.explain(query, args)
+}
+
+func (d *explainDB) GetContext(_ context.Context, _ interface{}, query string, args ...interface{}) error {
+	return d.explain(query, args)
+}
+
+func (d *explainDB) PrepareContext(_ context.Context, query string) (*sql.Stmt, error) {
+	return nil, d.explain(query, nil)
+}
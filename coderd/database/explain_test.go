@@ -0,0 +1,85 @@
+package database_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/coder/coder/coderd/database"
+)
+
+func TestExplainQuery(t *testing.T) {
+	t.Parallel()
+
+	const plan = `[{"Plan": {"Node Type": "Limit", "Plan Rows": 1, "Total Cost": 8.5, "Plans": [
+		{"Node Type": "Seq Scan", "Relation Name": "users", "Plan Rows": 1, "Total Cost": 8.4},
+		{"Node Type": "Index Scan", "Relation Name": "organizations", "Plan Rows": 1, "Total Cost": 0.1}
+	]}}]`
+	newDB := func(t *testing.T, opts ...database.Option) (database.Store, *recordingConnector) {
+		sqlDB, connector := newRecordingDB()
+		t.Cleanup(func() { _ = sqlDB.Close() })
+		connector.rows = func(query string) ([]string, [][]driver.Value) {
+			switch {
+			case strings.HasPrefix(query, "EXPLAIN"):
+				return []string{"QUERY PLAN"}, [][]driver.Value{{[]byte(plan)}}
+			case strings.Contains(query, "pg_class"):
+				return []string{"relname"}, [][]driver.Value{{"users"}}
+			}
+			return nil, nil
+		}
+		return database.New(sqlDB, opts...), connector
+	}
+	ctx := context.Background()
+
+	t.Run("QueryRow", func(t *testing.T) {
+		t.Parallel()
+		db, connector := newDB(t, database.WithQueryExplain())
+		summary, err := db.ExplainQuery(ctx, "GetUserByID", uuid.New())
+		require.NoError(t, err)
+		require.Equal(t, "GetUserByID", summary.Method)
+		require.Equal(t, []string{"Limit", "Seq Scan", "Index Scan"}, summary.NodeTypes)
+		require.EqualValues(t, 1, summary.EstimatedRows)
+		require.Equal(t, 8.5, summary.TotalCost)
+		require.Equal(t, []string{"users"}, summary.SeqScans)
+		require.Equal(t, []string{"users"}, summary.LargeSeqScans)
+		require.JSONEq(t, plan, string(summary.Plan))
+
+		queries := connector.Queries()
+		require.True(t, strings.HasPrefix(queries[0], "EXPLAIN (FORMAT JSON) -- name: GetUserByID"), queries[0])
+		for _, query := range queries {
+			require.False(t, strings.HasPrefix(query, "-- name: GetUserByID"), "the query itself is never run")
+		}
+	})
+
+	t.Run("Query", func(t *testing.T) {
+		t.Parallel()
+		db, connector := newDB(t, database.WithQueryExplain())
+		summary, err := db.ExplainQuery(ctx, "GetUsers", database.GetUsersParams{LimitOpt: 10})
+		require.NoError(t, err)
+		require.Equal(t, []string{"users"}, summary.SeqScans)
+		require.True(t, strings.HasPrefix(connector.Queries()[0], "EXPLAIN (FORMAT JSON) -- name: GetUsers"))
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		t.Parallel()
+		db, connector := newDB(t)
+		_, err := db.ExplainQuery(ctx, "GetUserByID", uuid.New())
+		require.Error(t, err)
+		require.Empty(t, connector.Queries())
+	})
+
+	t.Run("BadCall", func(t *testing.T) {
+		t.Parallel()
+		db, _ := newDB(t, database.WithQueryExplain())
+		_, err := db.ExplainQuery(ctx, "GetUserByNothing")
+		require.ErrorContains(t, err, "no method")
+		_, err = db.ExplainQuery(ctx, "GetUserByID")
+		require.ErrorContains(t, err, "takes 1 argument")
+		_, err = db.ExplainQuery(ctx, "GetUserByID", "not a uuid")
+		require.ErrorContains(t, err, "must be uuid.UUID")
+	})
+}
@@ -0,0 +1,320 @@
+package database
+
+import (
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/klauspost/compress/zstd"
+	"golang.org/x/xerrors"
+)
+
+type exportQuerier interface {
+	// CopyOut streams the result of a SELECT query to w as CSV with a
+	// header row, and returns the number of bytes written. Rows are written
+	// as they are read, so exports of any size use constant memory. The
+	// query runs in a read-only transaction, which holds a single pool
+	// connection until the export completes. lib/pq does not support
+	// COPY ... TO STDOUT, so only SELECT statements are accepted. It must
+	// not be called inside a transaction, which could not be made
+	// read-only.
+	CopyOut(ctx context.Context, query string, w io.Writer) (int64, error)
+	// StreamCompressed is like CopyOut for a query with args, but
+	// compresses the CSV with codec, CodecGzip or CodecZstd, as it is
+	// written. It returns the number of compressed bytes written to w.
+	// Exports of text such as audit logs shrink several times over, so
+	// streaming them to a remote client transfers far less.
+	StreamCompressed(ctx context.Context, query string, args []interface{}, w io.Writer, codec string) (int64, error)
+	// StreamInto sends the rows of a SELECT query with args to rows as they
+	// are read, closing rows when done, and returns the first error. Reading
+	// waits while rows is full, so a slow consumer throttles the query and
+	// memory is bounded by the channel's capacity. Like CopyOut, it holds a
+	// read-only transaction and a pool connection until the last row has
+	// been received or ctx is done, and must not be called inside a
+	// transaction.
+	StreamInto(ctx context.Context, query string, args []interface{}, rows chan<- Row) error
+	// ExportAuditLogsPage returns up to limit audit logs after cursor,
+	// oldest first, along with the cursor to pass to the next call. done
+	// is true once a page comes back short. Each page is a single
+	// statement and so its own short transaction: an export of any size
+	// can be resumed page by page, even from another process, without
+	// holding a transaction open. Pass the zero Cursor to start. Logs
+	// inserted during the export are included if they sort after the
+	// cursor; pages are not a consistent snapshot of the table.
+	ExportAuditLogsPage(ctx context.Context, cursor Cursor, limit int32) (rows []AuditLog, next Cursor, done bool, err error)
+}
+
+// Codecs accepted by StreamCompressed, named as in Content-Encoding.
+const (
+	CodecGzip = "gzip"
+	CodecZstd = "zstd"
+)
+
+// Row is a row sent by StreamInto. Values holds the driver values in the
+// order of Columns, which is shared by every row of a query and must not
+// be modified.
+type Row struct {
+	Columns []string
+	Values  []interface{}
+}
+
+// Cursor is a position in a keyset-paginated export: the sort key of the
+// last row returned. The zero Cursor is before every row.
+type Cursor struct {
+	Time time.Time `json:"time"`
+	ID   uuid.UUID `json:"id"`
+}
+
+// singleSelect trims query and checks that it is a single SELECT statement.
+func singleSelect(query string) (string, error) {
+	query = strings.TrimSuffix(strings.TrimSpace(query), ";")
+	if !strings.HasPrefix(strings.ToUpper(query), "SELECT") || strings.Contains(query, ";") {
+		return "", xerrors.New("query must be a single SELECT statement")
+	}
+	return query, nil
+}
+
+func (q *sqlQuerier) CopyOut(ctx context.Context, query string, w io.Writer) (int64, error) {
+	query, err := singleSelect(query)
+	if err != nil {
+		return 0, xerrors.Errorf("copy out: %w", err)
+	}
+
+	counter := &countingWriter{w: w}
+	err = q.copyCSV(ctx, query, nil, counter)
+	if err != nil {
+		return counter.n, xerrors.Errorf("copy out: %w", err)
+	}
+	return counter.n, nil
+}
+
+func (q *sqlQuerier) StreamCompressed(ctx context.Context, query string, args []interface{}, w io.Writer, codec string) (int64, error) {
+	query, err := singleSelect(query)
+	if err != nil {
+		return 0, xerrors.Errorf("stream compressed: %w", err)
+	}
+
+	counter := &countingWriter{w: w}
+	var compressor compressWriter
+	switch codec {
+	case CodecGzip:
+		compressor = gzip.NewWriter(counter)
+	case CodecZstd:
+		compressor, err = zstd.NewWriter(counter)
+		if err != nil {
+			return 0, xerrors.Errorf("stream compressed: %w", err)
+		}
+	default:
+		return 0, xerrors.Errorf("stream compressed: unsupported codec %q", codec)
+	}
+	err = q.copyCSV(ctx, query, args, compressor)
+	if err != nil {
+		// Closing would end the stream with a valid trailer, and a client
+		// already reading it could not tell a truncated export from a
+		// complete one. The encoder is instead pointed elsewhere, leaving
+		// w without a trailer, and closed to release its resources.
+		compressor.Reset(io.Discard)
+		_ = compressor.Close()
+		return counter.n, xerrors.Errorf("stream compressed: %w", err)
+	}
+	cerr := compressor.Close()
+	if cerr != nil {
+		return counter.n, xerrors.Errorf("stream compressed: close %s: %w", codec, cerr)
+	}
+	return counter.n, nil
+}
+
+// compressWriter is implemented by the gzip and zstd writers.
+type compressWriter interface {
+	io.WriteCloser
+	Reset(w io.Writer)
+}
+
+// copyCSV writes the result of query to w as CSV in a read-only
+// transaction. InReadTx would reuse an outer transaction, read-write or
+// not, so copyCSV refuses to run in one.
+func (q *sqlQuerier) copyCSV(ctx context.Context, query string, args []interface{}, w io.Writer) error {
+	if q.inTx {
+		return xerrors.New("must not be called inside a transaction")
+	}
+	return q.InReadTx(ctx, func(tx Store) error {
+		rows, err := txQuerier(tx).db.QueryContext(ctx, query, args...)
+		if err != nil {
+			return xerrors.Errorf("query: %w", err)
+		}
+		defer rows.Close()
+		return writeCSV(w, rows, q.opts.maxResultRows)
+	})
+}
+
+func (q *sqlQuerier) StreamInto(ctx context.Context, query string, args []interface{}, rows chan<- Row) error {
+	defer close(rows)
+	query, err := singleSelect(query)
+	if err != nil {
+		return xerrors.Errorf("stream: %w", err)
+	}
+	if q.inTx {
+		return xerrors.New("stream: must not be called inside a transaction")
+	}
+
+	err = q.InReadTx(ctx, func(tx Store) error {
+		result, err := txQuerier(tx).db.QueryContext(ctx, query, args...)
+		if err != nil {
+			return xerrors.Errorf("query: %w", err)
+		}
+		defer result.Close()
+		columns, err := result.Columns()
+		if err != nil {
+			return xerrors.Errorf("columns: %w", err)
+		}
+		sent := 0
+		for result.Next() {
+			if q.opts.maxResultRows > 0 && sent == q.opts.maxResultRows {
+				return ErrResultTooLarge
+			}
+			// Scanning into interface values copies bytes, so the row
+			// stays valid after the next one is read.
+			values := make([]interface{}, len(columns))
+			pointers := make([]interface{}, len(columns))
+			for i := range values {
+				pointers[i] = &values[i]
+			}
+			err = result.Scan(pointers...)
+			if err != nil {
+				return xerrors.Errorf("scan: %w", err)
+			}
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case rows <- Row{Columns: columns, Values: values}:
+			}
+			sent++
+		}
+		if err := result.Err(); err != nil {
+			return xerrors.Errorf("rows: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return xerrors.Errorf("stream: %w", err)
+	}
+	return nil
+}
+
+func (q *sqlQuerier) ExportAuditLogsPage(ctx context.Context, cursor Cursor, limit int32) ([]AuditLog, Cursor, bool, error) {
+	if limit <= 0 {
+		return nil, cursor, false, xerrors.Errorf("export page limit must be positive, got %d", limit)
+	}
+	// The row comparison matches the ORDER BY, so each page picks up
+	// exactly after the last row of the previous one even when many logs
+	// share a timestamp.
+	const query = `-- name: ExportAuditLogsPage :many
+	SELECT
+		id, "time", user_id, organization_id, ip, user_agent, resource_type, resource_id,
+		resource_target, action, diff, status_code, additional_fields, request_id, resource_icon
+	FROM
+		audit_logs
+	WHERE
+		("time", id) > ($1, $2)
+	ORDER BY
+		"time" ASC, id ASC
+	LIMIT
+		$3
+	`
+
+	logs := []AuditLog{}
+	err := q.db.SelectContext(ctx, &logs, query, cursor.Time, cursor.ID, limit)
+	if err != nil {
+		return nil, cursor, false, xerrors.Errorf("export audit logs page: %w", err)
+	}
+	return logs, nextCursor(cursor, logs), len(logs) < int(limit), nil
+}
+
+// nextCursor returns the cursor after the last of logs, or cursor if there
+// are none.
+func nextCursor(cursor Cursor, logs []AuditLog) Cursor {
+	if len(logs) == 0 {
+		return cursor
+	}
+	last := logs[len(logs)-1]
+	return Cursor{Time: last.Time, ID: last.ID}
+}
+
+func writeCSV(w io.Writer, rows *sql.Rows, maxRows int) error {
+	columns, err := rows.Columns()
+	if err != nil {
+		return xerrors.Errorf("columns: %w", err)
+	}
+	writer := csv.NewWriter(w)
+	err = writer.Write(columns)
+	if err != nil {
+		return xerrors.Errorf("write header: %w", err)
+	}
+
+	values := make([]interface{}, len(columns))
+	pointers := make([]interface{}, len(columns))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+	record := make([]string, len(columns))
+	written := 0
+	for rows.Next() {
+		if maxRows > 0 && written == maxRows {
+			return ErrResultTooLarge
+		}
+		written++
+		err = rows.Scan(pointers...)
+		if err != nil {
+			return xerrors.Errorf("scan: %w", err)
+		}
+		for i, value := range values {
+			record[i] = csvValue(value)
+		}
+		err = writer.Write(record)
+		if err != nil {
+			return xerrors.Errorf("write row: %w", err)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return xerrors.Errorf("rows: %w", err)
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// csvValue formats a driver value the way COPY ... CSV does for common
+// types, with NULL as an empty field.
+func csvValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case []byte:
+		return string(v)
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case bool:
+		if v {
+			return "t"
+		}
+		return "f"
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
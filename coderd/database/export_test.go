@@ -0,0 +1,221 @@
+package database_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/require"
+
+	"github.com/coder/coder/coderd/database"
+)
+
+func TestCopyOut(t *testing.T) {
+	t.Parallel()
+
+	sqlDB, connector := newRecordingDB()
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	connector.rows = func(string) ([]string, [][]driver.Value) {
+		at := time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC)
+		return []string{"id", "action", "time", "success"}, [][]driver.Value{
+			{int64(1), []byte("create"), at, true},
+			{int64(2), []byte("say \"hi\", then delete"), nil, false},
+		}
+	}
+	db := database.New(sqlDB)
+	ctx := context.Background()
+
+	var buf bytes.Buffer
+	n, err := db.CopyOut(ctx, "SELECT id, action, time, success FROM audit_logs;", &buf)
+	require.NoError(t, err)
+	require.Equal(t, "id,action,time,success\n"+
+		"1,create,2022-10-01T12:00:00Z,t\n"+
+		"2,\"say \"\"hi\"\", then delete\",,f\n", buf.String())
+	require.EqualValues(t, buf.Len(), n)
+
+	for _, query := range []string{
+		"DELETE FROM audit_logs",
+		"COPY audit_logs TO STDOUT",
+		"SELECT 1; DELETE FROM audit_logs",
+	} {
+		_, err = db.CopyOut(ctx, query, &buf)
+		require.Error(t, err, query)
+	}
+
+	// The export could not be made read-only inside a read-write
+	// transaction.
+	err = db.InTx(func(tx database.Store) error {
+		_, err := tx.CopyOut(ctx, "SELECT id FROM audit_logs", io.Discard)
+		return err
+	})
+	require.ErrorContains(t, err, "must not be called inside a transaction")
+}
+
+func TestStreamCompressed(t *testing.T) {
+	t.Parallel()
+
+	sqlDB, connector := newRecordingDB()
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	connector.rows = func(string) ([]string, [][]driver.Value) {
+		values := make([][]driver.Value, 1000)
+		for i := range values {
+			values[i] = []driver.Value{int64(i), []byte("workspace build started")}
+		}
+		return []string{"id", "action"}, values
+	}
+	db := database.New(sqlDB)
+	ctx := context.Background()
+
+	var want strings.Builder
+	want.WriteString("id,action\n")
+	for i := 0; i < 1000; i++ {
+		_, _ = fmt.Fprintf(&want, "%d,workspace build started\n", i)
+	}
+	decompress := map[string]func(r io.Reader) (io.Reader, error){
+		database.CodecGzip: func(r io.Reader) (io.Reader, error) {
+			return gzip.NewReader(r)
+		},
+		database.CodecZstd: func(r io.Reader) (io.Reader, error) {
+			decoder, err := zstd.NewReader(r)
+			if err != nil {
+				return nil, err
+			}
+			t.Cleanup(decoder.Close)
+			return decoder, nil
+		},
+	}
+	for codec, newReader := range decompress {
+		var buf bytes.Buffer
+		n, err := db.StreamCompressed(ctx, "SELECT id, action FROM audit_logs WHERE action != $1", []interface{}{"delete"}, &buf, codec)
+		require.NoError(t, err, codec)
+		require.EqualValues(t, buf.Len(), n, codec)
+		require.Less(t, buf.Len(), want.Len()/10, "%s should compress repetitive text", codec)
+
+		reader, err := newReader(&buf)
+		require.NoError(t, err, codec)
+		got, err := io.ReadAll(reader)
+		require.NoError(t, err, codec)
+		require.Equal(t, want.String(), string(got), codec)
+	}
+
+	// A truncated export does not end with a valid trailer, so a client
+	// decompressing it sees an error rather than a short export. The
+	// export is large enough that compressed data was already written.
+	largeDB, largeConnector := newRecordingDB()
+	t.Cleanup(func() { _ = largeDB.Close() })
+	largeConnector.rows = func(string) ([]string, [][]driver.Value) {
+		values := make([][]driver.Value, 20000)
+		for i := range values {
+			values[i] = []driver.Value{int64(i), []byte("workspace build started")}
+		}
+		return []string{"id", "action"}, values
+	}
+	limited := database.New(largeDB, database.WithMaxResultRows(15000))
+	for codec, newReader := range decompress {
+		var buf bytes.Buffer
+		n, err := limited.StreamCompressed(ctx, "SELECT id, action FROM audit_logs", nil, &buf, codec)
+		require.ErrorIs(t, err, database.ErrResultTooLarge, codec)
+		require.Positive(t, n, codec)
+
+		reader, err := newReader(&buf)
+		if err == nil {
+			_, err = io.ReadAll(reader)
+		}
+		require.Error(t, err, "%s stream must not be complete", codec)
+	}
+
+	_, err := db.StreamCompressed(ctx, "SELECT 1", nil, io.Discard, "br")
+	require.Error(t, err, "unsupported codec")
+	_, err = db.StreamCompressed(ctx, "DELETE FROM audit_logs", nil, io.Discard, database.CodecGzip)
+	require.Error(t, err, "not a select")
+}
+
+func TestStreamInto(t *testing.T) {
+	t.Parallel()
+
+	const total = 50
+	newDB := func(t *testing.T) (database.Store, *int64) {
+		sqlDB, connector := newRecordingDB()
+		t.Cleanup(func() { _ = sqlDB.Close() })
+		var produced int64
+		connector.next = func() { atomic.AddInt64(&produced, 1) }
+		connector.rows = func(string) ([]string, [][]driver.Value) {
+			values := make([][]driver.Value, total)
+			for i := range values {
+				values[i] = []driver.Value{int64(i), []byte("action")}
+			}
+			return []string{"id", "action"}, values
+		}
+		return database.New(sqlDB), &produced
+	}
+
+	t.Run("SlowConsumer", func(t *testing.T) {
+		t.Parallel()
+		db, produced := newDB(t)
+		const buffer = 4
+		rows := make(chan database.Row, buffer)
+		done := make(chan error, 1)
+		go func() {
+			done <- db.StreamInto(context.Background(), "SELECT id, action FROM audit_logs WHERE id > $1", []interface{}{0}, rows)
+		}()
+
+		consumed := 0
+		for row := range rows {
+			require.Equal(t, []string{"id", "action"}, row.Columns)
+			require.Equal(t, []interface{}{int64(consumed), []byte("action")}, row.Values)
+			consumed++
+			time.Sleep(time.Millisecond)
+			// One more row may have been read and be waiting to be sent.
+			require.LessOrEqual(t, atomic.LoadInt64(produced), int64(consumed+buffer+1), "reading is throttled by the consumer")
+		}
+		require.NoError(t, <-done)
+		require.Equal(t, total, consumed)
+	})
+
+	t.Run("Canceled", func(t *testing.T) {
+		t.Parallel()
+		db, produced := newDB(t)
+		ctx, cancel := context.WithCancel(context.Background())
+		rows := make(chan database.Row)
+		done := make(chan error, 1)
+		go func() {
+			done <- db.StreamInto(ctx, "SELECT id, action FROM audit_logs", nil, rows)
+		}()
+		<-rows
+		cancel()
+		require.ErrorIs(t, <-done, context.Canceled)
+		_, open := <-rows
+		require.False(t, open, "the channel is closed")
+		require.Less(t, atomic.LoadInt64(produced), int64(total))
+	})
+
+	t.Run("NotSelect", func(t *testing.T) {
+		t.Parallel()
+		db, _ := newDB(t)
+		rows := make(chan database.Row, 1)
+		err := db.StreamInto(context.Background(), "DELETE FROM audit_logs", nil, rows)
+		require.Error(t, err)
+		_, open := <-rows
+		require.False(t, open, "the channel is closed")
+	})
+
+	t.Run("InTx", func(t *testing.T) {
+		t.Parallel()
+		db, _ := newDB(t)
+		rows := make(chan database.Row, 1)
+		err := db.InTx(func(tx database.Store) error {
+			return tx.StreamInto(context.Background(), "SELECT id FROM audit_logs", nil, rows)
+		})
+		require.ErrorContains(t, err, "must not be called inside a transaction")
+		_, open := <-rows
+		require.False(t, open, "the channel is closed")
+	})
+}
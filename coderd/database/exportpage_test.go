@@ -0,0 +1,77 @@
+package database_test
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"github.com/tabbed/pqtype"
+
+	"github.com/coder/coder/coderd/database"
+	"github.com/coder/coder/coderd/database/dbtestutil"
+)
+
+func TestExportAuditLogsPage(t *testing.T) {
+	t.Parallel()
+
+	db, _ := dbtestutil.NewDB(t)
+	ctx := context.Background()
+	now := database.Now()
+
+	// Several logs share each timestamp so pages split ties.
+	const count = 7
+	for i := 0; i < count; i++ {
+		_, err := db.InsertAuditLog(ctx, database.InsertAuditLogParams{
+			ID:               uuid.New(),
+			Time:             now.Add(time.Duration(i/3) * time.Second),
+			UserID:           uuid.New(),
+			OrganizationID:   uuid.New(),
+			Ip:               pqtype.Inet{IPNet: net.IPNet{IP: net.IPv4(127, 0, 0, 1), Mask: net.CIDRMask(32, 32)}, Valid: true},
+			ResourceType:     database.ResourceTypeWorkspace,
+			ResourceID:       uuid.New(),
+			Action:           database.AuditActionCreate,
+			Diff:             []byte("{}"),
+			AdditionalFields: []byte("{}"),
+			RequestID:        uuid.New(),
+		})
+		require.NoError(t, err)
+	}
+
+	var (
+		cursor database.Cursor
+		seen   = map[uuid.UUID]bool{}
+		last   time.Time
+		pages  int
+	)
+	for {
+		logs, next, done, err := db.ExportAuditLogsPage(ctx, cursor, 2)
+		require.NoError(t, err)
+		pages++
+		for _, log := range logs {
+			require.False(t, seen[log.ID], "log exported twice")
+			require.False(t, log.Time.Before(last), "logs out of order")
+			seen[log.ID] = true
+			last = log.Time
+		}
+		cursor = next
+		if done {
+			break
+		}
+		require.Len(t, logs, 2)
+	}
+	require.Len(t, seen, count)
+	require.Equal(t, 4, pages)
+
+	// A finished cursor resumes with nothing left.
+	logs, next, done, err := db.ExportAuditLogsPage(ctx, cursor, 2)
+	require.NoError(t, err)
+	require.Empty(t, logs)
+	require.True(t, done)
+	require.Equal(t, cursor, next)
+
+	_, _, _, err = db.ExportAuditLogsPage(ctx, database.Cursor{}, 0)
+	require.Error(t, err)
+}
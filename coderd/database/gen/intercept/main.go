@@ -0,0 +1,284 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/xerrors"
+)
+
+const header = `// Code generated by gen/intercept. DO NOT EDIT.
+package database
+`
+
+// root is the interface whose methods are wrapped. Store-level methods such
+// as InTx are hand-written in interceptor.go.
+const root = "querier"
+
+func main() {
+	if err := run(); err != nil {
+		panic(err)
+	}
+}
+
+type method struct {
+	name    string
+	params  []param
+	results []string
+	// imports maps package names used in the signature to import paths.
+	imports map[string]string
+}
+
+type param struct {
+	name     string
+	typ      string
+	variadic bool
+}
+
+func run() error {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, ".", func(info os.FileInfo) bool {
+		return !strings.HasSuffix(info.Name(), "_test.go")
+	}, parser.ParseComments)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "error: %s must be run in the database directory\n", os.Args[0])
+		return err
+	}
+	pkg, ok := pkgs["database"]
+	if !ok {
+		return xerrors.New("database package not found")
+	}
+
+	interfaces := map[string]*ast.InterfaceType{}
+	fileOf := map[string]*ast.File{}
+	for _, file := range pkg.Files {
+		ast.Inspect(file, func(n ast.Node) bool {
+			spec, ok := n.(*ast.TypeSpec)
+			if !ok {
+				return true
+			}
+			if iface, ok := spec.Type.(*ast.InterfaceType); ok {
+				interfaces[spec.Name.Name] = iface
+				fileOf[spec.Name.Name] = file
+			}
+			return false
+		})
+	}
+
+	var methods []method
+	seen := map[string]bool{}
+	var collect func(name string) error
+	collect = func(name string) error {
+		iface, ok := interfaces[name]
+		if !ok {
+			return xerrors.Errorf("interface %q not found", name)
+		}
+		for _, field := range iface.Methods.List {
+			switch typ := field.Type.(type) {
+			case *ast.Ident:
+				err := collect(typ.Name)
+				if err != nil {
+					return err
+				}
+			case *ast.FuncType:
+				for _, n := range field.Names {
+					if seen[n.Name] {
+						continue
+					}
+					seen[n.Name] = true
+					m, err := parseMethod(fset, fileOf[name], n.Name, typ)
+					if err != nil {
+						return err
+					}
+					methods = append(methods, m)
+				}
+			default:
+				return xerrors.Errorf("unsupported embedded type %T in %s", typ, name)
+			}
+		}
+		return nil
+	}
+	err = collect(root)
+	if err != nil {
+		return err
+	}
+	sort.Slice(methods, func(i, j int) bool {
+		return methods[i].name < methods[j].name
+	})
+
+	src, err := generate(methods)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile("intercept.go", src, 0o600)
+}
+
+func parseMethod(fset *token.FileSet, file *ast.File, name string, fn *ast.FuncType) (method, error) {
+	m := method{
+		name:    name,
+		imports: map[string]string{},
+	}
+	fileImports := map[string]string{}
+	for _, imp := range file.Imports {
+		path, _ := strconv.Unquote(imp.Path.Value)
+		pkgName := path[strings.LastIndex(path, "/")+1:]
+		if imp.Name != nil {
+			pkgName = imp.Name.Name
+		}
+		fileImports[pkgName] = path
+	}
+	exprString := func(expr ast.Expr) string {
+		ast.Inspect(expr, func(n ast.Node) bool {
+			if sel, ok := n.(*ast.SelectorExpr); ok {
+				if ident, ok := sel.X.(*ast.Ident); ok {
+					m.imports[ident.Name] = fileImports[ident.Name]
+				}
+				return false
+			}
+			return true
+		})
+		var buf bytes.Buffer
+		_ = printer.Fprint(&buf, fset, expr)
+		return buf.String()
+	}
+
+	index := 0
+	for _, field := range fn.Params.List {
+		names := field.Names
+		if len(names) == 0 {
+			names = []*ast.Ident{{Name: ""}}
+		}
+		for _, n := range names {
+			p := param{name: n.Name}
+			typ := field.Type
+			if ellipsis, ok := typ.(*ast.Ellipsis); ok {
+				p.variadic = true
+				typ = ellipsis.Elt
+			}
+			p.typ = exprString(typ)
+			switch p.name {
+			case "", "_", "s", "res", "err":
+				p.name = fmt.Sprintf("p%d", index)
+			}
+			m.params = append(m.params, p)
+			index++
+		}
+	}
+	if len(m.params) == 0 || m.params[0].typ != "context.Context" {
+		return method{}, xerrors.Errorf("%s: first parameter must be context.Context", name)
+	}
+	m.params[0].name = "ctx"
+
+	if fn.Results != nil {
+		for _, field := range fn.Results.List {
+			count := len(field.Names)
+			if count == 0 {
+				count = 1
+			}
+			for i := 0; i < count; i++ {
+				m.results = append(m.results, exprString(field.Type))
+			}
+		}
+	}
+	if len(m.results) == 0 || m.results[len(m.results)-1] != "error" {
+		return method{}, xerrors.Errorf("%s: last result must be error", name)
+	}
+	return m, nil
+}
+
+func generate(methods []method) ([]byte, error) {
+	imports := map[string]string{}
+	for _, m := range methods {
+		for name, path := range m.imports {
+			imports[name] = path
+		}
+	}
+
+	s := &bytes.Buffer{}
+	_, _ = fmt.Fprint(s, header)
+	_, _ = fmt.Fprintln(s)
+	_, _ = fmt.Fprintln(s, "import (")
+	var std, external, internal []string
+	for name, path := range imports {
+		spec := strconv.Quote(path)
+		if path[strings.LastIndex(path, "/")+1:] != name {
+			spec = name + " " + spec
+		}
+		switch {
+		case strings.HasPrefix(path, "github.com/coder/coder/"):
+			internal = append(internal, spec)
+		case strings.Contains(strings.Split(path, "/")[0], "."):
+			external = append(external, spec)
+		default:
+			std = append(std, spec)
+		}
+	}
+	for i, group := range [][]string{std, external, internal} {
+		sort.Strings(group)
+		for _, spec := range group {
+			_, _ = fmt.Fprintf(s, "\t%s\n", spec)
+		}
+		if i < 2 && len(group) > 0 {
+			_, _ = fmt.Fprintln(s)
+		}
+	}
+	_, _ = fmt.Fprintln(s, ")")
+
+	for _, m := range methods {
+		var (
+			signature []string
+			args      []string
+			callArgs  []string
+		)
+		for _, p := range m.params {
+			typ := p.typ
+			callArg := p.name
+			if p.variadic {
+				typ = "..." + typ
+				callArg += "..."
+			}
+			signature = append(signature, fmt.Sprintf("%s %s", p.name, typ))
+			callArgs = append(callArgs, callArg)
+			if p.name != "ctx" {
+				args = append(args, p.name)
+			}
+		}
+		argList := "nil"
+		if len(args) > 0 {
+			argList = fmt.Sprintf("[]interface{}{%s}", strings.Join(args, ", "))
+		}
+		values := m.results[:len(m.results)-1]
+		results := strings.Join(m.results, ", ")
+		if len(m.results) > 1 {
+			results = "(" + results + ")"
+		}
+
+		_, _ = fmt.Fprintf(s, "\nfunc (s *interceptedStore) %s(%s) %s {\n", m.name, strings.Join(signature, ", "), results)
+		if len(values) == 0 {
+			_, _ = fmt.Fprintf(s, "\t_, err := s.intercept(ctx, %q, %s, func(ctx context.Context) ([]interface{}, error) {\n", m.name, argList)
+			_, _ = fmt.Fprintf(s, "\t\treturn nil, s.store.%s(%s)\n", m.name, strings.Join(callArgs, ", "))
+			_, _ = fmt.Fprint(s, "\t})\n\treturn err\n}\n")
+			continue
+		}
+		var names, returns []string
+		for i, typ := range values {
+			names = append(names, fmt.Sprintf("r%d", i))
+			returns = append(returns, fmt.Sprintf("resultAt[%s](res, %d)", typ, i))
+		}
+		_, _ = fmt.Fprintf(s, "\tres, err := s.intercept(ctx, %q, %s, func(ctx context.Context) ([]interface{}, error) {\n", m.name, argList)
+		_, _ = fmt.Fprintf(s, "\t\t%s, err := s.store.%s(%s)\n", strings.Join(names, ", "), m.name, strings.Join(callArgs, ", "))
+		_, _ = fmt.Fprintf(s, "\t\treturn []interface{}{%s}, err\n", strings.Join(names, ", "))
+		_, _ = fmt.Fprintf(s, "\t})\n\treturn %s, err\n}\n", strings.Join(returns, ", "))
+	}
+
+	return format.Source(s.Bytes())
+}
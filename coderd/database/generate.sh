@@ -52,4 +52,7 @@ SCRIPT_DIR=$(dirname "${BASH_SOURCE[0]}")
 
 	# Generate enums (e.g. unique constraints).
 	go run gen/enum/main.go
+
+	# Generate the interceptor wrapper for every query method.
+	go run gen/intercept/main.go
 )
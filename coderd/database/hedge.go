@@ -0,0 +1,124 @@
+package database
+
+import (
+	"context"
+	"reflect"
+	"time"
+)
+
+// WithHedgedReads sends a second copy of a read that has not returned
+// within delay, and uses whichever copy returns first, cancelling the
+// other. The copy goes to the pool given to WithSecondaryPool, or to
+// another connection of the primary pool if there is none. Only methods
+// classified as reads by name are hedged, and only outside transactions
+// and WithConn, so writes and anything relying on one session never run
+// twice. Only reads scanned through SelectContext or GetContext are
+// hedged, since they are fully read before returning; *sql.Rows and
+// *sql.Row offer no hook to end the winner's context once the caller is
+// done, so queries returning them, which includes the generated ones, run
+// on the primary alone. Hedging trades extra load, at most one extra query
+// per slow read, for lower tail latency, so delay should be near the
+// reads' p95.
+func WithHedgedReads(delay time.Duration) Option {
+	return func(o *options) {
+		o.hedgedReads = true
+		o.hedgeDelay = delay
+	}
+}
+
+// hedgedDB races reads on DBTX against a delayed copy on hedge.
+type hedgedDB struct {
+	DBTX
+	hedge DBTX
+	delay time.Duration
+}
+
+type hedgeResult struct {
+	value interface{}
+	err   error
+}
+
+// race runs attempt on the main DBTX and, if it has not returned within the
+// delay, on the hedge DBTX too. The first to return wins, and both contexts
+// are cancelled before race returns, so attempt must be done with its
+// connection by the time it returns.
+func (h *hedgedDB) race(ctx context.Context, attempt func(context.Context, DBTX) (interface{}, error)) (interface{}, error) {
+	// Buffered for both attempts, so the loser never blocks.
+	results := make(chan hedgeResult, 2)
+	var cancels []context.CancelFunc
+	defer func() {
+		for _, cancel := range cancels {
+			cancel()
+		}
+	}()
+	start := func(db DBTX) {
+		attemptCtx, cancel := context.WithCancel(ctx)
+		cancels = append(cancels, cancel)
+		go func() {
+			value, err := attempt(attemptCtx, db)
+			results <- hedgeResult{value: value, err: err}
+		}()
+	}
+
+	start(h.DBTX)
+	timer := time.NewTimer(h.delay)
+	defer timer.Stop()
+	var winner hedgeResult
+	select {
+	case winner = <-results:
+	case <-timer.C:
+		start(h.hedge)
+		winner = <-results
+	}
+	return winner.value, winner.err
+}
+
+// isHedgeable reports whether a query may race on the hedge pool. Reads
+// with a minimum LSN or QueryTagForcePrimary are not hedged, since the
+// hedge pool may be behind, and neither is GetCurrentLSN, which fails on a
+// replica.
+func isHedgeable(ctx context.Context, query string) bool {
+	if _, ok := MinLSNFromContext(ctx); ok || hasQueryTag(ctx, QueryTagForcePrimary) {
+		return false
+	}
+	method := queryMethod(query)
+	return method != "GetCurrentLSN" && isReadMethod(method)
+}
+
+func (h *hedgedDB) SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	if !isHedgeable(ctx, query) {
+		return h.DBTX.SelectContext(ctx, dest, query, args...)
+	}
+	return h.raceScan(ctx, dest, func(ctx context.Context, db DBTX, dest interface{}) error {
+		return db.SelectContext(ctx, dest, query, args...)
+	})
+}
+
+func (h *hedgedDB) GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	if !isHedgeable(ctx, query) {
+		return h.DBTX.GetContext(ctx, dest, query, args...)
+	}
+	return h.raceScan(ctx, dest, func(ctx context.Context, db DBTX, dest interface{}) error {
+		return db.GetContext(ctx, dest, query, args...)
+	})
+}
+
+// raceScan races scan, giving each attempt its own destination so the loser
+// cannot write into dest after the winner returns.
+func (h *hedgedDB) raceScan(ctx context.Context, dest interface{}, scan func(context.Context, DBTX, interface{}) error) error {
+	destType := reflect.TypeOf(dest)
+	if destType == nil || destType.Kind() != reflect.Pointer {
+		// Let sqlx report the invalid destination.
+		return scan(ctx, h.DBTX, dest)
+	}
+	value, err := h.race(ctx, func(ctx context.Context, db DBTX) (interface{}, error) {
+		attemptDest := reflect.New(destType.Elem())
+		return attemptDest, scan(ctx, db, attemptDest.Interface())
+	})
+	if err != nil {
+		return err
+	}
+	// nolint:forcetypeassert
+	reflect.ValueOf(dest).Elem().Set(value.(reflect.Value).Elem())
+	return nil
+}
@@ -0,0 +1,74 @@
+package database_test
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/coder/coder/coderd/database"
+)
+
+func TestWithHedgedReads(t *testing.T) {
+	t.Parallel()
+
+	primaryDB, primary := newRecordingDB()
+	t.Cleanup(func() { _ = primaryDB.Close() })
+	secondaryDB, secondary := newRecordingDB()
+	t.Cleanup(func() { _ = secondaryDB.Close() })
+	// Reads on the primary stall until cancelled or 100ms pass.
+	canceled := make(chan struct{}, 1)
+	primary.hook = func(ctx context.Context, query string) error {
+		if !strings.Contains(query, "GetWorkspacesModifiedSince") && !strings.Contains(query, "GetAPIKeyByID") {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			canceled <- struct{}{}
+			return ctx.Err()
+		case <-time.After(100 * time.Millisecond):
+			return nil
+		}
+	}
+	won := make(chan context.Context, 1)
+	secondary.hook = func(ctx context.Context, query string) error {
+		if strings.Contains(query, "GetWorkspacesModifiedSince") {
+			won <- ctx
+		}
+		return nil
+	}
+	db := database.New(primaryDB,
+		database.WithSecondaryPool(secondaryDB),
+		database.WithHedgedReads(10*time.Millisecond),
+	)
+	ctx := context.Background()
+
+	_, err := db.GetWorkspacesModifiedSince(ctx, database.GetWorkspacesModifiedSinceParams{Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, secondary.Queries(), 1, "slow read is hedged")
+	select {
+	case <-canceled:
+	case <-time.After(time.Second):
+		t.Fatal("slower read was not cancelled")
+	}
+	winner := <-won
+	require.Error(t, winner.Err(), "the winner's context is cancelled once it is scanned")
+
+	// The generated query returns a *sql.Row, which is not hedged.
+	_, err = db.GetAPIKeyByID(ctx, "a")
+	require.ErrorIs(t, err, sql.ErrNoRows)
+	require.Len(t, secondary.Queries(), 1, "rows are read from the primary alone")
+
+	require.NoError(t, db.DeleteAPIKeyByID(ctx, "a"))
+	require.Len(t, secondary.Queries(), 1, "writes are never hedged")
+
+	err = db.InTx(func(tx database.Store) error {
+		_, err := tx.GetWorkspacesModifiedSince(ctx, database.GetWorkspacesModifiedSinceParams{Limit: 1})
+		return err
+	})
+	require.NoError(t, err)
+	require.Len(t, secondary.Queries(), 1, "reads in transactions are never hedged")
+}
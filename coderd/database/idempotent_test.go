@@ -0,0 +1,76 @@
+//go:build linux
+
+package database_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/coder/coder/coderd/database"
+	"github.com/coder/coder/coderd/database/dbtestutil"
+)
+
+func TestInsertWorkspaceIdempotent(t *testing.T) {
+	t.Parallel()
+
+	db, _ := dbtestutil.NewDB(t)
+	ctx := context.Background()
+	user, org, template := insertTemplate(t, db)
+	params := func() database.InsertWorkspaceParams {
+		return database.InsertWorkspaceParams{
+			ID:             uuid.New(),
+			CreatedAt:      database.Now(),
+			UpdatedAt:      database.Now(),
+			OwnerID:        user.ID,
+			OrganizationID: org.ID,
+			TemplateID:     template.ID,
+			Name:           "retried",
+		}
+	}
+
+	_, _, err := db.InsertWorkspaceIdempotent(ctx, "", params())
+	require.Error(t, err, "empty key")
+
+	const racers = 8
+	var (
+		wg         sync.WaitGroup
+		workspaces [racers]database.Workspace
+		created    [racers]bool
+		errs       [racers]error
+	)
+	for i := 0; i < racers; i++ {
+		i := i
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			workspaces[i], created[i], errs[i] = db.InsertWorkspaceIdempotent(ctx, "create-retried", params())
+		}()
+	}
+	wg.Wait()
+
+	creators := 0
+	for i := 0; i < racers; i++ {
+		require.NoError(t, errs[i])
+		require.Equal(t, workspaces[0].ID, workspaces[i].ID, "every caller sees the same workspace")
+		if created[i] {
+			creators++
+		}
+	}
+	require.Equal(t, 1, creators, "exactly one caller inserts")
+
+	retry, ok, err := db.InsertWorkspaceIdempotent(ctx, "create-retried", params())
+	require.NoError(t, err)
+	require.False(t, ok)
+	require.Equal(t, workspaces[0].ID, retry.ID)
+
+	other := params()
+	other.Name = "other"
+	workspace, ok, err := db.InsertWorkspaceIdempotent(ctx, "create-other", other)
+	require.NoError(t, err)
+	require.True(t, ok, "a new key inserts")
+	require.Equal(t, other.ID, workspace.ID)
+}
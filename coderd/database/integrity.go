@@ -0,0 +1,136 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"golang.org/x/xerrors"
+)
+
+// maxViolationRows is the most row IDs reported per violated check.
+const maxViolationRows = 100
+
+// IntegrityCheck is an invariant across tables that foreign keys do not
+// enforce. Query is a single SELECT whose first column identifies each row
+// breaking the invariant; an empty result means it holds.
+type IntegrityCheck struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Query       string `json:"query"`
+}
+
+// IntegrityViolation is a check that found rows breaking its invariant.
+// RowIDs holds up to the first 100 of them, as text, and Count all of them.
+type IntegrityViolation struct {
+	Check  string   `json:"check"`
+	Count  int64    `json:"count"`
+	RowIDs []string `json:"row_ids"`
+}
+
+// DefaultIntegrityChecks are the invariants of the core tables that the
+// schema cannot express.
+var DefaultIntegrityChecks = []IntegrityCheck{
+	{
+		Name:        "workspace_without_build",
+		Description: "Every workspace that is not deleted has at least one build.",
+		Query: `SELECT id FROM workspaces WHERE NOT deleted
+			AND NOT EXISTS (SELECT 1 FROM workspace_builds WHERE workspace_id = workspaces.id)`,
+	},
+	{
+		Name:        "template_active_version_missing",
+		Description: "Every template's active version exists and belongs to the template.",
+		Query: `SELECT id FROM templates WHERE NOT EXISTS (
+			SELECT 1 FROM template_versions
+			WHERE template_versions.id = templates.active_version_id AND template_versions.template_id = templates.id)`,
+	},
+	{
+		Name:        "build_template_mismatch",
+		Description: "Every build's template version belongs to its workspace's template.",
+		Query: `SELECT workspace_builds.id FROM workspace_builds
+			JOIN workspaces ON workspaces.id = workspace_builds.workspace_id
+			JOIN template_versions ON template_versions.id = workspace_builds.template_version_id
+			WHERE template_versions.template_id IS DISTINCT FROM workspaces.template_id`,
+	},
+	{
+		Name:        "build_initiator_missing",
+		Description: "Every build was initiated by an existing user.",
+		Query: `SELECT id FROM workspace_builds
+			WHERE NOT EXISTS (SELECT 1 FROM users WHERE users.id = workspace_builds.initiator_id)`,
+	},
+	{
+		Name:        "build_job_mismatch",
+		Description: "Every build's job is a workspace build job.",
+		Query: `SELECT workspace_builds.id FROM workspace_builds
+			JOIN provisioner_jobs ON provisioner_jobs.id = workspace_builds.job_id
+			WHERE provisioner_jobs.type != 'workspace_build'`,
+	},
+}
+
+type integrityQuerier interface {
+	// RunIntegrityChecks runs checks, such as DefaultIntegrityChecks, in a
+	// single read-only repeatable read transaction, so they all see the
+	// same snapshot and a change committed halfway through cannot make
+	// them disagree. It returns the violated checks in the order given;
+	// none means every invariant holds. The checks may scan whole tables,
+	// so run them periodically off the request path. It cannot be called
+	// inside a transaction.
+	RunIntegrityChecks(ctx context.Context, checks []IntegrityCheck) ([]IntegrityViolation, error)
+}
+
+func (q *sqlQuerier) RunIntegrityChecks(ctx context.Context, checks []IntegrityCheck) ([]IntegrityViolation, error) {
+	if q.inTx {
+		return nil, xerrors.New("run integrity checks must not be called inside a transaction")
+	}
+	queries := make([]string, 0, len(checks))
+	for _, check := range checks {
+		query, err := singleSelect(check.Query)
+		if err != nil {
+			return nil, xerrors.Errorf("integrity check %q: %w", check.Name, err)
+		}
+		// The window count sees every row, while LIMIT only keeps the
+		// first ones.
+		queries = append(queries, fmt.Sprintf(`-- name: RunIntegrityChecks :many
+		SELECT violation::text, count(*) OVER () FROM (%s) AS violations(violation) LIMIT %d`, query, maxViolationRows))
+	}
+
+	violations := []IntegrityViolation{}
+	err := q.InTxOpts(ctx, TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true}, func(tx Store) error {
+		db := txQuerier(tx).db
+		for i, check := range checks {
+			violation, err := runIntegrityCheck(ctx, db, check.Name, queries[i])
+			if err != nil {
+				return err
+			}
+			if violation.Count > 0 {
+				violations = append(violations, violation)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, xerrors.Errorf("run integrity checks: %w", err)
+	}
+	return violations, nil
+}
+
+func runIntegrityCheck(ctx context.Context, db DBTX, name, query string) (IntegrityViolation, error) {
+	violation := IntegrityViolation{Check: name, RowIDs: []string{}}
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return IntegrityViolation{}, xerrors.Errorf("check %q: %w", name, err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id sql.NullString
+		err := rows.Scan(&id, &violation.Count)
+		if err != nil {
+			return IntegrityViolation{}, xerrors.Errorf("check %q: scan: %w", name, err)
+		}
+		violation.RowIDs = append(violation.RowIDs, id.String)
+	}
+	if err := rows.Err(); err != nil {
+		return IntegrityViolation{}, xerrors.Errorf("check %q: %w", name, err)
+	}
+	return violation, nil
+}
@@ -0,0 +1,95 @@
+//go:build linux
+
+package database_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/coder/coder/coderd/database"
+	"github.com/coder/coder/coderd/database/migrations"
+)
+
+func TestRunIntegrityChecks(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Query", func(t *testing.T) {
+		t.Parallel()
+		sqlDB, connector := newRecordingDB()
+		t.Cleanup(func() { _ = sqlDB.Close() })
+		connector.rows = func(query string) ([]string, [][]driver.Value) {
+			if strings.Contains(query, "FROM broken") {
+				return []string{"violation", "count"}, [][]driver.Value{{"a", int64(250)}, {"b", int64(250)}}
+			}
+			return []string{"violation", "count"}, nil
+		}
+		db := database.New(sqlDB)
+		ctx := context.Background()
+
+		violations, err := db.RunIntegrityChecks(ctx, []database.IntegrityCheck{
+			{Name: "ok", Query: "SELECT id FROM fine"},
+			{Name: "broken", Query: "SELECT id FROM broken;"},
+		})
+		require.NoError(t, err)
+		require.Equal(t, []database.IntegrityViolation{
+			{Check: "broken", Count: 250, RowIDs: []string{"a", "b"}},
+		}, violations)
+		queries := connector.Queries()
+		require.Len(t, queries, 2)
+		require.Contains(t, queries[1], "FROM (SELECT id FROM broken) AS violations(violation) LIMIT 100")
+
+		_, err = db.RunIntegrityChecks(ctx, []database.IntegrityCheck{
+			{Name: "write", Query: "DELETE FROM workspaces"},
+		})
+		require.Error(t, err, "checks must be reads")
+		err = db.InTx(func(tx database.Store) error {
+			_, err := tx.RunIntegrityChecks(ctx, database.DefaultIntegrityChecks)
+			return err
+		})
+		require.ErrorContains(t, err, "must not be called inside a transaction")
+		require.Len(t, connector.Queries(), 2)
+	})
+
+	t.Run("Postgres", func(t *testing.T) {
+		t.Parallel()
+		if testing.Short() {
+			t.SkipNow()
+		}
+
+		sqlDB := testSQLDB(t)
+		err := migrations.Up(sqlDB)
+		require.NoError(t, err, "migrations")
+		db := database.New(sqlDB)
+		ctx := context.Background()
+
+		violations, err := db.RunIntegrityChecks(ctx, database.DefaultIntegrityChecks)
+		require.NoError(t, err)
+		require.Empty(t, violations, "an empty database is consistent")
+
+		// The helper's template has no active version, and the workspace
+		// never got a build.
+		user, org, template := insertTemplate(t, db)
+		workspace, err := db.InsertWorkspace(ctx, database.InsertWorkspaceParams{
+			ID:             uuid.New(),
+			CreatedAt:      database.Now(),
+			UpdatedAt:      database.Now(),
+			OwnerID:        user.ID,
+			OrganizationID: org.ID,
+			TemplateID:     template.ID,
+			Name:           "broken",
+		})
+		require.NoError(t, err)
+
+		violations, err = db.RunIntegrityChecks(ctx, database.DefaultIntegrityChecks)
+		require.NoError(t, err)
+		require.Equal(t, []database.IntegrityViolation{
+			{Check: "workspace_without_build", Count: 1, RowIDs: []string{workspace.ID.String()}},
+			{Check: "template_active_version_missing", Count: 1, RowIDs: []string{template.ID.String()}},
+		}, violations)
+	})
+}
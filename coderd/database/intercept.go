@@ -0,0 +1,1900 @@
+// Code generated by gen/intercept. DO NOT EDIT.
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/coder/coder/coderd/rbac"
+)
+
+func (s *interceptedStore) AcquireLease(ctx context.Context, name string, owner uuid.UUID, ttl time.Duration) (bool, error) {
+	res, err := s.intercept(ctx, "AcquireLease", []interface{}{name, owner, ttl}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.AcquireLease(ctx, name, owner, ttl)
+		return []interface{}{r0}, err
+	})
+	return resultAt[bool](res, 0), err
+}
+
+func (s *interceptedStore) AcquireProvisionerJob(ctx context.Context, arg AcquireProvisionerJobParams) (ProvisionerJob, error) {
+	res, err := s.intercept(ctx, "AcquireProvisionerJob", []interface{}{arg}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.AcquireProvisionerJob(ctx, arg)
+		return []interface{}{r0}, err
+	})
+	return resultAt[ProvisionerJob](res, 0), err
+}
+
+func (s *interceptedStore) ArchiveAuditLogs(ctx context.Context, olderThan time.Time) (int64, error) {
+	res, err := s.intercept(ctx, "ArchiveAuditLogs", []interface{}{olderThan}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.ArchiveAuditLogs(ctx, olderThan)
+		return []interface{}{r0}, err
+	})
+	return resultAt[int64](res, 0), err
+}
+
+func (s *interceptedStore) CheckConstraintViolations(ctx context.Context, table string, constraintSQL string) (int64, error) {
+	res, err := s.intercept(ctx, "CheckConstraintViolations", []interface{}{table, constraintSQL}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.CheckConstraintViolations(ctx, table, constraintSQL)
+		return []interface{}{r0}, err
+	})
+	return resultAt[int64](res, 0), err
+}
+
+func (s *interceptedStore) CheckEncoding(ctx context.Context) error {
+	_, err := s.intercept(ctx, "CheckEncoding", nil, func(ctx context.Context) ([]interface{}, error) {
+		return nil, s.store.CheckEncoding(ctx)
+	})
+	return err
+}
+
+func (s *interceptedStore) CheckGroupsExist(ctx context.Context, ids []uuid.UUID) ([]uuid.UUID, error) {
+	res, err := s.intercept(ctx, "CheckGroupsExist", []interface{}{ids}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.CheckGroupsExist(ctx, ids)
+		return []interface{}{r0}, err
+	})
+	return resultAt[[]uuid.UUID](res, 0), err
+}
+
+func (s *interceptedStore) CheckRLSEnabled(ctx context.Context, tables []string) ([]RLSStatus, error) {
+	res, err := s.intercept(ctx, "CheckRLSEnabled", []interface{}{tables}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.CheckRLSEnabled(ctx, tables)
+		return []interface{}{r0}, err
+	})
+	return resultAt[[]RLSStatus](res, 0), err
+}
+
+func (s *interceptedStore) CheckServerVersion(ctx context.Context, minVersion int) error {
+	_, err := s.intercept(ctx, "CheckServerVersion", []interface{}{minVersion}, func(ctx context.Context) ([]interface{}, error) {
+		return nil, s.store.CheckServerVersion(ctx, minVersion)
+	})
+	return err
+}
+
+func (s *interceptedStore) CheckTemplatesExist(ctx context.Context, ids []uuid.UUID) ([]uuid.UUID, error) {
+	res, err := s.intercept(ctx, "CheckTemplatesExist", []interface{}{ids}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.CheckTemplatesExist(ctx, ids)
+		return []interface{}{r0}, err
+	})
+	return resultAt[[]uuid.UUID](res, 0), err
+}
+
+func (s *interceptedStore) CheckUsersExist(ctx context.Context, ids []uuid.UUID) ([]uuid.UUID, error) {
+	res, err := s.intercept(ctx, "CheckUsersExist", []interface{}{ids}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.CheckUsersExist(ctx, ids)
+		return []interface{}{r0}, err
+	})
+	return resultAt[[]uuid.UUID](res, 0), err
+}
+
+func (s *interceptedStore) ClaimPendingBuilds(ctx context.Context, workerID uuid.UUID, limit int32) ([]WorkspaceBuild, error) {
+	res, err := s.intercept(ctx, "ClaimPendingBuilds", []interface{}{workerID, limit}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.ClaimPendingBuilds(ctx, workerID, limit)
+		return []interface{}{r0}, err
+	})
+	return resultAt[[]WorkspaceBuild](res, 0), err
+}
+
+func (s *interceptedStore) CommitPrepared(ctx context.Context, gid string) error {
+	_, err := s.intercept(ctx, "CommitPrepared", []interface{}{gid}, func(ctx context.Context) ([]interface{}, error) {
+		return nil, s.store.CommitPrepared(ctx, gid)
+	})
+	return err
+}
+
+func (s *interceptedStore) CopyOut(ctx context.Context, query string, w io.Writer) (int64, error) {
+	res, err := s.intercept(ctx, "CopyOut", []interface{}{query, w}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.CopyOut(ctx, query, w)
+		return []interface{}{r0}, err
+	})
+	return resultAt[int64](res, 0), err
+}
+
+func (s *interceptedStore) DBNow(ctx context.Context) (time.Time, error) {
+	res, err := s.intercept(ctx, "DBNow", nil, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.DBNow(ctx)
+		return []interface{}{r0}, err
+	})
+	return resultAt[time.Time](res, 0), err
+}
+
+func (s *interceptedStore) DeleteAPIKeyByID(ctx context.Context, id string) error {
+	_, err := s.intercept(ctx, "DeleteAPIKeyByID", []interface{}{id}, func(ctx context.Context) ([]interface{}, error) {
+		return nil, s.store.DeleteAPIKeyByID(ctx, id)
+	})
+	return err
+}
+
+func (s *interceptedStore) DeleteAPIKeysByUserID(ctx context.Context, userID uuid.UUID) error {
+	_, err := s.intercept(ctx, "DeleteAPIKeysByUserID", []interface{}{userID}, func(ctx context.Context) ([]interface{}, error) {
+		return nil, s.store.DeleteAPIKeysByUserID(ctx, userID)
+	})
+	return err
+}
+
+func (s *interceptedStore) DeleteExpiredInChunks(ctx context.Context, table string, olderThan time.Time, chunkSize int) (int64, error) {
+	res, err := s.intercept(ctx, "DeleteExpiredInChunks", []interface{}{table, olderThan, chunkSize}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.DeleteExpiredInChunks(ctx, table, olderThan, chunkSize)
+		return []interface{}{r0}, err
+	})
+	return resultAt[int64](res, 0), err
+}
+
+func (s *interceptedStore) DeleteGitSSHKey(ctx context.Context, userID uuid.UUID) error {
+	_, err := s.intercept(ctx, "DeleteGitSSHKey", []interface{}{userID}, func(ctx context.Context) ([]interface{}, error) {
+		return nil, s.store.DeleteGitSSHKey(ctx, userID)
+	})
+	return err
+}
+
+func (s *interceptedStore) DeleteGroupByID(ctx context.Context, id uuid.UUID) error {
+	_, err := s.intercept(ctx, "DeleteGroupByID", []interface{}{id}, func(ctx context.Context) ([]interface{}, error) {
+		return nil, s.store.DeleteGroupByID(ctx, id)
+	})
+	return err
+}
+
+func (s *interceptedStore) DeleteGroupMember(ctx context.Context, userID uuid.UUID) error {
+	_, err := s.intercept(ctx, "DeleteGroupMember", []interface{}{userID}, func(ctx context.Context) ([]interface{}, error) {
+		return nil, s.store.DeleteGroupMember(ctx, userID)
+	})
+	return err
+}
+
+func (s *interceptedStore) DeleteLicense(ctx context.Context, id int32) (int32, error) {
+	res, err := s.intercept(ctx, "DeleteLicense", []interface{}{id}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.DeleteLicense(ctx, id)
+		return []interface{}{r0}, err
+	})
+	return resultAt[int32](res, 0), err
+}
+
+func (s *interceptedStore) DeleteOldAgentStats(ctx context.Context) error {
+	_, err := s.intercept(ctx, "DeleteOldAgentStats", nil, func(ctx context.Context) ([]interface{}, error) {
+		return nil, s.store.DeleteOldAgentStats(ctx)
+	})
+	return err
+}
+
+func (s *interceptedStore) DeleteParameterValueByID(ctx context.Context, id uuid.UUID) error {
+	_, err := s.intercept(ctx, "DeleteParameterValueByID", []interface{}{id}, func(ctx context.Context) ([]interface{}, error) {
+		return nil, s.store.DeleteParameterValueByID(ctx, id)
+	})
+	return err
+}
+
+func (s *interceptedStore) DeleteReplicasUpdatedBefore(ctx context.Context, updatedAt time.Time) error {
+	_, err := s.intercept(ctx, "DeleteReplicasUpdatedBefore", []interface{}{updatedAt}, func(ctx context.Context) ([]interface{}, error) {
+		return nil, s.store.DeleteReplicasUpdatedBefore(ctx, updatedAt)
+	})
+	return err
+}
+
+func (s *interceptedStore) DequeueOutbox(ctx context.Context, limit int32) ([]OutboxEvent, error) {
+	res, err := s.intercept(ctx, "DequeueOutbox", []interface{}{limit}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.DequeueOutbox(ctx, limit)
+		return []interface{}{r0}, err
+	})
+	return resultAt[[]OutboxEvent](res, 0), err
+}
+
+func (s *interceptedStore) DumpSchema(ctx context.Context) (string, error) {
+	res, err := s.intercept(ctx, "DumpSchema", nil, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.DumpSchema(ctx)
+		return []interface{}{r0}, err
+	})
+	return resultAt[string](res, 0), err
+}
+
+func (s *interceptedStore) EnqueueOutbox(ctx context.Context, topic string, payload json.RawMessage) (OutboxEvent, error) {
+	res, err := s.intercept(ctx, "EnqueueOutbox", []interface{}{topic, payload}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.EnqueueOutbox(ctx, topic, payload)
+		return []interface{}{r0}, err
+	})
+	return resultAt[OutboxEvent](res, 0), err
+}
+
+func (s *interceptedStore) ExplainQuery(ctx context.Context, method string, sampleArgs ...interface{}) (PlanSummary, error) {
+	res, err := s.intercept(ctx, "ExplainQuery", []interface{}{method, sampleArgs}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.ExplainQuery(ctx, method, sampleArgs...)
+		return []interface{}{r0}, err
+	})
+	return resultAt[PlanSummary](res, 0), err
+}
+
+func (s *interceptedStore) ExportAuditLogsPage(ctx context.Context, cursor Cursor, limit int32) ([]AuditLog, Cursor, bool, error) {
+	res, err := s.intercept(ctx, "ExportAuditLogsPage", []interface{}{cursor, limit}, func(ctx context.Context) ([]interface{}, error) {
+		r0, r1, r2, err := s.store.ExportAuditLogsPage(ctx, cursor, limit)
+		return []interface{}{r0, r1, r2}, err
+	})
+	return resultAt[[]AuditLog](res, 0), resultAt[Cursor](res, 1), resultAt[bool](res, 2), err
+}
+
+func (s *interceptedStore) FindDuplicates(ctx context.Context, table string, columns []string) ([]DuplicateGroup, error) {
+	res, err := s.intercept(ctx, "FindDuplicates", []interface{}{table, columns}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.FindDuplicates(ctx, table, columns)
+		return []interface{}{r0}, err
+	})
+	return resultAt[[]DuplicateGroup](res, 0), err
+}
+
+func (s *interceptedStore) FindOrphanedRows(ctx context.Context, childTable string, parentTable string, fkColumn string) ([]uuid.UUID, error) {
+	res, err := s.intercept(ctx, "FindOrphanedRows", []interface{}{childTable, parentTable, fkColumn}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.FindOrphanedRows(ctx, childTable, parentTable, fkColumn)
+		return []interface{}{r0}, err
+	})
+	return resultAt[[]uuid.UUID](res, 0), err
+}
+
+func (s *interceptedStore) GetAPIKeyByID(ctx context.Context, id string) (APIKey, error) {
+	res, err := s.intercept(ctx, "GetAPIKeyByID", []interface{}{id}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetAPIKeyByID(ctx, id)
+		return []interface{}{r0}, err
+	})
+	return resultAt[APIKey](res, 0), err
+}
+
+func (s *interceptedStore) GetAPIKeysByLoginType(ctx context.Context, loginType LoginType) ([]APIKey, error) {
+	res, err := s.intercept(ctx, "GetAPIKeysByLoginType", []interface{}{loginType}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetAPIKeysByLoginType(ctx, loginType)
+		return []interface{}{r0}, err
+	})
+	return resultAt[[]APIKey](res, 0), err
+}
+
+func (s *interceptedStore) GetAPIKeysLastUsedAfter(ctx context.Context, lastUsed time.Time) ([]APIKey, error) {
+	res, err := s.intercept(ctx, "GetAPIKeysLastUsedAfter", []interface{}{lastUsed}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetAPIKeysLastUsedAfter(ctx, lastUsed)
+		return []interface{}{r0}, err
+	})
+	return resultAt[[]APIKey](res, 0), err
+}
+
+func (s *interceptedStore) GetActiveUserCount(ctx context.Context) (int64, error) {
+	res, err := s.intercept(ctx, "GetActiveUserCount", nil, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetActiveUserCount(ctx)
+		return []interface{}{r0}, err
+	})
+	return resultAt[int64](res, 0), err
+}
+
+func (s *interceptedStore) GetAllOrganizationMembers(ctx context.Context, organizationID uuid.UUID) ([]User, error) {
+	res, err := s.intercept(ctx, "GetAllOrganizationMembers", []interface{}{organizationID}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetAllOrganizationMembers(ctx, organizationID)
+		return []interface{}{r0}, err
+	})
+	return resultAt[[]User](res, 0), err
+}
+
+func (s *interceptedStore) GetApproximateRowCount(ctx context.Context, table string) (int64, error) {
+	res, err := s.intercept(ctx, "GetApproximateRowCount", []interface{}{table}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetApproximateRowCount(ctx, table)
+		return []interface{}{r0}, err
+	})
+	return resultAt[int64](res, 0), err
+}
+
+func (s *interceptedStore) GetAuditLogCount(ctx context.Context, arg GetAuditLogCountParams) (int64, error) {
+	res, err := s.intercept(ctx, "GetAuditLogCount", []interface{}{arg}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetAuditLogCount(ctx, arg)
+		return []interface{}{r0}, err
+	})
+	return resultAt[int64](res, 0), err
+}
+
+func (s *interceptedStore) GetAuditLogsOffset(ctx context.Context, arg GetAuditLogsOffsetParams) ([]GetAuditLogsOffsetRow, error) {
+	res, err := s.intercept(ctx, "GetAuditLogsOffset", []interface{}{arg}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetAuditLogsOffset(ctx, arg)
+		return []interface{}{r0}, err
+	})
+	return resultAt[[]GetAuditLogsOffsetRow](res, 0), err
+}
+
+func (s *interceptedStore) GetAuthorizationUserRoles(ctx context.Context, userID uuid.UUID) (GetAuthorizationUserRolesRow, error) {
+	res, err := s.intercept(ctx, "GetAuthorizationUserRoles", []interface{}{userID}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetAuthorizationUserRoles(ctx, userID)
+		return []interface{}{r0}, err
+	})
+	return resultAt[GetAuthorizationUserRolesRow](res, 0), err
+}
+
+func (s *interceptedStore) GetAuthorizedWorkspaceCount(ctx context.Context, arg GetWorkspaceCountParams, authorizedFilter rbac.AuthorizeFilter) (int64, error) {
+	res, err := s.intercept(ctx, "GetAuthorizedWorkspaceCount", []interface{}{arg, authorizedFilter}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetAuthorizedWorkspaceCount(ctx, arg, authorizedFilter)
+		return []interface{}{r0}, err
+	})
+	return resultAt[int64](res, 0), err
+}
+
+func (s *interceptedStore) GetAuthorizedWorkspaces(ctx context.Context, arg GetWorkspacesParams, authorizedFilter rbac.AuthorizeFilter) ([]Workspace, error) {
+	res, err := s.intercept(ctx, "GetAuthorizedWorkspaces", []interface{}{arg, authorizedFilter}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetAuthorizedWorkspaces(ctx, arg, authorizedFilter)
+		return []interface{}{r0}, err
+	})
+	return resultAt[[]Workspace](res, 0), err
+}
+
+func (s *interceptedStore) GetBlockingLocks(ctx context.Context) ([]LockWait, error) {
+	res, err := s.intercept(ctx, "GetBlockingLocks", nil, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetBlockingLocks(ctx)
+		return []interface{}{r0}, err
+	})
+	return resultAt[[]LockWait](res, 0), err
+}
+
+func (s *interceptedStore) GetCacheHitRatio(ctx context.Context) (float64, error) {
+	res, err := s.intercept(ctx, "GetCacheHitRatio", nil, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetCacheHitRatio(ctx)
+		return []interface{}{r0}, err
+	})
+	return resultAt[float64](res, 0), err
+}
+
+func (s *interceptedStore) GetConsistentRowCounts(ctx context.Context, tables []string) (map[string]int64, error) {
+	res, err := s.intercept(ctx, "GetConsistentRowCounts", []interface{}{tables}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetConsistentRowCounts(ctx, tables)
+		return []interface{}{r0}, err
+	})
+	return resultAt[map[string]int64](res, 0), err
+}
+
+func (s *interceptedStore) GetCounts(ctx context.Context) (Counts, error) {
+	res, err := s.intercept(ctx, "GetCounts", nil, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetCounts(ctx)
+		return []interface{}{r0}, err
+	})
+	return resultAt[Counts](res, 0), err
+}
+
+func (s *interceptedStore) GetCurrentLSN(ctx context.Context) (LSN, error) {
+	res, err := s.intercept(ctx, "GetCurrentLSN", nil, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetCurrentLSN(ctx)
+		return []interface{}{r0}, err
+	})
+	return resultAt[LSN](res, 0), err
+}
+
+func (s *interceptedStore) GetDERPMeshKey(ctx context.Context) (string, error) {
+	res, err := s.intercept(ctx, "GetDERPMeshKey", nil, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetDERPMeshKey(ctx)
+		return []interface{}{r0}, err
+	})
+	return resultAt[string](res, 0), err
+}
+
+func (s *interceptedStore) GetDatabaseSize(ctx context.Context) (int64, error) {
+	res, err := s.intercept(ctx, "GetDatabaseSize", nil, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetDatabaseSize(ctx)
+		return []interface{}{r0}, err
+	})
+	return resultAt[int64](res, 0), err
+}
+
+func (s *interceptedStore) GetDeploymentID(ctx context.Context) (string, error) {
+	res, err := s.intercept(ctx, "GetDeploymentID", nil, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetDeploymentID(ctx)
+		return []interface{}{r0}, err
+	})
+	return resultAt[string](res, 0), err
+}
+
+func (s *interceptedStore) GetDisabledConstraints(ctx context.Context) ([]ConstraintInfo, error) {
+	res, err := s.intercept(ctx, "GetDisabledConstraints", nil, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetDisabledConstraints(ctx)
+		return []interface{}{r0}, err
+	})
+	return resultAt[[]ConstraintInfo](res, 0), err
+}
+
+func (s *interceptedStore) GetDistinctValues(ctx context.Context, table string, column string) ([]string, error) {
+	res, err := s.intercept(ctx, "GetDistinctValues", []interface{}{table, column}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetDistinctValues(ctx, table, column)
+		return []interface{}{r0}, err
+	})
+	return resultAt[[]string](res, 0), err
+}
+
+func (s *interceptedStore) GetFileByHashAndCreator(ctx context.Context, arg GetFileByHashAndCreatorParams) (File, error) {
+	res, err := s.intercept(ctx, "GetFileByHashAndCreator", []interface{}{arg}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetFileByHashAndCreator(ctx, arg)
+		return []interface{}{r0}, err
+	})
+	return resultAt[File](res, 0), err
+}
+
+func (s *interceptedStore) GetFileByID(ctx context.Context, id uuid.UUID) (File, error) {
+	res, err := s.intercept(ctx, "GetFileByID", []interface{}{id}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetFileByID(ctx, id)
+		return []interface{}{r0}, err
+	})
+	return resultAt[File](res, 0), err
+}
+
+func (s *interceptedStore) GetForeignKeyDependents(ctx context.Context, table string) ([]string, error) {
+	res, err := s.intercept(ctx, "GetForeignKeyDependents", []interface{}{table}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetForeignKeyDependents(ctx, table)
+		return []interface{}{r0}, err
+	})
+	return resultAt[[]string](res, 0), err
+}
+
+func (s *interceptedStore) GetGitSSHKey(ctx context.Context, userID uuid.UUID) (GitSSHKey, error) {
+	res, err := s.intercept(ctx, "GetGitSSHKey", []interface{}{userID}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetGitSSHKey(ctx, userID)
+		return []interface{}{r0}, err
+	})
+	return resultAt[GitSSHKey](res, 0), err
+}
+
+func (s *interceptedStore) GetGroupByID(ctx context.Context, id uuid.UUID) (Group, error) {
+	res, err := s.intercept(ctx, "GetGroupByID", []interface{}{id}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetGroupByID(ctx, id)
+		return []interface{}{r0}, err
+	})
+	return resultAt[Group](res, 0), err
+}
+
+func (s *interceptedStore) GetGroupByOrgAndName(ctx context.Context, arg GetGroupByOrgAndNameParams) (Group, error) {
+	res, err := s.intercept(ctx, "GetGroupByOrgAndName", []interface{}{arg}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetGroupByOrgAndName(ctx, arg)
+		return []interface{}{r0}, err
+	})
+	return resultAt[Group](res, 0), err
+}
+
+func (s *interceptedStore) GetGroupMembers(ctx context.Context, groupID uuid.UUID) ([]User, error) {
+	res, err := s.intercept(ctx, "GetGroupMembers", []interface{}{groupID}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetGroupMembers(ctx, groupID)
+		return []interface{}{r0}, err
+	})
+	return resultAt[[]User](res, 0), err
+}
+
+func (s *interceptedStore) GetGroupsByOrganizationID(ctx context.Context, organizationID uuid.UUID) ([]Group, error) {
+	res, err := s.intercept(ctx, "GetGroupsByOrganizationID", []interface{}{organizationID}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetGroupsByOrganizationID(ctx, organizationID)
+		return []interface{}{r0}, err
+	})
+	return resultAt[[]Group](res, 0), err
+}
+
+func (s *interceptedStore) GetIdleInTransaction(ctx context.Context, olderThan time.Duration) ([]ConnInfo, error) {
+	res, err := s.intercept(ctx, "GetIdleInTransaction", []interface{}{olderThan}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetIdleInTransaction(ctx, olderThan)
+		return []interface{}{r0}, err
+	})
+	return resultAt[[]ConnInfo](res, 0), err
+}
+
+func (s *interceptedStore) GetLatestAgentStat(ctx context.Context, agentID uuid.UUID) (AgentStat, error) {
+	res, err := s.intercept(ctx, "GetLatestAgentStat", []interface{}{agentID}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetLatestAgentStat(ctx, agentID)
+		return []interface{}{r0}, err
+	})
+	return resultAt[AgentStat](res, 0), err
+}
+
+func (s *interceptedStore) GetLatestWorkspaceBuildByWorkspaceID(ctx context.Context, workspaceID uuid.UUID) (WorkspaceBuild, error) {
+	res, err := s.intercept(ctx, "GetLatestWorkspaceBuildByWorkspaceID", []interface{}{workspaceID}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetLatestWorkspaceBuildByWorkspaceID(ctx, workspaceID)
+		return []interface{}{r0}, err
+	})
+	return resultAt[WorkspaceBuild](res, 0), err
+}
+
+func (s *interceptedStore) GetLatestWorkspaceBuilds(ctx context.Context) ([]WorkspaceBuild, error) {
+	res, err := s.intercept(ctx, "GetLatestWorkspaceBuilds", nil, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetLatestWorkspaceBuilds(ctx)
+		return []interface{}{r0}, err
+	})
+	return resultAt[[]WorkspaceBuild](res, 0), err
+}
+
+func (s *interceptedStore) GetLatestWorkspaceBuildsByWorkspaceIDs(ctx context.Context, ids []uuid.UUID) ([]WorkspaceBuild, error) {
+	res, err := s.intercept(ctx, "GetLatestWorkspaceBuildsByWorkspaceIDs", []interface{}{ids}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetLatestWorkspaceBuildsByWorkspaceIDs(ctx, ids)
+		return []interface{}{r0}, err
+	})
+	return resultAt[[]WorkspaceBuild](res, 0), err
+}
+
+func (s *interceptedStore) GetLicenses(ctx context.Context) ([]License, error) {
+	res, err := s.intercept(ctx, "GetLicenses", nil, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetLicenses(ctx)
+		return []interface{}{r0}, err
+	})
+	return resultAt[[]License](res, 0), err
+}
+
+func (s *interceptedStore) GetOrganizationByID(ctx context.Context, id uuid.UUID) (Organization, error) {
+	res, err := s.intercept(ctx, "GetOrganizationByID", []interface{}{id}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetOrganizationByID(ctx, id)
+		return []interface{}{r0}, err
+	})
+	return resultAt[Organization](res, 0), err
+}
+
+func (s *interceptedStore) GetOrganizationByName(ctx context.Context, name string) (Organization, error) {
+	res, err := s.intercept(ctx, "GetOrganizationByName", []interface{}{name}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetOrganizationByName(ctx, name)
+		return []interface{}{r0}, err
+	})
+	return resultAt[Organization](res, 0), err
+}
+
+func (s *interceptedStore) GetOrganizationIDsByMemberIDs(ctx context.Context, ids []uuid.UUID) ([]GetOrganizationIDsByMemberIDsRow, error) {
+	res, err := s.intercept(ctx, "GetOrganizationIDsByMemberIDs", []interface{}{ids}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetOrganizationIDsByMemberIDs(ctx, ids)
+		return []interface{}{r0}, err
+	})
+	return resultAt[[]GetOrganizationIDsByMemberIDsRow](res, 0), err
+}
+
+func (s *interceptedStore) GetOrganizationMemberByUserID(ctx context.Context, arg GetOrganizationMemberByUserIDParams) (OrganizationMember, error) {
+	res, err := s.intercept(ctx, "GetOrganizationMemberByUserID", []interface{}{arg}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetOrganizationMemberByUserID(ctx, arg)
+		return []interface{}{r0}, err
+	})
+	return resultAt[OrganizationMember](res, 0), err
+}
+
+func (s *interceptedStore) GetOrganizationMembershipsByUserID(ctx context.Context, userID uuid.UUID) ([]OrganizationMember, error) {
+	res, err := s.intercept(ctx, "GetOrganizationMembershipsByUserID", []interface{}{userID}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetOrganizationMembershipsByUserID(ctx, userID)
+		return []interface{}{r0}, err
+	})
+	return resultAt[[]OrganizationMember](res, 0), err
+}
+
+func (s *interceptedStore) GetOrganizations(ctx context.Context) ([]Organization, error) {
+	res, err := s.intercept(ctx, "GetOrganizations", nil, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetOrganizations(ctx)
+		return []interface{}{r0}, err
+	})
+	return resultAt[[]Organization](res, 0), err
+}
+
+func (s *interceptedStore) GetOrganizationsByUserID(ctx context.Context, userID uuid.UUID) ([]Organization, error) {
+	res, err := s.intercept(ctx, "GetOrganizationsByUserID", []interface{}{userID}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetOrganizationsByUserID(ctx, userID)
+		return []interface{}{r0}, err
+	})
+	return resultAt[[]Organization](res, 0), err
+}
+
+func (s *interceptedStore) GetParameterSchemasByJobID(ctx context.Context, jobID uuid.UUID) ([]ParameterSchema, error) {
+	res, err := s.intercept(ctx, "GetParameterSchemasByJobID", []interface{}{jobID}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetParameterSchemasByJobID(ctx, jobID)
+		return []interface{}{r0}, err
+	})
+	return resultAt[[]ParameterSchema](res, 0), err
+}
+
+func (s *interceptedStore) GetParameterSchemasCreatedAfter(ctx context.Context, createdAt time.Time) ([]ParameterSchema, error) {
+	res, err := s.intercept(ctx, "GetParameterSchemasCreatedAfter", []interface{}{createdAt}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetParameterSchemasCreatedAfter(ctx, createdAt)
+		return []interface{}{r0}, err
+	})
+	return resultAt[[]ParameterSchema](res, 0), err
+}
+
+func (s *interceptedStore) GetParameterValueByScopeAndName(ctx context.Context, arg GetParameterValueByScopeAndNameParams) (ParameterValue, error) {
+	res, err := s.intercept(ctx, "GetParameterValueByScopeAndName", []interface{}{arg}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetParameterValueByScopeAndName(ctx, arg)
+		return []interface{}{r0}, err
+	})
+	return resultAt[ParameterValue](res, 0), err
+}
+
+func (s *interceptedStore) GetProvisionerDaemonByID(ctx context.Context, id uuid.UUID) (ProvisionerDaemon, error) {
+	res, err := s.intercept(ctx, "GetProvisionerDaemonByID", []interface{}{id}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetProvisionerDaemonByID(ctx, id)
+		return []interface{}{r0}, err
+	})
+	return resultAt[ProvisionerDaemon](res, 0), err
+}
+
+func (s *interceptedStore) GetProvisionerDaemons(ctx context.Context) ([]ProvisionerDaemon, error) {
+	res, err := s.intercept(ctx, "GetProvisionerDaemons", nil, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetProvisionerDaemons(ctx)
+		return []interface{}{r0}, err
+	})
+	return resultAt[[]ProvisionerDaemon](res, 0), err
+}
+
+func (s *interceptedStore) GetProvisionerJobByID(ctx context.Context, id uuid.UUID) (ProvisionerJob, error) {
+	res, err := s.intercept(ctx, "GetProvisionerJobByID", []interface{}{id}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetProvisionerJobByID(ctx, id)
+		return []interface{}{r0}, err
+	})
+	return resultAt[ProvisionerJob](res, 0), err
+}
+
+func (s *interceptedStore) GetProvisionerJobsByIDs(ctx context.Context, ids []uuid.UUID) ([]ProvisionerJob, error) {
+	res, err := s.intercept(ctx, "GetProvisionerJobsByIDs", []interface{}{ids}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetProvisionerJobsByIDs(ctx, ids)
+		return []interface{}{r0}, err
+	})
+	return resultAt[[]ProvisionerJob](res, 0), err
+}
+
+func (s *interceptedStore) GetProvisionerJobsByIDsMustFindAll(ctx context.Context, ids []uuid.UUID) ([]ProvisionerJob, error) {
+	res, err := s.intercept(ctx, "GetProvisionerJobsByIDsMustFindAll", []interface{}{ids}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetProvisionerJobsByIDsMustFindAll(ctx, ids)
+		return []interface{}{r0}, err
+	})
+	return resultAt[[]ProvisionerJob](res, 0), err
+}
+
+func (s *interceptedStore) GetProvisionerJobsByIDsOrdered(ctx context.Context, ids []uuid.UUID) ([]ProvisionerJob, error) {
+	res, err := s.intercept(ctx, "GetProvisionerJobsByIDsOrdered", []interface{}{ids}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetProvisionerJobsByIDsOrdered(ctx, ids)
+		return []interface{}{r0}, err
+	})
+	return resultAt[[]ProvisionerJob](res, 0), err
+}
+
+func (s *interceptedStore) GetProvisionerJobsCreatedAfter(ctx context.Context, createdAt time.Time) ([]ProvisionerJob, error) {
+	res, err := s.intercept(ctx, "GetProvisionerJobsCreatedAfter", []interface{}{createdAt}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetProvisionerJobsCreatedAfter(ctx, createdAt)
+		return []interface{}{r0}, err
+	})
+	return resultAt[[]ProvisionerJob](res, 0), err
+}
+
+func (s *interceptedStore) GetProvisionerLogsByIDBetween(ctx context.Context, arg GetProvisionerLogsByIDBetweenParams) ([]ProvisionerJobLog, error) {
+	res, err := s.intercept(ctx, "GetProvisionerLogsByIDBetween", []interface{}{arg}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetProvisionerLogsByIDBetween(ctx, arg)
+		return []interface{}{r0}, err
+	})
+	return resultAt[[]ProvisionerJobLog](res, 0), err
+}
+
+func (s *interceptedStore) GetReplicasUpdatedAfter(ctx context.Context, updatedAt time.Time) ([]Replica, error) {
+	res, err := s.intercept(ctx, "GetReplicasUpdatedAfter", []interface{}{updatedAt}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetReplicasUpdatedAfter(ctx, updatedAt)
+		return []interface{}{r0}, err
+	})
+	return resultAt[[]Replica](res, 0), err
+}
+
+func (s *interceptedStore) GetShardedCounter(ctx context.Context, name string) (int64, error) {
+	res, err := s.intercept(ctx, "GetShardedCounter", []interface{}{name}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetShardedCounter(ctx, name)
+		return []interface{}{r0}, err
+	})
+	return resultAt[int64](res, 0), err
+}
+
+func (s *interceptedStore) GetTableBloat(ctx context.Context) ([]TableBloat, error) {
+	res, err := s.intercept(ctx, "GetTableBloat", nil, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetTableBloat(ctx)
+		return []interface{}{r0}, err
+	})
+	return resultAt[[]TableBloat](res, 0), err
+}
+
+func (s *interceptedStore) GetTableXIDAge(ctx context.Context, limit int32) ([]TableXIDAge, error) {
+	res, err := s.intercept(ctx, "GetTableXIDAge", []interface{}{limit}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetTableXIDAge(ctx, limit)
+		return []interface{}{r0}, err
+	})
+	return resultAt[[]TableXIDAge](res, 0), err
+}
+
+func (s *interceptedStore) GetTablespaceSizes(ctx context.Context) ([]TablespaceSpace, error) {
+	res, err := s.intercept(ctx, "GetTablespaceSizes", nil, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetTablespaceSizes(ctx)
+		return []interface{}{r0}, err
+	})
+	return resultAt[[]TablespaceSpace](res, 0), err
+}
+
+func (s *interceptedStore) GetTemplateAverageBuildTime(ctx context.Context, arg GetTemplateAverageBuildTimeParams) (GetTemplateAverageBuildTimeRow, error) {
+	res, err := s.intercept(ctx, "GetTemplateAverageBuildTime", []interface{}{arg}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetTemplateAverageBuildTime(ctx, arg)
+		return []interface{}{r0}, err
+	})
+	return resultAt[GetTemplateAverageBuildTimeRow](res, 0), err
+}
+
+func (s *interceptedStore) GetTemplateByID(ctx context.Context, id uuid.UUID) (Template, error) {
+	res, err := s.intercept(ctx, "GetTemplateByID", []interface{}{id}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetTemplateByID(ctx, id)
+		return []interface{}{r0}, err
+	})
+	return resultAt[Template](res, 0), err
+}
+
+func (s *interceptedStore) GetTemplateByIDForShare(ctx context.Context, id uuid.UUID) (Template, error) {
+	res, err := s.intercept(ctx, "GetTemplateByIDForShare", []interface{}{id}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetTemplateByIDForShare(ctx, id)
+		return []interface{}{r0}, err
+	})
+	return resultAt[Template](res, 0), err
+}
+
+func (s *interceptedStore) GetTemplateByOrganizationAndName(ctx context.Context, arg GetTemplateByOrganizationAndNameParams) (Template, error) {
+	res, err := s.intercept(ctx, "GetTemplateByOrganizationAndName", []interface{}{arg}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetTemplateByOrganizationAndName(ctx, arg)
+		return []interface{}{r0}, err
+	})
+	return resultAt[Template](res, 0), err
+}
+
+func (s *interceptedStore) GetTemplateDAUs(ctx context.Context, templateID uuid.UUID) ([]GetTemplateDAUsRow, error) {
+	res, err := s.intercept(ctx, "GetTemplateDAUs", []interface{}{templateID}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetTemplateDAUs(ctx, templateID)
+		return []interface{}{r0}, err
+	})
+	return resultAt[[]GetTemplateDAUsRow](res, 0), err
+}
+
+func (s *interceptedStore) GetTemplateETag(ctx context.Context, id uuid.UUID) (string, error) {
+	res, err := s.intercept(ctx, "GetTemplateETag", []interface{}{id}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetTemplateETag(ctx, id)
+		return []interface{}{r0}, err
+	})
+	return resultAt[string](res, 0), err
+}
+
+func (s *interceptedStore) GetTemplateGroupRoles(ctx context.Context, id uuid.UUID) ([]TemplateGroup, error) {
+	res, err := s.intercept(ctx, "GetTemplateGroupRoles", []interface{}{id}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetTemplateGroupRoles(ctx, id)
+		return []interface{}{r0}, err
+	})
+	return resultAt[[]TemplateGroup](res, 0), err
+}
+
+func (s *interceptedStore) GetTemplateUserRoles(ctx context.Context, id uuid.UUID) ([]TemplateUser, error) {
+	res, err := s.intercept(ctx, "GetTemplateUserRoles", []interface{}{id}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetTemplateUserRoles(ctx, id)
+		return []interface{}{r0}, err
+	})
+	return resultAt[[]TemplateUser](res, 0), err
+}
+
+func (s *interceptedStore) GetTemplateVersionByID(ctx context.Context, id uuid.UUID) (TemplateVersion, error) {
+	res, err := s.intercept(ctx, "GetTemplateVersionByID", []interface{}{id}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetTemplateVersionByID(ctx, id)
+		return []interface{}{r0}, err
+	})
+	return resultAt[TemplateVersion](res, 0), err
+}
+
+func (s *interceptedStore) GetTemplateVersionByJobID(ctx context.Context, jobID uuid.UUID) (TemplateVersion, error) {
+	res, err := s.intercept(ctx, "GetTemplateVersionByJobID", []interface{}{jobID}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetTemplateVersionByJobID(ctx, jobID)
+		return []interface{}{r0}, err
+	})
+	return resultAt[TemplateVersion](res, 0), err
+}
+
+func (s *interceptedStore) GetTemplateVersionByTemplateIDAndName(ctx context.Context, arg GetTemplateVersionByTemplateIDAndNameParams) (TemplateVersion, error) {
+	res, err := s.intercept(ctx, "GetTemplateVersionByTemplateIDAndName", []interface{}{arg}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetTemplateVersionByTemplateIDAndName(ctx, arg)
+		return []interface{}{r0}, err
+	})
+	return resultAt[TemplateVersion](res, 0), err
+}
+
+func (s *interceptedStore) GetTemplateVersionsByTemplateID(ctx context.Context, arg GetTemplateVersionsByTemplateIDParams) ([]TemplateVersion, error) {
+	res, err := s.intercept(ctx, "GetTemplateVersionsByTemplateID", []interface{}{arg}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetTemplateVersionsByTemplateID(ctx, arg)
+		return []interface{}{r0}, err
+	})
+	return resultAt[[]TemplateVersion](res, 0), err
+}
+
+func (s *interceptedStore) GetTemplateVersionsCreatedAfter(ctx context.Context, createdAt time.Time) ([]TemplateVersion, error) {
+	res, err := s.intercept(ctx, "GetTemplateVersionsCreatedAfter", []interface{}{createdAt}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetTemplateVersionsCreatedAfter(ctx, createdAt)
+		return []interface{}{r0}, err
+	})
+	return resultAt[[]TemplateVersion](res, 0), err
+}
+
+func (s *interceptedStore) GetTemplates(ctx context.Context) ([]Template, error) {
+	res, err := s.intercept(ctx, "GetTemplates", nil, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetTemplates(ctx)
+		return []interface{}{r0}, err
+	})
+	return resultAt[[]Template](res, 0), err
+}
+
+func (s *interceptedStore) GetTemplatesWithFilter(ctx context.Context, arg GetTemplatesWithFilterParams) ([]Template, error) {
+	res, err := s.intercept(ctx, "GetTemplatesWithFilter", []interface{}{arg}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetTemplatesWithFilter(ctx, arg)
+		return []interface{}{r0}, err
+	})
+	return resultAt[[]Template](res, 0), err
+}
+
+func (s *interceptedStore) GetTopQueriesByTime(ctx context.Context, limit int32) ([]QueryStat, error) {
+	res, err := s.intercept(ctx, "GetTopQueriesByTime", []interface{}{limit}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetTopQueriesByTime(ctx, limit)
+		return []interface{}{r0}, err
+	})
+	return resultAt[[]QueryStat](res, 0), err
+}
+
+func (s *interceptedStore) GetTxSnapshot(ctx context.Context) (SnapshotInfo, error) {
+	res, err := s.intercept(ctx, "GetTxSnapshot", nil, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetTxSnapshot(ctx)
+		return []interface{}{r0}, err
+	})
+	return resultAt[SnapshotInfo](res, 0), err
+}
+
+func (s *interceptedStore) GetUnexpiredLicenses(ctx context.Context) ([]License, error) {
+	res, err := s.intercept(ctx, "GetUnexpiredLicenses", nil, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetUnexpiredLicenses(ctx)
+		return []interface{}{r0}, err
+	})
+	return resultAt[[]License](res, 0), err
+}
+
+func (s *interceptedStore) GetUnusedIndexes(ctx context.Context) ([]IndexUsage, error) {
+	res, err := s.intercept(ctx, "GetUnusedIndexes", nil, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetUnusedIndexes(ctx)
+		return []interface{}{r0}, err
+	})
+	return resultAt[[]IndexUsage](res, 0), err
+}
+
+func (s *interceptedStore) GetUserByEmailOrUsername(ctx context.Context, arg GetUserByEmailOrUsernameParams) (User, error) {
+	res, err := s.intercept(ctx, "GetUserByEmailOrUsername", []interface{}{arg}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetUserByEmailOrUsername(ctx, arg)
+		return []interface{}{r0}, err
+	})
+	return resultAt[User](res, 0), err
+}
+
+func (s *interceptedStore) GetUserByID(ctx context.Context, id uuid.UUID) (User, error) {
+	res, err := s.intercept(ctx, "GetUserByID", []interface{}{id}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetUserByID(ctx, id)
+		return []interface{}{r0}, err
+	})
+	return resultAt[User](res, 0), err
+}
+
+func (s *interceptedStore) GetUserCount(ctx context.Context) (int64, error) {
+	res, err := s.intercept(ctx, "GetUserCount", nil, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetUserCount(ctx)
+		return []interface{}{r0}, err
+	})
+	return resultAt[int64](res, 0), err
+}
+
+func (s *interceptedStore) GetUserGroups(ctx context.Context, userID uuid.UUID) ([]Group, error) {
+	res, err := s.intercept(ctx, "GetUserGroups", []interface{}{userID}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetUserGroups(ctx, userID)
+		return []interface{}{r0}, err
+	})
+	return resultAt[[]Group](res, 0), err
+}
+
+func (s *interceptedStore) GetUserLinkByLinkedID(ctx context.Context, linkedID string) (UserLink, error) {
+	res, err := s.intercept(ctx, "GetUserLinkByLinkedID", []interface{}{linkedID}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetUserLinkByLinkedID(ctx, linkedID)
+		return []interface{}{r0}, err
+	})
+	return resultAt[UserLink](res, 0), err
+}
+
+func (s *interceptedStore) GetUserLinkByUserIDLoginType(ctx context.Context, arg GetUserLinkByUserIDLoginTypeParams) (UserLink, error) {
+	res, err := s.intercept(ctx, "GetUserLinkByUserIDLoginType", []interface{}{arg}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetUserLinkByUserIDLoginType(ctx, arg)
+		return []interface{}{r0}, err
+	})
+	return resultAt[UserLink](res, 0), err
+}
+
+func (s *interceptedStore) GetUsers(ctx context.Context, arg GetUsersParams) ([]User, error) {
+	res, err := s.intercept(ctx, "GetUsers", []interface{}{arg}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetUsers(ctx, arg)
+		return []interface{}{r0}, err
+	})
+	return resultAt[[]User](res, 0), err
+}
+
+func (s *interceptedStore) GetUsersByIDs(ctx context.Context, ids []uuid.UUID) ([]User, error) {
+	res, err := s.intercept(ctx, "GetUsersByIDs", []interface{}{ids}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetUsersByIDs(ctx, ids)
+		return []interface{}{r0}, err
+	})
+	return resultAt[[]User](res, 0), err
+}
+
+func (s *interceptedStore) GetUsersByIDsMustFindAll(ctx context.Context, ids []uuid.UUID) ([]User, error) {
+	res, err := s.intercept(ctx, "GetUsersByIDsMustFindAll", []interface{}{ids}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetUsersByIDsMustFindAll(ctx, ids)
+		return []interface{}{r0}, err
+	})
+	return resultAt[[]User](res, 0), err
+}
+
+func (s *interceptedStore) GetUsersByIDsOrdered(ctx context.Context, ids []uuid.UUID) ([]User, error) {
+	res, err := s.intercept(ctx, "GetUsersByIDsOrdered", []interface{}{ids}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetUsersByIDsOrdered(ctx, ids)
+		return []interface{}{r0}, err
+	})
+	return resultAt[[]User](res, 0), err
+}
+
+func (s *interceptedStore) GetWaitEvents(ctx context.Context) ([]WaitEventCount, error) {
+	res, err := s.intercept(ctx, "GetWaitEvents", nil, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetWaitEvents(ctx)
+		return []interface{}{r0}, err
+	})
+	return resultAt[[]WaitEventCount](res, 0), err
+}
+
+func (s *interceptedStore) GetWorkspaceAgentByAuthToken(ctx context.Context, authToken uuid.UUID) (WorkspaceAgent, error) {
+	res, err := s.intercept(ctx, "GetWorkspaceAgentByAuthToken", []interface{}{authToken}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetWorkspaceAgentByAuthToken(ctx, authToken)
+		return []interface{}{r0}, err
+	})
+	return resultAt[WorkspaceAgent](res, 0), err
+}
+
+func (s *interceptedStore) GetWorkspaceAgentByID(ctx context.Context, id uuid.UUID) (WorkspaceAgent, error) {
+	res, err := s.intercept(ctx, "GetWorkspaceAgentByID", []interface{}{id}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetWorkspaceAgentByID(ctx, id)
+		return []interface{}{r0}, err
+	})
+	return resultAt[WorkspaceAgent](res, 0), err
+}
+
+func (s *interceptedStore) GetWorkspaceAgentByInstanceID(ctx context.Context, authInstanceID string) (WorkspaceAgent, error) {
+	res, err := s.intercept(ctx, "GetWorkspaceAgentByInstanceID", []interface{}{authInstanceID}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetWorkspaceAgentByInstanceID(ctx, authInstanceID)
+		return []interface{}{r0}, err
+	})
+	return resultAt[WorkspaceAgent](res, 0), err
+}
+
+func (s *interceptedStore) GetWorkspaceAgentsByResourceIDs(ctx context.Context, ids []uuid.UUID) ([]WorkspaceAgent, error) {
+	res, err := s.intercept(ctx, "GetWorkspaceAgentsByResourceIDs", []interface{}{ids}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetWorkspaceAgentsByResourceIDs(ctx, ids)
+		return []interface{}{r0}, err
+	})
+	return resultAt[[]WorkspaceAgent](res, 0), err
+}
+
+func (s *interceptedStore) GetWorkspaceAgentsCreatedAfter(ctx context.Context, createdAt time.Time) ([]WorkspaceAgent, error) {
+	res, err := s.intercept(ctx, "GetWorkspaceAgentsCreatedAfter", []interface{}{createdAt}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetWorkspaceAgentsCreatedAfter(ctx, createdAt)
+		return []interface{}{r0}, err
+	})
+	return resultAt[[]WorkspaceAgent](res, 0), err
+}
+
+func (s *interceptedStore) GetWorkspaceAppByAgentIDAndName(ctx context.Context, arg GetWorkspaceAppByAgentIDAndNameParams) (WorkspaceApp, error) {
+	res, err := s.intercept(ctx, "GetWorkspaceAppByAgentIDAndName", []interface{}{arg}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetWorkspaceAppByAgentIDAndName(ctx, arg)
+		return []interface{}{r0}, err
+	})
+	return resultAt[WorkspaceApp](res, 0), err
+}
+
+func (s *interceptedStore) GetWorkspaceAppsByAgentID(ctx context.Context, agentID uuid.UUID) ([]WorkspaceApp, error) {
+	res, err := s.intercept(ctx, "GetWorkspaceAppsByAgentID", []interface{}{agentID}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetWorkspaceAppsByAgentID(ctx, agentID)
+		return []interface{}{r0}, err
+	})
+	return resultAt[[]WorkspaceApp](res, 0), err
+}
+
+func (s *interceptedStore) GetWorkspaceAppsByAgentIDs(ctx context.Context, ids []uuid.UUID) ([]WorkspaceApp, error) {
+	res, err := s.intercept(ctx, "GetWorkspaceAppsByAgentIDs", []interface{}{ids}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetWorkspaceAppsByAgentIDs(ctx, ids)
+		return []interface{}{r0}, err
+	})
+	return resultAt[[]WorkspaceApp](res, 0), err
+}
+
+func (s *interceptedStore) GetWorkspaceAppsCreatedAfter(ctx context.Context, createdAt time.Time) ([]WorkspaceApp, error) {
+	res, err := s.intercept(ctx, "GetWorkspaceAppsCreatedAfter", []interface{}{createdAt}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetWorkspaceAppsCreatedAfter(ctx, createdAt)
+		return []interface{}{r0}, err
+	})
+	return resultAt[[]WorkspaceApp](res, 0), err
+}
+
+func (s *interceptedStore) GetWorkspaceBuildByID(ctx context.Context, id uuid.UUID) (WorkspaceBuild, error) {
+	res, err := s.intercept(ctx, "GetWorkspaceBuildByID", []interface{}{id}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetWorkspaceBuildByID(ctx, id)
+		return []interface{}{r0}, err
+	})
+	return resultAt[WorkspaceBuild](res, 0), err
+}
+
+func (s *interceptedStore) GetWorkspaceBuildByJobID(ctx context.Context, jobID uuid.UUID) (WorkspaceBuild, error) {
+	res, err := s.intercept(ctx, "GetWorkspaceBuildByJobID", []interface{}{jobID}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetWorkspaceBuildByJobID(ctx, jobID)
+		return []interface{}{r0}, err
+	})
+	return resultAt[WorkspaceBuild](res, 0), err
+}
+
+func (s *interceptedStore) GetWorkspaceBuildByWorkspaceIDAndBuildNumber(ctx context.Context, arg GetWorkspaceBuildByWorkspaceIDAndBuildNumberParams) (WorkspaceBuild, error) {
+	res, err := s.intercept(ctx, "GetWorkspaceBuildByWorkspaceIDAndBuildNumber", []interface{}{arg}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetWorkspaceBuildByWorkspaceIDAndBuildNumber(ctx, arg)
+		return []interface{}{r0}, err
+	})
+	return resultAt[WorkspaceBuild](res, 0), err
+}
+
+func (s *interceptedStore) GetWorkspaceBuildsByWorkspaceID(ctx context.Context, arg GetWorkspaceBuildsByWorkspaceIDParams) ([]WorkspaceBuild, error) {
+	res, err := s.intercept(ctx, "GetWorkspaceBuildsByWorkspaceID", []interface{}{arg}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetWorkspaceBuildsByWorkspaceID(ctx, arg)
+		return []interface{}{r0}, err
+	})
+	return resultAt[[]WorkspaceBuild](res, 0), err
+}
+
+func (s *interceptedStore) GetWorkspaceBuildsCreatedAfter(ctx context.Context, createdAt time.Time) ([]WorkspaceBuild, error) {
+	res, err := s.intercept(ctx, "GetWorkspaceBuildsCreatedAfter", []interface{}{createdAt}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetWorkspaceBuildsCreatedAfter(ctx, createdAt)
+		return []interface{}{r0}, err
+	})
+	return resultAt[[]WorkspaceBuild](res, 0), err
+}
+
+func (s *interceptedStore) GetWorkspaceByID(ctx context.Context, id uuid.UUID) (Workspace, error) {
+	res, err := s.intercept(ctx, "GetWorkspaceByID", []interface{}{id}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetWorkspaceByID(ctx, id)
+		return []interface{}{r0}, err
+	})
+	return resultAt[Workspace](res, 0), err
+}
+
+func (s *interceptedStore) GetWorkspaceByOwnerIDAndName(ctx context.Context, arg GetWorkspaceByOwnerIDAndNameParams) (Workspace, error) {
+	res, err := s.intercept(ctx, "GetWorkspaceByOwnerIDAndName", []interface{}{arg}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetWorkspaceByOwnerIDAndName(ctx, arg)
+		return []interface{}{r0}, err
+	})
+	return resultAt[Workspace](res, 0), err
+}
+
+func (s *interceptedStore) GetWorkspaceCount(ctx context.Context, arg GetWorkspaceCountParams) (int64, error) {
+	res, err := s.intercept(ctx, "GetWorkspaceCount", []interface{}{arg}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetWorkspaceCount(ctx, arg)
+		return []interface{}{r0}, err
+	})
+	return resultAt[int64](res, 0), err
+}
+
+func (s *interceptedStore) GetWorkspaceCountByUserID(ctx context.Context, ownerID uuid.UUID) (int64, error) {
+	res, err := s.intercept(ctx, "GetWorkspaceCountByUserID", []interface{}{ownerID}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetWorkspaceCountByUserID(ctx, ownerID)
+		return []interface{}{r0}, err
+	})
+	return resultAt[int64](res, 0), err
+}
+
+func (s *interceptedStore) GetWorkspaceOwnerCountsByTemplateIDs(ctx context.Context, ids []uuid.UUID) ([]GetWorkspaceOwnerCountsByTemplateIDsRow, error) {
+	res, err := s.intercept(ctx, "GetWorkspaceOwnerCountsByTemplateIDs", []interface{}{ids}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetWorkspaceOwnerCountsByTemplateIDs(ctx, ids)
+		return []interface{}{r0}, err
+	})
+	return resultAt[[]GetWorkspaceOwnerCountsByTemplateIDsRow](res, 0), err
+}
+
+func (s *interceptedStore) GetWorkspaceResourceByID(ctx context.Context, id uuid.UUID) (WorkspaceResource, error) {
+	res, err := s.intercept(ctx, "GetWorkspaceResourceByID", []interface{}{id}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetWorkspaceResourceByID(ctx, id)
+		return []interface{}{r0}, err
+	})
+	return resultAt[WorkspaceResource](res, 0), err
+}
+
+func (s *interceptedStore) GetWorkspaceResourceMetadataByResourceID(ctx context.Context, workspaceResourceID uuid.UUID) ([]WorkspaceResourceMetadatum, error) {
+	res, err := s.intercept(ctx, "GetWorkspaceResourceMetadataByResourceID", []interface{}{workspaceResourceID}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetWorkspaceResourceMetadataByResourceID(ctx, workspaceResourceID)
+		return []interface{}{r0}, err
+	})
+	return resultAt[[]WorkspaceResourceMetadatum](res, 0), err
+}
+
+func (s *interceptedStore) GetWorkspaceResourceMetadataByResourceIDs(ctx context.Context, ids []uuid.UUID) ([]WorkspaceResourceMetadatum, error) {
+	res, err := s.intercept(ctx, "GetWorkspaceResourceMetadataByResourceIDs", []interface{}{ids}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetWorkspaceResourceMetadataByResourceIDs(ctx, ids)
+		return []interface{}{r0}, err
+	})
+	return resultAt[[]WorkspaceResourceMetadatum](res, 0), err
+}
+
+func (s *interceptedStore) GetWorkspaceResourceMetadataCreatedAfter(ctx context.Context, createdAt time.Time) ([]WorkspaceResourceMetadatum, error) {
+	res, err := s.intercept(ctx, "GetWorkspaceResourceMetadataCreatedAfter", []interface{}{createdAt}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetWorkspaceResourceMetadataCreatedAfter(ctx, createdAt)
+		return []interface{}{r0}, err
+	})
+	return resultAt[[]WorkspaceResourceMetadatum](res, 0), err
+}
+
+func (s *interceptedStore) GetWorkspaceResourcesByJobID(ctx context.Context, jobID uuid.UUID) ([]WorkspaceResource, error) {
+	res, err := s.intercept(ctx, "GetWorkspaceResourcesByJobID", []interface{}{jobID}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetWorkspaceResourcesByJobID(ctx, jobID)
+		return []interface{}{r0}, err
+	})
+	return resultAt[[]WorkspaceResource](res, 0), err
+}
+
+func (s *interceptedStore) GetWorkspaceResourcesByJobIDs(ctx context.Context, ids []uuid.UUID) ([]WorkspaceResource, error) {
+	res, err := s.intercept(ctx, "GetWorkspaceResourcesByJobIDs", []interface{}{ids}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetWorkspaceResourcesByJobIDs(ctx, ids)
+		return []interface{}{r0}, err
+	})
+	return resultAt[[]WorkspaceResource](res, 0), err
+}
+
+func (s *interceptedStore) GetWorkspaceResourcesCreatedAfter(ctx context.Context, createdAt time.Time) ([]WorkspaceResource, error) {
+	res, err := s.intercept(ctx, "GetWorkspaceResourcesCreatedAfter", []interface{}{createdAt}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetWorkspaceResourcesCreatedAfter(ctx, createdAt)
+		return []interface{}{r0}, err
+	})
+	return resultAt[[]WorkspaceResource](res, 0), err
+}
+
+func (s *interceptedStore) GetWorkspaceWithBuildsJSON(ctx context.Context, id uuid.UUID) (WorkspaceWithBuilds, error) {
+	res, err := s.intercept(ctx, "GetWorkspaceWithBuildsJSON", []interface{}{id}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetWorkspaceWithBuildsJSON(ctx, id)
+		return []interface{}{r0}, err
+	})
+	return resultAt[WorkspaceWithBuilds](res, 0), err
+}
+
+func (s *interceptedStore) GetWorkspaceWithUserAccess(ctx context.Context, workspaceID uuid.UUID, userID uuid.UUID) (WorkspaceAccess, error) {
+	res, err := s.intercept(ctx, "GetWorkspaceWithUserAccess", []interface{}{workspaceID, userID}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetWorkspaceWithUserAccess(ctx, workspaceID, userID)
+		return []interface{}{r0}, err
+	})
+	return resultAt[WorkspaceAccess](res, 0), err
+}
+
+func (s *interceptedStore) GetWorkspaces(ctx context.Context, arg GetWorkspacesParams) ([]Workspace, error) {
+	res, err := s.intercept(ctx, "GetWorkspaces", []interface{}{arg}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetWorkspaces(ctx, arg)
+		return []interface{}{r0}, err
+	})
+	return resultAt[[]Workspace](res, 0), err
+}
+
+func (s *interceptedStore) GetWorkspacesModifiedSince(ctx context.Context, arg GetWorkspacesModifiedSinceParams) ([]Workspace, error) {
+	res, err := s.intercept(ctx, "GetWorkspacesModifiedSince", []interface{}{arg}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.GetWorkspacesModifiedSince(ctx, arg)
+		return []interface{}{r0}, err
+	})
+	return resultAt[[]Workspace](res, 0), err
+}
+
+func (s *interceptedStore) IncrementShardedCounter(ctx context.Context, name string, delta int64) error {
+	_, err := s.intercept(ctx, "IncrementShardedCounter", []interface{}{name, delta}, func(ctx context.Context) ([]interface{}, error) {
+		return nil, s.store.IncrementShardedCounter(ctx, name, delta)
+	})
+	return err
+}
+
+func (s *interceptedStore) InsertAPIKey(ctx context.Context, arg InsertAPIKeyParams) (APIKey, error) {
+	res, err := s.intercept(ctx, "InsertAPIKey", []interface{}{arg}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.InsertAPIKey(ctx, arg)
+		return []interface{}{r0}, err
+	})
+	return resultAt[APIKey](res, 0), err
+}
+
+func (s *interceptedStore) InsertAgentStat(ctx context.Context, arg InsertAgentStatParams) (AgentStat, error) {
+	res, err := s.intercept(ctx, "InsertAgentStat", []interface{}{arg}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.InsertAgentStat(ctx, arg)
+		return []interface{}{r0}, err
+	})
+	return resultAt[AgentStat](res, 0), err
+}
+
+func (s *interceptedStore) InsertAllUsersGroup(ctx context.Context, organizationID uuid.UUID) (Group, error) {
+	res, err := s.intercept(ctx, "InsertAllUsersGroup", []interface{}{organizationID}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.InsertAllUsersGroup(ctx, organizationID)
+		return []interface{}{r0}, err
+	})
+	return resultAt[Group](res, 0), err
+}
+
+func (s *interceptedStore) InsertAuditLog(ctx context.Context, arg InsertAuditLogParams) (AuditLog, error) {
+	res, err := s.intercept(ctx, "InsertAuditLog", []interface{}{arg}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.InsertAuditLog(ctx, arg)
+		return []interface{}{r0}, err
+	})
+	return resultAt[AuditLog](res, 0), err
+}
+
+func (s *interceptedStore) InsertDERPMeshKey(ctx context.Context, value string) error {
+	_, err := s.intercept(ctx, "InsertDERPMeshKey", []interface{}{value}, func(ctx context.Context) ([]interface{}, error) {
+		return nil, s.store.InsertDERPMeshKey(ctx, value)
+	})
+	return err
+}
+
+func (s *interceptedStore) InsertDeploymentID(ctx context.Context, value string) error {
+	_, err := s.intercept(ctx, "InsertDeploymentID", []interface{}{value}, func(ctx context.Context) ([]interface{}, error) {
+		return nil, s.store.InsertDeploymentID(ctx, value)
+	})
+	return err
+}
+
+func (s *interceptedStore) InsertFile(ctx context.Context, arg InsertFileParams) (File, error) {
+	res, err := s.intercept(ctx, "InsertFile", []interface{}{arg}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.InsertFile(ctx, arg)
+		return []interface{}{r0}, err
+	})
+	return resultAt[File](res, 0), err
+}
+
+func (s *interceptedStore) InsertGitSSHKey(ctx context.Context, arg InsertGitSSHKeyParams) (GitSSHKey, error) {
+	res, err := s.intercept(ctx, "InsertGitSSHKey", []interface{}{arg}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.InsertGitSSHKey(ctx, arg)
+		return []interface{}{r0}, err
+	})
+	return resultAt[GitSSHKey](res, 0), err
+}
+
+func (s *interceptedStore) InsertGroup(ctx context.Context, arg InsertGroupParams) (Group, error) {
+	res, err := s.intercept(ctx, "InsertGroup", []interface{}{arg}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.InsertGroup(ctx, arg)
+		return []interface{}{r0}, err
+	})
+	return resultAt[Group](res, 0), err
+}
+
+func (s *interceptedStore) InsertGroupMember(ctx context.Context, arg InsertGroupMemberParams) error {
+	_, err := s.intercept(ctx, "InsertGroupMember", []interface{}{arg}, func(ctx context.Context) ([]interface{}, error) {
+		return nil, s.store.InsertGroupMember(ctx, arg)
+	})
+	return err
+}
+
+func (s *interceptedStore) InsertLicense(ctx context.Context, arg InsertLicenseParams) (License, error) {
+	res, err := s.intercept(ctx, "InsertLicense", []interface{}{arg}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.InsertLicense(ctx, arg)
+		return []interface{}{r0}, err
+	})
+	return resultAt[License](res, 0), err
+}
+
+func (s *interceptedStore) InsertOrGetUser(ctx context.Context, arg InsertUserParams) (bool, User, error) {
+	res, err := s.intercept(ctx, "InsertOrGetUser", []interface{}{arg}, func(ctx context.Context) ([]interface{}, error) {
+		r0, r1, err := s.store.InsertOrGetUser(ctx, arg)
+		return []interface{}{r0, r1}, err
+	})
+	return resultAt[bool](res, 0), resultAt[User](res, 1), err
+}
+
+func (s *interceptedStore) InsertOrganization(ctx context.Context, arg InsertOrganizationParams) (Organization, error) {
+	res, err := s.intercept(ctx, "InsertOrganization", []interface{}{arg}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.InsertOrganization(ctx, arg)
+		return []interface{}{r0}, err
+	})
+	return resultAt[Organization](res, 0), err
+}
+
+func (s *interceptedStore) InsertOrganizationMember(ctx context.Context, arg InsertOrganizationMemberParams) (OrganizationMember, error) {
+	res, err := s.intercept(ctx, "InsertOrganizationMember", []interface{}{arg}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.InsertOrganizationMember(ctx, arg)
+		return []interface{}{r0}, err
+	})
+	return resultAt[OrganizationMember](res, 0), err
+}
+
+func (s *interceptedStore) InsertParameterSchema(ctx context.Context, arg InsertParameterSchemaParams) (ParameterSchema, error) {
+	res, err := s.intercept(ctx, "InsertParameterSchema", []interface{}{arg}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.InsertParameterSchema(ctx, arg)
+		return []interface{}{r0}, err
+	})
+	return resultAt[ParameterSchema](res, 0), err
+}
+
+func (s *interceptedStore) InsertParameterValue(ctx context.Context, arg InsertParameterValueParams) (ParameterValue, error) {
+	res, err := s.intercept(ctx, "InsertParameterValue", []interface{}{arg}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.InsertParameterValue(ctx, arg)
+		return []interface{}{r0}, err
+	})
+	return resultAt[ParameterValue](res, 0), err
+}
+
+func (s *interceptedStore) InsertProvisionerDaemon(ctx context.Context, arg InsertProvisionerDaemonParams) (ProvisionerDaemon, error) {
+	res, err := s.intercept(ctx, "InsertProvisionerDaemon", []interface{}{arg}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.InsertProvisionerDaemon(ctx, arg)
+		return []interface{}{r0}, err
+	})
+	return resultAt[ProvisionerDaemon](res, 0), err
+}
+
+func (s *interceptedStore) InsertProvisionerJob(ctx context.Context, arg InsertProvisionerJobParams) (ProvisionerJob, error) {
+	res, err := s.intercept(ctx, "InsertProvisionerJob", []interface{}{arg}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.InsertProvisionerJob(ctx, arg)
+		return []interface{}{r0}, err
+	})
+	return resultAt[ProvisionerJob](res, 0), err
+}
+
+func (s *interceptedStore) InsertProvisionerJobLogs(ctx context.Context, arg InsertProvisionerJobLogsParams) ([]ProvisionerJobLog, error) {
+	res, err := s.intercept(ctx, "InsertProvisionerJobLogs", []interface{}{arg}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.InsertProvisionerJobLogs(ctx, arg)
+		return []interface{}{r0}, err
+	})
+	return resultAt[[]ProvisionerJobLog](res, 0), err
+}
+
+func (s *interceptedStore) InsertProvisionerJobLogsPrecise(ctx context.Context, arg InsertProvisionerJobLogsParams) ([]ProvisionerJobLog, error) {
+	res, err := s.intercept(ctx, "InsertProvisionerJobLogsPrecise", []interface{}{arg}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.InsertProvisionerJobLogsPrecise(ctx, arg)
+		return []interface{}{r0}, err
+	})
+	return resultAt[[]ProvisionerJobLog](res, 0), err
+}
+
+func (s *interceptedStore) InsertReplica(ctx context.Context, arg InsertReplicaParams) (Replica, error) {
+	res, err := s.intercept(ctx, "InsertReplica", []interface{}{arg}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.InsertReplica(ctx, arg)
+		return []interface{}{r0}, err
+	})
+	return resultAt[Replica](res, 0), err
+}
+
+func (s *interceptedStore) InsertTemplate(ctx context.Context, arg InsertTemplateParams) (Template, error) {
+	res, err := s.intercept(ctx, "InsertTemplate", []interface{}{arg}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.InsertTemplate(ctx, arg)
+		return []interface{}{r0}, err
+	})
+	return resultAt[Template](res, 0), err
+}
+
+func (s *interceptedStore) InsertTemplateVersion(ctx context.Context, arg InsertTemplateVersionParams) (TemplateVersion, error) {
+	res, err := s.intercept(ctx, "InsertTemplateVersion", []interface{}{arg}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.InsertTemplateVersion(ctx, arg)
+		return []interface{}{r0}, err
+	})
+	return resultAt[TemplateVersion](res, 0), err
+}
+
+func (s *interceptedStore) InsertUser(ctx context.Context, arg InsertUserParams) (User, error) {
+	res, err := s.intercept(ctx, "InsertUser", []interface{}{arg}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.InsertUser(ctx, arg)
+		return []interface{}{r0}, err
+	})
+	return resultAt[User](res, 0), err
+}
+
+func (s *interceptedStore) InsertUserLink(ctx context.Context, arg InsertUserLinkParams) (UserLink, error) {
+	res, err := s.intercept(ctx, "InsertUserLink", []interface{}{arg}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.InsertUserLink(ctx, arg)
+		return []interface{}{r0}, err
+	})
+	return resultAt[UserLink](res, 0), err
+}
+
+func (s *interceptedStore) InsertWorkspace(ctx context.Context, arg InsertWorkspaceParams) (Workspace, error) {
+	res, err := s.intercept(ctx, "InsertWorkspace", []interface{}{arg}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.InsertWorkspace(ctx, arg)
+		return []interface{}{r0}, err
+	})
+	return resultAt[Workspace](res, 0), err
+}
+
+func (s *interceptedStore) InsertWorkspaceAgent(ctx context.Context, arg InsertWorkspaceAgentParams) (WorkspaceAgent, error) {
+	res, err := s.intercept(ctx, "InsertWorkspaceAgent", []interface{}{arg}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.InsertWorkspaceAgent(ctx, arg)
+		return []interface{}{r0}, err
+	})
+	return resultAt[WorkspaceAgent](res, 0), err
+}
+
+func (s *interceptedStore) InsertWorkspaceApp(ctx context.Context, arg InsertWorkspaceAppParams) (WorkspaceApp, error) {
+	res, err := s.intercept(ctx, "InsertWorkspaceApp", []interface{}{arg}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.InsertWorkspaceApp(ctx, arg)
+		return []interface{}{r0}, err
+	})
+	return resultAt[WorkspaceApp](res, 0), err
+}
+
+func (s *interceptedStore) InsertWorkspaceBuild(ctx context.Context, arg InsertWorkspaceBuildParams) (WorkspaceBuild, error) {
+	res, err := s.intercept(ctx, "InsertWorkspaceBuild", []interface{}{arg}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.InsertWorkspaceBuild(ctx, arg)
+		return []interface{}{r0}, err
+	})
+	return resultAt[WorkspaceBuild](res, 0), err
+}
+
+func (s *interceptedStore) InsertWorkspaceIdempotent(ctx context.Context, idempotencyKey string, arg InsertWorkspaceParams) (Workspace, bool, error) {
+	res, err := s.intercept(ctx, "InsertWorkspaceIdempotent", []interface{}{idempotencyKey, arg}, func(ctx context.Context) ([]interface{}, error) {
+		r0, r1, err := s.store.InsertWorkspaceIdempotent(ctx, idempotencyKey, arg)
+		return []interface{}{r0, r1}, err
+	})
+	return resultAt[Workspace](res, 0), resultAt[bool](res, 1), err
+}
+
+func (s *interceptedStore) InsertWorkspaceResource(ctx context.Context, arg InsertWorkspaceResourceParams) (WorkspaceResource, error) {
+	res, err := s.intercept(ctx, "InsertWorkspaceResource", []interface{}{arg}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.InsertWorkspaceResource(ctx, arg)
+		return []interface{}{r0}, err
+	})
+	return resultAt[WorkspaceResource](res, 0), err
+}
+
+func (s *interceptedStore) InsertWorkspaceResourceMetadata(ctx context.Context, arg InsertWorkspaceResourceMetadataParams) (WorkspaceResourceMetadatum, error) {
+	res, err := s.intercept(ctx, "InsertWorkspaceResourceMetadata", []interface{}{arg}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.InsertWorkspaceResourceMetadata(ctx, arg)
+		return []interface{}{r0}, err
+	})
+	return resultAt[WorkspaceResourceMetadatum](res, 0), err
+}
+
+func (s *interceptedStore) InsertWorkspaceReturningComputed(ctx context.Context, arg InsertWorkspaceReturningComputedParams) (Workspace, error) {
+	res, err := s.intercept(ctx, "InsertWorkspaceReturningComputed", []interface{}{arg}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.InsertWorkspaceReturningComputed(ctx, arg)
+		return []interface{}{r0}, err
+	})
+	return resultAt[Workspace](res, 0), err
+}
+
+func (s *interceptedStore) InsertWorkspaceWithQuota(ctx context.Context, orgID uuid.UUID, max int, arg InsertWorkspaceParams) (Workspace, error) {
+	res, err := s.intercept(ctx, "InsertWorkspaceWithQuota", []interface{}{orgID, max, arg}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.InsertWorkspaceWithQuota(ctx, orgID, max, arg)
+		return []interface{}{r0}, err
+	})
+	return resultAt[Workspace](res, 0), err
+}
+
+func (s *interceptedStore) MarkWorkspacesInactive(ctx context.Context, olderThan time.Time) (WriteResult[Workspace], error) {
+	res, err := s.intercept(ctx, "MarkWorkspacesInactive", []interface{}{olderThan}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.MarkWorkspacesInactive(ctx, olderThan)
+		return []interface{}{r0}, err
+	})
+	return resultAt[WriteResult[Workspace]](res, 0), err
+}
+
+func (s *interceptedStore) NextBuildNumber(ctx context.Context, workspaceID uuid.UUID) (int32, error) {
+	res, err := s.intercept(ctx, "NextBuildNumber", []interface{}{workspaceID}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.NextBuildNumber(ctx, workspaceID)
+		return []interface{}{r0}, err
+	})
+	return resultAt[int32](res, 0), err
+}
+
+func (s *interceptedStore) Notify(ctx context.Context, channel string, payload string) error {
+	_, err := s.intercept(ctx, "Notify", []interface{}{channel, payload}, func(ctx context.Context) ([]interface{}, error) {
+		return nil, s.store.Notify(ctx, channel, payload)
+	})
+	return err
+}
+
+func (s *interceptedStore) ParameterValue(ctx context.Context, id uuid.UUID) (ParameterValue, error) {
+	res, err := s.intercept(ctx, "ParameterValue", []interface{}{id}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.ParameterValue(ctx, id)
+		return []interface{}{r0}, err
+	})
+	return resultAt[ParameterValue](res, 0), err
+}
+
+func (s *interceptedStore) ParameterValues(ctx context.Context, arg ParameterValuesParams) ([]ParameterValue, error) {
+	res, err := s.intercept(ctx, "ParameterValues", []interface{}{arg}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.ParameterValues(ctx, arg)
+		return []interface{}{r0}, err
+	})
+	return resultAt[[]ParameterValue](res, 0), err
+}
+
+func (s *interceptedStore) RenameWorkspace(ctx context.Context, id uuid.UUID, newName string) error {
+	_, err := s.intercept(ctx, "RenameWorkspace", []interface{}{id, newName}, func(ctx context.Context) ([]interface{}, error) {
+		return nil, s.store.RenameWorkspace(ctx, id, newName)
+	})
+	return err
+}
+
+func (s *interceptedStore) RenewLease(ctx context.Context, name string, owner uuid.UUID, ttl time.Duration) (bool, error) {
+	res, err := s.intercept(ctx, "RenewLease", []interface{}{name, owner, ttl}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.RenewLease(ctx, name, owner, ttl)
+		return []interface{}{r0}, err
+	})
+	return resultAt[bool](res, 0), err
+}
+
+func (s *interceptedStore) ReplicationLag(ctx context.Context) (time.Duration, error) {
+	res, err := s.intercept(ctx, "ReplicationLag", nil, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.ReplicationLag(ctx)
+		return []interface{}{r0}, err
+	})
+	return resultAt[time.Duration](res, 0), err
+}
+
+func (s *interceptedStore) ResetSequence(ctx context.Context, table string, column string) error {
+	_, err := s.intercept(ctx, "ResetSequence", []interface{}{table, column}, func(ctx context.Context) ([]interface{}, error) {
+		return nil, s.store.ResetSequence(ctx, table, column)
+	})
+	return err
+}
+
+func (s *interceptedStore) RollbackPrepared(ctx context.Context, gid string) error {
+	_, err := s.intercept(ctx, "RollbackPrepared", []interface{}{gid}, func(ctx context.Context) ([]interface{}, error) {
+		return nil, s.store.RollbackPrepared(ctx, gid)
+	})
+	return err
+}
+
+func (s *interceptedStore) RunIntegrityChecks(ctx context.Context, checks []IntegrityCheck) ([]IntegrityViolation, error) {
+	res, err := s.intercept(ctx, "RunIntegrityChecks", []interface{}{checks}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.RunIntegrityChecks(ctx, checks)
+		return []interface{}{r0}, err
+	})
+	return resultAt[[]IntegrityViolation](res, 0), err
+}
+
+func (s *interceptedStore) SoftDeleteUsersByOrg(ctx context.Context, orgID uuid.UUID) ([]uuid.UUID, error) {
+	res, err := s.intercept(ctx, "SoftDeleteUsersByOrg", []interface{}{orgID}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.SoftDeleteUsersByOrg(ctx, orgID)
+		return []interface{}{r0}, err
+	})
+	return resultAt[[]uuid.UUID](res, 0), err
+}
+
+func (s *interceptedStore) StreamCompressed(ctx context.Context, query string, args []interface{}, w io.Writer, codec string) (int64, error) {
+	res, err := s.intercept(ctx, "StreamCompressed", []interface{}{query, args, w, codec}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.StreamCompressed(ctx, query, args, w, codec)
+		return []interface{}{r0}, err
+	})
+	return resultAt[int64](res, 0), err
+}
+
+func (s *interceptedStore) StreamInto(ctx context.Context, query string, args []interface{}, rows chan<- Row) error {
+	_, err := s.intercept(ctx, "StreamInto", []interface{}{query, args, rows}, func(ctx context.Context) ([]interface{}, error) {
+		return nil, s.store.StreamInto(ctx, query, args, rows)
+	})
+	return err
+}
+
+func (s *interceptedStore) TouchTokens(ctx context.Context, ids []string, at time.Time) error {
+	_, err := s.intercept(ctx, "TouchTokens", []interface{}{ids, at}, func(ctx context.Context) ([]interface{}, error) {
+		return nil, s.store.TouchTokens(ctx, ids, at)
+	})
+	return err
+}
+
+func (s *interceptedStore) UpdateAPIKeyByID(ctx context.Context, arg UpdateAPIKeyByIDParams) error {
+	_, err := s.intercept(ctx, "UpdateAPIKeyByID", []interface{}{arg}, func(ctx context.Context) ([]interface{}, error) {
+		return nil, s.store.UpdateAPIKeyByID(ctx, arg)
+	})
+	return err
+}
+
+func (s *interceptedStore) UpdateGitSSHKey(ctx context.Context, arg UpdateGitSSHKeyParams) (GitSSHKey, error) {
+	res, err := s.intercept(ctx, "UpdateGitSSHKey", []interface{}{arg}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.UpdateGitSSHKey(ctx, arg)
+		return []interface{}{r0}, err
+	})
+	return resultAt[GitSSHKey](res, 0), err
+}
+
+func (s *interceptedStore) UpdateGroupByID(ctx context.Context, arg UpdateGroupByIDParams) (Group, error) {
+	res, err := s.intercept(ctx, "UpdateGroupByID", []interface{}{arg}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.UpdateGroupByID(ctx, arg)
+		return []interface{}{r0}, err
+	})
+	return resultAt[Group](res, 0), err
+}
+
+func (s *interceptedStore) UpdateMemberRoles(ctx context.Context, arg UpdateMemberRolesParams) (OrganizationMember, error) {
+	res, err := s.intercept(ctx, "UpdateMemberRoles", []interface{}{arg}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.UpdateMemberRoles(ctx, arg)
+		return []interface{}{r0}, err
+	})
+	return resultAt[OrganizationMember](res, 0), err
+}
+
+func (s *interceptedStore) UpdateProvisionerDaemonByID(ctx context.Context, arg UpdateProvisionerDaemonByIDParams) error {
+	_, err := s.intercept(ctx, "UpdateProvisionerDaemonByID", []interface{}{arg}, func(ctx context.Context) ([]interface{}, error) {
+		return nil, s.store.UpdateProvisionerDaemonByID(ctx, arg)
+	})
+	return err
+}
+
+func (s *interceptedStore) UpdateProvisionerJobByID(ctx context.Context, arg UpdateProvisionerJobByIDParams) error {
+	_, err := s.intercept(ctx, "UpdateProvisionerJobByID", []interface{}{arg}, func(ctx context.Context) ([]interface{}, error) {
+		return nil, s.store.UpdateProvisionerJobByID(ctx, arg)
+	})
+	return err
+}
+
+func (s *interceptedStore) UpdateProvisionerJobWithCancelByID(ctx context.Context, arg UpdateProvisionerJobWithCancelByIDParams) error {
+	_, err := s.intercept(ctx, "UpdateProvisionerJobWithCancelByID", []interface{}{arg}, func(ctx context.Context) ([]interface{}, error) {
+		return nil, s.store.UpdateProvisionerJobWithCancelByID(ctx, arg)
+	})
+	return err
+}
+
+func (s *interceptedStore) UpdateProvisionerJobWithCompleteByID(ctx context.Context, arg UpdateProvisionerJobWithCompleteByIDParams) error {
+	_, err := s.intercept(ctx, "UpdateProvisionerJobWithCompleteByID", []interface{}{arg}, func(ctx context.Context) ([]interface{}, error) {
+		return nil, s.store.UpdateProvisionerJobWithCompleteByID(ctx, arg)
+	})
+	return err
+}
+
+func (s *interceptedStore) UpdateReplica(ctx context.Context, arg UpdateReplicaParams) (Replica, error) {
+	res, err := s.intercept(ctx, "UpdateReplica", []interface{}{arg}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.UpdateReplica(ctx, arg)
+		return []interface{}{r0}, err
+	})
+	return resultAt[Replica](res, 0), err
+}
+
+func (s *interceptedStore) UpdateTemplateACLByID(ctx context.Context, arg UpdateTemplateACLByIDParams) (Template, error) {
+	res, err := s.intercept(ctx, "UpdateTemplateACLByID", []interface{}{arg}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.UpdateTemplateACLByID(ctx, arg)
+		return []interface{}{r0}, err
+	})
+	return resultAt[Template](res, 0), err
+}
+
+func (s *interceptedStore) UpdateTemplateActiveVersionByID(ctx context.Context, arg UpdateTemplateActiveVersionByIDParams) error {
+	_, err := s.intercept(ctx, "UpdateTemplateActiveVersionByID", []interface{}{arg}, func(ctx context.Context) ([]interface{}, error) {
+		return nil, s.store.UpdateTemplateActiveVersionByID(ctx, arg)
+	})
+	return err
+}
+
+func (s *interceptedStore) UpdateTemplateActiveVersionByIDReturning(ctx context.Context, arg UpdateTemplateActiveVersionByIDParams) (Template, error) {
+	res, err := s.intercept(ctx, "UpdateTemplateActiveVersionByIDReturning", []interface{}{arg}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.UpdateTemplateActiveVersionByIDReturning(ctx, arg)
+		return []interface{}{r0}, err
+	})
+	return resultAt[Template](res, 0), err
+}
+
+func (s *interceptedStore) UpdateTemplateDeletedByID(ctx context.Context, arg UpdateTemplateDeletedByIDParams) error {
+	_, err := s.intercept(ctx, "UpdateTemplateDeletedByID", []interface{}{arg}, func(ctx context.Context) ([]interface{}, error) {
+		return nil, s.store.UpdateTemplateDeletedByID(ctx, arg)
+	})
+	return err
+}
+
+func (s *interceptedStore) UpdateTemplateIfMatch(ctx context.Context, id uuid.UUID, etag string, arg UpdateTemplateIfVersionParams) (Template, error) {
+	res, err := s.intercept(ctx, "UpdateTemplateIfMatch", []interface{}{id, etag, arg}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.UpdateTemplateIfMatch(ctx, id, etag, arg)
+		return []interface{}{r0}, err
+	})
+	return resultAt[Template](res, 0), err
+}
+
+func (s *interceptedStore) UpdateTemplateIfVersion(ctx context.Context, id uuid.UUID, expectedUpdatedAt time.Time, arg UpdateTemplateIfVersionParams) (bool, error) {
+	res, err := s.intercept(ctx, "UpdateTemplateIfVersion", []interface{}{id, expectedUpdatedAt, arg}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.UpdateTemplateIfVersion(ctx, id, expectedUpdatedAt, arg)
+		return []interface{}{r0}, err
+	})
+	return resultAt[bool](res, 0), err
+}
+
+func (s *interceptedStore) UpdateTemplateMetaByID(ctx context.Context, arg UpdateTemplateMetaByIDParams) (Template, error) {
+	res, err := s.intercept(ctx, "UpdateTemplateMetaByID", []interface{}{arg}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.UpdateTemplateMetaByID(ctx, arg)
+		return []interface{}{r0}, err
+	})
+	return resultAt[Template](res, 0), err
+}
+
+func (s *interceptedStore) UpdateTemplateVersionByID(ctx context.Context, arg UpdateTemplateVersionByIDParams) error {
+	_, err := s.intercept(ctx, "UpdateTemplateVersionByID", []interface{}{arg}, func(ctx context.Context) ([]interface{}, error) {
+		return nil, s.store.UpdateTemplateVersionByID(ctx, arg)
+	})
+	return err
+}
+
+func (s *interceptedStore) UpdateTemplateVersionDescriptionByJobID(ctx context.Context, arg UpdateTemplateVersionDescriptionByJobIDParams) error {
+	_, err := s.intercept(ctx, "UpdateTemplateVersionDescriptionByJobID", []interface{}{arg}, func(ctx context.Context) ([]interface{}, error) {
+		return nil, s.store.UpdateTemplateVersionDescriptionByJobID(ctx, arg)
+	})
+	return err
+}
+
+func (s *interceptedStore) UpdateUserAudited(ctx context.Context, arg UpdateUserProfileParams) (User, User, error) {
+	res, err := s.intercept(ctx, "UpdateUserAudited", []interface{}{arg}, func(ctx context.Context) ([]interface{}, error) {
+		r0, r1, err := s.store.UpdateUserAudited(ctx, arg)
+		return []interface{}{r0, r1}, err
+	})
+	return resultAt[User](res, 0), resultAt[User](res, 1), err
+}
+
+func (s *interceptedStore) UpdateUserDeletedByID(ctx context.Context, arg UpdateUserDeletedByIDParams) error {
+	_, err := s.intercept(ctx, "UpdateUserDeletedByID", []interface{}{arg}, func(ctx context.Context) ([]interface{}, error) {
+		return nil, s.store.UpdateUserDeletedByID(ctx, arg)
+	})
+	return err
+}
+
+func (s *interceptedStore) UpdateUserHashedPassword(ctx context.Context, arg UpdateUserHashedPasswordParams) error {
+	_, err := s.intercept(ctx, "UpdateUserHashedPassword", []interface{}{arg}, func(ctx context.Context) ([]interface{}, error) {
+		return nil, s.store.UpdateUserHashedPassword(ctx, arg)
+	})
+	return err
+}
+
+func (s *interceptedStore) UpdateUserHashedPasswordReturning(ctx context.Context, arg UpdateUserHashedPasswordParams) (User, error) {
+	res, err := s.intercept(ctx, "UpdateUserHashedPasswordReturning", []interface{}{arg}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.UpdateUserHashedPasswordReturning(ctx, arg)
+		return []interface{}{r0}, err
+	})
+	return resultAt[User](res, 0), err
+}
+
+func (s *interceptedStore) UpdateUserLastSeenAt(ctx context.Context, arg UpdateUserLastSeenAtParams) (User, error) {
+	res, err := s.intercept(ctx, "UpdateUserLastSeenAt", []interface{}{arg}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.UpdateUserLastSeenAt(ctx, arg)
+		return []interface{}{r0}, err
+	})
+	return resultAt[User](res, 0), err
+}
+
+func (s *interceptedStore) UpdateUserLink(ctx context.Context, arg UpdateUserLinkParams) (UserLink, error) {
+	res, err := s.intercept(ctx, "UpdateUserLink", []interface{}{arg}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.UpdateUserLink(ctx, arg)
+		return []interface{}{r0}, err
+	})
+	return resultAt[UserLink](res, 0), err
+}
+
+func (s *interceptedStore) UpdateUserLinkedID(ctx context.Context, arg UpdateUserLinkedIDParams) (UserLink, error) {
+	res, err := s.intercept(ctx, "UpdateUserLinkedID", []interface{}{arg}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.UpdateUserLinkedID(ctx, arg)
+		return []interface{}{r0}, err
+	})
+	return resultAt[UserLink](res, 0), err
+}
+
+func (s *interceptedStore) UpdateUserProfile(ctx context.Context, arg UpdateUserProfileParams) (User, error) {
+	res, err := s.intercept(ctx, "UpdateUserProfile", []interface{}{arg}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.UpdateUserProfile(ctx, arg)
+		return []interface{}{r0}, err
+	})
+	return resultAt[User](res, 0), err
+}
+
+func (s *interceptedStore) UpdateUserRoles(ctx context.Context, arg UpdateUserRolesParams) (User, error) {
+	res, err := s.intercept(ctx, "UpdateUserRoles", []interface{}{arg}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.UpdateUserRoles(ctx, arg)
+		return []interface{}{r0}, err
+	})
+	return resultAt[User](res, 0), err
+}
+
+func (s *interceptedStore) UpdateUserStatus(ctx context.Context, arg UpdateUserStatusParams) (User, error) {
+	res, err := s.intercept(ctx, "UpdateUserStatus", []interface{}{arg}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.UpdateUserStatus(ctx, arg)
+		return []interface{}{r0}, err
+	})
+	return resultAt[User](res, 0), err
+}
+
+func (s *interceptedStore) UpdateWorkspace(ctx context.Context, arg UpdateWorkspaceParams) (Workspace, error) {
+	res, err := s.intercept(ctx, "UpdateWorkspace", []interface{}{arg}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.UpdateWorkspace(ctx, arg)
+		return []interface{}{r0}, err
+	})
+	return resultAt[Workspace](res, 0), err
+}
+
+func (s *interceptedStore) UpdateWorkspaceAgentConnectionByID(ctx context.Context, arg UpdateWorkspaceAgentConnectionByIDParams) error {
+	_, err := s.intercept(ctx, "UpdateWorkspaceAgentConnectionByID", []interface{}{arg}, func(ctx context.Context) ([]interface{}, error) {
+		return nil, s.store.UpdateWorkspaceAgentConnectionByID(ctx, arg)
+	})
+	return err
+}
+
+func (s *interceptedStore) UpdateWorkspaceAgentVersionByID(ctx context.Context, arg UpdateWorkspaceAgentVersionByIDParams) error {
+	_, err := s.intercept(ctx, "UpdateWorkspaceAgentVersionByID", []interface{}{arg}, func(ctx context.Context) ([]interface{}, error) {
+		return nil, s.store.UpdateWorkspaceAgentVersionByID(ctx, arg)
+	})
+	return err
+}
+
+func (s *interceptedStore) UpdateWorkspaceAppHealthByID(ctx context.Context, arg UpdateWorkspaceAppHealthByIDParams) error {
+	_, err := s.intercept(ctx, "UpdateWorkspaceAppHealthByID", []interface{}{arg}, func(ctx context.Context) ([]interface{}, error) {
+		return nil, s.store.UpdateWorkspaceAppHealthByID(ctx, arg)
+	})
+	return err
+}
+
+func (s *interceptedStore) UpdateWorkspaceAutostart(ctx context.Context, arg UpdateWorkspaceAutostartParams) error {
+	_, err := s.intercept(ctx, "UpdateWorkspaceAutostart", []interface{}{arg}, func(ctx context.Context) ([]interface{}, error) {
+		return nil, s.store.UpdateWorkspaceAutostart(ctx, arg)
+	})
+	return err
+}
+
+func (s *interceptedStore) UpdateWorkspaceAutostartReturning(ctx context.Context, arg UpdateWorkspaceAutostartParams) (Workspace, error) {
+	res, err := s.intercept(ctx, "UpdateWorkspaceAutostartReturning", []interface{}{arg}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.UpdateWorkspaceAutostartReturning(ctx, arg)
+		return []interface{}{r0}, err
+	})
+	return resultAt[Workspace](res, 0), err
+}
+
+func (s *interceptedStore) UpdateWorkspaceBuildByID(ctx context.Context, arg UpdateWorkspaceBuildByIDParams) error {
+	_, err := s.intercept(ctx, "UpdateWorkspaceBuildByID", []interface{}{arg}, func(ctx context.Context) ([]interface{}, error) {
+		return nil, s.store.UpdateWorkspaceBuildByID(ctx, arg)
+	})
+	return err
+}
+
+func (s *interceptedStore) UpdateWorkspaceDeletedByID(ctx context.Context, arg UpdateWorkspaceDeletedByIDParams) error {
+	_, err := s.intercept(ctx, "UpdateWorkspaceDeletedByID", []interface{}{arg}, func(ctx context.Context) ([]interface{}, error) {
+		return nil, s.store.UpdateWorkspaceDeletedByID(ctx, arg)
+	})
+	return err
+}
+
+func (s *interceptedStore) UpdateWorkspaceLastUsedAt(ctx context.Context, arg UpdateWorkspaceLastUsedAtParams) error {
+	_, err := s.intercept(ctx, "UpdateWorkspaceLastUsedAt", []interface{}{arg}, func(ctx context.Context) ([]interface{}, error) {
+		return nil, s.store.UpdateWorkspaceLastUsedAt(ctx, arg)
+	})
+	return err
+}
+
+func (s *interceptedStore) UpdateWorkspaceTTL(ctx context.Context, arg UpdateWorkspaceTTLParams) error {
+	_, err := s.intercept(ctx, "UpdateWorkspaceTTL", []interface{}{arg}, func(ctx context.Context) ([]interface{}, error) {
+		return nil, s.store.UpdateWorkspaceTTL(ctx, arg)
+	})
+	return err
+}
+
+func (s *interceptedStore) UpdateWorkspaceTTLReturning(ctx context.Context, arg UpdateWorkspaceTTLParams) (Workspace, error) {
+	res, err := s.intercept(ctx, "UpdateWorkspaceTTLReturning", []interface{}{arg}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.UpdateWorkspaceTTLReturning(ctx, arg)
+		return []interface{}{r0}, err
+	})
+	return resultAt[Workspace](res, 0), err
+}
+
+func (s *interceptedStore) UpdateWorkspaceTTLs(ctx context.Context, ids []uuid.UUID, ttls []sql.NullInt64) error {
+	_, err := s.intercept(ctx, "UpdateWorkspaceTTLs", []interface{}{ids, ttls}, func(ctx context.Context) ([]interface{}, error) {
+		return nil, s.store.UpdateWorkspaceTTLs(ctx, ids, ttls)
+	})
+	return err
+}
+
+func (s *interceptedStore) UpsertAgentStats(ctx context.Context, arg []InsertAgentStatParams) error {
+	_, err := s.intercept(ctx, "UpsertAgentStats", []interface{}{arg}, func(ctx context.Context) ([]interface{}, error) {
+		return nil, s.store.UpsertAgentStats(ctx, arg)
+	})
+	return err
+}
+
+func (s *interceptedStore) VerifySequenceOwnership(ctx context.Context) ([]SequenceIssue, error) {
+	res, err := s.intercept(ctx, "VerifySequenceOwnership", nil, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.store.VerifySequenceOwnership(ctx)
+		return []interface{}{r0}, err
+	})
+	return resultAt[[]SequenceIssue](res, 0), err
+}
@@ -0,0 +1,215 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Call describes a single Store method invocation.
+type Call struct {
+	// Method is the name of the Store method, e.g. "GetUserByID".
+	Method string
+	// Args are the arguments passed to the method, excluding the context.
+	Args []interface{}
+	// InTx is true when the call is made on a Store passed to an InTx
+	// callback.
+	InTx bool
+}
+
+// Invoker performs the underlying Store call and returns the method's
+// non-error results in order.
+type Invoker func(ctx context.Context) ([]interface{}, error)
+
+// Interceptor runs around every query method of a Store returned by
+// Intercept. An interceptor may alter the context, observe the results, or
+// short-circuit the call by not invoking next. Results returned without
+// calling next must match the types the method returns.
+type Interceptor func(ctx context.Context, call Call, next Invoker) ([]interface{}, error)
+
+// Intercept returns a Store that runs interceptors around every query method
+// of store, with the first interceptor being the outermost. Stores passed to
+// InTx callbacks are intercepted as well, as are ExecRaw and SelectRaw on
+// the store passed to WithTempTable. InTx, InTxOpts and InSavepoint are
+// reported as a call to "InTx" with the TxOptions as the only argument.
+func Intercept(store Store, interceptors ...Interceptor) Store {
+	if len(interceptors) == 0 {
+		return store
+	}
+	return &interceptedStore{
+		store:        store,
+		interceptors: interceptors,
+	}
+}
+
+type interceptedStore struct {
+	store        Store
+	interceptors []Interceptor
+	inTx         bool
+}
+
+func (s *interceptedStore) intercept(ctx context.Context, method string, args []interface{}, invoke Invoker) ([]interface{}, error) {
+	call := Call{
+		Method: method,
+		Args:   args,
+		InTx:   s.inTx,
+	}
+	next := invoke
+	for i := len(s.interceptors) - 1; i >= 0; i-- {
+		interceptor, inner := s.interceptors[i], next
+		next = func(ctx context.Context) ([]interface{}, error) {
+			return interceptor(ctx, call, inner)
+		}
+	}
+	return next(ctx)
+}
+
+func (s *interceptedStore) Ping(ctx context.Context) (time.Duration, error) {
+	return s.store.Ping(ctx)
+}
+
+func (s *interceptedStore) Reconnect(ctx context.Context) error {
+	return s.store.Reconnect(ctx)
+}
+
+func (s *interceptedStore) Unwrap() *sqlx.DB {
+	return s.store.Unwrap()
+}
+
+func (s *interceptedStore) InTx(function func(Store) error) error {
+	return s.InTxOpts(context.Background(), TxOptions{}, function)
+}
+
+func (s *interceptedStore) WithConn(ctx context.Context, function func(Store) error) error {
+	return s.store.WithConn(ctx, func(conn Store) error {
+		return function(&interceptedStore{
+			store:        conn,
+			interceptors: s.interceptors,
+			inTx:         s.inTx,
+		})
+	})
+}
+
+func (s *interceptedStore) WithTempTable(ctx context.Context, ddl string, function func(TempTableStore) error) error {
+	return s.store.WithTempTable(ctx, ddl, func(conn TempTableStore) error {
+		return function(&interceptedTempTableStore{
+			interceptedStore: &interceptedStore{
+				store:        conn,
+				interceptors: s.interceptors,
+				inTx:         s.inTx,
+			},
+			raw: conn,
+		})
+	})
+}
+
+// interceptedTempTableStore runs interceptors around ExecRaw and SelectRaw
+// as well as the query methods of the store WithTempTable passes.
+type interceptedTempTableStore struct {
+	*interceptedStore
+	raw TempTableStore
+}
+
+func (s *interceptedTempTableStore) ExecRaw(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	res, err := s.intercept(ctx, "ExecRaw", []interface{}{query, args}, func(ctx context.Context) ([]interface{}, error) {
+		r0, err := s.raw.ExecRaw(ctx, query, args...)
+		return []interface{}{r0}, err
+	})
+	return resultAt[sql.Result](res, 0), err
+}
+
+func (s *interceptedTempTableStore) SelectRaw(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	_, err := s.intercept(ctx, "SelectRaw", []interface{}{dest, query, args}, func(ctx context.Context) ([]interface{}, error) {
+		return nil, s.raw.SelectRaw(ctx, dest, query, args...)
+	})
+	return err
+}
+
+func (s *interceptedStore) WithMigrationLock(ctx context.Context, function func() error) error {
+	return s.store.WithMigrationLock(ctx, function)
+}
+
+func (s *interceptedStore) TxStartTime() time.Time {
+	return s.store.TxStartTime()
+}
+
+func (s *interceptedStore) TxAge() time.Duration {
+	return s.store.TxAge()
+}
+
+func (s *interceptedStore) TxDepth() int {
+	return s.store.TxDepth()
+}
+
+func (s *interceptedStore) InSavepoint(ctx context.Context, function func(Store) error) error {
+	_, err := s.intercept(ctx, "InTx", []interface{}{TxOptions{}}, func(ctx context.Context) ([]interface{}, error) {
+		return nil, s.store.InSavepoint(ctx, func(tx Store) error {
+			return function(&interceptedStore{
+				store:        tx,
+				interceptors: s.interceptors,
+				inTx:         true,
+			})
+		})
+	})
+	return err
+}
+
+func (s *interceptedStore) InPreparedTx(ctx context.Context, gid string, function func(Store) error) error {
+	_, err := s.intercept(ctx, "InTx", []interface{}{TxOptions{}}, func(ctx context.Context) ([]interface{}, error) {
+		return nil, s.store.InPreparedTx(ctx, gid, func(tx Store) error {
+			return function(&interceptedStore{
+				store:        tx,
+				interceptors: s.interceptors,
+				inTx:         true,
+			})
+		})
+	})
+	return err
+}
+
+func (s *interceptedStore) InReadTx(ctx context.Context, function func(Store) error) error {
+	return s.InTxOpts(ctx, TxOptions{ReadOnly: true}, function)
+}
+
+// InTxReadOnlyHint is reported to interceptors as a read-only InTx.
+func (s *interceptedStore) InTxReadOnlyHint(function func(Store) error) error {
+	_, err := s.intercept(context.Background(), "InTx", []interface{}{TxOptions{ReadOnly: true}}, func(ctx context.Context) ([]interface{}, error) {
+		return nil, s.store.InTxReadOnlyHint(func(tx Store) error {
+			return function(&interceptedStore{
+				store:        tx,
+				interceptors: s.interceptors,
+				inTx:         true,
+			})
+		})
+	})
+	return err
+}
+
+func (s *interceptedStore) InTxOpts(ctx context.Context, opts TxOptions, function func(Store) error) error {
+	_, err := s.intercept(ctx, "InTx", []interface{}{opts}, func(ctx context.Context) ([]interface{}, error) {
+		return nil, s.store.InTxOpts(ctx, opts, func(tx Store) error {
+			return function(&interceptedStore{
+				store:        tx,
+				interceptors: s.interceptors,
+				inTx:         true,
+			})
+		})
+	})
+	return err
+}
+
+// resultAt returns the i-th result as T, or the zero value if an interceptor
+// returned fewer or differently typed results.
+func resultAt[T any](results []interface{}, i int) T {
+	var zero T
+	if i >= len(results) {
+		return zero
+	}
+	v, ok := results[i].(T)
+	if !ok {
+		return zero
+	}
+	return v
+}
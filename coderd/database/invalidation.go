@@ -0,0 +1,256 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/google/uuid"
+)
+
+// Invalidation says that cached copies of an entity are stale. Entity is
+// the model name, e.g. "Workspace", and ID its primary key, or uuid.Nil
+// when the write could have touched any row of the entity.
+type Invalidation struct {
+	Entity string
+	ID     uuid.UUID
+}
+
+// InvalidationKeyFunc returns the entity a successful write changed, or
+// false if the write does not invalidate anything.
+type InvalidationKeyFunc func(call Call) (Invalidation, bool)
+
+// InvalidationOption configures NewWithInvalidation.
+type InvalidationOption func(*invalidator)
+
+// WithInvalidationKey replaces DefaultInvalidationKey, e.g. to key methods
+// whose first UUID argument is not the row's primary key, or to ignore
+// entities that are not cached.
+func WithInvalidationKey(keyOf InvalidationKeyFunc) InvalidationOption {
+	return func(i *invalidator) {
+		i.keyOf = keyOf
+	}
+}
+
+type invalidator struct {
+	emit  func(context.Context, []Invalidation)
+	keyOf InvalidationKeyFunc
+}
+
+// NewWithInvalidation returns a Store that calls emit with the entities
+// each committed write changed, so caches can drop them.
+//
+// Writes in a transaction are coalesced: emit is called once, after the
+// outermost transaction commits, with one Invalidation per entity and key
+// in the order they were first written, however often each was written.
+// An Invalidation with uuid.Nil makes the keyed ones of the same entity
+// redundant, so they are left out. Nothing is emitted for a transaction
+// that rolls back or for calls that fail, while writes in a savepoint that
+// rolls back are still reported, since an extra invalidation is harmless.
+// Writes outside a transaction are emitted as they succeed.
+//
+// Writes in InPreparedTx are not reported, since they only commit once
+// CommitPrepared is called, possibly by another process.
+func NewWithInvalidation(store Store, emit func(context.Context, []Invalidation), opts ...InvalidationOption) Store {
+	i := &invalidator{
+		emit:  emit,
+		keyOf: DefaultInvalidationKey,
+	}
+	for _, opt := range opts {
+		opt(i)
+	}
+	return &invalidatingStore{
+		Store:       Intercept(store, i.interceptAutocommit),
+		invalidator: i,
+	}
+}
+
+func (i *invalidator) interceptAutocommit(ctx context.Context, call Call, next Invoker) ([]interface{}, error) {
+	results, err := next(ctx)
+	if err != nil || call.InTx || call.Method == "InTx" {
+		return results, err
+	}
+	if key, ok := i.keyOf(call); ok {
+		i.emit(ctx, []Invalidation{key})
+	}
+	return results, nil
+}
+
+type invalidatingStore struct {
+	Store
+	invalidator *invalidator
+}
+
+// inTx runs begin with a callback that records the writes made on the
+// transaction, and emits them if begin succeeds.
+func (s *invalidatingStore) inTx(ctx context.Context, function func(Store) error, begin func(func(Store) error) error) error {
+	set := &invalidationSet{keyOf: s.invalidator.keyOf}
+	err := begin(func(tx Store) error {
+		return function(Intercept(tx, set.intercept))
+	})
+	if err != nil {
+		return err
+	}
+	if keys := set.coalesced(); len(keys) > 0 {
+		s.invalidator.emit(ctx, keys)
+	}
+	return nil
+}
+
+func (s *invalidatingStore) InTx(function func(Store) error) error {
+	return s.InTxOpts(context.Background(), TxOptions{}, function)
+}
+
+func (s *invalidatingStore) InTxOpts(ctx context.Context, opts TxOptions, function func(Store) error) error {
+	return s.inTx(ctx, function, func(fn func(Store) error) error {
+		return s.Store.InTxOpts(ctx, opts, fn)
+	})
+}
+
+func (s *invalidatingStore) InSavepoint(ctx context.Context, function func(Store) error) error {
+	return s.inTx(ctx, function, func(fn func(Store) error) error {
+		return s.Store.InSavepoint(ctx, fn)
+	})
+}
+
+func (s *invalidatingStore) InReadTx(ctx context.Context, function func(Store) error) error {
+	return s.inTx(ctx, function, func(fn func(Store) error) error {
+		return s.Store.InReadTx(ctx, fn)
+	})
+}
+
+func (s *invalidatingStore) InTxReadOnlyHint(function func(Store) error) error {
+	return s.inTx(context.Background(), function, s.Store.InTxReadOnlyHint)
+}
+
+func (s *invalidatingStore) WithConn(ctx context.Context, function func(Store) error) error {
+	return s.Store.WithConn(ctx, func(conn Store) error {
+		return function(&invalidatingStore{Store: conn, invalidator: s.invalidator})
+	})
+}
+
+func (s *invalidatingStore) WithTempTable(ctx context.Context, ddl string, function func(TempTableStore) error) error {
+	return s.Store.WithTempTable(ctx, ddl, func(conn TempTableStore) error {
+		return function(&invalidatingTempTableStore{
+			invalidatingStore: &invalidatingStore{Store: conn, invalidator: s.invalidator},
+			raw:               conn,
+		})
+	})
+}
+
+// invalidatingTempTableStore adds ExecRaw and SelectRaw to the store
+// WithTempTable passes. Raw statements name no sqlc method, so they never
+// invalidate cached reads.
+type invalidatingTempTableStore struct {
+	*invalidatingStore
+	raw TempTableStore
+}
+
+func (s *invalidatingTempTableStore) ExecRaw(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return s.raw.ExecRaw(ctx, query, args...)
+}
+
+func (s *invalidatingTempTableStore) SelectRaw(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	return s.raw.SelectRaw(ctx, dest, query, args...)
+}
+
+// invalidationSet collects the writes of one transaction, including those of
+// transactions nested in it.
+type invalidationSet struct {
+	keyOf InvalidationKeyFunc
+
+	mu   sync.Mutex
+	keys []Invalidation
+	seen map[Invalidation]bool
+}
+
+func (s *invalidationSet) intercept(ctx context.Context, call Call, next Invoker) ([]interface{}, error) {
+	results, err := next(ctx)
+	if err != nil || call.Method == "InTx" {
+		return results, err
+	}
+	key, ok := s.keyOf(call)
+	if !ok {
+		return results, nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.seen == nil {
+		s.seen = map[Invalidation]bool{}
+	}
+	if !s.seen[key] {
+		s.seen[key] = true
+		s.keys = append(s.keys, key)
+	}
+	return results, nil
+}
+
+// coalesced returns the recorded keys without those made redundant by an
+// entity-wide invalidation.
+func (s *invalidationSet) coalesced() []Invalidation {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	keys := make([]Invalidation, 0, len(s.keys))
+	for _, key := range s.keys {
+		if key.ID != uuid.Nil && s.seen[Invalidation{Entity: key.Entity}] {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// invalidationEntities are the model names DefaultInvalidationKey matches
+// method names against.
+var invalidationEntities = []string{
+	"APIKey", "AgentStat", "AuditLog", "File", "GitSSHKey", "Group",
+	"GroupMember", "Lease", "License", "Organization", "OrganizationMember",
+	"ParameterSchema", "ParameterValue", "ProvisionerDaemon",
+	"ProvisionerJob", "ProvisionerJobLog", "Replica", "SiteConfig",
+	"Template", "TemplateVersion", "User", "UserLink", "Workspace",
+	"WorkspaceAgent", "WorkspaceApp", "WorkspaceBuild", "WorkspaceResource",
+	"WorkspaceResourceMetadata",
+}
+
+// DefaultInvalidationKey keys every write method, as classified by
+// isReadMethod. The entity is the longest model name the method name
+// continues with after its verb, e.g. "WorkspaceBuild" for
+// UpdateWorkspaceBuildByID, or the rest of the name if none matches. The ID
+// is the first argument if it is a UUID, or the ID field of the first
+// argument if it has one, which is the primary key for most methods.
+// Otherwise it is uuid.Nil.
+func DefaultInvalidationKey(call Call) (Invalidation, bool) {
+	if call.Method == "InTx" || isReadMethod(call.Method) {
+		return Invalidation{}, false
+	}
+	noun := strings.TrimLeftFunc(call.Method[1:], unicode.IsLower)
+	entity := noun
+	longest := 0
+	for _, name := range invalidationEntities {
+		if len(name) > longest && strings.HasPrefix(noun, name) {
+			entity, longest = name, len(name)
+		}
+	}
+
+	key := Invalidation{Entity: entity}
+	if len(call.Args) == 0 {
+		return key, true
+	}
+	if id, ok := call.Args[0].(uuid.UUID); ok {
+		key.ID = id
+		return key, true
+	}
+	arg := reflect.Indirect(reflect.ValueOf(call.Args[0]))
+	if arg.Kind() != reflect.Struct {
+		return key, true
+	}
+	if field := arg.FieldByName("ID"); field.IsValid() {
+		if id, ok := field.Interface().(uuid.UUID); ok {
+			key.ID = id
+		}
+	}
+	return key, true
+}
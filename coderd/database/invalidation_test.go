@@ -0,0 +1,199 @@
+package database_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/xerrors"
+
+	"github.com/coder/coder/coderd/database"
+	"github.com/coder/coder/coderd/database/databasefake"
+)
+
+func TestNewWithInvalidation(t *testing.T) {
+	t.Parallel()
+
+	errFailed := xerrors.New("failed")
+	// newStore returns a Store whose writes succeed without touching the
+	// fake, except for UpdateWorkspaceDeletedByID, which fails.
+	newStore := func(t *testing.T, opts ...database.InvalidationOption) (database.Store, func() [][]database.Invalidation) {
+		var (
+			mu      sync.Mutex
+			emitted [][]database.Invalidation
+		)
+		stub := database.Intercept(databasefake.New(), func(ctx context.Context, call database.Call, next database.Invoker) ([]interface{}, error) {
+			switch call.Method {
+			case "InTx":
+				return next(ctx)
+			case "UpdateWorkspaceDeletedByID":
+				return nil, errFailed
+			}
+			return nil, nil
+		})
+		db := database.NewWithInvalidation(stub, func(_ context.Context, keys []database.Invalidation) {
+			mu.Lock()
+			defer mu.Unlock()
+			emitted = append(emitted, keys)
+		}, opts...)
+		return db, func() [][]database.Invalidation {
+			mu.Lock()
+			defer mu.Unlock()
+			return append([][]database.Invalidation(nil), emitted...)
+		}
+	}
+	workspace, other, user := uuid.New(), uuid.New(), uuid.New()
+
+	t.Run("CoalescesTransaction", func(t *testing.T) {
+		t.Parallel()
+		db, emitted := newStore(t)
+		ctx := context.Background()
+		err := db.InTx(func(tx database.Store) error {
+			for i := 0; i < 3; i++ {
+				err := tx.UpdateWorkspaceTTL(ctx, database.UpdateWorkspaceTTLParams{ID: workspace})
+				require.NoError(t, err)
+			}
+			_, err := tx.GetWorkspaceByID(ctx, workspace)
+			require.NoError(t, err)
+			err = tx.UpdateUserHashedPassword(ctx, database.UpdateUserHashedPasswordParams{ID: user})
+			require.NoError(t, err)
+			err = tx.UpdateWorkspaceAutostart(ctx, database.UpdateWorkspaceAutostartParams{ID: workspace})
+			require.NoError(t, err)
+			err = tx.UpdateWorkspaceLastUsedAt(ctx, database.UpdateWorkspaceLastUsedAtParams{ID: other})
+			require.NoError(t, err)
+			require.Empty(t, emitted(), "nothing is emitted before commit")
+			return nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, [][]database.Invalidation{{
+			{Entity: "Workspace", ID: workspace},
+			{Entity: "User", ID: user},
+			{Entity: "Workspace", ID: other},
+		}}, emitted())
+	})
+
+	t.Run("NestedTransaction", func(t *testing.T) {
+		t.Parallel()
+		db, emitted := newStore(t)
+		ctx := context.Background()
+		err := db.InTx(func(tx database.Store) error {
+			err := tx.UpdateWorkspaceTTL(ctx, database.UpdateWorkspaceTTLParams{ID: workspace})
+			require.NoError(t, err)
+			return tx.InSavepoint(ctx, func(tx database.Store) error {
+				err := tx.UpdateWorkspaceTTL(ctx, database.UpdateWorkspaceTTLParams{ID: workspace})
+				require.NoError(t, err)
+				return tx.UpdateWorkspaceBuildByID(ctx, database.UpdateWorkspaceBuildByIDParams{ID: other})
+			})
+		})
+		require.NoError(t, err)
+		require.Equal(t, [][]database.Invalidation{{
+			{Entity: "Workspace", ID: workspace},
+			{Entity: "WorkspaceBuild", ID: other},
+		}}, emitted(), "nested writes are emitted once with the outer transaction")
+	})
+
+	t.Run("Rollback", func(t *testing.T) {
+		t.Parallel()
+		db, emitted := newStore(t)
+		ctx := context.Background()
+		err := db.InTx(func(tx database.Store) error {
+			err := tx.UpdateWorkspaceTTL(ctx, database.UpdateWorkspaceTTLParams{ID: workspace})
+			require.NoError(t, err)
+			return errFailed
+		})
+		require.ErrorIs(t, err, errFailed)
+		require.Empty(t, emitted())
+	})
+
+	t.Run("FailedWrite", func(t *testing.T) {
+		t.Parallel()
+		db, emitted := newStore(t)
+		ctx := context.Background()
+		err := db.InTx(func(tx database.Store) error {
+			err := tx.UpdateWorkspaceDeletedByID(ctx, database.UpdateWorkspaceDeletedByIDParams{ID: workspace})
+			require.ErrorIs(t, err, errFailed)
+			return nil
+		})
+		require.NoError(t, err)
+		err = db.UpdateWorkspaceDeletedByID(ctx, database.UpdateWorkspaceDeletedByIDParams{ID: workspace})
+		require.ErrorIs(t, err, errFailed)
+		require.Empty(t, emitted())
+	})
+
+	t.Run("EntityWide", func(t *testing.T) {
+		t.Parallel()
+		db, emitted := newStore(t)
+		ctx := context.Background()
+		err := db.InTx(func(tx database.Store) error {
+			err := tx.UpdateWorkspaceTTL(ctx, database.UpdateWorkspaceTTLParams{ID: workspace})
+			require.NoError(t, err)
+			err = tx.UpsertAgentStats(ctx, nil)
+			require.NoError(t, err)
+			_, err = tx.MarkWorkspacesInactive(ctx, database.Now())
+			require.NoError(t, err)
+			return tx.UpdateWorkspaceTTL(ctx, database.UpdateWorkspaceTTLParams{ID: other})
+		})
+		require.NoError(t, err)
+		require.Equal(t, [][]database.Invalidation{{
+			{Entity: "AgentStat"},
+			{Entity: "Workspace"},
+		}}, emitted(), "an entity-wide invalidation replaces keyed ones")
+	})
+
+	t.Run("Autocommit", func(t *testing.T) {
+		t.Parallel()
+		db, emitted := newStore(t)
+		ctx := context.Background()
+		for i := 0; i < 2; i++ {
+			err := db.UpdateWorkspaceTTL(ctx, database.UpdateWorkspaceTTLParams{ID: workspace})
+			require.NoError(t, err)
+		}
+		_, err := db.GetWorkspaceByID(ctx, workspace)
+		require.NoError(t, err)
+		require.Equal(t, [][]database.Invalidation{
+			{{Entity: "Workspace", ID: workspace}},
+			{{Entity: "Workspace", ID: workspace}},
+		}, emitted(), "each committed write is emitted")
+	})
+
+	t.Run("KeyFunc", func(t *testing.T) {
+		t.Parallel()
+		db, emitted := newStore(t, database.WithInvalidationKey(func(call database.Call) (database.Invalidation, bool) {
+			key, ok := database.DefaultInvalidationKey(call)
+			return key, ok && key.Entity == "User"
+		}))
+		ctx := context.Background()
+		err := db.InTx(func(tx database.Store) error {
+			err := tx.UpdateWorkspaceTTL(ctx, database.UpdateWorkspaceTTLParams{ID: workspace})
+			require.NoError(t, err)
+			return tx.UpdateUserHashedPassword(ctx, database.UpdateUserHashedPasswordParams{ID: user})
+		})
+		require.NoError(t, err)
+		require.Equal(t, [][]database.Invalidation{{{Entity: "User", ID: user}}}, emitted())
+	})
+}
+
+func TestDefaultInvalidationKey(t *testing.T) {
+	t.Parallel()
+
+	id := uuid.New()
+	for _, tc := range []struct {
+		call database.Call
+		key  database.Invalidation
+		ok   bool
+	}{
+		{call: database.Call{Method: "GetWorkspaceByID", Args: []interface{}{id}}},
+		{call: database.Call{Method: "InTx", Args: []interface{}{database.TxOptions{}}}},
+		{call: database.Call{Method: "DeleteAPIKeyByID", Args: []interface{}{"key"}}, key: database.Invalidation{Entity: "APIKey"}, ok: true},
+		{call: database.Call{Method: "UpdateWorkspaceBuildByID", Args: []interface{}{database.UpdateWorkspaceBuildByIDParams{ID: id}}}, key: database.Invalidation{Entity: "WorkspaceBuild", ID: id}, ok: true},
+		{call: database.Call{Method: "UpdateUserDeletedByID", Args: []interface{}{database.UpdateUserDeletedByIDParams{ID: id}}}, key: database.Invalidation{Entity: "User", ID: id}, ok: true},
+		{call: database.Call{Method: "DeleteOldAgentStats"}, key: database.Invalidation{Entity: "OldAgentStats"}, ok: true},
+		{call: database.Call{Method: "UpdateWorkspaceDeletedByID", Args: []interface{}{&database.UpdateWorkspaceDeletedByIDParams{ID: id}}}, key: database.Invalidation{Entity: "Workspace", ID: id}, ok: true},
+	} {
+		key, ok := database.DefaultInvalidationKey(tc.call)
+		require.Equal(t, tc.ok, ok, tc.call.Method)
+		require.Equal(t, tc.key, key, tc.call.Method)
+	}
+}
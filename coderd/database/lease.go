@@ -0,0 +1,84 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/xerrors"
+)
+
+// leaseQuerier coordinates work across replicas with named leases. A lease
+// is held by one owner until it expires; owners must renew it well within
+// the TTL to keep it. Expiry is judged by the database clock.
+type leaseQuerier interface {
+	// AcquireLease takes the lease name for owner for ttl if it is
+	// unclaimed, expired, or already held by owner, and reports whether
+	// owner now holds it.
+	AcquireLease(ctx context.Context, name string, owner uuid.UUID, ttl time.Duration) (bool, error)
+	// RenewLease extends a lease owner still holds to expire ttl from now.
+	// It reports false if the lease expired or was taken over.
+	RenewLease(ctx context.Context, name string, owner uuid.UUID, ttl time.Duration) (bool, error)
+}
+
+func (q *sqlQuerier) AcquireLease(ctx context.Context, name string, owner uuid.UUID, ttl time.Duration) (bool, error) {
+	if ttl <= 0 {
+		return false, xerrors.Errorf("lease ttl must be positive, got %s", ttl)
+	}
+	// Concurrent inserts of the same name wait on each other, and the losers
+	// re-evaluate the WHERE against the winner's row, so at most one owner
+	// succeeds.
+	const query = `-- name: AcquireLease :one
+	INSERT INTO
+		leases (name, owner_id, expires_at)
+	VALUES
+		($1, $2, now() + make_interval(secs => $3))
+	ON CONFLICT (name) DO UPDATE
+	SET
+		owner_id = EXCLUDED.owner_id,
+		expires_at = EXCLUDED.expires_at
+	WHERE
+		leases.expires_at < now()
+		OR leases.owner_id = EXCLUDED.owner_id
+	RETURNING
+		owner_id
+	`
+
+	var holder uuid.UUID
+	err := q.db.GetContext(ctx, &holder, query, name, owner, ttl.Seconds())
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, xerrors.Errorf("acquire lease: %w", err)
+	}
+	return true, nil
+}
+
+func (q *sqlQuerier) RenewLease(ctx context.Context, name string, owner uuid.UUID, ttl time.Duration) (bool, error) {
+	if ttl <= 0 {
+		return false, xerrors.Errorf("lease ttl must be positive, got %s", ttl)
+	}
+	const query = `-- name: RenewLease :execrows
+	UPDATE
+		leases
+	SET
+		expires_at = now() + make_interval(secs => $3)
+	WHERE
+		name = $1
+		AND owner_id = $2
+		AND expires_at >= now()
+	`
+
+	result, err := q.db.ExecContext(ctx, query, name, owner, ttl.Seconds())
+	if err != nil {
+		return false, xerrors.Errorf("renew lease: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, xerrors.Errorf("rows affected: %w", err)
+	}
+	return rows > 0, nil
+}
@@ -0,0 +1,77 @@
+package database_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/coder/coder/coderd/database/dbtestutil"
+)
+
+func TestLease(t *testing.T) {
+	t.Parallel()
+
+	db, _ := dbtestutil.NewDB(t)
+	ctx := context.Background()
+	const contenders = 10
+
+	owners := make([]uuid.UUID, contenders)
+	var (
+		wg     sync.WaitGroup
+		wins   atomic.Int32
+		winner atomic.Value
+	)
+	for i := range owners {
+		owners[i] = uuid.New()
+		wg.Add(1)
+		go func(owner uuid.UUID) {
+			defer wg.Done()
+			acquired, err := db.AcquireLease(ctx, "leader", owner, time.Minute)
+			assert.NoError(t, err)
+			if acquired {
+				wins.Add(1)
+				winner.Store(owner)
+			}
+		}(owners[i])
+	}
+	wg.Wait()
+	require.EqualValues(t, 1, wins.Load(), "exactly one owner wins")
+
+	leader, _ := winner.Load().(uuid.UUID)
+	var loser uuid.UUID
+	for _, owner := range owners {
+		if owner != leader {
+			loser = owner
+			break
+		}
+	}
+
+	renewed, err := db.RenewLease(ctx, "leader", leader, time.Minute)
+	require.NoError(t, err)
+	require.True(t, renewed)
+	renewed, err = db.RenewLease(ctx, "leader", loser, time.Minute)
+	require.NoError(t, err)
+	require.False(t, renewed, "only the holder can renew")
+	acquired, err := db.AcquireLease(ctx, "leader", leader, time.Minute)
+	require.NoError(t, err)
+	require.True(t, acquired, "the holder can reacquire")
+
+	// Once the lease lapses, another owner can take it and the old holder
+	// can no longer renew.
+	acquired, err = db.AcquireLease(ctx, "short", leader, 10*time.Millisecond)
+	require.NoError(t, err)
+	require.True(t, acquired)
+	time.Sleep(50 * time.Millisecond)
+	acquired, err = db.AcquireLease(ctx, "short", loser, time.Minute)
+	require.NoError(t, err)
+	require.True(t, acquired, "expired leases can be taken over")
+	renewed, err = db.RenewLease(ctx, "short", leader, time.Minute)
+	require.NoError(t, err)
+	require.False(t, renewed)
+}
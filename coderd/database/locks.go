@@ -0,0 +1,156 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"golang.org/x/xerrors"
+)
+
+// LockWait is a backend waiting on a lock held by another backend. A
+// backend blocked by several others appears once per blocker. Query texts
+// have their literals redacted.
+type LockWait struct {
+	BlockedPID    int32  `json:"blocked_pid"`
+	BlockedQuery  string `json:"blocked_query"`
+	BlockingPID   int32  `json:"blocking_pid"`
+	BlockingQuery string `json:"blocking_query"`
+	// BlockingState is the blocking backend's state, such as "idle in
+	// transaction" for a client that opened a transaction and went quiet.
+	BlockingState string `json:"blocking_state"`
+	// WaitDuration is how long the blocked query has been running, which
+	// bounds how long it has waited.
+	WaitDuration time.Duration `json:"wait_duration"`
+	// BlockingTxAge is how long the blocking backend's transaction has been
+	// open.
+	BlockingTxAge time.Duration `json:"blocking_tx_age"`
+}
+
+// ConnInfo is a backend that has sat idle in an open transaction.
+type ConnInfo struct {
+	PID int32 `json:"pid"`
+	// State is "idle in transaction", or "idle in transaction (aborted)"
+	// once a statement in the transaction has failed.
+	State string `json:"state"`
+	// IdleDuration is how long the backend has been idle, and TxAge how
+	// long its transaction has been open.
+	IdleDuration time.Duration `json:"idle_duration"`
+	TxAge        time.Duration `json:"tx_age"`
+	// Query is the last statement the backend ran, with its literals
+	// redacted.
+	Query string `json:"query"`
+}
+
+type lockQuerier interface {
+	// GetBlockingLocks returns every backend in the current database that
+	// is waiting on another's lock, with the backend it waits on, longest
+	// wait first. It only reads, and never reports its own backend.
+	GetBlockingLocks(ctx context.Context) ([]LockWait, error)
+	// GetIdleInTransaction returns the backends of this application, as
+	// identified by application_name, that have been idle in a
+	// transaction for longer than olderThan, longest idle first. Such a
+	// transaction keeps its locks and holds back vacuum until the client
+	// finishes it, which a leaked transaction never does. It only reads,
+	// and never reports its own backend.
+	GetIdleInTransaction(ctx context.Context, olderThan time.Duration) ([]ConnInfo, error)
+}
+
+func (q *sqlQuerier) GetBlockingLocks(ctx context.Context) ([]LockWait, error) {
+	// pg_blocking_pids walks the lock manager for us. Joining pg_locks to
+	// itself by hand misses blockers that are only ahead in the wait queue
+	// and needs a column match for every lock type.
+	const query = `-- name: GetBlockingLocks :many
+	SELECT
+		blocked.pid AS blocked_pid,
+		COALESCE(blocked.query, '') AS blocked_query,
+		blocking.pid AS blocking_pid,
+		COALESCE(blocking.query, '') AS blocking_query,
+		COALESCE(blocking.state, '') AS blocking_state,
+		COALESCE(EXTRACT(EPOCH FROM now() - blocked.query_start), 0)::float8 AS wait_seconds,
+		COALESCE(EXTRACT(EPOCH FROM now() - blocking.xact_start), 0)::float8 AS blocking_tx_seconds
+	FROM
+		pg_stat_activity blocked
+	CROSS JOIN LATERAL
+		unnest(pg_blocking_pids(blocked.pid)) AS blocker(pid)
+	JOIN
+		pg_stat_activity blocking ON blocking.pid = blocker.pid
+	WHERE
+		blocked.datname = current_database()
+		AND blocked.pid <> pg_backend_pid()
+	ORDER BY
+		wait_seconds DESC, blocked.pid, blocking.pid
+	`
+
+	var rows []struct {
+		BlockedPID        int32   `db:"blocked_pid"`
+		BlockedQuery      string  `db:"blocked_query"`
+		BlockingPID       int32   `db:"blocking_pid"`
+		BlockingQuery     string  `db:"blocking_query"`
+		BlockingState     string  `db:"blocking_state"`
+		WaitSeconds       float64 `db:"wait_seconds"`
+		BlockingTxSeconds float64 `db:"blocking_tx_seconds"`
+	}
+	err := q.db.SelectContext(ctx, &rows, query)
+	if err != nil {
+		return nil, xerrors.Errorf("get blocking locks: %w", err)
+	}
+	waits := make([]LockWait, 0, len(rows))
+	for _, row := range rows {
+		waits = append(waits, LockWait{
+			BlockedPID:    row.BlockedPID,
+			BlockedQuery:  redactQueryText(row.BlockedQuery),
+			BlockingPID:   row.BlockingPID,
+			BlockingQuery: redactQueryText(row.BlockingQuery),
+			BlockingState: row.BlockingState,
+			WaitDuration:  time.Duration(row.WaitSeconds * float64(time.Second)),
+			BlockingTxAge: time.Duration(row.BlockingTxSeconds * float64(time.Second)),
+		})
+	}
+	return waits, nil
+}
+
+func (q *sqlQuerier) GetIdleInTransaction(ctx context.Context, olderThan time.Duration) ([]ConnInfo, error) {
+	// state_change is when the backend went idle, as the state has not
+	// changed since.
+	const query = `-- name: GetIdleInTransaction :many
+	SELECT
+		pid,
+		state,
+		EXTRACT(EPOCH FROM now() - state_change)::float8 AS idle_seconds,
+		COALESCE(EXTRACT(EPOCH FROM now() - xact_start), 0)::float8 AS tx_seconds,
+		COALESCE(query, '') AS query
+	FROM
+		pg_stat_activity
+	WHERE
+		datname = current_database()
+		AND application_name = current_setting('application_name')
+		AND state IN ('idle in transaction', 'idle in transaction (aborted)')
+		AND state_change < now() - $1 * interval '1 second'
+		AND pid <> pg_backend_pid()
+	ORDER BY
+		state_change, pid
+	`
+
+	var rows []struct {
+		PID         int32   `db:"pid"`
+		State       string  `db:"state"`
+		IdleSeconds float64 `db:"idle_seconds"`
+		TxSeconds   float64 `db:"tx_seconds"`
+		Query       string  `db:"query"`
+	}
+	err := q.db.SelectContext(ctx, &rows, query, olderThan.Seconds())
+	if err != nil {
+		return nil, xerrors.Errorf("get idle in transaction: %w", err)
+	}
+	conns := make([]ConnInfo, 0, len(rows))
+	for _, row := range rows {
+		conns = append(conns, ConnInfo{
+			PID:          row.PID,
+			State:        row.State,
+			IdleDuration: time.Duration(row.IdleSeconds * float64(time.Second)),
+			TxAge:        time.Duration(row.TxSeconds * float64(time.Second)),
+			Query:        redactQueryText(row.Query),
+		})
+	}
+	return conns, nil
+}
@@ -0,0 +1,64 @@
+//go:build linux
+
+package database_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/coder/coder/coderd/database"
+	"github.com/coder/coder/coderd/database/migrations"
+)
+
+func TestGetIdleInTransactionPostgres(t *testing.T) {
+	t.Parallel()
+	if testing.Short() {
+		t.SkipNow()
+	}
+
+	sqlDB := testSQLDB(t)
+	err := migrations.Up(sqlDB)
+	require.NoError(t, err, "migrations")
+	db := database.New(sqlDB)
+	ctx := context.Background()
+
+	// A transaction that ran a statement and then went quiet, as one
+	// leaked by a handler would.
+	tx, err := sqlDB.BeginTx(ctx, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = tx.Rollback() })
+	var pid int32
+	err = tx.QueryRowContext(ctx, "SELECT pg_backend_pid() WHERE 'secret' = 'secret'").Scan(&pid)
+	require.NoError(t, err)
+
+	conns, err := db.GetIdleInTransaction(ctx, time.Hour)
+	require.NoError(t, err)
+	require.Empty(t, conns, "not idle for long enough")
+
+	var found database.ConnInfo
+	require.Eventually(t, func() bool {
+		conns, err := db.GetIdleInTransaction(ctx, 100*time.Millisecond)
+		require.NoError(t, err)
+		for _, conn := range conns {
+			if conn.PID == pid {
+				found = conn
+				return true
+			}
+		}
+		return false
+	}, 10*time.Second, 50*time.Millisecond)
+	require.Equal(t, "idle in transaction", found.State)
+	require.GreaterOrEqual(t, found.IdleDuration, 100*time.Millisecond)
+	require.GreaterOrEqual(t, found.TxAge, found.IdleDuration)
+	require.Equal(t, "SELECT pg_backend_pid() WHERE ? = ?", found.Query)
+
+	require.NoError(t, tx.Rollback())
+	conns, err = db.GetIdleInTransaction(ctx, 0)
+	require.NoError(t, err)
+	for _, conn := range conns {
+		require.NotEqual(t, pid, conn.PID, "finished transactions are not reported")
+	}
+}
@@ -0,0 +1,76 @@
+package database_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/coder/coder/coderd/database"
+)
+
+func TestGetBlockingLocks(t *testing.T) {
+	t.Parallel()
+
+	sqlDB, connector := newRecordingDB()
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	connector.rows = func(string) ([]string, [][]driver.Value) {
+		return []string{"blocked_pid", "blocked_query", "blocking_pid", "blocking_query", "blocking_state", "wait_seconds", "blocking_tx_seconds"}, [][]driver.Value{{
+			int64(101),
+			"-- name: UpdateUserHashedPassword :exec\nUPDATE users SET hashed_password = $2 WHERE id = $1",
+			int64(202),
+			"UPDATE users SET email = 'jane@coder.com', login_type = E'pass\\'word', rbac_roles = $$x$$ WHERE id = 42",
+			"idle in transaction",
+			1.5,
+			90.0,
+		}}
+	}
+	waits, err := database.New(sqlDB).GetBlockingLocks(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, []database.LockWait{{
+		BlockedPID:    101,
+		BlockedQuery:  "-- name: UpdateUserHashedPassword :exec\nUPDATE users SET hashed_password = $2 WHERE id = $1",
+		BlockingPID:   202,
+		BlockingQuery: "UPDATE users SET email = ?, login_type = ?, rbac_roles = ? WHERE id = ?",
+		BlockingState: "idle in transaction",
+		WaitDuration:  1500 * time.Millisecond,
+		BlockingTxAge: 90 * time.Second,
+	}}, waits)
+
+	queries := connector.Queries()
+	require.Len(t, queries, 1)
+	require.Contains(t, queries[0], "pg_blocking_pids(blocked.pid)")
+	require.Contains(t, queries[0], "blocked.pid <> pg_backend_pid()")
+}
+
+func TestGetIdleInTransaction(t *testing.T) {
+	t.Parallel()
+
+	sqlDB, connector := newRecordingDB()
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	connector.rows = func(string) ([]string, [][]driver.Value) {
+		return []string{"pid", "state", "idle_seconds", "tx_seconds", "query"}, [][]driver.Value{{
+			int64(303),
+			"idle in transaction",
+			120.0,
+			125.5,
+			"UPDATE users SET email = 'jane@coder.com' WHERE id = $1",
+		}}
+	}
+	conns, err := database.New(sqlDB).GetIdleInTransaction(context.Background(), time.Minute)
+	require.NoError(t, err)
+	require.Equal(t, []database.ConnInfo{{
+		PID:          303,
+		State:        "idle in transaction",
+		IdleDuration: 2 * time.Minute,
+		TxAge:        125500 * time.Millisecond,
+		Query:        "UPDATE users SET email = ? WHERE id = $1",
+	}}, conns)
+
+	queries := connector.Queries()
+	require.Len(t, queries, 1)
+	require.Contains(t, queries[0], "application_name = current_setting('application_name')")
+	require.Contains(t, queries[0], "pid <> pg_backend_pid()")
+}
@@ -0,0 +1,63 @@
+package database
+
+import (
+	"context"
+	"sync/atomic"
+
+	"golang.org/x/xerrors"
+)
+
+// Maintenance is a Store whose writes can be paused, e.g. to quiesce the
+// application for a consistent backup or a risky migration without taking
+// it down. While writes are disabled, write methods and read-write
+// transactions fail with ErrMaintenanceMode and reads pass through.
+// Methods are classified by name as in isReadMethod, so callers that only
+// read inside a transaction should use InReadTx to keep working while
+// paused.
+//
+// Pausing does not wait for or interrupt work already running: a
+// transaction that began before SetWriteEnabled(false) may still commit,
+// though any write it attempts afterwards fails.
+type Maintenance struct {
+	Store
+
+	paused atomic.Bool
+}
+
+// NewMaintenance returns a Maintenance that wraps store, with writes
+// enabled.
+func NewMaintenance(store Store) *Maintenance {
+	m := &Maintenance{}
+	m.Store = Intercept(store, m.intercept)
+	return m
+}
+
+// SetWriteEnabled enables or disables writes.
+func (m *Maintenance) SetWriteEnabled(enabled bool) {
+	m.paused.Store(!enabled)
+}
+
+// WriteEnabled reports whether writes are enabled.
+func (m *Maintenance) WriteEnabled() bool {
+	return !m.paused.Load()
+}
+
+func (m *Maintenance) intercept(ctx context.Context, call Call, next Invoker) ([]interface{}, error) {
+	if !m.paused.Load() || isReadCall(call) {
+		return next(ctx)
+	}
+	return nil, xerrors.Errorf("%s: %w", call.Method, ErrMaintenanceMode)
+}
+
+// isReadCall reports whether call only reads: a read method, or a
+// read-only transaction.
+func isReadCall(call Call) bool {
+	if call.Method == "InTx" {
+		if len(call.Args) != 1 {
+			return false
+		}
+		opts, ok := call.Args[0].(TxOptions)
+		return ok && opts.ReadOnly
+	}
+	return isReadMethod(call.Method)
+}
@@ -0,0 +1,59 @@
+package database_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/coder/coder/coderd/database"
+	"github.com/coder/coder/coderd/database/databasefake"
+)
+
+func TestMaintenance(t *testing.T) {
+	t.Parallel()
+
+	db := database.NewMaintenance(databasefake.New())
+	ctx := context.Background()
+	insertUser := func(db database.Store) error {
+		id := uuid.New()
+		_, err := db.InsertUser(ctx, database.InsertUserParams{
+			ID:        id,
+			Email:     id.String() + "@coder.com",
+			Username:  id.String(),
+			RBACRoles: []string{},
+		})
+		return err
+	}
+	require.True(t, db.WriteEnabled())
+	require.NoError(t, insertUser(db))
+
+	db.SetWriteEnabled(false)
+	require.False(t, db.WriteEnabled())
+
+	err := insertUser(db)
+	require.ErrorIs(t, err, database.ErrMaintenanceMode)
+	require.ErrorContains(t, err, "InsertUser")
+	err = db.InTx(func(database.Store) error {
+		t.Error("read-write transaction started while paused")
+		return nil
+	})
+	require.ErrorIs(t, err, database.ErrMaintenanceMode)
+
+	_, err = db.GetUsers(ctx, database.GetUsersParams{})
+	require.NoError(t, err, "reads pass while paused")
+	err = db.InReadTx(ctx, func(tx database.Store) error {
+		_, err := tx.GetUserByID(ctx, uuid.New())
+		require.ErrorIs(t, err, sql.ErrNoRows)
+		require.ErrorIs(t, tx.DeleteAPIKeyByID(ctx, "key"), database.ErrMaintenanceMode)
+		return nil
+	})
+	require.NoError(t, err, "read-only transactions pass while paused")
+
+	db.SetWriteEnabled(true)
+	require.NoError(t, db.InTx(func(tx database.Store) error {
+		return insertUser(tx)
+	}))
+}
@@ -0,0 +1,201 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/HdrHistogram/hdrhistogram-go"
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// MetricsOption configures Metrics.
+type MetricsOption func(*Metrics)
+
+// WithHDRHistograms records every call into a per-method HDR histogram in
+// addition to Prometheus, exposing precise tail latencies through
+// Metrics.Percentile. Latencies are tracked in microseconds up to max with
+// the given number of significant figures (1-5). Histograms are only
+// allocated for methods that are called, and each has a fixed size
+// determined by max and significantFigures, so memory use is bounded by the
+// number of Store methods.
+func WithHDRHistograms(max time.Duration, significantFigures int) MetricsOption {
+	return func(m *Metrics) {
+		m.hdrMax = max.Microseconds()
+		m.hdrFigures = significantFigures
+		m.hdr = map[string]*methodHistogram{}
+	}
+}
+
+// WithTenantLabels adds a "tenant" label to the Prometheus metrics with the
+// organization set on each call's context by WithTenant, to attribute
+// database load to tenants. To bound cardinality, only the organizations in
+// allowlist get their own label; the rest share "other", or are hashed into
+// one of buckets "bucket-N" labels if buckets is positive, which spreads a
+// noisy tenant's load less than "other" would while still pointing at a
+// small set of candidates. Calls without a tenant are labeled "unknown".
+func WithTenantLabels(allowlist []uuid.UUID, buckets int) MetricsOption {
+	return func(m *Metrics) {
+		m.tenants = &tenantLabels{allowed: map[uuid.UUID]bool{}}
+		for _, orgID := range allowlist {
+			m.tenants.allowed[orgID] = true
+		}
+		if buckets > 0 {
+			m.tenants.buckets = uint32(buckets)
+		}
+	}
+}
+
+// WithExemplars attaches the trace ID of the sampled span on a call's
+// context as an exemplar to its latency observation, so a slow bucket can
+// be followed to an example trace. Spans are started by the tracing driver
+// wrapper, or by the caller. Calls without a sampled span are observed
+// without an exemplar, and exemplars are only exposed when scraped in the
+// OpenMetrics format.
+func WithExemplars() MetricsOption {
+	return func(m *Metrics) {
+		m.exemplars = true
+	}
+}
+
+// Metrics records per-method latency and error metrics for a Store. Attach
+// it with Intercept(store, metrics.Interceptor()).
+type Metrics struct {
+	latencies *prometheus.HistogramVec
+	errors    *prometheus.CounterVec
+	exemplars bool
+	tenants   *tenantLabels
+
+	hdrMax     int64
+	hdrFigures int
+	hdrMu      sync.RWMutex
+	hdr        map[string]*methodHistogram
+}
+
+type methodHistogram struct {
+	mu        sync.Mutex
+	histogram *hdrhistogram.Histogram
+}
+
+// NewMetrics creates query metrics. If registerer is nil no Prometheus
+// metrics are registered, which is useful when only HDR percentiles are
+// wanted (e.g. in load tests).
+func NewMetrics(registerer prometheus.Registerer, opts ...MetricsOption) *Metrics {
+	m := &Metrics{}
+	for _, opt := range opts {
+		opt(m)
+	}
+	if registerer != nil {
+		labels := []string{"method"}
+		if m.tenants != nil {
+			labels = append(labels, "tenant")
+		}
+		factory := promauto.With(registerer)
+		m.latencies = factory.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "coderd",
+			Subsystem: "db",
+			Name:      "query_latencies_seconds",
+			Help:      "Latency distribution of database queries in seconds.",
+			Buckets:   prometheus.DefBuckets,
+		}, labels)
+		m.errors = factory.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "coderd",
+			Subsystem: "db",
+			Name:      "query_errors_total",
+			Help:      "The total number of database queries that returned an error other than no rows.",
+		}, labels)
+	}
+	return m
+}
+
+// Interceptor returns an Interceptor that records metrics for each call.
+func (m *Metrics) Interceptor() Interceptor {
+	return func(ctx context.Context, call Call, next Invoker) ([]interface{}, error) {
+		start := time.Now()
+		res, err := next(ctx)
+		m.observe(ctx, call.Method, time.Since(start), err)
+		return res, err
+	}
+}
+
+func (m *Metrics) observe(ctx context.Context, method string, latency time.Duration, err error) {
+	if m.latencies != nil {
+		labels := []string{method}
+		if m.tenants != nil {
+			labels = append(labels, m.tenants.label(ctx))
+		}
+		m.observeLatency(ctx, labels, latency)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			m.errors.WithLabelValues(labels...).Inc()
+		}
+	}
+	if m.hdr == nil {
+		return
+	}
+	h := m.histogram(method)
+	value := latency.Microseconds()
+	if value > m.hdrMax {
+		value = m.hdrMax
+	}
+	h.mu.Lock()
+	// The value is clamped to the trackable range, so this cannot fail.
+	_ = h.histogram.RecordValue(value)
+	h.mu.Unlock()
+}
+
+func (m *Metrics) observeLatency(ctx context.Context, labels []string, latency time.Duration) {
+	observer := m.latencies.WithLabelValues(labels...)
+	span := trace.SpanContextFromContext(ctx)
+	if !m.exemplars || !span.IsSampled() {
+		observer.Observe(latency.Seconds())
+		return
+	}
+	// Histograms always implement ExemplarObserver.
+	// nolint:forcetypeassert
+	observer.(prometheus.ExemplarObserver).ObserveWithExemplar(latency.Seconds(), prometheus.Labels{
+		"trace_id": span.TraceID().String(),
+	})
+}
+
+func (m *Metrics) histogram(method string) *methodHistogram {
+	m.hdrMu.RLock()
+	h, ok := m.hdr[method]
+	m.hdrMu.RUnlock()
+	if ok {
+		return h
+	}
+
+	m.hdrMu.Lock()
+	defer m.hdrMu.Unlock()
+	h, ok = m.hdr[method]
+	if !ok {
+		h = &methodHistogram{
+			histogram: hdrhistogram.New(1, m.hdrMax, m.hdrFigures),
+		}
+		m.hdr[method] = h
+	}
+	return h
+}
+
+// Percentile returns the latency at percentile p (0-100, e.g. 99.9) for the
+// given method. It returns zero if HDR histograms are not enabled or the
+// method has not been called.
+func (m *Metrics) Percentile(method string, p float64) time.Duration {
+	if m.hdr == nil {
+		return 0
+	}
+	m.hdrMu.RLock()
+	h, ok := m.hdr[method]
+	m.hdrMu.RUnlock()
+	if !ok {
+		return 0
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return time.Duration(h.histogram.ValueAtPercentile(p)) * time.Microsecond
+}
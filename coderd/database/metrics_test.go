@@ -0,0 +1,149 @@
+package database_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/coder/coder/coderd/database"
+	"github.com/coder/coder/coderd/database/databasefake"
+)
+
+func TestMetrics(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Prometheus", func(t *testing.T) {
+		t.Parallel()
+
+		registry := prometheus.NewRegistry()
+		metrics := database.NewMetrics(registry)
+		db := database.Intercept(databasefake.New(), metrics.Interceptor())
+
+		_, err := db.GetUserByID(context.Background(), uuid.New())
+		require.Error(t, err)
+
+		families, err := registry.Gather()
+		require.NoError(t, err)
+		var found bool
+		for _, family := range families {
+			if family.GetName() != "coderd_db_query_latencies_seconds" {
+				continue
+			}
+			found = true
+			require.Len(t, family.GetMetric(), 1)
+			require.Equal(t, uint64(1), family.GetMetric()[0].GetHistogram().GetSampleCount())
+		}
+		require.True(t, found, "latency histogram registered")
+	})
+
+	t.Run("Exemplars", func(t *testing.T) {
+		t.Parallel()
+
+		registry := prometheus.NewRegistry()
+		metrics := database.NewMetrics(registry, database.WithExemplars())
+		db := database.Intercept(databasefake.New(), metrics.Interceptor())
+
+		traceID := trace.TraceID{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}
+		ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+			TraceID:    traceID,
+			SpanID:     trace.SpanID{1, 2, 3, 4, 5, 6, 7, 8},
+			TraceFlags: trace.FlagsSampled,
+		}))
+		_, _ = db.GetUserByID(ctx, uuid.New())
+		_, _ = db.GetAPIKeyByID(context.Background(), "untraced")
+
+		families, err := registry.Gather()
+		require.NoError(t, err)
+		exemplars := map[string][]string{}
+		for _, family := range families {
+			if family.GetName() != "coderd_db_query_latencies_seconds" {
+				continue
+			}
+			for _, metric := range family.GetMetric() {
+				method := metric.GetLabel()[0].GetValue()
+				exemplars[method] = nil
+				for _, bucket := range metric.GetHistogram().GetBucket() {
+					for _, label := range bucket.GetExemplar().GetLabel() {
+						exemplars[method] = append(exemplars[method], label.GetName()+"="+label.GetValue())
+					}
+				}
+			}
+		}
+		require.Equal(t, map[string][]string{
+			"GetUserByID":   {"trace_id=" + traceID.String()},
+			"GetAPIKeyByID": nil,
+		}, exemplars)
+	})
+
+	t.Run("TenantLabels", func(t *testing.T) {
+		t.Parallel()
+
+		known, other := uuid.New(), uuid.New()
+		tenantsOf := func(buckets int) map[string]uint64 {
+			registry := prometheus.NewRegistry()
+			metrics := database.NewMetrics(registry, database.WithTenantLabels([]uuid.UUID{known}, buckets))
+			db := database.Intercept(databasefake.New(), metrics.Interceptor())
+			ctx := context.Background()
+			_, _ = db.GetAPIKeyByID(database.WithTenant(ctx, known), "a")
+			_, _ = db.GetAPIKeyByID(database.WithTenant(ctx, other), "a")
+			_, _ = db.GetAPIKeyByID(database.WithTenant(ctx, other), "a")
+			_, _ = db.GetAPIKeyByID(ctx, "a")
+
+			families, err := registry.Gather()
+			require.NoError(t, err)
+			tenants := map[string]uint64{}
+			for _, family := range families {
+				if family.GetName() != "coderd_db_query_latencies_seconds" {
+					continue
+				}
+				for _, metric := range family.GetMetric() {
+					for _, label := range metric.GetLabel() {
+						if label.GetName() == "tenant" {
+							tenants[label.GetValue()] = metric.GetHistogram().GetSampleCount()
+						}
+					}
+				}
+			}
+			return tenants
+		}
+
+		require.Equal(t, map[string]uint64{known.String(): 1, "other": 2, "unknown": 1}, tenantsOf(0))
+		bucketed := tenantsOf(4)
+		require.Len(t, bucketed, 3)
+		require.EqualValues(t, 1, bucketed[known.String()])
+		require.EqualValues(t, 1, bucketed["unknown"])
+		delete(bucketed, known.String())
+		delete(bucketed, "unknown")
+		for label, count := range bucketed {
+			require.Regexp(t, `^bucket-[0-3]$`, label)
+			require.EqualValues(t, 2, count, "a tenant always hashes to the same bucket")
+		}
+	})
+
+	t.Run("Percentile", func(t *testing.T) {
+		t.Parallel()
+
+		metrics := database.NewMetrics(nil, database.WithHDRHistograms(time.Minute, 3))
+		slow := func(ctx context.Context, call database.Call, next database.Invoker) ([]interface{}, error) {
+			if call.Args[0] == "slow" {
+				time.Sleep(50 * time.Millisecond)
+			}
+			return next(ctx)
+		}
+		db := database.Intercept(databasefake.New(), metrics.Interceptor(), slow)
+
+		for i := 0; i < 99; i++ {
+			_, _ = db.GetAPIKeyByID(context.Background(), "fast")
+		}
+		_, _ = db.GetAPIKeyByID(context.Background(), "slow")
+
+		require.Less(t, metrics.Percentile("GetAPIKeyByID", 50), 50*time.Millisecond)
+		require.GreaterOrEqual(t, metrics.Percentile("GetAPIKeyByID", 100), 50*time.Millisecond)
+		require.Zero(t, metrics.Percentile("GetUserByID", 99))
+	})
+}
@@ -0,0 +1,56 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"golang.org/x/xerrors"
+)
+
+// migrationLockID is the advisory lock key held by WithMigrationLock. It is
+// fixed so that every replica contends for the same lock.
+const migrationLockID int64 = 0x636f6465726d6967 // "codermig"
+
+// migrationUnlockTimeout bounds the unlock after the callback, which must
+// run even if ctx was canceled meanwhile.
+const migrationUnlockTimeout = 5 * time.Second
+
+// WithMigrationLock runs function while holding a session-level advisory
+// lock on a pinned connection, so that when several replicas start at once
+// only one migrates and the others wait for it to finish. Waiting ends
+// with an error when ctx is done. The lock is released when function
+// returns, or by Postgres if the connection is lost. If releasing it
+// fails, the connection is closed rather than returned to the pool, where
+// it would hold the lock and block every other replica forever.
+func (q *sqlQuerier) WithMigrationLock(ctx context.Context, function func() error) error {
+	const lock = `-- name: WithMigrationLock :exec
+	SELECT pg_advisory_lock($1)
+	`
+	const unlock = `-- name: WithMigrationLock :exec
+	SELECT pg_advisory_unlock($1)
+	`
+
+	return q.WithConn(ctx, func(conn Store) error {
+		pinned := txQuerier(conn)
+		db := pinned.db
+		_, err := db.ExecContext(ctx, lock, migrationLockID)
+		if err != nil {
+			return xerrors.Errorf("acquire migration lock: %w", err)
+		}
+		err = function()
+
+		unlockCtx, cancel := context.WithTimeout(context.Background(), migrationUnlockTimeout)
+		defer cancel()
+		_, unlockErr := db.ExecContext(unlockCtx, unlock, migrationLockID)
+		if unlockErr != nil && pinned.conn != nil {
+			discardConn(pinned.conn)
+		}
+		if err != nil {
+			return err
+		}
+		if unlockErr != nil {
+			return xerrors.Errorf("release migration lock: %w", unlockErr)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,101 @@
+package database_test
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/xerrors"
+
+	"github.com/coder/coder/coderd/database"
+	"github.com/coder/coder/coderd/database/dbtestutil"
+)
+
+func TestWithMigrationLock(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Replicas", func(t *testing.T) {
+		t.Parallel()
+		// Replicas of one database contend for the same lock; against
+		// Postgres each call pins a connection of its own.
+		db, _ := dbtestutil.NewDB(t)
+		replicas := []database.Store{db, db}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		var (
+			wg      sync.WaitGroup
+			running atomic.Int32
+			ran     atomic.Int32
+		)
+		for _, db := range replicas {
+			db := db
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				err := db.WithMigrationLock(ctx, func() error {
+					if running.Add(1) != 1 {
+						t.Error("two migrations ran at once")
+					}
+					time.Sleep(50 * time.Millisecond)
+					running.Add(-1)
+					ran.Add(1)
+					return nil
+				})
+				assert.NoError(t, err)
+			}()
+		}
+		wg.Wait()
+		require.EqualValues(t, len(replicas), ran.Load(), "waiters migrate after the holder")
+
+		// A waiter gives up when its context is done.
+		holding := make(chan struct{})
+		release := make(chan struct{})
+		go func() {
+			_ = replicas[0].WithMigrationLock(ctx, func() error {
+				close(holding)
+				<-release
+				return nil
+			})
+		}()
+		<-holding
+		waitCtx, waitCancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer waitCancel()
+		err := replicas[len(replicas)-1].WithMigrationLock(waitCtx, func() error {
+			t.Error("migrated while the lock was held")
+			return nil
+		})
+		require.Error(t, err)
+		close(release)
+	})
+
+	t.Run("UnlockFails", func(t *testing.T) {
+		t.Parallel()
+		sqlDB, connector := newRecordingDB()
+		t.Cleanup(func() { _ = sqlDB.Close() })
+		var failUnlock atomic.Bool
+		connector.hook = func(_ context.Context, query string) error {
+			if failUnlock.Load() && strings.Contains(query, "pg_advisory_unlock") {
+				return xerrors.New("unlock failed")
+			}
+			return nil
+		}
+		db := database.New(sqlDB)
+		ctx := context.Background()
+
+		err := db.WithMigrationLock(ctx, func() error { return nil })
+		require.NoError(t, err)
+		require.Zero(t, connector.Closed(), "the connection is returned to the pool")
+
+		failUnlock.Store(true)
+		err = db.WithMigrationLock(ctx, func() error { return nil })
+		require.ErrorContains(t, err, "release migration lock")
+		require.Equal(t, 1, connector.Closed(), "a connection that may hold the lock is closed")
+		require.Zero(t, sqlDB.Stats().Idle)
+	})
+}
@@ -0,0 +1,5 @@
+BEGIN;
+
+DROP INDEX idx_workspaces_updated_at_id;
+
+COMMIT;
@@ -0,0 +1,5 @@
+BEGIN;
+
+CREATE INDEX idx_workspaces_updated_at_id ON workspaces USING btree (updated_at, id);
+
+COMMIT;
@@ -0,0 +1,12 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS leases (
+    name text NOT NULL,
+    owner_id uuid NOT NULL,
+    expires_at timestamp with time zone NOT NULL,
+    PRIMARY KEY (name)
+);
+
+COMMENT ON TABLE leases IS 'Named leases held by one owner until they expire, used for coordinating work across replicas.';
+
+COMMIT;
@@ -0,0 +1,26 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS audit_logs_archive (
+    id uuid NOT NULL,
+    "time" timestamp with time zone NOT NULL,
+    user_id uuid NOT NULL,
+    organization_id uuid NOT NULL,
+    ip inet NOT NULL,
+    user_agent character varying(256) NOT NULL,
+    resource_type resource_type NOT NULL,
+    resource_id uuid NOT NULL,
+    resource_target text NOT NULL,
+    action audit_action NOT NULL,
+    diff jsonb NOT NULL,
+    status_code integer NOT NULL,
+    additional_fields jsonb NOT NULL,
+    request_id uuid NOT NULL,
+    resource_icon text NOT NULL,
+    PRIMARY KEY (id)
+);
+
+CREATE INDEX idx_audit_logs_archive_time_desc ON audit_logs_archive USING btree ("time" DESC);
+
+COMMENT ON TABLE audit_logs_archive IS 'Audit logs moved out of audit_logs by retention. Columns must match audit_logs.';
+
+COMMIT;
@@ -0,0 +1,12 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS workspace_idempotency_keys (
+    idempotency_key text NOT NULL,
+    workspace_id uuid NOT NULL REFERENCES workspaces (id) ON DELETE CASCADE DEFERRABLE INITIALLY DEFERRED,
+    created_at timestamp with time zone NOT NULL,
+    PRIMARY KEY (idempotency_key)
+);
+
+COMMENT ON TABLE workspace_idempotency_keys IS 'Client-supplied keys that make workspace creation safe to retry. The key is claimed before the workspace is inserted, so the foreign key is deferred.';
+
+COMMIT;
@@ -0,0 +1,16 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS outbox (
+    id uuid NOT NULL,
+    topic text NOT NULL,
+    payload jsonb NOT NULL,
+    created_at timestamp with time zone NOT NULL,
+    published_at timestamp with time zone,
+    PRIMARY KEY (id)
+);
+
+COMMENT ON TABLE outbox IS 'Events written in the transaction that caused them, for a relay to publish to a broker once it commits.';
+
+CREATE INDEX idx_outbox_unpublished ON outbox USING btree (created_at, id) WHERE (published_at IS NULL);
+
+COMMIT;
@@ -0,0 +1,5 @@
+BEGIN;
+
+DROP TABLE sharded_counters;
+
+COMMIT;
@@ -0,0 +1,12 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS sharded_counters (
+    name text NOT NULL,
+    shard integer NOT NULL,
+    value bigint DEFAULT 0 NOT NULL,
+    PRIMARY KEY (name, shard)
+);
+
+COMMENT ON TABLE sharded_counters IS 'Counters split across rows so concurrent increments do not contend for one row lock. A counter''s value is the sum of its shards.';
+
+COMMIT;
@@ -0,0 +1,5 @@
+BEGIN;
+
+ALTER TABLE ONLY workspaces DROP COLUMN dormant_at;
+
+COMMIT;
@@ -0,0 +1,6 @@
+BEGIN;
+
+ALTER TABLE ONLY workspaces ADD COLUMN dormant_at timestamp with time zone;
+COMMENT ON COLUMN workspaces.dormant_at IS 'dormant_at is when the workspace was marked inactive for disuse, or NULL if it is active. Dormant workspaces are not autostarted, and using the workspace clears it.';
+
+COMMIT;
@@ -2,8 +2,10 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/lib/pq"
 
@@ -19,11 +21,70 @@ import (
 type customQuerier interface {
 	templateQuerier
 	workspaceQuerier
+	userQuerier
+	replicationQuerier
+	clockQuerier
+	schemaQuerier
+	notifyQuerier
+	existenceQuerier
+	aggregateQuerier
+	exportQuerier
+	orderedQuerier
+	twoPhaseQuerier
+	statsQuerier
+	leaseQuerier
+	countsQuerier
+	batchQuerier
+	accessQuerier
+	archiveQuerier
+	returningQuerier
+	capacityQuerier
+	touchQuerier
+	chunkedQuerier
+	upsertQuerier
+	lockQuerier
+	etagQuerier
+	consistencyQuerier
+	claimQuerier
+	explainQuerier
+	quotaQuerier
+	renameQuerier
+	distinctQuerier
+	outboxQuerier
+	counterQuerier
+	integrityQuerier
 }
 
 type templateQuerier interface {
 	GetTemplateGroupRoles(ctx context.Context, id uuid.UUID) ([]TemplateGroup, error)
 	GetTemplateUserRoles(ctx context.Context, id uuid.UUID) ([]TemplateUser, error)
+	// UpdateTemplateIfVersion updates the template metadata only if its
+	// updated_at still equals expectedUpdatedAt, which callers read along
+	// with the template. It reports whether the template was updated;
+	// false means another writer got there first (or the template does not
+	// exist) and the caller should re-read and retry. The new updated_at is
+	// set by the database and is always later than expectedUpdatedAt.
+	UpdateTemplateIfVersion(ctx context.Context, id uuid.UUID, expectedUpdatedAt time.Time, arg UpdateTemplateIfVersionParams) (bool, error)
+	// GetTemplateByIDForShare returns the template and locks it FOR SHARE
+	// until the transaction ends, for flows that validate the template and
+	// then depend on it staying as read, such as creating a workspace from
+	// it. Other transactions can still read it and take the same lock, so
+	// concurrent flows do not wait on each other, but updates and deletes
+	// wait until every holder commits. FOR UPDATE, as used by
+	// UpdateTemplateIfMatch, would also make the other lockers wait, and
+	// is for flows that go on to modify the row. It must be called inside
+	// InTx, since outside a transaction the lock is released as soon as it
+	// is taken, and not in a read-only one, where Postgres refuses row
+	// locks.
+	GetTemplateByIDForShare(ctx context.Context, id uuid.UUID) (Template, error)
+}
+
+type UpdateTemplateIfVersionParams struct {
+	Description          string `db:"description" json:"description"`
+	MaxTtl               int64  `db:"max_ttl" json:"max_ttl"`
+	MinAutostartInterval int64  `db:"min_autostart_interval" json:"min_autostart_interval"`
+	Name                 string `db:"name" json:"name"`
+	Icon                 string `db:"icon" json:"icon"`
 }
 
 type TemplateUser struct {
@@ -32,7 +93,7 @@ type TemplateUser struct {
 }
 
 func (q *sqlQuerier) GetTemplateUserRoles(ctx context.Context, id uuid.UUID) ([]TemplateUser, error) {
-	const query = `
+	const query = `-- name: GetTemplateUserRoles :many
 	SELECT
 		perms.value as actions, users.*
 	FROM
@@ -70,13 +131,74 @@ func (q *sqlQuerier) GetTemplateUserRoles(ctx context.Context, id uuid.UUID) ([]
 	return tus, nil
 }
 
+func (q *sqlQuerier) UpdateTemplateIfVersion(ctx context.Context, id uuid.UUID, expectedUpdatedAt time.Time, arg UpdateTemplateIfVersionParams) (bool, error) {
+	const query = `-- name: UpdateTemplateIfVersion :execrows
+	UPDATE
+		templates
+	SET
+		updated_at = GREATEST(now(), $2 + interval '1 microsecond'),
+		description = $3,
+		max_ttl = $4,
+		min_autostart_interval = $5,
+		name = $6,
+		icon = $7
+	WHERE
+		id = $1
+	AND
+		updated_at = $2
+	`
+
+	result, err := q.db.ExecContext(ctx, query,
+		id,
+		expectedUpdatedAt,
+		arg.Description,
+		arg.MaxTtl,
+		arg.MinAutostartInterval,
+		arg.Name,
+		arg.Icon,
+	)
+	if err != nil {
+		return false, xerrors.Errorf("update template: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, xerrors.Errorf("rows affected: %w", err)
+	}
+	return rows > 0, nil
+}
+
+func (q *sqlQuerier) GetTemplateByIDForShare(ctx context.Context, id uuid.UUID) (Template, error) {
+	if !q.inTx {
+		return Template{}, xerrors.New("get template for share must be called inside a transaction")
+	}
+	// Unlike the lock in NextBuildNumber, this one reads only the row it
+	// locks, which a locking read re-checks after waiting on a writer, so
+	// it needs no separate statement.
+	const query = `-- name: GetTemplateByIDForShare :one
+	SELECT
+		*
+	FROM
+		templates
+	WHERE
+		id = $1
+	FOR SHARE
+	`
+
+	var template Template
+	err := q.db.GetContext(ctx, &template, query, id)
+	if err != nil {
+		return Template{}, xerrors.Errorf("get template for share: %w", err)
+	}
+	return template, nil
+}
+
 type TemplateGroup struct {
 	Group
 	Actions Actions `db:"actions"`
 }
 
 func (q *sqlQuerier) GetTemplateGroupRoles(ctx context.Context, id uuid.UUID) ([]TemplateGroup, error) {
-	const query = `
+	const query = `-- name: GetTemplateGroupRoles :many
 	SELECT
 		perms.value as actions, groups.*
 	FROM
@@ -113,12 +235,50 @@ func (q *sqlQuerier) GetTemplateGroupRoles(ctx context.Context, id uuid.UUID) ([
 type workspaceQuerier interface {
 	GetAuthorizedWorkspaces(ctx context.Context, arg GetWorkspacesParams, authorizedFilter rbac.AuthorizeFilter) ([]Workspace, error)
 	GetAuthorizedWorkspaceCount(ctx context.Context, arg GetWorkspaceCountParams, authorizedFilter rbac.AuthorizeFilter) (int64, error)
+	// NextBuildNumber returns the build number to use for the next build of
+	// the workspace. It locks the workspace row, so it must be called inside
+	// InTx together with the insert of the build to prevent concurrent
+	// builds from receiving the same number, and it fails outside one.
+	NextBuildNumber(ctx context.Context, workspaceID uuid.UUID) (int32, error)
+	// InsertWorkspaceReturningComputed is like InsertWorkspace, but
+	// created_at and updated_at are set from the database clock instead of
+	// the caller's. The returned row includes them and the column defaults
+	// for deleted and last_used_at, so no follow-up read is needed.
+	InsertWorkspaceReturningComputed(ctx context.Context, arg InsertWorkspaceReturningComputedParams) (Workspace, error)
+	// GetWorkspacesModifiedSince returns workspaces ordered by (updated_at,
+	// id) that come after the cursor (arg.UpdatedAt, arg.AfterID), for
+	// incremental sync. Pass the updated_at and id of the last row of a page
+	// as the cursor for the next one; the id breaks ties between rows
+	// updated at the same time, so no row is skipped or repeated. Use
+	// uuid.Nil as AfterID to start from a timestamp. Deleted workspaces are
+	// included so deletions can be synced.
+	GetWorkspacesModifiedSince(ctx context.Context, arg GetWorkspacesModifiedSinceParams) ([]Workspace, error)
+}
+
+type GetWorkspacesModifiedSinceParams struct {
+	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+	AfterID   uuid.UUID `db:"after_id" json:"after_id"`
+	Limit     int32     `db:"limit" json:"limit"`
+}
+
+type InsertWorkspaceReturningComputedParams struct {
+	ID                uuid.UUID      `db:"id" json:"id"`
+	OwnerID           uuid.UUID      `db:"owner_id" json:"owner_id"`
+	OrganizationID    uuid.UUID      `db:"organization_id" json:"organization_id"`
+	TemplateID        uuid.UUID      `db:"template_id" json:"template_id"`
+	Name              string         `db:"name" json:"name"`
+	AutostartSchedule sql.NullString `db:"autostart_schedule" json:"autostart_schedule"`
+	Ttl               sql.NullInt64  `db:"ttl" json:"ttl"`
 }
 
 // GetAuthorizedWorkspaces returns all workspaces that the user is authorized to access.
 // This code is copied from `GetWorkspaces` and adds the authorized filter WHERE
 // clause.
 func (q *sqlQuerier) GetAuthorizedWorkspaces(ctx context.Context, arg GetWorkspacesParams, authorizedFilter rbac.AuthorizeFilter) ([]Workspace, error) {
+	err := validateStatusFilter(arg.Status)
+	if err != nil {
+		return nil, err
+	}
 	// In order to properly use ORDER BY, OFFSET, and LIMIT, we need to inject the
 	// authorizedFilter between the end of the where clause and those statements.
 	filter := strings.Replace(getWorkspaces, "-- @authorize_filter", fmt.Sprintf(" AND %s", authorizedFilter.SQLString(rbac.NoACLConfig())), 1)
@@ -141,6 +301,9 @@ func (q *sqlQuerier) GetAuthorizedWorkspaces(ctx context.Context, arg GetWorkspa
 	defer rows.Close()
 	var items []Workspace
 	for rows.Next() {
+		if q.opts.maxResultRows > 0 && len(items) == q.opts.maxResultRows {
+			return nil, ErrResultTooLarge
+		}
 		var i Workspace
 		if err := rows.Scan(
 			&i.ID,
@@ -154,6 +317,7 @@ func (q *sqlQuerier) GetAuthorizedWorkspaces(ctx context.Context, arg GetWorkspa
 			&i.AutostartSchedule,
 			&i.Ttl,
 			&i.LastUsedAt,
+			&i.DormantAt,
 		); err != nil {
 			return nil, err
 		}
@@ -169,6 +333,10 @@ func (q *sqlQuerier) GetAuthorizedWorkspaces(ctx context.Context, arg GetWorkspa
 }
 
 func (q *sqlQuerier) GetAuthorizedWorkspaceCount(ctx context.Context, arg GetWorkspaceCountParams, authorizedFilter rbac.AuthorizeFilter) (int64, error) {
+	err := validateStatusFilter(arg.Status)
+	if err != nil {
+		return 0, err
+	}
 	// In order to properly use ORDER BY, OFFSET, and LIMIT, we need to inject the
 	// authorizedFilter between the end of the where clause and those statements.
 	filter := strings.Replace(getWorkspaceCount, "-- @authorize_filter", fmt.Sprintf(" AND %s", authorizedFilter.SQLString(rbac.NoACLConfig())), 1)
@@ -184,6 +352,168 @@ func (q *sqlQuerier) GetAuthorizedWorkspaceCount(ctx context.Context, arg GetWor
 		arg.Name,
 	)
 	var count int64
-	err := row.Scan(&count)
+	err = row.Scan(&count)
 	return count, err
 }
+
+func (q *sqlQuerier) NextBuildNumber(ctx context.Context, workspaceID uuid.UUID) (int32, error) {
+	if !q.inTx {
+		return 0, xerrors.New("next build number must be called inside a transaction")
+	}
+	// The lock and the read are separate statements on purpose: under read
+	// committed, a statement that waits on a row lock still reads from the
+	// snapshot taken before the wait, and would miss the build committed by
+	// the transaction that held the lock.
+	const lock = `-- name: NextBuildNumber :one
+	SELECT id FROM workspaces WHERE id = $1 FOR UPDATE
+	`
+	const next = `-- name: NextBuildNumber :one
+	SELECT
+		COALESCE(MAX(build_number), 0) + 1
+	FROM
+		workspace_builds
+	WHERE
+		workspace_id = $1
+	`
+
+	var id uuid.UUID
+	err := q.db.GetContext(ctx, &id, lock, workspaceID)
+	if err != nil {
+		return 0, xerrors.Errorf("lock workspace: %w", err)
+	}
+	var number int32
+	err = q.db.GetContext(ctx, &number, next, workspaceID)
+	if err != nil {
+		return 0, xerrors.Errorf("select next build number: %w", err)
+	}
+	return number, nil
+}
+
+func (q *sqlQuerier) InsertWorkspaceReturningComputed(ctx context.Context, arg InsertWorkspaceReturningComputedParams) (Workspace, error) {
+	const query = `-- name: InsertWorkspaceReturningComputed :one
+	INSERT INTO
+		workspaces (
+			id,
+			created_at,
+			updated_at,
+			owner_id,
+			organization_id,
+			template_id,
+			name,
+			autostart_schedule,
+			ttl
+		)
+	VALUES
+		($1, now(), now(), $2, $3, $4, $5, $6, $7) RETURNING *
+	`
+
+	var workspace Workspace
+	err := q.db.GetContext(ctx, &workspace, query,
+		arg.ID,
+		arg.OwnerID,
+		arg.OrganizationID,
+		arg.TemplateID,
+		arg.Name,
+		arg.AutostartSchedule,
+		arg.Ttl,
+	)
+	if err != nil {
+		return Workspace{}, xerrors.Errorf("insert workspace: %w", err)
+	}
+	return workspace, nil
+}
+
+type userQuerier interface {
+	// SoftDeleteUsersByOrg marks every non-deleted member of the
+	// organization as deleted in a single statement and returns their IDs
+	// in no particular order, or an empty slice if none matched. Users that
+	// also belong to another organization are kept.
+	SoftDeleteUsersByOrg(ctx context.Context, orgID uuid.UUID) ([]uuid.UUID, error)
+	// UpdateUserAudited updates the user's profile like UpdateUserProfile
+	// and also returns the row as it was just before the update, so
+	// callers can record an audit diff without a racy read beforehand.
+	UpdateUserAudited(ctx context.Context, arg UpdateUserProfileParams) (before User, after User, err error)
+}
+
+func (q *sqlQuerier) SoftDeleteUsersByOrg(ctx context.Context, orgID uuid.UUID) ([]uuid.UUID, error) {
+	const query = `-- name: SoftDeleteUsersByOrg :many
+	UPDATE
+		users
+	SET
+		deleted = true
+	WHERE
+		deleted = false
+	AND
+		id IN (
+			SELECT user_id FROM organization_members WHERE organization_id = $1
+		)
+	AND
+		NOT EXISTS (
+			SELECT 1 FROM organization_members WHERE user_id = users.id AND organization_id <> $1
+		)
+	RETURNING id
+	`
+
+	ids := []uuid.UUID{}
+	err := q.db.SelectContext(ctx, &ids, query, orgID)
+	if err != nil {
+		return nil, xerrors.Errorf("soft delete users: %w", err)
+	}
+	return ids, nil
+}
+
+func (q *sqlQuerier) UpdateUserAudited(ctx context.Context, arg UpdateUserProfileParams) (User, User, error) {
+	// As in UpdateTemplateIfMatch, the row is locked by a separate
+	// statement so the before read sees what a concurrent writer that
+	// held the lock committed. Returning the old row from the UPDATE
+	// itself would need row_to_json, which does not round-trip bytea
+	// columns such as hashed_password.
+	const lock = `-- name: UpdateUserAudited :one
+	SELECT id FROM users WHERE id = $1 FOR UPDATE
+	`
+
+	var before, after User
+	err := q.inCurrentTx(ctx, func(tx Store) error {
+		var locked uuid.UUID
+		err := txQuerier(tx).db.GetContext(ctx, &locked, lock, arg.ID)
+		if err != nil {
+			return xerrors.Errorf("lock user: %w", err)
+		}
+		before, err = tx.GetUserByID(ctx, arg.ID)
+		if err != nil {
+			return xerrors.Errorf("get user: %w", err)
+		}
+		after, err = tx.UpdateUserProfile(ctx, arg)
+		if err != nil {
+			return xerrors.Errorf("update user profile: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return User{}, User{}, err
+	}
+	return before, after, nil
+}
+
+func (q *sqlQuerier) GetWorkspacesModifiedSince(ctx context.Context, arg GetWorkspacesModifiedSinceParams) ([]Workspace, error) {
+	// The row comparison matches idx_workspaces_updated_at_id.
+	const query = `-- name: GetWorkspacesModifiedSince :many
+	SELECT
+		*
+	FROM
+		workspaces
+	WHERE
+		(updated_at, id) > ($1, $2)
+	ORDER BY
+		updated_at, id
+	LIMIT
+		$3
+	`
+
+	workspaces := []Workspace{}
+	err := q.db.SelectContext(ctx, &workspaces, query, arg.UpdatedAt, arg.AfterID, arg.Limit)
+	if err != nil {
+		return nil, xerrors.Errorf("select workspaces modified since: %w", err)
+	}
+	return workspaces, nil
+}
@@ -0,0 +1,718 @@
+//go:build linux
+
+package database_test
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tabbed/pqtype"
+
+	"github.com/coder/coder/coderd/database"
+	"github.com/coder/coder/coderd/database/dbtestutil"
+	"github.com/coder/coder/coderd/database/migrations"
+	"github.com/coder/coder/coderd/rbac"
+)
+
+func TestNextBuildNumber(t *testing.T) {
+	t.Parallel()
+
+	// Outside a transaction the lock would be released at once.
+	recordingDB, connector := newRecordingDB()
+	t.Cleanup(func() { _ = recordingDB.Close() })
+	_, err := database.New(recordingDB).NextBuildNumber(context.Background(), uuid.New())
+	require.ErrorContains(t, err, "must be called inside a transaction")
+	require.Empty(t, connector.Queries())
+
+	if testing.Short() {
+		t.SkipNow()
+	}
+
+	sqlDB := testSQLDB(t)
+	err = migrations.Up(sqlDB)
+	require.NoError(t, err, "migrations")
+	db := database.New(sqlDB)
+	ctx := context.Background()
+
+	user, org, template := insertTemplate(t, db)
+	version, err := db.InsertTemplateVersion(ctx, database.InsertTemplateVersionParams{
+		ID:             uuid.New(),
+		TemplateID:     uuid.NullUUID{UUID: template.ID, Valid: true},
+		OrganizationID: org.ID,
+		CreatedAt:      database.Now(),
+		UpdatedAt:      database.Now(),
+		Name:           "version",
+		JobID:          uuid.New(),
+	})
+	require.NoError(t, err)
+	workspace, err := db.InsertWorkspace(ctx, database.InsertWorkspaceParams{
+		ID:             uuid.New(),
+		CreatedAt:      database.Now(),
+		UpdatedAt:      database.Now(),
+		OwnerID:        user.ID,
+		OrganizationID: org.ID,
+		TemplateID:     template.ID,
+		Name:           "workspace",
+	})
+	require.NoError(t, err)
+
+	err = db.InTx(func(tx database.Store) error {
+		number, err := tx.NextBuildNumber(ctx, workspace.ID)
+		require.NoError(t, err)
+		require.EqualValues(t, 1, number)
+		return nil
+	})
+	require.NoError(t, err)
+
+	// Each goroutine reserves a number and inserts a build with it in the
+	// same transaction. The unique (workspace_id, build_number) constraint
+	// would fail the insert if two builds received the same number.
+	const builds = 25
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		numbers = map[int32]bool{}
+	)
+	for i := 0; i < builds; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := db.InTx(func(tx database.Store) error {
+				number, err := tx.NextBuildNumber(ctx, workspace.ID)
+				if err != nil {
+					return err
+				}
+				job, err := tx.InsertProvisionerJob(ctx, database.InsertProvisionerJobParams{
+					ID:             uuid.New(),
+					CreatedAt:      database.Now(),
+					UpdatedAt:      database.Now(),
+					OrganizationID: org.ID,
+					InitiatorID:    user.ID,
+					Provisioner:    database.ProvisionerTypeEcho,
+					StorageMethod:  database.ProvisionerStorageMethodFile,
+					FileID:         uuid.New(),
+					Type:           database.ProvisionerJobTypeWorkspaceBuild,
+					Input:          json.RawMessage("{}"),
+				})
+				if err != nil {
+					return err
+				}
+				_, err = tx.InsertWorkspaceBuild(ctx, database.InsertWorkspaceBuildParams{
+					ID:                uuid.New(),
+					CreatedAt:         database.Now(),
+					UpdatedAt:         database.Now(),
+					WorkspaceID:       workspace.ID,
+					TemplateVersionID: version.ID,
+					BuildNumber:       number,
+					Transition:        database.WorkspaceTransitionStart,
+					InitiatorID:       user.ID,
+					JobID:             job.ID,
+					Reason:            database.BuildReasonInitiator,
+				})
+				if err != nil {
+					return err
+				}
+				mu.Lock()
+				numbers[number] = true
+				mu.Unlock()
+				return nil
+			})
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	require.Len(t, numbers, builds, "build numbers must be unique")
+	for i := int32(1); i <= builds; i++ {
+		require.True(t, numbers[i], "missing build number %d", i)
+	}
+
+	err = db.InTx(func(tx database.Store) error {
+		_, err := tx.NextBuildNumber(ctx, uuid.New())
+		return err
+	})
+	require.Error(t, err, "workspace does not exist")
+}
+
+// insertTemplate creates a user, an organization and a template owned by
+// both, which workspaces depend on.
+func insertTemplate(t *testing.T, db database.Store) (database.User, database.Organization, database.Template) {
+	t.Helper()
+	ctx := context.Background()
+
+	user, err := db.InsertUser(ctx, database.InsertUserParams{
+		ID:             uuid.New(),
+		Email:          "coder@coder.com",
+		Username:       "coder",
+		HashedPassword: []byte{},
+		CreatedAt:      database.Now(),
+		UpdatedAt:      database.Now(),
+		RBACRoles:      []string{},
+		LoginType:      database.LoginTypePassword,
+	})
+	require.NoError(t, err)
+	org, err := db.InsertOrganization(ctx, database.InsertOrganizationParams{
+		ID:        uuid.New(),
+		Name:      "org",
+		CreatedAt: database.Now(),
+		UpdatedAt: database.Now(),
+	})
+	require.NoError(t, err)
+	template, err := db.InsertTemplate(ctx, database.InsertTemplateParams{
+		ID:             uuid.New(),
+		CreatedAt:      database.Now(),
+		UpdatedAt:      database.Now(),
+		OrganizationID: org.ID,
+		Name:           "template",
+		Provisioner:    database.ProvisionerTypeEcho,
+		CreatedBy:      user.ID,
+		UserACL:        database.TemplateACL{},
+		GroupACL:       database.TemplateACL{},
+	})
+	require.NoError(t, err)
+
+	return user, org, template
+}
+
+func TestGetAuthorizedWorkspaces(t *testing.T) {
+	t.Parallel()
+
+	db, _ := dbtestutil.NewDB(t)
+	ctx := context.Background()
+	user, org, template := insertTemplate(t, db)
+	workspace, err := db.InsertWorkspace(ctx, database.InsertWorkspaceParams{
+		ID:             uuid.New(),
+		CreatedAt:      database.Now(),
+		UpdatedAt:      database.Now(),
+		OwnerID:        user.ID,
+		OrganizationID: org.ID,
+		TemplateID:     template.ID,
+		Name:           "workspace",
+	})
+	require.NoError(t, err)
+
+	workspaces, err := db.GetAuthorizedWorkspaces(ctx, database.GetWorkspacesParams{}, allowAll{})
+	require.NoError(t, err)
+	require.Len(t, workspaces, 1)
+	require.Equal(t, workspace.ID, workspaces[0].ID)
+	require.False(t, workspaces[0].DormantAt.Valid)
+}
+
+func TestInsertWorkspaceReturningComputed(t *testing.T) {
+	t.Parallel()
+	if testing.Short() {
+		t.SkipNow()
+	}
+
+	sqlDB := testSQLDB(t)
+	err := migrations.Up(sqlDB)
+	require.NoError(t, err, "migrations")
+	db := database.New(sqlDB)
+	ctx := context.Background()
+
+	user, org, template := insertTemplate(t, db)
+	before, err := db.DBNow(ctx)
+	require.NoError(t, err)
+	inserted, err := db.InsertWorkspaceReturningComputed(ctx, database.InsertWorkspaceReturningComputedParams{
+		ID:             uuid.New(),
+		OwnerID:        user.ID,
+		OrganizationID: org.ID,
+		TemplateID:     template.ID,
+		Name:           "workspace",
+	})
+	require.NoError(t, err)
+	require.False(t, inserted.CreatedAt.Before(before), "created_at is set by the database")
+	require.Equal(t, inserted.CreatedAt, inserted.UpdatedAt)
+	require.False(t, inserted.Deleted)
+
+	read, err := db.GetWorkspaceByID(ctx, inserted.ID)
+	require.NoError(t, err)
+	require.True(t, read.CreatedAt.Equal(inserted.CreatedAt))
+	require.True(t, read.UpdatedAt.Equal(inserted.UpdatedAt))
+	require.True(t, read.LastUsedAt.Equal(inserted.LastUsedAt))
+	require.Equal(t, read.Name, inserted.Name)
+}
+
+func TestSoftDeleteUsersByOrg(t *testing.T) {
+	t.Parallel()
+
+	db, _ := dbtestutil.NewDB(t)
+	ctx := context.Background()
+
+	org, err := db.InsertOrganization(ctx, database.InsertOrganizationParams{
+		ID:        uuid.New(),
+		Name:      "org",
+		CreatedAt: database.Now(),
+		UpdatedAt: database.Now(),
+	})
+	require.NoError(t, err)
+	other, err := db.InsertOrganization(ctx, database.InsertOrganizationParams{
+		ID:        uuid.New(),
+		Name:      "other",
+		CreatedAt: database.Now(),
+		UpdatedAt: database.Now(),
+	})
+	require.NoError(t, err)
+	var (
+		members  []uuid.UUID
+		outsider uuid.UUID
+		shared   uuid.UUID
+	)
+	for _, name := range []string{"member1", "member2", "outsider", "shared"} {
+		user, err := db.InsertUser(ctx, database.InsertUserParams{
+			ID:             uuid.New(),
+			Email:          name + "@coder.com",
+			Username:       name,
+			HashedPassword: []byte{},
+			CreatedAt:      database.Now(),
+			UpdatedAt:      database.Now(),
+			RBACRoles:      []string{},
+			LoginType:      database.LoginTypePassword,
+		})
+		require.NoError(t, err)
+		if name == "outsider" {
+			outsider = user.ID
+			continue
+		}
+		_, err = db.InsertOrganizationMember(ctx, database.InsertOrganizationMemberParams{
+			OrganizationID: org.ID,
+			UserID:         user.ID,
+			CreatedAt:      database.Now(),
+			UpdatedAt:      database.Now(),
+			Roles:          []string{},
+		})
+		require.NoError(t, err)
+		if name == "shared" {
+			shared = user.ID
+			_, err = db.InsertOrganizationMember(ctx, database.InsertOrganizationMemberParams{
+				OrganizationID: other.ID,
+				UserID:         user.ID,
+				CreatedAt:      database.Now(),
+				UpdatedAt:      database.Now(),
+				Roles:          []string{},
+			})
+			require.NoError(t, err)
+			continue
+		}
+		members = append(members, user.ID)
+	}
+
+	var deleted []uuid.UUID
+	err = db.InTx(func(tx database.Store) error {
+		var err error
+		deleted, err = tx.SoftDeleteUsersByOrg(ctx, org.ID)
+		return err
+	})
+	require.NoError(t, err)
+	require.ElementsMatch(t, members, deleted)
+	for _, id := range members {
+		user, err := db.GetUserByID(ctx, id)
+		require.NoError(t, err)
+		require.True(t, user.Deleted)
+	}
+	user, err := db.GetUserByID(ctx, outsider)
+	require.NoError(t, err)
+	require.False(t, user.Deleted, "users outside the organization are kept")
+	user, err = db.GetUserByID(ctx, shared)
+	require.NoError(t, err)
+	require.False(t, user.Deleted, "users also in another organization are kept")
+
+	deleted, err = db.SoftDeleteUsersByOrg(ctx, org.ID)
+	require.NoError(t, err)
+	require.NotNil(t, deleted)
+	require.Empty(t, deleted, "already deleted users do not match")
+}
+
+func TestUpdateUserAudited(t *testing.T) {
+	t.Parallel()
+
+	db, _ := dbtestutil.NewDB(t)
+	ctx := context.Background()
+
+	inserted, err := db.InsertUser(ctx, database.InsertUserParams{
+		ID:             uuid.New(),
+		Email:          "before@coder.com",
+		Username:       "before",
+		HashedPassword: []byte("hash"),
+		CreatedAt:      database.Now(),
+		UpdatedAt:      database.Now(),
+		RBACRoles:      []string{},
+		LoginType:      database.LoginTypePassword,
+	})
+	require.NoError(t, err)
+
+	updatedAt := database.Now().Add(time.Minute)
+	before, after, err := db.UpdateUserAudited(ctx, database.UpdateUserProfileParams{
+		ID:        inserted.ID,
+		Email:     "after@coder.com",
+		Username:  "after",
+		AvatarURL: sql.NullString{String: "https://example.com/avatar.png", Valid: true},
+		UpdatedAt: updatedAt,
+	})
+	require.NoError(t, err)
+	require.Equal(t, inserted.ID, before.ID)
+	require.Equal(t, "before@coder.com", before.Email)
+	require.Equal(t, "before", before.Username)
+	require.False(t, before.AvatarURL.Valid)
+	require.True(t, before.UpdatedAt.Equal(inserted.UpdatedAt))
+	require.Equal(t, []byte("hash"), before.HashedPassword)
+
+	require.Equal(t, inserted.ID, after.ID)
+	require.Equal(t, "after@coder.com", after.Email)
+	require.Equal(t, "after", after.Username)
+	require.Equal(t, "https://example.com/avatar.png", after.AvatarURL.String)
+	require.True(t, after.UpdatedAt.Equal(updatedAt))
+	require.Equal(t, before.HashedPassword, after.HashedPassword, "untouched columns are unchanged")
+
+	read, err := db.GetUserByID(ctx, inserted.ID)
+	require.NoError(t, err)
+	require.Equal(t, after.Username, read.Username)
+
+	// A second update diffs against the first one's result.
+	before, _, err = db.UpdateUserAudited(ctx, database.UpdateUserProfileParams{
+		ID:        inserted.ID,
+		Email:     after.Email,
+		Username:  "again",
+		UpdatedAt: updatedAt,
+	})
+	require.NoError(t, err)
+	require.Equal(t, "after", before.Username)
+
+	_, _, err = db.UpdateUserAudited(ctx, database.UpdateUserProfileParams{ID: uuid.New()})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestGetTemplateByIDForShare(t *testing.T) {
+	t.Parallel()
+
+	t.Run("InTx", func(t *testing.T) {
+		t.Parallel()
+		db, _ := dbtestutil.NewDB(t)
+		_, _, template := insertTemplate(t, db)
+		err := db.InTx(func(tx database.Store) error {
+			got, err := tx.GetTemplateByIDForShare(context.Background(), template.ID)
+			require.NoError(t, err)
+			require.Equal(t, template.ID, got.ID)
+			return nil
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("SharedLock", func(t *testing.T) {
+		t.Parallel()
+		if os.Getenv("DB") == "" {
+			t.Skip("Row locks need a real database")
+		}
+		db, _ := dbtestutil.NewDB(t)
+		ctx := context.Background()
+		_, _, template := insertTemplate(t, db)
+
+		_, err := db.GetTemplateByIDForShare(ctx, template.ID)
+		require.Error(t, err, "outside a transaction")
+
+		// Both readers hold the lock at once, so neither waits for the
+		// other.
+		locked := make(chan struct{}, 2)
+		release := make(chan struct{})
+		var wg sync.WaitGroup
+		for i := 0; i < 2; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				err := db.InTxOpts(ctx, database.TxOptions{}, func(tx database.Store) error {
+					got, err := tx.GetTemplateByIDForShare(ctx, template.ID)
+					if err != nil {
+						return err
+					}
+					assert.Equal(t, template.ID, got.ID)
+					locked <- struct{}{}
+					<-release
+					return nil
+				})
+				assert.NoError(t, err)
+			}()
+		}
+		for i := 0; i < 2; i++ {
+			select {
+			case <-locked:
+			case <-time.After(10 * time.Second):
+				t.Fatal("readers did not share the lock")
+			}
+		}
+
+		update := func() error {
+			return db.InTxOpts(ctx, database.TxOptions{LockTimeout: 100 * time.Millisecond}, func(tx database.Store) error {
+				_, err := tx.UpdateTemplateIfVersion(ctx, template.ID, template.UpdatedAt, database.UpdateTemplateIfVersionParams{
+					Name: "renamed",
+				})
+				return err
+			})
+		}
+		require.ErrorIs(t, update(), database.ErrLockTimeout, "writers wait for readers")
+		close(release)
+		wg.Wait()
+		require.NoError(t, update())
+	})
+}
+
+func TestUpdateTemplateIfVersion(t *testing.T) {
+	t.Parallel()
+
+	db, _ := dbtestutil.NewDB(t)
+	ctx := context.Background()
+
+	_, _, template := insertTemplate(t, db)
+	read, err := db.GetTemplateByID(ctx, template.ID)
+	require.NoError(t, err)
+
+	// Both updaters read the same version, so only one may win.
+	var (
+		wg      sync.WaitGroup
+		results = make([]bool, 2)
+	)
+	for i := range results {
+		i := i
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			updated, err := db.UpdateTemplateIfVersion(ctx, read.ID, read.UpdatedAt, database.UpdateTemplateIfVersionParams{
+				Name: fmt.Sprintf("updater-%d", i),
+			})
+			assert.NoError(t, err)
+			results[i] = updated
+		}()
+	}
+	wg.Wait()
+	require.NotEqual(t, results[0], results[1], "exactly one updater succeeds")
+
+	winner := 0
+	if results[1] {
+		winner = 1
+	}
+	current, err := db.GetTemplateByID(ctx, template.ID)
+	require.NoError(t, err)
+	require.Equal(t, fmt.Sprintf("updater-%d", winner), current.Name)
+	require.True(t, current.UpdatedAt.After(read.UpdatedAt))
+
+	// The loser retries against the current version.
+	updated, err := db.UpdateTemplateIfVersion(ctx, current.ID, current.UpdatedAt, database.UpdateTemplateIfVersionParams{
+		Name: "retried",
+	})
+	require.NoError(t, err)
+	require.True(t, updated)
+
+	updated, err = db.UpdateTemplateIfVersion(ctx, uuid.New(), current.UpdatedAt, database.UpdateTemplateIfVersionParams{})
+	require.NoError(t, err)
+	require.False(t, updated, "missing template")
+}
+
+func TestUpdateTemplateIfMatch(t *testing.T) {
+	t.Parallel()
+
+	db, _ := dbtestutil.NewDB(t)
+	ctx := context.Background()
+
+	_, _, template := insertTemplate(t, db)
+	etag, err := db.GetTemplateETag(ctx, template.ID)
+	require.NoError(t, err)
+	again, err := db.GetTemplateETag(ctx, template.ID)
+	require.NoError(t, err)
+	require.Equal(t, etag, again, "the version is stable")
+
+	updated, err := db.UpdateTemplateIfMatch(ctx, template.ID, etag, database.UpdateTemplateIfVersionParams{
+		Name: "matched",
+	})
+	require.NoError(t, err)
+	require.Equal(t, "matched", updated.Name)
+	newETag, err := db.GetTemplateETag(ctx, template.ID)
+	require.NoError(t, err)
+	require.NotEqual(t, etag, newETag)
+
+	_, err = db.UpdateTemplateIfMatch(ctx, template.ID, etag, database.UpdateTemplateIfVersionParams{
+		Name: "stale",
+	})
+	require.ErrorIs(t, err, database.ErrPreconditionFailed)
+
+	// Changes that do not bump updated_at still change the version.
+	_, err = db.UpdateTemplateACLByID(ctx, database.UpdateTemplateACLByIDParams{
+		ID:       template.ID,
+		UserACL:  database.TemplateACL{uuid.NewString(): []rbac.Action{rbac.ActionRead}},
+		GroupACL: database.TemplateACL{},
+	})
+	require.NoError(t, err)
+	_, err = db.UpdateTemplateIfMatch(ctx, template.ID, newETag, database.UpdateTemplateIfVersionParams{
+		Name: "stale",
+	})
+	require.ErrorIs(t, err, database.ErrPreconditionFailed)
+
+	current, err := db.GetTemplateByID(ctx, template.ID)
+	require.NoError(t, err)
+	require.Equal(t, "matched", current.Name, "failed updates write nothing")
+
+	_, err = db.UpdateTemplateIfMatch(ctx, uuid.New(), etag, database.UpdateTemplateIfVersionParams{})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestGetWorkspacesModifiedSince(t *testing.T) {
+	t.Parallel()
+
+	db, _ := dbtestutil.NewDB(t)
+	ctx := context.Background()
+
+	user, org, template := insertTemplate(t, db)
+	// Several workspaces share a timestamp so pages split ties.
+	start := database.Now().Add(-time.Hour)
+	want := map[uuid.UUID]bool{}
+	for i := 0; i < 5; i++ {
+		workspace, err := db.InsertWorkspace(ctx, database.InsertWorkspaceParams{
+			ID:             uuid.New(),
+			CreatedAt:      start,
+			UpdatedAt:      start.Add(time.Duration(i/3) * time.Second),
+			OwnerID:        user.ID,
+			OrganizationID: org.ID,
+			TemplateID:     template.ID,
+			Name:           fmt.Sprintf("workspace-%d", i),
+		})
+		require.NoError(t, err)
+		want[workspace.ID] = true
+	}
+
+	seen := map[uuid.UUID]bool{}
+	cursor := database.GetWorkspacesModifiedSinceParams{
+		UpdatedAt: start.Add(-time.Second),
+		Limit:     2,
+	}
+	for pages := 0; ; pages++ {
+		require.Less(t, pages, 5, "pagination must terminate")
+		page, err := db.GetWorkspacesModifiedSince(ctx, cursor)
+		require.NoError(t, err)
+		if len(page) == 0 {
+			break
+		}
+		for _, workspace := range page {
+			require.False(t, seen[workspace.ID], "workspace returned twice")
+			seen[workspace.ID] = true
+		}
+		last := page[len(page)-1]
+		cursor.UpdatedAt = last.UpdatedAt
+		cursor.AfterID = last.ID
+	}
+	require.Equal(t, want, seen)
+}
+
+func TestPaginationTiebreaker(t *testing.T) {
+	t.Parallel()
+
+	db, _ := dbtestutil.NewDB(t)
+	ctx := context.Background()
+	const count = 5
+	// Every row shares the timestamp the list is ordered by, so only the ID
+	// tiebreaker keeps pages from overlapping.
+	now := database.Now()
+
+	user, org, template := insertTemplate(t, db)
+	for i := 0; i < count; i++ {
+		workspace, err := db.InsertWorkspace(ctx, database.InsertWorkspaceParams{
+			ID:             uuid.New(),
+			CreatedAt:      now,
+			UpdatedAt:      now,
+			OwnerID:        user.ID,
+			OrganizationID: org.ID,
+			TemplateID:     template.ID,
+			Name:           fmt.Sprintf("workspace-%d", i),
+		})
+		require.NoError(t, err)
+		err = db.UpdateWorkspaceLastUsedAt(ctx, database.UpdateWorkspaceLastUsedAtParams{
+			ID:         workspace.ID,
+			LastUsedAt: now,
+		})
+		require.NoError(t, err)
+
+		_, err = db.InsertAuditLog(ctx, database.InsertAuditLogParams{
+			ID:               uuid.New(),
+			Time:             now,
+			UserID:           user.ID,
+			OrganizationID:   org.ID,
+			Ip:               pqtype.Inet{IPNet: net.IPNet{IP: net.IPv4(127, 0, 0, 1), Mask: net.CIDRMask(32, 32)}, Valid: true},
+			ResourceType:     database.ResourceTypeWorkspace,
+			ResourceID:       workspace.ID,
+			Action:           database.AuditActionCreate,
+			Diff:             []byte("{}"),
+			AdditionalFields: []byte("{}"),
+			RequestID:        uuid.New(),
+		})
+		require.NoError(t, err)
+	}
+
+	seenWorkspaces := map[uuid.UUID]bool{}
+	seenLogs := map[uuid.UUID]bool{}
+	for offset := int32(0); offset < count; offset += 2 {
+		workspaces, err := db.GetWorkspaces(ctx, database.GetWorkspacesParams{Offset: offset, Limit: 2})
+		require.NoError(t, err)
+		for _, workspace := range workspaces {
+			require.False(t, seenWorkspaces[workspace.ID], "workspace returned on two pages")
+			seenWorkspaces[workspace.ID] = true
+		}
+
+		logs, err := db.GetAuditLogsOffset(ctx, database.GetAuditLogsOffsetParams{Offset: offset, Limit: 2})
+		require.NoError(t, err)
+		for _, log := range logs {
+			require.False(t, seenLogs[log.ID], "audit log returned on two pages")
+			seenLogs[log.ID] = true
+		}
+	}
+	require.Len(t, seenWorkspaces, count)
+	require.Len(t, seenLogs, count)
+}
+
+func TestGetWorkspacesNeverUsedLast(t *testing.T) {
+	t.Parallel()
+	if testing.Short() {
+		t.SkipNow()
+	}
+
+	sqlDB := testSQLDB(t)
+	err := migrations.Up(sqlDB)
+	require.NoError(t, err, "migrations")
+	db := database.New(sqlDB)
+	ctx := context.Background()
+
+	user, org, template := insertTemplate(t, db)
+	insertWorkspace := func(name string) database.Workspace {
+		workspace, err := db.InsertWorkspace(ctx, database.InsertWorkspaceParams{
+			ID:             uuid.New(),
+			CreatedAt:      database.Now(),
+			UpdatedAt:      database.Now(),
+			OwnerID:        user.ID,
+			OrganizationID: org.ID,
+			TemplateID:     template.ID,
+			Name:           name,
+		})
+		require.NoError(t, err)
+		return workspace
+	}
+	neverUsed := insertWorkspace("never-used")
+	used := insertWorkspace("used")
+	err = db.UpdateWorkspaceLastUsedAt(ctx, database.UpdateWorkspaceLastUsedAtParams{
+		ID:         used.ID,
+		LastUsedAt: database.Now(),
+	})
+	require.NoError(t, err)
+
+	workspaces, err := db.GetWorkspaces(ctx, database.GetWorkspacesParams{})
+	require.NoError(t, err)
+	require.Len(t, workspaces, 2)
+	require.Equal(t, used.ID, workspaces[0].ID)
+	require.Equal(t, neverUsed.ID, workspaces[1].ID)
+	require.True(t, workspaces[1].LastUsedAt.IsZero(), "never used workspaces hold the zero time")
+}
@@ -419,6 +419,25 @@ type AuditLog struct {
 	ResourceIcon     string          `db:"resource_icon" json:"resource_icon"`
 }
 
+// Audit logs moved out of audit_logs by retention. Columns must match audit_logs.
+type AuditLogsArchive struct {
+	ID               uuid.UUID       `db:"id" json:"id"`
+	Time             time.Time       `db:"time" json:"time"`
+	UserID           uuid.UUID       `db:"user_id" json:"user_id"`
+	OrganizationID   uuid.UUID       `db:"organization_id" json:"organization_id"`
+	Ip               pqtype.Inet     `db:"ip" json:"ip"`
+	UserAgent        string          `db:"user_agent" json:"user_agent"`
+	ResourceType     ResourceType    `db:"resource_type" json:"resource_type"`
+	ResourceID       uuid.UUID       `db:"resource_id" json:"resource_id"`
+	ResourceTarget   string          `db:"resource_target" json:"resource_target"`
+	Action           AuditAction     `db:"action" json:"action"`
+	Diff             json.RawMessage `db:"diff" json:"diff"`
+	StatusCode       int32           `db:"status_code" json:"status_code"`
+	AdditionalFields json.RawMessage `db:"additional_fields" json:"additional_fields"`
+	RequestID        uuid.UUID       `db:"request_id" json:"request_id"`
+	ResourceIcon     string          `db:"resource_icon" json:"resource_icon"`
+}
+
 type File struct {
 	Hash      string    `db:"hash" json:"hash"`
 	CreatedAt time.Time `db:"created_at" json:"created_at"`
@@ -448,6 +467,13 @@ type GroupMember struct {
 	GroupID uuid.UUID `db:"group_id" json:"group_id"`
 }
 
+// Named leases held by one owner until they expire, used for coordinating work across replicas.
+type Lease struct {
+	Name      string    `db:"name" json:"name"`
+	OwnerID   uuid.UUID `db:"owner_id" json:"owner_id"`
+	ExpiresAt time.Time `db:"expires_at" json:"expires_at"`
+}
+
 type License struct {
 	ID         int32     `db:"id" json:"id"`
 	UploadedAt time.Time `db:"uploaded_at" json:"uploaded_at"`
@@ -472,6 +498,15 @@ type OrganizationMember struct {
 	Roles          []string  `db:"roles" json:"roles"`
 }
 
+// Events written in the transaction that caused them, for a relay to publish to a broker once it commits.
+type OutboxEvent struct {
+	ID          uuid.UUID       `db:"id" json:"id"`
+	Topic       string          `db:"topic" json:"topic"`
+	Payload     json.RawMessage `db:"payload" json:"payload"`
+	CreatedAt   time.Time       `db:"created_at" json:"created_at"`
+	PublishedAt sql.NullTime    `db:"published_at" json:"published_at"`
+}
+
 type ParameterSchema struct {
 	ID                       uuid.UUID                  `db:"id" json:"id"`
 	CreatedAt                time.Time                  `db:"created_at" json:"created_at"`
@@ -555,6 +590,13 @@ type Replica struct {
 	Error           string       `db:"error" json:"error"`
 }
 
+// Counters split across rows so concurrent increments do not contend for one row lock. A counter's value is the sum of its shards.
+type ShardedCounter struct {
+	Name  string `db:"name" json:"name"`
+	Shard int32  `db:"shard" json:"shard"`
+	Value int64  `db:"value" json:"value"`
+}
+
 type SiteConfig struct {
 	Key   string `db:"key" json:"key"`
 	Value string `db:"value" json:"value"`
@@ -626,6 +668,8 @@ type Workspace struct {
 	AutostartSchedule sql.NullString `db:"autostart_schedule" json:"autostart_schedule"`
 	Ttl               sql.NullInt64  `db:"ttl" json:"ttl"`
 	LastUsedAt        time.Time      `db:"last_used_at" json:"last_used_at"`
+	// dormant_at is when the workspace was marked inactive for disuse, or NULL if it is active. Dormant workspaces are not autostarted, and using the workspace clears it.
+	DormantAt sql.NullTime `db:"dormant_at" json:"dormant_at"`
 }
 
 type WorkspaceAgent struct {
@@ -681,6 +725,13 @@ type WorkspaceBuild struct {
 	Reason            BuildReason         `db:"reason" json:"reason"`
 }
 
+// Client-supplied keys that make workspace creation safe to retry. The key is claimed before the workspace is inserted, so the foreign key is deferred.
+type WorkspaceIdempotencyKey struct {
+	IdempotencyKey string    `db:"idempotency_key" json:"idempotency_key"`
+	WorkspaceID    uuid.UUID `db:"workspace_id" json:"workspace_id"`
+	CreatedAt      time.Time `db:"created_at" json:"created_at"`
+}
+
 type WorkspaceResource struct {
 	ID         uuid.UUID           `db:"id" json:"id"`
 	CreatedAt  time.Time           `db:"created_at" json:"created_at"`
@@ -0,0 +1,47 @@
+package database
+
+import (
+	"context"
+
+	"golang.org/x/xerrors"
+)
+
+// maxNotifyPayloadLength is the payload limit for NOTIFY in the default
+// Postgres configuration.
+const maxNotifyPayloadLength = 8000
+
+type notifyQuerier interface {
+	// Notify sends payload on channel with pg_notify. Inside a transaction,
+	// Postgres only delivers the notification when the transaction commits,
+	// so listeners never see events for rolled-back changes.
+	Notify(ctx context.Context, channel, payload string) error
+}
+
+func (q *sqlQuerier) Notify(ctx context.Context, channel, payload string) error {
+	const query = `-- name: Notify :exec
+	SELECT pg_notify($1, $2)
+	`
+
+	err := validateNotify(channel, payload)
+	if err != nil {
+		return err
+	}
+	_, err = q.db.ExecContext(ctx, query, channel, payload)
+	if err != nil {
+		return xerrors.Errorf("exec pg_notify: %w", err)
+	}
+	return nil
+}
+
+func validateNotify(channel, payload string) error {
+	if channel == "" {
+		return xerrors.New("notify channel must not be empty")
+	}
+	if len(channel) > maxIdentifierLength {
+		return xerrors.Errorf("notify channel %q is longer than %d bytes", channel, maxIdentifierLength)
+	}
+	if len(payload) >= maxNotifyPayloadLength {
+		return xerrors.Errorf("notify payload is %d bytes, must be less than %d", len(payload), maxNotifyPayloadLength)
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+//go:build linux
+
+package database_test
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/xerrors"
+
+	"github.com/coder/coder/coderd/database"
+	"github.com/coder/coder/testutil"
+)
+
+func TestNotify(t *testing.T) {
+	t.Parallel()
+	if testing.Short() {
+		t.SkipNow()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), testutil.WaitLong)
+	defer cancel()
+
+	dsn := testDSN(t)
+	sqlDB, err := sql.Open("postgres", dsn)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	pubsub, err := database.NewPubsub(ctx, sqlDB, dsn)
+	require.NoError(t, err)
+	defer pubsub.Close()
+
+	messages := make(chan string, 10)
+	unsubscribe, err := pubsub.Subscribe("test", func(_ context.Context, message []byte) {
+		messages <- string(message)
+	})
+	require.NoError(t, err)
+	defer unsubscribe()
+
+	db := database.New(sqlDB)
+	errRollback := xerrors.New("rollback")
+	err = db.InTx(func(tx database.Store) error {
+		err := tx.Notify(ctx, "test", "rolled back")
+		require.NoError(t, err)
+		return errRollback
+	})
+	require.ErrorIs(t, err, errRollback)
+	err = db.InTx(func(tx database.Store) error {
+		return tx.Notify(ctx, "test", "committed")
+	})
+	require.NoError(t, err)
+
+	select {
+	case message := <-messages:
+		require.Equal(t, "committed", message)
+	case <-ctx.Done():
+		t.Fatal("timed out waiting for notification")
+	}
+	select {
+	case message := <-messages:
+		t.Fatalf("unexpected notification %q", message)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	err = db.Notify(ctx, "", "payload")
+	require.Error(t, err, "empty channel")
+	err = db.Notify(ctx, strings.Repeat("a", 64), "payload")
+	require.Error(t, err, "channel too long")
+	err = db.Notify(ctx, "test", strings.Repeat("a", 8000))
+	require.Error(t, err, "payload too large")
+}
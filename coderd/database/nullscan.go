@@ -0,0 +1,51 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+)
+
+// nullScanPattern matches database/sql scan errors caused by a NULL value
+// being scanned into a non-nullable destination, capturing the column name.
+var nullScanPattern = regexp.MustCompile(`Scan error on column index \d+, name "([^"]+)": (converting NULL to|unsupported Scan, storing driver\.Value type <nil>)`)
+
+// NullScanError describes a column that unexpectedly returned NULL for a
+// non-nullable model field, which usually indicates schema drift or a bad
+// migration.
+type NullScanError struct {
+	Method string
+	Column string
+	Err    error
+}
+
+func (e *NullScanError) Error() string {
+	return fmt.Sprintf("%s: column %q returned NULL into a non-nullable field (possible schema drift): %s", e.Method, e.Column, e.Err)
+}
+
+func (e *NullScanError) Unwrap() error {
+	return e.Err
+}
+
+// DiagnoseNullScans is an Interceptor that enriches NULL scan errors with
+// the method name and offending column as a *NullScanError.
+func DiagnoseNullScans(ctx context.Context, call Call, next Invoker) ([]interface{}, error) {
+	res, err := next(ctx)
+	if err == nil {
+		return res, nil
+	}
+	var nullErr *NullScanError
+	if errors.As(err, &nullErr) {
+		return res, err
+	}
+	match := nullScanPattern.FindStringSubmatch(err.Error())
+	if match == nil {
+		return res, err
+	}
+	return res, &NullScanError{
+		Method: call.Method,
+		Column: match[1],
+		Err:    err,
+	}
+}
@@ -0,0 +1,36 @@
+package database_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/coder/coder/coderd/database"
+)
+
+func TestDiagnoseNullScans(t *testing.T) {
+	t.Parallel()
+
+	sqlDB, connector := newRecordingDB()
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	connector.rows = func(string) ([]string, [][]driver.Value) {
+		columns := []string{"id", "email", "username", "hashed_password", "created_at", "updated_at", "status", "rbac_roles", "login_type", "avatar_url", "deleted", "last_seen_at"}
+		// The email column is NULL, as if a migration dropped its NOT NULL.
+		row := []driver.Value{uuid.NewString(), nil, "coder", []byte{}, time.Now(), time.Now(), "active", "{}", "password", nil, false, time.Now()}
+		return columns, [][]driver.Value{row}
+	}
+	db := database.Intercept(database.New(sqlDB), database.DiagnoseNullScans)
+
+	_, err := db.GetUserByID(context.Background(), uuid.New())
+	require.Error(t, err)
+	var nullErr *database.NullScanError
+	require.True(t, errors.As(err, &nullErr), "expected NullScanError, got %v", err)
+	require.Equal(t, "GetUserByID", nullErr.Method)
+	require.Equal(t, "email", nullErr.Column)
+	require.Contains(t, err.Error(), `GetUserByID: column "email" returned NULL`)
+}
@@ -0,0 +1,82 @@
+package database_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/coder/coder/coderd/database"
+)
+
+func TestOpen(t *testing.T) {
+	t.Parallel()
+
+	t.Run("RetriesExhausted", func(t *testing.T) {
+		t.Parallel()
+
+		// Nothing listens on port 1, so every attempt is refused.
+		_, _, err := database.Open(context.Background(), "postgres://postgres@127.0.0.1:1/postgres?sslmode=disable",
+			database.WithConnectRetry(3, time.Millisecond))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "connect after 3 attempt(s)")
+	})
+
+	t.Run("Canceled", func(t *testing.T) {
+		t.Parallel()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		_, _, err := database.Open(ctx, "postgres://postgres@127.0.0.1:1/postgres?sslmode=disable",
+			database.WithConnectRetry(100, time.Second))
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("Eager", func(t *testing.T) {
+		t.Parallel()
+
+		_, _, err := database.Open(context.Background(), "postgres://postgres@127.0.0.1:1/postgres?sslmode=disable",
+			database.WithEagerConnect(true))
+		require.ErrorIs(t, err, database.ErrConnectFailed, "boot fails fast")
+	})
+
+	t.Run("Lazy", func(t *testing.T) {
+		t.Parallel()
+
+		db, sqlDB, err := database.Open(context.Background(), "postgres://postgres@127.0.0.1:1/postgres?sslmode=disable",
+			database.WithEagerConnect(false), database.WithConnectRetry(100, time.Second))
+		require.NoError(t, err, "nothing connects yet")
+		t.Cleanup(func() { _ = sqlDB.Close() })
+		require.Zero(t, sqlDB.Stats().OpenConnections)
+
+		_, err = db.Ping(context.Background())
+		require.ErrorIs(t, err, database.ErrConnectFailed, "readiness is gated on the first connection")
+	})
+}
+
+func TestWithDriverName(t *testing.T) {
+	t.Parallel()
+
+	sqlDB, connector := newRecordingDB()
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	db := database.New(sqlDB, database.WithDriverName("pgx"))
+	require.Equal(t, "pgx", db.Unwrap().DriverName())
+	require.Equal(t, "$1", db.Unwrap().Rebind("?"), "pgx binds like postgres")
+	require.Equal(t, "postgres", database.New(sqlDB).Unwrap().DriverName(), "default")
+
+	err := db.DeleteAPIKeyByID(context.Background(), "key")
+	require.NoError(t, err)
+	require.Len(t, connector.Queries(), 1)
+}
+
+func TestWithTimezone(t *testing.T) {
+	t.Parallel()
+
+	for _, name := range []string{"Not/AZone", "Local"} {
+		// Open takes session settings alongside any Option.
+		_, _, err := database.Open(context.Background(), "postgres://postgres@127.0.0.1:1/postgres?sslmode=disable",
+			database.WithEagerConnect(false), database.WithTimezone(name))
+		require.ErrorContains(t, err, "invalid timezone")
+	}
+}
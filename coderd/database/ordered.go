@@ -0,0 +1,90 @@
+package database
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"golang.org/x/xerrors"
+)
+
+// orderedQuerier fetches rows by ID with the result aligned to the input:
+// element i of the result is the row for ids[i]. IDs without a row leave a
+// gap holding the zero value, which callers can detect by its uuid.Nil ID.
+// Duplicate IDs repeat the row. Missing IDs are never an error; the
+// MustFindAll variants return ErrMissingIDs instead of leaving gaps, for
+// callers that require every row to exist.
+type orderedQuerier interface {
+	GetUsersByIDsOrdered(ctx context.Context, ids []uuid.UUID) ([]User, error)
+	GetUsersByIDsMustFindAll(ctx context.Context, ids []uuid.UUID) ([]User, error)
+	GetProvisionerJobsByIDsOrdered(ctx context.Context, ids []uuid.UUID) ([]ProvisionerJob, error)
+	GetProvisionerJobsByIDsMustFindAll(ctx context.Context, ids []uuid.UUID) ([]ProvisionerJob, error)
+}
+
+func (q *sqlQuerier) GetUsersByIDsOrdered(ctx context.Context, ids []uuid.UUID) ([]User, error) {
+	users, err := q.GetUsersByIDs(ctx, ids)
+	if err != nil {
+		return nil, xerrors.Errorf("get users: %w", err)
+	}
+	return alignByID(ids, users, func(u User) uuid.UUID { return u.ID }), nil
+}
+
+func (q *sqlQuerier) GetUsersByIDsMustFindAll(ctx context.Context, ids []uuid.UUID) ([]User, error) {
+	users, err := q.GetUsersByIDsOrdered(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	return users, MissingIDs(ids, users, func(u User) uuid.UUID { return u.ID })
+}
+
+func (q *sqlQuerier) GetProvisionerJobsByIDsOrdered(ctx context.Context, ids []uuid.UUID) ([]ProvisionerJob, error) {
+	jobs, err := q.GetProvisionerJobsByIDs(ctx, ids)
+	if err != nil {
+		return nil, xerrors.Errorf("get provisioner jobs: %w", err)
+	}
+	return alignByID(ids, jobs, func(j ProvisionerJob) uuid.UUID { return j.ID }), nil
+}
+
+func (q *sqlQuerier) GetProvisionerJobsByIDsMustFindAll(ctx context.Context, ids []uuid.UUID) ([]ProvisionerJob, error) {
+	jobs, err := q.GetProvisionerJobsByIDsOrdered(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	return jobs, MissingIDs(ids, jobs, func(j ProvisionerJob) uuid.UUID { return j.ID })
+}
+
+// alignByID reorders rows so element i is the row whose ID is ids[i], or
+// the zero value if there is none.
+func alignByID[T any](ids []uuid.UUID, rows []T, id func(T) uuid.UUID) []T {
+	byID := make(map[uuid.UUID]T, len(rows))
+	for _, row := range rows {
+		byID[id(row)] = row
+	}
+	aligned := make([]T, len(ids))
+	for i, want := range ids {
+		aligned[i] = byID[want]
+	}
+	return aligned
+}
+
+// MissingIDs returns ErrMissingIDs listing the IDs that have no row in
+// aligned, the output of an ordered batch read, or nil if every ID was
+// found. It is exported so Store implementations outside this package
+// report missing rows the same way.
+func MissingIDs[T any](ids []uuid.UUID, aligned []T, id func(T) uuid.UUID) error {
+	var missing []uuid.UUID
+	seen := make(map[uuid.UUID]struct{})
+	for i, row := range aligned {
+		if id(row) != uuid.Nil {
+			continue
+		}
+		if _, ok := seen[ids[i]]; ok {
+			continue
+		}
+		seen[ids[i]] = struct{}{}
+		missing = append(missing, ids[i])
+	}
+	if len(missing) > 0 {
+		return ErrMissingIDs{IDs: missing}
+	}
+	return nil
+}
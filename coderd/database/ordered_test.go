@@ -0,0 +1,109 @@
+package database_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/coder/coder/coderd/database"
+	"github.com/coder/coder/coderd/database/databasefake"
+)
+
+func TestGetUsersByIDsOrdered(t *testing.T) {
+	t.Parallel()
+
+	first, second, missing := uuid.New(), uuid.New(), uuid.New()
+	ids := []uuid.UUID{second, missing, first, second}
+	requireAligned := func(t *testing.T, users []database.User) {
+		t.Helper()
+		require.Len(t, users, len(ids))
+		require.Equal(t, second, users[0].ID)
+		require.Equal(t, uuid.Nil, users[1].ID, "missing IDs leave a gap")
+		require.Equal(t, first, users[2].ID)
+		require.Equal(t, second, users[3].ID, "duplicates repeat the row")
+	}
+
+	t.Run("SQL", func(t *testing.T) {
+		t.Parallel()
+
+		sqlDB, connector := newRecordingDB()
+		t.Cleanup(func() { _ = sqlDB.Close() })
+		connector.rows = func(string) ([]string, [][]driver.Value) {
+			columns := []string{"id", "email", "username", "hashed_password", "created_at", "updated_at", "status", "rbac_roles", "login_type", "avatar_url", "deleted", "last_seen_at"}
+			row := func(id uuid.UUID) []driver.Value {
+				return []driver.Value{id.String(), id.String() + "@coder.com", id.String(), []byte{}, time.Now(), time.Now(), "active", "{}", "password", nil, false, time.Now()}
+			}
+			// Rows come back in storage order, not input order.
+			return columns, [][]driver.Value{row(first), row(second)}
+		}
+		users, err := database.New(sqlDB).GetUsersByIDsOrdered(context.Background(), ids)
+		require.NoError(t, err)
+		requireAligned(t, users)
+	})
+
+	t.Run("Fake", func(t *testing.T) {
+		t.Parallel()
+
+		db := databasefake.New()
+		for _, id := range []uuid.UUID{first, second} {
+			_, err := db.InsertUser(context.Background(), database.InsertUserParams{
+				ID:        id,
+				Email:     id.String() + "@coder.com",
+				Username:  id.String(),
+				RBACRoles: []string{},
+			})
+			require.NoError(t, err)
+		}
+		users, err := db.GetUsersByIDsOrdered(context.Background(), ids)
+		require.NoError(t, err)
+		requireAligned(t, users)
+	})
+}
+
+func TestMustFindAll(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Users", func(t *testing.T) {
+		t.Parallel()
+
+		db := databasefake.New()
+		found, missing := uuid.New(), uuid.New()
+		_, err := db.InsertUser(context.Background(), database.InsertUserParams{
+			ID:        found,
+			Email:     "found@coder.com",
+			Username:  "found",
+			RBACRoles: []string{},
+		})
+		require.NoError(t, err)
+
+		users, err := db.GetUsersByIDsMustFindAll(context.Background(), []uuid.UUID{found})
+		require.NoError(t, err)
+		require.Len(t, users, 1)
+
+		users, err = db.GetUsersByIDsMustFindAll(context.Background(), []uuid.UUID{missing, found, missing})
+		var missingErr database.ErrMissingIDs
+		require.ErrorAs(t, err, &missingErr)
+		require.Equal(t, []uuid.UUID{missing}, missingErr.IDs, "missing IDs are listed once")
+		require.Equal(t, found, users[1].ID, "found rows are still returned")
+	})
+
+	t.Run("ProvisionerJobs", func(t *testing.T) {
+		t.Parallel()
+
+		// Nothing matching is not sql.ErrNoRows, only missing IDs.
+		db := databasefake.New()
+		missing := uuid.New()
+		jobs, err := db.GetProvisionerJobsByIDsOrdered(context.Background(), []uuid.UUID{missing})
+		require.NoError(t, err)
+		require.Len(t, jobs, 1)
+
+		_, err = db.GetProvisionerJobsByIDsMustFindAll(context.Background(), []uuid.UUID{missing})
+		var missingErr database.ErrMissingIDs
+		require.ErrorAs(t, err, &missingErr)
+		require.Equal(t, []uuid.UUID{missing}, missingErr.IDs)
+	})
+}
@@ -0,0 +1,93 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/google/uuid"
+	"golang.org/x/xerrors"
+)
+
+// outboxQuerier implements the transactional outbox: events are written in
+// the transaction whose changes they announce, so they exist if and only
+// if it commits, and a relay publishes them to a broker afterwards.
+type outboxQuerier interface {
+	// EnqueueOutbox appends an event to the outbox. Call it on the Store
+	// of the transaction making the change the event announces, so the
+	// event is rolled back with it.
+	EnqueueOutbox(ctx context.Context, topic string, payload json.RawMessage) (OutboxEvent, error)
+	// DequeueOutbox marks up to limit unpublished events as published and
+	// returns them, oldest first. Events locked by a concurrent dequeue
+	// are skipped rather than waited for, so relay workers get disjoint
+	// batches. Call it in a transaction that publishes the events before
+	// committing: if publishing fails, returning an error rolls the
+	// events back to be dequeued again, so each is published at least
+	// once. Outside a transaction, events are marked as published as soon
+	// as they are returned.
+	DequeueOutbox(ctx context.Context, limit int32) ([]OutboxEvent, error)
+}
+
+func (q *sqlQuerier) EnqueueOutbox(ctx context.Context, topic string, payload json.RawMessage) (OutboxEvent, error) {
+	if topic == "" {
+		return OutboxEvent{}, xerrors.New("outbox topic must not be empty")
+	}
+	const query = `-- name: EnqueueOutbox :one
+	INSERT INTO
+		outbox (id, topic, payload, created_at)
+	VALUES
+		($1, $2, $3, now())
+	RETURNING
+		*
+	`
+
+	var event OutboxEvent
+	err := q.db.GetContext(ctx, &event, query, uuid.New(), topic, payload)
+	if err != nil {
+		return OutboxEvent{}, xerrors.Errorf("enqueue outbox: %w", err)
+	}
+	return event, nil
+}
+
+func (q *sqlQuerier) DequeueOutbox(ctx context.Context, limit int32) ([]OutboxEvent, error) {
+	// As in ClaimPendingBuilds, SKIP LOCKED jumps over events another
+	// relay is publishing, and the lock and the update are one statement.
+	const query = `-- name: DequeueOutbox :many
+	WITH next AS (
+		SELECT
+			id
+		FROM
+			outbox
+		WHERE
+			published_at IS NULL
+		ORDER BY
+			created_at, id
+		LIMIT
+			$1
+		FOR UPDATE SKIP LOCKED
+	), published AS (
+		UPDATE
+			outbox
+		SET
+			published_at = now()
+		FROM
+			next
+		WHERE
+			outbox.id = next.id
+		RETURNING
+			outbox.*
+	)
+	SELECT
+		*
+	FROM
+		published
+	ORDER BY
+		created_at, id
+	`
+
+	events := []OutboxEvent{}
+	err := q.db.SelectContext(ctx, &events, query, limit)
+	if err != nil {
+		return nil, xerrors.Errorf("dequeue outbox: %w", err)
+	}
+	return events, nil
+}
@@ -0,0 +1,119 @@
+package database_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/xerrors"
+
+	"github.com/coder/coder/coderd/database"
+	"github.com/coder/coder/coderd/database/dbtestutil"
+)
+
+func TestOutbox(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Dequeue", func(t *testing.T) {
+		t.Parallel()
+		db, _ := dbtestutil.NewDB(t)
+		ctx := context.Background()
+
+		_, err := db.EnqueueOutbox(ctx, "", json.RawMessage(`{}`))
+		require.Error(t, err, "empty topic")
+		first, err := db.EnqueueOutbox(ctx, "workspace.created", json.RawMessage(`{"n":1}`))
+		require.NoError(t, err)
+		second, err := db.EnqueueOutbox(ctx, "workspace.created", json.RawMessage(`{"n":2}`))
+		require.NoError(t, err)
+
+		events, err := db.DequeueOutbox(ctx, 1)
+		require.NoError(t, err)
+		require.Len(t, events, 1)
+		require.Equal(t, first.ID, events[0].ID)
+		require.True(t, events[0].PublishedAt.Valid)
+		events, err = db.DequeueOutbox(ctx, 10)
+		require.NoError(t, err)
+		require.Len(t, events, 1)
+		require.Equal(t, second.ID, events[0].ID)
+		events, err = db.DequeueOutbox(ctx, 10)
+		require.NoError(t, err)
+		require.Empty(t, events)
+	})
+
+	t.Run("Relays", func(t *testing.T) {
+		t.Parallel()
+		if os.Getenv("DB") == "" {
+			t.Skip("Rollbacks and concurrent relays need a real database")
+		}
+		db, _ := dbtestutil.NewDB(t)
+		ctx := context.Background()
+		errRollback := xerrors.New("rollback")
+
+		err := db.InTx(func(tx database.Store) error {
+			_, err := tx.EnqueueOutbox(ctx, "workspace.created", json.RawMessage(`{}`))
+			require.NoError(t, err)
+			return errRollback
+		})
+		require.ErrorIs(t, err, errRollback)
+		events, err := db.DequeueOutbox(ctx, 10)
+		require.NoError(t, err)
+		require.Empty(t, events, "a rolled back transaction leaves no event")
+
+		const total = 20
+		enqueued := map[uuid.UUID]bool{}
+		for i := 0; i < total; i++ {
+			event, err := db.EnqueueOutbox(ctx, "workspace.created", json.RawMessage(fmt.Sprintf(`{"n":%d}`, i)))
+			require.NoError(t, err)
+			enqueued[event.ID] = true
+		}
+
+		// A failed publish rolls its batch back for another relay.
+		err = db.InTx(func(tx database.Store) error {
+			events, err := tx.DequeueOutbox(ctx, 5)
+			require.NoError(t, err)
+			require.Len(t, events, 5)
+			return errRollback
+		})
+		require.ErrorIs(t, err, errRollback)
+
+		var (
+			wg        sync.WaitGroup
+			mu        sync.Mutex
+			published = map[uuid.UUID]int{}
+		)
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for {
+					var batch []database.OutboxEvent
+					err := db.InTx(func(tx database.Store) error {
+						var err error
+						batch, err = tx.DequeueOutbox(ctx, 3)
+						return err
+					})
+					if !assert.NoError(t, err) || len(batch) == 0 {
+						return
+					}
+					mu.Lock()
+					for _, event := range batch {
+						published[event.ID]++
+					}
+					mu.Unlock()
+				}
+			}()
+		}
+		wg.Wait()
+		require.Len(t, published, total)
+		for id, count := range published {
+			require.True(t, enqueued[id])
+			require.Equal(t, 1, count, "each event is dequeued by one relay")
+		}
+	})
+}
@@ -0,0 +1,103 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"sync/atomic"
+)
+
+// WithSecondaryPool runs the named query methods on db instead of the
+// primary pool, so expensive queries such as reports cannot take every
+// connection from latency-sensitive ones. Only calls made outside a
+// transaction are routed: transactions, WithConn and everything inside them
+// always use the primary pool. Single calls can be routed either way with
+// WithQueryTag. db keeps its own pool settings; New does not resize it.
+func WithSecondaryPool(db *sql.DB, methods ...string) Option {
+	return func(o *options) {
+		o.secondaryPool = db
+		o.secondaryMethods = make(map[string]bool, len(methods))
+		for _, method := range methods {
+			o.secondaryMethods[method] = true
+		}
+	}
+}
+
+// routingDB sends each query to the secondary pool if its method is listed
+// or it is tagged with QueryTagAnalyticsPool, and to the primary pool
+// otherwise or if it is tagged with QueryTagForcePrimary. Queries with a
+// minimum LSN only go to the secondary pool once it has replayed that far.
+type routingDB struct {
+	DBTX
+	secondary DBTX
+	methods   map[string]bool
+	// replayed is the highest LSN the secondary pool was seen to have
+	// replayed. It only grows, so it is a lower bound on the current one.
+	replayed atomic.Uint64
+}
+
+func (r *routingDB) route(ctx context.Context, query string) DBTX {
+	if hasQueryTag(ctx, QueryTagForcePrimary) {
+		return r.DBTX
+	}
+	if !r.methods[queryMethod(query)] && !hasQueryTag(ctx, QueryTagAnalyticsPool) {
+		return r.DBTX
+	}
+	if minLSN, ok := MinLSNFromContext(ctx); ok && !r.caughtUp(ctx, minLSN) {
+		return r.DBTX
+	}
+	return r.secondary
+}
+
+// caughtUp reports whether the secondary pool has replayed minLSN. The
+// pool is only asked when the last answer was not enough.
+func (r *routingDB) caughtUp(ctx context.Context, minLSN LSN) bool {
+	if LSN(r.replayed.Load()) >= minLSN {
+		return true
+	}
+	// A secondary pool on the primary itself has no replay position; it
+	// is always caught up.
+	const query = `-- name: ReplayLSN :one
+	SELECT
+		CASE WHEN pg_is_in_recovery() THEN pg_last_wal_replay_lsn() ELSE pg_current_wal_lsn() END::text
+	`
+	var text sql.NullString
+	err := r.secondary.GetContext(ctx, &text, query)
+	if err != nil || !text.Valid {
+		return false
+	}
+	lsn, err := ParseLSN(text.String)
+	if err != nil {
+		return false
+	}
+	for {
+		seen := r.replayed.Load()
+		if uint64(lsn) <= seen || r.replayed.CompareAndSwap(seen, uint64(lsn)) {
+			break
+		}
+	}
+	return lsn >= minLSN
+}
+
+func (r *routingDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return r.route(ctx, query).ExecContext(ctx, query, args...)
+}
+
+func (r *routingDB) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return r.route(ctx, query).PrepareContext(ctx, query)
+}
+
+func (r *routingDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return r.route(ctx, query).QueryContext(ctx, query, args...)
+}
+
+func (r *routingDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return r.route(ctx, query).QueryRowContext(ctx, query, args...)
+}
+
+func (r *routingDB) SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	return r.route(ctx, query).SelectContext(ctx, dest, query, args...)
+}
+
+func (r *routingDB) GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	return r.route(ctx, query).GetContext(ctx, dest, query, args...)
+}
@@ -0,0 +1,140 @@
+package database_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/coder/coder/coderd/database"
+)
+
+func TestWithSecondaryPool(t *testing.T) {
+	t.Parallel()
+
+	primaryDB, primary := newRecordingDB()
+	t.Cleanup(func() { _ = primaryDB.Close() })
+	secondaryDB, secondary := newRecordingDB()
+	t.Cleanup(func() { _ = secondaryDB.Close() })
+	db := database.New(primaryDB, database.WithSecondaryPool(secondaryDB, "GetAPIKeyByID"))
+	ctx := context.Background()
+
+	_, err := db.GetAPIKeyByID(ctx, "a")
+	require.ErrorIs(t, err, sql.ErrNoRows)
+	require.NoError(t, db.DeleteAPIKeyByID(ctx, "a"))
+	require.Len(t, secondary.Queries(), 1, "listed method uses the secondary pool")
+	require.Contains(t, secondary.Queries()[0], "GetAPIKeyByID")
+	require.Len(t, primary.Queries(), 1, "other methods use the primary pool")
+
+	err = db.InTx(func(tx database.Store) error {
+		_, err := tx.GetAPIKeyByID(ctx, "b")
+		require.ErrorIs(t, err, sql.ErrNoRows)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Len(t, secondary.Queries(), 1)
+	require.Len(t, primary.Queries(), 2, "transactions stay on the primary pool")
+}
+
+func TestWithMinLSN(t *testing.T) {
+	t.Parallel()
+
+	primaryDB, primary := newRecordingDB()
+	t.Cleanup(func() { _ = primaryDB.Close() })
+	replicaDB, replica := newRecordingDB()
+	t.Cleanup(func() { _ = replicaDB.Close() })
+	var replayed atomic.Value
+	replayed.Store("0/100")
+	replica.rows = func(query string) ([]string, [][]driver.Value) {
+		if strings.Contains(query, "ReplayLSN") {
+			return []string{"lsn"}, [][]driver.Value{{replayed.Load()}}
+		}
+		return nil, nil
+	}
+	primary.rows = func(query string) ([]string, [][]driver.Value) {
+		if strings.Contains(query, "GetCurrentLSN") {
+			return []string{"lsn"}, [][]driver.Value{{"0/200"}}
+		}
+		return nil, nil
+	}
+	db := database.New(primaryDB, database.WithSecondaryPool(replicaDB, "GetAPIKeyByID"))
+	ctx := context.Background()
+	countReads := func(c *recordingConnector) int {
+		n := 0
+		for _, query := range c.Queries() {
+			if strings.Contains(query, "GetAPIKeyByID") {
+				n++
+			}
+		}
+		return n
+	}
+
+	token, err := db.GetCurrentLSN(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "0/200", token.String())
+	consistent, ok := database.MinLSNFromContext(database.WithMinLSN(ctx, token))
+	require.True(t, ok)
+	require.Equal(t, token, consistent)
+
+	_, err = db.GetAPIKeyByID(ctx, "a")
+	require.ErrorIs(t, err, sql.ErrNoRows)
+	require.Equal(t, 1, countReads(replica), "reads without a token use the replica")
+
+	_, err = db.GetAPIKeyByID(database.WithMinLSN(ctx, token), "a")
+	require.ErrorIs(t, err, sql.ErrNoRows)
+	require.Equal(t, 1, countReads(primary), "a lagging replica falls back to the primary")
+	require.Equal(t, 1, countReads(replica))
+
+	replayed.Store("0/250")
+	_, err = db.GetAPIKeyByID(database.WithMinLSN(ctx, token), "a")
+	require.ErrorIs(t, err, sql.ErrNoRows)
+	require.Equal(t, 2, countReads(replica), "a caught up replica is used")
+
+	checks := len(replica.Queries())
+	_, err = db.GetAPIKeyByID(database.WithMinLSN(ctx, token), "a")
+	require.ErrorIs(t, err, sql.ErrNoRows)
+	require.Len(t, replica.Queries(), checks+1, "the replay position is cached")
+}
+
+func TestWithQueryTag(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	tagged := database.WithQueryTag(database.WithQueryTag(ctx, "custom"), database.QueryTagForcePrimary)
+	require.Equal(t, []database.QueryTag{"custom", database.QueryTagForcePrimary}, database.QueryTagsFromContext(tagged))
+	require.Len(t, database.QueryTagsFromContext(database.WithQueryTag(tagged, "custom")), 2, "tags are added once")
+	require.Empty(t, database.QueryTagsFromContext(ctx))
+
+	primaryDB, primary := newRecordingDB()
+	t.Cleanup(func() { _ = primaryDB.Close() })
+	secondaryDB, secondary := newRecordingDB()
+	t.Cleanup(func() { _ = secondaryDB.Close() })
+	db := database.New(primaryDB, database.WithSecondaryPool(secondaryDB, "GetAPIKeyByID"))
+
+	_, err := db.GetAPIKeyByID(database.WithQueryTag(ctx, database.QueryTagForcePrimary), "a")
+	require.ErrorIs(t, err, sql.ErrNoRows)
+	require.Len(t, primary.Queries(), 1, "force-primary overrides the listed method")
+	require.Empty(t, secondary.Queries())
+
+	_, err = db.GetLicenses(database.WithQueryTag(ctx, database.QueryTagAnalyticsPool))
+	require.NoError(t, err)
+	require.Len(t, secondary.Queries(), 1, "analytics-pool routes an unlisted method")
+	require.Contains(t, secondary.Queries()[0], "GetLicenses")
+
+	both := database.WithQueryTag(database.WithQueryTag(ctx, database.QueryTagAnalyticsPool), database.QueryTagForcePrimary)
+	_, err = db.GetAPIKeyByID(both, "a")
+	require.ErrorIs(t, err, sql.ErrNoRows)
+	require.Len(t, primary.Queries(), 2, "the primary wins")
+
+	err = db.InTx(func(tx database.Store) error {
+		_, err := tx.GetAPIKeyByID(database.WithQueryTag(ctx, database.QueryTagAnalyticsPool), "b")
+		require.ErrorIs(t, err, sql.ErrNoRows)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Len(t, secondary.Queries(), 1, "transactions stay on the primary pool")
+}
@@ -0,0 +1,180 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"golang.org/x/sync/semaphore"
+	"golang.org/x/xerrors"
+)
+
+// Priority classifies Store calls for NewWithPriority.
+type Priority int
+
+const (
+	// PriorityHigh is for interactive work, such as serving API requests.
+	// Calls without a priority are high priority.
+	PriorityHigh Priority = iota
+	// PriorityLow is for background work that can wait, such as batch
+	// jobs.
+	PriorityLow
+)
+
+func (p Priority) String() string {
+	if p == PriorityLow {
+		return "low"
+	}
+	return "high"
+}
+
+type priorityKey struct{}
+
+// WithPriority returns a context whose Store calls have priority p.
+func WithPriority(ctx context.Context, p Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, p)
+}
+
+// PriorityFromContext returns the priority set by WithPriority, or
+// PriorityHigh.
+func PriorityFromContext(ctx context.Context) Priority {
+	p, ok := ctx.Value(priorityKey{}).(Priority)
+	if !ok {
+		return PriorityHigh
+	}
+	return p
+}
+
+// PriorityOption configures NewWithPriority.
+type PriorityOption func(*prioritizer)
+
+// WithPriorityMetrics registers metrics for the calls waiting to be
+// admitted, labeled by priority: how many are waiting, how long they
+// waited and how many gave up because their context ended, by reason.
+// Unlike the pool statistics, these show the queueing in front of the
+// pool, to tune limit and lowLimit with.
+func WithPriorityMetrics(registerer prometheus.Registerer) PriorityOption {
+	return func(p *prioritizer) {
+		factory := promauto.With(registerer)
+		p.waiting = factory.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "coderd",
+			Subsystem: "db",
+			Name:      "admission_waiting",
+			Help:      "The number of database calls waiting to be admitted by the priority limiter.",
+		}, []string{"priority"})
+		p.waits = factory.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "coderd",
+			Subsystem: "db",
+			Name:      "admission_wait_seconds",
+			Help:      "Time database calls waited to be admitted by the priority limiter in seconds.",
+			Buckets:   []float64{0.0001, 0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1, 5},
+		}, []string{"priority"})
+		p.rejections = factory.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "coderd",
+			Subsystem: "db",
+			Name:      "admission_rejections_total",
+			Help:      "The total number of database calls that gave up waiting for the priority limiter.",
+		}, []string{"priority", "reason"})
+	}
+}
+
+type prioritizer struct {
+	all *semaphore.Weighted
+	low *semaphore.Weighted
+
+	// waiting, waits and rejections are set by WithPriorityMetrics.
+	waiting    *prometheus.GaugeVec
+	waits      *prometheus.HistogramVec
+	rejections *prometheus.CounterVec
+}
+
+// NewWithPriority returns a Store that admits at most limit concurrent
+// calls, of which at most lowLimit may be low priority, so background jobs
+// cannot take the connections that interactive calls need. limit should
+// not exceed the pool's maximum open connections. A low priority call
+// waits until it fits in both limits; at most lowLimit low priority calls
+// are queued ahead of a high priority one. Calls wait in FIFO order within
+// each limit and give up when their context is done.
+//
+// A transaction is admitted once and holds its slot until it ends; calls
+// inside it are not limited again. It panics if either limit is less than
+// one, since no call could ever be admitted.
+func NewWithPriority(store Store, limit, lowLimit int, opts ...PriorityOption) Store {
+	if limit < 1 || lowLimit < 1 {
+		panic(fmt.Sprintf("developer error: priority limits must be at least 1, got limit %d and lowLimit %d", limit, lowLimit))
+	}
+	if lowLimit > limit {
+		lowLimit = limit
+	}
+	p := &prioritizer{
+		all: semaphore.NewWeighted(int64(limit)),
+		low: semaphore.NewWeighted(int64(lowLimit)),
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return Intercept(store, p.intercept)
+}
+
+func (p *prioritizer) intercept(ctx context.Context, call Call, next Invoker) ([]interface{}, error) {
+	if call.InTx {
+		return next(ctx)
+	}
+	priority := PriorityFromContext(ctx)
+	release, err := p.admit(ctx, priority)
+	if err != nil {
+		return nil, xerrors.Errorf("%s: %w", call.Method, err)
+	}
+	defer release()
+	return next(ctx)
+}
+
+// admit waits for the slots a call of priority needs and returns a function
+// that releases them.
+func (p *prioritizer) admit(ctx context.Context, priority Priority) (func(), error) {
+	if p.waiting != nil {
+		label := priority.String()
+		start := time.Now()
+		p.waiting.WithLabelValues(label).Inc()
+		defer func() {
+			p.waiting.WithLabelValues(label).Dec()
+			p.waits.WithLabelValues(label).Observe(time.Since(start).Seconds())
+		}()
+	}
+
+	if priority == PriorityLow {
+		err := p.low.Acquire(ctx, 1)
+		if err != nil {
+			p.reject(priority, err)
+			return nil, xerrors.Errorf("wait for low priority slot: %w", err)
+		}
+	}
+	err := p.all.Acquire(ctx, 1)
+	if err != nil {
+		if priority == PriorityLow {
+			p.low.Release(1)
+		}
+		p.reject(priority, err)
+		return nil, xerrors.Errorf("wait for slot: %w", err)
+	}
+	return func() {
+		p.all.Release(1)
+		if priority == PriorityLow {
+			p.low.Release(1)
+		}
+	}, nil
+}
+
+func (p *prioritizer) reject(priority Priority, err error) {
+	if p.rejections == nil {
+		return
+	}
+	reason := "canceled"
+	if errors.Is(err, context.DeadlineExceeded) {
+		reason = "timeout"
+	}
+	p.rejections.WithLabelValues(priority.String(), reason).Inc()
+}
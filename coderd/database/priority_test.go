@@ -0,0 +1,165 @@
+package database_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/coder/coder/coderd/database"
+	"github.com/coder/coder/coderd/database/databasefake"
+)
+
+func TestWithPriority(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	low := database.WithPriority(ctx, database.PriorityLow)
+	require.Equal(t, database.PriorityHigh, database.PriorityFromContext(ctx))
+	require.Equal(t, database.PriorityLow, database.PriorityFromContext(low))
+
+	// Block calls inside a fake store until released.
+	entered := make(chan database.Priority)
+	release := make(chan struct{})
+	blocking := database.Intercept(databasefake.New(), func(ctx context.Context, call database.Call, next database.Invoker) ([]interface{}, error) {
+		entered <- database.PriorityFromContext(ctx)
+		<-release
+		return next(ctx)
+	})
+	db := database.NewWithPriority(blocking, 2, 1)
+	call := func(ctx context.Context) chan error {
+		errs := make(chan error, 1)
+		go func() {
+			_, err := db.GetUsers(ctx, database.GetUsersParams{})
+			errs <- err
+		}()
+		return errs
+	}
+
+	lowDone := call(low)
+	require.Equal(t, database.PriorityLow, <-entered)
+
+	// The second low priority call waits, even though the pool has room.
+	lowWaiting := call(low)
+	select {
+	case <-entered:
+		t.Fatal("low priority call exceeded its limit")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// A high priority call still gets the reserved slot.
+	highDone := call(ctx)
+	require.Equal(t, database.PriorityHigh, <-entered)
+
+	// A call whose context ends while waiting gives up.
+	waitCtx, waitCancel := context.WithCancel(low)
+	canceled := call(waitCtx)
+	waitCancel()
+	require.ErrorIs(t, <-canceled, context.Canceled)
+
+	release <- struct{}{}
+	release <- struct{}{}
+	require.NoError(t, <-lowDone)
+	require.NoError(t, <-highDone)
+	require.Equal(t, database.PriorityLow, <-entered, "the waiting low priority call proceeds")
+	release <- struct{}{}
+	require.NoError(t, <-lowWaiting)
+}
+
+func TestNewWithPriorityInvalidLimits(t *testing.T) {
+	t.Parallel()
+
+	for _, limits := range [][2]int{{0, 1}, {2, 0}, {-1, -1}} {
+		require.Panics(t, func() {
+			database.NewWithPriority(databasefake.New(), limits[0], limits[1])
+		}, "limit %d, lowLimit %d", limits[0], limits[1])
+	}
+	require.NotPanics(t, func() {
+		database.NewWithPriority(databasefake.New(), 1, 1)
+	})
+}
+
+func TestWithPriorityMetrics(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	blocking := database.Intercept(databasefake.New(), func(ctx context.Context, call database.Call, next database.Invoker) ([]interface{}, error) {
+		entered <- struct{}{}
+		<-release
+		return next(ctx)
+	})
+	registry := prometheus.NewRegistry()
+	db := database.NewWithPriority(blocking, 1, 1, database.WithPriorityMetrics(registry))
+	call := func(ctx context.Context) chan error {
+		errs := make(chan error, 1)
+		go func() {
+			_, err := db.GetUsers(ctx, database.GetUsersParams{})
+			errs <- err
+		}()
+		return errs
+	}
+	// metric returns the value of the sample of name with the given
+	// labels, or -1 if there is none.
+	metric := func(name string, labels ...string) float64 {
+		families, err := registry.Gather()
+		require.NoError(t, err)
+		for _, family := range families {
+			if family.GetName() != name {
+				continue
+			}
+		next:
+			for _, m := range family.GetMetric() {
+				for i, pair := range m.GetLabel() {
+					if pair.GetValue() != labels[i] {
+						continue next
+					}
+				}
+				switch {
+				case m.GetGauge() != nil:
+					return m.GetGauge().GetValue()
+				case m.GetCounter() != nil:
+					return m.GetCounter().GetValue()
+				default:
+					return float64(m.GetHistogram().GetSampleCount())
+				}
+			}
+		}
+		return -1
+	}
+
+	holder := call(ctx)
+	<-entered
+	waiter := call(ctx)
+	require.Eventually(t, func() bool {
+		return metric("coderd_db_admission_waiting", "high") == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	timeoutCtx, timeoutCancel := context.WithTimeout(database.WithPriority(ctx, database.PriorityLow), 20*time.Millisecond)
+	defer timeoutCancel()
+	require.ErrorIs(t, <-call(timeoutCtx), context.DeadlineExceeded)
+	canceledCtx, canceledCancel := context.WithCancel(ctx)
+	canceled := call(canceledCtx)
+	require.Eventually(t, func() bool {
+		return metric("coderd_db_admission_waiting", "high") == 2
+	}, 5*time.Second, 10*time.Millisecond)
+	canceledCancel()
+	require.ErrorIs(t, <-canceled, context.Canceled)
+	require.Equal(t, float64(1), metric("coderd_db_admission_rejections_total", "low", "timeout"))
+	require.Equal(t, float64(1), metric("coderd_db_admission_rejections_total", "high", "canceled"))
+
+	release <- struct{}{}
+	require.NoError(t, <-holder)
+	<-entered
+	release <- struct{}{}
+	require.NoError(t, <-waiter)
+	require.Equal(t, float64(0), metric("coderd_db_admission_waiting", "high"))
+	require.Equal(t, float64(0), metric("coderd_db_admission_waiting", "low"))
+	require.Equal(t, float64(3), metric("coderd_db_admission_wait_seconds", "high"), "every admission attempt is observed")
+	require.Equal(t, float64(1), metric("coderd_db_admission_wait_seconds", "low"))
+}
@@ -51,7 +51,9 @@ WHERE
 		ELSE true
 	END
 ORDER BY
-    "time" DESC
+    -- Deterministic and consistent ordering of all rows, even if they share
+    -- a timestamp. This is to ensure consistent pagination.
+    "time" DESC, id DESC
 LIMIT
     $1
 OFFSET
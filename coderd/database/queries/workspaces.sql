@@ -135,7 +135,11 @@ WHERE
 	-- Authorize Filter clause will be injected below in GetAuthorizedWorkspaces
 	-- @authorize_filter
 ORDER BY
-    last_used_at DESC
+    -- Deterministic and consistent ordering of all rows, even if they share
+    -- a timestamp. This is to ensure consistent pagination.
+    -- last_used_at is never null: workspaces that were never used hold
+    -- '0001-01-01', so they sort after every used workspace.
+    last_used_at DESC, id DESC
 LIMIT
     CASE
         WHEN @limit_ :: integer > 0 THEN
@@ -362,6 +366,7 @@ WHERE
 UPDATE
 	workspaces
 SET
-	last_used_at = $2
+	last_used_at = $2,
+	dormant_at = NULL
 WHERE
 	id = $1;
@@ -0,0 +1,59 @@
+package database
+
+import (
+	"context"
+	"sync/atomic"
+
+	"golang.org/x/xerrors"
+)
+
+// ErrTooManyQueries is returned by Stores created with NewWithMaxQueries
+// once the context has made as many query method calls as it may.
+var ErrTooManyQueries = xerrors.New("too many queries")
+
+type maxQueriesKey struct{}
+
+type queryCount struct {
+	max   int64
+	count atomic.Int64
+}
+
+// WithMaxQueries returns a context that allows at most n query method calls
+// on a Store created with NewWithMaxQueries, as a guardrail against a
+// handler whose queries multiply with the data, such as an N+1 loop. Unlike
+// WithQueryBudget it caps the number of calls, however fast each is. The
+// count is shared by every call made with the context or a context derived
+// from it, so attach it once per request.
+func WithMaxQueries(ctx context.Context, n int) context.Context {
+	return context.WithValue(ctx, maxQueriesKey{}, &queryCount{max: int64(n)})
+}
+
+// QueriesMade returns the number of query method calls counted against the
+// limit set by WithMaxQueries, including those rejected for exceeding it.
+func QueriesMade(ctx context.Context) (int, bool) {
+	count, ok := ctx.Value(maxQueriesKey{}).(*queryCount)
+	if !ok {
+		return 0, false
+	}
+	return int(count.count.Load()), true
+}
+
+// NewWithMaxQueries returns a Store that counts each query method call
+// against the limit set on its context by WithMaxQueries and fails calls
+// past it with ErrTooManyQueries, without running them. Calls without a
+// limit are not counted. Calls inside transactions are counted, but
+// beginning the transaction is not.
+func NewWithMaxQueries(store Store) Store {
+	return Intercept(store, interceptMaxQueries)
+}
+
+func interceptMaxQueries(ctx context.Context, call Call, next Invoker) ([]interface{}, error) {
+	count, ok := ctx.Value(maxQueriesKey{}).(*queryCount)
+	if !ok || call.Method == "InTx" {
+		return next(ctx)
+	}
+	if made := count.count.Add(1); made > count.max {
+		return nil, xerrors.Errorf("%s: %w: limit is %d", call.Method, ErrTooManyQueries, count.max)
+	}
+	return next(ctx)
+}
@@ -0,0 +1,51 @@
+package database_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/coder/coder/coderd/database"
+)
+
+func TestMaxQueries(t *testing.T) {
+	t.Parallel()
+
+	sqlDB, connector := newRecordingDB()
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	db := database.NewWithMaxQueries(database.New(sqlDB))
+
+	for i := 0; i < 5; i++ {
+		require.NoError(t, db.DeleteAPIKeyByID(context.Background(), "key"), "no limit")
+	}
+	_, ok := database.QueriesMade(context.Background())
+	require.False(t, ok)
+
+	request := func() context.Context {
+		return database.WithMaxQueries(context.Background(), 3)
+	}
+	ctx := request()
+	require.NoError(t, db.DeleteAPIKeyByID(ctx, "key"))
+	require.NoError(t, db.InTxOpts(ctx, database.TxOptions{}, func(tx database.Store) error {
+		err := tx.DeleteAPIKeyByID(ctx, "key")
+		if err != nil {
+			return err
+		}
+		return tx.DeleteAPIKeyByID(ctx, "key")
+	}), "calls inside transactions are counted, the transaction is not")
+	made, ok := database.QueriesMade(ctx)
+	require.True(t, ok)
+	require.Equal(t, 3, made)
+
+	before := len(connector.Queries())
+	err := db.DeleteAPIKeyByID(ctx, "key")
+	require.ErrorIs(t, err, database.ErrTooManyQueries)
+	require.ErrorContains(t, err, "DeleteAPIKeyByID")
+	require.Len(t, connector.Queries(), before, "calls past the limit do not query")
+
+	ctx = request()
+	require.NoError(t, db.DeleteAPIKeyByID(ctx, "key"), "each request has its own count")
+	made, _ = database.QueriesMade(ctx)
+	require.Equal(t, 1, made)
+}
@@ -0,0 +1,108 @@
+package database
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+
+	"cdr.dev/slog"
+)
+
+type logTenantKey struct{}
+
+// WithLogTenant returns a context whose calls on a QueryLog belong to the
+// organization tenant, so SetLogTenants can select them. Attach it once
+// per request, after authenticating it.
+func WithLogTenant(ctx context.Context, tenant uuid.UUID) context.Context {
+	return context.WithValue(ctx, logTenantKey{}, tenant)
+}
+
+// QueryLog is a Store that logs its calls, with their arguments summarized
+// and redacted as in ArgsError, for debugging production traffic without
+// turning on log_statement for the whole database. Nothing is logged
+// until SetLogSampleRate or SetLogTenants selects some calls, and both can
+// be changed at any time, e.g. from a debug endpoint, to log a
+// misbehaving tenant for a while and then stop. Transactions are logged as
+// "InTx" calls where they begin, along with the calls made inside them
+// that are selected.
+type QueryLog struct {
+	Store
+	logger slog.Logger
+
+	// rate holds the sample rate's float64 bits.
+	rate    atomic.Uint64
+	mu      sync.RWMutex
+	tenants map[uuid.UUID]bool
+}
+
+// NewQueryLog returns a QueryLog that wraps store and logs to logger.
+func NewQueryLog(store Store, logger slog.Logger) *QueryLog {
+	l := &QueryLog{logger: logger}
+	l.Store = Intercept(store, l.intercept)
+	return l
+}
+
+// SetLogSampleRate logs the given fraction of calls, chosen at random,
+// from 0 for none to 1 for all.
+func (l *QueryLog) SetLogSampleRate(rate float64) {
+	l.rate.Store(math.Float64bits(math.Max(0, math.Min(1, rate))))
+}
+
+// SetLogTenants logs every call made with a context from WithLogTenant for
+// one of tenants, whatever the sample rate, replacing the previous set.
+// Call it with none to stop.
+func (l *QueryLog) SetLogTenants(tenants ...uuid.UUID) {
+	set := make(map[uuid.UUID]bool, len(tenants))
+	for _, tenant := range tenants {
+		set[tenant] = true
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.tenants = set
+}
+
+// selected reports whether a call made with ctx is logged, and the tenant
+// it belongs to.
+func (l *QueryLog) selected(ctx context.Context) (uuid.UUID, bool) {
+	tenant, hasTenant := ctx.Value(logTenantKey{}).(uuid.UUID)
+	if hasTenant {
+		l.mu.RLock()
+		allowed := l.tenants[tenant]
+		l.mu.RUnlock()
+		if allowed {
+			return tenant, true
+		}
+	}
+	rate := math.Float64frombits(l.rate.Load())
+	// Sampling needs no secure randomness.
+	// nolint:gosec
+	return tenant, rate > 0 && rand.Float64() < rate
+}
+
+func (l *QueryLog) intercept(ctx context.Context, call Call, next Invoker) ([]interface{}, error) {
+	tenant, ok := l.selected(ctx)
+	if !ok {
+		return next(ctx)
+	}
+	start := time.Now()
+	results, err := next(ctx)
+	fields := []slog.Field{
+		slog.F("method", call.Method),
+		slog.F("args", summarizeArgs(call.Args)),
+		slog.F("in_tx", call.InTx),
+		slog.F("duration", time.Since(start)),
+	}
+	if tenant != uuid.Nil {
+		fields = append(fields, slog.F("tenant", tenant))
+	}
+	if err != nil {
+		fields = append(fields, slog.Error(err))
+	}
+	l.logger.Info(ctx, "query", fields...)
+	return results, err
+}
@@ -0,0 +1,80 @@
+package database_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"cdr.dev/slog"
+
+	"github.com/coder/coder/coderd/database"
+	"github.com/coder/coder/coderd/database/databasefake"
+)
+
+func TestQueryLog(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Sampling", func(t *testing.T) {
+		t.Parallel()
+		sink := &captureSink{}
+		db := database.NewQueryLog(databasefake.New(), slog.Make(sink))
+		ctx := context.Background()
+		calls := func(n int) int {
+			before := len(sink.Messages())
+			for i := 0; i < n; i++ {
+				_, _ = db.GetTemplates(ctx)
+			}
+			return len(sink.Messages()) - before
+		}
+
+		require.Zero(t, calls(100), "nothing is logged by default")
+		db.SetLogSampleRate(1)
+		require.Equal(t, 100, calls(100))
+		db.SetLogSampleRate(0.5)
+		logged := calls(1000)
+		require.Greater(t, logged, 350)
+		require.Less(t, logged, 650)
+		db.SetLogSampleRate(0)
+		require.Zero(t, calls(100), "turned back off")
+	})
+
+	t.Run("Tenants", func(t *testing.T) {
+		t.Parallel()
+		sink := &captureSink{}
+		db := database.NewQueryLog(databasefake.New(), slog.Make(sink))
+		noisy, quiet := uuid.New(), uuid.New()
+		noisyCtx := database.WithLogTenant(context.Background(), noisy)
+		quietCtx := database.WithLogTenant(context.Background(), quiet)
+
+		db.SetLogTenants(noisy)
+		_, _ = db.GetTemplates(noisyCtx)
+		_, _ = db.GetTemplates(quietCtx)
+		_, _ = db.GetTemplates(context.Background())
+		entries := sink.Entries()
+		require.Len(t, entries, 1, "only the allowed tenant is logged")
+		var method, tenant interface{}
+		for _, field := range entries[0].Fields {
+			switch field.Name {
+			case "method":
+				method = field.Value
+			case "tenant":
+				tenant = field.Value
+			}
+		}
+		require.Equal(t, "GetTemplates", method)
+		require.Equal(t, noisy, tenant)
+
+		err := db.InTx(func(tx database.Store) error {
+			_, err := tx.GetTemplates(noisyCtx)
+			return err
+		})
+		require.NoError(t, err)
+		require.Len(t, sink.Messages(), 2, "calls inside transactions are logged too")
+
+		db.SetLogTenants()
+		_, _ = db.GetTemplates(noisyCtx)
+		require.Len(t, sink.Messages(), 2, "allowlist cleared")
+	})
+}
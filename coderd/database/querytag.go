@@ -0,0 +1,55 @@
+package database
+
+import "context"
+
+// QueryTag marks the Store calls made with a context for special handling
+// by the layers below, without needing a variant of each method.
+type QueryTag string
+
+const (
+	// QueryTagForcePrimary runs calls on the primary pool, even methods
+	// listed in WithSecondaryPool, and never hedges them. Use it for reads
+	// that must see the caller's own recent writes.
+	QueryTagForcePrimary QueryTag = "force-primary"
+	// QueryTagAnalyticsPool runs calls outside transactions on the pool
+	// set by WithSecondaryPool, even methods not listed there, so ad hoc
+	// reports stay off the primary pool. Without a secondary pool it has
+	// no effect.
+	QueryTagAnalyticsPool QueryTag = "analytics-pool"
+)
+
+type queryTagsKey struct{}
+
+// WithQueryTag returns a context whose Store calls carry tag in addition to
+// the tags ctx already has. Other tags than the QueryTag constants are
+// ignored by this package, but interceptors can read them with
+// QueryTagsFromContext. If a context has both QueryTagForcePrimary and
+// QueryTagAnalyticsPool, the primary wins.
+func WithQueryTag(ctx context.Context, tag QueryTag) context.Context {
+	tags := QueryTagsFromContext(ctx)
+	for _, existing := range tags {
+		if existing == tag {
+			return ctx
+		}
+	}
+	// Copy so that contexts derived from the same parent do not share
+	// appends.
+	tags = append(append(make([]QueryTag, 0, len(tags)+1), tags...), tag)
+	return context.WithValue(ctx, queryTagsKey{}, tags)
+}
+
+// QueryTagsFromContext returns the tags set by WithQueryTag, in the order
+// they were added.
+func QueryTagsFromContext(ctx context.Context) []QueryTag {
+	tags, _ := ctx.Value(queryTagsKey{}).([]QueryTag)
+	return tags
+}
+
+func hasQueryTag(ctx context.Context, tag QueryTag) bool {
+	for _, t := range QueryTagsFromContext(ctx) {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,73 @@
+package database_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/coder/coder/coderd/database"
+)
+
+// TestCustomQueryShape asserts that the hand-written queries are tagged with
+// their name and send the same statement text whatever the arguments, so
+// they group into a single pg_stat_statements entry.
+func TestCustomQueryShape(t *testing.T) {
+	t.Parallel()
+
+	type args struct {
+		id   uuid.UUID
+		time time.Time
+		name string
+	}
+	calls := map[string]func(ctx context.Context, db database.Store, a args){
+		"GetTemplateUserRoles": func(ctx context.Context, db database.Store, a args) {
+			_, _ = db.GetTemplateUserRoles(ctx, a.id)
+		},
+		"GetTemplateGroupRoles": func(ctx context.Context, db database.Store, a args) {
+			_, _ = db.GetTemplateGroupRoles(ctx, a.id)
+		},
+		"UpdateTemplateIfVersion": func(ctx context.Context, db database.Store, a args) {
+			_, _ = db.UpdateTemplateIfVersion(ctx, a.id, a.time, database.UpdateTemplateIfVersionParams{Name: a.name})
+		},
+		"SoftDeleteUsersByOrg": func(ctx context.Context, db database.Store, a args) {
+			_, _ = db.SoftDeleteUsersByOrg(ctx, a.id)
+		},
+		"GetWorkspacesModifiedSince": func(ctx context.Context, db database.Store, a args) {
+			_, _ = db.GetWorkspacesModifiedSince(ctx, database.GetWorkspacesModifiedSinceParams{UpdatedAt: a.time, AfterID: a.id, Limit: 10})
+		},
+		"CheckUsersExist": func(ctx context.Context, db database.Store, a args) {
+			_, _ = db.CheckUsersExist(ctx, []uuid.UUID{a.id})
+		},
+		"Notify": func(ctx context.Context, db database.Store, a args) {
+			_ = db.Notify(ctx, "shape", a.name)
+		},
+		"GetWorkspaceWithBuildsJSON": func(ctx context.Context, db database.Store, a args) {
+			_, _ = db.GetWorkspaceWithBuildsJSON(ctx, a.id)
+		},
+	}
+	for name, call := range calls {
+		name, call := name, call
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			sqlDB, connector := newRecordingDB()
+			t.Cleanup(func() { _ = sqlDB.Close() })
+			db := database.New(sqlDB)
+			ctx := context.Background()
+
+			first := args{id: uuid.New(), time: time.Unix(1, 0), name: "first"}
+			second := args{id: uuid.New(), time: time.Unix(2, 0), name: "second"}
+			call(ctx, db, first)
+			call(ctx, db, second)
+
+			queries := connector.Queries()
+			require.Len(t, queries, 2)
+			require.Equal(t, queries[0], queries[1], "statement text depends on the arguments")
+			require.True(t, strings.HasPrefix(strings.TrimSpace(queries[0]), "-- name: "+name+" "), "query is not tagged with its name")
+			require.NotContains(t, queries[0], first.id.String(), "arguments are inlined")
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+	"golang.org/x/xerrors"
+)
+
+// ErrQuotaExceeded is matched by errors from quota-limited inserts when the
+// organization is already at its limit.
+var ErrQuotaExceeded = xerrors.New("quota exceeded")
+
+type quotaQuerier interface {
+	// InsertWorkspaceWithQuota inserts the workspace only if orgID, which
+	// must be the workspace's organization, has fewer than max workspaces
+	// that are not deleted, and returns an error matching
+	// ErrQuotaExceeded otherwise. Concurrent quota-limited inserts into the
+	// same organization are serialized, so racing creates cannot
+	// overshoot the limit, but plain InsertWorkspace calls are not counted
+	// against it until they commit.
+	InsertWorkspaceWithQuota(ctx context.Context, orgID uuid.UUID, max int, arg InsertWorkspaceParams) (Workspace, error)
+}
+
+func (q *sqlQuerier) InsertWorkspaceWithQuota(ctx context.Context, orgID uuid.UUID, max int, arg InsertWorkspaceParams) (Workspace, error) {
+	if arg.OrganizationID != orgID {
+		return Workspace{}, xerrors.Errorf("workspace belongs to organization %s, not %s", arg.OrganizationID, orgID)
+	}
+	// A count and an insert in one statement would share a snapshot taken
+	// before any lock is granted, so a racing insert that commits in
+	// between is not counted. Locking the organization first and counting
+	// in a new statement closes that window under READ COMMITTED. FOR NO
+	// KEY UPDATE only conflicts with itself and stronger locks, not with
+	// the key share locks of foreign key checks, so other inserts into the
+	// organization are not blocked.
+	const lock = `-- name: InsertWorkspaceWithQuota :one
+	SELECT
+		id
+	FROM
+		organizations
+	WHERE
+		id = $1
+	FOR NO KEY UPDATE
+	`
+	const insert = `-- name: InsertWorkspaceWithQuota :one
+	INSERT INTO
+		workspaces (
+			id,
+			created_at,
+			updated_at,
+			owner_id,
+			organization_id,
+			template_id,
+			name,
+			autostart_schedule,
+			ttl
+		)
+	SELECT
+		$1::uuid, $2::timestamptz, $3::timestamptz, $4::uuid, $5::uuid, $6::uuid, $7::varchar, $8::text, $9::bigint
+	WHERE
+		(
+			SELECT
+				count(*)
+			FROM
+				workspaces
+			WHERE
+				organization_id = $5
+				AND deleted = false
+		) < $10
+	RETURNING
+		*
+	`
+
+	var workspace Workspace
+	err := q.inCurrentTx(ctx, func(tx Store) error {
+		db := txQuerier(tx).db
+		var id uuid.UUID
+		err := db.GetContext(ctx, &id, lock, orgID)
+		if err != nil {
+			return xerrors.Errorf("lock organization: %w", err)
+		}
+		err = db.GetContext(ctx, &workspace, insert,
+			arg.ID,
+			arg.CreatedAt,
+			arg.UpdatedAt,
+			arg.OwnerID,
+			arg.OrganizationID,
+			arg.TemplateID,
+			arg.Name,
+			arg.AutostartSchedule,
+			arg.Ttl,
+			max,
+		)
+		if xerrors.Is(err, sql.ErrNoRows) {
+			return xerrors.Errorf("%w: organization has %d workspaces", ErrQuotaExceeded, max)
+		}
+		return err
+	})
+	if err != nil {
+		return Workspace{}, xerrors.Errorf("insert workspace with quota: %w", err)
+	}
+	return workspace, nil
+}
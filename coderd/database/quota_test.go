@@ -0,0 +1,72 @@
+//go:build linux
+
+package database_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/coder/coder/coderd/database"
+	"github.com/coder/coder/coderd/database/dbtestutil"
+)
+
+func TestInsertWorkspaceWithQuota(t *testing.T) {
+	t.Parallel()
+
+	db, _ := dbtestutil.NewDB(t)
+	ctx := context.Background()
+	user, org, template := insertTemplate(t, db)
+	params := func(name string) database.InsertWorkspaceParams {
+		return database.InsertWorkspaceParams{
+			ID:             uuid.New(),
+			CreatedAt:      database.Now(),
+			UpdatedAt:      database.Now(),
+			OwnerID:        user.ID,
+			OrganizationID: org.ID,
+			TemplateID:     template.ID,
+			Name:           name,
+		}
+	}
+
+	_, err := db.InsertWorkspaceWithQuota(ctx, uuid.New(), 2, params("elsewhere"))
+	require.Error(t, err, "organization mismatch")
+	first, err := db.InsertWorkspaceWithQuota(ctx, org.ID, 2, params("first"))
+	require.NoError(t, err)
+	require.Equal(t, "first", first.Name)
+
+	// With one slot left, exactly one of the racing creates gets it.
+	const racers = 2
+	var (
+		wg   sync.WaitGroup
+		errs [racers]error
+	)
+	for i := 0; i < racers; i++ {
+		i := i
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, errs[i] = db.InsertWorkspaceWithQuota(ctx, org.ID, 2, params(uuid.NewString()[:8]))
+		}()
+	}
+	wg.Wait()
+	succeeded := 0
+	for _, err := range errs {
+		if err == nil {
+			succeeded++
+			continue
+		}
+		require.ErrorIs(t, err, database.ErrQuotaExceeded)
+	}
+	require.Equal(t, 1, succeeded, "exactly one create succeeds")
+
+	err = db.UpdateWorkspaceDeletedByID(ctx, database.UpdateWorkspaceDeletedByIDParams{ID: first.ID, Deleted: true})
+	require.NoError(t, err)
+	_, err = db.InsertWorkspaceWithQuota(ctx, org.ID, 2, params("replacement"))
+	require.NoError(t, err, "deleted workspaces do not count")
+	_, err = db.InsertWorkspaceWithQuota(ctx, org.ID, 2, params("over"))
+	require.ErrorIs(t, err, database.ErrQuotaExceeded)
+}
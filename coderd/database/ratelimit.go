@@ -0,0 +1,72 @@
+package database
+
+import (
+	"context"
+
+	"golang.org/x/time/rate"
+	"golang.org/x/xerrors"
+)
+
+// ErrRateLimited is returned by Stores created with NewWithRateLimit when a
+// method is called more often than its configured rate.
+var ErrRateLimited = xerrors.New("database query rate limited")
+
+// RateLimit is a token bucket for a single Store method: Rate calls per
+// second with bursts of up to Burst calls.
+type RateLimit struct {
+	Rate  rate.Limit
+	Burst int
+}
+
+// RateLimitOption configures NewWithRateLimit.
+type RateLimitOption func(*rateLimiter)
+
+// WithRateLimitWait makes rate-limited calls wait for a token instead of
+// failing immediately. ErrRateLimited is still returned if the token would
+// not be available before the context deadline.
+func WithRateLimitWait() RateLimitOption {
+	return func(r *rateLimiter) {
+		r.wait = true
+	}
+}
+
+type rateLimiter struct {
+	limiters map[string]*rate.Limiter
+	wait     bool
+}
+
+// NewWithRateLimit returns a Store that limits how often each method in
+// limits may be called, to protect the database from expensive queries
+// triggered by API clients. Methods not in limits are not limited. This
+// limits requests per second, not concurrency. Calls inside transactions
+// count towards the limits as well.
+func NewWithRateLimit(store Store, limits map[string]RateLimit, opts ...RateLimitOption) Store {
+	r := &rateLimiter{
+		limiters: make(map[string]*rate.Limiter, len(limits)),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	for method, limit := range limits {
+		r.limiters[method] = rate.NewLimiter(limit.Rate, limit.Burst)
+	}
+	return Intercept(store, r.intercept)
+}
+
+func (r *rateLimiter) intercept(ctx context.Context, call Call, next Invoker) ([]interface{}, error) {
+	limiter, ok := r.limiters[call.Method]
+	if !ok {
+		return next(ctx)
+	}
+	if !r.wait {
+		if !limiter.Allow() {
+			return nil, xerrors.Errorf("%s: %w", call.Method, ErrRateLimited)
+		}
+		return next(ctx)
+	}
+	err := limiter.Wait(ctx)
+	if err != nil {
+		return nil, xerrors.Errorf("%s: %w: %s", call.Method, ErrRateLimited, err)
+	}
+	return next(ctx)
+}
@@ -0,0 +1,60 @@
+package database_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+
+	"github.com/coder/coder/coderd/database"
+	"github.com/coder/coder/coderd/database/databasefake"
+)
+
+func TestNewWithRateLimit(t *testing.T) {
+	t.Parallel()
+
+	t.Run("NonBlocking", func(t *testing.T) {
+		t.Parallel()
+
+		db := database.NewWithRateLimit(databasefake.New(), map[string]database.RateLimit{
+			"GetAuditLogsOffset": {Rate: rate.Every(time.Hour), Burst: 2},
+		})
+		ctx := context.Background()
+
+		for i := 0; i < 2; i++ {
+			_, err := db.GetAuditLogsOffset(ctx, database.GetAuditLogsOffsetParams{})
+			require.NoError(t, err, "within burst")
+		}
+		_, err := db.GetAuditLogsOffset(ctx, database.GetAuditLogsOffsetParams{})
+		require.ErrorIs(t, err, database.ErrRateLimited)
+
+		// Other methods are not limited.
+		for i := 0; i < 5; i++ {
+			_, err := db.GetUsers(ctx, database.GetUsersParams{})
+			require.NoError(t, err)
+		}
+	})
+
+	t.Run("Blocking", func(t *testing.T) {
+		t.Parallel()
+
+		db := database.NewWithRateLimit(databasefake.New(), map[string]database.RateLimit{
+			"GetAuditLogsOffset": {Rate: rate.Every(50 * time.Millisecond), Burst: 1},
+		}, database.WithRateLimitWait())
+		ctx := context.Background()
+
+		_, err := db.GetAuditLogsOffset(ctx, database.GetAuditLogsOffsetParams{})
+		require.NoError(t, err)
+		start := time.Now()
+		_, err = db.GetAuditLogsOffset(ctx, database.GetAuditLogsOffsetParams{})
+		require.NoError(t, err, "waits for a token")
+		require.GreaterOrEqual(t, time.Since(start), 40*time.Millisecond)
+
+		ctx, cancel := context.WithTimeout(ctx, 5*time.Millisecond)
+		defer cancel()
+		_, err = db.GetAuditLogsOffset(ctx, database.GetAuditLogsOffsetParams{})
+		require.ErrorIs(t, err, database.ErrRateLimited, "token is not available before the deadline")
+	})
+}
@@ -0,0 +1,29 @@
+package database
+
+import (
+	"context"
+
+	"golang.org/x/xerrors"
+)
+
+// Reconnect closes every idle pooled connection so that the next queries
+// dial again, picking up a new primary through DNS or a load balancer after
+// a failover instead of waiting for connections to age out. It returns
+// without waiting for connections in use, since under steady traffic the
+// pool is never empty: they finish their queries and are pooled again,
+// unless the driver reports them broken, which is how database/sql drops
+// connections to a primary that went away. Queries issued meanwhile work
+// normally on fresh connections. Only the primary pool is recycled, and it
+// gets New's idle limit back afterwards, replacing any limit set on the
+// *sql.DB since. It cannot be called inside a transaction or WithConn,
+// whose connection could not be closed.
+func (q *sqlQuerier) Reconnect(_ context.Context) error {
+	if q.inTx || q.conn != nil {
+		return xerrors.New("reconnect must not be called inside a transaction or WithConn")
+	}
+
+	// Lowering the idle limit to zero closes every idle connection at once.
+	q.sdb.SetMaxIdleConns(0)
+	q.sdb.SetMaxIdleConns(defaultMaxIdleConns)
+	return nil
+}
@@ -0,0 +1,46 @@
+package database_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/coder/coder/coderd/database"
+)
+
+func TestReconnect(t *testing.T) {
+	t.Parallel()
+
+	sqlDB, _ := newRecordingDB()
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	db := database.New(sqlDB)
+	ctx := context.Background()
+
+	// One stale connection sits idle and another is in use when the
+	// failover is noticed.
+	held, err := sqlDB.Conn(ctx)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = held.Close() })
+	require.NoError(t, db.DeleteAPIKeyByID(ctx, "a"))
+	require.Equal(t, 1, sqlDB.Stats().Idle)
+
+	// It returns while a connection is still in use.
+	err = db.Reconnect(ctx)
+	require.NoError(t, err)
+	stats := sqlDB.Stats()
+	require.Zero(t, stats.Idle, "idle connections are closed")
+	require.Equal(t, 1, stats.InUse)
+	require.EqualValues(t, 1, stats.MaxIdleClosed)
+
+	require.NoError(t, db.DeleteAPIKeyByID(ctx, "b"))
+	require.Equal(t, 1, sqlDB.Stats().Idle, "new connections are pooled again")
+
+	t.Run("InTx", func(t *testing.T) {
+		t.Parallel()
+		err := db.InTx(func(tx database.Store) error {
+			return tx.Reconnect(ctx)
+		})
+		require.ErrorContains(t, err, "must not be called inside a transaction")
+	})
+}
@@ -0,0 +1,79 @@
+package database
+
+import (
+	"context"
+	"sync"
+)
+
+// CallRecord is one Store call captured by a Recording.
+type CallRecord struct {
+	Method string
+	// Args summarizes the arguments with registered fields redacted, as
+	// in ArgsError.
+	Args string
+	// InTx reports whether the call was made inside a transaction.
+	InTx bool
+	Err  error
+}
+
+// Recording is a Store that logs every call made through it, for tests that
+// compare the sequence of queries a handler makes against a baseline, e.g.
+// to catch a refactor that introduces an N+1. Transactions are logged as
+// "InTx" calls where they begin. It is not a mock: calls still reach the
+// wrapped Store.
+type Recording struct {
+	Store
+
+	mu    sync.Mutex
+	calls []CallRecord
+}
+
+// NewRecording returns a Recording that wraps store.
+func NewRecording(store Store) *Recording {
+	r := &Recording{}
+	r.Store = Intercept(store, r.intercept)
+	return r
+}
+
+func (r *Recording) intercept(ctx context.Context, call Call, next Invoker) ([]interface{}, error) {
+	r.mu.Lock()
+	index := len(r.calls)
+	r.calls = append(r.calls, CallRecord{
+		Method: call.Method,
+		Args:   summarizeArgs(call.Args),
+		InTx:   call.InTx,
+	})
+	r.mu.Unlock()
+
+	results, err := next(ctx)
+
+	r.mu.Lock()
+	r.calls[index].Err = err
+	r.mu.Unlock()
+	return results, err
+}
+
+// Recorded returns the calls made so far, in the order they started.
+func (r *Recording) Recorded() []CallRecord {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]CallRecord(nil), r.calls...)
+}
+
+// Methods returns the method name of each recorded call, in order.
+func (r *Recording) Methods() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	methods := make([]string, 0, len(r.calls))
+	for _, call := range r.calls {
+		methods = append(methods, call.Method)
+	}
+	return methods
+}
+
+// Reset clears the recorded calls, e.g. after test setup.
+func (r *Recording) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = nil
+}
@@ -0,0 +1,60 @@
+package database_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/coder/coder/coderd/database"
+	"github.com/coder/coder/coderd/database/databasefake"
+)
+
+func TestRecording(t *testing.T) {
+	t.Parallel()
+
+	rec := database.NewRecording(databasefake.New())
+	ctx := context.Background()
+
+	_, err := rec.InsertUser(ctx, database.InsertUserParams{
+		ID:             uuid.New(),
+		Username:       "coder",
+		HashedPassword: []byte("secret"),
+		RBACRoles:      []string{},
+	})
+	require.NoError(t, err)
+	rec.Reset()
+
+	err = rec.InTx(func(tx database.Store) error {
+		_, err := tx.GetUserByEmailOrUsername(ctx, database.GetUserByEmailOrUsernameParams{Username: "coder"})
+		return err
+	})
+	require.NoError(t, err)
+	_, err = rec.GetUserByID(ctx, uuid.Nil)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	calls := rec.Recorded()
+	require.Equal(t, []string{"InTx", "GetUserByEmailOrUsername", "GetUserByID"}, rec.Methods())
+	require.True(t, calls[1].InTx)
+	require.Contains(t, calls[1].Args, `Username="coder"`)
+	require.False(t, calls[2].InTx)
+	require.ErrorIs(t, calls[2].Err, sql.ErrNoRows)
+}
+
+func TestRecordingRedactsArgs(t *testing.T) {
+	t.Parallel()
+
+	rec := database.NewRecording(databasefake.New())
+	_, err := rec.InsertUser(context.Background(), database.InsertUserParams{
+		ID:             uuid.New(),
+		Username:       "coder",
+		HashedPassword: []byte("secret"),
+		RBACRoles:      []string{},
+	})
+	require.NoError(t, err)
+	calls := rec.Recorded()
+	require.Len(t, calls, 1)
+	require.Contains(t, calls[0].Args, "HashedPassword=<redacted>")
+}
@@ -0,0 +1,226 @@
+package database
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+const (
+	// redactedValue replaces the value of redacted fields.
+	redactedValue = "<redacted>"
+	// maxSummaryValueLength truncates long argument values in summaries.
+	maxSummaryValueLength = 64
+)
+
+var (
+	redactedFieldsMu sync.RWMutex
+	// redactedFields names argument struct fields that hold credentials
+	// or user secrets.
+	redactedFields = map[string]bool{
+		"AuthToken":         true,
+		"HashedPassword":    true,
+		"HashedSecret":      true,
+		"JWT":               true,
+		"OAuthAccessToken":  true,
+		"OAuthRefreshToken": true,
+		"PrivateKey":        true,
+		"ProvisionerState":  true,
+		"SourceValue":       true,
+	}
+)
+
+// RedactField registers a struct field name whose value is hidden wherever
+// Store arguments are summarized, such as in errors from
+// AnnotateErrorArgs. Fields holding credentials are registered by default.
+func RedactField(name string) {
+	redactedFieldsMu.Lock()
+	defer redactedFieldsMu.Unlock()
+	redactedFields[name] = true
+}
+
+func isRedactedField(name string) bool {
+	redactedFieldsMu.RLock()
+	defer redactedFieldsMu.RUnlock()
+	return redactedFields[name]
+}
+
+// summarizeArgs formats the arguments of a Store call for humans. Struct
+// arguments are expanded to their fields, registered fields are redacted,
+// and long values are truncated.
+func summarizeArgs(args []interface{}) string {
+	var parts []string
+	for i, arg := range args {
+		v := reflect.ValueOf(arg)
+		for v.Kind() == reflect.Pointer && !v.IsNil() {
+			v = v.Elem()
+		}
+		if v.Kind() != reflect.Struct || isSummaryLeaf(v) {
+			parts = append(parts, fmt.Sprintf("arg%d=%s", i, summarizeValue(arg)))
+			continue
+		}
+		for j := 0; j < v.NumField(); j++ {
+			field := v.Type().Field(j)
+			if !field.IsExported() {
+				continue
+			}
+			value := redactedValue
+			if !isRedactedField(field.Name) {
+				value = summarizeValue(v.Field(j).Interface())
+			}
+			parts = append(parts, fmt.Sprintf("%s=%s", field.Name, value))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// isSummaryLeaf reports whether a struct should be formatted as a single
+// value rather than expanded, e.g. time.Time or sql.NullString.
+func isSummaryLeaf(v reflect.Value) bool {
+	switch v.Interface().(type) {
+	case fmt.Stringer, driver.Valuer:
+		return true
+	}
+	return false
+}
+
+func summarizeValue(value interface{}) string {
+	if valuer, ok := value.(driver.Valuer); ok {
+		if _, isStringer := value.(fmt.Stringer); !isStringer {
+			v, err := valuer.Value()
+			if err == nil {
+				value = v
+			}
+		}
+	}
+	var s string
+	switch v := value.(type) {
+	case nil:
+		s = "NULL"
+	case []byte:
+		s = fmt.Sprintf("<%d bytes>", len(v))
+	case string:
+		s = fmt.Sprintf("%q", v)
+	case fmt.Stringer:
+		s = v.String()
+	default:
+		s = fmt.Sprintf("%v", v)
+	}
+	if len(s) > maxSummaryValueLength {
+		s = s[:maxSummaryValueLength] + "..."
+	}
+	return s
+}
+
+// redactQueryText replaces the literals in a SQL statement with "?" so query
+// text read from the server, which may embed user data or secrets sent over
+// the simple query protocol, can be shown to operators. String, escape
+// string, dollar-quoted and numeric literals are replaced; identifiers,
+// parameters such as $1 and comments, including the "-- name:" tag, are
+// kept.
+func redactQueryText(query string) string {
+	var b strings.Builder
+	b.Grow(len(query))
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case strings.HasPrefix(query[i:], "--"):
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				end = len(query) - i
+			}
+			b.WriteString(query[i : i+end])
+			i += end
+		case strings.HasPrefix(query[i:], "/*"):
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				end = len(query) - i - 4
+			}
+			b.WriteString(query[i : i+end+4])
+			i += end + 4
+		case c == '\'':
+			b.WriteByte('?')
+			i = skipStringLiteral(query, i, false)
+		case c == '"':
+			end := strings.IndexByte(query[i+1:], '"')
+			if end < 0 {
+				end = len(query) - i - 2
+			}
+			b.WriteString(query[i : i+end+2])
+			i += end + 2
+		case c == '$':
+			j := i + 1
+			for j < len(query) && isIdentByte(query[j]) && (query[j] < '0' || query[j] > '9' || j > i+1) {
+				j++
+			}
+			if j < len(query) && query[j] == '$' {
+				// Dollar-quoted string: $$...$$ or $tag$...$tag$.
+				tag := query[i : j+1]
+				end := strings.Index(query[j+1:], tag)
+				b.WriteByte('?')
+				if end < 0 {
+					i = len(query)
+				} else {
+					i = j + 1 + end + len(tag)
+				}
+				continue
+			}
+			// A parameter such as $1.
+			j = i + 1
+			for j < len(query) && query[j] >= '0' && query[j] <= '9' {
+				j++
+			}
+			b.WriteString(query[i:j])
+			i = j
+		case c >= '0' && c <= '9', c == '.' && i+1 < len(query) && query[i+1] >= '0' && query[i+1] <= '9':
+			j := i
+			for j < len(query) && (isIdentByte(query[j]) || query[j] == '.' ||
+				((query[j] == '+' || query[j] == '-') && (query[j-1] == 'e' || query[j-1] == 'E'))) {
+				j++
+			}
+			b.WriteByte('?')
+			i = j
+		case isIdentByte(c):
+			j := i
+			for j < len(query) && (isIdentByte(query[j]) || query[j] == '$') {
+				j++
+			}
+			if j-i == 1 && (c == 'E' || c == 'e') && j < len(query) && query[j] == '\'' {
+				b.WriteByte('?')
+				i = skipStringLiteral(query, j, true)
+				continue
+			}
+			b.WriteString(query[i:j])
+			i = j
+		default:
+			b.WriteByte(c)
+			i++
+		}
+	}
+	return b.String()
+}
+
+// skipStringLiteral returns the index just past the string literal whose
+// opening quote is at start. Doubled quotes are always escapes, and
+// backslashes are too in escape strings.
+func skipStringLiteral(query string, start int, backslashEscapes bool) int {
+	for i := start + 1; i < len(query); i++ {
+		switch {
+		case backslashEscapes && query[i] == '\\':
+			i++
+		case query[i] == '\'':
+			if i+1 < len(query) && query[i+1] == '\'' {
+				i++
+				continue
+			}
+			return i + 1
+		}
+	}
+	return len(query)
+}
+
+func isIdentByte(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c >= 0x80
+}
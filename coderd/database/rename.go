@@ -0,0 +1,46 @@
+package database
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"golang.org/x/xerrors"
+)
+
+// ErrNameTaken is matched by errors from renames whose new name is already
+// used by another live resource in the same scope.
+var ErrNameTaken = xerrors.New("name taken")
+
+type renameQuerier interface {
+	// RenameWorkspace renames the workspace unless it is deleted, in which
+	// case it returns sql.ErrNoRows. The name is checked by the unique
+	// index on the owner's live workspaces as part of the update, so no
+	// separate check can race with a concurrent rename or create: if
+	// another of the owner's workspaces has the name, ignoring case, it
+	// returns an error matching ErrNameTaken.
+	RenameWorkspace(ctx context.Context, id uuid.UUID, newName string) error
+}
+
+func (q *sqlQuerier) RenameWorkspace(ctx context.Context, id uuid.UUID, newName string) error {
+	const query = `-- name: RenameWorkspace :one
+	UPDATE
+		workspaces
+	SET
+		name = $2
+	WHERE
+		id = $1
+		AND deleted = false
+	RETURNING
+		id
+	`
+
+	var renamed uuid.UUID
+	err := q.db.GetContext(ctx, &renamed, query, id, newName)
+	if IsUniqueViolation(err, UniqueWorkspacesOwnerIDLowerIndex) {
+		return xerrors.Errorf("rename workspace to %q: %w", newName, ErrNameTaken)
+	}
+	if err != nil {
+		return xerrors.Errorf("rename workspace: %w", err)
+	}
+	return nil
+}
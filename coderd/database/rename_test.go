@@ -0,0 +1,74 @@
+//go:build linux
+
+package database_test
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/coder/coder/coderd/database"
+	"github.com/coder/coder/coderd/database/dbtestutil"
+)
+
+func TestRenameWorkspace(t *testing.T) {
+	t.Parallel()
+
+	db, _ := dbtestutil.NewDB(t)
+	ctx := context.Background()
+	user, org, template := insertTemplate(t, db)
+	insert := func(name string) database.Workspace {
+		workspace, err := db.InsertWorkspace(ctx, database.InsertWorkspaceParams{
+			ID:             uuid.New(),
+			CreatedAt:      database.Now(),
+			UpdatedAt:      database.Now(),
+			OwnerID:        user.ID,
+			OrganizationID: org.ID,
+			TemplateID:     template.ID,
+			Name:           name,
+		})
+		require.NoError(t, err)
+		return workspace
+	}
+	first, second := insert("first"), insert("second")
+
+	// Both renames pass any check made before either commits, so only the
+	// unique index can tell them apart.
+	var (
+		wg   sync.WaitGroup
+		errs [2]error
+	)
+	for i, workspace := range []database.Workspace{first, second} {
+		i, workspace := i, workspace
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = db.RenameWorkspace(ctx, workspace.ID, "renamed")
+		}()
+	}
+	wg.Wait()
+	succeeded := 0
+	for _, err := range errs {
+		if err == nil {
+			succeeded++
+			continue
+		}
+		require.ErrorIs(t, err, database.ErrNameTaken)
+	}
+	require.Equal(t, 1, succeeded, "exactly one rename succeeds")
+
+	err := db.RenameWorkspace(ctx, first.ID, "first-again")
+	require.NoError(t, err)
+	workspace, err := db.GetWorkspaceByID(ctx, first.ID)
+	require.NoError(t, err)
+	require.Equal(t, "first-again", workspace.Name)
+	err = db.RenameWorkspace(ctx, second.ID, "First-Again")
+	require.ErrorIs(t, err, database.ErrNameTaken, "names are unique ignoring case")
+
+	err = db.RenameWorkspace(ctx, uuid.New(), "missing")
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
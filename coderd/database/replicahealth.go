@@ -0,0 +1,193 @@
+package database
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"cdr.dev/slog"
+)
+
+// ReplicaProbeOptions configures a ReplicaProber.
+type ReplicaProbeOptions struct {
+	// Interval is how often every replica is probed.
+	Interval time.Duration
+	// MaxLag is the replication lag beyond which a replica is ejected.
+	MaxLag time.Duration
+	// RecoveryWindow is how long an ejected replica must keep passing
+	// probes before it is re-admitted, so a replica hovering around MaxLag
+	// does not flap in and out of rotation. Zero re-admits on the first
+	// passing probe.
+	RecoveryWindow time.Duration
+}
+
+// ReplicaHealth is the health of one replica as of its last probe.
+type ReplicaHealth struct {
+	Name    string `json:"name"`
+	Healthy bool   `json:"healthy"`
+	// Lag is the replication lag from the last probe that measured it.
+	Lag time.Duration `json:"lag"`
+	// Error is why the last probe failed, or empty if it passed.
+	Error     string    `json:"error,omitempty"`
+	LastProbe time.Time `json:"last_probe"`
+	// EjectedAt is when the replica was last ejected, or zero if it never
+	// was.
+	EjectedAt time.Time `json:"ejected_at"`
+}
+
+type replicaState struct {
+	ReplicaHealth
+	// passingSince is when an ejected replica started passing probes
+	// again, or zero if it has not.
+	passingSince time.Time
+}
+
+// ReplicaProber periodically pings each replica and checks its
+// ReplicationLag, ejecting replicas that fail or lag beyond MaxLag and
+// re-admitting them once they have passed for RecoveryWindow. Replicas
+// start out healthy and are first probed as soon as the prober starts.
+type ReplicaProber struct {
+	replicas map[string]Store
+	logger   slog.Logger
+	opts     ReplicaProbeOptions
+
+	mu     sync.Mutex
+	health map[string]replicaState
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewReplicaProber starts probing replicas, keyed by name, until ctx is
+// done or Close is called.
+func NewReplicaProber(ctx context.Context, replicas map[string]Store, logger slog.Logger, opts ReplicaProbeOptions) *ReplicaProber {
+	ctx, cancel := context.WithCancel(ctx)
+	p := &ReplicaProber{
+		replicas: replicas,
+		logger:   logger,
+		opts:     opts,
+		health:   make(map[string]replicaState, len(replicas)),
+		cancel:   cancel,
+		done:     make(chan struct{}),
+	}
+	for name := range replicas {
+		p.health[name] = replicaState{ReplicaHealth: ReplicaHealth{Name: name, Healthy: true}}
+	}
+	go func() {
+		defer close(p.done)
+		p.run(ctx)
+	}()
+	return p
+}
+
+// Health returns the state of every replica, sorted by name.
+func (p *ReplicaProber) Health() []ReplicaHealth {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	health := make([]ReplicaHealth, 0, len(p.health))
+	for _, h := range p.health {
+		health = append(health, h.ReplicaHealth)
+	}
+	sort.Slice(health, func(i, j int) bool { return health[i].Name < health[j].Name })
+	return health
+}
+
+// Healthy returns the replicas currently in rotation, by name.
+func (p *ReplicaProber) Healthy() map[string]Store {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	healthy := make(map[string]Store, len(p.replicas))
+	for name, store := range p.replicas {
+		if p.health[name].Healthy {
+			healthy[name] = store
+		}
+	}
+	return healthy
+}
+
+// Probe checks every replica once and updates its health.
+func (p *ReplicaProber) Probe(ctx context.Context) {
+	var wg sync.WaitGroup
+	for name, store := range p.replicas {
+		name, store := name, store
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			lag, err := probeReplica(ctx, store)
+			p.record(name, lag, err)
+		}()
+	}
+	wg.Wait()
+}
+
+// Close stops probing.
+func (p *ReplicaProber) Close() {
+	p.cancel()
+	<-p.done
+}
+
+// probeTimeout bounds a single probe so a hung replica is ejected instead of
+// stalling every later probe.
+const probeTimeout = 10 * time.Second
+
+func probeReplica(ctx context.Context, store Store) (time.Duration, error) {
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+	_, err := store.Ping(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return store.ReplicationLag(ctx)
+}
+
+func (p *ReplicaProber) record(name string, lag time.Duration, err error) {
+	now := time.Now()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	h := p.health[name]
+	h.LastProbe = now
+	h.Error = ""
+	if err != nil {
+		h.Error = err.Error()
+	} else {
+		h.Lag = lag
+	}
+	passing := err == nil && lag <= p.opts.MaxLag
+
+	switch {
+	case !passing:
+		h.passingSince = time.Time{}
+		if h.Healthy {
+			h.Healthy = false
+			h.EjectedAt = now
+			p.logger.Warn(context.Background(), "ejecting database replica",
+				slog.F("replica", name), slog.F("lag", h.Lag), slog.Error(err))
+		}
+	case !h.Healthy:
+		if h.passingSince.IsZero() {
+			h.passingSince = now
+		}
+		if now.Sub(h.passingSince) >= p.opts.RecoveryWindow {
+			h.Healthy = true
+			h.passingSince = time.Time{}
+			p.logger.Info(context.Background(), "re-admitting database replica",
+				slog.F("replica", name), slog.F("lag", h.Lag))
+		}
+	}
+	p.health[name] = h
+}
+
+func (p *ReplicaProber) run(ctx context.Context) {
+	ticker := time.NewTicker(p.opts.Interval)
+	defer ticker.Stop()
+	for {
+		p.Probe(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
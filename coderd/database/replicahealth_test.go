@@ -0,0 +1,88 @@
+package database_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"cdr.dev/slog/sloggers/slogtest"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/xerrors"
+
+	"github.com/coder/coder/coderd/database"
+	"github.com/coder/coder/coderd/database/databasefake"
+)
+
+// fakeReplica reports a settable replication lag and ping error.
+type fakeReplica struct {
+	database.Store
+	mu      sync.Mutex
+	lag     time.Duration
+	pingErr error
+}
+
+func (r *fakeReplica) set(lag time.Duration, pingErr error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lag, r.pingErr = lag, pingErr
+}
+
+func (r *fakeReplica) Ping(context.Context) (time.Duration, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return 0, r.pingErr
+}
+
+func (r *fakeReplica) ReplicationLag(context.Context) (time.Duration, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.lag, nil
+}
+
+func TestReplicaProber(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	steady := &fakeReplica{Store: databasefake.New()}
+	lagging := &fakeReplica{Store: databasefake.New()}
+	// Probe manually rather than waiting for the interval.
+	prober := database.NewReplicaProber(ctx, map[string]database.Store{
+		"steady":  steady,
+		"lagging": lagging,
+	}, slogtest.Make(t, nil), database.ReplicaProbeOptions{
+		Interval:       time.Hour,
+		MaxLag:         time.Second,
+		RecoveryWindow: 50 * time.Millisecond,
+	})
+	t.Cleanup(prober.Close)
+
+	lagging.set(time.Minute, nil)
+	prober.Probe(ctx)
+	require.Equal(t, map[string]database.Store{"steady": steady}, prober.Healthy())
+	health := prober.Health()
+	require.Len(t, health, 2)
+	require.Equal(t, "lagging", health[0].Name)
+	require.False(t, health[0].Healthy)
+	require.Equal(t, time.Minute, health[0].Lag)
+	require.False(t, health[0].EjectedAt.IsZero())
+	require.True(t, health[1].Healthy)
+
+	lagging.set(0, nil)
+	prober.Probe(ctx)
+	require.NotContains(t, prober.Healthy(), "lagging", "recovery window has not passed")
+
+	time.Sleep(60 * time.Millisecond)
+	prober.Probe(ctx)
+	require.Contains(t, prober.Healthy(), "lagging", "re-admitted after the recovery window")
+
+	steady.set(0, xerrors.New("connection refused"))
+	prober.Probe(ctx)
+	require.NotContains(t, prober.Healthy(), "steady", "failing replicas are ejected")
+	health = prober.Health()
+	require.Equal(t, "connection refused", health[1].Error)
+
+	lagging.set(time.Minute, nil)
+	prober.Probe(ctx)
+	require.Empty(t, prober.Healthy())
+}
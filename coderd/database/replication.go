@@ -0,0 +1,42 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"golang.org/x/xerrors"
+)
+
+type replicationQuerier interface {
+	// ReplicationLag returns how far behind the primary a replica is. It
+	// must be executed against a replica; ErrNotReplica is returned if the
+	// database is not in recovery.
+	ReplicationLag(ctx context.Context) (time.Duration, error)
+}
+
+func (q *sqlQuerier) ReplicationLag(ctx context.Context) (time.Duration, error) {
+	// When everything received has also been replayed, the replica is caught
+	// up even if the last replayed transaction is old (e.g. an idle primary),
+	// so the timestamp difference is only used while WAL is outstanding.
+	const query = `-- name: ReplicationLag :one
+	SELECT
+		pg_is_in_recovery(),
+		CASE
+			WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+			ELSE COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()), 0)
+		END::float8
+	`
+
+	var (
+		inRecovery bool
+		seconds    float64
+	)
+	err := q.db.QueryRowContext(ctx, query).Scan(&inRecovery, &seconds)
+	if err != nil {
+		return 0, xerrors.Errorf("get replication lag: %w", err)
+	}
+	if !inRecovery {
+		return 0, ErrNotReplica
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
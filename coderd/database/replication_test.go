@@ -0,0 +1,28 @@
+//go:build linux
+
+package database_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/coder/coder/coderd/database"
+	"github.com/coder/coder/coderd/database/migrations"
+)
+
+func TestReplicationLag(t *testing.T) {
+	t.Parallel()
+	if testing.Short() {
+		t.SkipNow()
+	}
+
+	sqlDB := testSQLDB(t)
+	err := migrations.Up(sqlDB)
+	require.NoError(t, err, "migrations")
+
+	db := database.New(sqlDB)
+	_, err = db.ReplicationLag(context.Background())
+	require.ErrorIs(t, err, database.ErrNotReplica)
+}
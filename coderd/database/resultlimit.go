@@ -0,0 +1,109 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+
+	"github.com/jmoiron/sqlx"
+	"golang.org/x/xerrors"
+)
+
+// WithMaxResultRows makes multi-row reads fail with ErrResultTooLarge once
+// a result set exceeds max rows, instead of scanning it all into memory. It
+// guards replicas against a missing WHERE clause or a pathological filter.
+// The cap applies to the custom reads, and to CopyOut and
+// StreamCompressed, which stop after writing max rows; generated queries
+// are not capped. Writes that return rows are never capped, since failing
+// them after the write is committed would lose their result. Zero, the
+// default, means no limit.
+func WithMaxResultRows(max int) Option {
+	return func(o *options) {
+		o.maxResultRows = max
+	}
+}
+
+// limitDB caps the rows scanned by SelectContext for reads.
+type limitDB struct {
+	DBTX
+	max int
+}
+
+func (l *limitDB) SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	if !isReadMethod(queryMethod(query)) {
+		return l.DBTX.SelectContext(ctx, dest, query, args...)
+	}
+	rows, err := l.DBTX.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	limited := &limitedRows{Rows: rows, max: l.max}
+	if isScannableSlice(dest) {
+		err = scanColumn(limited, dest)
+	} else {
+		err = sqlx.StructScan(limited, dest)
+	}
+	if limited.exceeded {
+		return ErrResultTooLarge
+	}
+	return err
+}
+
+// limitedRows stops iterating after max rows and records whether there were
+// more.
+type limitedRows struct {
+	*sql.Rows
+	max      int
+	n        int
+	exceeded bool
+}
+
+func (r *limitedRows) Next() bool {
+	if !r.Rows.Next() {
+		return false
+	}
+	r.n++
+	if r.n > r.max {
+		r.exceeded = true
+		return false
+	}
+	return true
+}
+
+var scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+
+// isScannableSlice reports whether dest points to a slice of single-column
+// values rather than structs, following sqlx's rules: a type is scannable if
+// it implements sql.Scanner, is not a struct, or has no exported fields.
+func isScannableSlice(dest interface{}) bool {
+	elem := reflect.TypeOf(dest).Elem().Elem()
+	for elem.Kind() == reflect.Ptr {
+		elem = elem.Elem()
+	}
+	if reflect.PtrTo(elem).Implements(scannerType) || elem.Kind() != reflect.Struct {
+		return true
+	}
+	for i := 0; i < elem.NumField(); i++ {
+		if elem.Field(i).IsExported() {
+			return false
+		}
+	}
+	return true
+}
+
+// scanColumn scans a single-column result into the slice dest points to.
+func scanColumn(rows *limitedRows, dest interface{}) error {
+	slice := reflect.ValueOf(dest).Elem()
+	elem := slice.Type().Elem()
+	for rows.Next() {
+		value := reflect.New(elem)
+		err := rows.Scan(value.Interface())
+		if err != nil {
+			return xerrors.Errorf("scan: %w", err)
+		}
+		slice.Set(reflect.Append(slice, value.Elem()))
+	}
+	return rows.Err()
+}
@@ -0,0 +1,85 @@
+package database_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/coder/coder/coderd/database"
+)
+
+func TestWithMaxResultRows(t *testing.T) {
+	t.Parallel()
+
+	newDB := func(t *testing.T, max int, rows func(string) ([]string, [][]driver.Value)) database.Store {
+		t.Helper()
+		sqlDB, connector := newRecordingDB()
+		t.Cleanup(func() { _ = sqlDB.Close() })
+		connector.rows = rows
+		return database.New(sqlDB, database.WithMaxResultRows(max))
+	}
+	ids := func(string) ([]string, [][]driver.Value) {
+		return []string{"id"}, [][]driver.Value{
+			{uuid.NewString()}, {uuid.NewString()}, {uuid.NewString()},
+		}
+	}
+	values := func(query string) ([]string, [][]driver.Value) {
+		if strings.Contains(query, "pg_index") {
+			return []string{"exists"}, [][]driver.Value{{false}}
+		}
+		return []string{"value"}, [][]driver.Value{{"a"}, {"b"}, {"c"}}
+	}
+	workspaces := func(string) ([]string, [][]driver.Value) {
+		columns := []string{"id", "created_at", "updated_at", "owner_id", "organization_id", "template_id", "deleted", "name", "autostart_schedule", "ttl", "last_used_at"}
+		row := func() []driver.Value {
+			return []driver.Value{uuid.NewString(), time.Now(), time.Now(), uuid.NewString(), uuid.NewString(), uuid.NewString(), false, "ws", nil, nil, time.Now()}
+		}
+		return columns, [][]driver.Value{row(), row(), row()}
+	}
+
+	t.Run("Column", func(t *testing.T) {
+		t.Parallel()
+		_, err := newDB(t, 2, values).GetDistinctValues(context.Background(), "audit_logs", "action")
+		require.ErrorIs(t, err, database.ErrResultTooLarge)
+
+		found, err := newDB(t, 3, values).GetDistinctValues(context.Background(), "audit_logs", "action")
+		require.NoError(t, err)
+		require.Len(t, found, 3)
+	})
+
+	t.Run("Write", func(t *testing.T) {
+		t.Parallel()
+		// The write has already happened by the time the rows are scanned,
+		// so a write returning more rows than the cap still returns them.
+		deleted, err := newDB(t, 2, ids).SoftDeleteUsersByOrg(context.Background(), uuid.New())
+		require.NoError(t, err)
+		require.Len(t, deleted, 3)
+
+		marked, err := newDB(t, 2, workspaces).MarkWorkspacesInactive(context.Background(), time.Now())
+		require.NoError(t, err)
+		require.Len(t, marked.Rows, 3)
+	})
+
+	t.Run("Struct", func(t *testing.T) {
+		t.Parallel()
+		arg := database.GetWorkspacesModifiedSinceParams{Limit: 10}
+		_, err := newDB(t, 2, workspaces).GetWorkspacesModifiedSince(context.Background(), arg)
+		require.ErrorIs(t, err, database.ErrResultTooLarge)
+
+		found, err := newDB(t, 3, workspaces).GetWorkspacesModifiedSince(context.Background(), arg)
+		require.NoError(t, err)
+		require.Len(t, found, 3)
+	})
+
+	t.Run("Unlimited", func(t *testing.T) {
+		t.Parallel()
+		deleted, err := newDB(t, 0, ids).SoftDeleteUsersByOrg(context.Background(), uuid.New())
+		require.NoError(t, err)
+		require.Len(t, deleted, 3)
+	})
+}
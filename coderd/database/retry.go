@@ -0,0 +1,23 @@
+package database
+
+// WithTxRetries makes InTx and InTxOpts run the whole transaction again,
+// up to retries more times, when it fails with an error the retryable
+// classifier accepts, such as a serialization failure under
+// sql.LevelSerializable. The callback must be safe to run more than once,
+// so it must not have side effects outside the transaction. Nested calls
+// are part of the outer transaction and are not retried on their own.
+func WithTxRetries(retries int) Option {
+	return func(o *options) {
+		o.txRetries = retries
+	}
+}
+
+// WithRetryableClassifier replaces IsRetryableError as the predicate that
+// decides which transaction errors WithTxRetries retries, for
+// Postgres-compatible databases that report retryable conditions
+// differently, such as CockroachDB.
+func WithRetryableClassifier(retryable func(error) bool) Option {
+	return func(o *options) {
+		o.retryable = retryable
+	}
+}
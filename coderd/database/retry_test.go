@@ -0,0 +1,89 @@
+package database_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/xerrors"
+
+	"github.com/coder/coder/coderd/database"
+)
+
+func TestTxRetries(t *testing.T) {
+	t.Parallel()
+
+	serialization := &pq.Error{Code: "40001"}
+	require.True(t, database.IsRetryableError(xerrors.Errorf("wrapped: %w", serialization)))
+	require.True(t, database.IsRetryableError(&pq.Error{Code: "40P01"}))
+	require.False(t, database.IsRetryableError(&pq.Error{Code: "23505"}))
+	require.False(t, database.IsRetryableError(xerrors.New("other")))
+
+	// failing returns a callback that fails with err the first n times.
+	failing := func(n int, err error) (func(database.Store) error, *int) {
+		attempts := 0
+		return func(database.Store) error {
+			attempts++
+			if attempts <= n {
+				return err
+			}
+			return nil
+		}, &attempts
+	}
+	open := func(t *testing.T, opts ...database.Option) database.Store {
+		sqlDB, _ := newRecordingDB()
+		t.Cleanup(func() { _ = sqlDB.Close() })
+		return database.New(sqlDB, opts...)
+	}
+
+	t.Run("Default", func(t *testing.T) {
+		t.Parallel()
+		db := open(t, database.WithTxRetries(2))
+		fn, attempts := failing(2, serialization)
+		require.NoError(t, db.InTx(fn))
+		require.Equal(t, 3, *attempts)
+
+		fn, attempts = failing(3, serialization)
+		require.ErrorIs(t, db.InTx(fn), serialization, "retries are bounded")
+		require.Equal(t, 3, *attempts)
+
+		fn, attempts = failing(1, &pq.Error{Code: "23505"})
+		require.Error(t, db.InTx(fn))
+		require.Equal(t, 1, *attempts, "other errors are not retried")
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		t.Parallel()
+		fn, attempts := failing(1, serialization)
+		require.ErrorIs(t, open(t).InTx(fn), serialization)
+		require.Equal(t, 1, *attempts)
+	})
+
+	t.Run("CustomClassifier", func(t *testing.T) {
+		t.Parallel()
+		// CockroachDB asks clients to retry with this message on 40001.
+		restart := xerrors.New("restart transaction: TransactionRetryWithProtoRefreshError")
+		db := open(t, database.WithTxRetries(1), database.WithRetryableClassifier(func(err error) bool {
+			return xerrors.Is(err, restart)
+		}))
+		fn, attempts := failing(1, restart)
+		require.NoError(t, db.InTx(fn))
+		require.Equal(t, 2, *attempts)
+
+		fn, attempts = failing(1, serialization)
+		require.ErrorIs(t, db.InTx(fn), serialization, "the classifier replaces the default")
+		require.Equal(t, 1, *attempts)
+	})
+
+	t.Run("Nested", func(t *testing.T) {
+		t.Parallel()
+		db := open(t, database.WithTxRetries(1))
+		fn, attempts := failing(1, serialization)
+		err := db.InTx(func(tx database.Store) error {
+			return tx.InTxOpts(context.Background(), database.TxOptions{}, fn)
+		})
+		require.NoError(t, err, "the outer transaction is retried")
+		require.Equal(t, 2, *attempts)
+	})
+}
@@ -0,0 +1,145 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"golang.org/x/xerrors"
+)
+
+// returningQuerier has variants of generated :exec updates that return the
+// updated row, so callers that cache rows by updated_at can store the
+// authoritative row without a follow-up read. Updates that do not take an
+// updated_at argument set it to the database clock so every write changes
+// it.
+//
+// To add one for another update, copy its SET and WHERE clauses, bump
+// updated_at and end with RETURNING *.
+type returningQuerier interface {
+	UpdateUserHashedPasswordReturning(ctx context.Context, arg UpdateUserHashedPasswordParams) (User, error)
+	UpdateWorkspaceAutostartReturning(ctx context.Context, arg UpdateWorkspaceAutostartParams) (Workspace, error)
+	UpdateWorkspaceTTLReturning(ctx context.Context, arg UpdateWorkspaceTTLParams) (Workspace, error)
+	UpdateTemplateActiveVersionByIDReturning(ctx context.Context, arg UpdateTemplateActiveVersionByIDParams) (Template, error)
+	// MarkWorkspacesInactive sets dormant_at on workspaces with an
+	// autostart schedule that have not been used since olderThan, so
+	// abandoned workspaces stop being started, and returns them ordered by
+	// ID. The schedule itself is kept, and the next
+	// UpdateWorkspaceLastUsedAt makes the workspace active again.
+	MarkWorkspacesInactive(ctx context.Context, olderThan time.Time) (WriteResult[Workspace], error)
+}
+
+// WriteResult is the outcome of a write that affects many rows: how many
+// it affected and their new state, read in the same statement so
+// reconcilers can emit events without a follow-up read.
+type WriteResult[T any] struct {
+	Affected int64
+	Rows     []T
+}
+
+func (q *sqlQuerier) UpdateUserHashedPasswordReturning(ctx context.Context, arg UpdateUserHashedPasswordParams) (User, error) {
+	const query = `-- name: UpdateUserHashedPasswordReturning :one
+	UPDATE
+		users
+	SET
+		hashed_password = $2,
+		updated_at = now()
+	WHERE
+		id = $1
+	RETURNING *
+	`
+
+	var user User
+	err := q.db.GetContext(ctx, &user, query, arg.ID, arg.HashedPassword)
+	if err != nil {
+		return User{}, xerrors.Errorf("update user hashed password: %w", err)
+	}
+	return user, nil
+}
+
+func (q *sqlQuerier) UpdateWorkspaceAutostartReturning(ctx context.Context, arg UpdateWorkspaceAutostartParams) (Workspace, error) {
+	const query = `-- name: UpdateWorkspaceAutostartReturning :one
+	UPDATE
+		workspaces
+	SET
+		autostart_schedule = $2,
+		updated_at = now()
+	WHERE
+		id = $1
+	RETURNING *
+	`
+
+	var workspace Workspace
+	err := q.db.GetContext(ctx, &workspace, query, arg.ID, arg.AutostartSchedule)
+	if err != nil {
+		return Workspace{}, xerrors.Errorf("update workspace autostart: %w", err)
+	}
+	return workspace, nil
+}
+
+func (q *sqlQuerier) UpdateWorkspaceTTLReturning(ctx context.Context, arg UpdateWorkspaceTTLParams) (Workspace, error) {
+	const query = `-- name: UpdateWorkspaceTTLReturning :one
+	UPDATE
+		workspaces
+	SET
+		ttl = $2,
+		updated_at = now()
+	WHERE
+		id = $1
+	RETURNING *
+	`
+
+	var workspace Workspace
+	err := q.db.GetContext(ctx, &workspace, query, arg.ID, arg.Ttl)
+	if err != nil {
+		return Workspace{}, xerrors.Errorf("update workspace ttl: %w", err)
+	}
+	return workspace, nil
+}
+
+func (q *sqlQuerier) UpdateTemplateActiveVersionByIDReturning(ctx context.Context, arg UpdateTemplateActiveVersionByIDParams) (Template, error) {
+	const query = `-- name: UpdateTemplateActiveVersionByIDReturning :one
+	UPDATE
+		templates
+	SET
+		active_version_id = $2,
+		updated_at = $3
+	WHERE
+		id = $1
+	RETURNING *
+	`
+
+	var template Template
+	err := q.db.GetContext(ctx, &template, query, arg.ID, arg.ActiveVersionID, arg.UpdatedAt)
+	if err != nil {
+		return Template{}, xerrors.Errorf("update template active version: %w", err)
+	}
+	return template, nil
+}
+
+func (q *sqlQuerier) MarkWorkspacesInactive(ctx context.Context, olderThan time.Time) (WriteResult[Workspace], error) {
+	// RETURNING yields exactly the updated rows, so their number is the
+	// affected count.
+	const query = `-- name: MarkWorkspacesInactive :many
+	WITH updated AS (
+		UPDATE
+			workspaces
+		SET
+			dormant_at = now(),
+			updated_at = now()
+		WHERE
+			deleted = false
+			AND dormant_at IS NULL
+			AND autostart_schedule IS NOT NULL
+			AND last_used_at < $1
+		RETURNING *
+	)
+	SELECT * FROM updated ORDER BY id
+	`
+
+	var workspaces []Workspace
+	err := q.db.SelectContext(ctx, &workspaces, query, olderThan)
+	if err != nil {
+		return WriteResult[Workspace]{}, xerrors.Errorf("mark workspaces inactive: %w", err)
+	}
+	return WriteResult[Workspace]{Affected: int64(len(workspaces)), Rows: workspaces}, nil
+}
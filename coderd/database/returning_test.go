@@ -0,0 +1,154 @@
+//go:build linux
+
+package database_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/coder/coder/coderd/database"
+	"github.com/coder/coder/coderd/database/dbtestutil"
+)
+
+func TestUpdateReturning(t *testing.T) {
+	t.Parallel()
+
+	db, _ := dbtestutil.NewDB(t)
+	ctx := context.Background()
+
+	user, org, template := insertTemplate(t, db)
+	workspace, err := db.InsertWorkspace(ctx, database.InsertWorkspaceParams{
+		ID:             uuid.New(),
+		CreatedAt:      database.Now(),
+		UpdatedAt:      database.Now(),
+		OwnerID:        user.ID,
+		OrganizationID: org.ID,
+		TemplateID:     template.ID,
+		Name:           "workspace",
+	})
+	require.NoError(t, err)
+
+	updatedUser, err := db.UpdateUserHashedPasswordReturning(ctx, database.UpdateUserHashedPasswordParams{
+		ID:             user.ID,
+		HashedPassword: []byte("hashed"),
+	})
+	require.NoError(t, err)
+	require.Equal(t, []byte("hashed"), updatedUser.HashedPassword)
+	require.False(t, updatedUser.UpdatedAt.Before(user.UpdatedAt))
+	got, err := db.GetUserByID(ctx, user.ID)
+	require.NoError(t, err)
+	require.Equal(t, got, updatedUser)
+
+	updatedWorkspace, err := db.UpdateWorkspaceAutostartReturning(ctx, database.UpdateWorkspaceAutostartParams{
+		ID:                workspace.ID,
+		AutostartSchedule: sql.NullString{String: "CRON_TZ=UTC 0 9 * * 1-5", Valid: true},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "CRON_TZ=UTC 0 9 * * 1-5", updatedWorkspace.AutostartSchedule.String)
+	require.False(t, updatedWorkspace.UpdatedAt.Before(workspace.UpdatedAt))
+	updatedWorkspace, err = db.UpdateWorkspaceTTLReturning(ctx, database.UpdateWorkspaceTTLParams{
+		ID:  workspace.ID,
+		Ttl: sql.NullInt64{Int64: 3600, Valid: true},
+	})
+	require.NoError(t, err)
+	require.EqualValues(t, 3600, updatedWorkspace.Ttl.Int64)
+	gotWorkspace, err := db.GetWorkspaceByID(ctx, workspace.ID)
+	require.NoError(t, err)
+	require.Equal(t, gotWorkspace, updatedWorkspace)
+
+	activeVersion := uuid.New()
+	updatedTemplate, err := db.UpdateTemplateActiveVersionByIDReturning(ctx, database.UpdateTemplateActiveVersionByIDParams{
+		ID:              template.ID,
+		ActiveVersionID: activeVersion,
+		UpdatedAt:       database.Now(),
+	})
+	require.NoError(t, err)
+	require.Equal(t, activeVersion, updatedTemplate.ActiveVersionID)
+	gotTemplate, err := db.GetTemplateByID(ctx, template.ID)
+	require.NoError(t, err)
+	require.Equal(t, gotTemplate, updatedTemplate)
+
+	_, err = db.UpdateWorkspaceTTLReturning(ctx, database.UpdateWorkspaceTTLParams{ID: uuid.New()})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+// testUpdateReturning checks that each returning update hands back the row
+// a follow-up read would see, with updated_at moved forward.
+
+func TestMarkWorkspacesInactive(t *testing.T) {
+	t.Parallel()
+
+	db, _ := dbtestutil.NewDB(t)
+	ctx := context.Background()
+
+	user, org, template := insertTemplate(t, db)
+	now := database.Now()
+	cutoff := now.Add(-24 * time.Hour)
+	newWorkspace := func(name string, lastUsed time.Time, autostart, deleted bool) database.Workspace {
+		workspace, err := db.InsertWorkspace(ctx, database.InsertWorkspaceParams{
+			ID:             uuid.New(),
+			CreatedAt:      now,
+			UpdatedAt:      now,
+			OwnerID:        user.ID,
+			OrganizationID: org.ID,
+			TemplateID:     template.ID,
+			Name:           name,
+		})
+		require.NoError(t, err)
+		err = db.UpdateWorkspaceLastUsedAt(ctx, database.UpdateWorkspaceLastUsedAtParams{ID: workspace.ID, LastUsedAt: lastUsed})
+		require.NoError(t, err)
+		if autostart {
+			err = db.UpdateWorkspaceAutostart(ctx, database.UpdateWorkspaceAutostartParams{
+				ID:                workspace.ID,
+				AutostartSchedule: sql.NullString{String: "CRON_TZ=UTC 0 9 * * 1-5", Valid: true},
+			})
+			require.NoError(t, err)
+		}
+		if deleted {
+			err = db.UpdateWorkspaceDeletedByID(ctx, database.UpdateWorkspaceDeletedByIDParams{ID: workspace.ID, Deleted: true})
+			require.NoError(t, err)
+		}
+		return workspace
+	}
+	stale := []database.Workspace{
+		newWorkspace("stale-1", cutoff.Add(-time.Hour), true, false),
+		newWorkspace("stale-2", cutoff.Add(-48*time.Hour), true, false),
+	}
+	newWorkspace("fresh", now, true, false)
+	newWorkspace("manual", cutoff.Add(-time.Hour), false, false)
+	newWorkspace("deleted", cutoff.Add(-time.Hour), true, true)
+
+	result, err := db.MarkWorkspacesInactive(ctx, cutoff)
+	require.NoError(t, err)
+	require.EqualValues(t, len(result.Rows), result.Affected)
+	require.Len(t, result.Rows, len(stale))
+	ids := map[uuid.UUID]bool{}
+	for _, workspace := range result.Rows {
+		ids[workspace.ID] = true
+		require.True(t, workspace.DormantAt.Valid)
+		require.True(t, workspace.AutostartSchedule.Valid, "the schedule is kept")
+		got, err := db.GetWorkspaceByID(ctx, workspace.ID)
+		require.NoError(t, err)
+		require.Equal(t, got, workspace, "the returned row is the new state")
+	}
+	for _, workspace := range stale {
+		require.True(t, ids[workspace.ID], workspace.Name)
+	}
+
+	result, err = db.MarkWorkspacesInactive(ctx, cutoff)
+	require.NoError(t, err)
+	require.Zero(t, result.Affected, "already inactive")
+	require.Empty(t, result.Rows)
+
+	err = db.UpdateWorkspaceLastUsedAt(ctx, database.UpdateWorkspaceLastUsedAtParams{ID: stale[0].ID, LastUsedAt: now})
+	require.NoError(t, err)
+	got, err := db.GetWorkspaceByID(ctx, stale[0].ID)
+	require.NoError(t, err)
+	require.False(t, got.DormantAt.Valid, "using a workspace makes it active")
+	require.True(t, got.AutostartSchedule.Valid)
+}
@@ -0,0 +1,66 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+)
+
+// WithQueryRewriter rewrites every SQL statement before it is sent to the
+// driver. method is the Store method that issued the statement (taken from
+// the "-- name:" comment), or empty for statements without one.
+//
+// The rewriter applies to all statements, including those executed inside
+// transactions. It is intended for patching minor dialect differences on
+// Postgres-compatible databases; a rewriter that changes the columns or
+// parameters of a statement will break the generated scanning code, so use
+// it with care.
+func WithQueryRewriter(rewrite func(method, query string) string) Option {
+	return func(o *options) {
+		o.queryRewriter = rewrite
+	}
+}
+
+// queryMethod returns the method name from a query's "-- name: X :kind"
+// comment.
+func queryMethod(query string) string {
+	const prefix = "-- name: "
+	query = strings.TrimLeft(query, " \t\n")
+	if !strings.HasPrefix(query, prefix) {
+		return ""
+	}
+	return strings.SplitN(strings.TrimPrefix(query, prefix), " ", 2)[0]
+}
+
+type rewriteDB struct {
+	DBTX
+	rewrite func(method, query string) string
+}
+
+func (r *rewriteDB) apply(query string) string {
+	return r.rewrite(queryMethod(query), query)
+}
+
+func (r *rewriteDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return r.DBTX.ExecContext(ctx, r.apply(query), args...)
+}
+
+func (r *rewriteDB) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return r.DBTX.PrepareContext(ctx, r.apply(query))
+}
+
+func (r *rewriteDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return r.DBTX.QueryContext(ctx, r.apply(query), args...)
+}
+
+func (r *rewriteDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return r.DBTX.QueryRowContext(ctx, r.apply(query), args...)
+}
+
+func (r *rewriteDB) SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	return r.DBTX.SelectContext(ctx, dest, r.apply(query), args...)
+}
+
+func (r *rewriteDB) GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	return r.DBTX.GetContext(ctx, dest, r.apply(query), args...)
+}
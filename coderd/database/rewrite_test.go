@@ -0,0 +1,42 @@
+package database_test
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/coder/coder/coderd/database"
+)
+
+func TestQueryRewriter(t *testing.T) {
+	t.Parallel()
+
+	sqlDB, connector := newRecordingDB()
+	t.Cleanup(func() { _ = sqlDB.Close() })
+
+	var methods []string
+	db := database.New(sqlDB, database.WithQueryRewriter(func(method, query string) string {
+		methods = append(methods, method)
+		return strings.Replace(query, "FROM\n\tusers", "FROM\n\tusers_compat", 1)
+	}))
+
+	_, err := db.GetUserByID(context.Background(), uuid.New())
+	require.ErrorIs(t, err, sql.ErrNoRows)
+	err = db.InTx(func(tx database.Store) error {
+		_, err := tx.GetUserByID(context.Background(), uuid.New())
+		require.ErrorIs(t, err, sql.ErrNoRows)
+		return nil
+	})
+	require.NoError(t, err)
+
+	queries := connector.Queries()
+	require.Len(t, queries, 2)
+	for _, query := range queries {
+		require.Contains(t, query, "FROM\n\tusers_compat")
+	}
+	require.Equal(t, []string{"GetUserByID", "GetUserByID"}, methods)
+}
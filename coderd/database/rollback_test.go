@@ -0,0 +1,49 @@
+package database_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/xerrors"
+
+	"github.com/coder/coder/coderd/database"
+	"github.com/coder/coder/testutil"
+)
+
+func TestRollbackTimeout(t *testing.T) {
+	t.Parallel()
+
+	sqlDB, connector := newRecordingDB()
+	release := make(chan struct{})
+	connector.rollback = func() error {
+		<-release
+		return nil
+	}
+	t.Cleanup(func() {
+		close(release)
+		_ = sqlDB.Close()
+	})
+	db := database.New(sqlDB, database.WithRollbackTimeout(10*time.Millisecond))
+
+	errFail := xerrors.New("fail")
+	done := make(chan error, 1)
+	go func() {
+		done <- db.InTx(func(tx database.Store) error {
+			return errFail
+		})
+	}()
+	select {
+	case err := <-done:
+		require.ErrorIs(t, err, errFail)
+	case <-time.After(testutil.WaitShort):
+		t.Fatal("InTx blocked on a hung rollback")
+	}
+
+	// A committed transaction is unaffected by a slow rollback hook.
+	err := db.InTx(func(tx database.Store) error {
+		return tx.DeleteAPIKeyByID(context.Background(), "key")
+	})
+	require.NoError(t, err)
+}
@@ -0,0 +1,94 @@
+package database_test
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tabbed/pqtype"
+
+	"github.com/coder/coder/coderd/database"
+	"github.com/coder/coder/coderd/database/dbtestutil"
+)
+
+func TestGetConsistentRowCounts(t *testing.T) {
+	t.Parallel()
+
+	db, _ := dbtestutil.NewDB(t)
+	ctx := context.Background()
+
+	_, err := db.GetConsistentRowCounts(ctx, []string{"users", "users; DROP TABLE users"})
+	require.Error(t, err)
+
+	// Each write adds one user and one audit log in a transaction, so any
+	// consistent snapshot has as many of one as the other.
+	const writes = 50
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < writes; i++ {
+			err := db.InTx(func(tx database.Store) error {
+				id := uuid.New()
+				_, err := tx.InsertUser(ctx, database.InsertUserParams{
+					ID:        id,
+					Email:     id.String() + "@coder.com",
+					Username:  id.String(),
+					RBACRoles: []string{},
+					LoginType: database.LoginTypePassword,
+				})
+				if err != nil {
+					return err
+				}
+				_, err = tx.InsertAuditLog(ctx, database.InsertAuditLogParams{
+					ID:               uuid.New(),
+					Time:             database.Now(),
+					UserID:           id,
+					OrganizationID:   uuid.New(),
+					Ip:               pqtype.Inet{IPNet: net.IPNet{IP: net.IPv4(127, 0, 0, 1), Mask: net.CIDRMask(32, 32)}, Valid: true},
+					ResourceType:     database.ResourceTypeUser,
+					ResourceID:       id,
+					Action:           database.AuditActionCreate,
+					Diff:             []byte("{}"),
+					AdditionalFields: []byte("{}"),
+					RequestID:        uuid.New(),
+				})
+				return err
+			})
+			if !assert.NoError(t, err) {
+				return
+			}
+		}
+	}()
+
+	for finished := false; !finished; {
+		select {
+		case <-done:
+			// Count once more after the last write.
+			finished = true
+		default:
+		}
+		counts, err := db.GetConsistentRowCounts(ctx, []string{"users", "audit_logs"})
+		require.NoError(t, err)
+		require.Equal(t, counts["users"], counts["audit_logs"], "counts are from one snapshot")
+	}
+
+	err = db.InTx(func(tx database.Store) error {
+		_, err := tx.GetConsistentRowCounts(context.Background(), []string{"users"})
+		return err
+	})
+	require.ErrorContains(t, err, "must not be called inside a transaction")
+
+	// The SQL store refuses before it issues anything in the caller's
+	// transaction.
+	sqlDB, connector := newRecordingDB()
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	err = database.New(sqlDB).InTx(func(tx database.Store) error {
+		_, err := tx.GetConsistentRowCounts(ctx, []string{"users"})
+		return err
+	})
+	require.ErrorContains(t, err, "must not be called inside a transaction")
+	require.Empty(t, connector.Queries())
+}
@@ -0,0 +1,724 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"golang.org/x/xerrors"
+)
+
+// maxIdentifierLength is the longest identifier Postgres accepts
+// (NAMEDATALEN - 1).
+const maxIdentifierLength = 63
+
+// identifierPattern matches the unquoted lower-case identifiers used by the
+// schema. Table and column names passed to schema queries must match it.
+var identifierPattern = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+func validateIdentifier(kind, name string) error {
+	if len(name) > maxIdentifierLength || !identifierPattern.MatchString(name) {
+		return xerrors.Errorf("invalid %s name %q", kind, name)
+	}
+	return nil
+}
+
+// schemaQuerier inspects the database schema itself rather than its data.
+type schemaQuerier interface {
+	// GetForeignKeyDependents returns the tables in the current schema with a
+	// foreign key referencing table, sorted by name. Self-references are
+	// excluded. It can be used to validate a manual deletion order against
+	// the live schema.
+	GetForeignKeyDependents(ctx context.Context, table string) ([]string, error)
+	// CheckConstraintViolations counts the rows of table that would violate
+	// a CHECK constraint with the boolean expression constraintSQL, such as
+	// "email IS NOT NULL". As with CHECK constraints, rows where the
+	// expression is NULL pass. It is meant for validating migrations before
+	// applying them and never modifies anything: the count runs in its own
+	// read-only transaction, so it cannot be called inside InTx, and
+	// expressions containing ";" are rejected.
+	CheckConstraintViolations(ctx context.Context, table, constraintSQL string) (int64, error)
+	// FindDuplicates returns the groups of rows of table that share values
+	// for columns and would keep a unique index on them from being built,
+	// largest group first. Rows with a NULL in any of the columns are left
+	// out, since a unique index does not consider them equal. Like
+	// CheckConstraintViolations it is a pre-check for migrations: it runs
+	// in its own read-only transaction, so it cannot be called inside InTx.
+	FindDuplicates(ctx context.Context, table string, columns []string) ([]DuplicateGroup, error)
+	// FindOrphanedRows returns the ids of the rows of childTable whose
+	// fkColumn references an id that parentTable does not have, sorted, as
+	// left behind by a partial delete while the foreign key was deferred or
+	// disabled. Both tables must have a UUID id column. A NULL reference
+	// is not an orphan, and neither is one to a soft-deleted parent. It
+	// only reports: it runs in its own read-only transaction, so it cannot
+	// be called inside InTx.
+	FindOrphanedRows(ctx context.Context, childTable, parentTable, fkColumn string) ([]uuid.UUID, error)
+	// ResetSequence sets the sequence backing a serial column to the
+	// column's current maximum, so the next default value does not collide
+	// with rows imported with explicit keys. For an empty table the next
+	// value is 1. It returns an error if the column has no sequence.
+	ResetSequence(ctx context.Context, table, column string) error
+	// VerifySequenceOwnership reports every column in the current schema
+	// whose default draws from a sequence that is not owned by that
+	// column, which breaks pg_get_serial_sequence and therefore
+	// ResetSequence. It is read-only and intended for validating a restore
+	// from a logical dump; repair with ALTER SEQUENCE ... OWNED BY.
+	VerifySequenceOwnership(ctx context.Context) ([]SequenceIssue, error)
+	// GetDisabledConstraints reports every trigger in the current schema
+	// that does not fire, as after ALTER TABLE ... DISABLE TRIGGER, and
+	// every constraint added NOT VALID and never validated. Foreign keys
+	// are enforced by internal triggers, so DISABLE TRIGGER ALL shows up as
+	// the foreign keys it switched off. It is read-only and meant to gate
+	// serving traffic after a bulk load; an empty result means everything
+	// is enforced.
+	GetDisabledConstraints(ctx context.Context) ([]ConstraintInfo, error)
+	// CheckRLSEnabled reports the row-level security of each of tables in
+	// the current schema, in the order given, and returns an error if one
+	// does not exist. Call it at startup in deployments that isolate
+	// tenants with RLS and refuse to serve unless every status is
+	// Enforced, since a table missing RLS or a policy that allows every
+	// row leaks data across tenants without any error. It is read-only.
+	CheckRLSEnabled(ctx context.Context, tables []string) ([]RLSStatus, error)
+	// CheckEncoding returns an error wrapping ErrUnsupportedEncoding,
+	// naming the offending setting, unless the database stores text as
+	// UTF8 with a UTF-8 character classification locale. Otherwise lower()
+	// only folds ASCII, and the case-insensitive uniqueness of usernames
+	// and emails silently breaks for other letters. The collation may be
+	// C or POSIX as well, since it only affects ordering. Databases using
+	// the ICU locale provider pass whatever their libc locale, since ICU
+	// classifies characters itself.
+	CheckEncoding(ctx context.Context) error
+	// CheckServerVersion returns an error wrapping
+	// ErrUnsupportedServerVersion if the server is older than minVersion,
+	// given in the server_version_num form, e.g. 130000 for 13.0. Pass
+	// MinServerVersion to check against what Coder requires, so an ancient
+	// server fails at startup instead of with syntax errors at runtime.
+	CheckServerVersion(ctx context.Context, minVersion int) error
+	// DumpSchema renders the tables, columns, constraints and indexes of
+	// the current schema as DDL for detecting drift between databases by
+	// diffing. Everything is sorted by name, columns included, so the
+	// output only depends on the schema and not on the order it was
+	// created in. It is not a replacement for pg_dump: types, functions,
+	// triggers, grants and the like are left out.
+	DumpSchema(ctx context.Context) (string, error)
+}
+
+// DuplicateGroup is a set of rows found by FindDuplicates. Values holds
+// their shared values as text, in the order the columns were given.
+type DuplicateGroup struct {
+	Values []string `json:"values"`
+	Count  int64    `json:"count"`
+}
+
+// SequenceIssue is a column whose default sequence is not owned by it.
+// OwnedBy is the "table.column" that owns the sequence instead, or empty if
+// nothing does.
+type SequenceIssue struct {
+	Table    string `db:"table_name" json:"table"`
+	Column   string `db:"column_name" json:"column"`
+	Sequence string `db:"sequence_name" json:"sequence"`
+	OwnedBy  string `db:"owned_by" json:"owned_by"`
+}
+
+// RLSStatus is the row-level security of a table.
+type RLSStatus struct {
+	Table string `db:"table_name" json:"table"`
+	// Enabled is whether ENABLE ROW LEVEL SECURITY is set. Forced is
+	// whether FORCE ROW LEVEL SECURITY is too, without which the table's
+	// owner, often the role Coder connects as, bypasses the policies.
+	Enabled bool `db:"enabled" json:"enabled"`
+	Forced  bool `db:"forced" json:"forced"`
+	// Policies is the number of policies on the table. With RLS enabled
+	// and none, every row is hidden.
+	Policies int64 `db:"policies" json:"policies"`
+	// UnrestrictedPolicies are the permissive policies that admit every
+	// row, such as USING (true).
+	UnrestrictedPolicies []string `db:"-" json:"unrestricted_policies"`
+}
+
+// Enforced reports whether the table's policies apply to every role that is
+// not a superuser, and none of them admits every row.
+func (s RLSStatus) Enforced() bool {
+	return s.Enabled && s.Forced && s.Policies > 0 && len(s.UnrestrictedPolicies) == 0
+}
+
+// ConstraintInfo is a constraint or trigger that is not being enforced.
+type ConstraintInfo struct {
+	Table string `db:"table_name" json:"table"`
+	// Name is the constraint's name, or the trigger's for triggers that do
+	// not implement a constraint.
+	Name string `db:"name" json:"name"`
+	// Kind is "foreign_key", "check", "constraint_trigger" or "trigger".
+	Kind string `db:"kind" json:"kind"`
+	// Problem is "disabled" for triggers that do not fire, or "not_valid"
+	// for constraints that existing rows were never checked against.
+	Problem string `db:"problem" json:"problem"`
+}
+
+func (q *sqlQuerier) GetForeignKeyDependents(ctx context.Context, table string) ([]string, error) {
+	const query = `-- name: GetForeignKeyDependents :many
+	SELECT DISTINCT
+		fk.table_name
+	FROM
+		information_schema.referential_constraints rc
+	JOIN
+		information_schema.table_constraints fk
+	ON
+		fk.constraint_schema = rc.constraint_schema
+	AND
+		fk.constraint_name = rc.constraint_name
+	JOIN
+		information_schema.table_constraints pk
+	ON
+		pk.constraint_schema = rc.unique_constraint_schema
+	AND
+		pk.constraint_name = rc.unique_constraint_name
+	WHERE
+		pk.table_schema = current_schema()
+	AND
+		pk.table_name = $1
+	AND
+		fk.table_name != pk.table_name
+	ORDER BY
+		fk.table_name
+	`
+
+	var tables []string
+	err := q.db.SelectContext(ctx, &tables, query, table)
+	if err != nil {
+		return nil, xerrors.Errorf("select foreign key dependents: %w", err)
+	}
+	return tables, nil
+}
+
+func (q *sqlQuerier) CheckConstraintViolations(ctx context.Context, table, constraintSQL string) (int64, error) {
+	if q.inTx {
+		return 0, xerrors.New("check constraint violations must not be called inside a transaction")
+	}
+	err := validateIdentifier("table", table)
+	if err != nil {
+		return 0, err
+	}
+	if strings.TrimSpace(constraintSQL) == "" || strings.Contains(constraintSQL, ";") {
+		return 0, xerrors.Errorf("invalid constraint expression %q", constraintSQL)
+	}
+	// The expression is followed by a newline so a trailing comment cannot
+	// swallow the closing parentheses.
+	query := fmt.Sprintf("-- name: CheckConstraintViolations :one\nSELECT count(*) FROM %s WHERE NOT COALESCE((%s\n), true)",
+		pq.QuoteIdentifier(table), constraintSQL)
+
+	var count int64
+	err = q.InReadTx(ctx, func(tx Store) error {
+		return txQuerier(tx).db.GetContext(ctx, &count, query)
+	})
+	if err != nil {
+		return 0, xerrors.Errorf("count constraint violations: %w", err)
+	}
+	return count, nil
+}
+
+func (q *sqlQuerier) FindDuplicates(ctx context.Context, table string, columns []string) ([]DuplicateGroup, error) {
+	if q.inTx {
+		return nil, xerrors.New("find duplicates must not be called inside a transaction")
+	}
+	err := validateIdentifier("table", table)
+	if err != nil {
+		return nil, err
+	}
+	if len(columns) == 0 {
+		return nil, xerrors.New("find duplicates needs at least one column")
+	}
+	quoted := make([]string, 0, len(columns))
+	values := make([]string, 0, len(columns))
+	notNull := make([]string, 0, len(columns))
+	for _, column := range columns {
+		err := validateIdentifier("column", column)
+		if err != nil {
+			return nil, err
+		}
+		column = pq.QuoteIdentifier(column)
+		quoted = append(quoted, column)
+		values = append(values, column+"::text")
+		notNull = append(notNull, column+" IS NOT NULL")
+	}
+	query := fmt.Sprintf(`-- name: FindDuplicates :many
+	SELECT
+		ARRAY[%s] AS key_values,
+		count(*) AS count
+	FROM
+		%s
+	WHERE
+		%s
+	GROUP BY
+		%s
+	HAVING
+		count(*) > 1
+	ORDER BY
+		count DESC, key_values
+	`, strings.Join(values, ", "), pq.QuoteIdentifier(table), strings.Join(notNull, " AND "), strings.Join(quoted, ", "))
+
+	var groups []DuplicateGroup
+	err = q.InReadTx(ctx, func(tx Store) error {
+		rows, err := txQuerier(tx).db.QueryContext(ctx, query)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var group DuplicateGroup
+			err := rows.Scan(pq.Array(&group.Values), &group.Count)
+			if err != nil {
+				return err
+			}
+			groups = append(groups, group)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, xerrors.Errorf("find duplicates: %w", err)
+	}
+	return groups, nil
+}
+
+func (q *sqlQuerier) FindOrphanedRows(ctx context.Context, childTable, parentTable, fkColumn string) ([]uuid.UUID, error) {
+	if q.inTx {
+		return nil, xerrors.New("find orphaned rows must not be called inside a transaction")
+	}
+	for _, identifier := range []struct{ kind, name string }{
+		{"table", childTable},
+		{"table", parentTable},
+		{"column", fkColumn},
+	} {
+		err := validateIdentifier(identifier.kind, identifier.name)
+		if err != nil {
+			return nil, err
+		}
+	}
+	child, parent, column := pq.QuoteIdentifier(childTable), pq.QuoteIdentifier(parentTable), pq.QuoteIdentifier(fkColumn)
+	query := fmt.Sprintf(`-- name: FindOrphanedRows :many
+	SELECT
+		child.id
+	FROM
+		%s child
+	WHERE
+		child.%s IS NOT NULL
+		AND NOT EXISTS (
+			SELECT 1 FROM %s parent WHERE parent.id = child.%s
+		)
+	ORDER BY
+		child.id
+	`, child, column, parent, column)
+
+	ids := []uuid.UUID{}
+	err := q.InReadTx(ctx, func(tx Store) error {
+		return txQuerier(tx).db.SelectContext(ctx, &ids, query)
+	})
+	if err != nil {
+		return nil, xerrors.Errorf("find orphaned rows: %w", err)
+	}
+	return ids, nil
+}
+
+func (q *sqlQuerier) ResetSequence(ctx context.Context, table, column string) error {
+	err := validateIdentifier("table", table)
+	if err != nil {
+		return err
+	}
+	err = validateIdentifier("column", column)
+	if err != nil {
+		return err
+	}
+	// setval is strict, so the result is NULL when the column has no
+	// sequence.
+	query := fmt.Sprintf(`-- name: ResetSequence :one
+	SELECT
+		setval(seq.name, COALESCE(m.max, 1), m.max IS NOT NULL)
+	FROM
+		(SELECT pg_get_serial_sequence($1, $2) AS name) seq,
+		(SELECT MAX(%[2]s) AS max FROM %[1]s) m
+	`, pq.QuoteIdentifier(table), pq.QuoteIdentifier(column))
+
+	var value sql.NullInt64
+	err = q.db.GetContext(ctx, &value, query, table, column)
+	if err != nil {
+		return xerrors.Errorf("reset sequence: %w", err)
+	}
+	if !value.Valid {
+		return xerrors.Errorf("column %s.%s has no sequence", table, column)
+	}
+	return nil
+}
+
+func (q *sqlQuerier) VerifySequenceOwnership(ctx context.Context) ([]SequenceIssue, error) {
+	// A column default depends on its sequence through pg_attrdef, while
+	// ownership is an automatic dependency of the sequence on the column.
+	const query = `-- name: VerifySequenceOwnership :many
+	SELECT
+		tbl.relname AS table_name,
+		col.attname AS column_name,
+		seq.relname AS sequence_name,
+		COALESCE(owner_tbl.relname || '.' || owner_col.attname, '') AS owned_by
+	FROM
+		pg_attrdef def
+	JOIN
+		pg_class tbl ON tbl.oid = def.adrelid
+	JOIN
+		pg_attribute col ON col.attrelid = def.adrelid AND col.attnum = def.adnum
+	JOIN
+		pg_depend uses ON uses.classid = 'pg_attrdef'::regclass
+			AND uses.objid = def.oid
+			AND uses.refclassid = 'pg_class'::regclass
+	JOIN
+		pg_class seq ON seq.oid = uses.refobjid AND seq.relkind = 'S'
+	LEFT JOIN
+		pg_depend owns ON owns.classid = 'pg_class'::regclass
+			AND owns.objid = seq.oid
+			AND owns.refclassid = 'pg_class'::regclass
+			AND owns.deptype = 'a'
+	LEFT JOIN
+		pg_class owner_tbl ON owner_tbl.oid = owns.refobjid
+	LEFT JOIN
+		pg_attribute owner_col ON owner_col.attrelid = owns.refobjid AND owner_col.attnum = owns.refobjsubid
+	WHERE
+		tbl.relnamespace = current_schema()::regnamespace
+		AND (
+			owns.objid IS NULL
+			OR owns.refobjid != def.adrelid
+			OR owns.refobjsubid != def.adnum
+		)
+	ORDER BY
+		tbl.relname, col.attname
+	`
+
+	issues := []SequenceIssue{}
+	err := q.db.SelectContext(ctx, &issues, query)
+	if err != nil {
+		return nil, xerrors.Errorf("verify sequence ownership: %w", err)
+	}
+	return issues, nil
+}
+
+func (q *sqlQuerier) GetDisabledConstraints(ctx context.Context) ([]ConstraintInfo, error) {
+	// Triggers set to fire only on replicas ('R') are as good as disabled
+	// on a primary. Each foreign key has several internal triggers, which
+	// DISTINCT folds into one row per table.
+	const query = `-- name: GetDisabledConstraints :many
+	SELECT DISTINCT
+		tbl.relname AS table_name,
+		COALESCE(con.conname, tg.tgname) AS name,
+		CASE
+			WHEN con.contype = 'f' THEN 'foreign_key'
+			WHEN con.oid IS NOT NULL THEN 'constraint_trigger'
+			ELSE 'trigger'
+		END AS kind,
+		'disabled' AS problem
+	FROM
+		pg_trigger tg
+	JOIN
+		pg_class tbl ON tbl.oid = tg.tgrelid
+	LEFT JOIN
+		pg_constraint con ON con.oid = tg.tgconstraint
+	WHERE
+		tbl.relnamespace = current_schema()::regnamespace
+		AND tg.tgenabled IN ('D', 'R')
+	UNION
+	SELECT
+		tbl.relname AS table_name,
+		con.conname AS name,
+		CASE con.contype
+			WHEN 'f' THEN 'foreign_key'
+			WHEN 'c' THEN 'check'
+			ELSE 'constraint_trigger'
+		END AS kind,
+		'not_valid' AS problem
+	FROM
+		pg_constraint con
+	JOIN
+		pg_class tbl ON tbl.oid = con.conrelid
+	WHERE
+		tbl.relnamespace = current_schema()::regnamespace
+		AND NOT con.convalidated
+	ORDER BY
+		table_name, name, problem
+	`
+
+	constraints := []ConstraintInfo{}
+	err := q.db.SelectContext(ctx, &constraints, query)
+	if err != nil {
+		return nil, xerrors.Errorf("get disabled constraints: %w", err)
+	}
+	return constraints, nil
+}
+
+func (q *sqlQuerier) CheckRLSEnabled(ctx context.Context, tables []string) ([]RLSStatus, error) {
+	for _, table := range tables {
+		err := validateIdentifier("table", table)
+		if err != nil {
+			return nil, err
+		}
+	}
+	// Policies are permissive unless created AS RESTRICTIVE, and a
+	// permissive policy whose expression is the constant true admits every
+	// row no matter what other policies say. INSERT policies only have a
+	// WITH CHECK expression.
+	const query = `-- name: CheckRLSEnabled :many
+	SELECT
+		input.name AS table_name,
+		tbl.oid IS NOT NULL AS found,
+		COALESCE(tbl.relrowsecurity, false) AS enabled,
+		COALESCE(tbl.relforcerowsecurity, false) AS forced,
+		count(pol.policyname) AS policies,
+		COALESCE(
+			array_agg(pol.policyname ORDER BY pol.policyname) FILTER (
+				WHERE pol.permissive = 'PERMISSIVE' AND COALESCE(pol.qual, pol.with_check) = 'true'
+			),
+			'{}'
+		) AS unrestricted_policies
+	FROM
+		unnest($1::text[]) WITH ORDINALITY AS input(name, position)
+	LEFT JOIN
+		pg_class tbl ON tbl.relname = input.name
+			AND tbl.relnamespace = current_schema()::regnamespace
+			AND tbl.relkind IN ('r', 'p')
+	LEFT JOIN
+		pg_policies pol ON pol.schemaname = current_schema() AND pol.tablename = input.name
+	GROUP BY
+		input.name, input.position, tbl.oid, tbl.relrowsecurity, tbl.relforcerowsecurity
+	ORDER BY
+		input.position
+	`
+	var rows []struct {
+		RLSStatus
+		Found                bool           `db:"found"`
+		UnrestrictedPolicies pq.StringArray `db:"unrestricted_policies"`
+	}
+	err := q.db.SelectContext(ctx, &rows, query, pq.Array(tables))
+	if err != nil {
+		return nil, xerrors.Errorf("check rls: %w", err)
+	}
+	statuses := make([]RLSStatus, 0, len(rows))
+	for _, row := range rows {
+		if !row.Found {
+			return nil, xerrors.Errorf("check rls: table %q does not exist", row.Table)
+		}
+		row.RLSStatus.UnrestrictedPolicies = row.UnrestrictedPolicies
+		statuses = append(statuses, row.RLSStatus)
+	}
+	return statuses, nil
+}
+
+func (q *sqlQuerier) CheckEncoding(ctx context.Context) error {
+	const query = `-- name: CheckEncoding :one
+	SELECT
+		current_setting('server_encoding') AS server_encoding,
+		datcollate AS collate,
+		datctype AS ctype,
+		-- datlocprovider only exists from PostgreSQL 15.
+		coalesce(to_jsonb(pg_database)->>'datlocprovider', 'c') AS locale_provider
+	FROM
+		pg_database
+	WHERE
+		datname = current_database()
+	`
+
+	var settings struct {
+		ServerEncoding string `db:"server_encoding"`
+		Collate        string `db:"collate"`
+		Ctype          string `db:"ctype"`
+		LocaleProvider string `db:"locale_provider"`
+	}
+	err := q.db.GetContext(ctx, &settings, query)
+	if err != nil {
+		return xerrors.Errorf("get database encoding: %w", err)
+	}
+	if settings.ServerEncoding != "UTF8" {
+		return xerrors.Errorf("server_encoding is %q, must be UTF8: %w", settings.ServerEncoding, ErrUnsupportedEncoding)
+	}
+	if settings.LocaleProvider == "i" {
+		return nil
+	}
+	if !isUTF8Locale(settings.Ctype) {
+		return xerrors.Errorf("LC_CTYPE is %q, must be a UTF-8 locale such as C.UTF-8 or en_US.UTF-8: %w", settings.Ctype, ErrUnsupportedEncoding)
+	}
+	if settings.Collate != "C" && settings.Collate != "POSIX" && !isUTF8Locale(settings.Collate) {
+		return xerrors.Errorf("LC_COLLATE is %q, must be C or a UTF-8 locale: %w", settings.Collate, ErrUnsupportedEncoding)
+	}
+	return nil
+}
+
+// isUTF8Locale reports whether locale names a UTF-8 codeset, which libc
+// spells in several ways.
+func isUTF8Locale(locale string) bool {
+	_, codeset, ok := strings.Cut(strings.ToLower(locale), ".")
+	if !ok {
+		return false
+	}
+	codeset, _, _ = strings.Cut(codeset, "@")
+	return codeset == "utf8" || codeset == "utf-8"
+}
+
+// MinServerVersion is the oldest Postgres release Coder supports, in the
+// server_version_num form.
+const MinServerVersion = 130000
+
+func (q *sqlQuerier) CheckServerVersion(ctx context.Context, minVersion int) error {
+	const query = `-- name: CheckServerVersion :one
+	SELECT
+		current_setting('server_version_num')::integer AS server_version_num,
+		current_setting('server_version') AS server_version
+	`
+
+	var version struct {
+		Num  int    `db:"server_version_num"`
+		Name string `db:"server_version"`
+	}
+	err := q.db.GetContext(ctx, &version, query)
+	if err != nil {
+		return xerrors.Errorf("get server version: %w", err)
+	}
+	if version.Num < minVersion {
+		return xerrors.Errorf("PostgreSQL %s is older than %s, the oldest supported version: %w",
+			version.Name, formatServerVersion(minVersion), ErrUnsupportedServerVersion)
+	}
+	return nil
+}
+
+// formatServerVersion renders a server_version_num as Postgres prints the
+// release: major.minor from 10 on and major.major.minor before.
+func formatServerVersion(num int) string {
+	if num >= 100000 {
+		return fmt.Sprintf("%d.%d", num/10000, num%10000)
+	}
+	return fmt.Sprintf("%d.%d.%d", num/10000, num/100%100, num%100)
+}
+
+func (q *sqlQuerier) DumpSchema(ctx context.Context) (string, error) {
+	// Identifiers are quoted only where needed, like pg_dump does, and
+	// COLLATE "C" keeps the order independent of the database locale.
+	const columnsQuery = `-- name: DumpSchema :many
+	SELECT
+		quote_ident(tbl.relname) AS table_name,
+		quote_ident(att.attname) AS column_name,
+		format_type(att.atttypid, att.atttypmod) AS column_type,
+		att.attnotnull AS not_null,
+		COALESCE(pg_get_expr(def.adbin, def.adrelid), '') AS column_default
+	FROM
+		pg_attribute att
+	JOIN
+		pg_class tbl ON tbl.oid = att.attrelid
+	LEFT JOIN
+		pg_attrdef def ON def.adrelid = att.attrelid AND def.adnum = att.attnum
+	WHERE
+		tbl.relnamespace = current_schema()::regnamespace
+		AND tbl.relkind IN ('r', 'p')
+		AND att.attnum > 0
+		AND NOT att.attisdropped
+	ORDER BY
+		tbl.relname COLLATE "C", att.attname COLLATE "C"
+	`
+	const constraintsQuery = `-- name: DumpSchema :many
+	SELECT
+		quote_ident(tbl.relname) AS table_name,
+		quote_ident(con.conname) AS name,
+		pg_get_constraintdef(con.oid) AS definition
+	FROM
+		pg_constraint con
+	JOIN
+		pg_class tbl ON tbl.oid = con.conrelid
+	WHERE
+		tbl.relnamespace = current_schema()::regnamespace
+	ORDER BY
+		tbl.relname COLLATE "C", con.conname COLLATE "C"
+	`
+	// Indexes that back a table's own primary key, unique or exclusion
+	// constraints are implied by them. A foreign key's conindid names the
+	// referenced index, which the dump still needs.
+	const indexesQuery = `-- name: DumpSchema :many
+	SELECT
+		quote_ident(tbl.relname) AS table_name,
+		quote_ident(idx.relname) AS name,
+		pg_get_indexdef(idx.oid) AS definition
+	FROM
+		pg_index
+	JOIN
+		pg_class idx ON idx.oid = pg_index.indexrelid
+	JOIN
+		pg_class tbl ON tbl.oid = pg_index.indrelid
+	WHERE
+		tbl.relnamespace = current_schema()::regnamespace
+		AND NOT EXISTS (
+			SELECT 1 FROM pg_constraint
+			WHERE pg_constraint.conindid = idx.oid
+				AND pg_constraint.conrelid = tbl.oid
+				AND pg_constraint.contype IN ('p', 'u', 'x')
+		)
+	ORDER BY
+		tbl.relname COLLATE "C", idx.relname COLLATE "C"
+	`
+
+	var columns []struct {
+		TableName     string `db:"table_name"`
+		ColumnName    string `db:"column_name"`
+		ColumnType    string `db:"column_type"`
+		NotNull       bool   `db:"not_null"`
+		ColumnDefault string `db:"column_default"`
+	}
+	err := q.db.SelectContext(ctx, &columns, columnsQuery)
+	if err != nil {
+		return "", xerrors.Errorf("get columns: %w", err)
+	}
+	type definition struct {
+		TableName  string `db:"table_name"`
+		Name       string `db:"name"`
+		Definition string `db:"definition"`
+	}
+	var constraints, indexes []definition
+	err = q.db.SelectContext(ctx, &constraints, constraintsQuery)
+	if err != nil {
+		return "", xerrors.Errorf("get constraints: %w", err)
+	}
+	err = q.db.SelectContext(ctx, &indexes, indexesQuery)
+	if err != nil {
+		return "", xerrors.Errorf("get indexes: %w", err)
+	}
+
+	var dump strings.Builder
+	for i, column := range columns {
+		if i == 0 || columns[i-1].TableName != column.TableName {
+			if i > 0 {
+				dump.WriteString("\n);\n\n")
+			}
+			fmt.Fprintf(&dump, "CREATE TABLE %s (\n", column.TableName)
+		} else {
+			dump.WriteString(",\n")
+		}
+		fmt.Fprintf(&dump, "    %s %s", column.ColumnName, column.ColumnType)
+		if column.NotNull {
+			dump.WriteString(" NOT NULL")
+		}
+		if column.ColumnDefault != "" {
+			fmt.Fprintf(&dump, " DEFAULT %s", column.ColumnDefault)
+		}
+	}
+	if len(columns) > 0 {
+		dump.WriteString("\n);\n")
+	}
+	if len(constraints) > 0 {
+		dump.WriteString("\n")
+	}
+	for _, constraint := range constraints {
+		fmt.Fprintf(&dump, "ALTER TABLE %s ADD CONSTRAINT %s %s;\n",
+			constraint.TableName, constraint.Name, constraint.Definition)
+	}
+	if len(indexes) > 0 {
+		dump.WriteString("\n")
+	}
+	for _, index := range indexes {
+		fmt.Fprintf(&dump, "%s;\n", index.Definition)
+	}
+	return dump.String(), nil
+}
@@ -0,0 +1,433 @@
+//go:build linux
+
+package database_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/coder/coder/coderd/database"
+	"github.com/coder/coder/coderd/database/migrations"
+)
+
+func TestGetForeignKeyDependents(t *testing.T) {
+	t.Parallel()
+	if testing.Short() {
+		t.SkipNow()
+	}
+
+	sqlDB := testSQLDB(t)
+	err := migrations.Up(sqlDB)
+	require.NoError(t, err, "migrations")
+	db := database.New(sqlDB)
+
+	tables, err := db.GetForeignKeyDependents(context.Background(), "organizations")
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"groups",
+		"organization_members",
+		"provisioner_jobs",
+		"template_versions",
+		"templates",
+		"workspaces",
+	}, tables)
+
+	tables, err = db.GetForeignKeyDependents(context.Background(), "does_not_exist")
+	require.NoError(t, err)
+	require.Empty(t, tables)
+}
+
+func TestCheckConstraintViolations(t *testing.T) {
+	t.Parallel()
+	if testing.Short() {
+		t.SkipNow()
+	}
+
+	sqlDB := testSQLDB(t)
+	err := migrations.Up(sqlDB)
+	require.NoError(t, err, "migrations")
+	db := database.New(sqlDB)
+	ctx := context.Background()
+
+	for _, name := range []string{"alice", "bob"} {
+		_, err := db.InsertUser(ctx, database.InsertUserParams{
+			ID:             uuid.New(),
+			Email:          name + "@coder.com",
+			Username:       name,
+			HashedPassword: []byte{},
+			CreatedAt:      database.Now(),
+			UpdatedAt:      database.Now(),
+			RBACRoles:      []string{},
+			LoginType:      database.LoginTypePassword,
+		})
+		require.NoError(t, err)
+	}
+
+	count, err := db.CheckConstraintViolations(ctx, "users", "username <> 'alice'")
+	require.NoError(t, err)
+	require.EqualValues(t, 1, count)
+	count, err = db.CheckConstraintViolations(ctx, "users", "email IS NOT NULL")
+	require.NoError(t, err)
+	require.EqualValues(t, 0, count)
+
+	_, err = db.CheckConstraintViolations(ctx, "users; DROP TABLE users", "true")
+	require.Error(t, err, "invalid table")
+	_, err = db.CheckConstraintViolations(ctx, "users", "true); DELETE FROM users; SELECT (true")
+	require.Error(t, err, "multiple statements")
+	_, err = db.CheckConstraintViolations(ctx, "users", "(SELECT true FROM (DELETE FROM users RETURNING 1) AS d LIMIT 1)")
+	require.Error(t, err, "expressions cannot write")
+	err = db.InTx(func(tx database.Store) error {
+		_, err := tx.CheckConstraintViolations(ctx, "users", "true")
+		return err
+	})
+	require.Error(t, err, "inside a transaction")
+
+	users, err := db.GetUsers(ctx, database.GetUsersParams{})
+	require.NoError(t, err)
+	require.Len(t, users, 2)
+}
+
+func TestResetSequence(t *testing.T) {
+	t.Parallel()
+	if testing.Short() {
+		t.SkipNow()
+	}
+
+	sqlDB := testSQLDB(t)
+	err := migrations.Up(sqlDB)
+	require.NoError(t, err, "migrations")
+	db := database.New(sqlDB)
+	ctx := context.Background()
+
+	err = db.ResetSequence(ctx, "licenses", "id")
+	require.NoError(t, err, "empty table")
+
+	// Simulate a bulk import with explicit keys.
+	_, err = sqlDB.ExecContext(ctx, `INSERT INTO licenses (id, uploaded_at, jwt, exp) VALUES (10, now(), 'a', now())`)
+	require.NoError(t, err)
+	err = db.ResetSequence(ctx, "licenses", "id")
+	require.NoError(t, err)
+	license, err := db.InsertLicense(ctx, database.InsertLicenseParams{
+		UploadedAt: database.Now(),
+		JWT:        "b",
+		Exp:        database.Now(),
+	})
+	require.NoError(t, err)
+	require.EqualValues(t, 11, license.ID)
+
+	err = db.ResetSequence(ctx, "users", "username")
+	require.Error(t, err, "no sequence")
+	err = db.ResetSequence(ctx, "licenses", "id); DROP TABLE licenses; --")
+	require.Error(t, err, "invalid column")
+}
+
+func TestVerifySequenceOwnership(t *testing.T) {
+	t.Parallel()
+	if testing.Short() {
+		t.SkipNow()
+	}
+
+	sqlDB := testSQLDB(t)
+	err := migrations.Up(sqlDB)
+	require.NoError(t, err, "migrations")
+	db := database.New(sqlDB)
+	ctx := context.Background()
+
+	issues, err := db.VerifySequenceOwnership(ctx)
+	require.NoError(t, err)
+	require.Empty(t, issues)
+
+	// A restore can drop the OWNED BY link while keeping the default.
+	_, err = sqlDB.ExecContext(ctx, "ALTER SEQUENCE licenses_id_seq OWNED BY NONE")
+	require.NoError(t, err)
+	issues, err = db.VerifySequenceOwnership(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []database.SequenceIssue{{
+		Table:    "licenses",
+		Column:   "id",
+		Sequence: "licenses_id_seq",
+	}}, issues)
+}
+
+func TestGetDisabledConstraints(t *testing.T) {
+	t.Parallel()
+	if testing.Short() {
+		t.SkipNow()
+	}
+
+	sqlDB := testSQLDB(t)
+	err := migrations.Up(sqlDB)
+	require.NoError(t, err, "migrations")
+	db := database.New(sqlDB)
+	ctx := context.Background()
+
+	constraints, err := db.GetDisabledConstraints(ctx)
+	require.NoError(t, err)
+	require.Empty(t, constraints)
+
+	// Bulk loaders disable every trigger, including those enforcing
+	// foreign keys, and may add constraints without checking old rows.
+	_, err = sqlDB.ExecContext(ctx, "ALTER TABLE gitsshkeys DISABLE TRIGGER ALL")
+	require.NoError(t, err)
+	_, err = sqlDB.ExecContext(ctx, "ALTER TABLE licenses ADD CONSTRAINT licenses_jwt_check CHECK (jwt != '') NOT VALID")
+	require.NoError(t, err)
+	constraints, err = db.GetDisabledConstraints(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []database.ConstraintInfo{
+		{Table: "gitsshkeys", Name: "gitsshkeys_user_id_fkey", Kind: "foreign_key", Problem: "disabled"},
+		{Table: "licenses", Name: "licenses_jwt_check", Kind: "check", Problem: "not_valid"},
+	}, constraints)
+
+	_, err = sqlDB.ExecContext(ctx, "ALTER TABLE gitsshkeys ENABLE TRIGGER ALL")
+	require.NoError(t, err)
+	_, err = sqlDB.ExecContext(ctx, "ALTER TABLE licenses VALIDATE CONSTRAINT licenses_jwt_check")
+	require.NoError(t, err)
+	constraints, err = db.GetDisabledConstraints(ctx)
+	require.NoError(t, err)
+	require.Empty(t, constraints)
+}
+
+func TestCheckRLSEnabled(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Query", func(t *testing.T) {
+		t.Parallel()
+		sqlDB, connector := newRecordingDB()
+		t.Cleanup(func() { _ = sqlDB.Close() })
+		found := true
+		connector.rows = func(string) ([]string, [][]driver.Value) {
+			return []string{"table_name", "found", "enabled", "forced", "policies", "unrestricted_policies"}, [][]driver.Value{
+				{"workspaces", true, true, true, int64(1), "{}"},
+				{"templates", found, true, false, int64(2), "{allow_all}"},
+			}
+		}
+		db := database.New(sqlDB)
+		ctx := context.Background()
+
+		statuses, err := db.CheckRLSEnabled(ctx, []string{"workspaces", "templates"})
+		require.NoError(t, err)
+		require.Equal(t, []database.RLSStatus{
+			{Table: "workspaces", Enabled: true, Forced: true, Policies: 1, UnrestrictedPolicies: []string{}},
+			{Table: "templates", Enabled: true, Policies: 2, UnrestrictedPolicies: []string{"allow_all"}},
+		}, statuses)
+		require.True(t, statuses[0].Enforced())
+		require.False(t, statuses[1].Enforced())
+
+		found = false
+		_, err = db.CheckRLSEnabled(ctx, []string{"workspaces", "templates"})
+		require.ErrorContains(t, err, `table "templates" does not exist`)
+		_, err = db.CheckRLSEnabled(ctx, []string{"users; DROP TABLE users"})
+		require.Error(t, err, "invalid table")
+		require.Len(t, connector.Queries(), 2)
+	})
+
+	t.Run("Postgres", func(t *testing.T) {
+		t.Parallel()
+		if testing.Short() {
+			t.SkipNow()
+		}
+
+		sqlDB := testSQLDB(t)
+		err := migrations.Up(sqlDB)
+		require.NoError(t, err, "migrations")
+		db := database.New(sqlDB)
+		ctx := context.Background()
+
+		statuses, err := db.CheckRLSEnabled(ctx, []string{"workspaces", "templates"})
+		require.NoError(t, err)
+		require.Equal(t, []database.RLSStatus{
+			{Table: "workspaces", UnrestrictedPolicies: []string{}},
+			{Table: "templates", UnrestrictedPolicies: []string{}},
+		}, statuses, "disabled")
+
+		for _, statement := range []string{
+			"ALTER TABLE workspaces ENABLE ROW LEVEL SECURITY",
+			"ALTER TABLE workspaces FORCE ROW LEVEL SECURITY",
+			"CREATE POLICY tenant ON workspaces USING (organization_id = current_setting('app.organization_id')::uuid)",
+			"ALTER TABLE templates ENABLE ROW LEVEL SECURITY",
+			"CREATE POLICY tenant ON templates USING (organization_id = current_setting('app.organization_id')::uuid)",
+			"CREATE POLICY allow_all ON templates USING (true)",
+		} {
+			_, err = sqlDB.ExecContext(ctx, statement)
+			require.NoError(t, err, statement)
+		}
+		statuses, err = db.CheckRLSEnabled(ctx, []string{"workspaces", "templates"})
+		require.NoError(t, err)
+		require.Equal(t, []database.RLSStatus{
+			{Table: "workspaces", Enabled: true, Forced: true, Policies: 1, UnrestrictedPolicies: []string{}},
+			{Table: "templates", Enabled: true, Policies: 2, UnrestrictedPolicies: []string{"allow_all"}},
+		}, statuses)
+		require.True(t, statuses[0].Enforced())
+		require.False(t, statuses[1].Enforced(), "not forced and allowing all rows")
+
+		_, err = db.CheckRLSEnabled(ctx, []string{"missing"})
+		require.ErrorContains(t, err, `table "missing" does not exist`)
+	})
+}
+
+func TestFindDuplicates(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Query", func(t *testing.T) {
+		t.Parallel()
+		sqlDB, connector := newRecordingDB()
+		t.Cleanup(func() { _ = sqlDB.Close() })
+		connector.rows = func(string) ([]string, [][]driver.Value) {
+			return []string{"key_values", "count"}, [][]driver.Value{
+				{"{alice,\"a b\"}", int64(3)},
+				{"{bob,c}", int64(2)},
+			}
+		}
+		db := database.New(sqlDB)
+		ctx := context.Background()
+
+		groups, err := db.FindDuplicates(ctx, "users", []string{"username", "email"})
+		require.NoError(t, err)
+		require.Equal(t, []database.DuplicateGroup{
+			{Values: []string{"alice", "a b"}, Count: 3},
+			{Values: []string{"bob", "c"}, Count: 2},
+		}, groups)
+		var query string
+		for _, q := range connector.Queries() {
+			if strings.Contains(q, "FindDuplicates") {
+				query = q
+			}
+		}
+		require.Contains(t, query, `GROUP BY
+		"username", "email"`)
+		require.Contains(t, query, `"username" IS NOT NULL AND "email" IS NOT NULL`)
+
+		_, err = db.FindDuplicates(ctx, "users; DROP TABLE users", []string{"id"})
+		require.Error(t, err, "invalid table")
+		_, err = db.FindDuplicates(ctx, "users", []string{"id", "email)"})
+		require.Error(t, err, "invalid column")
+		_, err = db.FindDuplicates(ctx, "users", nil)
+		require.Error(t, err, "no columns")
+	})
+
+	t.Run("Postgres", func(t *testing.T) {
+		t.Parallel()
+		if testing.Short() {
+			t.SkipNow()
+		}
+
+		sqlDB := testSQLDB(t)
+		err := migrations.Up(sqlDB)
+		require.NoError(t, err, "migrations")
+		db := database.New(sqlDB)
+		ctx := context.Background()
+
+		uploaded := time.Date(2022, 10, 1, 0, 0, 0, 0, time.UTC)
+		for i, exp := range []time.Time{
+			uploaded.Add(time.Hour), uploaded.Add(time.Hour), uploaded.Add(time.Hour),
+			uploaded.Add(2 * time.Hour), uploaded.Add(2 * time.Hour),
+			uploaded.Add(3 * time.Hour),
+		} {
+			_, err := db.InsertLicense(ctx, database.InsertLicenseParams{
+				UploadedAt: uploaded,
+				JWT:        fmt.Sprintf("jwt-%d", i),
+				Exp:        exp,
+			})
+			require.NoError(t, err)
+		}
+
+		groups, err := db.FindDuplicates(ctx, "licenses", []string{"exp"})
+		require.NoError(t, err)
+		require.Len(t, groups, 2)
+		require.EqualValues(t, 3, groups[0].Count, "largest group first")
+		require.EqualValues(t, 2, groups[1].Count)
+		require.Len(t, groups[0].Values, 1)
+
+		groups, err = db.FindDuplicates(ctx, "licenses", []string{"jwt", "exp"})
+		require.NoError(t, err)
+		require.Empty(t, groups)
+
+		err = db.InTx(func(tx database.Store) error {
+			_, err := tx.FindDuplicates(ctx, "licenses", []string{"exp"})
+			return err
+		})
+		require.Error(t, err, "inside a transaction")
+	})
+}
+
+func TestFindOrphanedRows(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Query", func(t *testing.T) {
+		t.Parallel()
+		sqlDB, connector := newRecordingDB()
+		t.Cleanup(func() { _ = sqlDB.Close() })
+		orphan := uuid.New()
+		connector.rows = func(string) ([]string, [][]driver.Value) {
+			return []string{"id"}, [][]driver.Value{{orphan.String()}}
+		}
+		db := database.New(sqlDB)
+		ctx := context.Background()
+
+		ids, err := db.FindOrphanedRows(ctx, "workspace_builds", "workspaces", "workspace_id")
+		require.NoError(t, err)
+		require.Equal(t, []uuid.UUID{orphan}, ids)
+		var query string
+		for _, q := range connector.Queries() {
+			if strings.Contains(q, "FindOrphanedRows") {
+				query = q
+			}
+		}
+		require.Contains(t, query, `"workspace_builds" child`)
+		require.Contains(t, query, `SELECT 1 FROM "workspaces" parent WHERE parent.id = child."workspace_id"`)
+
+		_, err = db.FindOrphanedRows(ctx, "workspace_builds; DROP TABLE users", "workspaces", "workspace_id")
+		require.Error(t, err, "invalid child table")
+		_, err = db.FindOrphanedRows(ctx, "workspace_builds", "workspaces)", "workspace_id")
+		require.Error(t, err, "invalid parent table")
+		_, err = db.FindOrphanedRows(ctx, "workspace_builds", "workspaces", "")
+		require.Error(t, err, "invalid column")
+	})
+
+	t.Run("Postgres", func(t *testing.T) {
+		t.Parallel()
+		if testing.Short() {
+			t.SkipNow()
+		}
+
+		sqlDB := testSQLDB(t)
+		db := database.New(sqlDB)
+		ctx := context.Background()
+
+		// The tables have no foreign key, as if it had been disabled
+		// during a partial delete.
+		_, err := sqlDB.ExecContext(ctx, `
+			CREATE TABLE parents (id uuid PRIMARY KEY);
+			CREATE TABLE children (id uuid PRIMARY KEY, parent_id uuid);
+		`)
+		require.NoError(t, err)
+		kept, gone := uuid.New(), uuid.New()
+		_, err = sqlDB.ExecContext(ctx, "INSERT INTO parents VALUES ($1), ($2)", kept, gone)
+		require.NoError(t, err)
+		orphans := []uuid.UUID{uuid.New(), uuid.New()}
+		sort.Slice(orphans, func(i, j int) bool { return orphans[i].String() < orphans[j].String() })
+		_, err = sqlDB.ExecContext(ctx, "INSERT INTO children VALUES ($1, $2), ($3, $4), ($5, $4), ($6, NULL)",
+			uuid.New(), kept, orphans[0], gone, orphans[1], uuid.New())
+		require.NoError(t, err)
+		_, err = sqlDB.ExecContext(ctx, "DELETE FROM parents WHERE id = $1", gone)
+		require.NoError(t, err)
+
+		ids, err := db.FindOrphanedRows(ctx, "children", "parents", "parent_id")
+		require.NoError(t, err)
+		require.Equal(t, orphans, ids)
+
+		err = db.InTx(func(tx database.Store) error {
+			_, err := tx.FindOrphanedRows(ctx, "children", "parents", "parent_id")
+			return err
+		})
+		require.Error(t, err, "inside a transaction")
+	})
+}
@@ -0,0 +1,78 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/jmoiron/sqlx"
+	"golang.org/x/xerrors"
+)
+
+// SingleUse is a Store over a single connection, for short-lived processes
+// such as serverless functions that serve one invocation and exit, where a
+// pool only holds connections the database then counts against its limit.
+// Every call, including transactions, runs on the one connection, and a
+// broken connection is not replaced: open a new SingleUse instead.
+//
+// The connection runs one statement at a time, so calls from several
+// goroutines queue behind each other. Inside a transaction, use the Store
+// passed to InTx: calls on the SingleUse itself share the session and so
+// land in the open transaction. Options that need a second
+// connection, WithSecondaryPool and WithHedgedReads, are ignored, WithConn
+// runs on the same connection and Reconnect fails. Close the SingleUse
+// when done.
+type SingleUse struct {
+	Store
+	conn *sqlx.Conn
+	sdb  *sql.DB
+}
+
+// NewSingleUse connects to the Postgres database at dsn.
+func NewSingleUse(ctx context.Context, dsn string, opts ...Option) (*SingleUse, error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	driverName := o.driverName
+	if driverName == "" {
+		driverName = "postgres"
+	}
+
+	sdb, err := o.openDB(dsn)
+	if err != nil {
+		return nil, xerrors.Errorf("open database: %w", err)
+	}
+	// The pool only ever holds the connection taken below, so nothing can
+	// open a second one behind its back.
+	sdb.SetMaxOpenConns(1)
+	dbx := sqlx.NewDb(sdb, driverName)
+	conn, err := dbx.Connx(ctx)
+	if err != nil {
+		_ = sdb.Close()
+		return nil, xerrors.Errorf("connect: %w", err)
+	}
+
+	var store Store = &sqlQuerier{
+		sdb:  dbx,
+		db:   o.wrap(conn),
+		opts: &o,
+		conn: conn,
+	}
+	if o.defaultTimeout > 0 {
+		store = Intercept(store, defaultTimeout(o.defaultTimeout))
+	}
+	return &SingleUse{Store: store, conn: conn, sdb: sdb}, nil
+}
+
+// Close closes the connection.
+func (s *SingleUse) Close() error {
+	err := s.conn.Close()
+	cerr := s.sdb.Close()
+	if err != nil {
+		return xerrors.Errorf("close connection: %w", err)
+	}
+	if cerr != nil {
+		return xerrors.Errorf("close database: %w", cerr)
+	}
+	return nil
+}
@@ -0,0 +1,253 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"golang.org/x/xerrors"
+
+	"cdr.dev/slog"
+)
+
+const (
+	// slowQueryAdviceTimeout bounds the EXPLAIN run for a slow query's
+	// advice.
+	slowQueryAdviceTimeout = 5 * time.Second
+	// slowQueryAdviceConcurrency is how many EXPLAINs may run at once.
+	// Slow queries are often a symptom of a saturated pool, so slow
+	// queries beyond it are logged without advice rather than waiting for
+	// a connection.
+	slowQueryAdviceConcurrency = 2
+	// slowQueryAdviceInterval is how long after explaining a method its
+	// slow queries are logged without advice, since a plan rarely changes
+	// from one call to the next.
+	slowQueryAdviceInterval = time.Minute
+)
+
+// WithSlowQueryLog logs a warning for every statement that takes longer
+// than threshold, with the method, duration and query text with its
+// literals redacted. Statements inside transactions are logged as well.
+func WithSlowQueryLog(threshold time.Duration) Option {
+	return func(o *options) {
+		o.slowQueryThreshold = threshold
+	}
+}
+
+// WithSlowQueryAdvice makes WithSlowQueryLog explain slow reads and, when a
+// sequential scan of a large table dominates the plan, add advice naming
+// the table and the columns it was filtered on as candidates for an index.
+// The EXPLAIN runs in a separate read-only transaction after the query has
+// returned, so it adds a planning round trip; it is meant for debugging.
+// At most two EXPLAINs run at once and each method is explained at most
+// once a minute, and slow queries beyond that are logged without advice.
+// SingleUse Stores, which have no connection to spare for the EXPLAIN, log
+// without advice.
+func WithSlowQueryAdvice() Option {
+	return func(o *options) {
+		o.slowQueryAdvice = true
+	}
+}
+
+// slowDB logs statements slower than the threshold.
+type slowDB struct {
+	DBTX
+	opts *options
+}
+
+func (s *slowDB) observe(ctx context.Context, query string, args []interface{}, start time.Time, err error) {
+	elapsed := time.Since(start)
+	if elapsed < s.opts.slowQueryThreshold {
+		return
+	}
+	method := queryMethod(query)
+	fields := []slog.Field{
+		slog.F("method", method),
+		slog.F("duration", elapsed),
+		slog.F("query", redactQueryText(query)),
+	}
+	if err != nil {
+		fields = append(fields, slog.Error(err))
+	}
+	if s.opts.advisor == nil || !isReadMethod(method) || !s.opts.advisor.acquire(method) {
+		s.opts.logger.Warn(ctx, "slow query", fields...)
+		return
+	}
+	// The caller has waited long enough, and its context may be done by
+	// the time the plan is ready.
+	go func() {
+		defer s.opts.advisor.release()
+		actx, cancel := context.WithTimeout(context.Background(), slowQueryAdviceTimeout)
+		defer cancel()
+		advice, aerr := adviseIndex(actx, s.opts.advisor.pool, query, args)
+		if aerr != nil {
+			fields = append(fields, slog.F("advice_error", aerr.Error()))
+		} else if advice != "" {
+			fields = append(fields, slog.F("advice", advice))
+		}
+		s.opts.logger.Warn(ctx, "slow query", fields...)
+	}()
+}
+
+// indexAdvisor limits how often slow queries are explained.
+type indexAdvisor struct {
+	pool  *sqlx.DB
+	slots chan struct{}
+
+	mu        sync.Mutex
+	explained map[string]time.Time
+}
+
+func newIndexAdvisor(pool *sqlx.DB) *indexAdvisor {
+	return &indexAdvisor{
+		pool:      pool,
+		slots:     make(chan struct{}, slowQueryAdviceConcurrency),
+		explained: map[string]time.Time{},
+	}
+}
+
+// acquire reports whether a slow call to method may be explained now, and
+// if so takes a slot that release returns.
+func (a *indexAdvisor) acquire(method string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if last, ok := a.explained[method]; ok && time.Since(last) < slowQueryAdviceInterval {
+		return false
+	}
+	select {
+	case a.slots <- struct{}{}:
+	default:
+		return false
+	}
+	a.explained[method] = time.Now()
+	return true
+}
+
+func (a *indexAdvisor) release() {
+	<-a.slots
+}
+
+// adviseIndex explains query and, if a sequential scan of a large table
+// accounts for most of its cost, suggests an index on the columns the scan
+// filtered on. It returns an empty string when it has nothing to suggest.
+func adviseIndex(ctx context.Context, pool *sqlx.DB, query string, args []interface{}) (string, error) {
+	tx, err := pool.BeginTxx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return "", xerrors.Errorf("begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var plan string
+	err = tx.QueryRowContext(ctx, "EXPLAIN (FORMAT JSON) "+query, args...).Scan(&plan)
+	if err != nil {
+		return "", xerrors.Errorf("explain: %w", err)
+	}
+	var plans []struct {
+		Plan planNode `json:"Plan"`
+	}
+	err = json.Unmarshal([]byte(plan), &plans)
+	if err != nil || len(plans) == 0 {
+		return "", xerrors.Errorf("parse plan: %w", err)
+	}
+	root := plans[0].Plan
+
+	// Costs are cumulative, so a scan costing half the plan's total
+	// dominates it whatever sits above it.
+	var scans []planNode
+	var walk func(node planNode)
+	walk = func(node planNode) {
+		if node.NodeType == "Seq Scan" && node.RelationName != "" && node.TotalCost*2 >= root.TotalCost {
+			scans = append(scans, node)
+		}
+		for _, child := range node.Plans {
+			walk(child)
+		}
+	}
+	walk(root)
+	if len(scans) == 0 {
+		return "", nil
+	}
+	tables := make([]string, 0, len(scans))
+	for _, scan := range scans {
+		tables = append(tables, scan.RelationName)
+	}
+	large, err := largeTables(ctx, tx, tables)
+	if err != nil {
+		return "", err
+	}
+
+	var advice []string
+	for _, scan := range scans {
+		if !large[scan.RelationName] {
+			continue
+		}
+		note := "seq scan on " + scan.RelationName
+		if columns := filterColumns(scan.Filter); len(columns) > 0 {
+			note += fmt.Sprintf("; consider an index on (%s)", strings.Join(columns, ", "))
+		}
+		advice = append(advice, note)
+	}
+	return strings.Join(advice, "; "), nil
+}
+
+// filterColumnPattern matches a column compared in a plan's Filter, such as
+// owner_id in "(owner_id = '…'::uuid)" or username in
+// "(lower((username)::text) = 'admin'::text)".
+var filterColumnPattern = regexp.MustCompile(`([a-z_][a-z0-9_]*)\)?(?:::[a-z ]+)?\)?\s*(?:=|<>|<=|>=|<|>|~~|!~~|IS )`)
+
+// filterColumns returns the columns compared in filter, in order of first
+// appearance.
+func filterColumns(filter string) []string {
+	var columns []string
+	seen := map[string]bool{}
+	for _, match := range filterColumnPattern.FindAllStringSubmatch(filter, -1) {
+		if column := match[1]; !seen[column] {
+			seen[column] = true
+			columns = append(columns, column)
+		}
+	}
+	return columns
+}
+
+func (s *slowDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	start := time.Now()
+	result, err := s.DBTX.ExecContext(ctx, query, args...)
+	s.observe(ctx, query, args, start, err)
+	return result, err
+}
+
+func (s *slowDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	start := time.Now()
+	rows, err := s.DBTX.QueryContext(ctx, query, args...)
+	s.observe(ctx, query, args, start, err)
+	return rows, err
+}
+
+// QueryRowContext defers its error to Scan, so slow statements are logged
+// without it.
+func (s *slowDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	start := time.Now()
+	row := s.DBTX.QueryRowContext(ctx, query, args...)
+	s.observe(ctx, query, args, start, nil)
+	return row
+}
+
+func (s *slowDB) SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	start := time.Now()
+	err := s.DBTX.SelectContext(ctx, dest, query, args...)
+	s.observe(ctx, query, args, start, err)
+	return err
+}
+
+func (s *slowDB) GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	start := time.Now()
+	err := s.DBTX.GetContext(ctx, dest, query, args...)
+	s.observe(ctx, query, args, start, err)
+	return err
+}
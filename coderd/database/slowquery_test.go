@@ -0,0 +1,119 @@
+package database_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"cdr.dev/slog"
+
+	"github.com/coder/coder/coderd/database"
+)
+
+func TestSlowQueryLog(t *testing.T) {
+	t.Parallel()
+
+	const plan = `[{"Plan": {"Node Type": "Limit", "Total Cost": 100, "Plans": [
+		{"Node Type": "Seq Scan", "Relation Name": "users", "Total Cost": 90,
+		 "Filter": "((lower((username)::text) = 'admin'::text) AND (deleted = false))"}
+	]}}]`
+	slow := []string{"GetUserByID", "GetUserByEmailOrUsername", "GetAPIKeyByID", "DeleteAPIKeyByID"}
+	newDB := func(t *testing.T, explaining chan<- struct{}, opts ...database.Option) (database.Store, *captureSink) {
+		sqlDB, connector := newRecordingDB()
+		t.Cleanup(func() { _ = sqlDB.Close() })
+		connector.hook = func(ctx context.Context, query string) error {
+			if explaining != nil && strings.HasPrefix(query, "EXPLAIN") {
+				explaining <- struct{}{}
+				<-ctx.Done()
+				return ctx.Err()
+			}
+			for _, method := range slow {
+				if strings.Contains(query, "-- name: "+method+" ") {
+					time.Sleep(20 * time.Millisecond)
+				}
+			}
+			return nil
+		}
+		connector.rows = func(query string) ([]string, [][]driver.Value) {
+			switch {
+			case strings.HasPrefix(query, "EXPLAIN"):
+				return []string{"QUERY PLAN"}, [][]driver.Value{{plan}}
+			case strings.Contains(query, "pg_class"):
+				return []string{"relname"}, [][]driver.Value{{"users"}}
+			}
+			return nil, nil
+		}
+		sink := &captureSink{}
+		opts = append(opts, database.WithLogger(slog.Make(sink)), database.WithSlowQueryLog(10*time.Millisecond))
+		return database.New(sqlDB, opts...), sink
+	}
+	field := func(entry slog.SinkEntry, name string) interface{} {
+		for _, f := range entry.Fields {
+			if f.Name == name {
+				return f.Value
+			}
+		}
+		return nil
+	}
+
+	t.Run("Log", func(t *testing.T) {
+		t.Parallel()
+		db, sink := newDB(t, nil)
+		ctx := context.Background()
+
+		_, _ = db.GetTemplates(ctx)
+		require.Empty(t, sink.Messages(), "fast queries are not logged")
+		_, _ = db.GetUserByID(ctx, uuid.New())
+		require.Equal(t, []string{"slow query"}, sink.Messages())
+		entry := sink.Entries()[0]
+		require.Equal(t, "GetUserByID", field(entry, "method"))
+		require.Contains(t, field(entry, "query"), "-- name: GetUserByID :one")
+		require.Nil(t, field(entry, "advice"), "advice is opt-in")
+	})
+
+	t.Run("Advice", func(t *testing.T) {
+		t.Parallel()
+		db, sink := newDB(t, nil, database.WithSlowQueryAdvice())
+		ctx := context.Background()
+
+		_, _ = db.GetUserByID(ctx, uuid.New())
+		require.Eventually(t, func() bool {
+			return len(sink.Messages()) == 1
+		}, 5*time.Second, 10*time.Millisecond)
+		require.Equal(t, "seq scan on users; consider an index on (username, deleted)", field(sink.Entries()[0], "advice"))
+
+		err := db.DeleteAPIKeyByID(ctx, "key")
+		require.NoError(t, err)
+		require.Len(t, sink.Messages(), 2, "writes are logged without being explained")
+		require.Nil(t, field(sink.Entries()[1], "advice"))
+
+		_, _ = db.GetUserByID(ctx, uuid.New())
+		require.Len(t, sink.Messages(), 3, "a method explained recently is logged at once")
+		require.Nil(t, field(sink.Entries()[2], "advice"))
+	})
+
+	t.Run("Bounded", func(t *testing.T) {
+		t.Parallel()
+		explaining := make(chan struct{})
+		db, sink := newDB(t, explaining, database.WithSlowQueryAdvice())
+		ctx := context.Background()
+
+		// Both slots are taken by EXPLAINs that are stuck until their
+		// timeout.
+		_, _ = db.GetUserByID(ctx, uuid.New())
+		<-explaining
+		_, _ = db.GetUserByEmailOrUsername(ctx, database.GetUserByEmailOrUsernameParams{Username: "admin"})
+		<-explaining
+
+		_, _ = db.GetAPIKeyByID(ctx, "key")
+		require.Equal(t, []string{"slow query"}, sink.Messages(), "a slow query is not explained while both slots are busy")
+		entry := sink.Entries()[0]
+		require.Equal(t, "GetAPIKeyByID", field(entry, "method"))
+		require.Nil(t, field(entry, "advice"))
+	})
+}
@@ -0,0 +1,319 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/lib/pq"
+	"golang.org/x/xerrors"
+)
+
+// QueryStat is one normalized statement tracked by pg_stat_statements.
+// Times are in milliseconds.
+type QueryStat struct {
+	Query         string  `db:"query" json:"query"`
+	Calls         int64   `db:"calls" json:"calls"`
+	TotalExecTime float64 `db:"total_exec_time" json:"total_exec_time"`
+	MeanExecTime  float64 `db:"mean_exec_time" json:"mean_exec_time"`
+}
+
+type statsQuerier interface {
+	// GetTopQueriesByTime returns up to limit statements run against the
+	// current database, ordered by total execution time. It requires the
+	// pg_stat_statements extension on Postgres 13 or later and returns
+	// ErrExtensionMissing if it is not available.
+	GetTopQueriesByTime(ctx context.Context, limit int32) ([]QueryStat, error)
+	// GetUnusedIndexes returns indexes in the current schema that have not
+	// been scanned since statistics were last reset, largest first.
+	// Indexes that enforce a primary key, unique or exclusion constraint
+	// are never reported, since they are needed even if never scanned.
+	GetUnusedIndexes(ctx context.Context) ([]IndexUsage, error)
+	// GetTableXIDAge returns up to limit tables in the current database
+	// with the oldest unfrozen transaction IDs, oldest first, so operators
+	// can be warned well before Postgres forces an anti-wraparound vacuum
+	// or stops accepting writes.
+	GetTableXIDAge(ctx context.Context, limit int32) ([]TableXIDAge, error)
+	// GetTableBloat estimates the space wasted by dead and free tuples in
+	// each table in the current schema, most bloated first, to decide
+	// which tables need VACUUM FULL or pg_repack. The estimate is derived
+	// from planner statistics rather than by reading the tables, so it is
+	// cheap but only as fresh as the last ANALYZE.
+	GetTableBloat(ctx context.Context) ([]TableBloat, error)
+	// GetWaitEvents samples what the backends of the current database with
+	// this connection's application_name are doing, counted by state and
+	// wait event, most common first. Sampled repeatedly it shows whether
+	// connections are mostly blocked on locks, IO or the client. Its own
+	// backend is left out.
+	GetWaitEvents(ctx context.Context) ([]WaitEventCount, error)
+	// GetCacheHitRatio returns the fraction of the user tables' heap block
+	// reads in the current database that were served from shared_buffers,
+	// from 0 to 1, or 1 if no blocks were read. A falling ratio means the
+	// working set no longer fits in memory. The counters are cumulative
+	// since statistics were last reset, so after a long uptime the ratio
+	// is slow to reflect a change; pg_stat_reset starts a new period.
+	GetCacheHitRatio(ctx context.Context) (float64, error)
+}
+
+// IndexUsage is an index that has not been scanned.
+type IndexUsage struct {
+	IndexName string `db:"index_name" json:"index_name"`
+	TableName string `db:"table_name" json:"table_name"`
+	SizeBytes int64  `db:"size_bytes" json:"size_bytes"`
+	// StatsSince is when the database's statistics were last reset, or
+	// null if they never were. Postgres does not record when an index was
+	// created, so an index is only known to be unused for this long, and a
+	// recent reset or a newly created index can make a needed index
+	// appear unused.
+	StatsSince sql.NullTime `db:"stats_since" json:"stats_since"`
+}
+
+// TableXIDAge is how many transactions old a table's oldest unfrozen
+// row is.
+type TableXIDAge struct {
+	SchemaName string `db:"schema_name" json:"schema_name"`
+	TableName  string `db:"table_name" json:"table_name"`
+	XIDAge     int64  `db:"xid_age" json:"xid_age"`
+	// FreezeMaxAge is the server's autovacuum_freeze_max_age. Autovacuum
+	// vacuums a table for wraparound once XIDAge passes it, and writes stop
+	// when XIDAge nears two billion.
+	FreezeMaxAge int64 `db:"freeze_max_age" json:"freeze_max_age"`
+}
+
+// TableBloat is the estimated bloat of a table, including its TOAST table.
+type TableBloat struct {
+	SchemaName string `db:"schema_name" json:"schema_name"`
+	TableName  string `db:"table_name" json:"table_name"`
+	SizeBytes  int64  `db:"size_bytes" json:"size_bytes"`
+	// BloatBytes is how much smaller the table would be if rewritten with
+	// its fillfactor.
+	BloatBytes   int64   `db:"bloat_bytes" json:"bloat_bytes"`
+	BloatPercent float64 `db:"bloat_percent" json:"bloat_percent"`
+	// Inaccurate is set when the statistics cannot support an estimate,
+	// e.g. the table was never analyzed or has columns without statistics.
+	Inaccurate bool `db:"inaccurate" json:"inaccurate"`
+}
+
+// WaitEventCount is the number of backends in a state waiting on a wait
+// event. WaitEventType and WaitEvent are empty for active backends that are
+// not waiting, i.e. running on a CPU. Idle connections wait on the client, so
+// they are reported with type "Client" and state "idle".
+type WaitEventCount struct {
+	State         string `db:"state" json:"state"`
+	WaitEventType string `db:"wait_event_type" json:"wait_event_type"`
+	WaitEvent     string `db:"wait_event" json:"wait_event"`
+	Count         int64  `db:"count" json:"count"`
+}
+
+func (q *sqlQuerier) GetTopQueriesByTime(ctx context.Context, limit int32) ([]QueryStat, error) {
+	const query = `-- name: GetTopQueriesByTime :many
+	SELECT
+		query, calls, total_exec_time, mean_exec_time
+	FROM
+		pg_stat_statements
+	WHERE
+		dbid = (SELECT oid FROM pg_database WHERE datname = current_database())
+	ORDER BY
+		total_exec_time DESC
+	LIMIT
+		$1
+	`
+
+	stats := []QueryStat{}
+	err := q.db.SelectContext(ctx, &stats, query, limit)
+	if err != nil {
+		var pqErr *pq.Error
+		// The view is missing until CREATE EXTENSION, and unusable until
+		// the library is in shared_preload_libraries.
+		if errors.As(err, &pqErr) && (pqErr.Code.Name() == "undefined_table" || pqErr.Code.Name() == "object_not_in_prerequisite_state") {
+			return nil, ErrExtensionMissing{Extension: "pg_stat_statements"}
+		}
+		return nil, xerrors.Errorf("get top queries: %w", err)
+	}
+	return stats, nil
+}
+
+func (q *sqlQuerier) GetUnusedIndexes(ctx context.Context) ([]IndexUsage, error) {
+	const query = `-- name: GetUnusedIndexes :many
+	SELECT
+		pg_stat_user_indexes.indexrelname AS index_name,
+		pg_stat_user_indexes.relname AS table_name,
+		pg_relation_size(pg_stat_user_indexes.indexrelid) AS size_bytes,
+		(SELECT stats_reset FROM pg_stat_database WHERE datname = current_database()) AS stats_since
+	FROM
+		pg_stat_user_indexes
+	JOIN
+		pg_index ON pg_index.indexrelid = pg_stat_user_indexes.indexrelid
+	WHERE
+		pg_stat_user_indexes.schemaname = current_schema()
+		AND pg_stat_user_indexes.idx_scan = 0
+		AND NOT pg_index.indisunique
+		AND NOT pg_index.indisprimary
+		AND NOT EXISTS (
+			SELECT 1 FROM pg_constraint WHERE pg_constraint.conindid = pg_stat_user_indexes.indexrelid
+		)
+	ORDER BY
+		size_bytes DESC, index_name
+	`
+
+	indexes := []IndexUsage{}
+	err := q.db.SelectContext(ctx, &indexes, query)
+	if err != nil {
+		return nil, xerrors.Errorf("get unused indexes: %w", err)
+	}
+	return indexes, nil
+}
+
+func (q *sqlQuerier) GetTableXIDAge(ctx context.Context, limit int32) ([]TableXIDAge, error) {
+	// System catalogs and TOAST tables are included, since any of them
+	// can hold back the database's frozen transaction ID.
+	const query = `-- name: GetTableXIDAge :many
+	SELECT
+		pg_namespace.nspname AS schema_name,
+		pg_class.relname AS table_name,
+		age(pg_class.relfrozenxid) AS xid_age,
+		current_setting('autovacuum_freeze_max_age')::bigint AS freeze_max_age
+	FROM
+		pg_class
+	JOIN
+		pg_namespace ON pg_namespace.oid = pg_class.relnamespace
+	WHERE
+		pg_class.relkind IN ('r', 'm', 't')
+	ORDER BY
+		xid_age DESC, schema_name, table_name
+	LIMIT
+		$1
+	`
+
+	tables := []TableXIDAge{}
+	err := q.db.SelectContext(ctx, &tables, query, limit)
+	if err != nil {
+		return nil, xerrors.Errorf("get table xid age: %w", err)
+	}
+	return tables, nil
+}
+
+func (q *sqlQuerier) GetTableBloat(ctx context.Context) ([]TableBloat, error) {
+	// This is the widely used estimate from ioguix/pgsql-bloat-estimation:
+	// the expected pages are the row count times the average row width
+	// from pg_stats, padded for tuple headers and alignment, and the rest
+	// of the table is bloat.
+	const query = `-- name: GetTableBloat :many
+	SELECT
+		schema_name,
+		table_name,
+		(block_size * table_pages)::bigint AS size_bytes,
+		(CASE WHEN table_pages > expected_pages THEN (table_pages - expected_pages) * block_size ELSE 0 END)::bigint AS bloat_bytes,
+		(CASE WHEN table_pages > 0 AND table_pages > expected_pages THEN 100 * (table_pages - expected_pages) / table_pages ELSE 0 END)::float8 AS bloat_percent,
+		inaccurate
+	FROM (
+		SELECT
+			ceil(reltuples / ((block_size - page_header) * fillfactor / (tuple_size * 100))) + ceil(toast_tuples / 4) AS expected_pages,
+			heap_pages + toast_pages AS table_pages,
+			block_size, schema_name, table_name, inaccurate
+		FROM (
+			SELECT
+				4 + tuple_header_size + tuple_data_size + (2 * max_align)
+					- CASE WHEN tuple_header_size % max_align = 0 THEN max_align ELSE tuple_header_size % max_align END
+					- CASE WHEN ceil(tuple_data_size)::int % max_align = 0 THEN max_align ELSE ceil(tuple_data_size)::int % max_align END
+					AS tuple_size,
+				heap_pages, toast_pages, reltuples, toast_tuples, block_size, page_header,
+				schema_name, table_name, fillfactor, inaccurate
+			FROM (
+				SELECT
+					pg_namespace.nspname AS schema_name,
+					tbl.relname AS table_name,
+					greatest(tbl.reltuples, 0) AS reltuples,
+					tbl.relpages AS heap_pages,
+					COALESCE(toast.relpages, 0) AS toast_pages,
+					COALESCE(greatest(toast.reltuples, 0), 0) AS toast_tuples,
+					COALESCE(substring(array_to_string(tbl.reloptions, ' ') FROM 'fillfactor=([0-9]+)')::smallint, 100) AS fillfactor,
+					current_setting('block_size')::numeric AS block_size,
+					CASE WHEN version() ~ 'mingw32|64-bit|x86_64|ppc64|ia64|amd64|aarch64' THEN 8 ELSE 4 END AS max_align,
+					24 AS page_header,
+					23 + CASE WHEN max(COALESCE(pg_stats.null_frac, 0)) > 0 THEN (7 + count(pg_stats.attname)) / 8 ELSE 0 END AS tuple_header_size,
+					sum((1 - COALESCE(pg_stats.null_frac, 0)) * COALESCE(pg_stats.avg_width, 0)) AS tuple_data_size,
+					tbl.reltuples < 0
+						OR bool_or(pg_attribute.atttypid = 'pg_catalog.name'::regtype)
+						OR count(*) <> count(pg_stats.attname) AS inaccurate
+				FROM
+					pg_attribute
+				JOIN
+					pg_class AS tbl ON tbl.oid = pg_attribute.attrelid
+				JOIN
+					pg_namespace ON pg_namespace.oid = tbl.relnamespace
+				LEFT JOIN
+					pg_stats ON pg_stats.schemaname = pg_namespace.nspname
+					AND pg_stats.tablename = tbl.relname
+					AND NOT pg_stats.inherited
+					AND pg_stats.attname = pg_attribute.attname
+				LEFT JOIN
+					pg_class AS toast ON toast.oid = tbl.reltoastrelid
+				WHERE
+					pg_attribute.attnum > 0
+					AND NOT pg_attribute.attisdropped
+					AND tbl.relkind IN ('r', 'm')
+					AND pg_namespace.nspname = current_schema()
+				GROUP BY
+					pg_namespace.nspname, tbl.relname, tbl.reltuples, tbl.relpages,
+					toast.relpages, toast.reltuples, tbl.reloptions
+			) AS columns
+		) AS tuples
+	) AS pages
+	ORDER BY
+		bloat_bytes DESC, table_name
+	`
+
+	tables := []TableBloat{}
+	err := q.db.SelectContext(ctx, &tables, query)
+	if err != nil {
+		return nil, xerrors.Errorf("get table bloat: %w", err)
+	}
+	return tables, nil
+}
+
+func (q *sqlQuerier) GetWaitEvents(ctx context.Context) ([]WaitEventCount, error) {
+	const query = `-- name: GetWaitEvents :many
+	SELECT
+		COALESCE(state, '') AS state,
+		COALESCE(wait_event_type, '') AS wait_event_type,
+		COALESCE(wait_event, '') AS wait_event,
+		count(*) AS count
+	FROM
+		pg_stat_activity
+	WHERE
+		datname = current_database()
+		AND application_name = current_setting('application_name')
+		AND pid <> pg_backend_pid()
+	GROUP BY
+		1, 2, 3
+	ORDER BY
+		count DESC, state, wait_event_type, wait_event
+	`
+
+	events := []WaitEventCount{}
+	err := q.db.SelectContext(ctx, &events, query)
+	if err != nil {
+		return nil, xerrors.Errorf("get wait events: %w", err)
+	}
+	return events, nil
+}
+
+func (q *sqlQuerier) GetCacheHitRatio(ctx context.Context) (float64, error) {
+	const query = `-- name: GetCacheHitRatio :one
+	SELECT
+		COALESCE(
+			sum(heap_blks_hit)::float8 / NULLIF(sum(heap_blks_hit) + sum(heap_blks_read), 0),
+			1
+		)
+	FROM
+		pg_statio_user_tables
+	`
+
+	var ratio float64
+	err := q.db.GetContext(ctx, &ratio, query)
+	if err != nil {
+		return 0, xerrors.Errorf("get cache hit ratio: %w", err)
+	}
+	return ratio, nil
+}
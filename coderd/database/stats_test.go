@@ -0,0 +1,174 @@
+package database_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+
+	"github.com/coder/coder/coderd/database"
+	"github.com/coder/coder/coderd/database/databasefake"
+)
+
+func TestGetTopQueriesByTime(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Stats", func(t *testing.T) {
+		t.Parallel()
+		sqlDB, connector := newRecordingDB()
+		t.Cleanup(func() { _ = sqlDB.Close() })
+		connector.rows = func(string) ([]string, [][]driver.Value) {
+			return []string{"query", "calls", "total_exec_time", "mean_exec_time"}, [][]driver.Value{
+				{"SELECT $1", int64(4), 10.0, 2.5},
+			}
+		}
+		stats, err := database.New(sqlDB).GetTopQueriesByTime(context.Background(), 10)
+		require.NoError(t, err)
+		require.Equal(t, []database.QueryStat{{Query: "SELECT $1", Calls: 4, TotalExecTime: 10, MeanExecTime: 2.5}}, stats)
+	})
+
+	t.Run("ExtensionMissing", func(t *testing.T) {
+		t.Parallel()
+		for _, code := range []pq.ErrorCode{"42P01", "55000"} {
+			code := code
+			sqlDB, connector := newRecordingDB()
+			t.Cleanup(func() { _ = sqlDB.Close() })
+			connector.hook = func(context.Context, string) error {
+				return &pq.Error{Code: code}
+			}
+			_, err := database.New(sqlDB).GetTopQueriesByTime(context.Background(), 10)
+			var missing database.ErrExtensionMissing
+			require.ErrorAs(t, err, &missing)
+			require.Equal(t, "pg_stat_statements", missing.Extension)
+		}
+	})
+
+	t.Run("Fake", func(t *testing.T) {
+		t.Parallel()
+		_, err := databasefake.New().GetTopQueriesByTime(context.Background(), 10)
+		require.ErrorAs(t, err, &database.ErrExtensionMissing{})
+	})
+}
+
+func TestGetUnusedIndexes(t *testing.T) {
+	t.Parallel()
+
+	sqlDB, connector := newRecordingDB()
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	reset := time.Date(2022, 10, 1, 0, 0, 0, 0, time.UTC)
+	connector.rows = func(string) ([]string, [][]driver.Value) {
+		return []string{"index_name", "table_name", "size_bytes", "stats_since"}, [][]driver.Value{
+			{"idx_audit_log_resource_id", "audit_logs", int64(16384), reset},
+		}
+	}
+	indexes, err := database.New(sqlDB).GetUnusedIndexes(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, []database.IndexUsage{{
+		IndexName:  "idx_audit_log_resource_id",
+		TableName:  "audit_logs",
+		SizeBytes:  16384,
+		StatsSince: sql.NullTime{Time: reset, Valid: true},
+	}}, indexes)
+
+	queries := connector.Queries()
+	require.Len(t, queries, 1)
+	for _, filter := range []string{"idx_scan = 0", "NOT pg_index.indisunique", "NOT pg_index.indisprimary", "schemaname = current_schema()"} {
+		require.Contains(t, queries[0], filter)
+	}
+}
+
+func TestGetTableXIDAge(t *testing.T) {
+	t.Parallel()
+
+	sqlDB, connector := newRecordingDB()
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	connector.rows = func(string) ([]string, [][]driver.Value) {
+		return []string{"schema_name", "table_name", "xid_age", "freeze_max_age"}, [][]driver.Value{
+			{"public", "workspace_builds", int64(150000000), int64(200000000)},
+			{"pg_catalog", "pg_class", int64(1200), int64(200000000)},
+		}
+	}
+	tables, err := database.New(sqlDB).GetTableXIDAge(context.Background(), 2)
+	require.NoError(t, err)
+	require.Equal(t, []database.TableXIDAge{
+		{SchemaName: "public", TableName: "workspace_builds", XIDAge: 150000000, FreezeMaxAge: 200000000},
+		{SchemaName: "pg_catalog", TableName: "pg_class", XIDAge: 1200, FreezeMaxAge: 200000000},
+	}, tables)
+
+	queries := connector.Queries()
+	require.Len(t, queries, 1)
+	require.Contains(t, queries[0], "age(pg_class.relfrozenxid)")
+	require.Contains(t, queries[0], "xid_age DESC")
+}
+
+func TestGetTableBloat(t *testing.T) {
+	t.Parallel()
+
+	sqlDB, connector := newRecordingDB()
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	connector.rows = func(string) ([]string, [][]driver.Value) {
+		return []string{"schema_name", "table_name", "size_bytes", "bloat_bytes", "bloat_percent", "inaccurate"}, [][]driver.Value{
+			{"public", "audit_logs", int64(8 << 20), int64(6 << 20), float64(75), false},
+			{"public", "licenses", int64(8192), int64(0), float64(0), true},
+		}
+	}
+	tables, err := database.New(sqlDB).GetTableBloat(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, []database.TableBloat{
+		{SchemaName: "public", TableName: "audit_logs", SizeBytes: 8 << 20, BloatBytes: 6 << 20, BloatPercent: 75},
+		{SchemaName: "public", TableName: "licenses", SizeBytes: 8192, Inaccurate: true},
+	}, tables)
+
+	queries := connector.Queries()
+	require.Len(t, queries, 1)
+	require.Contains(t, queries[0], "pg_stats.avg_width")
+	require.Contains(t, queries[0], "bloat_bytes DESC")
+}
+
+func TestGetWaitEvents(t *testing.T) {
+	t.Parallel()
+
+	sqlDB, connector := newRecordingDB()
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	connector.rows = func(string) ([]string, [][]driver.Value) {
+		return []string{"state", "wait_event_type", "wait_event", "count"}, [][]driver.Value{
+			{"active", "LWLock", "WALWrite", int64(7)},
+			{"idle", "Client", "ClientRead", int64(3)},
+			{"active", "", "", int64(1)},
+		}
+	}
+	events, err := database.New(sqlDB).GetWaitEvents(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, []database.WaitEventCount{
+		{State: "active", WaitEventType: "LWLock", WaitEvent: "WALWrite", Count: 7},
+		{State: "idle", WaitEventType: "Client", WaitEvent: "ClientRead", Count: 3},
+		{State: "active", Count: 1},
+	}, events)
+
+	queries := connector.Queries()
+	require.Len(t, queries, 1)
+	require.Contains(t, queries[0], "application_name = current_setting('application_name')")
+	require.Contains(t, queries[0], "pid <> pg_backend_pid()")
+}
+
+func TestGetCacheHitRatio(t *testing.T) {
+	t.Parallel()
+
+	sqlDB, connector := newRecordingDB()
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	connector.rows = func(string) ([]string, [][]driver.Value) {
+		return []string{"coalesce"}, [][]driver.Value{{0.985}}
+	}
+	ratio, err := database.New(sqlDB).GetCacheHitRatio(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 0.985, ratio)
+
+	queries := connector.Queries()
+	require.Len(t, queries, 1)
+	require.Contains(t, queries[0], "pg_statio_user_tables")
+	require.Contains(t, queries[0], "NULLIF(sum(heap_blks_hit) + sum(heap_blks_read), 0)")
+}
@@ -0,0 +1,88 @@
+package database_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/xerrors"
+
+	"github.com/coder/coder/coderd/database"
+)
+
+func TestStrictTransactions(t *testing.T) {
+	t.Parallel()
+
+	sqlDB, connector := newRecordingDB()
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	db := database.New(sqlDB, database.WithStrictTransactions(true))
+	ctx := context.Background()
+
+	err := db.InTx(func(tx database.Store) error {
+		err := tx.InTx(func(database.Store) error { return nil })
+		require.ErrorIs(t, err, database.ErrNestedTransaction)
+		err = tx.InReadTx(ctx, func(database.Store) error { return nil })
+		require.ErrorIs(t, err, database.ErrNestedTransaction)
+
+		err = tx.InSavepoint(ctx, func(database.Store) error {
+			return xerrors.New("fail")
+		})
+		require.Error(t, err)
+		return tx.InSavepoint(ctx, func(sp database.Store) error {
+			require.Equal(t, 2, sp.TxDepth())
+			return nil
+		})
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"SAVEPOINT savepoint_2",
+		"ROLLBACK TO SAVEPOINT savepoint_2",
+		"SAVEPOINT savepoint_2",
+		"RELEASE SAVEPOINT savepoint_2",
+	}, connector.Queries())
+}
+
+func TestStrictTransactionsReuseOuter(t *testing.T) {
+	t.Parallel()
+
+	// Methods that open their own transaction run in the caller's instead,
+	// so they still work when nesting is refused.
+	for name, call := range map[string]func(context.Context, database.Store) error{
+		"InsertWorkspaceWithQuota": func(ctx context.Context, tx database.Store) error {
+			orgID := uuid.New()
+			_, err := tx.InsertWorkspaceWithQuota(ctx, orgID, 1, database.InsertWorkspaceParams{OrganizationID: orgID})
+			return err
+		},
+		"UpdateTemplateIfMatch": func(ctx context.Context, tx database.Store) error {
+			_, err := tx.UpdateTemplateIfMatch(ctx, uuid.New(), "etag", database.UpdateTemplateIfVersionParams{})
+			return err
+		},
+		"InsertWorkspaceIdempotent": func(ctx context.Context, tx database.Store) error {
+			_, _, err := tx.InsertWorkspaceIdempotent(ctx, "key", database.InsertWorkspaceParams{})
+			return err
+		},
+		"UpdateUserAudited": func(ctx context.Context, tx database.Store) error {
+			_, _, err := tx.UpdateUserAudited(ctx, database.UpdateUserProfileParams{ID: uuid.New()})
+			return err
+		},
+	} {
+		call := call
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			sqlDB, connector := newRecordingDB()
+			t.Cleanup(func() { _ = sqlDB.Close() })
+			db := database.New(sqlDB, database.WithStrictTransactions(true))
+			ctx := context.Background()
+
+			err := db.InTx(func(tx database.Store) error {
+				err := call(ctx, tx)
+				require.NotErrorIs(t, err, database.ErrNestedTransaction)
+				return nil
+			})
+			require.NoError(t, err)
+			require.NotEmpty(t, connector.Queries(), "the method reached the outer transaction")
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"strings"
+
+	"golang.org/x/xerrors"
+)
+
+// TempTableStore is the Store that WithTempTable passes to its function. It
+// is the only Store that runs raw SQL, so statements sqlc cannot check stay
+// confined to the callback that owns the temporary table.
+type TempTableStore interface {
+	Store
+	// ExecRaw runs a statement that sqlc cannot express, such as one on the
+	// temporary table. It is always treated as a write.
+	ExecRaw(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	// SelectRaw runs a query that sqlc cannot express and scans every row
+	// into dest, which must be a pointer to a slice. Like ExecRaw it is
+	// treated as a write, since the query is not known to only read.
+	SelectRaw(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+}
+
+// tempTableDDL matches the start of a CREATE TEMP TABLE statement and
+// captures the table name. Only plain identifiers are accepted so the name
+// can be used to drop the table afterwards.
+var tempTableDDL = regexp.MustCompile(`(?is)^\s*CREATE\s+(?:TEMP|TEMPORARY)\s+TABLE\s+(?:IF\s+NOT\s+EXISTS\s+)?([a-z_][a-z0-9_]*)\s*\(`)
+
+// tempTableName validates ddl as a single CREATE TEMP TABLE statement and
+// returns the name of the table it creates.
+func tempTableName(ddl string) (string, error) {
+	match := tempTableDDL.FindStringSubmatch(ddl)
+	if match == nil {
+		return "", xerrors.New("ddl must be a CREATE TEMP TABLE statement with an unquoted, unqualified table name")
+	}
+	if strings.Contains(strings.TrimRight(strings.TrimSpace(ddl), ";"), ";") {
+		return "", xerrors.New("ddl must be a single statement")
+	}
+	return strings.ToLower(match[1]), nil
+}
+
+// WithTempTable creates a temporary table with ddl on a connection pinned
+// by WithConn, runs function, and then drops the table. function uses
+// ExecRaw and SelectRaw on the TempTableStore it is given to work with the
+// table.
+// Pooled connections outlive the callback, so the table is dropped
+// explicitly rather than left for the session to end.
+func (q *sqlQuerier) WithTempTable(ctx context.Context, ddl string, function func(TempTableStore) error) error {
+	name, err := tempTableName(ddl)
+	if err != nil {
+		return err
+	}
+	return q.WithConn(ctx, func(store Store) error {
+		conn := txQuerier(store)
+		_, err := conn.ExecRaw(ctx, ddl)
+		if err != nil {
+			return xerrors.Errorf("create temp table %s: %w", name, err)
+		}
+		err = function(conn)
+		// pg_temp ensures only the temporary table is dropped, even if a
+		// regular table has the same name. In a transaction that function
+		// aborted this fails, but the rollback drops the table instead.
+		_, dropErr := conn.ExecRaw(ctx, "DROP TABLE IF EXISTS pg_temp."+name)
+		if err != nil {
+			return err
+		}
+		if dropErr != nil {
+			return xerrors.Errorf("drop temp table %s: %w", name, dropErr)
+		}
+		return nil
+	})
+}
+
+func (q *sqlQuerier) ExecRaw(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	result, err := q.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return nil, xerrors.Errorf("exec raw: %w", err)
+	}
+	return result, nil
+}
+
+func (q *sqlQuerier) SelectRaw(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	err := q.db.SelectContext(ctx, dest, query, args...)
+	if err != nil {
+		return xerrors.Errorf("select raw: %w", err)
+	}
+	return nil
+}
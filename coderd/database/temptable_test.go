@@ -0,0 +1,111 @@
+package database_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/xerrors"
+
+	"github.com/coder/coder/coderd/database"
+)
+
+func TestWithTempTable(t *testing.T) {
+	t.Parallel()
+
+	t.Run("CreatesAndDrops", func(t *testing.T) {
+		t.Parallel()
+		sqlDB, connector := newRecordingDB()
+		t.Cleanup(func() { _ = sqlDB.Close() })
+		db := database.New(sqlDB)
+		ctx := context.Background()
+
+		const ddl = "CREATE TEMP TABLE staged_ids (id uuid PRIMARY KEY)"
+		err := db.WithTempTable(ctx, ddl, func(conn database.TempTableStore) error {
+			require.Equal(t, 1, sqlDB.Stats().InUse, "the connection is pinned")
+			_, err := conn.ExecRaw(ctx, "INSERT INTO staged_ids VALUES ($1)", "a")
+			return err
+		})
+		require.NoError(t, err)
+		require.Equal(t, []string{ddl, "INSERT INTO staged_ids VALUES ($1)", "DROP TABLE IF EXISTS pg_temp.staged_ids"}, connector.Queries())
+	})
+
+	t.Run("Intercepted", func(t *testing.T) {
+		t.Parallel()
+		sqlDB, _ := newRecordingDB()
+		t.Cleanup(func() { _ = sqlDB.Close() })
+		var methods []string
+		db := database.Intercept(database.New(sqlDB), func(ctx context.Context, call database.Call, next database.Invoker) ([]interface{}, error) {
+			methods = append(methods, call.Method)
+			return next(ctx)
+		})
+		ctx := context.Background()
+
+		err := db.WithTempTable(ctx, "CREATE TEMP TABLE staged (id uuid)", func(conn database.TempTableStore) error {
+			_, err := conn.ExecRaw(ctx, "INSERT INTO staged VALUES ($1)", "a")
+			if err != nil {
+				return err
+			}
+			var ids []string
+			return conn.SelectRaw(ctx, &ids, "SELECT id FROM staged")
+		})
+		require.NoError(t, err)
+		require.Equal(t, []string{"ExecRaw", "SelectRaw"}, methods)
+	})
+
+	t.Run("InterceptedWithoutResults", func(t *testing.T) {
+		t.Parallel()
+		sqlDB, connector := newRecordingDB()
+		t.Cleanup(func() { _ = sqlDB.Close() })
+		// An interceptor may skip a call and return no results.
+		db := database.Intercept(database.New(sqlDB), func(ctx context.Context, call database.Call, next database.Invoker) ([]interface{}, error) {
+			if call.Method == "ExecRaw" {
+				return nil, nil
+			}
+			return next(ctx)
+		})
+
+		err := db.WithTempTable(context.Background(), "CREATE TEMP TABLE staged (id uuid)", func(conn database.TempTableStore) error {
+			result, err := conn.ExecRaw(context.Background(), "INSERT INTO staged VALUES ($1)", "a")
+			require.Nil(t, result)
+			return err
+		})
+		require.NoError(t, err)
+		require.NotContains(t, connector.Queries(), "INSERT INTO staged VALUES ($1)")
+	})
+
+	t.Run("DropsOnError", func(t *testing.T) {
+		t.Parallel()
+		sqlDB, connector := newRecordingDB()
+		t.Cleanup(func() { _ = sqlDB.Close() })
+		db := database.New(sqlDB)
+
+		want := xerrors.New("validation failed")
+		err := db.WithTempTable(context.Background(), "create temporary table IF NOT EXISTS Staged (id uuid)", func(database.TempTableStore) error {
+			return want
+		})
+		require.ErrorIs(t, err, want)
+		require.Contains(t, connector.Queries(), "DROP TABLE IF EXISTS pg_temp.staged")
+	})
+
+	t.Run("RejectsOtherDDL", func(t *testing.T) {
+		t.Parallel()
+		sqlDB, connector := newRecordingDB()
+		t.Cleanup(func() { _ = sqlDB.Close() })
+		db := database.New(sqlDB)
+
+		for _, ddl := range []string{
+			"CREATE TABLE staged (id uuid)",
+			"CREATE TEMP TABLE public.staged (id uuid)",
+			"CREATE TEMP TABLE staged (id uuid); DROP TABLE users",
+			"DROP TABLE users",
+		} {
+			err := db.WithTempTable(context.Background(), ddl, func(database.TempTableStore) error {
+				t.Errorf("callback ran for %q", ddl)
+				return nil
+			})
+			require.Error(t, err, ddl)
+		}
+		require.Empty(t, connector.Queries())
+	})
+}
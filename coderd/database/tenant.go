@@ -0,0 +1,53 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+
+	"github.com/google/uuid"
+)
+
+type tenantKey struct{}
+
+// WithTenant returns a context attributing Store calls made with it to the
+// organization orgID, for the tenant label of Metrics.
+func WithTenant(ctx context.Context, orgID uuid.UUID) context.Context {
+	return context.WithValue(ctx, tenantKey{}, orgID)
+}
+
+// TenantFromContext returns the organization set by WithTenant.
+func TenantFromContext(ctx context.Context) (uuid.UUID, bool) {
+	orgID, ok := ctx.Value(tenantKey{}).(uuid.UUID)
+	return orgID, ok
+}
+
+const (
+	// tenantUnknown labels calls made without a tenant on the context.
+	tenantUnknown = "unknown"
+	// tenantOther labels tenants outside the allowlist when unknown
+	// tenants are not bucketed.
+	tenantOther = "other"
+)
+
+// tenantLabels maps tenants to a bounded set of metric label values.
+type tenantLabels struct {
+	allowed map[uuid.UUID]bool
+	buckets uint32
+}
+
+func (t *tenantLabels) label(ctx context.Context) string {
+	orgID, ok := TenantFromContext(ctx)
+	if !ok {
+		return tenantUnknown
+	}
+	if t.allowed[orgID] {
+		return orgID.String()
+	}
+	if t.buckets == 0 {
+		return tenantOther
+	}
+	h := fnv.New32a()
+	_, _ = h.Write(orgID[:])
+	return fmt.Sprintf("bucket-%d", h.Sum32()%t.buckets)
+}
@@ -1,6 +1,11 @@
 package database
 
-import "time"
+import (
+	"context"
+	"time"
+
+	"golang.org/x/xerrors"
+)
 
 // Now returns a standardized timezone used for database resources.
 func Now() time.Time {
@@ -12,3 +17,25 @@ func Now() time.Time {
 func Time(t time.Time) time.Time {
 	return t.Round(time.Microsecond)
 }
+
+type clockQuerier interface {
+	// DBNow returns the database server's now(). Some generated queries
+	// compare against now() in SQL, so code that needs to agree with those
+	// queries (rather than with the application host's clock) should use
+	// DBNow instead of Now. Inside a transaction now() is fixed at the
+	// transaction's start, so repeated calls return the same value.
+	DBNow(ctx context.Context) (time.Time, error)
+}
+
+func (q *sqlQuerier) DBNow(ctx context.Context) (time.Time, error) {
+	const query = `-- name: DBNow :one
+	SELECT now()
+	`
+
+	var now time.Time
+	err := q.db.GetContext(ctx, &now, query)
+	if err != nil {
+		return time.Time{}, xerrors.Errorf("get database time: %w", err)
+	}
+	return Time(now.UTC()), nil
+}
@@ -0,0 +1,62 @@
+//go:build linux
+
+package database_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/coder/coder/coderd/database"
+)
+
+func TestDBNow(t *testing.T) {
+	t.Parallel()
+	if testing.Short() {
+		t.SkipNow()
+	}
+
+	db := database.New(testSQLDB(t))
+	ctx := context.Background()
+
+	// now() is fixed for the duration of a transaction, so every read in
+	// the same transaction observes the same clock.
+	err := db.InTx(func(tx database.Store) error {
+		first, err := tx.DBNow(ctx)
+		require.NoError(t, err)
+		time.Sleep(10 * time.Millisecond)
+		second, err := tx.DBNow(ctx)
+		require.NoError(t, err)
+		require.Equal(t, first, second)
+		require.Equal(t, time.UTC, first.Location())
+		require.Equal(t, database.Time(first), first)
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func TestDBNowServerClock(t *testing.T) {
+	t.Parallel()
+
+	// The server's clock is an hour ahead of the host's and reports a zone
+	// and precision Postgres would not store.
+	serverNow := time.Now().Add(time.Hour).In(time.FixedZone("UTC+2", 2*60*60)).Truncate(time.Second).Add(1500 * time.Nanosecond)
+	sqlDB, connector := newRecordingDB()
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	connector.rows = func(query string) ([]string, [][]driver.Value) {
+		if strings.Contains(query, "DBNow") {
+			return []string{"now"}, [][]driver.Value{{serverNow}}
+		}
+		return nil, nil
+	}
+	db := database.New(sqlDB)
+
+	now, err := db.DBNow(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, time.UTC, now.Location())
+	require.True(t, now.Equal(serverNow.Round(time.Microsecond)), "got %s, want the server's %s", now, serverNow)
+}
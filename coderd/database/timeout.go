@@ -0,0 +1,29 @@
+package database
+
+import (
+	"context"
+	"time"
+)
+
+// WithDefaultTimeout bounds query methods called with a context that can
+// never be canceled, such as context.Background() in background jobs, so
+// they cannot hang forever during a database stall. Contexts that can be
+// canceled or have a deadline, like request-scoped contexts, always take
+// precedence and are used unchanged. The timeout applies to each query
+// method call, not to whole transactions.
+func WithDefaultTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.defaultTimeout = timeout
+	}
+}
+
+func defaultTimeout(timeout time.Duration) Interceptor {
+	return func(ctx context.Context, call Call, next Invoker) ([]interface{}, error) {
+		if call.Method == "InTx" || ctx.Done() != nil {
+			return next(ctx)
+		}
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		return next(ctx)
+	}
+}
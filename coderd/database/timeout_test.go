@@ -0,0 +1,41 @@
+package database_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/coder/coder/coderd/database"
+)
+
+func TestWithDefaultTimeout(t *testing.T) {
+	t.Parallel()
+
+	sqlDB, connector := newRecordingDB()
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	deadlines := make(chan bool, 10)
+	connector.hook = func(ctx context.Context, _ string) error {
+		_, ok := ctx.Deadline()
+		deadlines <- ok
+		return nil
+	}
+	db := database.New(sqlDB, database.WithDefaultTimeout(time.Minute))
+
+	err := db.DeleteAPIKeyByID(context.Background(), "key")
+	require.NoError(t, err)
+	require.True(t, <-deadlines, "background context gets the default timeout")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	err = db.DeleteAPIKeyByID(ctx, "key")
+	require.NoError(t, err)
+	require.False(t, <-deadlines, "cancelable context is used unchanged")
+
+	err = db.InTx(func(tx database.Store) error {
+		return tx.DeleteAPIKeyByID(context.Background(), "key")
+	})
+	require.NoError(t, err)
+	require.True(t, <-deadlines, "applies inside transactions")
+}
@@ -0,0 +1,143 @@
+package database
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"cdr.dev/slog"
+	"github.com/lib/pq"
+	"golang.org/x/xerrors"
+)
+
+type touchQuerier interface {
+	// TouchTokens sets last_used to at for every API key in ids, in one
+	// statement. Keys already used at or after at are left alone, so
+	// touches applied out of order never move last_used backwards. API key
+	// IDs are text, not UUIDs.
+	TouchTokens(ctx context.Context, ids []string, at time.Time) error
+}
+
+func (q *sqlQuerier) TouchTokens(ctx context.Context, ids []string, at time.Time) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	const query = `-- name: TouchTokens :exec
+	UPDATE
+		api_keys
+	SET
+		last_used = $2
+	WHERE
+		id = ANY($1 :: text[])
+		AND last_used < $2
+	`
+
+	_, err := q.db.ExecContext(ctx, query, pq.Array(ids), at)
+	if err != nil {
+		return xerrors.Errorf("touch tokens: %w", err)
+	}
+	return nil
+}
+
+// TokenToucher buffers API key touches in memory and writes them with one
+// TouchTokens call per interval, instead of an update per request.
+//
+// Debouncing trades freshness for write volume: last_used in the database
+// lags real use by up to the interval, and touches still buffered when the
+// process dies without Close are lost. Each flush writes the latest touch
+// in the batch to every key in it, so a key's last_used may also be ahead
+// of its own last use by up to the interval. Anything that acts on
+// last_used, such as idle expiry, must tolerate an error of one interval
+// in either direction.
+type TokenToucher struct {
+	store    Store
+	logger   slog.Logger
+	interval time.Duration
+
+	mu      sync.Mutex
+	pending map[string]time.Time
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewTokenToucher starts a TokenToucher that flushes to store every
+// interval until ctx is done or Close is called.
+func NewTokenToucher(ctx context.Context, store Store, logger slog.Logger, interval time.Duration) *TokenToucher {
+	ctx, cancel := context.WithCancel(ctx)
+	t := &TokenToucher{
+		store:    store,
+		logger:   logger,
+		interval: interval,
+		pending:  map[string]time.Time{},
+		cancel:   cancel,
+		done:     make(chan struct{}),
+	}
+	go func() {
+		defer close(t.done)
+		t.run(ctx)
+	}()
+	return t
+}
+
+// Touch records that the API key id was used at at. It never blocks on the
+// database.
+func (t *TokenToucher) Touch(id string, at time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if at.After(t.pending[id]) {
+		t.pending[id] = at
+	}
+}
+
+// Flush writes the buffered touches now. If the write fails they are kept
+// for the next flush.
+func (t *TokenToucher) Flush(ctx context.Context) error {
+	t.mu.Lock()
+	pending := t.pending
+	t.pending = map[string]time.Time{}
+	t.mu.Unlock()
+	if len(pending) == 0 {
+		return nil
+	}
+
+	ids := make([]string, 0, len(pending))
+	var latest time.Time
+	for id, at := range pending {
+		ids = append(ids, id)
+		if at.After(latest) {
+			latest = at
+		}
+	}
+	err := t.store.TouchTokens(ctx, ids, latest)
+	if err != nil {
+		for id, at := range pending {
+			t.Touch(id, at)
+		}
+		return err
+	}
+	return nil
+}
+
+// Close stops the periodic flush, then flushes what is still buffered.
+func (t *TokenToucher) Close(ctx context.Context) error {
+	t.cancel()
+	<-t.done
+	return t.Flush(ctx)
+}
+
+func (t *TokenToucher) run(ctx context.Context) {
+	ticker := time.NewTicker(t.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		err := t.Flush(ctx)
+		if err != nil && ctx.Err() == nil {
+			t.logger.Warn(ctx, "flush api key touches", slog.Error(err))
+		}
+	}
+}
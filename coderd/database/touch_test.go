@@ -0,0 +1,81 @@
+package database_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"cdr.dev/slog/sloggers/slogtest"
+
+	"github.com/coder/coder/coderd/database"
+	"github.com/coder/coder/coderd/database/databasefake"
+)
+
+func TestTouchTokens(t *testing.T) {
+	t.Parallel()
+
+	db := databasefake.New()
+	ctx := context.Background()
+	start := database.Now()
+	insertKey := func(id string) {
+		_, err := db.InsertAPIKey(ctx, database.InsertAPIKeyParams{
+			ID:        id,
+			UserID:    uuid.New(),
+			LastUsed:  start,
+			LoginType: database.LoginTypePassword,
+			Scope:     database.APIKeyScopeAll,
+		})
+		require.NoError(t, err)
+	}
+	lastUsed := func(id string) time.Time {
+		key, err := db.GetAPIKeyByID(ctx, id)
+		require.NoError(t, err)
+		return key.LastUsed
+	}
+	insertKey("a")
+	insertKey("b")
+	insertKey("c")
+
+	later := start.Add(time.Minute)
+	err := db.TouchTokens(ctx, []string{"a", "b"}, later)
+	require.NoError(t, err)
+	require.Equal(t, later, lastUsed("a"))
+	require.Equal(t, later, lastUsed("b"))
+	require.Equal(t, start, lastUsed("c"))
+
+	// Touches never move last_used backwards.
+	err = db.TouchTokens(ctx, []string{"a"}, start)
+	require.NoError(t, err)
+	require.Equal(t, later, lastUsed("a"))
+
+	t.Run("TokenToucher", func(t *testing.T) {
+		t.Parallel()
+
+		recording := database.NewRecording(db)
+		// The interval is long enough that only explicit flushes write.
+		toucher := database.NewTokenToucher(ctx, recording, slogtest.Make(t, nil), time.Hour)
+		for i := 0; i < 10; i++ {
+			toucher.Touch("c", start.Add(time.Duration(i)*time.Second))
+		}
+		toucher.Touch("b", start)
+		require.Empty(t, recording.Methods(), "touches are buffered")
+
+		err := toucher.Flush(ctx)
+		require.NoError(t, err)
+		require.Equal(t, []string{"TouchTokens"}, recording.Methods())
+		require.Equal(t, start.Add(9*time.Second), lastUsed("c"))
+		require.Equal(t, later, lastUsed("b"), "older touch is ignored")
+
+		recording.Reset()
+		require.NoError(t, toucher.Flush(ctx))
+		require.Empty(t, recording.Methods(), "nothing to flush")
+
+		toucher.Touch("c", later.Add(time.Minute))
+		require.NoError(t, toucher.Close(ctx))
+		require.Equal(t, []string{"TouchTokens"}, recording.Methods(), "close flushes")
+		require.Equal(t, later.Add(time.Minute), lastUsed("c"))
+	})
+}
@@ -0,0 +1,127 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/lib/pq"
+	"golang.org/x/xerrors"
+
+	"cdr.dev/slog"
+)
+
+// maxPreparedTxIDLength is the longest global transaction identifier
+// Postgres accepts.
+const maxPreparedTxIDLength = 199
+
+// twoPhaseQuerier finishes transactions prepared with InPreparedTx. Neither
+// method may be called inside a transaction.
+type twoPhaseQuerier interface {
+	// CommitPrepared commits the prepared transaction gid.
+	CommitPrepared(ctx context.Context, gid string) error
+	// RollbackPrepared rolls back the prepared transaction gid.
+	RollbackPrepared(ctx context.Context, gid string) error
+}
+
+func validatePreparedTxID(gid string) error {
+	if gid == "" || len(gid) > maxPreparedTxIDLength {
+		return xerrors.Errorf("invalid prepared transaction id %q", gid)
+	}
+	return nil
+}
+
+// InPreparedTx runs function in a transaction and, if it succeeds, prepares
+// the transaction for two-phase commit as gid instead of committing it. The
+// transaction must then be finished with CommitPrepared or
+// RollbackPrepared, from any connection.
+//
+// Prepared transactions require max_prepared_transactions to be non-zero,
+// which it is not by default. Until finished, a prepared transaction keeps
+// its locks and holds back the xmin horizon, so an orphaned one blocks
+// vacuum and can eventually force a wraparound shutdown. Coordinators must
+// resolve every gid they prepare; pg_prepared_xacts lists pending ones.
+func (q *sqlQuerier) InPreparedTx(ctx context.Context, gid string, function func(Store) error) error {
+	if q.inTx {
+		return xerrors.New("cannot prepare a nested transaction")
+	}
+	err := validatePreparedTxID(gid)
+	if err != nil {
+		return err
+	}
+
+	// database/sql cannot prepare a transaction it started, so the
+	// transaction is managed by hand on a dedicated connection, or on the
+	// one the Store is scoped to.
+	conn := q.conn
+	if conn == nil {
+		conn, err = q.sdb.Connx(ctx)
+		if err != nil {
+			return xerrors.Errorf("acquire connection: %w", err)
+		}
+		defer conn.Close()
+	}
+	// A session whose transaction could not be ended is closed rather than
+	// returned to the pool, or left as the only connection of a Store
+	// scoped to it, inside the transaction.
+	_, err = conn.ExecContext(ctx, "BEGIN")
+	if err != nil {
+		discardConn(conn)
+		return xerrors.Errorf("begin transaction: %w", err)
+	}
+	rollback := func() {
+		timeout := q.opts.rollbackTimeout
+		if timeout <= 0 {
+			timeout = defaultRollbackTimeout
+		}
+		rctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		_, rerr := conn.ExecContext(rctx, "ROLLBACK")
+		if rerr != nil {
+			q.opts.txLogger(ctx).Warn(ctx, "roll back transaction", slog.Error(rerr))
+			discardConn(conn)
+		}
+	}
+
+	depth := 1
+	err = function(&sqlQuerier{
+		db:      q.opts.wrap(nameTx(ctx, conn)),
+		opts:    q.opts,
+		inTx:    true,
+		depth:   &depth,
+		conn:    conn,
+		txStart: time.Now(),
+	})
+	if err != nil {
+		rollback()
+		return xerrors.Errorf("execute transaction: %w", mapTxError(err))
+	}
+	_, err = conn.ExecContext(ctx, "PREPARE TRANSACTION "+pq.QuoteLiteral(gid))
+	if err != nil {
+		rollback()
+		return xerrors.Errorf("prepare transaction: %w", err)
+	}
+	return nil
+}
+
+func (q *sqlQuerier) CommitPrepared(ctx context.Context, gid string) error {
+	return q.finishPrepared(ctx, "COMMIT PREPARED", gid)
+}
+
+func (q *sqlQuerier) RollbackPrepared(ctx context.Context, gid string) error {
+	return q.finishPrepared(ctx, "ROLLBACK PREPARED", gid)
+}
+
+func (q *sqlQuerier) finishPrepared(ctx context.Context, statement, gid string) error {
+	if q.inTx {
+		return xerrors.Errorf("%s cannot run inside a transaction", statement)
+	}
+	err := validatePreparedTxID(gid)
+	if err != nil {
+		return err
+	}
+	_, err = q.db.ExecContext(ctx, statement+" "+pq.QuoteLiteral(gid))
+	if err != nil {
+		return xerrors.Errorf("%s: %w", statement, err)
+	}
+	return nil
+}
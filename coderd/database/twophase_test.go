@@ -0,0 +1,106 @@
+package database_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/xerrors"
+
+	"github.com/coder/coder/coderd/database"
+)
+
+func TestInPreparedTx(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Prepares", func(t *testing.T) {
+		t.Parallel()
+		sqlDB, connector := newRecordingDB()
+		t.Cleanup(func() { _ = sqlDB.Close() })
+		db := database.New(sqlDB)
+		ctx := context.Background()
+
+		err := db.InPreparedTx(ctx, "it's-1", func(tx database.Store) error {
+			require.Equal(t, 1, tx.TxDepth())
+			return tx.DeleteAPIKeyByID(ctx, "a")
+		})
+		require.NoError(t, err)
+		queries := connector.Queries()
+		require.Len(t, queries, 3)
+		require.Equal(t, "BEGIN", queries[0])
+		require.Contains(t, queries[1], "DeleteAPIKeyByID")
+		require.Equal(t, "PREPARE TRANSACTION 'it''s-1'", queries[2])
+
+		err = db.CommitPrepared(ctx, "it's-1")
+		require.NoError(t, err)
+		err = db.RollbackPrepared(ctx, "it's-1")
+		require.NoError(t, err)
+		queries = connector.Queries()
+		require.Equal(t, "COMMIT PREPARED 'it''s-1'", queries[3])
+		require.Equal(t, "ROLLBACK PREPARED 'it''s-1'", queries[4])
+	})
+
+	t.Run("RollsBackOnError", func(t *testing.T) {
+		t.Parallel()
+		sqlDB, connector := newRecordingDB()
+		t.Cleanup(func() { _ = sqlDB.Close() })
+		db := database.New(sqlDB)
+
+		err := db.InPreparedTx(context.Background(), "gid", func(database.Store) error {
+			return xerrors.New("boom")
+		})
+		require.ErrorContains(t, err, "boom")
+		require.Equal(t, []string{"BEGIN", "ROLLBACK"}, connector.Queries())
+		require.Zero(t, connector.Closed(), "a rolled back session is reused")
+	})
+
+	t.Run("RollbackFails", func(t *testing.T) {
+		t.Parallel()
+		sqlDB, connector := newRecordingDB()
+		t.Cleanup(func() { _ = sqlDB.Close() })
+		connector.hook = func(_ context.Context, query string) error {
+			if query == "ROLLBACK" {
+				return xerrors.New("connection lost")
+			}
+			return nil
+		}
+		db := database.New(sqlDB)
+
+		err := db.InPreparedTx(context.Background(), "gid", func(database.Store) error {
+			return xerrors.New("boom")
+		})
+		require.ErrorContains(t, err, "boom")
+		require.Equal(t, 1, connector.Closed(), "the session is not reused mid-transaction")
+		require.Zero(t, sqlDB.Stats().Idle)
+	})
+
+	t.Run("InvalidID", func(t *testing.T) {
+		t.Parallel()
+		sqlDB, connector := newRecordingDB()
+		t.Cleanup(func() { _ = sqlDB.Close() })
+		db := database.New(sqlDB)
+		ctx := context.Background()
+
+		err := db.InPreparedTx(ctx, "", func(database.Store) error { return nil })
+		require.Error(t, err)
+		err = db.CommitPrepared(ctx, strings.Repeat("a", 200))
+		require.Error(t, err)
+		require.Empty(t, connector.Queries())
+	})
+
+	t.Run("Nested", func(t *testing.T) {
+		t.Parallel()
+		sqlDB, _ := newRecordingDB()
+		t.Cleanup(func() { _ = sqlDB.Close() })
+		db := database.New(sqlDB)
+		ctx := context.Background()
+
+		err := db.InTx(func(tx database.Store) error {
+			err := tx.InPreparedTx(ctx, "gid", func(database.Store) error { return nil })
+			require.Error(t, err)
+			return tx.CommitPrepared(ctx, "gid")
+		})
+		require.Error(t, err)
+	})
+}
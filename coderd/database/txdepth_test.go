@@ -0,0 +1,76 @@
+package database_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/coder/coder/coderd/database"
+	"github.com/coder/coder/coderd/database/databasefake"
+)
+
+func TestTxDepth(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Depth", func(t *testing.T) {
+		t.Parallel()
+
+		sqlDB, _ := newRecordingDB()
+		t.Cleanup(func() { _ = sqlDB.Close() })
+		testTxDepth(t, database.New(sqlDB))
+	})
+
+	t.Run("Fake", func(t *testing.T) {
+		t.Parallel()
+		testTxDepth(t, databasefake.New())
+	})
+
+	t.Run("Limit", func(t *testing.T) {
+		t.Parallel()
+
+		sqlDB, _ := newRecordingDB()
+		t.Cleanup(func() { _ = sqlDB.Close() })
+		db := database.New(sqlDB, database.WithMaxTxDepth(2))
+
+		var recurse func(tx database.Store) error
+		deepest := 0
+		recurse = func(tx database.Store) error {
+			deepest = tx.TxDepth()
+			return tx.InTx(recurse)
+		}
+		err := db.InTx(recurse)
+		require.ErrorIs(t, err, database.ErrTxDepthExceeded)
+		require.Equal(t, 2, deepest)
+
+		err = db.InTx(func(tx database.Store) error {
+			return tx.InTx(func(database.Store) error { return nil })
+		})
+		require.NoError(t, err, "nesting within the limit")
+	})
+}
+
+func testTxDepth(t *testing.T, db database.Store) {
+	t.Helper()
+
+	require.Equal(t, 0, db.TxDepth())
+	require.True(t, db.TxStartTime().IsZero())
+	require.Equal(t, time.Duration(-1), db.TxAge())
+	before := time.Now()
+	err := db.InTx(func(outer database.Store) error {
+		require.Equal(t, 1, outer.TxDepth())
+		start := outer.TxStartTime()
+		require.False(t, start.Before(before), "start time is set when the transaction begins")
+		require.GreaterOrEqual(t, outer.TxAge(), time.Duration(0))
+		err := outer.InTx(func(inner database.Store) error {
+			require.Equal(t, 2, inner.TxDepth())
+			require.Equal(t, start, inner.TxStartTime(), "nested calls report the outer transaction")
+			return nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, 1, outer.TxDepth(), "depth is restored after the nested call")
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 0, db.TxDepth())
+}
@@ -0,0 +1,172 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"sync"
+
+	"golang.org/x/exp/slices"
+	"golang.org/x/xerrors"
+)
+
+// writeTracker records which methods a transaction called and whether any
+// of them were writes.
+type writeTracker struct {
+	mu      sync.Mutex
+	called  []string
+	written bool
+	// first is the first write method called.
+	first string
+}
+
+func (w *writeTracker) record(query string) {
+	method := queryMethod(query)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !isReadMethod(method) {
+		if !w.written {
+			w.first = method
+		}
+		w.written = true
+	}
+	if method != "" && !slices.Contains(w.called, method) {
+		w.called = append(w.called, method)
+	}
+}
+
+func (w *writeTracker) wrote() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.written
+}
+
+// firstWrite returns the first write method called, or "" if there were
+// none. Statements without a method name count as writes and are reported
+// as "unnamed query".
+func (w *writeTracker) firstWrite() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.written {
+		return ""
+	}
+	return writeName(w.first)
+}
+
+// writeName names a write method in errors and logs.
+func writeName(method string) string {
+	if method == "" {
+		return "unnamed query"
+	}
+	return method
+}
+
+func (w *writeTracker) methods() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]string(nil), w.called...)
+}
+
+// writeTrackingDB records every statement executed on a transaction.
+type writeTrackingDB struct {
+	DBTX
+	tracker *writeTracker
+}
+
+func (w *writeTrackingDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	w.tracker.record(query)
+	return w.DBTX.ExecContext(ctx, query, args...)
+}
+
+func (w *writeTrackingDB) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	w.tracker.record(query)
+	return w.DBTX.PrepareContext(ctx, query)
+}
+
+func (w *writeTrackingDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	w.tracker.record(query)
+	return w.DBTX.QueryContext(ctx, query, args...)
+}
+
+func (w *writeTrackingDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	w.tracker.record(query)
+	return w.DBTX.QueryRowContext(ctx, query, args...)
+}
+
+func (w *writeTrackingDB) SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	w.tracker.record(query)
+	return w.DBTX.SelectContext(ctx, dest, query, args...)
+}
+
+func (w *writeTrackingDB) GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	w.tracker.record(query)
+	return w.DBTX.GetContext(ctx, dest, query, args...)
+}
+
+// readOnlyGuardDB rejects write methods before they reach the database.
+// QueryRowContext cannot return an error of its own, so writes through it
+// are only recorded and left for the read-only transaction to refuse.
+type readOnlyGuardDB struct {
+	DBTX
+	tracker *writeTracker
+}
+
+// check records query and returns an error if it is a write. Savepoint
+// statements are let through so InSavepoint works inside the transaction.
+func (r *readOnlyGuardDB) check(query string) error {
+	if isSavepointStatement(query) {
+		return nil
+	}
+	r.tracker.record(query)
+	method := queryMethod(query)
+	if isReadMethod(method) {
+		return nil
+	}
+	return xerrors.Errorf("%w: %s called in InTxReadOnlyHint", ErrWriteInReadOnlyTx, writeName(method))
+}
+
+func isSavepointStatement(query string) bool {
+	return strings.HasPrefix(query, "SAVEPOINT ") ||
+		strings.HasPrefix(query, "RELEASE SAVEPOINT ") ||
+		strings.HasPrefix(query, "ROLLBACK TO SAVEPOINT ")
+}
+
+func (r *readOnlyGuardDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	if err := r.check(query); err != nil {
+		return nil, err
+	}
+	return r.DBTX.ExecContext(ctx, query, args...)
+}
+
+func (r *readOnlyGuardDB) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	if err := r.check(query); err != nil {
+		return nil, err
+	}
+	return r.DBTX.PrepareContext(ctx, query)
+}
+
+func (r *readOnlyGuardDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	if err := r.check(query); err != nil {
+		return nil, err
+	}
+	return r.DBTX.QueryContext(ctx, query, args...)
+}
+
+func (r *readOnlyGuardDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	r.tracker.record(query)
+	return r.DBTX.QueryRowContext(ctx, query, args...)
+}
+
+func (r *readOnlyGuardDB) SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	if err := r.check(query); err != nil {
+		return err
+	}
+	return r.DBTX.SelectContext(ctx, dest, query, args...)
+}
+
+func (r *readOnlyGuardDB) GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	if err := r.check(query); err != nil {
+		return err
+	}
+	return r.DBTX.GetContext(ctx, dest, query, args...)
+}
@@ -0,0 +1,118 @@
+package database_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"cdr.dev/slog"
+
+	"github.com/coder/coder/coderd/database"
+)
+
+// captureSink records log entries for assertions.
+type captureSink struct {
+	mu      sync.Mutex
+	entries []slog.SinkEntry
+}
+
+func (s *captureSink) LogEntry(_ context.Context, e slog.SinkEntry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries = append(s.entries, e)
+}
+
+func (*captureSink) Sync() {}
+
+func (s *captureSink) Entries() []slog.SinkEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]slog.SinkEntry(nil), s.entries...)
+}
+
+func (s *captureSink) Messages() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var messages []string
+	for _, e := range s.entries {
+		messages = append(messages, e.Message)
+	}
+	return messages
+}
+
+func TestReadOnlyTxHints(t *testing.T) {
+	t.Parallel()
+
+	sqlDB, _ := newRecordingDB()
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	sink := &captureSink{}
+	logger := slog.Make(sink).Leveled(slog.LevelDebug)
+	db := database.New(sqlDB, database.WithLogger(logger), database.WithReadOnlyTxHints(true))
+	ctx := context.Background()
+
+	err := db.InTx(func(tx database.Store) error {
+		_, _ = tx.GetUserByID(ctx, uuid.New())
+		return nil
+	})
+	require.NoError(t, err)
+	require.Len(t, sink.Messages(), 1, "read-only work in a read-write tx")
+
+	err = db.InTx(func(tx database.Store) error {
+		_, _ = tx.GetUserByID(ctx, uuid.New())
+		return tx.DeleteAPIKeyByID(ctx, "key")
+	})
+	require.NoError(t, err)
+	err = db.InReadTx(ctx, func(tx database.Store) error {
+		_, _ = tx.GetUserByID(ctx, uuid.New())
+		return nil
+	})
+	require.NoError(t, err)
+	require.Len(t, sink.Messages(), 1, "no hint for writes or read-only transactions")
+}
+
+func TestInTxReadOnlyHint(t *testing.T) {
+	t.Parallel()
+
+	sqlDB, connector := newRecordingDB()
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	db := database.New(sqlDB)
+	ctx := context.Background()
+
+	err := db.InTxReadOnlyHint(func(tx database.Store) error {
+		_, _ = tx.GetUserByID(ctx, uuid.New())
+		return nil
+	})
+	require.NoError(t, err)
+
+	err = db.InTxReadOnlyHint(func(tx database.Store) error {
+		return tx.DeleteAPIKeyByID(ctx, "key")
+	})
+	require.ErrorIs(t, err, database.ErrWriteInReadOnlyTx)
+	require.ErrorContains(t, err, "DeleteAPIKeyByID called in InTxReadOnlyHint")
+	for _, query := range connector.Queries() {
+		require.NotContains(t, query, "DeleteAPIKeyByID", "write reached the database")
+	}
+
+	// Writes through QueryRowContext are left to the database to refuse,
+	// but are still reported even if the callback ignores the error.
+	err = db.InTxReadOnlyHint(func(tx database.Store) error {
+		_, _ = tx.UpdateUserStatus(ctx, database.UpdateUserStatusParams{ID: uuid.New()})
+		return nil
+	})
+	require.ErrorIs(t, err, database.ErrWriteInReadOnlyTx)
+	require.ErrorContains(t, err, "UpdateUserStatus")
+
+	// Nested hints run in a savepoint of the outer transaction.
+	err = db.InTx(func(tx database.Store) error {
+		return tx.InTxReadOnlyHint(func(tx database.Store) error {
+			return tx.InSavepoint(ctx, func(tx database.Store) error {
+				_, _ = tx.GetUserByID(ctx, uuid.New())
+				return nil
+			})
+		})
+	})
+	require.NoError(t, err)
+}
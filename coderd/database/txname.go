@@ -0,0 +1,80 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	"cdr.dev/slog"
+
+	"github.com/coder/coder/coderd/tracing"
+)
+
+// WithTxName returns a context that names the transactions begun with it,
+// so one transaction can be followed across traces and logs. The name is
+// appended to the span name of every statement in the transaction, e.g.
+// "QUERY GetWorkspaceByID [build workspace]", including statements made
+// with other contexts, and is logged as tx_name with the messages about
+// the transaction. A transaction nested in a named one runs in the outer
+// transaction and keeps its name.
+func WithTxName(ctx context.Context, name string) context.Context {
+	return tracing.WithDBTxName(ctx, name)
+}
+
+// TxNameFromContext returns the name set by WithTxName, or "".
+func TxNameFromContext(ctx context.Context) string {
+	return tracing.DBTxNameFromContext(ctx)
+}
+
+// txLogger returns the logger for messages about the transaction begun with
+// ctx.
+func (o *options) txLogger(ctx context.Context) slog.Logger {
+	if name := TxNameFromContext(ctx); name != "" {
+		return o.logger.With(slog.F("tx_name", name))
+	}
+	return o.logger
+}
+
+// nameTx names the statements run on db after the transaction begun with
+// ctx, if it has a name.
+func nameTx(ctx context.Context, db DBTX) DBTX {
+	if name := TxNameFromContext(ctx); name != "" {
+		return &txNameDB{DBTX: db, name: name}
+	}
+	return db
+}
+
+type txNameDB struct {
+	DBTX
+	name string
+}
+
+func (t *txNameDB) apply(ctx context.Context) context.Context {
+	if TxNameFromContext(ctx) == t.name {
+		return ctx
+	}
+	return WithTxName(ctx, t.name)
+}
+
+func (t *txNameDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return t.DBTX.ExecContext(t.apply(ctx), query, args...)
+}
+
+func (t *txNameDB) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return t.DBTX.PrepareContext(t.apply(ctx), query)
+}
+
+func (t *txNameDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return t.DBTX.QueryContext(t.apply(ctx), query, args...)
+}
+
+func (t *txNameDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return t.DBTX.QueryRowContext(t.apply(ctx), query, args...)
+}
+
+func (t *txNameDB) SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	return t.DBTX.SelectContext(t.apply(ctx), dest, query, args...)
+}
+
+func (t *txNameDB) GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	return t.DBTX.GetContext(t.apply(ctx), dest, query, args...)
+}
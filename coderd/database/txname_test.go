@@ -0,0 +1,56 @@
+package database_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"cdr.dev/slog"
+
+	"github.com/coder/coder/coderd/database"
+	"github.com/coder/coder/coderd/tracing"
+)
+
+func TestWithTxName(t *testing.T) {
+	t.Parallel()
+
+	sqlDB, connector := newRecordingDB()
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	var (
+		mu    sync.Mutex
+		names []string
+	)
+	// The tracing driver names spans after the context it is given, so
+	// record what reaches the driver.
+	connector.hook = func(ctx context.Context, _ string) error {
+		mu.Lock()
+		defer mu.Unlock()
+		names = append(names, tracing.DBTxNameFromContext(ctx))
+		return nil
+	}
+	sink := &captureSink{}
+	logger := slog.Make(sink).Leveled(slog.LevelDebug)
+	db := database.New(sqlDB, database.WithLogger(logger), database.WithReadOnlyTxHints(true))
+
+	ctx := database.WithTxName(context.Background(), "build workspace")
+	require.Equal(t, "build workspace", database.TxNameFromContext(ctx))
+	err := db.InTxOpts(ctx, database.TxOptions{}, func(tx database.Store) error {
+		// Statements made with an unnamed context are named too.
+		_, _ = tx.GetUserByID(context.Background(), uuid.New())
+		return nil
+	})
+	require.NoError(t, err)
+	_, _ = db.GetUserByID(context.Background(), uuid.New())
+
+	mu.Lock()
+	require.Equal(t, []string{"build workspace", ""}, names)
+	mu.Unlock()
+
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	require.Len(t, sink.entries, 1)
+	require.Contains(t, sink.entries[0].Fields, slog.F("tx_name", "build workspace"))
+}
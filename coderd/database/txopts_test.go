@@ -0,0 +1,65 @@
+package database_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/coder/coder/coderd/database"
+)
+
+func TestDeferrable(t *testing.T) {
+	t.Parallel()
+
+	sqlDB, connector := newRecordingDB()
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	db := database.New(sqlDB)
+	ctx := context.Background()
+
+	opts := database.TxOptions{Isolation: sql.LevelSerializable, ReadOnly: true, Deferrable: true}
+	err := db.InTxOpts(ctx, opts, func(tx database.Store) error {
+		return tx.DeleteAPIKeyByID(ctx, "a")
+	})
+	require.NoError(t, err)
+	queries := connector.Queries()
+	require.Len(t, queries, 2)
+	require.Equal(t, "SET TRANSACTION DEFERRABLE", queries[0], "must precede other statements")
+
+	for _, invalid := range []database.TxOptions{
+		{ReadOnly: true, Deferrable: true},
+		{Isolation: sql.LevelSerializable, Deferrable: true},
+	} {
+		err = db.InTxOpts(ctx, invalid, func(database.Store) error { return nil })
+		require.Error(t, err)
+	}
+}
+
+func TestPlannerSettings(t *testing.T) {
+	t.Parallel()
+
+	sqlDB, connector := newRecordingDB()
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	db := database.New(sqlDB)
+	ctx := context.Background()
+
+	opts := database.TxOptions{PlannerSettings: map[string]string{
+		"enable_seqscan":      "off",
+		"join_collapse_limit": "1",
+	}}
+	err := db.InTxOpts(ctx, opts, func(tx database.Store) error {
+		return tx.DeleteAPIKeyByID(ctx, "a")
+	})
+	require.NoError(t, err)
+	queries := connector.Queries()
+	require.Len(t, queries, 3)
+	require.Equal(t, "SELECT set_config($1, $2, true)", queries[0], "settings precede the transaction's statements")
+	require.Equal(t, "SELECT set_config($1, $2, true)", queries[1])
+
+	err = db.InTxOpts(ctx, database.TxOptions{PlannerSettings: map[string]string{
+		"statement_timeout": "0",
+	}}, func(database.Store) error { return nil })
+	require.ErrorContains(t, err, "statement_timeout")
+	require.Len(t, connector.Queries(), 3, "nothing runs for a disallowed setting")
+}
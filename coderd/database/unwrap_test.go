@@ -0,0 +1,34 @@
+package database_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/coder/coder/coderd/database"
+	"github.com/coder/coder/coderd/database/databasefake"
+)
+
+func TestUnwrap(t *testing.T) {
+	t.Parallel()
+
+	sqlDB, _ := newRecordingDB()
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	db := database.New(sqlDB, database.WithDefaultTimeout(time.Minute))
+	require.NotNil(t, db.Unwrap())
+	require.Same(t, sqlDB, db.Unwrap().DB, "the pool passed to New, through wrappers")
+
+	ctx := context.Background()
+	require.NoError(t, db.InTx(func(tx database.Store) error {
+		require.Nil(t, tx.Unwrap(), "transactions have no pool")
+		return nil
+	}))
+	require.NoError(t, db.WithConn(ctx, func(conn database.Store) error {
+		require.Nil(t, conn.Unwrap(), "pinned connections have no pool")
+		return nil
+	}))
+
+	require.Nil(t, databasefake.New().Unwrap())
+}
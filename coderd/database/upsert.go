@@ -0,0 +1,200 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"golang.org/x/xerrors"
+)
+
+type upsertQuerier interface {
+	// InsertOrGetUser inserts the user unless a live user already has the
+	// same email, ignoring case, in which case that user is returned
+	// unchanged. inserted reports which happened. A conflict on any other
+	// unique column, such as the username, is still an error.
+	InsertOrGetUser(ctx context.Context, arg InsertUserParams) (inserted bool, user User, err error)
+	// InsertWorkspaceIdempotent inserts the workspace the first time it is
+	// called with idempotencyKey and returns that workspace, unchanged, on
+	// every later call with the key, so clients can retry a create safely.
+	// created reports which happened. A call racing the first one waits
+	// for it to commit and then returns its workspace.
+	InsertWorkspaceIdempotent(ctx context.Context, idempotencyKey string, arg InsertWorkspaceParams) (workspace Workspace, created bool, err error)
+	// UpsertAgentStats inserts the stats in one statement, replacing
+	// existing stats with the same ID. If the batch has the same ID more
+	// than once, the last occurrence wins, as if the rows were upserted one
+	// at a time in order.
+	UpsertAgentStats(ctx context.Context, arg []InsertAgentStatParams) error
+}
+
+func (q *sqlQuerier) InsertOrGetUser(ctx context.Context, arg InsertUserParams) (bool, User, error) {
+	// DO NOTHING returns no row on conflict, and a follow-up read can miss
+	// a row committed after the statement's snapshot. A no-op DO UPDATE
+	// locks and returns the existing row instead. xmax is zero only for a
+	// row version created by an insert, which tells the two paths apart.
+	const query = `-- name: InsertOrGetUser :one
+	INSERT INTO
+		users (
+			id,
+			email,
+			username,
+			hashed_password,
+			created_at,
+			updated_at,
+			rbac_roles,
+			login_type
+		)
+	VALUES
+		($1, $2, $3, $4, $5, $6, $7, $8)
+	ON CONFLICT (lower(email)) WHERE deleted = false DO UPDATE
+	SET
+		email = users.email
+	RETURNING
+		*, (xmax = 0) AS inserted
+	`
+
+	var row struct {
+		User
+		Inserted bool `db:"inserted"`
+	}
+	err := q.db.GetContext(ctx, &row, query,
+		arg.ID,
+		arg.Email,
+		arg.Username,
+		arg.HashedPassword,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.RBACRoles,
+		arg.LoginType,
+	)
+	if err != nil {
+		return false, User{}, xerrors.Errorf("insert or get user: %w", err)
+	}
+	return row.Inserted, row.User, nil
+}
+
+func (q *sqlQuerier) InsertWorkspaceIdempotent(ctx context.Context, idempotencyKey string, arg InsertWorkspaceParams) (Workspace, bool, error) {
+	if idempotencyKey == "" {
+		return Workspace{}, false, xerrors.New("idempotency key must not be empty")
+	}
+	// Claiming the key first makes a concurrent claim wait on its row
+	// lock until this transaction ends, so only one caller inserts. As in
+	// InsertOrGetUser, the no-op DO UPDATE returns the existing row and
+	// xmax tells the paths apart.
+	const claim = `-- name: InsertWorkspaceIdempotent :one
+	INSERT INTO
+		workspace_idempotency_keys (idempotency_key, workspace_id, created_at)
+	VALUES
+		($1, $2, $3)
+	ON CONFLICT (idempotency_key) DO UPDATE
+	SET
+		idempotency_key = EXCLUDED.idempotency_key
+	RETURNING
+		workspace_id, (xmax = 0) AS created
+	`
+
+	var (
+		workspace Workspace
+		created   bool
+	)
+	err := q.inCurrentTx(ctx, func(tx Store) error {
+		var key struct {
+			WorkspaceID uuid.UUID `db:"workspace_id"`
+			Created     bool      `db:"created"`
+		}
+		err := txQuerier(tx).db.GetContext(ctx, &key, claim, idempotencyKey, arg.ID, arg.CreatedAt)
+		if err != nil {
+			return xerrors.Errorf("claim idempotency key: %w", err)
+		}
+		created = key.Created
+		if created {
+			workspace, err = tx.InsertWorkspace(ctx, arg)
+			return err
+		}
+		// This is a new statement, so under READ COMMITTED it sees the
+		// workspace committed by the caller that claimed the key.
+		workspace, err = tx.GetWorkspaceByID(ctx, key.WorkspaceID)
+		return err
+	})
+	if err != nil {
+		return Workspace{}, false, xerrors.Errorf("insert workspace idempotent: %w", err)
+	}
+	return workspace, created, nil
+}
+
+// dedupeLast returns rows with only the last row for each key, in the
+// order of those last rows. A single INSERT ... ON CONFLICT DO UPDATE
+// fails if two of its rows conflict on the same key, so batch upserts use
+// it to resolve duplicates the way consecutive upserts would.
+func dedupeLast[T any, K comparable](rows []T, key func(T) K) []T {
+	last := make(map[K]int, len(rows))
+	for i, row := range rows {
+		last[key(row)] = i
+	}
+	if len(last) == len(rows) {
+		return rows
+	}
+	deduped := make([]T, 0, len(last))
+	for i, row := range rows {
+		if last[key(row)] == i {
+			deduped = append(deduped, row)
+		}
+	}
+	return deduped
+}
+
+func (q *sqlQuerier) UpsertAgentStats(ctx context.Context, arg []InsertAgentStatParams) error {
+	arg = dedupeLast(arg, func(stat InsertAgentStatParams) uuid.UUID { return stat.ID })
+	if len(arg) == 0 {
+		return nil
+	}
+	const query = `-- name: UpsertAgentStats :exec
+	INSERT INTO
+		agent_stats (id, created_at, user_id, workspace_id, template_id, agent_id, payload)
+	SELECT
+		*
+	FROM
+		unnest($1::uuid[], $2::timestamptz[], $3::uuid[], $4::uuid[], $5::uuid[], $6::uuid[], $7::jsonb[])
+	ON CONFLICT (id) DO UPDATE
+	SET
+		created_at = EXCLUDED.created_at,
+		user_id = EXCLUDED.user_id,
+		workspace_id = EXCLUDED.workspace_id,
+		template_id = EXCLUDED.template_id,
+		agent_id = EXCLUDED.agent_id,
+		payload = EXCLUDED.payload
+	`
+
+	var (
+		ids          = make([]uuid.UUID, len(arg))
+		createdAts   = make([]time.Time, len(arg))
+		userIDs      = make([]uuid.UUID, len(arg))
+		workspaceIDs = make([]uuid.UUID, len(arg))
+		templateIDs  = make([]uuid.UUID, len(arg))
+		agentIDs     = make([]uuid.UUID, len(arg))
+		payloads     = make([]string, len(arg))
+	)
+	for i, stat := range arg {
+		ids[i] = stat.ID
+		createdAts[i] = stat.CreatedAt
+		userIDs[i] = stat.UserID
+		workspaceIDs[i] = stat.WorkspaceID
+		templateIDs[i] = stat.TemplateID
+		agentIDs[i] = stat.AgentID
+		payloads[i] = string(stat.Payload)
+	}
+	_, err := q.db.ExecContext(ctx, query,
+		pq.Array(ids),
+		pq.Array(createdAts),
+		pq.Array(userIDs),
+		pq.Array(workspaceIDs),
+		pq.Array(templateIDs),
+		pq.Array(agentIDs),
+		pq.Array(payloads),
+	)
+	if err != nil {
+		return xerrors.Errorf("upsert agent stats: %w", err)
+	}
+	return nil
+}
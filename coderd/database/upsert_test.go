@@ -0,0 +1,93 @@
+package database_test
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/coder/coder/coderd/database"
+	"github.com/coder/coder/coderd/database/dbtestutil"
+)
+
+func TestInsertOrGetUser(t *testing.T) {
+	t.Parallel()
+
+	db, _ := dbtestutil.NewDB(t)
+	ctx := context.Background()
+	now := database.Now()
+	params := database.InsertUserParams{
+		ID:        uuid.New(),
+		Email:     "jane@coder.com",
+		Username:  "jane",
+		CreatedAt: now,
+		UpdatedAt: now,
+		RBACRoles: []string{},
+		LoginType: database.LoginTypePassword,
+	}
+
+	inserted, created, err := db.InsertOrGetUser(ctx, params)
+	require.NoError(t, err)
+	require.True(t, inserted)
+	require.Equal(t, params.ID, created.ID)
+
+	again := params
+	again.ID = uuid.New()
+	again.Email = strings.ToUpper(params.Email)
+	again.Username = "jane2"
+	inserted, existing, err := db.InsertOrGetUser(ctx, again)
+	require.NoError(t, err)
+	require.False(t, inserted, "a user with the same email exists")
+	require.Equal(t, created.ID, existing.ID)
+	require.Equal(t, "jane", existing.Username, "the existing user is unchanged")
+
+	other := params
+	other.ID = uuid.New()
+	other.Email = "other@coder.com"
+	other.Username = "Jane"
+	_, _, err = db.InsertOrGetUser(ctx, other)
+	require.Error(t, err, "a username conflict, ignoring case, is not the same user")
+}
+
+func TestUpsertAgentStats(t *testing.T) {
+	t.Parallel()
+
+	db, _ := dbtestutil.NewDB(t)
+	ctx := context.Background()
+	agentID := uuid.New()
+	now := database.Now()
+	stat := func(id uuid.UUID, payload string) database.InsertAgentStatParams {
+		now = now.Add(time.Second)
+		return database.InsertAgentStatParams{
+			ID:          id,
+			CreatedAt:   now,
+			UserID:      uuid.New(),
+			WorkspaceID: uuid.New(),
+			TemplateID:  uuid.New(),
+			AgentID:     agentID,
+			Payload:     json.RawMessage(payload),
+		}
+	}
+
+	retried := uuid.New()
+	require.NoError(t, db.UpsertAgentStats(ctx, []database.InsertAgentStatParams{
+		stat(retried, `{"rx_bytes":1}`),
+	}))
+
+	// The same stat appears twice in the batch and already exists.
+	require.NoError(t, db.UpsertAgentStats(ctx, []database.InsertAgentStatParams{
+		stat(retried, `{"rx_bytes":2}`),
+		stat(uuid.New(), `{"rx_bytes":10}`),
+		stat(retried, `{"rx_bytes":3}`),
+	}))
+	require.NoError(t, db.UpsertAgentStats(ctx, nil))
+
+	latest, err := db.GetLatestAgentStat(ctx, agentID)
+	require.NoError(t, err)
+	require.Equal(t, retried, latest.ID, "the last occurrence of the duplicate wins")
+	require.JSONEq(t, `{"rx_bytes":3}`, string(latest.Payload))
+}
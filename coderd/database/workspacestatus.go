@@ -0,0 +1,45 @@
+package database
+
+import "golang.org/x/xerrors"
+
+// WorkspaceStatus is a status the workspace list queries can filter by. It
+// is derived from the latest build and its job rather than stored, so
+// Postgres does not know the set and an unknown value would silently match
+// every workspace.
+type WorkspaceStatus string
+
+const (
+	WorkspaceStatusPending   WorkspaceStatus = "pending"
+	WorkspaceStatusStarting  WorkspaceStatus = "starting"
+	WorkspaceStatusRunning   WorkspaceStatus = "running"
+	WorkspaceStatusStopping  WorkspaceStatus = "stopping"
+	WorkspaceStatusStopped   WorkspaceStatus = "stopped"
+	WorkspaceStatusFailed    WorkspaceStatus = "failed"
+	WorkspaceStatusCanceling WorkspaceStatus = "canceling"
+	WorkspaceStatusCanceled  WorkspaceStatus = "canceled"
+	WorkspaceStatusDeleted   WorkspaceStatus = "deleted"
+	WorkspaceStatusDeleting  WorkspaceStatus = "deleting"
+)
+
+// IsValidWorkspaceStatus reports whether status is one the workspace list
+// queries understand.
+func IsValidWorkspaceStatus(status string) bool {
+	switch WorkspaceStatus(status) {
+	case WorkspaceStatusPending, WorkspaceStatusStarting, WorkspaceStatusRunning,
+		WorkspaceStatusStopping, WorkspaceStatusStopped, WorkspaceStatusFailed,
+		WorkspaceStatusCanceling, WorkspaceStatusCanceled, WorkspaceStatusDeleted,
+		WorkspaceStatusDeleting:
+		return true
+	default:
+		return false
+	}
+}
+
+// validateStatusFilter returns an error for a non-empty status filter the
+// queries do not understand.
+func validateStatusFilter(status string) error {
+	if status != "" && !IsValidWorkspaceStatus(status) {
+		return xerrors.Errorf("unknown workspace status in filter: %q", status)
+	}
+	return nil
+}
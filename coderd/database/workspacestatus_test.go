@@ -0,0 +1,61 @@
+package database_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/coder/coder/coderd/database"
+	"github.com/coder/coder/coderd/database/databasefake"
+	"github.com/coder/coder/coderd/rbac"
+)
+
+func TestIsValidWorkspaceStatus(t *testing.T) {
+	t.Parallel()
+
+	for _, status := range []string{"pending", "starting", "running", "stopping", "stopped", "failed", "canceling", "canceled", "deleted", "deleting"} {
+		require.True(t, database.IsValidWorkspaceStatus(status), status)
+	}
+	for _, status := range []string{"", "runing", "Running", "start"} {
+		require.False(t, database.IsValidWorkspaceStatus(status), status)
+	}
+}
+
+// allowAll is an authorization filter that matches every object.
+type allowAll struct{}
+
+func (allowAll) RegoString() string              { return "true" }
+func (allowAll) SQLString(rbac.SQLConfig) string { return "true" }
+func (allowAll) Eval(rbac.Object) bool           { return true }
+
+func TestWorkspaceStatusFilter(t *testing.T) {
+	t.Parallel()
+
+	sqlDB, connector := newRecordingDB()
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	ctx := context.Background()
+
+	for _, db := range []database.Store{database.New(sqlDB), databasefake.New()} {
+		_, err := db.GetAuthorizedWorkspaces(ctx, database.GetWorkspacesParams{Status: "runing"}, nil)
+		require.ErrorContains(t, err, `unknown workspace status in filter: "runing"`)
+		_, err = db.GetAuthorizedWorkspaceCount(ctx, database.GetWorkspaceCountParams{Status: "runing"}, nil)
+		require.ErrorContains(t, err, `unknown workspace status in filter: "runing"`)
+	}
+	require.Empty(t, connector.Queries(), "invalid status rejected before the query")
+
+	// Rows carry every workspaces column, so the scan must bind each one.
+	dormantAt := database.Now()
+	connector.rows = func(string) ([]string, [][]driver.Value) {
+		columns := []string{"id", "created_at", "updated_at", "owner_id", "organization_id", "template_id", "deleted", "name", "autostart_schedule", "ttl", "last_used_at", "dormant_at"}
+		return columns, [][]driver.Value{{uuid.NewString(), time.Now(), time.Now(), uuid.NewString(), uuid.NewString(), uuid.NewString(), false, "workspace", nil, nil, time.Now(), dormantAt}}
+	}
+	workspaces, err := database.New(sqlDB).GetAuthorizedWorkspaces(ctx, database.GetWorkspacesParams{Status: string(database.WorkspaceStatusRunning)}, allowAll{})
+	require.NoError(t, err)
+	require.Len(t, connector.Queries(), 1)
+	require.Len(t, workspaces, 1)
+	require.True(t, workspaces[0].DormantAt.Time.Equal(dormantAt))
+}
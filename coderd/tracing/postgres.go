@@ -30,16 +30,34 @@ func PostgresDriver(tp trace.TracerProvider, service string) (string, error) {
 	return driverName, nil
 }
 
+type dbTxNameKey struct{}
+
+// WithDBTxName returns a context whose database spans are named after the
+// transaction they belong to. Use database.WithTxName instead, which also
+// names the statements of the transaction made with other contexts.
+func WithDBTxName(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, dbTxNameKey{}, name)
+}
+
+// DBTxNameFromContext returns the name set by WithDBTxName, or "".
+func DBTxNameFromContext(ctx context.Context) string {
+	name, _ := ctx.Value(dbTxNameKey{}).(string)
+	return name
+}
+
 func formatPostgresSpan(ctx context.Context, op string) string {
+	name := strings.ToUpper(op)
 	const qPrefix = "-- name: "
 	q := otelsql.QueryFromContext(ctx)
-	if q == "" || !strings.HasPrefix(q, qPrefix) {
-		return strings.ToUpper(op)
+	if strings.HasPrefix(q, qPrefix) {
+		// Remove the qPrefix and then grab the method name.
+		// We expect the first line of the query to be in
+		// the format "-- name: GetAPIKeyByID :one".
+		s := strings.SplitN(strings.TrimPrefix(q, qPrefix), " ", 2)[0]
+		name = fmt.Sprintf("%s %s", name, s)
 	}
-
-	// Remove the qPrefix and then grab the method name.
-	// We expect the first line of the query to be in
-	// the format "-- name: GetAPIKeyByID :one".
-	s := strings.SplitN(strings.TrimPrefix(q, qPrefix), " ", 2)[0]
-	return fmt.Sprintf("%s %s", strings.ToUpper(op), s)
+	if tx := DBTxNameFromContext(ctx); tx != "" {
+		name = fmt.Sprintf("%s [%s]", name, tx)
+	}
+	return name
 }
@@ -0,0 +1,21 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/nhatthm/otelsql"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatPostgresSpan(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	query := otelsql.ContextWithQuery(ctx, "-- name: GetAPIKeyByID :one\nSELECT 1")
+	require.Equal(t, "QUERY", formatPostgresSpan(ctx, "query"))
+	require.Equal(t, "QUERY", formatPostgresSpan(otelsql.ContextWithQuery(ctx, "SELECT 1"), "query"))
+	require.Equal(t, "QUERY GetAPIKeyByID", formatPostgresSpan(query, "query"))
+	require.Equal(t, "QUERY GetAPIKeyByID [build workspace]", formatPostgresSpan(WithDBTxName(query, "build workspace"), "query"))
+	require.Equal(t, "BEGIN [build workspace]", formatPostgresSpan(WithDBTxName(ctx, "build workspace"), "begin"))
+}